@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"xdp-banner/agent/cmd/ctl"
 	"xdp-banner/agent/cmd/global"
 	"xdp-banner/agent/cmd/join"
 	"xdp-banner/agent/cmd/server"
@@ -21,8 +22,10 @@ func NewAgentCmd() *cobra.Command {
 	}
 
 	opt.SetFlags(cmd)
+	opt.SetConfigFlag(cmd)
 	cmd.AddCommand(join.Cmd(opt))
 	cmd.AddCommand(server.Cmd(opt))
+	cmd.AddCommand(ctl.Cmd())
 
 	return cmd
 }
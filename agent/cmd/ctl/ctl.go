@@ -0,0 +1,231 @@
+package ctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"xdp-banner/agent/ebpf/xdp"
+	"xdp-banner/agent/internal/admin"
+	"xdp-banner/agent/internal/ruleset"
+	"xdp-banner/pkg/rule"
+
+	"github.com/spf13/cobra"
+)
+
+func Cmd() *cobra.Command {
+	opt := DefaultOption()
+
+	cmd := &cobra.Command{
+		Use:   "ctl",
+		Short: "Inspect and control the local agent through its admin socket",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	opt.SetFlags(cmd)
+	cmd.AddCommand(
+		mapsCmd(opt),
+		interfacesCmd(opt),
+		countersCmd(opt),
+		banCmd(opt),
+		bansCmd(opt),
+		resyncCmd(opt),
+	)
+
+	return cmd
+}
+
+func mapsCmd(opt *Option) *cobra.Command {
+	return &cobra.Command{
+		Use:   "maps",
+		Short: "Dump the live BPF maps decoded into rules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cli, err := admin.NewClient(opt.AdminAddr)
+			if err != nil {
+				return err
+			}
+
+			dump, err := cli.Maps()
+			if err != nil {
+				return err
+			}
+			if opt.JSON {
+				return printJSON(dump)
+			}
+
+			w := newTable()
+			fmt.Fprintln(w, "CIDR\tIDENTITY\tPROTOCOL\tSPORT\tDPORT")
+			for _, r := range dump.Rules {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.CIDR, r.Identity, xdp.ProtocolName(r.BannedProtocol), port(r.Sport), port(r.Dport))
+			}
+			w.Flush()
+
+			// 没有对应 banlist 的 identity, 通常是 allow 规则或残留条目
+			banned := make(map[uint32]bool, len(dump.Bans))
+			for _, b := range dump.Bans {
+				banned[b.Identity] = true
+			}
+			for _, i := range dump.Identities {
+				if !banned[i.Identity] {
+					fmt.Printf("\nidentity %d of %s has no ban entry\n", i.Identity, i.CIDR)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func interfacesCmd(opt *Option) *cobra.Command {
+	return &cobra.Command{
+		Use:   "interfaces",
+		Short: "Show the XDP attach state of each interface",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cli, err := admin.NewClient(opt.AdminAddr)
+			if err != nil {
+				return err
+			}
+
+			states, err := cli.Interfaces()
+			if err != nil {
+				return err
+			}
+			if opt.JSON {
+				return printJSON(states)
+			}
+
+			w := newTable()
+			fmt.Fprintln(w, "INDEX\tNAME\tUP\tATTACHED\tMODE")
+			for _, s := range states {
+				fmt.Fprintf(w, "%d\t%s\t%t\t%t\t%s\n", s.Index, s.Name, s.Up, s.Attached, s.Mode)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func countersCmd(opt *Option) *cobra.Command {
+	return &cobra.Command{
+		Use:   "counters",
+		Short: "Show the pass/drop packet counters",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cli, err := admin.NewClient(opt.AdminAddr)
+			if err != nil {
+				return err
+			}
+
+			counters, err := cli.Counters()
+			if err != nil {
+				return err
+			}
+			if opt.JSON {
+				return printJSON(counters)
+			}
+
+			w := newTable()
+			fmt.Fprintln(w, "PASS\tDROP")
+			fmt.Fprintf(w, "%d\t%d\n", counters.Pass, counters.Drop)
+			return w.Flush()
+		},
+	}
+}
+
+func banCmd(opt *Option) *cobra.Command {
+	r := ruleset.StaticRule{RuleInfo: rule.RuleInfo{Duration: admin.DefaultBanDuration}}
+
+	cmd := &cobra.Command{
+		Use:   "ban",
+		Short: "Add a temporary local ban, it is lost when the agent stops or reloads",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cli, err := admin.NewClient(opt.AdminAddr)
+			if err != nil {
+				return err
+			}
+
+			r.Action = ruleset.ActionDeny
+			if err := cli.Ban(r); err != nil {
+				return err
+			}
+			fmt.Printf("banned %s for %s\n", r.Key(), r.Duration)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&r.Cidr, "cidr", r.Cidr, "source cidr to ban")
+	cmd.Flags().StringVar(&r.Protocol, "protocol", "TCP", "protocol to ban, TCP/UDP/ICMP")
+	cmd.Flags().Uint16Var(&r.Sport, "sport", r.Sport, "source port, 0 for any")
+	cmd.Flags().Uint16Var(&r.Dport, "dport", r.Dport, "destination port, 0 for any")
+	cmd.Flags().StringVar(&r.Duration, "duration", r.Duration, "how long the ban lasts, e.g. 30m, 1d")
+	cmd.Flags().StringVar(&r.Comment, "comment", r.Comment, "comment of the ban")
+	cmd.MarkFlagRequired("cidr")
+
+	return cmd
+}
+
+func bansCmd(opt *Option) *cobra.Command {
+	return &cobra.Command{
+		Use:   "bans",
+		Short: "List the temporary local bans",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cli, err := admin.NewClient(opt.AdminAddr)
+			if err != nil {
+				return err
+			}
+
+			rules, err := cli.TempRules()
+			if err != nil {
+				return err
+			}
+			if opt.JSON {
+				return printJSON(rules)
+			}
+
+			w := newTable()
+			fmt.Fprintln(w, "CIDR\tPROTOCOL\tSPORT\tDPORT\tEXPIRES\tCOMMENT")
+			for _, r := range rules {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Cidr, r.Protocol, port(r.Sport), port(r.Dport),
+					time.Until(r.ExpiresAt).Truncate(time.Second), r.Comment)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func resyncCmd(opt *Option) *cobra.Command {
+	return &cobra.Command{
+		Use:   "resync",
+		Short: "Reload the rules file and rewrite all rules to the BPF maps",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cli, err := admin.NewClient(opt.AdminAddr)
+			if err != nil {
+				return err
+			}
+
+			if err := cli.Resync(); err != nil {
+				return err
+			}
+			fmt.Println("resynced")
+			return nil
+		},
+	}
+}
+
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func port(p uint16) string {
+	if p == 0 {
+		return "*"
+	}
+	return fmt.Sprint(p)
+}
@@ -0,0 +1,23 @@
+package ctl
+
+import (
+	"xdp-banner/agent/cmd/server"
+
+	"github.com/spf13/cobra"
+)
+
+type Option struct {
+	AdminAddr string
+	JSON      bool
+}
+
+func DefaultOption() *Option {
+	return &Option{
+		AdminAddr: server.DefaultAdminAddr,
+	}
+}
+
+func (o *Option) SetFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&o.AdminAddr, "admin-addr", o.AdminAddr, "admin unix socket of the local agent")
+	cmd.PersistentFlags().BoolVar(&o.JSON, "json", o.JSON, "print raw json instead of tables")
+}
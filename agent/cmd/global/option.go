@@ -2,23 +2,42 @@ package global
 
 import (
 	"fmt"
+	"net"
+	"strings"
 	"xdp-banner/agent/internal/icert"
+	"xdp-banner/pkg/config"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap/zapcore"
 )
 
+// DefaultConfigFile is read when it exists, --config makes it mandatory
+const DefaultConfigFile = "/etc/xdp-banner/agent.yaml"
+
+// EnvPrefix is the prefix of the environment variables overriding the config file
+const EnvPrefix = "XDP_AGENT"
+
 type Option struct {
-	Orch *OrchOption
+	Orch *OrchOption `mapstructure:"orch"`
+	Log  *LogOption  `mapstructure:"log"`
+
+	// ConfigFile is set by --config, it is not part of the file itself
+	ConfigFile string `mapstructure:"-"`
 }
 
 func DefaultOption() *Option {
 	return &Option{
-		&OrchOption{
-			Endpoints:   "az.evilsp4.ltd:6061",
+		Orch: &OrchOption{
+			Endpoints:   DefaultEndpoints,
 			CAPath:      icert.CAFile,
 			CertPath:    icert.CertFile,
 			CertKeyPath: icert.KeyFile,
 		},
+		Log: &LogOption{
+			Level: "info",
+		},
+		ConfigFile: DefaultConfigFile,
 	}
 }
 
@@ -27,18 +46,43 @@ func (e *Option) Check() error {
 		return err
 	}
 
+	if err := e.Log.Check(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func (e *Option) SetFlags(cmd *cobra.Command) {
 	e.Orch.SetFlags(cmd)
+	e.Log.SetFlags(cmd)
+}
+
+// SetConfigFlag registers --config on the root command
+func (e *Option) SetConfigFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&e.ConfigFile, "config", "c", e.ConfigFile, "config file, flags and XDP_AGENT_* environment variables override it")
+}
+
+// Loader returns the loader of the config file, the default file may be missing
+func (e *Option) Loader(flags *pflag.FlagSet) config.Loader {
+	return config.Loader{
+		File:      e.ConfigFile,
+		Optional:  !flags.Changed("config"),
+		EnvPrefix: EnvPrefix,
+		Flags:     flags,
+	}
 }
 
+// DefaultEndpoints is the orch service of the cluster, it resolves to every orchestrator
+const DefaultEndpoints = "orch.xdp-banner.svc.cluster.local:6061"
+
 type OrchOption struct {
-	Endpoints   string
-	CAPath      string
-	CertPath    string
-	CertKeyPath string
+	// Endpoints is a comma separated host:port list, a host resolving to several
+	// addresses stands for all of them. More orchestrators are discovered at runtime.
+	Endpoints   string `mapstructure:"endpoints"`
+	CAPath      string `mapstructure:"caPath"`
+	CertPath    string `mapstructure:"certPath"`
+	CertKeyPath string `mapstructure:"certKeyPath"`
 }
 
 func (o *OrchOption) Check() error {
@@ -46,13 +90,39 @@ func (o *OrchOption) Check() error {
 		return fmt.Errorf("xdp-banner endpoints is required")
 	}
 
+	for _, e := range strings.Split(o.Endpoints, ",") {
+		if e = strings.TrimSpace(e); e == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(e); err != nil {
+			return fmt.Errorf("invalid xdp-banner endpoint %q: %w", e, err)
+		}
+	}
+
 	return nil
 }
 
 func (o *OrchOption) SetFlags(cmd *cobra.Command) {
 	cmdPrefix := "xdp-banner-"
-	cmd.Flags().StringVarP(&o.Endpoints, cmdPrefix+"endpoints", "e", o.Endpoints, "xdp-banner orchestrator endpoints")
+	cmd.Flags().StringVarP(&o.Endpoints, cmdPrefix+"endpoints", "e", o.Endpoints, "comma separated xdp-banner orchestrator endpoints, e.g. orch-1:6061,orch-2:6061")
 	cmd.Flags().StringVar(&o.CAPath, cmdPrefix+"capath", o.CAPath, "xdp-banner cluster ca path")
 	cmd.Flags().StringVar(&o.CertPath, cmdPrefix+"certpath", o.CertPath, "local node cert path")
 	cmd.Flags().StringVar(&o.CertKeyPath, cmdPrefix+"certkeypath", o.CertKeyPath, "local node cert key path")
 }
+
+type LogOption struct {
+	// Level is one of debug, info, warn and error, it is reloaded at runtime
+	Level string `mapstructure:"level"`
+}
+
+func (o *LogOption) Check() error {
+	if _, err := zapcore.ParseLevel(o.Level); err != nil {
+		return fmt.Errorf("invalid log level %q", o.Level)
+	}
+
+	return nil
+}
+
+func (o *LogOption) SetFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.Level, "log-level", o.Level, "log level, one of debug, info, warn and error")
+}
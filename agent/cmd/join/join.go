@@ -3,6 +3,8 @@ package join
 import (
 	"crypto/tls"
 	"xdp-banner/agent/cmd/global"
+	"xdp-banner/agent/internal/capability"
+	"xdp-banner/agent/internal/client"
 	"xdp-banner/agent/internal/icert"
 	"xdp-banner/api/orch/v1/agent/control"
 	"xdp-banner/pkg/cert"
@@ -22,6 +24,11 @@ func Cmd(parentOpt *global.Option) *cobra.Command {
 		Use:   "join",
 		Short: "join to the cluster",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			loader := opt.Parent.Loader(cmd.Flags())
+			if _, err := loader.Load(map[string]any{"": opt.Parent}); err != nil {
+				return err
+			}
+
 			if err := opt.Check(); err != nil {
 				return err
 			}
@@ -69,8 +76,10 @@ func newGrpcServer(_ context.Context, endpoints string) *grpc.ClientConn {
 		InsecureSkipVerify: true,
 	})
 
+	// 依次尝试每个 orch, 直到有一个可以连接
 	conn, err := grpc.NewClient(
-		endpoints,
+		client.Target(endpoints),
+		grpc.WithResolvers(client.NewResolver()),
 		grpc.WithTransportCredentials(cred),
 	)
 	if err != nil {
@@ -114,6 +123,7 @@ func joinToCluster(ctx context.Context, conn *grpc.ClientConn, name string, toke
 		IpAddresses: []string{
 			ip.String(),
 		},
+		Capabilities: capability.Probe().ToProto(),
 	})
 	if err != nil {
 		log.FatalE("init failed", err)
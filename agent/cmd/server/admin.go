@@ -0,0 +1,154 @@
+package server
+
+import (
+	"time"
+
+	"xdp-banner/agent/ebpf/xdp"
+	"xdp-banner/agent/internal/admin"
+	"xdp-banner/agent/internal/ruleset"
+	"xdp-banner/agent/internal/service"
+	"xdp-banner/pkg/errors"
+	"xdp-banner/pkg/log"
+)
+
+var errNotRunning = errors.NewServiceError("xdp controller is not running")
+
+// adminBackend exposes the controller on the admin socket
+type adminBackend struct {
+	*controller
+	opt *Option
+}
+
+// serveAdmin starts the admin socket in background, an empty address disables it
+func serveAdmin(opt *Option, c *controller) {
+	if opt.AdminAddr == "" {
+		return
+	}
+
+	srv, err := admin.NewServer(opt.AdminAddr, &adminBackend{controller: c, opt: opt})
+	if err != nil {
+		log.Fatal("create admin socket", log.ErrorField(err))
+	}
+
+	go func() {
+		if err := srv.Serve(); err != nil {
+			log.Error("serve admin socket", log.ErrorField(err))
+		}
+	}()
+}
+
+func (c *controller) Dump() (*xdp.MapDump, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.xdpMap == nil {
+		return nil, errNotRunning
+	}
+	return c.xdpMap.Dump()
+}
+
+// DumpMaps returns the live maps and the entries written for local rules,
+// it backs the DumpMaps rpc used by the orchestrator drift check
+func (c *controller) DumpMaps() (*service.MapsSnapshot, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.xdpMap == nil || c.rules == nil {
+		return nil, errNotRunning
+	}
+
+	dump, err := c.xdpMap.Dump()
+	if err != nil {
+		return nil, err
+	}
+	local, allowed := c.rules.LocalRules()
+
+	return &service.MapsSnapshot{
+		ConfigName:   c.config,
+		Dump:         dump,
+		LocalRules:   local,
+		AllowedCIDRs: allowed,
+	}, nil
+}
+
+// RepairMaps 用 orch 给出的完整规则替换内存中的 orch 规则, 并原地修复 datapath:
+// 先覆盖写入全部规则再删除多余条目, 修复期间不会解封仍然有效的规则
+func (c *controller) RepairMaps(configName string, rules map[string]xdp.IPRule) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.rules == nil {
+		return errNotRunning
+	}
+	if configName != c.config {
+		return errors.NewInputErrorf("agent is running config %q, not %q", c.config, configName)
+	}
+
+	log.Info("repair datapath", log.StringField("config", configName), log.IntField("rules", len(rules)))
+	c.rules.ReplaceOrchRules(rules)
+	return c.rules.Resync()
+}
+
+func (c *controller) Interfaces() ([]xdp.InterfaceState, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.xdpProg == nil {
+		return nil, errNotRunning
+	}
+	return c.xdpProg.InterfaceStates()
+}
+
+func (c *controller) Counters() (xdp.Counters, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.xdpMap == nil {
+		return xdp.Counters{}, errNotRunning
+	}
+	return c.xdpMap.Counters()
+}
+
+// Ban 添加本地临时封禁, 只保存在内存中, Stop/Reload 后失效
+func (c *controller) Ban(rule ruleset.StaticRule, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.rules == nil {
+		return errNotRunning
+	}
+
+	log.Info("add temporary rule", log.StringField("rule", rule.Key()), log.DurationField("ttl", ttl))
+	return c.rules.AddTempRule(rule, ttl)
+}
+
+func (c *controller) TempRules() ([]ruleset.TempRule, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.rules == nil {
+		return nil, errNotRunning
+	}
+	return c.rules.TempRules(), nil
+}
+
+// Resync reloads the rules file and writes all rules to the datapath again
+func (b *adminBackend) Resync() error {
+	if b.opt.RulesFile != "" {
+		rules, err := ruleset.Load(b.opt.RulesFile)
+		if err != nil {
+			return err
+		}
+		b.SetStaticRules(rules)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rules == nil {
+		return errNotRunning
+	}
+
+	log.Info("resync rules to datapath")
+	return b.rules.Resync()
+}
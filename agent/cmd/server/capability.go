@@ -0,0 +1,29 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"xdp-banner/agent/internal/capability"
+	"xdp-banner/agent/internal/client"
+)
+
+// capabilityInterval is how often the host is probed again, nics can be
+// added or rebound to another driver while the agent is running
+const capabilityInterval = 10 * time.Minute
+
+// watchCapabilities probes the host every interval and updates the report
+func watchCapabilities(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		client.SetCapabilities(capability.Probe())
+	}
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"xdp-banner/agent/ebpf"
 	"xdp-banner/agent/ebpf/xdp"
 	"xdp-banner/agent/internal/client"
+	"xdp-banner/agent/internal/ruleset"
 	"xdp-banner/api/orch/v1/rule"
 	"xdp-banner/pkg/log"
 	model "xdp-banner/pkg/rule"
@@ -18,15 +22,24 @@ import (
 )
 
 type controller struct {
-	client    client.Client
+	client    client.Client // nil in standalone mode
 	ctx       context.Context
 	cancelCtx context.CancelFunc
 	xdpMap    *xdp.BannedIPXdpMap
 	xdpProg   *xdp.XdpProgManager
-	attached  bool       // 标记是否已附加到接口
-	attachIf  []string   // 记录附加的接口名
-	mu        sync.Mutex // 保护并发访问
+	attached  bool             // 标记是否已附加到接口
+	attachIf  []string         // 记录附加的接口名
+	attachErr map[string]error // 挂载失败的接口及原因
+	rules     *ruleset.RuleSet
+	static    []ruleset.StaticRule // 本地规则文件中的静态规则
+	protect   []string             // 本地配置的永不封禁网段
+	orchProt  []string             // orch 下发的永不封禁网段, 拉取失败时保留上一次的结果
+	config    string               // 当前监听的 orch config, standalone 时为空
+	mu        sync.Mutex           // 保护并发访问
 	wg        sync.WaitGroup
+
+	streamConnected atomic.Bool  // orch 规则 stream 是否处于连接状态
+	streamDownAt    atomic.Int64 // stream 断开的时间 (unix nano), 连接时为 0
 }
 
 // Global Controller Ctx
@@ -59,6 +72,21 @@ func (c *controller) Start(ctx context.Context, e *fsm.Event) (err error) {
 		}
 	}()
 
+	return c.start(configName)
+}
+
+// StartStandalone 在没有 orch 的情况下启动, 只下发本地规则文件中的静态规则
+func (c *controller) StartStandalone() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	log.Info("Starting XDP controller in standalone mode", log.IntField("static rules", len(c.static)))
+
+	return c.start("")
+}
+
+// start 初始化 eBPF 并下发静态规则, configName 非空时同时监听 orch 下发的规则
+func (c *controller) start(configName string) (err error) {
 	if c.cancelCtx != nil {
 		c.cancelCtx()
 	}
@@ -66,19 +94,103 @@ func (c *controller) Start(ctx context.Context, e *fsm.Event) (err error) {
 	c.ctx, c.cancelCtx = context.WithCancel(context.Background())
 
 	if c.xdpMap == nil || c.xdpProg == nil {
-		c.xdpMap, c.xdpProg, c.attachIf, err = ebpf.Init()
+		var result ebpf.AttachResult
+		c.xdpMap, c.xdpProg, result, err = ebpf.Init()
 		if err != nil {
 			return fmt.Errorf("ebpf init failed: %w", err)
 		}
+		c.attachIf, c.attachErr = result.Attached, result.Failed
+		c.rules = ruleset.New(c.xdpMap)
 	}
 	c.attached = true
+	c.config = configName
 
-	c.wg.Add(1)
-	go c.watchRules(configName)
+	// 保护网段必须先于任何规则生效
+	c.applyProtected()
+	if err := c.rules.SetStaticRules(c.static); err != nil {
+		log.Error("apply static rules", log.ErrorField(err))
+	}
+
+	if configName != "" && c.client != nil {
+		c.wg.Add(2)
+		go c.refreshProtected(c.ctx)
+		go c.watchRules(configName, c.rules)
+	}
 
 	return nil
 }
 
+// SetStaticRules 替换静态规则, 运行中则立即与 orch 规则合并后下发
+func (c *controller) SetStaticRules(rules []ruleset.StaticRule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.static = rules
+	if c.rules == nil {
+		return
+	}
+
+	if err := c.rules.SetStaticRules(rules); err != nil {
+		log.Error("apply static rules", log.ErrorField(err))
+	}
+}
+
+// SetProtected 设置本地配置的保护网段
+func (c *controller) SetProtected(ranges []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.protect = ranges
+	if c.rules != nil {
+		c.applyProtected()
+	}
+}
+
+// applyProtected merges the local, orchestrator and orchestrator address
+// ranges into the rule set, c.mu must be held
+func (c *controller) applyProtected() {
+	ranges := slices.Concat(c.protect, c.orchProt)
+	if c.client != nil {
+		ranges = append(ranges, c.client.Orchestrators()...)
+	}
+
+	if err := c.rules.SetProtected(ranges); err != nil {
+		log.Error("apply protected ranges", log.ErrorField(err))
+	}
+}
+
+const protectRefreshInterval = time.Minute
+
+// refreshProtected 定期拉取 orch 的保护网段, orch 的地址也可能随解析结果变化
+func (c *controller) refreshProtected(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(protectRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		ranges, err := c.client.ProtectedRanges(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Warn("fetch protected ranges, keeping the previous ones", log.ErrorField(err))
+		}
+
+		c.mu.Lock()
+		if ctx.Err() == nil && c.rules != nil {
+			if err == nil {
+				c.orchProt = ranges
+			}
+			c.applyProtected()
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func (c *controller) Stop(ctx context.Context, e *fsm.Event) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -108,9 +220,14 @@ func (c *controller) Stop(ctx context.Context, e *fsm.Event) error {
 		return err
 	}
 
+	if c.rules != nil {
+		c.rules.Close()
+	}
 	c.xdpProg = nil
 	c.xdpMap = nil
+	c.rules = nil
 	c.attached = false
+	c.config = ""
 
 	return nil
 }
@@ -151,8 +268,12 @@ func (c *controller) Reload(ctx context.Context, e *fsm.Event) (err error) {
 		return err
 	}
 
+	if c.rules != nil {
+		c.rules.Close()
+	}
 	c.xdpProg = nil
 	c.xdpMap = nil
+	c.rules = nil
 	c.attached = false
 
 	configName := e.Args[0].(string)
@@ -163,31 +284,55 @@ func (c *controller) Reload(ctx context.Context, e *fsm.Event) (err error) {
 		}
 	}()
 
-	c.ctx, c.cancelCtx = context.WithCancel(context.Background())
-	c.xdpMap, c.xdpProg, c.attachIf, err = ebpf.Init()
-	if err != nil {
-		return fmt.Errorf("ebpf init failed: %w", err)
-	}
-	c.attached = true
-
-	c.wg.Add(1)
-	go c.watchRules(configName)
-
-	return nil
+	return c.start(configName)
 }
 
+const (
+	watchRetryMin = time.Second
+	watchRetryMax = 30 * time.Second
+)
+
 // watchRules 根据给定的 configName, 用 c.ctx 监听服务器下发的 WatchRuleResponse
-func (c *controller) watchRules(configName string) {
+func (c *controller) watchRules(configName string, rules *ruleset.RuleSet) {
 	defer c.wg.Done()
 
 	ruleChan := make(chan *rule.WatchRuleResponse, 100)
 	// 第一次拉取并开启 stream
 
 	go func() {
-		if err := c.client.GetRule(c.ctx, configName, ruleChan); err != nil {
-			log.Error("GetRule failed", zap.Error(err))
+		defer close(ruleChan)
+
+		// stream 断开后重新建立, 请求会被负载均衡到其它健康的 orch;
+		// 新的 stream 会先下发全部规则, 断开期间漏掉的删除由 orch 的 drift 检查修复
+		retry := watchRetryMin
+		c.streamDownAt.Store(0)
+		for {
+			c.streamConnected.Store(true)
+			start := time.Now()
+			err := c.client.GetRule(c.ctx, configName, ruleChan)
+			c.streamConnected.Store(false)
+
+			if c.ctx.Err() != nil {
+				c.streamDownAt.Store(time.Now().UnixNano())
+				return
+			}
+			if time.Since(start) > watchRetryMax {
+				// 之前的 stream 是正常的, 从现在开始计算断开时间
+				retry = watchRetryMin
+				c.streamDownAt.Store(time.Now().UnixNano())
+			} else {
+				// 连续重试失败时保留第一次断开的时间, watchdog 据此判断断开了多久
+				c.streamDownAt.CompareAndSwap(0, time.Now().UnixNano())
+			}
+			log.Error("GetRule failed, rewatching", zap.Error(err), log.DurationField("retry", retry))
+
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-time.After(retry):
+			}
+			retry = min(retry*2, watchRetryMax)
 		}
-		close(ruleChan)
 	}()
 
 	for {
@@ -197,7 +342,15 @@ func (c *controller) watchRules(configName string) {
 				// 服务器 stream 关闭
 				return
 			}
-			c.handleRuleEvent(resp)
+			c.handleRuleEvent(rules, resp)
+			// 同一批到达的事件合并后统一下发
+			open := c.drainRuleEvents(rules, ruleChan)
+			if err := rules.Sync(); err != nil {
+				log.Error("sync rules failed", zap.Error(err))
+			}
+			if !open {
+				return
+			}
 		case <-c.ctx.Done():
 			// 上层 cancelContext() 被调用
 			return
@@ -205,8 +358,28 @@ func (c *controller) watchRules(configName string) {
 	}
 }
 
-// handleRuleEvent 负责把 WatchRuleResponse 转成 IPRule 并打到 eBPF map
-func (c *controller) handleRuleEvent(resp *rule.WatchRuleResponse) {
+// drainRuleEvents 处理 ruleChan 中已经到达的事件, chan 已关闭时返回 false
+func (c *controller) drainRuleEvents(rules *ruleset.RuleSet, ruleChan chan *rule.WatchRuleResponse) bool {
+	for {
+		select {
+		case resp, ok := <-ruleChan:
+			if !ok {
+				return false
+			}
+			c.handleRuleEvent(rules, resp)
+		default:
+			return true
+		}
+	}
+}
+
+// handleRuleEvent 负责把 WatchRuleResponse 转成 IPRule 并记录到 RuleSet, 由 Sync 统一打到 eBPF map
+func (c *controller) handleRuleEvent(rules *ruleset.RuleSet, resp *rule.WatchRuleResponse) {
+	if resp.EventType == rule.EventType_DELETE {
+		rules.DeleteOrchRule(resp.RuleKey)
+		return
+	}
+
 	cidr, proto, sport, dport, err := xdp.ParseIPRuleKey(resp.RuleKey)
 	if err != nil {
 		log.Error("parse RuleKey failed", zap.Error(err))
@@ -230,16 +403,7 @@ func (c *controller) handleRuleEvent(resp *rule.WatchRuleResponse) {
 		Identity:       meta.Identity,
 	}
 
-	switch resp.EventType {
-	case 0:
-		if err := c.xdpMap.AddCIDRRule(ipRule); err != nil {
-			log.Error("AddCIDRRule failed", zap.Error(err))
-		}
-	case 1:
-		if err := c.xdpMap.RemoveCIDRRule(ipRule); err != nil {
-			log.Error("RemoveCIDRRule failed", zap.Error(err))
-		}
-	}
+	rules.PutOrchRule(resp.RuleKey, ipRule)
 }
 
 func ErrorWrapper(op func(context.Context, *fsm.Event) error) func(context.Context, *fsm.Event) {
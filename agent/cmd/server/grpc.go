@@ -20,8 +20,8 @@ func NewGrpcServer(services server.GrpcServices, creds credentials.TransportCred
 	}
 }
 
-func NewGrpcServices(fsm *statusfsm.StatusFSM) server.GrpcServices {
+func NewGrpcServices(fsm *statusfsm.StatusFSM, maps service.Maps) server.GrpcServices {
 	return server.GrpcServices{
-		"control": service.NewControlService(fsm),
+		"control": service.NewControlService(fsm, maps),
 	}
 }
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"xdp-banner/agent/ebpf/xdp"
+	"xdp-banner/agent/internal/client"
+	"xdp-banner/agent/internal/health"
+	"xdp-banner/agent/internal/statusfsm"
+	"xdp-banner/pkg/log"
+)
+
+// watchHealth evaluates the controller health every interval, reports the
+// conditions and moves the fsm between Running, Degraded and Failed
+func (c *controller) watchHealth(ctx context.Context, fsm *statusfsm.StatusFSM, interval time.Duration) {
+	tracker := health.NewTracker()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		snapshot, ok := c.healthSnapshot()
+		if !ok {
+			// 未启动时不上报健康状态
+			client.SetHealth(nil, nil, nil)
+			continue
+		}
+
+		verdict, conds := health.Evaluate(snapshot)
+		conds = tracker.Update(conds, time.Now())
+		client.SetHealth(conds, snapshot.Interfaces, snapshot.Maps)
+		fsm.SetHealth(verdict)
+
+		if verdict != health.Healthy {
+			log.Debug("agent is unhealthy", log.StringField("verdict", verdict.String()), log.AnyField("conditions", conds))
+		}
+	}
+}
+
+// healthSnapshot gathers the raw health data, false when the controller is not started
+func (c *controller) healthSnapshot() (health.Snapshot, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.attached || c.xdpProg == nil || c.xdpMap == nil {
+		return health.Snapshot{}, false
+	}
+
+	snapshot := health.Snapshot{
+		StreamRequired:  c.client != nil && c.config != "",
+		StreamConnected: c.streamConnected.Load(),
+	}
+
+	states, err := c.xdpProg.InterfaceStates()
+	if err != nil {
+		log.Warn("list interface states", log.ErrorField(err))
+	}
+	byName := make(map[string]xdp.InterfaceState, len(states))
+	for _, s := range states {
+		byName[s.Name] = s
+	}
+
+	for _, name := range c.attachIf {
+		s := byName[name]
+		ih := health.InterfaceHealth{Name: name, Attached: s.Attached && s.Up, Up: s.Up}
+		if !ih.Attached {
+			ih.Error = "interface is down or gone"
+		}
+		snapshot.Interfaces = append(snapshot.Interfaces, ih)
+	}
+	for name, err := range c.attachErr {
+		snapshot.Interfaces = append(snapshot.Interfaces, health.InterfaceHealth{Name: name, Error: err.Error()})
+	}
+
+	sort.Slice(snapshot.Interfaces, func(i, j int) bool { return snapshot.Interfaces[i].Name < snapshot.Interfaces[j].Name })
+
+	usage, err := c.xdpMap.Usage()
+	if err != nil {
+		log.Warn("read map usage", log.ErrorField(err))
+	}
+	for _, u := range usage {
+		snapshot.Maps = append(snapshot.Maps, health.MapUsage{Name: u.Name, Entries: u.Entries, MaxEntries: u.MaxEntries})
+	}
+
+	return snapshot, true
+}
@@ -4,17 +4,44 @@ import (
 	"fmt"
 	"time"
 	"xdp-banner/agent/cmd/global"
+	"xdp-banner/agent/internal"
+	"xdp-banner/pkg/cidr"
 	"xdp-banner/pkg/option"
 
 	"github.com/spf13/cobra"
 )
 
+const DefaultAdminAddr = "/run/xdp-banner/admin.sock"
+
+// Option is the server section of the config file, e.g.
+//
+//	server:
+//	  grpcAddr: 0.0.0.0:6063
+//	  reportInterval: 15s
+//	  otlp:
+//	    loggerHttpEndpoint: http://loki-gateway/otlp/v1/logs
 type Option struct {
-	Parent *global.Option
+	Parent *global.Option `mapstructure:"-"`
+
+	GrpcAddr string `mapstructure:"grpcAddr"`
+	// ReportInterval is reloaded at runtime
+	ReportInterval time.Duration `mapstructure:"reportInterval"`
+	// Otlp logger endpoints are reloaded at runtime
+	Otlp *option.OtlpOption `mapstructure:"otlp"`
+
+	// Standalone runs the agent without the orchestrator, only rules from RulesFile are applied
+	Standalone bool `mapstructure:"standalone"`
+	// RulesFile is a yaml or json file of static rules, merged with orchestrator rules in connected mode
+	RulesFile      string `mapstructure:"rulesFile"`
+	WatchRulesFile bool   `mapstructure:"watchRulesFile"`
 
-	GrpcAddr       string
-	ReportInterval time.Duration
-	Otlp           *option.OtlpOption
+	// AdminAddr is the local admin unix socket used by `xdp-agent ctl`, optionally followed by "|<octal perm>"
+	AdminAddr string `mapstructure:"adminAddr"`
+
+	// Protect are CIDRs never banned by this agent whatever the orchestrator
+	// says, "private" stands for the private ranges. The ranges protected by
+	// the orchestrators and the orchestrator addresses are always added.
+	Protect []string `mapstructure:"protect"`
 }
 
 func DefaultOption(parent *global.Option) *Option {
@@ -23,18 +50,47 @@ func DefaultOption(parent *global.Option) *Option {
 		GrpcAddr:       "0.0.0.0:6063",
 		ReportInterval: 15 * time.Second,
 		Otlp:           option.DefaultOtelOption(),
+		AdminAddr:      DefaultAdminAddr,
 	}
 }
 
+// IsStandalone reports whether the agent runs without the orchestrator,
+// either requested explicitly or because no orchestrator endpoint is set
+func (o *Option) IsStandalone() bool {
+	return o.Standalone || o.Parent.Orch.Endpoints == ""
+}
+
+// sections maps the sections of the config file to the options
+func (o *Option) sections() map[string]any {
+	return map[string]any{"": o.Parent, "server": o}
+}
+
 func (o *Option) Check() error {
-	if err := o.Parent.Check(); err != nil {
+	if o.IsStandalone() {
+		if o.RulesFile == "" {
+			return fmt.Errorf("rules file is required in standalone mode")
+		}
+		if err := o.Parent.Log.Check(); err != nil {
+			return err
+		}
+	} else if err := o.Parent.Check(); err != nil {
 		return err
 	}
 
+	if o.ReportInterval <= 0 {
+		return fmt.Errorf("report interval must be positive")
+	}
+
 	if o.GrpcAddr == "" {
 		return fmt.Errorf("grpc addr is empty")
 	}
 
+	for _, r := range internal.ExpandRanges(o.Protect) {
+		if _, err := cidr.Parse(r); err != nil {
+			return fmt.Errorf("protect: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -44,4 +100,9 @@ func (o *Option) SetFlags(cmd *cobra.Command) {
 
 	cmd.Flags().StringVar(&o.GrpcAddr, "grpc-addr", o.GrpcAddr, "grpc server address")
 	cmd.Flags().DurationVar(&o.ReportInterval, "report-interval", o.ReportInterval, "set agent report status interval to the orch")
+	cmd.Flags().BoolVar(&o.Standalone, "standalone", o.Standalone, "run without the orchestrator, only apply rules from --rules-file")
+	cmd.Flags().StringVar(&o.RulesFile, "rules-file", o.RulesFile, "yaml or json file of local static rules")
+	cmd.Flags().BoolVar(&o.WatchRulesFile, "watch-rules-file", o.WatchRulesFile, "reload the rules file when it changes")
+	cmd.Flags().StringVar(&o.AdminAddr, "admin-addr", o.AdminAddr, "local admin unix socket, e.g. /run/xdp-banner/admin.sock|0600, empty to disable")
+	cmd.Flags().StringSliceVar(&o.Protect, "protect", o.Protect, `cidrs never banned by this agent, "private" for the private ranges`)
 }
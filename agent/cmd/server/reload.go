@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"slices"
+
+	"xdp-banner/agent/cmd/global"
+	"xdp-banner/agent/internal/client"
+	"xdp-banner/pkg/config"
+
+	"github.com/spf13/viper"
+)
+
+// watchConfig applies the safe fields of the config on SIGHUP or when the file changes
+func watchConfig(ctx context.Context, v *viper.Viper, loader config.Loader, opt *Option, logging *config.Logging) {
+	r := &config.Reloader[Option]{
+		Loader:  loader,
+		Logging: logging,
+		Fresh: func() (*Option, map[string]any) {
+			fresh := DefaultOption(global.DefaultOption())
+			return fresh, fresh.sections()
+		},
+		Common: func(o *Option) config.Common {
+			return config.Common{Level: &o.Parent.Log.Level, Otlp: o.Otlp}
+		},
+		Reloadable: map[string]func(dst, src *Option){
+			"report-interval": func(dst, src *Option) { dst.ReportInterval = src.ReportInterval },
+		},
+		Check: (*Option).Check,
+		Apply: applyConfig,
+	}
+	r.Watch(ctx, v, opt)
+}
+
+// applyConfig applies the report interval, it reports whether fields which
+// only take effect after a restart changed
+func applyConfig(fresh, old *Option) bool {
+	if fresh.ReportInterval != old.ReportInterval && !old.IsStandalone() {
+		client.SetReportInterval(fresh.ReportInterval)
+	}
+
+	return fresh.GrpcAddr != old.GrpcAddr || fresh.AdminAddr != old.AdminAddr ||
+		fresh.IsStandalone() != old.IsStandalone() || fresh.RulesFile != old.RulesFile ||
+		fresh.WatchRulesFile != old.WatchRulesFile || *fresh.Parent.Orch != *old.Parent.Orch ||
+		!slices.Equal(fresh.Protect, old.Protect)
+}
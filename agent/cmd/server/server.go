@@ -1,19 +1,25 @@
 package server
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"net"
 	"xdp-banner/agent/cmd/global"
+	"xdp-banner/agent/internal"
+	"xdp-banner/agent/internal/capability"
 	"xdp-banner/agent/internal/client"
 	"xdp-banner/agent/internal/icert"
+	"xdp-banner/agent/internal/ruleset"
 	"xdp-banner/agent/internal/statusfsm"
+	"xdp-banner/pkg/config"
 	"xdp-banner/pkg/log"
 	"xdp-banner/pkg/node"
-	"xdp-banner/pkg/otlp"
+	"xdp-banner/pkg/notify"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -26,11 +32,17 @@ func Cmd(parentOpt *global.Option) *cobra.Command {
 		Use:   "server",
 		Short: "Start the server",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			loader := opt.Parent.Loader(cmd.Flags())
+			v, err := loader.Load(opt.sections())
+			if err != nil {
+				return err
+			}
+
 			if err := opt.Check(); err != nil {
 				return err
 			}
 
-			run(opt)
+			run(opt, loader, v)
 			return nil
 		},
 	}
@@ -40,45 +52,118 @@ func Cmd(parentOpt *global.Option) *cobra.Command {
 	return cmd
 }
 
-func run(opt *Option) {
-	setupOtlpLog(opt)
+func run(opt *Option, loader config.Loader, v *viper.Viper) {
+	if err := log.SetLevel(opt.Parent.Log.Level); err != nil {
+		log.Fatal("set log level", zap.Error(err))
+	}
+	logging := config.NewLogging("agent")
+	if err := logging.Setup(opt.Otlp); err != nil {
+		log.Fatal("otlp log error", zap.Error(err))
+	}
+	go watchConfig(context.Background(), v, loader, opt, logging)
+
+	if opt.IsStandalone() {
+		runStandalone(opt)
+		return
+	}
+
+	keyPair, err := icert.LoadKeyPair()
+	if err != nil {
+		log.Fatal("load node cert pair", zap.Error(err))
+	}
 
-	cred, err := NewCredits()
+	cred, err := NewCredits(keyPair)
 	if err != nil {
 		log.Fatal("create credentials", zap.Error(err))
 	}
 
-	cli, err := client.New(opt.Parent.Orch.Endpoints,
-		grpc.WithTransportCredentials(cred),
-		grpc.WithDefaultServiceConfig(`{"loadBalancingPolicy":"round_robin"}`), // 启用 Round Robin
-	)
+	cli, err := client.New(opt.Parent.Orch.Endpoints, grpc.WithTransportCredentials(cred))
 	if err != nil {
 		log.Fatal("create client", zap.Error(err))
 	}
 
+	go client.RotateCertificate(context.Background(), cli, keyPair)
+
 	client.SetupReporter(cli, opt.ReportInterval)
 	GatherBasicInfo(opt)
 	client.StartReporter()
+	go watchCapabilities(context.Background(), capabilityInterval)
 
 	controller := initControllerCtx(cli)
-	if err != nil {
-		log.Fatal("create credentials", zap.Error(err))
+	controller.SetProtected(internal.ExpandRanges(opt.Protect))
+	if err := setupStaticRules(opt, controller); err != nil {
+		log.Fatal("load static rules", zap.Error(err))
 	}
 
+	serveAdmin(opt, controller)
+
 	fsm := statusfsm.New(
 		ErrorWrapper(controller.Start),
 		ErrorWrapper(controller.Stop),
 		ErrorWrapper(controller.Reload),
 	)
 
-	grpcServices := NewGrpcServices(fsm)
+	go controller.watchHealth(context.Background(), fsm, opt.ReportInterval)
+
+	grpcServices := NewGrpcServices(fsm, controller)
 	grpcServer := NewGrpcServer(grpcServices, cred)
 
+	trapShutdown(controller)
+	go controller.runWatchdog(context.Background(), fsm)
+	_ = notify.Ready()
+
 	if err := grpcServer.Serve(opt.GrpcAddr); err != nil {
 		log.Fatal("serve", log.ErrorField(err))
 	}
 }
 
+// runStandalone applies the static rules directly, there is no orchestrator
+// to drive the status fsm and no grpc control service is exposed.
+func runStandalone(opt *Option) {
+	controller := initControllerCtx(nil)
+	controller.SetProtected(internal.ExpandRanges(opt.Protect))
+	if err := setupStaticRules(opt, controller); err != nil {
+		log.Fatal("load static rules", zap.Error(err))
+	}
+
+	if err := controller.StartStandalone(); err != nil {
+		log.Fatal("start controller", zap.Error(err))
+	}
+	serveAdmin(opt, controller)
+
+	trapShutdown(controller)
+	go controller.runWatchdog(context.Background(), nil)
+	_ = notify.Ready()
+
+	select {}
+}
+
+// setupStaticRules loads the local rules file into the controller, and
+// watches it when asked to
+func setupStaticRules(opt *Option, controller *controller) error {
+	if opt.RulesFile == "" {
+		return nil
+	}
+
+	var (
+		rules []ruleset.StaticRule
+		err   error
+	)
+	if opt.WatchRulesFile {
+		rules, err = ruleset.Watch(opt.RulesFile, controller.SetStaticRules)
+	} else {
+		rules, err = ruleset.Load(opt.RulesFile)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Info("loaded static rules", log.StringField("file", opt.RulesFile), log.IntField("rules", len(rules)))
+	controller.SetStaticRules(rules)
+
+	return nil
+}
+
 // GatherBasicInfo gathers basic info about this agent, and set to reporter
 func GatherBasicInfo(opt *Option) {
 	// Node name
@@ -99,9 +184,15 @@ func GatherBasicInfo(opt *Option) {
 		log.FatalE("extract port from grpc listen address", err)
 	}
 	client.SetGrpcEndpoint(fmt.Sprintf("%s:%s", ip, port))
+
+	// host capabilities
+	client.SetCapabilities(capability.Probe())
 }
 
-func NewCredits() (credentials.TransportCredentials, error) {
+// NewCredits builds the credentials shared by the orch client and the grpc
+// server, the certificate is taken from keyPair at every handshake so that
+// renewed certificates are used without a restart
+func NewCredits(keyPair *icert.KeyPair) (credentials.TransportCredentials, error) {
 	// load ca
 	caCert, err := icert.GetCA()
 	if err != nil {
@@ -113,40 +204,11 @@ func NewCredits() (credentials.TransportCredentials, error) {
 		return nil, fmt.Errorf("add CA certificate")
 	}
 
-	// load cert
-	cert, err := icert.GetCertPair()
-	if err != nil {
-		return nil, fmt.Errorf("load node cert pair: %w", err)
-	}
-
 	return credentials.NewTLS(&tls.Config{
-		Certificates: []tls.Certificate{cert},
-		RootCAs:      caCertPool,
-		ClientCAs:    caCertPool,
-		ClientAuth:   tls.VerifyClientCertIfGiven,
+		GetCertificate:       keyPair.GetCertificate,
+		GetClientCertificate: keyPair.GetClientCertificate,
+		RootCAs:              caCertPool,
+		ClientCAs:            caCertPool,
+		ClientAuth:           tls.VerifyClientCertIfGiven,
 	}), nil
 }
-
-func setupOtlpLog(opt *Option) {
-	res, err := otlp.DefaultResource("agent")
-	if err != nil {
-		log.Fatal("otlp resource error", zap.Error(err))
-	}
-
-	core, err := otlp.NewLog(
-		otlp.WithName("agent"),
-		otlp.WithHTTPEndpoint(opt.Otlp.LoggerHTTPEndpoint),
-		otlp.WithGrpcEndpoint(opt.Otlp.LoggerGrpcEndpoint),
-		otlp.WithResource(res),
-		otlp.WithInsecure(opt.Otlp.Insecure),
-		otlp.WithGzip(opt.Otlp.Insecure),
-		otlp.WithHeader(opt.Otlp.Header),
-		otlp.WithTimeout(opt.Otlp.Timeout),
-	)
-	if err != nil {
-		log.Fatal("otlp log error", zap.Error(err))
-	}
-
-	core = log.NewTreeWithDefaultLogger(core)
-	log.SetGlobalLogger(zap.New(core))
-}
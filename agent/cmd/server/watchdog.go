@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"xdp-banner/agent/internal/statusfsm"
+	"xdp-banner/pkg/log"
+	"xdp-banner/pkg/notify"
+	"xdp-banner/pkg/sig"
+)
+
+const (
+	// fsmAliveTimeout is how long a fsm callback may block the event loop,
+	// attaching to many interfaces can take a while
+	fsmAliveTimeout = time.Minute
+	// streamGrace is how long the rule stream may stay down before the
+	// watchdog gives up, the orchestrator reloads a disconnected agent first
+	streamGrace = 3 * time.Minute
+)
+
+// runWatchdog keeps the systemd watchdog fed while the agent is healthy,
+// fsm is nil in standalone mode
+func (c *controller) runWatchdog(ctx context.Context, fsm *statusfsm.StatusFSM) {
+	checks := []notify.Check{{Name: "rule stream", Check: c.streamHealthy}}
+	if fsm != nil {
+		checks = append(checks, notify.Check{Name: "fsm", Check: func() error { return fsm.Alive(fsmAliveTimeout) }})
+	}
+
+	notify.RunWatchdog(ctx, func() string { return c.statusLine(fsm) }, checks...)
+}
+
+// streamHealthy fails when the rule stream of a started agent has been down
+// for longer than streamGrace
+func (c *controller) streamHealthy() error {
+	c.mu.Lock()
+	required := c.client != nil && c.config != ""
+	c.mu.Unlock()
+
+	if !required || c.streamConnected.Load() {
+		return nil
+	}
+
+	down := c.streamDownAt.Load()
+	if down == 0 {
+		return nil
+	}
+	if since := time.Since(time.Unix(0, down)); since > streamGrace {
+		return fmt.Errorf("disconnected for %s", since.Truncate(time.Second))
+	}
+	return nil
+}
+
+// statusLine summarizes the agent for the STATUS= line of systemd
+func (c *controller) statusLine(fsm *statusfsm.StatusFSM) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	phase := "Standalone"
+	if fsm != nil {
+		phase = fsm.Current().String()
+	}
+	if !c.attached {
+		return phase
+	}
+
+	stream := "none"
+	if c.client != nil && c.config != "" {
+		stream = "disconnected"
+		if c.streamConnected.Load() {
+			stream = "connected"
+		}
+	}
+
+	return fmt.Sprintf("%s, config %q, %d interfaces attached, rule stream %s", phase, c.config, len(c.attachIf), stream)
+}
+
+// trapShutdown stops the controller on SIGINT/SIGTERM and tells systemd we are stopping
+func trapShutdown(controller *controller) {
+	sig.TrapSignals(func() {
+		_ = notify.Stopping()
+
+		if err := controller.Stop(context.Background(), nil); err != nil {
+			log.Error("stop controller", log.ErrorField(err))
+			os.Exit(sig.ExitCodeFailedQuit)
+		}
+		os.Exit(sig.ExitCodeSuccess)
+	})
+}
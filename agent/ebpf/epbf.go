@@ -8,18 +8,27 @@ import (
 	"xdp-banner/agent/ebpf/xdp"
 )
 
-func Init() (*xdp.BannedIPXdpMap, *xdp.XdpProgManager, []string, error) {
+// AttachResult records the interfaces the program was attached to, and the
+// error of those it could not be attached to
+type AttachResult struct {
+	Attached []string
+	Failed   map[string]error
+}
+
+func Init() (*xdp.BannedIPXdpMap, *xdp.XdpProgManager, AttachResult, error) {
+	result := AttachResult{Failed: make(map[string]error)}
+
 	// 1. 初始化 XDP 封禁映射
 	mapInstance, err := xdp.NewBannedIPXdpMap()
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to create XDP map: %w", err)
+		return nil, nil, result, fmt.Errorf("failed to create XDP map: %w", err)
 	}
 
 	// 2. 初始化 XDP 程序管理器
 	progInstance, err := xdp.NewXdpProgManager()
 	if err != nil {
 		mapInstance.Close()
-		return nil, nil, nil, fmt.Errorf("failed to create XDP program manager: %w", err)
+		return nil, nil, result, fmt.Errorf("failed to create XDP program manager: %w", err)
 	}
 
 	// 3. 获取并过滤网络接口
@@ -27,35 +36,36 @@ func Init() (*xdp.BannedIPXdpMap, *xdp.XdpProgManager, []string, error) {
 	if err != nil {
 		mapInstance.Close()
 		progInstance.Close()
-		return nil, nil, nil, fmt.Errorf("failed to get network interfaces: %w", err)
+		return nil, nil, result, fmt.Errorf("failed to get network interfaces: %w", err)
 	}
 
 	if len(interfaces) == 0 {
 		mapInstance.Close()
 		progInstance.Close()
-		return nil, nil, nil, fmt.Errorf("no suitable network interfaces found")
+		return nil, nil, result, fmt.Errorf("no suitable network interfaces found")
 	}
 
 	// 4. 在所有接口上挂载XDP程序
-	attachedInterfaces := make([]string, 0, len(interfaces))
+	result.Attached = make([]string, 0, len(interfaces))
 	for _, iface := range interfaces {
 		// Use Generic Mode
 		if err := progInstance.Attach(iface.Name, 2); err != nil {
 			log.Printf("Failed to attach to interface %s: %v", iface.Name, err)
+			result.Failed[iface.Name] = err
 			continue
 		}
-		attachedInterfaces = append(attachedInterfaces, iface.Name)
+		result.Attached = append(result.Attached, iface.Name)
 	}
 
-	if len(attachedInterfaces) == 0 {
+	if len(result.Attached) == 0 {
 		mapInstance.Close()
 		progInstance.Close()
-		return nil, nil, nil, fmt.Errorf("failed to attach to any interface")
+		return nil, nil, result, fmt.Errorf("failed to attach to any interface")
 	}
 
-	log.Printf("Successfully attached to interfaces: %v", attachedInterfaces)
+	log.Printf("Successfully attached to interfaces: %v", result.Attached)
 
-	return mapInstance, progInstance, attachedInterfaces, nil
+	return mapInstance, progInstance, result, nil
 }
 
 func getAttachableInterfaces() ([]net.Interface, error) {
@@ -0,0 +1,174 @@
+package xdp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"xdp-banner/agent/ebpf/xdp/types"
+
+	"github.com/cilium/ebpf"
+)
+
+// IdentityEntry is one decoded identity_ipcache entry
+type IdentityEntry struct {
+	CIDR     string `json:"cidr"`
+	Identity uint32 `json:"identity"`
+}
+
+// BanEntry is one decoded xdp_banner_banlist entry, ports are in host order
+type BanEntry struct {
+	PrefixLen             uint32 `json:"prefixlen"`
+	Protocol              string `json:"protocol"`
+	Identity              uint32 `json:"identity"`
+	Sport                 uint16 `json:"sport"`
+	Dport                 uint16 `json:"dport"`
+	LatestAccessTimestamp uint64 `json:"latest_access_timestamp"`
+	RefuseTimes           uint64 `json:"refuse_times"`
+}
+
+// MapDump is the live content of the datapath maps, Rules joins both maps
+// back into the IPRule form they were written from
+type MapDump struct {
+	Identities []IdentityEntry `json:"identities"`
+	Bans       []BanEntry      `json:"bans"`
+	Rules      []IPRule        `json:"rules"`
+}
+
+// Counters is pkg_count_metrics summed over all cpus
+type Counters struct {
+	Pass uint64 `json:"pass"`
+	Drop uint64 `json:"drop"`
+}
+
+// Dump 遍历 identity_ipcache 与 xdp_banner_banlist, 并解码回 CIDR/协议/端口
+func (b *BannedIPXdpMap) Dump() (*MapDump, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	dump := &MapDump{}
+	cidrs := make(map[uint32][]string)
+
+	var (
+		ipKey xdpIpcacheKey
+		info  xdpIdentityInfo
+	)
+	iter1 := b.maps.IdentityIpcache.Iterate()
+	for iter1.Next(&ipKey, &info) {
+		cidr, err := ipKey.cidr()
+		if err != nil {
+			return nil, err
+		}
+		dump.Identities = append(dump.Identities, IdentityEntry{CIDR: cidr, Identity: info.Identity})
+		cidrs[info.Identity] = append(cidrs[info.Identity], cidr)
+	}
+	if err := iter1.Err(); err != nil {
+		return nil, fmt.Errorf("iterate identity_ipcache failed: %w", err)
+	}
+
+	var (
+		banKey xdpBanruleKey
+		banVal xdpBanruleVal
+	)
+	iter2 := b.maps.XdpBannerBanlist.Iterate()
+	for iter2.Next(&banKey, &banVal) {
+		entry := BanEntry{
+			PrefixLen:             banKey.Prefixlen,
+			Protocol:              ProtocolName(banKey.Protocol),
+			Identity:              banKey.Identity,
+			Sport:                 htons(banKey.Sport),
+			Dport:                 htons(banKey.Dport),
+			LatestAccessTimestamp: banVal.LatestAccessTimestamp,
+			RefuseTimes:           banVal.RefuseTimes,
+		}
+		dump.Bans = append(dump.Bans, entry)
+
+		for _, cidr := range cidrs[banKey.Identity] {
+			dump.Rules = append(dump.Rules, IPRule{
+				CIDR:           cidr,
+				Identity:       strconv.FormatUint(uint64(banKey.Identity), 10),
+				BannedProtocol: banKey.Protocol,
+				Sport:          entry.Sport,
+				Dport:          entry.Dport,
+			})
+		}
+	}
+	if err := iter2.Err(); err != nil {
+		return nil, fmt.Errorf("iterate xdp_banner_banlist failed: %w", err)
+	}
+
+	return dump, nil
+}
+
+// MapUsage is the number of entries of a map against its capacity
+type MapUsage struct {
+	Name       string `json:"name"`
+	Entries    uint32 `json:"entries"`
+	MaxEntries uint32 `json:"max_entries"`
+}
+
+// Usage 统计 identity_ipcache 与 xdp_banner_banlist 的条目数
+func (b *BannedIPXdpMap) Usage() ([]MapUsage, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	maps := []struct {
+		name string
+		m    *ebpf.Map
+	}{
+		{"identity_ipcache", b.maps.IdentityIpcache},
+		{"xdp_banner_banlist", b.maps.XdpBannerBanlist},
+	}
+
+	usage := make([]MapUsage, 0, len(maps))
+	for _, bm := range maps {
+		m := bm.m
+		var entries uint32
+		key := make([]byte, m.KeySize())
+		val := make([]byte, m.ValueSize())
+		iter := m.Iterate()
+		for iter.Next(&key, &val) {
+			entries++
+		}
+		if err := iter.Err(); err != nil {
+			return nil, fmt.Errorf("iterate %s failed: %w", bm.name, err)
+		}
+
+		usage = append(usage, MapUsage{Name: bm.name, Entries: entries, MaxEntries: m.MaxEntries()})
+	}
+
+	return usage, nil
+}
+
+// Counters 读取 pkg_count_metrics, index 0 为放行, 1 为丢弃
+func (b *BannedIPXdpMap) Counters() (Counters, error) {
+	var counters Counters
+
+	for i, dst := range []*uint64{&counters.Pass, &counters.Drop} {
+		var perCPU []uint64
+		if err := b.maps.PkgCountMetrics.Lookup(uint32(i), &perCPU); err != nil {
+			return counters, fmt.Errorf("lookup pkg_count_metrics[%d] failed: %w", i, err)
+		}
+		for _, v := range perCPU {
+			*dst += v
+		}
+	}
+
+	return counters, nil
+}
+
+// cidr 把 LPM key 还原为 CIDR 字符串
+func (k xdpIpcacheKey) cidr() (string, error) {
+	ones := int(k.Prefixlen) - IPCACHE_STATIC_PREFIX_BITS
+
+	switch k.Family {
+	case types.AF_INET:
+		ipNet := net.IPNet{IP: net.IP(k.IP[:4]), Mask: net.CIDRMask(ones, 32)}
+		return ipNet.String(), nil
+	case types.AF_INET6:
+		ipNet := net.IPNet{IP: net.IP(k.IP[:]), Mask: net.CIDRMask(ones, 128)}
+		return ipNet.String(), nil
+	default:
+		return "", fmt.Errorf("unknown family %d in identity_ipcache", k.Family)
+	}
+}
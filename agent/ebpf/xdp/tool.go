@@ -43,16 +43,9 @@ func ParseIPRuleKey(ruleKey string) (cidr string, proto uint8, sport, dport uint
 	cidr = ipPart + "/" + maskPart // "192.168.0.1/24"
 
 	// 3. 协议
-	protoStr := parts[6] // "6"
-	switch protoStr {
-	case "TCP", "tcp":
-		proto = types.IPPROTO_TCP
-	case "UDP", "udp":
-		proto = types.IPPROTO_UDP
-	case "ICMP", "icmp":
-		proto = types.IPPROTO_ICMP
-	default:
-		return "", 0, 0, 0, fmt.Errorf("无法解析协议号 %q", protoStr)
+	proto, err = ParseProtocol(parts[6])
+	if err != nil {
+		return "", 0, 0, 0, err
 	}
 
 	// 4. 端口范围
@@ -75,6 +68,34 @@ func ParseIPRuleKey(ruleKey string) (cidr string, proto uint8, sport, dport uint
 	return cidr, proto, sport, dport, nil
 }
 
+// ParseProtocol 把协议名 (TCP/UDP/ICMP) 转换为 IPPROTO 协议号
+func ParseProtocol(name string) (uint8, error) {
+	switch name {
+	case "TCP", "tcp":
+		return types.IPPROTO_TCP, nil
+	case "UDP", "udp":
+		return types.IPPROTO_UDP, nil
+	case "ICMP", "icmp":
+		return types.IPPROTO_ICMP, nil
+	default:
+		return 0, fmt.Errorf("无法解析协议号 %q", name)
+	}
+}
+
+// ProtocolName 把 IPPROTO 协议号转换为协议名, 未知协议返回协议号
+func ProtocolName(proto uint8) string {
+	switch proto {
+	case types.IPPROTO_TCP:
+		return "TCP"
+	case types.IPPROTO_UDP:
+		return "UDP"
+	case types.IPPROTO_ICMP:
+		return "ICMP"
+	default:
+		return strconv.Itoa(int(proto))
+	}
+}
+
 // WaitForInterrupt 等待中断信号
 func (b *BannedIPXdpMap) WaitForInterrupt() {
 	sig := make(chan os.Signal, 1)
@@ -1,6 +1,7 @@
 package xdp
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -21,11 +22,11 @@ func htons(v uint16) uint16 {
 }
 
 type IPRule struct {
-	CIDR           string
-	Identity       string
-	BannedProtocol uint8 // IPPROTO_ICMP：1 IPPROTO_TCP：6 IPPROTO_UDP：17
-	Sport          uint16
-	Dport          uint16
+	CIDR           string `json:"cidr"`
+	Identity       string `json:"identity"`
+	BannedProtocol uint8  `json:"protocol"` // IPPROTO_ICMP：1 IPPROTO_TCP：6 IPPROTO_UDP：17
+	Sport          uint16 `json:"sport"`
+	Dport          uint16 `json:"dport"`
 }
 
 // BannedIPXdpMap 主结构体
@@ -107,17 +108,85 @@ func (b *BannedIPXdpMap) AddCIDRRule(rule IPRule) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	// 1) parse CIDR
-	ipAddr, ipNet, err := net.ParseCIDR(rule.CIDR)
+	// 1) parse CIDR, 构造 ipcache_key
+	ipKey, err := newIpcacheKey(rule.CIDR)
 	if err != nil {
-		return fmt.Errorf("invalid CIDR %q: %w", rule.CIDR, err)
+		return err
 	}
-	ones, _ := ipNet.Mask.Size()
-	prefixLen := uint32(ones)
 
-	// 2) 构造 ipcache_key
+	// 3) 解析 identity
+	idVal, err := strconv.ParseUint(rule.Identity, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid identity %q: %w", rule.Identity, err)
+	}
+	identInfo := xdpIdentityInfo{Identity: uint32(idVal)}
+
+	// 4) 更新 identity map
+	if err := b.maps.IdentityIpcache.Update(ipKey, identInfo, ebpf.UpdateAny); err != nil {
+		return fmt.Errorf("update identity_ipcache failed: %w", err)
+	}
+
+	// 5) 构造 banrule_key
+	banKey := newBanruleKey(rule, identInfo.Identity)
+
+	// 6) 更新 banlist map
+	var banVal xdpBanruleVal
+	if err := b.maps.XdpBannerBanlist.Update(banKey, banVal, ebpf.UpdateAny); err != nil {
+		return fmt.Errorf("update xdp_banner_banlist failed: %w", err)
+	}
+
+	return nil
+}
+
+// SetCIDRIdentity 只更新 identity_ipcache 中 CIDR 对应的 identity, 不修改 banlist
+func (b *BannedIPXdpMap) SetCIDRIdentity(cidr string, identity string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ipKey, err := newIpcacheKey(cidr)
+	if err != nil {
+		return err
+	}
+
+	idVal, err := strconv.ParseUint(identity, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid identity %q: %w", identity, err)
+	}
+
+	if err := b.maps.IdentityIpcache.Update(ipKey, xdpIdentityInfo{Identity: uint32(idVal)}, ebpf.UpdateAny); err != nil {
+		return fmt.Errorf("update identity_ipcache failed: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveCIDRIdentity 删除 identity_ipcache 中 CIDR 对应的条目, 条目不存在时不报错
+func (b *BannedIPXdpMap) RemoveCIDRIdentity(cidr string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ipKey, err := newIpcacheKey(cidr)
+	if err != nil {
+		return err
+	}
+
+	if err := b.maps.IdentityIpcache.Delete(ipKey); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return fmt.Errorf("delete identity_ipcache failed for %s: %w", cidr, err)
+	}
+
+	return nil
+}
+
+// newIpcacheKey 把 CIDR 转换为 identity_ipcache 的 LPM key
+func newIpcacheKey(cidr string) (xdpIpcacheKey, error) {
 	var ipKey xdpIpcacheKey
-	ipKey.Prefixlen = IPCACHE_STATIC_PREFIX_BITS + prefixLen
+
+	ipAddr, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return ipKey, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	ones, _ := ipNet.Mask.Size()
+	ipKey.Prefixlen = IPCACHE_STATIC_PREFIX_BITS + uint32(ones)
 
 	if ip4 := ipAddr.To4(); ip4 != nil {
 		ipKey.Family = types.AF_INET
@@ -127,28 +196,20 @@ func (b *BannedIPXdpMap) AddCIDRRule(rule IPRule) error {
 		// 比 ParseAddr(ip.String()) + FromAddr 更简洁：
 		ip16 := ipAddr.To16()
 		if ip16 == nil {
-			return fmt.Errorf("bad IPv6 %q", ipAddr)
+			return ipKey, fmt.Errorf("bad IPv6 %q", ipAddr)
 		}
 		// 假设 xdpIpcacheKey.IP 底层是 [16]byte
 		copy(ipKey.IP[:], ip16)
 	}
 
-	// 3) 解析 identity
-	idVal, err := strconv.ParseUint(rule.Identity, 10, 32)
-	if err != nil {
-		return fmt.Errorf("invalid identity %q: %w", rule.Identity, err)
-	}
-	identInfo := xdpIdentityInfo{Identity: uint32(idVal)}
-
-	// 4) 更新 identity map
-	if err := b.maps.IdentityIpcache.Update(ipKey, identInfo, ebpf.UpdateAny); err != nil {
-		return fmt.Errorf("update identity_ipcache failed: %w", err)
-	}
+	return ipKey, nil
+}
 
-	// 5) 构造 banrule_key
+// newBanruleKey 构造 rule 的 banrule_key, 端口按网络字节序写入
+func newBanruleKey(rule IPRule, identity uint32) xdpBanruleKey {
 	var banKey xdpBanruleKey
 	banKey.Protocol = rule.BannedProtocol
-	banKey.Identity = identInfo.Identity
+	banKey.Identity = identity
 	banKey.Sport = htons(rule.Sport)
 	banKey.Dport = htons(rule.Dport)
 
@@ -162,21 +223,14 @@ func (b *BannedIPXdpMap) AddCIDRRule(rule IPRule) error {
 		case rule.Sport != 0 && rule.Dport == 0:
 			banKey.Prefixlen = BANLIST_L4_SPORT
 		default:
-			// 4) 粗粒度 L3，只按 protocol+identity
+			// 粗粒度 L3，只按 protocol+identity
 			banKey.Prefixlen = BANLIST_L3_FULL
 		}
 	default:
 		// ICMP 之类用粗粒度 L3
 		banKey.Prefixlen = BANLIST_L3_FULL
 	}
-
-	// 6) 更新 banlist map
-	var banVal xdpBanruleVal
-	if err := b.maps.XdpBannerBanlist.Update(banKey, banVal, ebpf.UpdateAny); err != nil {
-		return fmt.Errorf("update xdp_banner_banlist failed: %w", err)
-	}
-
-	return nil
+	return banKey
 }
 
 // removeCIDRRule 只删除与给定 IPRule 完全匹配的那一条 banlist 规则
@@ -192,30 +246,7 @@ func (b *BannedIPXdpMap) RemoveCIDRRule(rule IPRule) error {
 	identity := uint32(idVal)
 
 	// 2. 构造要删的 banrule key
-	var banKey xdpBanruleKey
-	banKey.Identity = identity
-	banKey.Protocol = rule.BannedProtocol
-
-	banKey.Sport = htons(rule.Sport)
-	banKey.Dport = htons(rule.Dport)
-
-	switch rule.BannedProtocol {
-	case types.IPPROTO_TCP, types.IPPROTO_UDP:
-		switch {
-		case rule.Sport != 0 && rule.Dport != 0:
-			banKey.Prefixlen = BANLIST_L4_FULL
-		case rule.Sport == 0 && rule.Dport != 0:
-			banKey.Prefixlen = BANLIST_L4_DPORT
-		case rule.Sport != 0 && rule.Dport == 0:
-			banKey.Prefixlen = BANLIST_L4_SPORT
-		default:
-			// 4) 粗粒度 L3，只按 protocol+identity
-			banKey.Prefixlen = BANLIST_L3_FULL
-		}
-	default:
-		// ICMP 之类用粗粒度 L3
-		banKey.Prefixlen = BANLIST_L3_FULL
-	}
+	banKey := newBanruleKey(rule, identity)
 
 	// 3. 调用 eBPF map 的 Delete
 	if err := b.maps.XdpBannerBanlist.Delete(banKey); err != nil {
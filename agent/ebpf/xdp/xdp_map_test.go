@@ -0,0 +1,86 @@
+package xdp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"strconv"
+	"testing"
+
+	"xdp-banner/agent/ebpf/xdp/types"
+	"xdp-banner/pkg/datapath"
+)
+
+func marshal(t *testing.T, key any) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.NativeEndian, key); err != nil {
+		t.Fatalf("marshal %+v: %v", key, err)
+	}
+	return buf.Bytes()
+}
+
+// TestKeyEncoding checks the keys AddCIDRRule writes against the reference
+// matcher of pkg/datapath
+func TestKeyEncoding(t *testing.T) {
+	rules := []IPRule{
+		{CIDR: "10.0.0.0/8", Identity: "1", BannedProtocol: types.IPPROTO_TCP, Dport: 22},
+		{CIDR: "10.1.0.0/16", Identity: "2", BannedProtocol: types.IPPROTO_UDP, Sport: 53},
+		{CIDR: "10.1.0.0/16", Identity: "2", BannedProtocol: types.IPPROTO_TCP, Sport: 1234, Dport: 443},
+		{CIDR: "2001:db8::/32", Identity: "3", BannedProtocol: types.IPPROTO_ICMP, Sport: 8},
+		{CIDR: "2001:db8::/32", Identity: "3", BannedProtocol: types.IPPROTO_TCP},
+	}
+
+	maps := datapath.NewMaps()
+	for _, r := range rules {
+		ipKey, err := newIpcacheKey(r.CIDR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := strconv.ParseUint(r.Identity, 10, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		identity := uint32(id)
+		banKey := newBanruleKey(r, identity)
+
+		ipBytes, banBytes := marshal(t, ipKey), marshal(t, banKey)
+		// 不经过 NewEntry, ICMP 规则的端口也原样写入 key, 只是不在前缀内
+		entry := datapath.Entry{Protocol: r.BannedProtocol, Sport: r.Sport, Dport: r.Dport}
+		if want := datapath.IpcacheKey(netip.MustParsePrefix(r.CIDR)); !bytes.Equal(ipBytes, want) {
+			t.Errorf("ipcache key of %s = %x, want %x", r.CIDR, ipBytes, want)
+		}
+		if want := datapath.BanlistKey(identity, entry); !bytes.Equal(banBytes, want) {
+			t.Errorf("banlist key of %+v = %x, want %x", r, banBytes, want)
+		}
+
+		if err := maps.UpdateIpcache(ipBytes, identity); err != nil {
+			t.Fatal(err)
+		}
+		if err := maps.UpdateBanlist(banBytes); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		packet datapath.Packet
+		drop   bool
+	}{
+		{datapath.Packet{Src: netip.MustParseAddr("10.2.0.1"), Protocol: types.IPPROTO_TCP, Sport: 40000, Dport: 22}, true},
+		{datapath.Packet{Src: netip.MustParseAddr("10.2.0.1"), Protocol: types.IPPROTO_TCP, Sport: 40000, Dport: 80}, false},
+		// 10.1.0.0/16 有自己的 identity, 10.0.0.0/8 的规则不再生效
+		{datapath.Packet{Src: netip.MustParseAddr("10.1.0.1"), Protocol: types.IPPROTO_TCP, Sport: 40000, Dport: 22}, false},
+		{datapath.Packet{Src: netip.MustParseAddr("10.1.0.1"), Protocol: types.IPPROTO_UDP, Sport: 53, Dport: 40000}, true},
+		{datapath.Packet{Src: netip.MustParseAddr("10.1.0.1"), Protocol: types.IPPROTO_TCP, Sport: 1234, Dport: 443}, true},
+		{datapath.Packet{Src: netip.MustParseAddr("10.1.0.1"), Protocol: types.IPPROTO_TCP, Sport: 1235, Dport: 443}, false},
+		// IPv6 的 ICMP 报文按 ICMPv6 查找, 不匹配协议号 1 的规则
+		{datapath.Packet{Src: netip.MustParseAddr("2001:db8::1"), Protocol: datapath.ProtoICMPv6}, false},
+		{datapath.Packet{Src: netip.MustParseAddr("2001:db8::1"), Protocol: types.IPPROTO_TCP, Sport: 1, Dport: 2}, true},
+		{datapath.Packet{Src: netip.MustParseAddr("192.0.2.1"), Protocol: types.IPPROTO_TCP, Dport: 22}, false},
+	}
+	for _, tt := range tests {
+		if v := maps.Decide(tt.packet); v.Drop != tt.drop {
+			t.Errorf("Decide(%+v) = %+v, want drop %v", tt.packet, v, tt.drop)
+		}
+	}
+}
@@ -13,10 +13,20 @@ import (
 
 type XdpProgManager struct {
 	program *ebpf.Program
-	links   map[string]link.Link // 按接口名存储多个链接
+	links   map[string]link.Link           // 按接口名存储多个链接
+	flags   map[string]link.XDPAttachFlags // 按接口名记录挂载模式
 	mu      sync.Mutex
 }
 
+// InterfaceState is the attach state of one network interface
+type InterfaceState struct {
+	Name     string `json:"name"`
+	Index    int    `json:"index"`
+	Up       bool   `json:"up"`
+	Attached bool   `json:"attached"`
+	Mode     string `json:"mode,omitempty"`
+}
+
 func NewXdpProgManager() (*XdpProgManager, error) {
 	// 确保 pin 目录存在
 	pinDir := filepath.Join("/sys/fs/bpf", "xdp_banner")
@@ -50,6 +60,7 @@ func NewXdpProgManager() (*XdpProgManager, error) {
 	return &XdpProgManager{
 		program: objs.CilXdpEntry,
 		links:   make(map[string]link.Link),
+		flags:   make(map[string]link.XDPAttachFlags),
 	}, nil
 }
 
@@ -88,6 +99,7 @@ func (m *XdpProgManager) Attach(ifaceName string, flags link.XDPAttachFlags) err
 	}
 
 	m.links[ifaceName] = l
+	m.flags[ifaceName] = flags
 	return nil
 }
 
@@ -97,11 +109,52 @@ func (m *XdpProgManager) Detach(ifaceName string) error {
 
 	if l, ok := m.links[ifaceName]; ok {
 		delete(m.links, ifaceName)
+		delete(m.flags, ifaceName)
 		return l.Close()
 	}
 	return nil
 }
 
+// InterfaceStates 列出本机所有网络接口及 XDP 挂载状态
+func (m *XdpProgManager) InterfaceStates() ([]InterfaceState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]InterfaceState, 0, len(interfaces))
+	for _, iface := range interfaces {
+		state := InterfaceState{
+			Name:  iface.Name,
+			Index: iface.Index,
+			Up:    iface.Flags&net.FlagUp != 0,
+		}
+		if _, ok := m.links[iface.Name]; ok {
+			state.Attached = true
+			state.Mode = attachModeName(m.flags[iface.Name])
+		}
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+func attachModeName(flags link.XDPAttachFlags) string {
+	switch flags {
+	case link.XDPGenericMode:
+		return "generic"
+	case link.XDPDriverMode:
+		return "driver"
+	case link.XDPOffloadMode:
+		return "offload"
+	default:
+		return "default"
+	}
+}
+
 // Close 释放所有资源
 func (m *XdpProgManager) Close() error {
 	// 先分离程序
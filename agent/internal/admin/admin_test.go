@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"xdp-banner/agent/ebpf/xdp"
+	"xdp-banner/agent/internal/ruleset"
+	"xdp-banner/pkg/rule"
+)
+
+type fakeBackend struct {
+	banned   ruleset.StaticRule
+	ttl      time.Duration
+	resynced bool
+}
+
+func (f *fakeBackend) Dump() (*xdp.MapDump, error) {
+	return &xdp.MapDump{Rules: []xdp.IPRule{{CIDR: "192.0.2.0/24", Identity: "1", BannedProtocol: 6, Dport: 22}}}, nil
+}
+
+func (f *fakeBackend) Interfaces() ([]xdp.InterfaceState, error) {
+	return []xdp.InterfaceState{{Name: "eth0", Index: 2, Up: true, Attached: true, Mode: "generic"}}, nil
+}
+
+func (f *fakeBackend) Counters() (xdp.Counters, error) {
+	return xdp.Counters{Pass: 10, Drop: 2}, nil
+}
+
+func (f *fakeBackend) Ban(r ruleset.StaticRule, ttl time.Duration) error {
+	f.banned, f.ttl = r, ttl
+	return nil
+}
+
+func (f *fakeBackend) TempRules() ([]ruleset.TempRule, error) {
+	return nil, nil
+}
+
+func (f *fakeBackend) Resync() error {
+	f.resynced = true
+	return nil
+}
+
+func TestAdminSocket(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "admin.sock") + "|0600"
+	backend := &fakeBackend{}
+
+	srv, err := NewServer(addr, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	defer srv.Close()
+
+	cli, err := NewClient(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dump, err := cli.Maps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dump.Rules) != 1 || dump.Rules[0].Dport != 22 {
+		t.Fatalf("unexpected dump %+v", dump)
+	}
+
+	counters, err := cli.Counters()
+	if err != nil || counters.Drop != 2 {
+		t.Fatalf("unexpected counters %+v %v", counters, err)
+	}
+
+	err = cli.Ban(ruleset.StaticRule{RuleInfo: rule.RuleInfo{Cidr: "203.0.113.1/32", Protocol: "TCP", Duration: "1d"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backend.banned.Cidr != "203.0.113.1/32" || backend.ttl != 24*time.Hour {
+		t.Fatalf("unexpected ban %+v %s", backend.banned, backend.ttl)
+	}
+
+	if err := cli.Ban(ruleset.StaticRule{RuleInfo: rule.RuleInfo{Cidr: "203.0.113.1/32", Duration: "soon"}}); err == nil {
+		t.Fatal("invalid duration should be rejected")
+	}
+
+	if err := cli.Resync(); err != nil || !backend.resynced {
+		t.Fatalf("resync not called: %v", err)
+	}
+}
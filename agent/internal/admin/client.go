@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"xdp-banner/agent/ebpf/xdp"
+	"xdp-banner/agent/internal"
+	"xdp-banner/agent/internal/ruleset"
+)
+
+// Client talks to the admin socket of a local agent
+type Client struct {
+	http *http.Client
+}
+
+// NewClient accepts the same address format as NewServer, permission bits are ignored
+func NewClient(addr string) (*Client, error) {
+	path, _, err := internal.SplitUnixSocketPermissionsBits(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := net.Dialer{}
+	return &Client{
+		http: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", path)
+				},
+			},
+		},
+	}, nil
+}
+
+func (c *Client) Maps() (*xdp.MapDump, error) {
+	dump := &xdp.MapDump{}
+	return dump, c.do(http.MethodGet, PathMaps, nil, dump)
+}
+
+func (c *Client) Interfaces() ([]xdp.InterfaceState, error) {
+	var states []xdp.InterfaceState
+	return states, c.do(http.MethodGet, PathInterfaces, nil, &states)
+}
+
+func (c *Client) Counters() (xdp.Counters, error) {
+	var counters xdp.Counters
+	return counters, c.do(http.MethodGet, PathCounters, nil, &counters)
+}
+
+func (c *Client) TempRules() ([]ruleset.TempRule, error) {
+	var rules []ruleset.TempRule
+	return rules, c.do(http.MethodGet, PathBan, nil, &rules)
+}
+
+func (c *Client) Ban(rule ruleset.StaticRule) error {
+	return c.do(http.MethodPost, PathBan, rule, nil)
+}
+
+func (c *Client) Resync() error {
+	return c.do(http.MethodPost, PathResync, nil, nil)
+}
+
+func (c *Client) do(method string, path string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	// host 部分不会被使用, 连接总是走 unix socket
+	req, err := http.NewRequest(method, "http://agent"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("request admin socket: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errResp := ErrorResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == "" {
+			return fmt.Errorf("admin socket returned %s", resp.Status)
+		}
+		return fmt.Errorf("%s", errResp.Error)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,172 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"xdp-banner/agent/ebpf/xdp"
+	"xdp-banner/agent/internal"
+	"xdp-banner/agent/internal/ruleset"
+	"xdp-banner/pkg/log"
+	"xdp-banner/pkg/rule"
+)
+
+const (
+	PathMaps       = "/maps"
+	PathInterfaces = "/interfaces"
+	PathCounters   = "/counters"
+	PathBan        = "/ban"
+	PathResync     = "/resync"
+)
+
+// Backend is what the admin socket exposes, implemented by the agent controller
+type Backend interface {
+	Dump() (*xdp.MapDump, error)
+	Interfaces() ([]xdp.InterfaceState, error)
+	Counters() (xdp.Counters, error)
+	Ban(rule ruleset.StaticRule, ttl time.Duration) error
+	TempRules() ([]ruleset.TempRule, error)
+	Resync() error
+}
+
+// DefaultBanDuration is used when POST /ban does not set a duration, the
+// same default as rules added through the orchestrator
+const DefaultBanDuration = "300s"
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server serves the admin api on a local unix socket
+type Server struct {
+	path     string
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewServer listens on addr, addr is a unix socket path optionally followed
+// by octal permission bits, e.g. "/run/xdp-banner/admin.sock|0600"
+func NewServer(addr string, backend Backend) (*Server, error) {
+	path, mode, err := internal.SplitUnixSocketPermissionsBits(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create admin socket dir: %w", err)
+	}
+	// 清理上次未正常退出残留的 socket 文件
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("remove stale admin socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen admin socket: %w", err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("chmod admin socket: %w", err)
+	}
+
+	return &Server{
+		path:     path,
+		listener: listener,
+		server:   &http.Server{Handler: newHandler(backend)},
+	}, nil
+}
+
+func (s *Server) Serve() error {
+	log.Info("admin socket listening", log.StringField("path", s.path))
+
+	if err := s.server.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.server.Shutdown(ctx)
+}
+
+func newHandler(backend Backend) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET "+PathMaps, func(w http.ResponseWriter, r *http.Request) {
+		dump, err := backend.Dump()
+		writeResponse(w, dump, err)
+	})
+
+	mux.HandleFunc("GET "+PathInterfaces, func(w http.ResponseWriter, r *http.Request) {
+		states, err := backend.Interfaces()
+		writeResponse(w, states, err)
+	})
+
+	mux.HandleFunc("GET "+PathCounters, func(w http.ResponseWriter, r *http.Request) {
+		counters, err := backend.Counters()
+		writeResponse(w, counters, err)
+	})
+
+	mux.HandleFunc("GET "+PathBan, func(w http.ResponseWriter, r *http.Request) {
+		rules, err := backend.TempRules()
+		writeResponse(w, rules, err)
+	})
+
+	mux.HandleFunc("POST "+PathBan, func(w http.ResponseWriter, r *http.Request) {
+		// body 与 rule dto 一致, duration 即临时封禁的时长
+		req := ruleset.StaticRule{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if req.Duration == "" {
+			req.Duration = DefaultBanDuration
+		}
+
+		ttl, err := rule.ParseDuration(req.Duration)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid duration %q: %w", req.Duration, err))
+			return
+		}
+
+		if err := backend.Ban(req, ttl); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeResponse(w, req, nil)
+	})
+
+	mux.HandleFunc("POST "+PathResync, func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, struct{}{}, backend.Resync())
+	})
+
+	return mux
+}
+
+func writeResponse(w http.ResponseWriter, body any, err error) {
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Warn("write admin response", log.ErrorField(err))
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+}
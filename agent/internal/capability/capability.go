@@ -0,0 +1,181 @@
+package capability
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+	"xdp-banner/api/orch/v1/agent/report"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/features"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// nativeXDPDrivers are the drivers with native (driver mode) XDP support in
+// the mainline kernel
+var nativeXDPDrivers = map[string]bool{
+	"bnxt_en":     true,
+	"dpaa2-eth":   true,
+	"ena":         true,
+	"enetc":       true,
+	"fec":         true,
+	"gve":         true,
+	"hv_netvsc":   true,
+	"i40e":        true,
+	"ice":         true,
+	"igb":         true,
+	"igc":         true,
+	"ixgbe":       true,
+	"ixgbevf":     true,
+	"mlx4_en":     true,
+	"mlx5_core":   true,
+	"mvneta":      true,
+	"mvpp2":       true,
+	"nfp":         true,
+	"qede":        true,
+	"sfc":         true,
+	"stmmac":      true,
+	"thunder-nic": true,
+	"tun":         true,
+	"veth":        true,
+	"virtio_net":  true,
+}
+
+// Capabilities is what the agent can tell about its host
+type Capabilities struct {
+	KernelVersion string
+	Arch          string
+	CPUCount      int
+	BTF           bool
+	BPFFeatures   map[string]bool
+	NICs          []NIC
+	ProbedAt      time.Time
+}
+
+type NIC struct {
+	Name      string
+	Driver    string
+	RxQueues  uint32
+	TxQueues  uint32
+	XDPNative bool
+	Up        bool
+}
+
+// bpfFeatures are probed by loading a minimal object of each kind
+var bpfFeatures = map[string]func() error{
+	"prog_type/xdp":         func() error { return features.HaveProgramType(ebpf.XDP) },
+	"map_type/lpm_trie":     func() error { return features.HaveMapType(ebpf.LPMTrie) },
+	"map_type/percpu_array": func() error { return features.HaveMapType(ebpf.PerCPUArray) },
+	"map_type/lru_hash":     func() error { return features.HaveMapType(ebpf.LRUHash) },
+	"bounded_loops":         features.HaveBoundedLoops,
+	"large_instructions":    features.HaveLargeInstructions,
+}
+
+// Probe inspects the running host
+func Probe() Capabilities {
+	c := probeFS("/")
+	c.CPUCount = runtime.NumCPU()
+	c.Arch = runtime.GOARCH
+
+	c.BPFFeatures = make(map[string]bool, len(bpfFeatures))
+	for name, probe := range bpfFeatures {
+		c.BPFFeatures[name] = probe() == nil
+	}
+
+	return c
+}
+
+// probeFS reads everything that comes from procfs and sysfs under root
+func probeFS(root string) Capabilities {
+	c := Capabilities{ProbedAt: time.Now()}
+
+	if release, err := os.ReadFile(filepath.Join(root, "proc/sys/kernel/osrelease")); err == nil {
+		c.KernelVersion = strings.TrimSpace(string(release))
+	}
+	if _, err := os.Stat(filepath.Join(root, "sys/kernel/btf/vmlinux")); err == nil {
+		c.BTF = true
+	}
+
+	netDir := filepath.Join(root, "sys/class/net")
+	entries, err := os.ReadDir(netDir)
+	if err != nil {
+		return c
+	}
+	for _, e := range entries {
+		dir := filepath.Join(netDir, e.Name())
+		// 只关心物理网卡和常见虚拟网卡, 没有 device 的 (lo, bridge 等) 跳过
+		// veth 和 tun 没有 device, 通过 uevent 里的 DEVTYPE 识别
+		nic := NIC{Name: e.Name(), Driver: driver(dir)}
+		if nic.Driver == "" {
+			continue
+		}
+		nic.RxQueues, nic.TxQueues = queues(dir)
+		nic.XDPNative = nativeXDPDrivers[nic.Driver]
+		if state, err := os.ReadFile(filepath.Join(dir, "operstate")); err == nil {
+			nic.Up = strings.TrimSpace(string(state)) == "up"
+		}
+		c.NICs = append(c.NICs, nic)
+	}
+	sort.Slice(c.NICs, func(i, j int) bool { return c.NICs[i].Name < c.NICs[j].Name })
+
+	return c
+}
+
+// driver returns the driver name of the interface, empty when it has none
+func driver(dir string) string {
+	if link, err := os.Readlink(filepath.Join(dir, "device/driver")); err == nil {
+		return filepath.Base(link)
+	}
+
+	uevent, err := os.ReadFile(filepath.Join(dir, "uevent"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(uevent), "\n") {
+		if devtype, ok := strings.CutPrefix(line, "DEVTYPE="); ok && (devtype == "veth" || devtype == "tun") {
+			return devtype
+		}
+	}
+	return ""
+}
+
+func queues(dir string) (rx, tx uint32) {
+	entries, err := os.ReadDir(filepath.Join(dir, "queues"))
+	if err != nil {
+		return 0, 0
+	}
+	for _, e := range entries {
+		switch {
+		case strings.HasPrefix(e.Name(), "rx-"):
+			rx++
+		case strings.HasPrefix(e.Name(), "tx-"):
+			tx++
+		}
+	}
+	return rx, tx
+}
+
+func (c Capabilities) ToProto() *report.HostCapabilities {
+	p := &report.HostCapabilities{
+		KernelVersion: c.KernelVersion,
+		Arch:          c.Arch,
+		CpuCount:      int32(c.CPUCount),
+		Btf:           c.BTF,
+		BpfFeatures:   c.BPFFeatures,
+		ProbedAt:      timestamppb.New(c.ProbedAt),
+	}
+	for _, n := range c.NICs {
+		p.Nics = append(p.Nics, &report.NicCapability{
+			Name:      n.Name,
+			Driver:    n.Driver,
+			RxQueues:  n.RxQueues,
+			TxQueues:  n.TxQueues,
+			XdpNative: n.XDPNative,
+			Up:        n.Up,
+		})
+	}
+	return p
+}
@@ -0,0 +1,60 @@
+package capability
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProbeFS(t *testing.T) {
+	root := t.TempDir()
+	write := func(path, data string) {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("proc/sys/kernel/osrelease", "5.15.0-91-generic\n")
+	write("sys/kernel/btf/vmlinux", "")
+
+	// physical nic bound to a driver
+	write("sys/bus/pci/drivers/ixgbe/.keep", "")
+	write("sys/class/net/eth0/operstate", "up\n")
+	write("sys/class/net/eth0/queues/rx-0/.keep", "")
+	write("sys/class/net/eth0/queues/rx-1/.keep", "")
+	write("sys/class/net/eth0/queues/tx-0/.keep", "")
+	if err := os.MkdirAll(filepath.Join(root, "sys/class/net/eth0/device"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../../bus/pci/drivers/ixgbe", filepath.Join(root, "sys/class/net/eth0/device/driver")); err != nil {
+		t.Fatal(err)
+	}
+
+	// veth has no device, identified by uevent
+	write("sys/class/net/veth0/uevent", "DEVTYPE=veth\nINTERFACE=veth0\n")
+	write("sys/class/net/veth0/operstate", "down\n")
+
+	// loopback is skipped
+	write("sys/class/net/lo/operstate", "unknown\n")
+
+	c := probeFS(root)
+	if c.KernelVersion != "5.15.0-91-generic" || !c.BTF {
+		t.Fatalf("unexpected kernel info %q btf=%v", c.KernelVersion, c.BTF)
+	}
+	if len(c.NICs) != 2 {
+		t.Fatalf("expected 2 nics, got %+v", c.NICs)
+	}
+
+	eth0 := c.NICs[0]
+	if eth0.Name != "eth0" || eth0.Driver != "ixgbe" || eth0.RxQueues != 2 || eth0.TxQueues != 1 || !eth0.XDPNative || !eth0.Up {
+		t.Fatalf("unexpected eth0 %+v", eth0)
+	}
+	veth0 := c.NICs[1]
+	if veth0.Driver != "veth" || !veth0.XDPNative || veth0.Up {
+		t.Fatalf("unexpected veth0 %+v", veth0)
+	}
+}
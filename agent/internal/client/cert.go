@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"time"
+	"xdp-banner/agent/internal/icert"
+	"xdp-banner/pkg/log"
+	"xdp-banner/pkg/node"
+)
+
+const (
+	certRenewTimeout = 30 * time.Second
+	certRetryMin     = time.Minute
+	certRetryMax     = time.Hour
+)
+
+// RotateCertificate renews the agent certificate through the orchestrator once
+// two thirds of its lifetime have passed, until ctx is done. Failures are
+// retried with backoff, the current certificate keeps being used meanwhile.
+func RotateCertificate(ctx context.Context, c Client, keyPair *icert.KeyPair) {
+	retry := certRetryMin
+
+	for {
+		wait := time.Until(keyPair.RenewAt())
+		log.Info("next certificate renewal", log.StringField("not_after", keyPair.Leaf().NotAfter.String()), log.DurationField("in", wait))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if err := renewCertificate(ctx, c, keyPair); err != nil {
+			log.Error("renew certificate failed", log.ErrorField(err), log.DurationField("retry", retry))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retry):
+			}
+			retry = min(retry*2, certRetryMax)
+			continue
+		}
+
+		retry = certRetryMin
+		log.Info("certificate renewed", log.StringField("not_after", keyPair.Leaf().NotAfter.String()))
+	}
+}
+
+func renewCertificate(ctx context.Context, c Client, keyPair *icert.KeyPair) error {
+	ip, err := node.DefaultIP()
+	if err != nil {
+		return err
+	}
+
+	return keyPair.Renew(func(pubPem []byte) ([]byte, []byte, error) {
+		ctx, cancel := context.WithTimeout(ctx, certRenewTimeout)
+		defer cancel()
+
+		return c.RenewCertificate(ctx, pubPem, []string{ip.String()})
+	})
+}
@@ -2,45 +2,74 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"xdp-banner/api/orch/v1/agent/control"
 	"xdp-banner/api/orch/v1/agent/report"
+	"xdp-banner/api/orch/v1/protect"
 	"xdp-banner/api/orch/v1/rule"
 	"xdp-banner/pkg/log"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/health" // client side health checking
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 type Client interface {
 	Close()
 	GetRule(ctx context.Context, name string, ruleChan chan *rule.WatchRuleResponse) error
 	Report(ctx context.Context, status *report.Status) error
+	RenewCertificate(ctx context.Context, pubPem []byte, ipAddresses []string) (certPem []byte, caPem []byte, err error)
+	// ProtectedRanges returns the never-ban ranges of the orchestrators
+	ProtectedRanges(ctx context.Context) ([]string, error)
+	// Orchestrators returns the orchestrator IPs the agent connects to
+	Orchestrators() []string
 }
 
 type client struct {
-	conn *grpc.ClientConn
+	conn     *grpc.ClientConn
+	resolver *Resolver
 
-	rule   rule.RuleServiceClient
-	report report.ReportServiceClient
+	rule    rule.RuleServiceClient
+	report  report.ReportServiceClient
+	control control.ControlServiceClient
+	protect protect.ProtectServiceClient
 }
 
-func New(endpoint string, opts ...grpc.DialOption) (Client, error) {
-	conn, err := grpc.NewClient(
-		endpoint,
-		opts...,
-	)
+// serviceConfig balances the requests over the orchestrators passing the grpc health check
+const serviceConfig = `{"loadBalancingPolicy":"round_robin","healthCheckConfig":{"serviceName":""}}`
+
+var errNoOrchestrator = errors.New("no orchestrator endpoint resolved")
+
+// New connects to the comma separated orchestrator endpoints, the orchestrators
+// advertised in the orch infos are discovered and used as well.
+func New(endpoints string, opts ...grpc.DialOption) (Client, error) {
+	r := NewResolver()
+
+	opts = append([]grpc.DialOption{
+		grpc.WithResolvers(r),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+	}, opts...)
+	conn, err := grpc.NewClient(Target(endpoints), opts...)
 	if err != nil {
 		return nil, fmt.Errorf("grpc client connect failed: %w", err)
 	}
+	r.SetDiscovery(discoverFrom(conn))
 
 	ruc := rule.NewRuleServiceClient(conn)
 	rec := report.NewReportServiceClient(conn)
+	coc := control.NewControlServiceClient(conn)
+	prc := protect.NewProtectServiceClient(conn)
 
 	return &client{
-		conn: conn,
+		conn:     conn,
+		resolver: r,
 
-		rule:   ruc,
-		report: rec,
+		rule:    ruc,
+		report:  rec,
+		control: coc,
+		protect: prc,
 	}, nil
 }
 
@@ -93,3 +122,32 @@ func (c *client) Report(ctx context.Context, status *report.Status) error {
 
 	return nil
 }
+
+func (c *client) RenewCertificate(ctx context.Context, pubPem []byte, ipAddresses []string) ([]byte, []byte, error) {
+	resp, err := c.control.RenewCertificate(ctx, &control.RenewCertificateRequest{
+		PubKeyPem:   pubPem,
+		IpAddresses: ipAddresses,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("renew certificate: %w", err)
+	}
+
+	return resp.Cert, resp.Ca, nil
+}
+
+func (c *client) ProtectedRanges(ctx context.Context) ([]string, error) {
+	resp, err := c.protect.ListEffectiveRanges(ctx, &emptypb.Empty{})
+	if err != nil {
+		return nil, fmt.Errorf("list protected ranges: %w", err)
+	}
+
+	ranges := make([]string, 0, len(resp.Ranges))
+	for _, r := range resp.Ranges {
+		ranges = append(ranges, r.Cidr)
+	}
+	return ranges, nil
+}
+
+func (c *client) Orchestrators() []string {
+	return c.resolver.Addresses()
+}
@@ -6,6 +6,8 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+	"xdp-banner/agent/internal/capability"
+	"xdp-banner/agent/internal/health"
 	"xdp-banner/api/orch/v1/agent/report"
 	"xdp-banner/pkg/log"
 
@@ -51,6 +53,17 @@ func SetPhase(phase report.Phase) {
 	r.SetData(PhaseKey, phase)
 }
 
+// SetHealth sets the conditions, interface health and map usage evaluated by
+// the controller, they are sent along with the phase
+func SetHealth(conditions []health.Condition, interfaces []health.InterfaceHealth, maps []health.MapUsage) {
+	r.SetDatas([]MetricKey{ConditionsKey, InterfacesKey, MapsKey}, []any{conditions, interfaces, maps})
+}
+
+// SetCapabilities sets the host capabilities probed by the agent
+func SetCapabilities(c capability.Capabilities) {
+	r.SetData(CapabilitiesKey, c)
+}
+
 type ErrorTime struct {
 	Message string
 	RetryAt *timestamppb.Timestamp
@@ -63,9 +76,15 @@ func SetError(err string) {
 	})
 }
 
+// SetReportInterval changes the interval of the following reports
+func SetReportInterval(interval time.Duration) {
+	r.SetInterval(interval)
+}
+
 type Reporter interface {
 	Start()
 	Close()
+	SetInterval(interval time.Duration)
 
 	SetData(key MetricKey, value any)
 	SetDatas(key []MetricKey, value []any)
@@ -74,7 +93,7 @@ type Reporter interface {
 type MetricKey int
 
 // fieldNum is the number of fields below
-const filedNum = 5
+const filedNum = 9
 
 const (
 	NameKey MetricKey = iota
@@ -82,6 +101,10 @@ const (
 	ConfigNameKey
 	PhaseKey
 	Error
+	ConditionsKey
+	InterfacesKey
+	MapsKey
+	CapabilitiesKey
 )
 
 // mustInitialized is true when the field must be initialized
@@ -93,12 +116,16 @@ var mustInitialized = []bool{
 	false,
 	true,
 	false,
+	false,
+	false,
+	false,
+	false,
 }
 
 type reporter struct {
 	done     chan struct{}
 	client   Client
-	interval time.Duration
+	interval atomic.Int64 // time.Duration
 	// trigger a report immediately
 	trigger chan struct{}
 
@@ -119,13 +146,23 @@ func NewReporter(client Client, interval time.Duration) Reporter {
 		initmap[i] = !mustInitialized[i]
 	}
 
-	return &reporter{
-		done:     make(chan struct{}),
-		client:   client,
-		interval: interval,
-		trigger:  make(chan struct{}, 1),
-		initmap:  initmap,
+	r := &reporter{
+		done:    make(chan struct{}),
+		client:  client,
+		trigger: make(chan struct{}, 1),
+		initmap: initmap,
 	}
+	r.SetInterval(interval)
+
+	return r
+}
+
+func (r *reporter) SetInterval(interval time.Duration) {
+	r.interval.Store(int64(interval))
+}
+
+func (r *reporter) getInterval() time.Duration {
+	return time.Duration(r.interval.Load())
 }
 
 func (r *reporter) Close() {
@@ -133,7 +170,7 @@ func (r *reporter) Close() {
 }
 
 func (r *reporter) Start() {
-	timer := time.NewTimer(r.interval)
+	timer := time.NewTimer(r.getInterval())
 
 	for {
 		select {
@@ -146,7 +183,7 @@ func (r *reporter) Start() {
 
 		r.tryReport()
 
-		timer = time.NewTimer(r.interval)
+		timer = time.NewTimer(r.getInterval())
 	}
 }
 
@@ -184,11 +221,40 @@ func (r *reporter) tryReport() {
 				Message: t.Message,
 				RetryAt: t.RetryAt,
 			}
+		case ConditionsKey:
+			for _, c := range v.([]health.Condition) {
+				status.Conditions = append(status.Conditions, &report.Condition{
+					Type:               c.Type,
+					Status:             c.Status,
+					Reason:             c.Reason,
+					Message:            c.Message,
+					LastTransitionTime: timestamppb.New(c.LastTransitionTime),
+				})
+			}
+		case InterfacesKey:
+			for _, i := range v.([]health.InterfaceHealth) {
+				status.Interfaces = append(status.Interfaces, &report.InterfaceHealth{
+					Name:     i.Name,
+					Attached: i.Attached,
+					Up:       i.Up,
+					Error:    i.Error,
+				})
+			}
+		case MapsKey:
+			for _, m := range v.([]health.MapUsage) {
+				status.Maps = append(status.Maps, &report.MapUsage{
+					Name:       m.Name,
+					Entries:    m.Entries,
+					MaxEntries: m.MaxEntries,
+				})
+			}
+		case CapabilitiesKey:
+			status.Capabilities = v.(capability.Capabilities).ToProto()
 		}
 	}
 
 	// set request timeout to 1/2 * interval
-	timeout := r.interval / 2
+	timeout := r.getInterval() / 2
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
@@ -0,0 +1,263 @@
+package client
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"xdp-banner/api/orch/v1/orch"
+	"xdp-banner/pkg/log"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+)
+
+// Scheme is the grpc target scheme of the orchestrator endpoints
+const Scheme = "xdp-orch"
+
+const (
+	resolveInterval    = 30 * time.Second
+	resolveNowInterval = 5 * time.Second
+	discoverTimeout    = 5 * time.Second
+	discoverPageSize   = 100
+)
+
+// DiscoverFunc returns the endpoints advertised by the orchestrators
+type DiscoverFunc func(ctx context.Context) ([]string, error)
+
+// Resolver resolves a comma separated list of orchestrator endpoints. A host
+// resolving to several addresses is expanded to all of them, and the
+// endpoints found by the discover func are added to the configured ones.
+type Resolver struct {
+	mu       sync.Mutex
+	discover DiscoverFunc
+	// addrs 是最近一次解析出的 orchestrator 地址, 不含端口
+	addrs []string
+}
+
+// NewResolver returns a resolver without discovery, see SetDiscovery
+func NewResolver() *Resolver {
+	return &Resolver{}
+}
+
+// Target returns the grpc target of the comma separated endpoints
+func Target(endpoints string) string {
+	return Scheme + ":///" + endpoints
+}
+
+// SetDiscovery sets the func called on every resolution to find more endpoints
+func (r *Resolver) SetDiscovery(discover DiscoverFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.discover = discover
+}
+
+func (r *Resolver) discovery() DiscoverFunc {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.discover
+}
+
+// Addresses returns the orchestrator IPs of the last resolution, the agent
+// must never ban them.
+func (r *Resolver) Addresses() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.addrs)
+}
+
+func (r *Resolver) setAddresses(addrs []resolver.Address) {
+	ips := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		if host, _, err := net.SplitHostPort(addr.Addr); err == nil {
+			ips = append(ips, host)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.addrs = ips
+}
+
+func (r *Resolver) Scheme() string {
+	return Scheme
+}
+
+func (r *Resolver) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	or := &orchResolver{
+		parent:    r,
+		cc:        cc,
+		endpoints: splitEndpoints(target.Endpoint()),
+		ctx:       ctx,
+		cancel:    cancel,
+		now:       make(chan struct{}, 1),
+	}
+
+	go or.run()
+	return or, nil
+}
+
+type orchResolver struct {
+	parent *Resolver
+	cc     resolver.ClientConn
+	// endpoints are the configured seeds, they are always resolved
+	endpoints []string
+	// discovered 是上一次成功发现的结果, 每次成功发现时整体替换, 下线的
+	// orchestrator 不再被解析; 发现失败时继续使用
+	discovered []string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	now    chan struct{}
+}
+
+func (r *orchResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.now <- struct{}{}:
+	default:
+	}
+}
+
+func (r *orchResolver) Close() {
+	r.cancel()
+}
+
+func (r *orchResolver) run() {
+	ticker := time.NewTicker(resolveInterval)
+	defer ticker.Stop()
+
+	for {
+		r.resolve()
+
+		// 连接断开时 grpc 会频繁调用 ResolveNow, 限制解析频率
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-time.After(resolveNowInterval):
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.now:
+		}
+	}
+}
+
+func (r *orchResolver) resolve() {
+	if discover := r.parent.discovery(); discover != nil {
+		ctx, cancel := context.WithTimeout(r.ctx, discoverTimeout)
+		discovered, err := discover(ctx)
+		cancel()
+		if err != nil {
+			log.Debug("discover orchestrators failed, keeping the previous ones", log.ErrorField(err))
+		} else {
+			r.discovered = slices.Clone(discovered)
+		}
+	}
+
+	addrs := resolveEndpoints(r.ctx, net.DefaultResolver.LookupHost, r.targets())
+	if len(addrs) == 0 {
+		r.cc.ReportError(errNoOrchestrator)
+		return
+	}
+	r.parent.setAddresses(addrs)
+
+	if err := r.cc.UpdateState(resolver.State{Addresses: addrs}); err != nil {
+		log.Debug("update orchestrator addresses", log.ErrorField(err))
+	}
+}
+
+// targets are the configured endpoints and the last discovered ones
+func (r *orchResolver) targets() []string {
+	return append(slices.Clone(r.endpoints), r.discovered...)
+}
+
+type lookupFunc func(ctx context.Context, host string) ([]string, error)
+
+// resolveEndpoints expands every host:port to the addresses of the host, the
+// host is kept as the tls server name since the target holds the whole list.
+// Duplicated addresses are dropped.
+func resolveEndpoints(ctx context.Context, lookup lookupFunc, endpoints []string) []resolver.Address {
+	var addrs []resolver.Address
+	seen := make(map[string]struct{})
+	add := func(addr resolver.Address) {
+		if _, ok := seen[addr.Addr]; ok {
+			return
+		}
+		seen[addr.Addr] = struct{}{}
+		addrs = append(addrs, addr)
+	}
+
+	for _, endpoint := range endpoints {
+		host, port, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			log.Warn("invalid orchestrator endpoint", log.StringField("endpoint", endpoint), log.ErrorField(err))
+			continue
+		}
+
+		if net.ParseIP(host) != nil {
+			add(resolver.Address{Addr: endpoint, ServerName: host})
+			continue
+		}
+
+		ips, err := lookup(ctx, host)
+		if err != nil {
+			log.Warn("resolve orchestrator endpoint", log.StringField("endpoint", endpoint), log.ErrorField(err))
+			continue
+		}
+		for _, ip := range ips {
+			add(resolver.Address{Addr: net.JoinHostPort(ip, port), ServerName: host})
+		}
+	}
+
+	return addrs
+}
+
+func splitEndpoints(endpoints string) []string {
+	var result []string
+	for _, e := range strings.Split(endpoints, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// discoverFrom lists the endpoints advertised in the orch infos
+func discoverFrom(conn grpc.ClientConnInterface) DiscoverFunc {
+	oc := orch.NewOrchServiceClient(conn)
+
+	return func(ctx context.Context) ([]string, error) {
+		var endpoints []string
+		cursor := ""
+		for {
+			resp, err := oc.ListInfo(ctx, &orch.ListInfoRequest{Pagesize: discoverPageSize, Cursor: cursor})
+			if err != nil {
+				return nil, err
+			}
+
+			for _, info := range resp.Items {
+				if endpoint := info.GetFields()["endpoint"].GetStringValue(); endpoint != "" {
+					endpoints = append(endpoints, endpoint)
+				}
+			}
+
+			if !resp.HasNext || resp.NextCursor == "" {
+				break
+			}
+			cursor = resp.NextCursor
+		}
+
+		slices.Sort(endpoints)
+		return endpoints, nil
+	}
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/resolver"
+)
+
+func TestSplitEndpoints(t *testing.T) {
+	got := splitEndpoints(" a:1, ,b:2,")
+	want := []string{"a:1", "b:2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("splitEndpoints = %v, want %v", got, want)
+	}
+}
+
+func TestResolveEndpoints(t *testing.T) {
+	lookup := func(_ context.Context, host string) ([]string, error) {
+		switch host {
+		case "orch":
+			return []string{"10.0.0.1", "10.0.0.2"}, nil
+		case "v6":
+			return []string{"fd00::1"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	got := resolveEndpoints(context.Background(), lookup, []string{
+		"orch:6061",
+		"10.0.0.2:6061", // 和 orch 解析的结果重复
+		"10.0.0.3:6061",
+		"v6:6061",
+		"missing:6061",
+		"no-port",
+	})
+	want := []resolver.Address{
+		{Addr: "10.0.0.1:6061", ServerName: "orch"},
+		{Addr: "10.0.0.2:6061", ServerName: "orch"},
+		{Addr: "10.0.0.3:6061", ServerName: "10.0.0.3"},
+		{Addr: "[fd00::1]:6061", ServerName: "v6"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("resolveEndpoints =\n%v\nwant\n%v", got, want)
+	}
+}
+
+type fakeClientConn struct {
+	resolver.ClientConn
+	states chan resolver.State
+	errs   chan error
+}
+
+func (f *fakeClientConn) UpdateState(s resolver.State) error {
+	f.states <- s
+	return nil
+}
+
+func (f *fakeClientConn) ReportError(err error) {
+	f.errs <- err
+}
+
+func TestResolverDiscovery(t *testing.T) {
+	r := NewResolver()
+	r.SetDiscovery(func(context.Context) ([]string, error) {
+		return []string{"10.0.0.9:6061", "10.0.0.1:6061"}, nil
+	})
+
+	cc := &fakeClientConn{states: make(chan resolver.State, 1), errs: make(chan error, 1)}
+	target := resolver.Target{}
+	target.URL.Scheme = Scheme
+	target.URL.Path = "/10.0.0.1:6061,10.0.0.2:6061"
+
+	res, err := r.Build(target, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+
+	select {
+	case s := <-cc.states:
+		var got []string
+		for _, a := range s.Addresses {
+			got = append(got, a.Addr)
+		}
+		want := []string{"10.0.0.1:6061", "10.0.0.2:6061", "10.0.0.9:6061"}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("addresses = %v, want %v", got, want)
+		}
+	case err := <-cc.errs:
+		t.Fatalf("resolve failed: %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("no state reported")
+	}
+}
+
+func TestResolverNoEndpoint(t *testing.T) {
+	cc := &fakeClientConn{states: make(chan resolver.State, 1), errs: make(chan error, 1)}
+	target := resolver.Target{}
+	target.URL.Path = "/"
+
+	res, err := NewResolver().Build(target, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+
+	select {
+	case err := <-cc.errs:
+		if !errors.Is(err, errNoOrchestrator) {
+			t.Fatalf("error = %v, want %v", err, errNoOrchestrator)
+		}
+	case <-cc.states:
+		t.Fatal("state reported without any endpoint")
+	case <-time.After(time.Second):
+		t.Fatal("no error reported")
+	}
+}
+
+func TestResolverDiscoveryPrunes(t *testing.T) {
+	discovered := []string{"10.0.0.8:6061", "10.0.0.9:6061"}
+	var failed bool
+	r := NewResolver()
+	r.SetDiscovery(func(context.Context) ([]string, error) {
+		if failed {
+			return nil, errors.New("unavailable")
+		}
+		return discovered, nil
+	})
+
+	cc := &fakeClientConn{states: make(chan resolver.State, 1), errs: make(chan error, 1)}
+	or := &orchResolver{parent: r, cc: cc, endpoints: []string{"10.0.0.1:6061"}, ctx: context.Background()}
+	addrs := func() []string {
+		or.resolve()
+		var got []string
+		for _, a := range (<-cc.states).Addresses {
+			got = append(got, a.Addr)
+		}
+		return got
+	}
+
+	if got := addrs(); !reflect.DeepEqual(got, []string{"10.0.0.1:6061", "10.0.0.8:6061", "10.0.0.9:6061"}) {
+		t.Fatalf("addresses = %v", got)
+	}
+	// 下线的 orchestrator 不再被解析, 配置的 endpoint 保留
+	discovered = []string{"10.0.0.9:6061"}
+	if got := addrs(); !reflect.DeepEqual(got, []string{"10.0.0.1:6061", "10.0.0.9:6061"}) {
+		t.Fatalf("addresses after a decommission = %v", got)
+	}
+	// 发现失败时保留上一次的结果
+	failed = true
+	if got := addrs(); !reflect.DeepEqual(got, []string{"10.0.0.1:6061", "10.0.0.9:6061"}) {
+		t.Fatalf("addresses after a failed discovery = %v", got)
+	}
+}
@@ -0,0 +1,159 @@
+package health
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Condition types
+const (
+	InterfacesAttached  = "InterfacesAttached"
+	RuleStreamConnected = "RuleStreamConnected"
+	MapUsageOK          = "MapUsage"
+)
+
+// MapUsageThreshold is the fill ratio above which a map is reported as nearly full
+const MapUsageThreshold = 0.9
+
+// Verdict is the overall health derived from the conditions
+type Verdict int
+
+const (
+	Healthy Verdict = iota
+	// Degraded means the agent still filters traffic, but not all of it
+	Degraded
+	// Failed means no traffic is filtered at all
+	Failed
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Healthy:
+		return "Healthy"
+	case Degraded:
+		return "Degraded"
+	default:
+		return "Failed"
+	}
+}
+
+type Condition struct {
+	Type               string    `json:"type"`
+	Status             bool      `json:"status"`
+	Reason             string    `json:"reason,omitempty"`
+	Message            string    `json:"message,omitempty"`
+	LastTransitionTime time.Time `json:"last_transition_time"`
+}
+
+type InterfaceHealth struct {
+	Name     string `json:"name"`
+	Attached bool   `json:"attached"`
+	Up       bool   `json:"up"`
+	Error    string `json:"error,omitempty"`
+}
+
+type MapUsage struct {
+	Name       string `json:"name"`
+	Entries    uint32 `json:"entries"`
+	MaxEntries uint32 `json:"max_entries"`
+}
+
+// Snapshot is the raw health data gathered from the datapath and the rule watcher
+type Snapshot struct {
+	Interfaces []InterfaceHealth
+	// StreamRequired is false in standalone mode, where there is no rule stream
+	StreamRequired  bool
+	StreamConnected bool
+	Maps            []MapUsage
+}
+
+// Evaluate derives the verdict and the conditions of a snapshot. The
+// LastTransitionTime of the conditions is left zero, see Tracker.
+func Evaluate(s Snapshot) (Verdict, []Condition) {
+	verdict := Healthy
+	degrade := func() {
+		if verdict == Healthy {
+			verdict = Degraded
+		}
+	}
+
+	conds := make([]Condition, 0, 3)
+
+	// interfaces
+	var failed []string
+	attached := 0
+	for _, i := range s.Interfaces {
+		if i.Attached {
+			attached++
+		} else {
+			failed = append(failed, i.Name)
+		}
+	}
+	sort.Strings(failed)
+	switch {
+	case attached == 0:
+		verdict = Failed
+		conds = append(conds, Condition{Type: InterfacesAttached, Reason: "NoneAttached", Message: "xdp is not attached to any interface"})
+	case len(failed) > 0:
+		degrade()
+		conds = append(conds, Condition{
+			Type:    InterfacesAttached,
+			Reason:  "PartiallyAttached",
+			Message: fmt.Sprintf("%d/%d attached, failed: %s", attached, len(s.Interfaces), strings.Join(failed, ",")),
+		})
+	default:
+		conds = append(conds, Condition{Type: InterfacesAttached, Status: true, Message: fmt.Sprintf("%d attached", attached)})
+	}
+
+	// rule stream
+	switch {
+	case !s.StreamRequired:
+		conds = append(conds, Condition{Type: RuleStreamConnected, Status: true, Reason: "NotRequired"})
+	case s.StreamConnected:
+		conds = append(conds, Condition{Type: RuleStreamConnected, Status: true})
+	default:
+		degrade()
+		conds = append(conds, Condition{Type: RuleStreamConnected, Reason: "Disconnected", Message: "rule watch stream is not connected, rules may be stale"})
+	}
+
+	// map usage
+	var full []string
+	for _, m := range s.Maps {
+		if m.MaxEntries > 0 && float64(m.Entries) >= float64(m.MaxEntries)*MapUsageThreshold {
+			full = append(full, fmt.Sprintf("%s %d/%d", m.Name, m.Entries, m.MaxEntries))
+		}
+	}
+	if len(full) > 0 {
+		degrade()
+		conds = append(conds, Condition{Type: MapUsageOK, Reason: "NearlyFull", Message: strings.Join(full, ", ")})
+	} else {
+		conds = append(conds, Condition{Type: MapUsageOK, Status: true})
+	}
+
+	return verdict, conds
+}
+
+// Tracker keeps the LastTransitionTime of conditions between evaluations
+type Tracker struct {
+	last map[string]Condition
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{last: make(map[string]Condition)}
+}
+
+// Update sets LastTransitionTime to now for conditions whose status changed
+func (t *Tracker) Update(conds []Condition, now time.Time) []Condition {
+	for i, c := range conds {
+		last, ok := t.last[c.Type]
+		if ok && last.Status == c.Status {
+			conds[i].LastTransitionTime = last.LastTransitionTime
+		} else {
+			conds[i].LastTransitionTime = now
+		}
+		t.last[c.Type] = conds[i]
+	}
+	return conds
+}
@@ -0,0 +1,71 @@
+package health
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	healthy := Snapshot{
+		Interfaces:      []InterfaceHealth{{Name: "eth0", Attached: true}, {Name: "eth1", Attached: true}},
+		StreamRequired:  true,
+		StreamConnected: true,
+		Maps:            []MapUsage{{Name: "identity_ipcache", Entries: 10, MaxEntries: 100}},
+	}
+	if v, _ := Evaluate(healthy); v != Healthy {
+		t.Fatalf("expected healthy, got %s", v)
+	}
+
+	partial := healthy
+	partial.Interfaces = []InterfaceHealth{{Name: "eth0", Attached: true}, {Name: "eth1", Error: "busy"}}
+	v, conds := Evaluate(partial)
+	if v != Degraded || conds[0].Status || conds[0].Reason != "PartiallyAttached" {
+		t.Fatalf("expected degraded by interfaces, got %s %+v", v, conds[0])
+	}
+
+	stream := healthy
+	stream.StreamConnected = false
+	if v, _ := Evaluate(stream); v != Degraded {
+		t.Fatalf("expected degraded by stream, got %s", v)
+	}
+	stream.StreamRequired = false
+	if v, _ := Evaluate(stream); v != Healthy {
+		t.Fatalf("stream is not required in standalone mode, got %s", v)
+	}
+
+	full := healthy
+	full.Maps = []MapUsage{{Name: "xdp_banner_banlist", Entries: 95, MaxEntries: 100}}
+	if v, conds := Evaluate(full); v != Degraded || conds[2].Status {
+		t.Fatalf("expected degraded by map usage, got %s %+v", v, conds[2])
+	}
+
+	none := healthy
+	none.Interfaces = []InterfaceHealth{{Name: "eth0", Error: "gone"}}
+	none.StreamConnected = false
+	if v, _ := Evaluate(none); v != Failed {
+		t.Fatalf("expected failed, got %s", v)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker()
+	t0 := time.Unix(1000, 0)
+
+	_, conds := Evaluate(Snapshot{Interfaces: []InterfaceHealth{{Name: "eth0", Attached: true}}})
+	conds = tracker.Update(conds, t0)
+	if !conds[0].LastTransitionTime.Equal(t0) {
+		t.Fatalf("first evaluation should set the transition time")
+	}
+
+	_, conds = Evaluate(Snapshot{Interfaces: []InterfaceHealth{{Name: "eth0", Attached: true}}})
+	conds = tracker.Update(conds, t0.Add(time.Minute))
+	if !conds[0].LastTransitionTime.Equal(t0) {
+		t.Fatalf("unchanged condition should keep its transition time")
+	}
+
+	_, conds = Evaluate(Snapshot{Interfaces: []InterfaceHealth{{Name: "eth0"}}})
+	conds = tracker.Update(conds, t0.Add(2*time.Minute))
+	if !conds[0].LastTransitionTime.Equal(t0.Add(2 * time.Minute)) {
+		t.Fatalf("changed condition should update its transition time")
+	}
+}
@@ -0,0 +1,159 @@
+package icert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+	"xdp-banner/pkg/cert"
+)
+
+// KeyPair holds the agent certificate in memory. TLS configs built on it pick
+// the current certificate at every handshake, so Swap takes effect for new
+// connections of both the orch client and the agent grpc server without a restart.
+type KeyPair struct {
+	caFile   string
+	certFile string
+	keyFile  string
+
+	cert atomic.Pointer[tls.Certificate]
+}
+
+// LoadKeyPair loads the certificate stored by `agent join`
+func LoadKeyPair() (*KeyPair, error) {
+	return loadKeyPair(CAFile, CertFile, KeyFile)
+}
+
+func loadKeyPair(caFile, certFile, keyFile string) (*KeyPair, error) {
+	k := &KeyPair{caFile: caFile, certFile: certFile, keyFile: keyFile}
+
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		// Swap 在两次 rename 之间退出时私钥已经换新, 新证书还在临时文件里
+		var recoverErr error
+		if pair, recoverErr = recoverSwap(certFile, keyFile); recoverErr != nil {
+			return nil, err
+		}
+	}
+	if err := k.set(pair); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// recoverSwap finishes a Swap interrupted after the key was replaced: the new
+// certificate left in its temporary file is moved in place when it matches
+// the key
+func recoverSwap(certFile, keyFile string) (tls.Certificate, error) {
+	tmp := certFile + ".new"
+	pair, err := tls.LoadX509KeyPair(tmp, keyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := os.Rename(tmp, certFile); err != nil {
+		return tls.Certificate{}, fmt.Errorf("replace %s: %w", certFile, err)
+	}
+	return pair, nil
+}
+
+func (k *KeyPair) set(pair tls.Certificate) error {
+	if pair.Leaf == nil {
+		leaf, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			return fmt.Errorf("parse certificate: %w", err)
+		}
+		pair.Leaf = leaf
+	}
+
+	k.cert.Store(&pair)
+	return nil
+}
+
+// Leaf returns the current certificate
+func (k *KeyPair) Leaf() *x509.Certificate {
+	return k.cert.Load().Leaf
+}
+
+// Swap validates the new pair, writes it to disk and starts using it
+func (k *KeyPair) Swap(certPem, keyPem []byte) error {
+	pair, err := tls.X509KeyPair(certPem, keyPem)
+	if err != nil {
+		return fmt.Errorf("invalid key pair: %w", err)
+	}
+
+	// 先写临时文件再 rename, 私钥先于证书替换. 两次 rename 之间退出时
+	// 新证书留在临时文件中, 下次加载时由 recoverSwap 完成替换
+	if err := writeAtomic(k.keyFile, keyPem); err != nil {
+		return err
+	}
+	if err := writeAtomic(k.certFile, certPem); err != nil {
+		return err
+	}
+
+	return k.set(pair)
+}
+
+// SignFunc asks the orchestrator to sign a certificate for the PEM public key
+type SignFunc func(pubPem []byte) (certPem []byte, caPem []byte, err error)
+
+// Renew generates a new private key, gets it signed and swaps it in. The CA
+// returned is only stored on disk, it is loaded again at the next start.
+func (k *KeyPair) Renew(sign SignFunc) error {
+	priv, err := cert.GeneratePrivateKey()
+	if err != nil {
+		return fmt.Errorf("generate private key: %w", err)
+	}
+
+	pubPem, err := cert.PubKeyToPem(&priv.PublicKey)
+	if err != nil {
+		return err
+	}
+	privPem, err := cert.PrivToPem(priv, "")
+	if err != nil {
+		return err
+	}
+
+	certPem, caPem, err := sign(pubPem)
+	if err != nil {
+		return fmt.Errorf("sign certificate: %w", err)
+	}
+
+	if len(caPem) > 0 {
+		if err := writeAtomic(k.caFile, caPem); err != nil {
+			return err
+		}
+	}
+
+	return k.Swap(certPem, privPem)
+}
+
+// GetCertificate is used as tls.Config.GetCertificate of the grpc server
+func (k *KeyPair) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return k.cert.Load(), nil
+}
+
+// GetClientCertificate is used as tls.Config.GetClientCertificate of the orch client
+func (k *KeyPair) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return k.cert.Load(), nil
+}
+
+// RenewAt returns when the certificate should be renewed, after two thirds of its lifetime
+func (k *KeyPair) RenewAt() time.Time {
+	leaf := k.Leaf()
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+	return leaf.NotBefore.Add(lifetime * 2 / 3)
+}
+
+func writeAtomic(path string, pem []byte) error {
+	tmp := path + ".new"
+	if err := cert.WritePemFile(tmp, pem); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
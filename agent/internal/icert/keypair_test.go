@@ -0,0 +1,127 @@
+package icert
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"xdp-banner/pkg/cert"
+)
+
+func TestKeyPairRenew(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "cert.key")
+
+	ca, caKey, err := cert.GenerateCA("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPem, keyPem, err := cert.GenerateCert(ca, caKey, "", "node-1", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, data := range map[string][]byte{caFile: ca, certFile: certPem, keyFile: keyPem} {
+		if err := cert.WritePemFile(path, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	kp, err := loadKeyPair(caFile, certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, _ := kp.GetCertificate(nil)
+
+	renewAt := kp.RenewAt()
+	if !renewAt.After(kp.Leaf().NotBefore) || !renewAt.Before(kp.Leaf().NotAfter) {
+		t.Fatalf("renew time %s out of the certificate lifetime", renewAt)
+	}
+
+	err = kp.Renew(func(pubPem []byte) ([]byte, []byte, error) {
+		pub, err := cert.ParsePemPubkey(pubPem)
+		if err != nil {
+			return nil, nil, err
+		}
+		signed, err := cert.SignCert(ca, caKey, "", "node-1", nil, pub)
+		return signed, ca, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renewed, _ := kp.GetClientCertificate(nil)
+	if bytes.Equal(old.Certificate[0], renewed.Certificate[0]) {
+		t.Fatal("certificate was not swapped")
+	}
+	if kp.Leaf().Subject.CommonName != "node-1" {
+		t.Fatalf("unexpected common name %q", kp.Leaf().Subject.CommonName)
+	}
+
+	// the new pair is persisted and loads again
+	if _, err := loadKeyPair(caFile, certFile, keyFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(certFile + ".new"); !os.IsNotExist(err) {
+		t.Fatal("temporary file left behind")
+	}
+
+	// a certificate not matching the key is rejected and the old one kept
+	if err := kp.Swap(certPem, keyPem[:0]); err == nil {
+		t.Fatal("invalid pair should be rejected")
+	}
+	if current, _ := kp.GetCertificate(nil); current != renewed {
+		t.Fatal("failed swap replaced the certificate")
+	}
+}
+
+func TestKeyPairRecoverSwap(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "cert.key")
+
+	ca, caKey, err := cert.GenerateCA("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldCert, _, err := cert.GenerateCert(ca, caKey, "", "node-1", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCert, newKey, err := cert.GenerateCert(ca, caKey, "", "node-1", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the process exited between the two renames of Swap
+	for path, data := range map[string][]byte{certFile: oldCert, keyFile: newKey, certFile + ".new": newCert} {
+		if err := cert.WritePemFile(path, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	kp, err := loadKeyPair("", certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, _ := kp.GetCertificate(nil)
+	onDisk, err := cert.ReadPemFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(onDisk, newCert) || current.Leaf == nil {
+		t.Fatal("interrupted swap was not finished")
+	}
+	if _, err := os.Stat(certFile + ".new"); !os.IsNotExist(err) {
+		t.Fatal("temporary file left behind")
+	}
+
+	// without the temporary file a mismatched pair still fails
+	if err := cert.WritePemFile(certFile, oldCert); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadKeyPair("", certFile, keyFile); err == nil {
+		t.Fatal("mismatched pair should fail to load")
+	}
+}
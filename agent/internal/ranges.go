@@ -7,8 +7,24 @@ func PrivateRangesCIDR() []string {
 		"192.168.0.0/16",
 		"172.16.0.0/12",
 		"10.0.0.0/8",
-		"127.0.0.1/8",
-		"fd00::/8",
-		"::1",
+		"127.0.0.0/8",
+		"fc00::/7",
+		"::1/128",
 	}
 }
+
+// PrivateRanges is the shortcut of PrivateRangesCIDR in a list of ranges
+const PrivateRanges = "private"
+
+// ExpandRanges replaces the PrivateRanges shortcut by the private CIDRs
+func ExpandRanges(ranges []string) []string {
+	var result []string
+	for _, r := range ranges {
+		if r == PrivateRanges {
+			result = append(result, PrivateRangesCIDR()...)
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
@@ -0,0 +1,133 @@
+package ruleset
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"xdp-banner/agent/ebpf/xdp"
+	"xdp-banner/pkg/config"
+	"xdp-banner/pkg/log"
+	"xdp-banner/pkg/rule"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+type Action string
+
+const (
+	// ActionDeny bans the matched traffic, it is the default action
+	ActionDeny Action = "deny"
+	// ActionAllow keeps the whole CIDR reachable, it wins over every deny rule
+	ActionAllow Action = "allow"
+)
+
+// StaticRule is a rule loaded from the local rule file, it uses the same
+// schema as the rule dto with an extra action field.
+// Static rules live as long as they are in the file, duration is ignored.
+type StaticRule struct {
+	rule.RuleInfo `mapstructure:",squash"`
+	Action        Action `json:"action" mapstructure:"action"`
+}
+
+// File is the layout of the local rule file, e.g.
+//
+//	rules:
+//	  - cidr: 10.0.0.0/24
+//	    action: allow
+//	    comment: management network
+//	  - cidr: 192.0.2.0/24
+//	    protocol: TCP
+//	    dport: 22
+type File struct {
+	Rules []StaticRule `json:"rules" mapstructure:"rules"`
+}
+
+func (r *StaticRule) Check() error {
+	if _, _, err := net.ParseCIDR(r.Cidr); err != nil {
+		return fmt.Errorf("invalid cidr %q: %w", r.Cidr, err)
+	}
+
+	switch r.Action {
+	case ActionAllow:
+		// allow 作用于整个 CIDR, datapath 无法表达按协议/端口放行
+		if r.Protocol != "" || r.Sport != 0 || r.Dport != 0 {
+			return fmt.Errorf("allow rule %s can not set protocol or ports", r.Cidr)
+		}
+	case ActionDeny:
+		if _, err := xdp.ParseProtocol(r.Protocol); err != nil {
+			return fmt.Errorf("deny rule %s: %w", r.Cidr, err)
+		}
+	default:
+		return fmt.Errorf("unknown action %q of rule %s", r.Action, r.Cidr)
+	}
+
+	return nil
+}
+
+// Load reads static rules from the given yaml or json file
+func Load(path string) ([]StaticRule, error) {
+	_, rules, err := load(path)
+	return rules, err
+}
+
+// Watch loads static rules from the given file and calls onChange every time
+// the file changes. An invalid file is logged and skipped, the last good rules
+// are kept in that case.
+func Watch(path string, onChange func([]StaticRule)) ([]StaticRule, error) {
+	v, rules, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v.OnConfigChange(func(e fsnotify.Event) {
+		file := File{}
+		if err := v.Unmarshal(&file); err != nil {
+			log.Error("reload rule file", log.StringField("file", e.Name), log.ErrorField(err))
+			return
+		}
+
+		rules, err := checkRules(file.Rules)
+		if err != nil {
+			log.Error("reload rule file", log.StringField("file", e.Name), log.ErrorField(err))
+			return
+		}
+
+		log.Info("rule file changed", log.StringField("file", e.Name), log.IntField("rules", len(rules)))
+		onChange(rules)
+	})
+	v.WatchConfig()
+
+	return rules, nil
+}
+
+func load(path string) (*viper.Viper, []StaticRule, error) {
+	file := File{}
+	v, err := config.NewFromFile(path, &file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load rule file %s: %w", path, err)
+	}
+
+	rules, err := checkRules(file.Rules)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load rule file %s: %w", path, err)
+	}
+
+	return v, rules, nil
+}
+
+func checkRules(rules []StaticRule) ([]StaticRule, error) {
+	for i := range rules {
+		if rules[i].Action == "" {
+			rules[i].Action = ActionDeny
+		}
+		rules[i].Action = Action(strings.ToLower(string(rules[i].Action)))
+
+		if err := rules[i].Check(); err != nil {
+			return nil, fmt.Errorf("rule #%d: %w", i, err)
+		}
+	}
+
+	return rules, nil
+}
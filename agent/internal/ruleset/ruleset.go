@@ -0,0 +1,501 @@
+package ruleset
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"xdp-banner/agent/ebpf/xdp"
+	"xdp-banner/pkg/cidr"
+	"xdp-banner/pkg/log"
+)
+
+// Datapath is the subset of xdp.BannedIPXdpMap used by RuleSet
+type Datapath interface {
+	AddCIDRRule(rule xdp.IPRule) error
+	RemoveCIDRRule(rule xdp.IPRule) error
+	SetCIDRIdentity(cidr string, identity string) error
+	RemoveCIDRIdentity(cidr string) error
+	Dump() (*xdp.MapDump, error)
+}
+
+// AllowIdentity is written to identity_ipcache for allowed CIDRs which are
+// nested in a banned CIDR. No banlist entry ever uses it, so the longest
+// prefix match of such source addresses ends up with no ban.
+var AllowIdentity = identityOf("static/allow")
+
+// TempRule is a deny rule added at runtime through the admin socket,
+// it is removed once ExpiresAt is reached
+type TempRule struct {
+	StaticRule
+	ExpiresAt time.Time `json:"expires_at"`
+
+	timer *time.Timer
+}
+
+type banKey struct {
+	identity string
+	protocol uint8
+	sport    uint16
+	dport    uint16
+}
+
+// RuleSet merges the rules watched from the orchestrator with the static
+// rules of the local rule file, and keeps the datapath in sync with the result.
+//
+// Precedence:
+//  1. protected ranges and static allow rules win over everything, deny rules
+//     inside them are not installed, and they are punched out of the banned
+//     CIDRs containing them.
+//  2. orchestrator, static and temporary deny rules are merged, a CIDR present
+//     in several of them keeps the orchestrator identity.
+type RuleSet struct {
+	mu sync.Mutex
+	dp Datapath
+
+	orch   map[string]xdp.IPRule // keyed by etcd rule key
+	static []StaticRule
+	temp   map[string]*TempRule // keyed by rule.RuleInfo.Key()
+	// protected 是永不封禁的网段, 最后一道防线, 即使 orch 下发了覆盖它们的规则
+	protected []*net.IPNet
+
+	// what is currently written to the datapath
+	appliedIdentity map[string]string
+	appliedBan      map[banKey]xdp.IPRule
+}
+
+func New(dp Datapath) *RuleSet {
+	return &RuleSet{
+		dp:              dp,
+		orch:            make(map[string]xdp.IPRule),
+		temp:            make(map[string]*TempRule),
+		appliedIdentity: make(map[string]string),
+		appliedBan:      make(map[banKey]xdp.IPRule),
+	}
+}
+
+// PutOrchRule records an orchestrator rule, call Sync to apply it
+func (s *RuleSet) PutOrchRule(key string, rule xdp.IPRule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.orch[key] = rule
+}
+
+// DeleteOrchRule forgets an orchestrator rule, call Sync to apply it
+func (s *RuleSet) DeleteOrchRule(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.orch, key)
+}
+
+// ReplaceOrchRules replaces all orchestrator rules, call Sync or Resync to apply them
+func (s *RuleSet) ReplaceOrchRules(rules map[string]xdp.IPRule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.orch = rules
+}
+
+// LocalRules returns the deny entries written for static and temporary rules
+// with the identity they were given, and the CIDRs of the static allow rules
+// and of the protected ranges.
+// Together they explain every datapath entry not coming from the orchestrator.
+func (s *RuleSet) LocalRules() ([]xdp.IPRule, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identities, bans := s.desired()
+
+	fromOrch := make(map[banKey]bool, len(s.orch))
+	for _, r := range s.orch {
+		_, ipNet, err := net.ParseCIDR(r.CIDR)
+		if err != nil {
+			continue
+		}
+		fromOrch[banKey{identity: identities[ipNet.String()], protocol: r.BannedProtocol, sport: r.Sport, dport: r.Dport}] = true
+	}
+
+	var local []xdp.IPRule
+	for key, r := range bans {
+		if !fromOrch[key] {
+			local = append(local, r)
+		}
+	}
+
+	var allowed []string
+	for _, allow := range s.allows() {
+		allowed = append(allowed, allow.String())
+	}
+
+	return local, allowed
+}
+
+// SetProtected replaces the protected ranges and applies them. Bare
+// addresses are accepted as host ranges.
+func (s *RuleSet) SetProtected(ranges []string) error {
+	var protected []*net.IPNet
+	seen := make(map[string]bool, len(ranges))
+	for _, r := range ranges {
+		prefix, err := cidr.Parse(r)
+		if err != nil {
+			return fmt.Errorf("protected range: %w", err)
+		}
+		if seen[prefix.String()] {
+			continue
+		}
+		seen[prefix.String()] = true
+
+		_, ipNet, _ := net.ParseCIDR(prefix.String())
+		protected = append(protected, ipNet)
+	}
+	slices.SortFunc(protected, func(a, b *net.IPNet) int { return strings.Compare(a.String(), b.String()) })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.EqualFunc(protected, s.protected, func(a, b *net.IPNet) bool { return a.String() == b.String() }) {
+		return nil
+	}
+	log.Info("protected ranges changed", log.IntField("ranges", len(protected)))
+	s.protected = protected
+	return s.sync()
+}
+
+// SetStaticRules replaces the static rules and applies them
+func (s *RuleSet) SetStaticRules(rules []StaticRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.static = rules
+	return s.sync()
+}
+
+// AddTempRule adds a deny rule which is removed after ttl, adding the same
+// rule again extends it
+func (s *RuleSet) AddTempRule(r StaticRule, ttl time.Duration) error {
+	if r.Action == "" {
+		r.Action = ActionDeny
+	}
+	if r.Action != ActionDeny {
+		return fmt.Errorf("temporary rule must be a deny rule")
+	}
+	if err := r.Check(); err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("ttl of temporary rule must be positive")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := r.Key()
+	if old, ok := s.temp[key]; ok {
+		old.timer.Stop()
+	}
+
+	temp := &TempRule{StaticRule: r, ExpiresAt: time.Now().Add(ttl)}
+	temp.timer = time.AfterFunc(ttl, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.temp[key] != temp {
+			return
+		}
+		delete(s.temp, key)
+		log.Info("temporary rule expired", log.StringField("rule", key))
+		if err := s.sync(); err != nil {
+			log.Error("sync rules failed", log.ErrorField(err))
+		}
+	})
+	s.temp[key] = temp
+
+	return s.sync()
+}
+
+// TempRules returns the temporary rules which are not expired yet
+func (s *RuleSet) TempRules() []TempRule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := make([]TempRule, 0, len(s.temp))
+	for _, r := range s.temp {
+		rules = append(rules, TempRule{StaticRule: r.StaticRule, ExpiresAt: r.ExpiresAt})
+	}
+	return rules
+}
+
+// Resync writes all merged rules again and removes the entries the datapath
+// holds beyond them, it repairs entries modified or removed behind the
+// agent's back. Like sync, every entry is written before the extra ones are
+// removed, a ban is never lifted in between.
+func (s *RuleSet) Resync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 清空记录后 sync 会无条件覆盖写入全部期望条目
+	clear(s.appliedIdentity)
+	clear(s.appliedBan)
+
+	var errs []error
+	if err := s.sync(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := s.prune(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// prune removes the datapath entries which are not desired, read from a dump
+// of the maps
+func (s *RuleSet) prune() error {
+	identities, bans := s.desired()
+	dump, err := s.dp.Dump()
+	if err != nil {
+		return fmt.Errorf("dump datapath failed: %w", err)
+	}
+	var errs []error
+
+	for _, e := range dump.Bans {
+		proto, ok := protocolNumber(e.Protocol)
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown protocol %q in banlist", e.Protocol))
+			continue
+		}
+		key := banKey{identity: strconv.FormatUint(uint64(e.Identity), 10), protocol: proto, sport: e.Sport, dport: e.Dport}
+		if _, ok := bans[key]; ok {
+			continue
+		}
+		rule := xdp.IPRule{Identity: key.identity, BannedProtocol: proto, Sport: e.Sport, Dport: e.Dport}
+		if err := s.dp.RemoveCIDRRule(rule); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, e := range dump.Identities {
+		if _, ok := identities[e.CIDR]; ok {
+			continue
+		}
+		if err := s.dp.RemoveCIDRIdentity(e.CIDR); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// protocolNumber parses the protocol of a dumped banlist entry, a name or the
+// number of an unknown protocol, see xdp.ProtocolName
+func protocolNumber(name string) (uint8, bool) {
+	if proto, err := xdp.ParseProtocol(name); err == nil {
+		return proto, true
+	}
+	proto, err := strconv.ParseUint(name, 10, 8)
+	return uint8(proto), err == nil
+}
+
+// Close stops the expiry timers of temporary rules, the datapath is left untouched
+func (s *RuleSet) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, r := range s.temp {
+		r.timer.Stop()
+		delete(s.temp, key)
+	}
+}
+
+// Sync writes the difference between the merged rules and the datapath
+func (s *RuleSet) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sync()
+}
+
+func (s *RuleSet) sync() error {
+	identities, bans := s.desired()
+	var errs []error
+
+	// 1) 先写入新的 identity 与 banlist, 再删除过期条目, 避免中间状态放行
+	for cidr, identity := range identities {
+		if s.appliedIdentity[cidr] == identity {
+			continue
+		}
+		if err := s.dp.SetCIDRIdentity(cidr, identity); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.appliedIdentity[cidr] = identity
+	}
+
+	for key, rule := range bans {
+		if _, ok := s.appliedBan[key]; ok {
+			continue
+		}
+		if err := s.dp.AddCIDRRule(rule); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.appliedBan[key] = rule
+	}
+
+	// 2) 删除不再需要的条目
+	for key, rule := range s.appliedBan {
+		if _, ok := bans[key]; ok {
+			continue
+		}
+		if err := s.dp.RemoveCIDRRule(rule); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(s.appliedBan, key)
+	}
+
+	for cidr := range s.appliedIdentity {
+		if _, ok := identities[cidr]; ok {
+			continue
+		}
+		if err := s.dp.RemoveCIDRIdentity(cidr); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(s.appliedIdentity, cidr)
+	}
+
+	return errors.Join(errs...)
+}
+
+// desired computes the identity_ipcache and banlist content from the merged rules
+func (s *RuleSet) desired() (map[string]string, map[banKey]xdp.IPRule) {
+	allows := s.allows()
+
+	identities := make(map[string]string)
+	// 同一 CIDR 存在多个 orch identity 时, 取 key 最小的规则的 identity, 保证结果稳定
+	owner := make(map[string]string)
+	denies := make([]xdp.IPRule, 0, len(s.orch)+len(s.static))
+
+	for key, r := range s.orch {
+		cidr, ok := s.normalize(r.CIDR, allows)
+		if !ok {
+			continue
+		}
+		if o, exist := owner[cidr]; !exist || key < o {
+			owner[cidr] = key
+			identities[cidr] = r.Identity
+		}
+		r.CIDR = cidr
+		denies = append(denies, r)
+	}
+
+	locals := make([]StaticRule, 0, len(s.static)+len(s.temp))
+	locals = append(locals, s.static...)
+	for _, r := range s.temp {
+		locals = append(locals, r.StaticRule)
+	}
+
+	for _, sr := range locals {
+		if sr.Action != ActionDeny {
+			continue
+		}
+		cidr, ok := s.normalize(sr.Cidr, allows)
+		if !ok {
+			continue
+		}
+		if _, exist := identities[cidr]; !exist {
+			identities[cidr] = identityOf("static/" + cidr)
+		}
+		proto, _ := xdp.ParseProtocol(sr.Protocol)
+		denies = append(denies, xdp.IPRule{
+			CIDR:           cidr,
+			BannedProtocol: proto,
+			Sport:          sr.Sport,
+			Dport:          sr.Dport,
+		})
+	}
+
+	bans := make(map[banKey]xdp.IPRule, len(denies))
+	for _, r := range denies {
+		r.Identity = identities[r.CIDR]
+		bans[banKey{identity: r.Identity, protocol: r.BannedProtocol, sport: r.Sport, dport: r.Dport}] = r
+	}
+
+	// allowed CIDR 被更大的 banned CIDR 包含时, 写入 AllowIdentity 以覆盖最长前缀匹配
+	for _, allow := range allows {
+		for cidr, identity := range identities {
+			if identity == AllowIdentity {
+				continue
+			}
+			if contains(cidr, allow) {
+				identities[allow.String()] = AllowIdentity
+				break
+			}
+		}
+	}
+
+	return identities, bans
+}
+
+// allows returns the CIDRs which are never banned, static allow rules and protected ranges
+func (s *RuleSet) allows() []*net.IPNet {
+	allows := slices.Clone(s.protected)
+	for _, r := range s.static {
+		if r.Action != ActionAllow {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(r.Cidr)
+		if err != nil {
+			continue
+		}
+		allows = append(allows, ipNet)
+	}
+	return allows
+}
+
+// normalize returns the network form of cidr, and false if it is invalid or
+// covered by an allowed CIDR
+func (s *RuleSet) normalize(cidr string, allows []*net.IPNet) (string, bool) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		log.Warn("skip rule with invalid cidr", log.StringField("cidr", cidr), log.ErrorField(err))
+		return "", false
+	}
+
+	for _, allow := range allows {
+		if contains(allow.String(), ipNet) {
+			log.Debug("skip rule covered by allow rule", log.StringField("cidr", cidr), log.StringField("allow", allow.String()))
+			return "", false
+		}
+	}
+
+	return ipNet.String(), true
+}
+
+// contains reports whether network outer contains inner entirely
+func contains(outer string, inner *net.IPNet) bool {
+	_, outerNet, err := net.ParseCIDR(outer)
+	if err != nil {
+		return false
+	}
+
+	outerOnes, outerBits := outerNet.Mask.Size()
+	innerOnes, innerBits := inner.Mask.Size()
+	if outerBits != innerBits || outerOnes > innerOnes {
+		return false
+	}
+
+	return outerNet.Contains(inner.IP)
+}
+
+func identityOf(s string) string {
+	return strconv.FormatUint(uint64(crc32.ChecksumIEEE([]byte(s))), 10)
+}
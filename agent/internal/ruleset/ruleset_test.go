@@ -0,0 +1,341 @@
+package ruleset
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"xdp-banner/agent/ebpf/xdp"
+	"xdp-banner/agent/ebpf/xdp/types"
+	"xdp-banner/pkg/rule"
+)
+
+type fakeDatapath struct {
+	identity map[string]string
+	ban      map[banKey]xdp.IPRule
+	// removed records the bans removed, in order
+	removed []banKey
+}
+
+func newFakeDatapath() *fakeDatapath {
+	return &fakeDatapath{
+		identity: make(map[string]string),
+		ban:      make(map[banKey]xdp.IPRule),
+	}
+}
+
+func (f *fakeDatapath) AddCIDRRule(r xdp.IPRule) error {
+	f.identity[r.CIDR] = r.Identity
+	f.ban[banKey{identity: r.Identity, protocol: r.BannedProtocol, sport: r.Sport, dport: r.Dport}] = r
+	return nil
+}
+
+func (f *fakeDatapath) RemoveCIDRRule(r xdp.IPRule) error {
+	key := banKey{identity: r.Identity, protocol: r.BannedProtocol, sport: r.Sport, dport: r.Dport}
+	f.removed = append(f.removed, key)
+	delete(f.ban, key)
+	return nil
+}
+
+func (f *fakeDatapath) SetCIDRIdentity(cidr string, identity string) error {
+	f.identity[cidr] = identity
+	return nil
+}
+
+func (f *fakeDatapath) RemoveCIDRIdentity(cidr string) error {
+	delete(f.identity, cidr)
+	return nil
+}
+
+func (f *fakeDatapath) Dump() (*xdp.MapDump, error) {
+	dump := &xdp.MapDump{}
+	for cidr, identity := range f.identity {
+		id, _ := strconv.ParseUint(identity, 10, 32)
+		dump.Identities = append(dump.Identities, xdp.IdentityEntry{CIDR: cidr, Identity: uint32(id)})
+	}
+	for key := range f.ban {
+		id, _ := strconv.ParseUint(key.identity, 10, 32)
+		dump.Bans = append(dump.Bans, xdp.BanEntry{
+			Protocol: xdp.ProtocolName(key.protocol),
+			Identity: uint32(id),
+			Sport:    key.sport,
+			Dport:    key.dport,
+		})
+	}
+	return dump, nil
+}
+
+func staticRule(cidr string, action Action, proto string, dport uint16) StaticRule {
+	return StaticRule{
+		RuleInfo: rule.RuleInfo{Cidr: cidr, Protocol: proto, Dport: dport},
+		Action:   action,
+	}
+}
+
+func TestMergeOrchAndStatic(t *testing.T) {
+	dp := newFakeDatapath()
+	s := New(dp)
+
+	s.PutOrchRule("/agent/rule/a/192.0.2.0/24/TCP/0-22/", xdp.IPRule{
+		CIDR: "192.0.2.0/24", Identity: "100", BannedProtocol: types.IPPROTO_TCP, Dport: 22,
+	})
+	if err := s.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// static deny on the same CIDR shares the orch identity
+	err := s.SetStaticRules([]StaticRule{
+		staticRule("192.0.2.0/24", ActionDeny, "UDP", 53),
+		staticRule("198.51.100.0/24", ActionDeny, "ICMP", 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dp.identity["192.0.2.0/24"] != "100" {
+		t.Fatalf("want orch identity kept, got %s", dp.identity["192.0.2.0/24"])
+	}
+	if _, ok := dp.ban[banKey{identity: "100", protocol: types.IPPROTO_UDP, dport: 53}]; !ok {
+		t.Fatalf("static deny not installed under orch identity: %v", dp.ban)
+	}
+	if dp.identity["198.51.100.0/24"] != identityOf("static/198.51.100.0/24") {
+		t.Fatalf("unexpected static identity %s", dp.identity["198.51.100.0/24"])
+	}
+	if len(dp.ban) != 3 {
+		t.Fatalf("want 3 bans, got %d", len(dp.ban))
+	}
+
+	// removing the orch rule moves the static rule to its own identity
+	s.DeleteOrchRule("/agent/rule/a/192.0.2.0/24/TCP/0-22/")
+	if err := s.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	staticID := identityOf("static/192.0.2.0/24")
+	if dp.identity["192.0.2.0/24"] != staticID {
+		t.Fatalf("want static identity, got %s", dp.identity["192.0.2.0/24"])
+	}
+	if _, ok := dp.ban[banKey{identity: staticID, protocol: types.IPPROTO_UDP, dport: 53}]; !ok {
+		t.Fatalf("static deny not moved: %v", dp.ban)
+	}
+	if len(dp.ban) != 2 {
+		t.Fatalf("want 2 bans, got %d", len(dp.ban))
+	}
+
+	if err := s.SetStaticRules(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(dp.ban) != 0 || len(dp.identity) != 0 {
+		t.Fatalf("datapath not cleaned: %v %v", dp.identity, dp.ban)
+	}
+}
+
+func TestAllowPrecedence(t *testing.T) {
+	dp := newFakeDatapath()
+	s := New(dp)
+
+	s.PutOrchRule("inside", xdp.IPRule{CIDR: "10.1.2.3/32", Identity: "1", BannedProtocol: types.IPPROTO_TCP})
+	s.PutOrchRule("outside", xdp.IPRule{CIDR: "10.0.0.0/8", Identity: "2", BannedProtocol: types.IPPROTO_TCP})
+	err := s.SetStaticRules([]StaticRule{
+		staticRule("10.1.0.0/16", ActionAllow, "", 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := dp.identity["10.1.2.3/32"]; ok {
+		t.Fatal("rule inside allowed CIDR should not be installed")
+	}
+	if dp.identity["10.0.0.0/8"] != "2" {
+		t.Fatal("rule outside allowed CIDR should be installed")
+	}
+	if dp.identity["10.1.0.0/16"] != AllowIdentity {
+		t.Fatal("allowed CIDR should be punched out of the banned CIDR")
+	}
+
+	// without the enclosing ban the allow entry is not needed
+	s.DeleteOrchRule("outside")
+	if err := s.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if len(dp.identity) != 0 || len(dp.ban) != 0 {
+		t.Fatalf("datapath not cleaned: %v %v", dp.identity, dp.ban)
+	}
+}
+
+func TestProtected(t *testing.T) {
+	dp := newFakeDatapath()
+	s := New(dp)
+
+	s.PutOrchRule("orch", xdp.IPRule{CIDR: "198.51.100.7/32", Identity: "1", BannedProtocol: types.IPPROTO_TCP})
+	s.PutOrchRule("wide", xdp.IPRule{CIDR: "198.51.0.0/16", Identity: "2", BannedProtocol: types.IPPROTO_TCP})
+	if err := s.SetProtected([]string{"198.51.100.7", "198.51.100.7/32"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if dp.identity["198.51.100.7/32"] != AllowIdentity {
+		t.Fatal("protected address should be punched out of the banned CIDR")
+	}
+	if _, allowed := s.LocalRules(); len(allowed) != 1 || allowed[0] != "198.51.100.7/32" {
+		t.Fatalf("allowed = %v, want the protected range", allowed)
+	}
+
+	if err := s.SetProtected([]string{"not a cidr"}); err == nil {
+		t.Fatal("invalid protected range should fail")
+	}
+
+	if err := s.SetProtected(nil); err != nil {
+		t.Fatal(err)
+	}
+	if dp.identity["198.51.100.7/32"] != "1" {
+		t.Fatal("rule should be installed once the range is not protected")
+	}
+}
+
+func TestTempRule(t *testing.T) {
+	dp := newFakeDatapath()
+	s := New(dp)
+	defer s.Close()
+
+	if err := s.AddTempRule(staticRule("203.0.113.7/32", "", "TCP", 443), 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if len(dp.ban) != 1 || len(s.TempRules()) != 1 {
+		t.Fatalf("temporary rule not installed: %v", dp.ban)
+	}
+
+	// resync rewrites what was removed behind our back
+	clear(dp.ban)
+	if err := s.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if len(dp.ban) != 1 {
+		t.Fatalf("resync did not restore the rule: %v", dp.ban)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(dp.ban) != 0 || len(dp.identity) != 0 || len(s.temp) != 0 {
+		t.Fatalf("temporary rule not expired: %v %v", dp.identity, dp.ban)
+	}
+}
+
+func TestLocalRulesAndReplace(t *testing.T) {
+	dp := newFakeDatapath()
+	s := New(dp)
+
+	s.PutOrchRule("/agent/rule/a/192.0.2.0/24/TCP/0-22/", xdp.IPRule{
+		CIDR: "192.0.2.0/24", Identity: "100", BannedProtocol: types.IPPROTO_TCP, Dport: 22,
+	})
+	kept := xdp.IPRule{CIDR: "203.0.113.0/24", Identity: "300", BannedProtocol: types.IPPROTO_TCP, Dport: 80}
+	s.PutOrchRule("/agent/rule/a/203.0.113.0/24/TCP/0-80/", kept)
+	err := s.SetStaticRules([]StaticRule{
+		staticRule("192.0.2.0/24", ActionDeny, "UDP", 53),
+		staticRule("10.0.0.0/8", ActionAllow, "", 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local, allowed := s.LocalRules()
+	if len(local) != 1 || local[0].Identity != "100" || local[0].Dport != 53 {
+		t.Fatalf("unexpected local rules %+v", local)
+	}
+	if len(allowed) != 1 || allowed[0] != "10.0.0.0/8" {
+		t.Fatalf("unexpected allowed cidrs %v", allowed)
+	}
+
+	// a missed delete event is repaired by replacing the orch rules, the
+	// rules kept are never lifted during the repair
+	dp.removed = nil
+	s.ReplaceOrchRules(map[string]xdp.IPRule{
+		"/agent/rule/a/198.51.100.0/24/TCP/0-80/": {CIDR: "198.51.100.0/24", Identity: "200", BannedProtocol: types.IPPROTO_TCP, Dport: 80},
+		"/agent/rule/a/203.0.113.0/24/TCP/0-80/":  kept,
+	})
+	if err := s.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if dp.identity["198.51.100.0/24"] != "200" || len(dp.ban) != 3 {
+		t.Fatalf("orch rules not replaced: %v %v", dp.identity, dp.ban)
+	}
+	if _, ok := dp.ban[banKey{identity: "100", protocol: types.IPPROTO_TCP, dport: 22}]; ok {
+		t.Fatal("stale orch rule is still installed")
+	}
+	for _, key := range dp.removed {
+		if key == (banKey{identity: kept.Identity, protocol: kept.BannedProtocol, dport: kept.Dport}) {
+			t.Fatal("repair lifted a kept rule")
+		}
+	}
+}
+
+func TestResyncInPlace(t *testing.T) {
+	dp := newFakeDatapath()
+	s := New(dp)
+
+	want := banKey{identity: "100", protocol: types.IPPROTO_TCP, dport: 22}
+	s.PutOrchRule("/agent/rule/a/192.0.2.0/24/TCP/0-22/", xdp.IPRule{
+		CIDR: "192.0.2.0/24", Identity: "100", BannedProtocol: types.IPPROTO_TCP, Dport: 22,
+	})
+	if err := s.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// entries written behind our back, one with a protocol without a name
+	dp.identity["198.51.100.0/24"] = "300"
+	dp.ban[banKey{identity: "300", protocol: 47}] = xdp.IPRule{}
+	dp.ban[banKey{identity: "100", protocol: types.IPPROTO_UDP, dport: 53}] = xdp.IPRule{}
+
+	if err := s.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range dp.removed {
+		if key == want {
+			t.Fatal("resync lifted a desired ban")
+		}
+	}
+	if len(dp.identity) != 1 || dp.identity["192.0.2.0/24"] != "100" {
+		t.Fatalf("stray identity not pruned: %v", dp.identity)
+	}
+	if _, ok := dp.ban[want]; !ok || len(dp.ban) != 1 {
+		t.Fatalf("stray bans not pruned: %v", dp.ban)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlFile := filepath.Join(dir, "rules.yaml")
+	err := os.WriteFile(yamlFile, []byte(`
+rules:
+  - cidr: 10.0.0.0/24
+    action: allow
+    comment: management network
+  - cidr: 192.0.2.0/24
+    protocol: TCP
+    dport: 22
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := Load(yamlFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].Action != ActionAllow || rules[1].Action != ActionDeny || rules[1].Dport != 22 {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+
+	jsonFile := filepath.Join(dir, "rules.json")
+	err = os.WriteFile(jsonFile, []byte(`{"rules":[{"cidr":"10.0.0.0/24","action":"allow","dport":22}]}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(jsonFile); err == nil {
+		t.Fatal("allow rule with ports should be rejected")
+	}
+}
@@ -11,13 +11,15 @@ import (
 )
 
 type ControlService struct {
-	fsm *statusfsm.StatusFSM
+	fsm  *statusfsm.StatusFSM
+	maps Maps
 	control.UnimplementedControlServiceServer
 }
 
-func NewControlService(fsm *statusfsm.StatusFSM) *ControlService {
+func NewControlService(fsm *statusfsm.StatusFSM, maps Maps) *ControlService {
 	return &ControlService{
-		fsm: fsm,
+		fsm:  fsm,
+		maps: maps,
 	}
 }
 
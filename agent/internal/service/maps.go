@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+
+	"xdp-banner/agent/ebpf/xdp"
+	"xdp-banner/api/agent/v1/control"
+	"xdp-banner/pkg/log"
+	"xdp-banner/pkg/server/common"
+)
+
+// MapsSnapshot is the live datapath content together with what the agent
+// wrote on its own, so the orchestrator can tell its rules apart
+type MapsSnapshot struct {
+	ConfigName   string
+	Dump         *xdp.MapDump
+	LocalRules   []xdp.IPRule
+	AllowedCIDRs []string
+}
+
+// Maps is implemented by the agent controller
+type Maps interface {
+	DumpMaps() (*MapsSnapshot, error)
+	// RepairMaps replaces the orchestrator rules of configName and rewrites the datapath
+	RepairMaps(configName string, rules map[string]xdp.IPRule) error
+}
+
+func (s *ControlService) DumpMaps(ctx context.Context, req *control.DumpMapsRequest) (*control.DumpMapsResponse, error) {
+	if req == nil {
+		return nil, common.InvalidArgumentError("request is nil")
+	}
+
+	snapshot, err := s.maps.DumpMaps()
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	resp := &control.DumpMapsResponse{
+		ConfigName:   snapshot.ConfigName,
+		Identities:   make([]*control.IdentityEntry, 0, len(snapshot.Dump.Identities)),
+		Bans:         make([]*control.BanEntry, 0, len(snapshot.Dump.Bans)),
+		Rules:        ipRulesToDto(snapshot.Dump.Rules),
+		LocalRules:   ipRulesToDto(snapshot.LocalRules),
+		AllowedCidrs: snapshot.AllowedCIDRs,
+	}
+	for _, i := range snapshot.Dump.Identities {
+		resp.Identities = append(resp.Identities, &control.IdentityEntry{Cidr: i.CIDR, Identity: i.Identity})
+	}
+	for _, b := range snapshot.Dump.Bans {
+		resp.Bans = append(resp.Bans, &control.BanEntry{
+			Identity:              b.Identity,
+			Protocol:              b.Protocol,
+			Sport:                 uint32(b.Sport),
+			Dport:                 uint32(b.Dport),
+			Prefixlen:             b.PrefixLen,
+			LatestAccessTimestamp: b.LatestAccessTimestamp,
+			RefuseTimes:           b.RefuseTimes,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *ControlService) RepairMaps(ctx context.Context, req *control.RepairMapsRequest) (*control.RepairMapsResponse, error) {
+	if req == nil {
+		return nil, common.InvalidArgumentError("request is nil")
+	} else if req.ConfigName == "" {
+		return nil, common.InvalidArgumentError("missing config name")
+	}
+
+	rules := make(map[string]xdp.IPRule, len(req.Rules))
+	for _, r := range req.Rules {
+		if r.Key == "" {
+			return nil, common.InvalidArgumentError("missing rule key")
+		}
+		if r.Protocol > 0xff || r.Sport > 0xffff || r.Dport > 0xffff {
+			return nil, common.InvalidArgumentError("invalid rule " + r.Key)
+		}
+		rules[r.Key] = xdp.IPRule{
+			CIDR:           r.Cidr,
+			Identity:       r.Identity,
+			BannedProtocol: uint8(r.Protocol),
+			Sport:          uint16(r.Sport),
+			Dport:          uint16(r.Dport),
+		}
+	}
+
+	log.Info("Received repair maps request", log.StringField("config_name", req.ConfigName), log.IntField("rules", len(rules)))
+	if err := s.maps.RepairMaps(req.ConfigName, rules); err != nil {
+		return nil, common.HandleError(err)
+	}
+	return &control.RepairMapsResponse{}, nil
+}
+
+func ipRulesToDto(rules []xdp.IPRule) []*control.MapRule {
+	dto := make([]*control.MapRule, 0, len(rules))
+	for _, r := range rules {
+		dto = append(dto, &control.MapRule{
+			Cidr:     r.CIDR,
+			Identity: r.Identity,
+			Protocol: uint32(r.BannedProtocol),
+			Sport:    uint32(r.Sport),
+			Dport:    uint32(r.Dport),
+		})
+	}
+	return dto
+}
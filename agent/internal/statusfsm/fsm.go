@@ -3,7 +3,11 @@ package statusfsm
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
 	"xdp-banner/agent/internal/client"
+	"xdp-banner/agent/internal/health"
 	"xdp-banner/api/orch/v1/agent/report"
 	"xdp-banner/pkg/log"
 	"xdp-banner/pkg/queue"
@@ -17,6 +21,14 @@ const (
 	Start  Action = "start"
 	Stop   Action = "stop"
 	Reload Action = "reload"
+
+	// health actions, only fired by SetHealth on a started agent
+	Degrade Action = "degrade"
+	Fail    Action = "fail"
+	Recover Action = "recover"
+
+	// ping is answered by the event loop itself, see Alive
+	ping Action = "ping"
 )
 
 type Event struct {
@@ -32,13 +44,22 @@ type StatusFSM struct {
 	queue queue.Queue[Event]
 	fsm   *fsm.FSM
 	done  chan struct{}
+
+	pong atomic.Int64 // unix nano of the last ping handled by the event loop
 }
 
 func New(startCallback fsm.Callback, stopCallback fsm.Callback, reloadCallback fsm.Callback) *StatusFSM {
+	running := report.Phase_Running.String()
+	degraded := report.Phase_Degraded.String()
+	failed := report.Phase_Failed.String()
+
 	events := fsm.Events{
-		{Name: Start, Src: []string{report.Phase_Ready.String(), report.Phase_Stopped.String()}, Dst: report.Phase_Running.String()},
-		{Name: Stop, Src: []string{report.Phase_Running.String(), report.Phase_Ready.String()}, Dst: report.Phase_Stopped.String()},
-		{Name: Reload, Src: []string{report.Phase_Running.String()}, Dst: report.Phase_Running.String()},
+		{Name: Start, Src: []string{report.Phase_Ready.String(), report.Phase_Stopped.String()}, Dst: running},
+		{Name: Stop, Src: []string{running, degraded, failed, report.Phase_Ready.String()}, Dst: report.Phase_Stopped.String()},
+		{Name: Reload, Src: []string{running, degraded, failed}, Dst: running},
+		{Name: Degrade, Src: []string{running, failed}, Dst: degraded},
+		{Name: Fail, Src: []string{running, degraded}, Dst: failed},
+		{Name: Recover, Src: []string{degraded, failed}, Dst: running},
 	}
 
 	callback := fsm.Callbacks{
@@ -62,6 +83,7 @@ func New(startCallback fsm.Callback, stopCallback fsm.Callback, reloadCallback f
 		fsm:   fsm,
 		done:  make(chan struct{}),
 	}
+	sf.pong.Store(time.Now().UnixNano())
 
 	go sf.run()
 
@@ -77,6 +99,10 @@ func (sf *StatusFSM) run() {
 		}
 
 		event := sf.queue.Pop()
+		if event.Action == ping {
+			sf.pong.Store(time.Now().UnixNano())
+			continue
+		}
 
 		err := sf.fsm.Event(context.Background(), event.Action, event.Args...)
 		if err != nil {
@@ -84,7 +110,13 @@ func (sf *StatusFSM) run() {
 			// but we allow this to happen,
 			// for example, reload event which is from Running to Running
 			if errors.As(err, &fsm.NoTransitionError{}) {
-				return
+				continue
+			}
+			// health events are queued asynchronously, the agent may have
+			// been stopped or reloaded in between, that is not an error
+			if isHealthAction(event.Action) && errors.As(err, &fsm.InvalidEventError{}) {
+				log.Debug("drop outdated health event", log.StringField("action", event.Action), log.AnyField("phase", sf.Current()))
+				continue
 			}
 			log.Warn("agent control", log.StringField("action", event.Action), log.AnyField("phase", sf.Current()), log.ErrorField(err))
 			client.SetError(err.Error())
@@ -106,3 +138,41 @@ func (sf *StatusFSM) Event(action Action, args ...any) {
 func (sf *StatusFSM) Current() report.Phase {
 	return report.ToPhase(sf.fsm.Current())
 }
+
+// SetHealth moves a started agent between Running, Degraded and Failed
+// according to the verdict, it does nothing for an agent which is not started.
+func (sf *StatusFSM) SetHealth(v health.Verdict) {
+	current := sf.Current()
+	if !report.IsActive(current) {
+		return
+	}
+
+	target, action := report.Phase_Running, Recover
+	switch v {
+	case health.Degraded:
+		target, action = report.Phase_Degraded, Degrade
+	case health.Failed:
+		target, action = report.Phase_Failed, Fail
+	}
+
+	if current != target {
+		sf.Event(action)
+	}
+}
+
+// Alive returns an error when the event loop did not handle a ping within
+// the duration, e.g. it is stuck in a callback. Each call queues the ping
+// answered before the next call.
+func (sf *StatusFSM) Alive(within time.Duration) error {
+	last := time.Unix(0, sf.pong.Load())
+	sf.Event(ping)
+
+	if since := time.Since(last); since > within {
+		return fmt.Errorf("event loop has not responded for %s", since.Truncate(time.Second))
+	}
+	return nil
+}
+
+func isHealthAction(action Action) bool {
+	return action == Degrade || action == Fail || action == Recover
+}
@@ -344,6 +344,452 @@ func (*ReloadResponse) Descriptor() ([]byte, []int) {
 	return file_control_proto_rawDescGZIP(), []int{7}
 }
 
+// Entry of identity_ipcache
+type IdentityEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cidr          string                 `protobuf:"bytes,1,opt,name=cidr,proto3" json:"cidr,omitempty"`
+	Identity      uint32                 `protobuf:"varint,2,opt,name=identity,proto3" json:"identity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IdentityEntry) Reset() {
+	*x = IdentityEntry{}
+	mi := &file_control_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IdentityEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IdentityEntry) ProtoMessage() {}
+
+func (x *IdentityEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IdentityEntry.ProtoReflect.Descriptor instead.
+func (*IdentityEntry) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *IdentityEntry) GetCidr() string {
+	if x != nil {
+		return x.Cidr
+	}
+	return ""
+}
+
+func (x *IdentityEntry) GetIdentity() uint32 {
+	if x != nil {
+		return x.Identity
+	}
+	return 0
+}
+
+// Entry of xdp_banner_banlist
+type BanEntry struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	Identity              uint32                 `protobuf:"varint,1,opt,name=identity,proto3" json:"identity,omitempty"`
+	Protocol              string                 `protobuf:"bytes,2,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	Sport                 uint32                 `protobuf:"varint,3,opt,name=sport,proto3" json:"sport,omitempty"`
+	Dport                 uint32                 `protobuf:"varint,4,opt,name=dport,proto3" json:"dport,omitempty"`
+	Prefixlen             uint32                 `protobuf:"varint,5,opt,name=prefixlen,proto3" json:"prefixlen,omitempty"`
+	LatestAccessTimestamp uint64                 `protobuf:"varint,6,opt,name=latest_access_timestamp,json=latestAccessTimestamp,proto3" json:"latest_access_timestamp,omitempty"`
+	RefuseTimes           uint64                 `protobuf:"varint,7,opt,name=refuse_times,json=refuseTimes,proto3" json:"refuse_times,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *BanEntry) Reset() {
+	*x = BanEntry{}
+	mi := &file_control_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BanEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BanEntry) ProtoMessage() {}
+
+func (x *BanEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BanEntry.ProtoReflect.Descriptor instead.
+func (*BanEntry) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *BanEntry) GetIdentity() uint32 {
+	if x != nil {
+		return x.Identity
+	}
+	return 0
+}
+
+func (x *BanEntry) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *BanEntry) GetSport() uint32 {
+	if x != nil {
+		return x.Sport
+	}
+	return 0
+}
+
+func (x *BanEntry) GetDport() uint32 {
+	if x != nil {
+		return x.Dport
+	}
+	return 0
+}
+
+func (x *BanEntry) GetPrefixlen() uint32 {
+	if x != nil {
+		return x.Prefixlen
+	}
+	return 0
+}
+
+func (x *BanEntry) GetLatestAccessTimestamp() uint64 {
+	if x != nil {
+		return x.LatestAccessTimestamp
+	}
+	return 0
+}
+
+func (x *BanEntry) GetRefuseTimes() uint64 {
+	if x != nil {
+		return x.RefuseTimes
+	}
+	return 0
+}
+
+// Rule decoded from the maps, or held by the agent
+type MapRule struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Cidr     string                 `protobuf:"bytes,1,opt,name=cidr,proto3" json:"cidr,omitempty"`
+	Identity string                 `protobuf:"bytes,2,opt,name=identity,proto3" json:"identity,omitempty"`
+	Protocol uint32                 `protobuf:"varint,3,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	Sport    uint32                 `protobuf:"varint,4,opt,name=sport,proto3" json:"sport,omitempty"`
+	Dport    uint32                 `protobuf:"varint,5,opt,name=dport,proto3" json:"dport,omitempty"`
+	// etcd key of the rule, only set for orchestrator rules
+	Key           string `protobuf:"bytes,6,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MapRule) Reset() {
+	*x = MapRule{}
+	mi := &file_control_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MapRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MapRule) ProtoMessage() {}
+
+func (x *MapRule) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MapRule.ProtoReflect.Descriptor instead.
+func (*MapRule) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *MapRule) GetCidr() string {
+	if x != nil {
+		return x.Cidr
+	}
+	return ""
+}
+
+func (x *MapRule) GetIdentity() string {
+	if x != nil {
+		return x.Identity
+	}
+	return ""
+}
+
+func (x *MapRule) GetProtocol() uint32 {
+	if x != nil {
+		return x.Protocol
+	}
+	return 0
+}
+
+func (x *MapRule) GetSport() uint32 {
+	if x != nil {
+		return x.Sport
+	}
+	return 0
+}
+
+func (x *MapRule) GetDport() uint32 {
+	if x != nil {
+		return x.Dport
+	}
+	return 0
+}
+
+func (x *MapRule) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type DumpMapsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DumpMapsRequest) Reset() {
+	*x = DumpMapsRequest{}
+	mi := &file_control_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DumpMapsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DumpMapsRequest) ProtoMessage() {}
+
+func (x *DumpMapsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DumpMapsRequest.ProtoReflect.Descriptor instead.
+func (*DumpMapsRequest) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{11}
+}
+
+type DumpMapsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// config the agent is running with, empty in standalone mode
+	ConfigName string           `protobuf:"bytes,1,opt,name=config_name,json=configName,proto3" json:"config_name,omitempty"`
+	Identities []*IdentityEntry `protobuf:"bytes,2,rep,name=identities,proto3" json:"identities,omitempty"`
+	Bans       []*BanEntry      `protobuf:"bytes,3,rep,name=bans,proto3" json:"bans,omitempty"`
+	// identities joined with bans
+	Rules []*MapRule `protobuf:"bytes,4,rep,name=rules,proto3" json:"rules,omitempty"`
+	// deny rules written by the agent itself (static file, temporary bans)
+	LocalRules []*MapRule `protobuf:"bytes,5,rep,name=local_rules,json=localRules,proto3" json:"local_rules,omitempty"`
+	// cidrs allowed by the static file, orchestrator rules inside them are not written
+	AllowedCidrs  []string `protobuf:"bytes,6,rep,name=allowed_cidrs,json=allowedCidrs,proto3" json:"allowed_cidrs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DumpMapsResponse) Reset() {
+	*x = DumpMapsResponse{}
+	mi := &file_control_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DumpMapsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DumpMapsResponse) ProtoMessage() {}
+
+func (x *DumpMapsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DumpMapsResponse.ProtoReflect.Descriptor instead.
+func (*DumpMapsResponse) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DumpMapsResponse) GetConfigName() string {
+	if x != nil {
+		return x.ConfigName
+	}
+	return ""
+}
+
+func (x *DumpMapsResponse) GetIdentities() []*IdentityEntry {
+	if x != nil {
+		return x.Identities
+	}
+	return nil
+}
+
+func (x *DumpMapsResponse) GetBans() []*BanEntry {
+	if x != nil {
+		return x.Bans
+	}
+	return nil
+}
+
+func (x *DumpMapsResponse) GetRules() []*MapRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+func (x *DumpMapsResponse) GetLocalRules() []*MapRule {
+	if x != nil {
+		return x.LocalRules
+	}
+	return nil
+}
+
+func (x *DumpMapsResponse) GetAllowedCidrs() []string {
+	if x != nil {
+		return x.AllowedCidrs
+	}
+	return nil
+}
+
+type RepairMapsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// must match the config the agent is running with
+	ConfigName string `protobuf:"bytes,1,opt,name=config_name,json=configName,proto3" json:"config_name,omitempty"`
+	// the full set of orchestrator rules of the config
+	Rules         []*MapRule `protobuf:"bytes,2,rep,name=rules,proto3" json:"rules,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RepairMapsRequest) Reset() {
+	*x = RepairMapsRequest{}
+	mi := &file_control_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RepairMapsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RepairMapsRequest) ProtoMessage() {}
+
+func (x *RepairMapsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RepairMapsRequest.ProtoReflect.Descriptor instead.
+func (*RepairMapsRequest) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *RepairMapsRequest) GetConfigName() string {
+	if x != nil {
+		return x.ConfigName
+	}
+	return ""
+}
+
+func (x *RepairMapsRequest) GetRules() []*MapRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+type RepairMapsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RepairMapsResponse) Reset() {
+	*x = RepairMapsResponse{}
+	mi := &file_control_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RepairMapsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RepairMapsResponse) ProtoMessage() {}
+
+func (x *RepairMapsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RepairMapsResponse.ProtoReflect.Descriptor instead.
+func (*RepairMapsResponse) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{14}
+}
+
 var File_control_proto protoreflect.FileDescriptor
 
 const file_control_proto_rawDesc = "" +
@@ -364,12 +810,50 @@ const file_control_proto_rawDesc = "" +
 	"\rReloadRequest\x12\x1f\n" +
 	"\vconfig_name\x18\x01 \x01(\tR\n" +
 	"configName\"\x10\n" +
-	"\x0eReloadResponse2\xfb\x01\n" +
+	"\x0eReloadResponse\"?\n" +
+	"\rIdentityEntry\x12\x12\n" +
+	"\x04cidr\x18\x01 \x01(\tR\x04cidr\x12\x1a\n" +
+	"\bidentity\x18\x02 \x01(\rR\bidentity\"\xe7\x01\n" +
+	"\bBanEntry\x12\x1a\n" +
+	"\bidentity\x18\x01 \x01(\rR\bidentity\x12\x1a\n" +
+	"\bprotocol\x18\x02 \x01(\tR\bprotocol\x12\x14\n" +
+	"\x05sport\x18\x03 \x01(\rR\x05sport\x12\x14\n" +
+	"\x05dport\x18\x04 \x01(\rR\x05dport\x12\x1c\n" +
+	"\tprefixlen\x18\x05 \x01(\rR\tprefixlen\x126\n" +
+	"\x17latest_access_timestamp\x18\x06 \x01(\x04R\x15latestAccessTimestamp\x12!\n" +
+	"\frefuse_times\x18\a \x01(\x04R\vrefuseTimes\"\x93\x01\n" +
+	"\aMapRule\x12\x12\n" +
+	"\x04cidr\x18\x01 \x01(\tR\x04cidr\x12\x1a\n" +
+	"\bidentity\x18\x02 \x01(\tR\bidentity\x12\x1a\n" +
+	"\bprotocol\x18\x03 \x01(\rR\bprotocol\x12\x14\n" +
+	"\x05sport\x18\x04 \x01(\rR\x05sport\x12\x14\n" +
+	"\x05dport\x18\x05 \x01(\rR\x05dport\x12\x10\n" +
+	"\x03key\x18\x06 \x01(\tR\x03key\"\x11\n" +
+	"\x0fDumpMapsRequest\"\x92\x02\n" +
+	"\x10DumpMapsResponse\x12\x1f\n" +
+	"\vconfig_name\x18\x01 \x01(\tR\n" +
+	"configName\x126\n" +
+	"\n" +
+	"identities\x18\x02 \x03(\v2\x16.control.IdentityEntryR\n" +
+	"identities\x12%\n" +
+	"\x04bans\x18\x03 \x03(\v2\x11.control.BanEntryR\x04bans\x12&\n" +
+	"\x05rules\x18\x04 \x03(\v2\x10.control.MapRuleR\x05rules\x121\n" +
+	"\vlocal_rules\x18\x05 \x03(\v2\x10.control.MapRuleR\n" +
+	"localRules\x12#\n" +
+	"\rallowed_cidrs\x18\x06 \x03(\tR\fallowedCidrs\"\\\n" +
+	"\x11RepairMapsRequest\x12\x1f\n" +
+	"\vconfig_name\x18\x01 \x01(\tR\n" +
+	"configName\x12&\n" +
+	"\x05rules\x18\x02 \x03(\v2\x10.control.MapRuleR\x05rules\"\x14\n" +
+	"\x12RepairMapsResponse2\x87\x03\n" +
 	"\x0eControlService\x128\n" +
 	"\x05Start\x12\x15.control.StartRequest\x1a\x16.control.StartResponse\"\x00\x125\n" +
 	"\x04Stop\x12\x14.control.StopRequest\x1a\x15.control.StopResponse\"\x00\x12;\n" +
 	"\x06Update\x12\x16.control.UpdateRequest\x1a\x17.control.UpdateResponse\"\x00\x12;\n" +
-	"\x06Reload\x12\x16.control.ReloadRequest\x1a\x17.control.ReloadResponse\"\x00B\x12Z\x10agent/v1/controlb\x06proto3"
+	"\x06Reload\x12\x16.control.ReloadRequest\x1a\x17.control.ReloadResponse\"\x00\x12A\n" +
+	"\bDumpMaps\x12\x18.control.DumpMapsRequest\x1a\x19.control.DumpMapsResponse\"\x00\x12G\n" +
+	"\n" +
+	"RepairMaps\x12\x1a.control.RepairMapsRequest\x1a\x1b.control.RepairMapsResponse\"\x00B\x12Z\x10agent/v1/controlb\x06proto3"
 
 var (
 	file_control_proto_rawDescOnce sync.Once
@@ -383,31 +867,47 @@ func file_control_proto_rawDescGZIP() []byte {
 	return file_control_proto_rawDescData
 }
 
-var file_control_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_control_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_control_proto_goTypes = []any{
-	(*StartRequest)(nil),   // 0: control.StartRequest
-	(*StartResponse)(nil),  // 1: control.StartResponse
-	(*StopRequest)(nil),    // 2: control.StopRequest
-	(*StopResponse)(nil),   // 3: control.StopResponse
-	(*UpdateRequest)(nil),  // 4: control.UpdateRequest
-	(*UpdateResponse)(nil), // 5: control.UpdateResponse
-	(*ReloadRequest)(nil),  // 6: control.ReloadRequest
-	(*ReloadResponse)(nil), // 7: control.ReloadResponse
+	(*StartRequest)(nil),       // 0: control.StartRequest
+	(*StartResponse)(nil),      // 1: control.StartResponse
+	(*StopRequest)(nil),        // 2: control.StopRequest
+	(*StopResponse)(nil),       // 3: control.StopResponse
+	(*UpdateRequest)(nil),      // 4: control.UpdateRequest
+	(*UpdateResponse)(nil),     // 5: control.UpdateResponse
+	(*ReloadRequest)(nil),      // 6: control.ReloadRequest
+	(*ReloadResponse)(nil),     // 7: control.ReloadResponse
+	(*IdentityEntry)(nil),      // 8: control.IdentityEntry
+	(*BanEntry)(nil),           // 9: control.BanEntry
+	(*MapRule)(nil),            // 10: control.MapRule
+	(*DumpMapsRequest)(nil),    // 11: control.DumpMapsRequest
+	(*DumpMapsResponse)(nil),   // 12: control.DumpMapsResponse
+	(*RepairMapsRequest)(nil),  // 13: control.RepairMapsRequest
+	(*RepairMapsResponse)(nil), // 14: control.RepairMapsResponse
 }
 var file_control_proto_depIdxs = []int32{
-	0, // 0: control.ControlService.Start:input_type -> control.StartRequest
-	2, // 1: control.ControlService.Stop:input_type -> control.StopRequest
-	4, // 2: control.ControlService.Update:input_type -> control.UpdateRequest
-	6, // 3: control.ControlService.Reload:input_type -> control.ReloadRequest
-	1, // 4: control.ControlService.Start:output_type -> control.StartResponse
-	3, // 5: control.ControlService.Stop:output_type -> control.StopResponse
-	5, // 6: control.ControlService.Update:output_type -> control.UpdateResponse
-	7, // 7: control.ControlService.Reload:output_type -> control.ReloadResponse
-	4, // [4:8] is the sub-list for method output_type
-	0, // [0:4] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	8,  // 0: control.DumpMapsResponse.identities:type_name -> control.IdentityEntry
+	9,  // 1: control.DumpMapsResponse.bans:type_name -> control.BanEntry
+	10, // 2: control.DumpMapsResponse.rules:type_name -> control.MapRule
+	10, // 3: control.DumpMapsResponse.local_rules:type_name -> control.MapRule
+	10, // 4: control.RepairMapsRequest.rules:type_name -> control.MapRule
+	0,  // 5: control.ControlService.Start:input_type -> control.StartRequest
+	2,  // 6: control.ControlService.Stop:input_type -> control.StopRequest
+	4,  // 7: control.ControlService.Update:input_type -> control.UpdateRequest
+	6,  // 8: control.ControlService.Reload:input_type -> control.ReloadRequest
+	11, // 9: control.ControlService.DumpMaps:input_type -> control.DumpMapsRequest
+	13, // 10: control.ControlService.RepairMaps:input_type -> control.RepairMapsRequest
+	1,  // 11: control.ControlService.Start:output_type -> control.StartResponse
+	3,  // 12: control.ControlService.Stop:output_type -> control.StopResponse
+	5,  // 13: control.ControlService.Update:output_type -> control.UpdateResponse
+	7,  // 14: control.ControlService.Reload:output_type -> control.ReloadResponse
+	12, // 15: control.ControlService.DumpMaps:output_type -> control.DumpMapsResponse
+	14, // 16: control.ControlService.RepairMaps:output_type -> control.RepairMapsResponse
+	11, // [11:17] is the sub-list for method output_type
+	5,  // [5:11] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_control_proto_init() }
@@ -421,7 +921,7 @@ func file_control_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_control_proto_rawDesc), len(file_control_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
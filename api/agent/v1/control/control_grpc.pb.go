@@ -19,10 +19,12 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ControlService_Start_FullMethodName  = "/control.ControlService/Start"
-	ControlService_Stop_FullMethodName   = "/control.ControlService/Stop"
-	ControlService_Update_FullMethodName = "/control.ControlService/Update"
-	ControlService_Reload_FullMethodName = "/control.ControlService/Reload"
+	ControlService_Start_FullMethodName      = "/control.ControlService/Start"
+	ControlService_Stop_FullMethodName       = "/control.ControlService/Stop"
+	ControlService_Update_FullMethodName     = "/control.ControlService/Update"
+	ControlService_Reload_FullMethodName     = "/control.ControlService/Reload"
+	ControlService_DumpMaps_FullMethodName   = "/control.ControlService/DumpMaps"
+	ControlService_RepairMaps_FullMethodName = "/control.ControlService/RepairMaps"
 )
 
 // ControlServiceClient is the client API for ControlService service.
@@ -38,6 +40,10 @@ type ControlServiceClient interface {
 	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateResponse, error)
 	// Reload the agent
 	Reload(ctx context.Context, in *ReloadRequest, opts ...grpc.CallOption) (*ReloadResponse, error)
+	// Dump the decoded contents of the datapath maps
+	DumpMaps(ctx context.Context, in *DumpMapsRequest, opts ...grpc.CallOption) (*DumpMapsResponse, error)
+	// Replace the orchestrator rules held by the agent and rewrite the datapath
+	RepairMaps(ctx context.Context, in *RepairMapsRequest, opts ...grpc.CallOption) (*RepairMapsResponse, error)
 }
 
 type controlServiceClient struct {
@@ -88,6 +94,26 @@ func (c *controlServiceClient) Reload(ctx context.Context, in *ReloadRequest, op
 	return out, nil
 }
 
+func (c *controlServiceClient) DumpMaps(ctx context.Context, in *DumpMapsRequest, opts ...grpc.CallOption) (*DumpMapsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DumpMapsResponse)
+	err := c.cc.Invoke(ctx, ControlService_DumpMaps_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) RepairMaps(ctx context.Context, in *RepairMapsRequest, opts ...grpc.CallOption) (*RepairMapsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RepairMapsResponse)
+	err := c.cc.Invoke(ctx, ControlService_RepairMaps_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ControlServiceServer is the server API for ControlService service.
 // All implementations must embed UnimplementedControlServiceServer
 // for forward compatibility.
@@ -101,6 +127,10 @@ type ControlServiceServer interface {
 	Update(context.Context, *UpdateRequest) (*UpdateResponse, error)
 	// Reload the agent
 	Reload(context.Context, *ReloadRequest) (*ReloadResponse, error)
+	// Dump the decoded contents of the datapath maps
+	DumpMaps(context.Context, *DumpMapsRequest) (*DumpMapsResponse, error)
+	// Replace the orchestrator rules held by the agent and rewrite the datapath
+	RepairMaps(context.Context, *RepairMapsRequest) (*RepairMapsResponse, error)
 	mustEmbedUnimplementedControlServiceServer()
 }
 
@@ -123,6 +153,12 @@ func (UnimplementedControlServiceServer) Update(context.Context, *UpdateRequest)
 func (UnimplementedControlServiceServer) Reload(context.Context, *ReloadRequest) (*ReloadResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Reload not implemented")
 }
+func (UnimplementedControlServiceServer) DumpMaps(context.Context, *DumpMapsRequest) (*DumpMapsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DumpMaps not implemented")
+}
+func (UnimplementedControlServiceServer) RepairMaps(context.Context, *RepairMapsRequest) (*RepairMapsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RepairMaps not implemented")
+}
 func (UnimplementedControlServiceServer) mustEmbedUnimplementedControlServiceServer() {}
 func (UnimplementedControlServiceServer) testEmbeddedByValue()                        {}
 
@@ -216,6 +252,42 @@ func _ControlService_Reload_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ControlService_DumpMaps_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DumpMapsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).DumpMaps(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ControlService_DumpMaps_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).DumpMaps(ctx, req.(*DumpMapsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_RepairMaps_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RepairMapsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).RepairMaps(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ControlService_RepairMaps_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).RepairMaps(ctx, req.(*RepairMapsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ControlService_ServiceDesc is the grpc.ServiceDesc for ControlService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -239,6 +311,14 @@ var ControlService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Reload",
 			Handler:    _ControlService_Reload_Handler,
 		},
+		{
+			MethodName: "DumpMaps",
+			Handler:    _ControlService_DumpMaps_Handler,
+		},
+		{
+			MethodName: "RepairMaps",
+			Handler:    _ControlService_RepairMaps_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "control.proto",
@@ -23,6 +23,7 @@ const (
 	ControlService_Unregister_FullMethodName       = "/agent.control.ControlService/Unregister"
 	ControlService_ListRegistration_FullMethodName = "/agent.control.ControlService/ListRegistration"
 	ControlService_Init_FullMethodName             = "/agent.control.ControlService/Init"
+	ControlService_RenewCertificate_FullMethodName = "/agent.control.ControlService/RenewCertificate"
 	ControlService_Enable_FullMethodName           = "/agent.control.ControlService/Enable"
 	ControlService_SetConfig_FullMethodName        = "/agent.control.ControlService/SetConfig"
 	ControlService_GetConfig_FullMethodName        = "/agent.control.ControlService/GetConfig"
@@ -32,6 +33,7 @@ const (
 	ControlService_GetInfo_FullMethodName          = "/agent.control.ControlService/GetInfo"
 	ControlService_GetAgent_FullMethodName         = "/agent.control.ControlService/GetAgent"
 	ControlService_ListAgents_FullMethodName       = "/agent.control.ControlService/ListAgents"
+	ControlService_CheckDatapath_FullMethodName    = "/agent.control.ControlService/CheckDatapath"
 )
 
 // ControlServiceClient is the client API for ControlService service.
@@ -48,6 +50,8 @@ type ControlServiceClient interface {
 	ListRegistration(ctx context.Context, in *ListRegistrationRequest, opts ...grpc.CallOption) (*ListRegistrationResponse, error)
 	// Agent initializes itself by providing the registration token
 	Init(ctx context.Context, in *InitRequest, opts ...grpc.CallOption) (*InitResponse, error)
+	// Agent renews its certificate, authenticated by its current certificate
+	RenewCertificate(ctx context.Context, in *RenewCertificateRequest, opts ...grpc.CallOption) (*RenewCertificateResponse, error)
 	// Enable or disable an agent by the admin
 	Enable(ctx context.Context, in *EnableRequest, opts ...grpc.CallOption) (*EnableResponse, error)
 	// Set the config name to an agent
@@ -66,6 +70,8 @@ type ControlServiceClient interface {
 	GetAgent(ctx context.Context, in *GetAgentRequest, opts ...grpc.CallOption) (*GetAgentResponse, error)
 	// List all agents
 	ListAgents(ctx context.Context, in *ListAgentsRequest, opts ...grpc.CallOption) (*ListAgentsResponse, error)
+	// Compare the datapath maps of agents with the rules of their config
+	CheckDatapath(ctx context.Context, in *CheckDatapathRequest, opts ...grpc.CallOption) (*CheckDatapathResponse, error)
 }
 
 type controlServiceClient struct {
@@ -116,6 +122,16 @@ func (c *controlServiceClient) Init(ctx context.Context, in *InitRequest, opts .
 	return out, nil
 }
 
+func (c *controlServiceClient) RenewCertificate(ctx context.Context, in *RenewCertificateRequest, opts ...grpc.CallOption) (*RenewCertificateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RenewCertificateResponse)
+	err := c.cc.Invoke(ctx, ControlService_RenewCertificate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *controlServiceClient) Enable(ctx context.Context, in *EnableRequest, opts ...grpc.CallOption) (*EnableResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(EnableResponse)
@@ -206,6 +222,16 @@ func (c *controlServiceClient) ListAgents(ctx context.Context, in *ListAgentsReq
 	return out, nil
 }
 
+func (c *controlServiceClient) CheckDatapath(ctx context.Context, in *CheckDatapathRequest, opts ...grpc.CallOption) (*CheckDatapathResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckDatapathResponse)
+	err := c.cc.Invoke(ctx, ControlService_CheckDatapath_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ControlServiceServer is the server API for ControlService service.
 // All implementations must embed UnimplementedControlServiceServer
 // for forward compatibility.
@@ -220,6 +246,8 @@ type ControlServiceServer interface {
 	ListRegistration(context.Context, *ListRegistrationRequest) (*ListRegistrationResponse, error)
 	// Agent initializes itself by providing the registration token
 	Init(context.Context, *InitRequest) (*InitResponse, error)
+	// Agent renews its certificate, authenticated by its current certificate
+	RenewCertificate(context.Context, *RenewCertificateRequest) (*RenewCertificateResponse, error)
 	// Enable or disable an agent by the admin
 	Enable(context.Context, *EnableRequest) (*EnableResponse, error)
 	// Set the config name to an agent
@@ -238,6 +266,8 @@ type ControlServiceServer interface {
 	GetAgent(context.Context, *GetAgentRequest) (*GetAgentResponse, error)
 	// List all agents
 	ListAgents(context.Context, *ListAgentsRequest) (*ListAgentsResponse, error)
+	// Compare the datapath maps of agents with the rules of their config
+	CheckDatapath(context.Context, *CheckDatapathRequest) (*CheckDatapathResponse, error)
 	mustEmbedUnimplementedControlServiceServer()
 }
 
@@ -260,6 +290,9 @@ func (UnimplementedControlServiceServer) ListRegistration(context.Context, *List
 func (UnimplementedControlServiceServer) Init(context.Context, *InitRequest) (*InitResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Init not implemented")
 }
+func (UnimplementedControlServiceServer) RenewCertificate(context.Context, *RenewCertificateRequest) (*RenewCertificateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenewCertificate not implemented")
+}
 func (UnimplementedControlServiceServer) Enable(context.Context, *EnableRequest) (*EnableResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Enable not implemented")
 }
@@ -287,6 +320,9 @@ func (UnimplementedControlServiceServer) GetAgent(context.Context, *GetAgentRequ
 func (UnimplementedControlServiceServer) ListAgents(context.Context, *ListAgentsRequest) (*ListAgentsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListAgents not implemented")
 }
+func (UnimplementedControlServiceServer) CheckDatapath(context.Context, *CheckDatapathRequest) (*CheckDatapathResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckDatapath not implemented")
+}
 func (UnimplementedControlServiceServer) mustEmbedUnimplementedControlServiceServer() {}
 func (UnimplementedControlServiceServer) testEmbeddedByValue()                        {}
 
@@ -380,6 +416,24 @@ func _ControlService_Init_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ControlService_RenewCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).RenewCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ControlService_RenewCertificate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).RenewCertificate(ctx, req.(*RenewCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ControlService_Enable_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(EnableRequest)
 	if err := dec(in); err != nil {
@@ -542,6 +596,24 @@ func _ControlService_ListAgents_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ControlService_CheckDatapath_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckDatapathRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).CheckDatapath(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ControlService_CheckDatapath_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).CheckDatapath(ctx, req.(*CheckDatapathRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ControlService_ServiceDesc is the grpc.ServiceDesc for ControlService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -565,6 +637,10 @@ var ControlService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Init",
 			Handler:    _ControlService_Init_Handler,
 		},
+		{
+			MethodName: "RenewCertificate",
+			Handler:    _ControlService_RenewCertificate_Handler,
+		},
 		{
 			MethodName: "Enable",
 			Handler:    _ControlService_Enable_Handler,
@@ -601,6 +677,10 @@ var ControlService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListAgents",
 			Handler:    _ControlService_ListAgents_Handler,
 		},
+		{
+			MethodName: "CheckDatapath",
+			Handler:    _ControlService_CheckDatapath_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "orch/v1/agent/control/control.proto",
@@ -10,6 +10,10 @@ func String(p Phase) string {
 		return "Running"
 	case Phase_Stopped:
 		return "Stopped"
+	case Phase_Degraded:
+		return "Degraded"
+	case Phase_Failed:
+		return "Failed"
 	default:
 		return "Unknown"
 	}
@@ -25,7 +29,17 @@ func ToPhase(s string) Phase {
 		return Phase_Running
 	case "Stopped":
 		return Phase_Stopped
+	case "Degraded":
+		return Phase_Degraded
+	case "Failed":
+		return Phase_Failed
 	default:
 		return Phase_Unknown
 	}
 }
+
+// IsActive reports whether the agent is started with a config, Degraded and
+// Failed agents are still running it.
+func IsActive(p Phase) bool {
+	return p == Phase_Running || p == Phase_Degraded || p == Phase_Failed
+}
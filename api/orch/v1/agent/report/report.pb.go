@@ -24,10 +24,12 @@ const (
 type Phase int32
 
 const (
-	Phase_Unknown Phase = 0
-	Phase_Ready   Phase = 1
-	Phase_Running Phase = 2
-	Phase_Stopped Phase = 3
+	Phase_Unknown  Phase = 0
+	Phase_Ready    Phase = 1
+	Phase_Running  Phase = 2
+	Phase_Stopped  Phase = 3
+	Phase_Degraded Phase = 4 // running, but some condition is unhealthy
+	Phase_Failed   Phase = 5 // running, but not filtering any traffic
 )
 
 // Enum value maps for Phase.
@@ -37,12 +39,16 @@ var (
 		1: "Ready",
 		2: "Running",
 		3: "Stopped",
+		4: "Degraded",
+		5: "Failed",
 	}
 	Phase_value = map[string]int32{
-		"Unknown": 0,
-		"Ready":   1,
-		"Running": 2,
-		"Stopped": 3,
+		"Unknown":  0,
+		"Ready":    1,
+		"Running":  2,
+		"Stopped":  3,
+		"Degraded": 4,
+		"Failed":   5,
 	}
 )
 
@@ -134,11 +140,15 @@ type Status struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Name         string     `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	GrpcEndpoint string     `protobuf:"bytes,2,opt,name=grpc_endpoint,json=grpcEndpoint,proto3" json:"grpc_endpoint,omitempty"`
-	ConfigName   string     `protobuf:"bytes,3,opt,name=config_name,json=configName,proto3" json:"config_name,omitempty"`
-	Phase        Phase      `protobuf:"varint,4,opt,name=phase,proto3,enum=agent.reoprt.Phase" json:"phase,omitempty"`
-	Error        *ErrorTime `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	Name         string             `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	GrpcEndpoint string             `protobuf:"bytes,2,opt,name=grpc_endpoint,json=grpcEndpoint,proto3" json:"grpc_endpoint,omitempty"`
+	ConfigName   string             `protobuf:"bytes,3,opt,name=config_name,json=configName,proto3" json:"config_name,omitempty"`
+	Phase        Phase              `protobuf:"varint,4,opt,name=phase,proto3,enum=agent.reoprt.Phase" json:"phase,omitempty"`
+	Error        *ErrorTime         `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	Conditions   []*Condition       `protobuf:"bytes,6,rep,name=conditions,proto3" json:"conditions,omitempty"`
+	Interfaces   []*InterfaceHealth `protobuf:"bytes,7,rep,name=interfaces,proto3" json:"interfaces,omitempty"`
+	Maps         []*MapUsage        `protobuf:"bytes,8,rep,name=maps,proto3" json:"maps,omitempty"`
+	Capabilities *HostCapabilities  `protobuf:"bytes,9,opt,name=capabilities,proto3" json:"capabilities,omitempty"`
 }
 
 func (x *Status) Reset() {
@@ -208,6 +218,434 @@ func (x *Status) GetError() *ErrorTime {
 	return nil
 }
 
+func (x *Status) GetConditions() []*Condition {
+	if x != nil {
+		return x.Conditions
+	}
+	return nil
+}
+
+func (x *Status) GetInterfaces() []*InterfaceHealth {
+	if x != nil {
+		return x.Interfaces
+	}
+	return nil
+}
+
+func (x *Status) GetMaps() []*MapUsage {
+	if x != nil {
+		return x.Maps
+	}
+	return nil
+}
+
+func (x *Status) GetCapabilities() *HostCapabilities {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+// Condition is one aspect of the agent health, e.g. InterfacesAttached,
+// RuleStreamConnected or MapUsage
+type Condition struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type               string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Status             bool                   `protobuf:"varint,2,opt,name=status,proto3" json:"status,omitempty"` // true when healthy
+	Reason             string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	Message            string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	LastTransitionTime *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=last_transition_time,json=lastTransitionTime,proto3" json:"last_transition_time,omitempty"`
+}
+
+func (x *Condition) Reset() {
+	*x = Condition{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_orch_v1_agent_report_report_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Condition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Condition) ProtoMessage() {}
+
+func (x *Condition) ProtoReflect() protoreflect.Message {
+	mi := &file_orch_v1_agent_report_report_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Condition.ProtoReflect.Descriptor instead.
+func (*Condition) Descriptor() ([]byte, []int) {
+	return file_orch_v1_agent_report_report_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Condition) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Condition) GetStatus() bool {
+	if x != nil {
+		return x.Status
+	}
+	return false
+}
+
+func (x *Condition) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Condition) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Condition) GetLastTransitionTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastTransitionTime
+	}
+	return nil
+}
+
+type InterfaceHealth struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name     string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Attached bool   `protobuf:"varint,2,opt,name=attached,proto3" json:"attached,omitempty"`
+	Error    string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	// up is the link state, it changes without a new capability probe
+	Up bool `protobuf:"varint,4,opt,name=up,proto3" json:"up,omitempty"`
+}
+
+func (x *InterfaceHealth) Reset() {
+	*x = InterfaceHealth{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_orch_v1_agent_report_report_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InterfaceHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InterfaceHealth) ProtoMessage() {}
+
+func (x *InterfaceHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_orch_v1_agent_report_report_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InterfaceHealth.ProtoReflect.Descriptor instead.
+func (*InterfaceHealth) Descriptor() ([]byte, []int) {
+	return file_orch_v1_agent_report_report_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *InterfaceHealth) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *InterfaceHealth) GetAttached() bool {
+	if x != nil {
+		return x.Attached
+	}
+	return false
+}
+
+func (x *InterfaceHealth) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *InterfaceHealth) GetUp() bool {
+	if x != nil {
+		return x.Up
+	}
+	return false
+}
+
+type MapUsage struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name       string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Entries    uint32 `protobuf:"varint,2,opt,name=entries,proto3" json:"entries,omitempty"`
+	MaxEntries uint32 `protobuf:"varint,3,opt,name=max_entries,json=maxEntries,proto3" json:"max_entries,omitempty"`
+}
+
+func (x *MapUsage) Reset() {
+	*x = MapUsage{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_orch_v1_agent_report_report_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MapUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MapUsage) ProtoMessage() {}
+
+func (x *MapUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_orch_v1_agent_report_report_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MapUsage.ProtoReflect.Descriptor instead.
+func (*MapUsage) Descriptor() ([]byte, []int) {
+	return file_orch_v1_agent_report_report_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *MapUsage) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *MapUsage) GetEntries() uint32 {
+	if x != nil {
+		return x.Entries
+	}
+	return 0
+}
+
+func (x *MapUsage) GetMaxEntries() uint32 {
+	if x != nil {
+		return x.MaxEntries
+	}
+	return 0
+}
+
+// HostCapabilities is what the agent probed on its host, sent at join and
+// with every report
+type HostCapabilities struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	KernelVersion string                 `protobuf:"bytes,1,opt,name=kernel_version,json=kernelVersion,proto3" json:"kernel_version,omitempty"` // e.g. 5.15.0-91-generic
+	Arch          string                 `protobuf:"bytes,2,opt,name=arch,proto3" json:"arch,omitempty"`
+	CpuCount      int32                  `protobuf:"varint,3,opt,name=cpu_count,json=cpuCount,proto3" json:"cpu_count,omitempty"`
+	Btf           bool                   `protobuf:"varint,4,opt,name=btf,proto3" json:"btf,omitempty"`                                                                                                                            // /sys/kernel/btf/vmlinux is present
+	BpfFeatures   map[string]bool        `protobuf:"bytes,5,rep,name=bpf_features,json=bpfFeatures,proto3" json:"bpf_features,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"` // e.g. prog_type/xdp, map_type/lpm_trie
+	Nics          []*NicCapability       `protobuf:"bytes,6,rep,name=nics,proto3" json:"nics,omitempty"`
+	ProbedAt      *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=probed_at,json=probedAt,proto3" json:"probed_at,omitempty"`
+}
+
+func (x *HostCapabilities) Reset() {
+	*x = HostCapabilities{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_orch_v1_agent_report_report_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HostCapabilities) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostCapabilities) ProtoMessage() {}
+
+func (x *HostCapabilities) ProtoReflect() protoreflect.Message {
+	mi := &file_orch_v1_agent_report_report_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostCapabilities.ProtoReflect.Descriptor instead.
+func (*HostCapabilities) Descriptor() ([]byte, []int) {
+	return file_orch_v1_agent_report_report_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *HostCapabilities) GetKernelVersion() string {
+	if x != nil {
+		return x.KernelVersion
+	}
+	return ""
+}
+
+func (x *HostCapabilities) GetArch() string {
+	if x != nil {
+		return x.Arch
+	}
+	return ""
+}
+
+func (x *HostCapabilities) GetCpuCount() int32 {
+	if x != nil {
+		return x.CpuCount
+	}
+	return 0
+}
+
+func (x *HostCapabilities) GetBtf() bool {
+	if x != nil {
+		return x.Btf
+	}
+	return false
+}
+
+func (x *HostCapabilities) GetBpfFeatures() map[string]bool {
+	if x != nil {
+		return x.BpfFeatures
+	}
+	return nil
+}
+
+func (x *HostCapabilities) GetNics() []*NicCapability {
+	if x != nil {
+		return x.Nics
+	}
+	return nil
+}
+
+func (x *HostCapabilities) GetProbedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ProbedAt
+	}
+	return nil
+}
+
+type NicCapability struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name      string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Driver    string `protobuf:"bytes,2,opt,name=driver,proto3" json:"driver,omitempty"`
+	RxQueues  uint32 `protobuf:"varint,3,opt,name=rx_queues,json=rxQueues,proto3" json:"rx_queues,omitempty"`
+	TxQueues  uint32 `protobuf:"varint,4,opt,name=tx_queues,json=txQueues,proto3" json:"tx_queues,omitempty"`
+	XdpNative bool   `protobuf:"varint,5,opt,name=xdp_native,json=xdpNative,proto3" json:"xdp_native,omitempty"` // the driver supports native (driver mode) XDP
+	Up        bool   `protobuf:"varint,6,opt,name=up,proto3" json:"up,omitempty"`
+}
+
+func (x *NicCapability) Reset() {
+	*x = NicCapability{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_orch_v1_agent_report_report_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NicCapability) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NicCapability) ProtoMessage() {}
+
+func (x *NicCapability) ProtoReflect() protoreflect.Message {
+	mi := &file_orch_v1_agent_report_report_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NicCapability.ProtoReflect.Descriptor instead.
+func (*NicCapability) Descriptor() ([]byte, []int) {
+	return file_orch_v1_agent_report_report_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *NicCapability) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *NicCapability) GetDriver() string {
+	if x != nil {
+		return x.Driver
+	}
+	return ""
+}
+
+func (x *NicCapability) GetRxQueues() uint32 {
+	if x != nil {
+		return x.RxQueues
+	}
+	return 0
+}
+
+func (x *NicCapability) GetTxQueues() uint32 {
+	if x != nil {
+		return x.TxQueues
+	}
+	return 0
+}
+
+func (x *NicCapability) GetXdpNative() bool {
+	if x != nil {
+		return x.XdpNative
+	}
+	return false
+}
+
+func (x *NicCapability) GetUp() bool {
+	if x != nil {
+		return x.Up
+	}
+	return false
+}
+
 type ReportResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -217,7 +655,7 @@ type ReportResponse struct {
 func (x *ReportResponse) Reset() {
 	*x = ReportResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_orch_v1_agent_report_report_proto_msgTypes[2]
+		mi := &file_orch_v1_agent_report_report_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -230,7 +668,7 @@ func (x *ReportResponse) String() string {
 func (*ReportResponse) ProtoMessage() {}
 
 func (x *ReportResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_orch_v1_agent_report_report_proto_msgTypes[2]
+	mi := &file_orch_v1_agent_report_report_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -243,7 +681,7 @@ func (x *ReportResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportResponse.ProtoReflect.Descriptor instead.
 func (*ReportResponse) Descriptor() ([]byte, []int) {
-	return file_orch_v1_agent_report_report_proto_rawDescGZIP(), []int{2}
+	return file_orch_v1_agent_report_report_proto_rawDescGZIP(), []int{7}
 }
 
 var File_orch_v1_agent_report_report_proto protoreflect.FileDescriptor
@@ -260,7 +698,7 @@ var file_orch_v1_agent_report_report_proto_rawDesc = []byte{
 	0x72, 0x79, 0x5f, 0x61, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f,
 	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69,
 	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x07, 0x72, 0x65, 0x74, 0x72, 0x79, 0x41, 0x74,
-	0x22, 0xbc, 0x01, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x22, 0xa4, 0x03, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x6e,
 	0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
 	0x23, 0x0a, 0x0d, 0x67, 0x72, 0x70, 0x63, 0x5f, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74,
 	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x67, 0x72, 0x70, 0x63, 0x45, 0x6e, 0x64, 0x70,
@@ -271,19 +709,93 @@ var file_orch_v1_agent_report_report_proto_rawDesc = []byte{
 	0x70, 0x72, 0x74, 0x2e, 0x50, 0x68, 0x61, 0x73, 0x65, 0x52, 0x05, 0x70, 0x68, 0x61, 0x73, 0x65,
 	0x12, 0x2d, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32,
 	0x17, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x72, 0x65, 0x6f, 0x70, 0x72, 0x74, 0x2e, 0x45,
-	0x72, 0x72, 0x6f, 0x72, 0x54, 0x69, 0x6d, 0x65, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22,
-	0x10, 0x0a, 0x0e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x2a, 0x39, 0x0a, 0x05, 0x50, 0x68, 0x61, 0x73, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x55, 0x6e,
-	0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x10, 0x00, 0x12, 0x09, 0x0a, 0x05, 0x52, 0x65, 0x61, 0x64, 0x79,
-	0x10, 0x01, 0x12, 0x0b, 0x0a, 0x07, 0x52, 0x75, 0x6e, 0x6e, 0x69, 0x6e, 0x67, 0x10, 0x02, 0x12,
-	0x0b, 0x0a, 0x07, 0x53, 0x74, 0x6f, 0x70, 0x70, 0x65, 0x64, 0x10, 0x03, 0x32, 0x4d, 0x0a, 0x0d,
-	0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x3c, 0x0a,
-	0x06, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x14, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e,
-	0x72, 0x65, 0x6f, 0x70, 0x72, 0x74, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x1a, 0x1c, 0x2e,
-	0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x72, 0x65, 0x6f, 0x70, 0x72, 0x74, 0x2e, 0x52, 0x65, 0x70,
-	0x6f, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x16, 0x5a, 0x14, 0x6f,
-	0x72, 0x63, 0x68, 0x2f, 0x76, 0x31, 0x2f, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2f, 0x72, 0x65, 0x70,
-	0x6f, 0x72, 0x74, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x72, 0x72, 0x6f, 0x72, 0x54, 0x69, 0x6d, 0x65, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12,
+	0x37, 0x0a, 0x0a, 0x63, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x06, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x72, 0x65, 0x6f, 0x70,
+	0x72, 0x74, 0x2e, 0x43, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0a, 0x63, 0x6f,
+	0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x3d, 0x0a, 0x0a, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x66, 0x61, 0x63, 0x65, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x61,
+	0x67, 0x65, 0x6e, 0x74, 0x2e, 0x72, 0x65, 0x6f, 0x70, 0x72, 0x74, 0x2e, 0x49, 0x6e, 0x74, 0x65,
+	0x72, 0x66, 0x61, 0x63, 0x65, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x52, 0x0a, 0x69, 0x6e, 0x74,
+	0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x73, 0x12, 0x2a, 0x0a, 0x04, 0x6d, 0x61, 0x70, 0x73, 0x18,
+	0x08, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x72, 0x65,
+	0x6f, 0x70, 0x72, 0x74, 0x2e, 0x4d, 0x61, 0x70, 0x55, 0x73, 0x61, 0x67, 0x65, 0x52, 0x04, 0x6d,
+	0x61, 0x70, 0x73, 0x12, 0x42, 0x0a, 0x0c, 0x63, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74,
+	0x69, 0x65, 0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x61, 0x67, 0x65, 0x6e,
+	0x74, 0x2e, 0x72, 0x65, 0x6f, 0x70, 0x72, 0x74, 0x2e, 0x48, 0x6f, 0x73, 0x74, 0x43, 0x61, 0x70,
+	0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x52, 0x0c, 0x63, 0x61, 0x70, 0x61, 0x62,
+	0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x22, 0xb7, 0x01, 0x0a, 0x09, 0x43, 0x6f, 0x6e, 0x64,
+	0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61,
+	0x74, 0x75, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73,
+	0x73, 0x61, 0x67, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x12, 0x4c, 0x0a, 0x14, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x74, 0x72, 0x61, 0x6e,
+	0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x12, 0x6c,
+	0x61, 0x73, 0x74, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x69, 0x6d,
+	0x65, 0x22, 0x67, 0x0a, 0x0f, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x48, 0x65,
+	0x61, 0x6c, 0x74, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x61, 0x74, 0x74, 0x61,
+	0x63, 0x68, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x61, 0x74, 0x74, 0x61,
+	0x63, 0x68, 0x65, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x75, 0x70,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x02, 0x75, 0x70, 0x22, 0x59, 0x0a, 0x08, 0x4d, 0x61,
+	0x70, 0x55, 0x73, 0x61, 0x67, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x6e,
+	0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x65, 0x6e, 0x74,
+	0x72, 0x69, 0x65, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x6d, 0x61, 0x78, 0x5f, 0x65, 0x6e, 0x74, 0x72,
+	0x69, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x6d, 0x61, 0x78, 0x45, 0x6e,
+	0x74, 0x72, 0x69, 0x65, 0x73, 0x22, 0xfa, 0x02, 0x0a, 0x10, 0x48, 0x6f, 0x73, 0x74, 0x43, 0x61,
+	0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x6b, 0x65,
+	0x72, 0x6e, 0x65, 0x6c, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0d, 0x6b, 0x65, 0x72, 0x6e, 0x65, 0x6c, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x72, 0x63, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x61, 0x72, 0x63, 0x68, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x70, 0x75, 0x5f, 0x63, 0x6f, 0x75,
+	0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x63, 0x70, 0x75, 0x43, 0x6f, 0x75,
+	0x6e, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x62, 0x74, 0x66, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x03, 0x62, 0x74, 0x66, 0x12, 0x52, 0x0a, 0x0c, 0x62, 0x70, 0x66, 0x5f, 0x66, 0x65, 0x61, 0x74,
+	0x75, 0x72, 0x65, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2f, 0x2e, 0x61, 0x67, 0x65,
+	0x6e, 0x74, 0x2e, 0x72, 0x65, 0x6f, 0x70, 0x72, 0x74, 0x2e, 0x48, 0x6f, 0x73, 0x74, 0x43, 0x61,
+	0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x42, 0x70, 0x66, 0x46, 0x65,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0b, 0x62, 0x70, 0x66,
+	0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x12, 0x2f, 0x0a, 0x04, 0x6e, 0x69, 0x63, 0x73,
+	0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x72,
+	0x65, 0x6f, 0x70, 0x72, 0x74, 0x2e, 0x4e, 0x69, 0x63, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c,
+	0x69, 0x74, 0x79, 0x52, 0x04, 0x6e, 0x69, 0x63, 0x73, 0x12, 0x37, 0x0a, 0x09, 0x70, 0x72, 0x6f,
+	0x62, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54,
+	0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x08, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x64,
+	0x41, 0x74, 0x1a, 0x3e, 0x0a, 0x10, 0x42, 0x70, 0x66, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02,
+	0x38, 0x01, 0x22, 0xa4, 0x01, 0x0a, 0x0d, 0x4e, 0x69, 0x63, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69,
+	0x6c, 0x69, 0x74, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x64, 0x72, 0x69, 0x76,
+	0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x64, 0x72, 0x69, 0x76, 0x65, 0x72,
+	0x12, 0x1b, 0x0a, 0x09, 0x72, 0x78, 0x5f, 0x71, 0x75, 0x65, 0x75, 0x65, 0x73, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x08, 0x72, 0x78, 0x51, 0x75, 0x65, 0x75, 0x65, 0x73, 0x12, 0x1b, 0x0a,
+	0x09, 0x74, 0x78, 0x5f, 0x71, 0x75, 0x65, 0x75, 0x65, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x08, 0x74, 0x78, 0x51, 0x75, 0x65, 0x75, 0x65, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x78, 0x64,
+	0x70, 0x5f, 0x6e, 0x61, 0x74, 0x69, 0x76, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09,
+	0x78, 0x64, 0x70, 0x4e, 0x61, 0x74, 0x69, 0x76, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x75, 0x70, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x02, 0x75, 0x70, 0x22, 0x10, 0x0a, 0x0e, 0x52, 0x65, 0x70,
+	0x6f, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2a, 0x53, 0x0a, 0x05, 0x50,
+	0x68, 0x61, 0x73, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x55, 0x6e, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x10,
+	0x00, 0x12, 0x09, 0x0a, 0x05, 0x52, 0x65, 0x61, 0x64, 0x79, 0x10, 0x01, 0x12, 0x0b, 0x0a, 0x07,
+	0x52, 0x75, 0x6e, 0x6e, 0x69, 0x6e, 0x67, 0x10, 0x02, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x74, 0x6f,
+	0x70, 0x70, 0x65, 0x64, 0x10, 0x03, 0x12, 0x0c, 0x0a, 0x08, 0x44, 0x65, 0x67, 0x72, 0x61, 0x64,
+	0x65, 0x64, 0x10, 0x04, 0x12, 0x0a, 0x0a, 0x06, 0x46, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x10, 0x05,
+	0x32, 0x4d, 0x0a, 0x0d, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x12, 0x3c, 0x0a, 0x06, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x14, 0x2e, 0x61, 0x67,
+	0x65, 0x6e, 0x74, 0x2e, 0x72, 0x65, 0x6f, 0x70, 0x72, 0x74, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x1a, 0x1c, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x72, 0x65, 0x6f, 0x70, 0x72, 0x74,
+	0x2e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42,
+	0x16, 0x5a, 0x14, 0x6f, 0x72, 0x63, 0x68, 0x2f, 0x76, 0x31, 0x2f, 0x61, 0x67, 0x65, 0x6e, 0x74,
+	0x2f, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -299,25 +811,39 @@ func file_orch_v1_agent_report_report_proto_rawDescGZIP() []byte {
 }
 
 var file_orch_v1_agent_report_report_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_orch_v1_agent_report_report_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_orch_v1_agent_report_report_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
 var file_orch_v1_agent_report_report_proto_goTypes = []any{
 	(Phase)(0),                    // 0: agent.reoprt.Phase
 	(*ErrorTime)(nil),             // 1: agent.reoprt.ErrorTime
 	(*Status)(nil),                // 2: agent.reoprt.Status
-	(*ReportResponse)(nil),        // 3: agent.reoprt.ReportResponse
-	(*timestamppb.Timestamp)(nil), // 4: google.protobuf.Timestamp
+	(*Condition)(nil),             // 3: agent.reoprt.Condition
+	(*InterfaceHealth)(nil),       // 4: agent.reoprt.InterfaceHealth
+	(*MapUsage)(nil),              // 5: agent.reoprt.MapUsage
+	(*HostCapabilities)(nil),      // 6: agent.reoprt.HostCapabilities
+	(*NicCapability)(nil),         // 7: agent.reoprt.NicCapability
+	(*ReportResponse)(nil),        // 8: agent.reoprt.ReportResponse
+	nil,                           // 9: agent.reoprt.HostCapabilities.BpfFeaturesEntry
+	(*timestamppb.Timestamp)(nil), // 10: google.protobuf.Timestamp
 }
 var file_orch_v1_agent_report_report_proto_depIdxs = []int32{
-	4, // 0: agent.reoprt.ErrorTime.retry_at:type_name -> google.protobuf.Timestamp
-	0, // 1: agent.reoprt.Status.phase:type_name -> agent.reoprt.Phase
-	1, // 2: agent.reoprt.Status.error:type_name -> agent.reoprt.ErrorTime
-	2, // 3: agent.reoprt.ReportService.Report:input_type -> agent.reoprt.Status
-	3, // 4: agent.reoprt.ReportService.Report:output_type -> agent.reoprt.ReportResponse
-	4, // [4:5] is the sub-list for method output_type
-	3, // [3:4] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+	10, // 0: agent.reoprt.ErrorTime.retry_at:type_name -> google.protobuf.Timestamp
+	0,  // 1: agent.reoprt.Status.phase:type_name -> agent.reoprt.Phase
+	1,  // 2: agent.reoprt.Status.error:type_name -> agent.reoprt.ErrorTime
+	3,  // 3: agent.reoprt.Status.conditions:type_name -> agent.reoprt.Condition
+	4,  // 4: agent.reoprt.Status.interfaces:type_name -> agent.reoprt.InterfaceHealth
+	5,  // 5: agent.reoprt.Status.maps:type_name -> agent.reoprt.MapUsage
+	6,  // 6: agent.reoprt.Status.capabilities:type_name -> agent.reoprt.HostCapabilities
+	10, // 7: agent.reoprt.Condition.last_transition_time:type_name -> google.protobuf.Timestamp
+	9,  // 8: agent.reoprt.HostCapabilities.bpf_features:type_name -> agent.reoprt.HostCapabilities.BpfFeaturesEntry
+	7,  // 9: agent.reoprt.HostCapabilities.nics:type_name -> agent.reoprt.NicCapability
+	10, // 10: agent.reoprt.HostCapabilities.probed_at:type_name -> google.protobuf.Timestamp
+	2,  // 11: agent.reoprt.ReportService.Report:input_type -> agent.reoprt.Status
+	8,  // 12: agent.reoprt.ReportService.Report:output_type -> agent.reoprt.ReportResponse
+	12, // [12:13] is the sub-list for method output_type
+	11, // [11:12] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_orch_v1_agent_report_report_proto_init() }
@@ -351,6 +877,66 @@ func file_orch_v1_agent_report_report_proto_init() {
 			}
 		}
 		file_orch_v1_agent_report_report_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*Condition); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_orch_v1_agent_report_report_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*InterfaceHealth); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_orch_v1_agent_report_report_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*MapUsage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_orch_v1_agent_report_report_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*HostCapabilities); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_orch_v1_agent_report_report_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*NicCapability); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_orch_v1_agent_report_report_proto_msgTypes[7].Exporter = func(v any, i int) any {
 			switch v := v.(*ReportResponse); i {
 			case 0:
 				return &v.state
@@ -369,7 +955,7 @@ func file_orch_v1_agent_report_report_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_orch_v1_agent_report_report_proto_rawDesc,
 			NumEnums:      1,
-			NumMessages:   3,
+			NumMessages:   9,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -0,0 +1,286 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.2
+// source: orch/v1/protect/protect.proto
+
+package protect
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ProtectService_AddProtectedRange_FullMethodName    = "/protect.ProtectService/AddProtectedRange"
+	ProtectService_DeleteProtectedRange_FullMethodName = "/protect.ProtectService/DeleteProtectedRange"
+	ProtectService_GetProtectedRange_FullMethodName    = "/protect.ProtectService/GetProtectedRange"
+	ProtectService_ListProtectedRange_FullMethodName   = "/protect.ProtectService/ListProtectedRange"
+	ProtectService_ListEffectiveRanges_FullMethodName  = "/protect.ProtectService/ListEffectiveRanges"
+)
+
+// ProtectServiceClient is the client API for ProtectService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ProtectService manages the never-ban list. Rules overlapping a protected
+// range are rejected or clipped by the rule center, and agents never install
+// a ban covering one.
+type ProtectServiceClient interface {
+	AddProtectedRange(ctx context.Context, in *ProtectedRange, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	DeleteProtectedRange(ctx context.Context, in *DeleteProtectedRangeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetProtectedRange(ctx context.Context, in *GetProtectedRangeRequest, opts ...grpc.CallOption) (*ProtectedRange, error)
+	ListProtectedRange(ctx context.Context, in *ListProtectedRangeRequest, opts ...grpc.CallOption) (*ListProtectedRangeResponse, error)
+	// ListEffectiveRanges returns every protected CIDR: the managed ranges, the
+	// ranges of the orch config and the addresses of the orchestrators
+	ListEffectiveRanges(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListEffectiveRangesResponse, error)
+}
+
+type protectServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewProtectServiceClient(cc grpc.ClientConnInterface) ProtectServiceClient {
+	return &protectServiceClient{cc}
+}
+
+func (c *protectServiceClient) AddProtectedRange(ctx context.Context, in *ProtectedRange, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ProtectService_AddProtectedRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *protectServiceClient) DeleteProtectedRange(ctx context.Context, in *DeleteProtectedRangeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ProtectService_DeleteProtectedRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *protectServiceClient) GetProtectedRange(ctx context.Context, in *GetProtectedRangeRequest, opts ...grpc.CallOption) (*ProtectedRange, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ProtectedRange)
+	err := c.cc.Invoke(ctx, ProtectService_GetProtectedRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *protectServiceClient) ListProtectedRange(ctx context.Context, in *ListProtectedRangeRequest, opts ...grpc.CallOption) (*ListProtectedRangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListProtectedRangeResponse)
+	err := c.cc.Invoke(ctx, ProtectService_ListProtectedRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *protectServiceClient) ListEffectiveRanges(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListEffectiveRangesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListEffectiveRangesResponse)
+	err := c.cc.Invoke(ctx, ProtectService_ListEffectiveRanges_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProtectServiceServer is the server API for ProtectService service.
+// All implementations must embed UnimplementedProtectServiceServer
+// for forward compatibility.
+//
+// ProtectService manages the never-ban list. Rules overlapping a protected
+// range are rejected or clipped by the rule center, and agents never install
+// a ban covering one.
+type ProtectServiceServer interface {
+	AddProtectedRange(context.Context, *ProtectedRange) (*emptypb.Empty, error)
+	DeleteProtectedRange(context.Context, *DeleteProtectedRangeRequest) (*emptypb.Empty, error)
+	GetProtectedRange(context.Context, *GetProtectedRangeRequest) (*ProtectedRange, error)
+	ListProtectedRange(context.Context, *ListProtectedRangeRequest) (*ListProtectedRangeResponse, error)
+	// ListEffectiveRanges returns every protected CIDR: the managed ranges, the
+	// ranges of the orch config and the addresses of the orchestrators
+	ListEffectiveRanges(context.Context, *emptypb.Empty) (*ListEffectiveRangesResponse, error)
+	mustEmbedUnimplementedProtectServiceServer()
+}
+
+// UnimplementedProtectServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedProtectServiceServer struct{}
+
+func (UnimplementedProtectServiceServer) AddProtectedRange(context.Context, *ProtectedRange) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddProtectedRange not implemented")
+}
+func (UnimplementedProtectServiceServer) DeleteProtectedRange(context.Context, *DeleteProtectedRangeRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteProtectedRange not implemented")
+}
+func (UnimplementedProtectServiceServer) GetProtectedRange(context.Context, *GetProtectedRangeRequest) (*ProtectedRange, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProtectedRange not implemented")
+}
+func (UnimplementedProtectServiceServer) ListProtectedRange(context.Context, *ListProtectedRangeRequest) (*ListProtectedRangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListProtectedRange not implemented")
+}
+func (UnimplementedProtectServiceServer) ListEffectiveRanges(context.Context, *emptypb.Empty) (*ListEffectiveRangesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEffectiveRanges not implemented")
+}
+func (UnimplementedProtectServiceServer) mustEmbedUnimplementedProtectServiceServer() {}
+func (UnimplementedProtectServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeProtectServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ProtectServiceServer will
+// result in compilation errors.
+type UnsafeProtectServiceServer interface {
+	mustEmbedUnimplementedProtectServiceServer()
+}
+
+func RegisterProtectServiceServer(s grpc.ServiceRegistrar, srv ProtectServiceServer) {
+	// If the following call pancis, it indicates UnimplementedProtectServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ProtectService_ServiceDesc, srv)
+}
+
+func _ProtectService_AddProtectedRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProtectedRange)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProtectServiceServer).AddProtectedRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProtectService_AddProtectedRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProtectServiceServer).AddProtectedRange(ctx, req.(*ProtectedRange))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProtectService_DeleteProtectedRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteProtectedRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProtectServiceServer).DeleteProtectedRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProtectService_DeleteProtectedRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProtectServiceServer).DeleteProtectedRange(ctx, req.(*DeleteProtectedRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProtectService_GetProtectedRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProtectedRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProtectServiceServer).GetProtectedRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProtectService_GetProtectedRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProtectServiceServer).GetProtectedRange(ctx, req.(*GetProtectedRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProtectService_ListProtectedRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListProtectedRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProtectServiceServer).ListProtectedRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProtectService_ListProtectedRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProtectServiceServer).ListProtectedRange(ctx, req.(*ListProtectedRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProtectService_ListEffectiveRanges_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProtectServiceServer).ListEffectiveRanges(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProtectService_ListEffectiveRanges_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProtectServiceServer).ListEffectiveRanges(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ProtectService_ServiceDesc is the grpc.ServiceDesc for ProtectService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ProtectService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "protect.ProtectService",
+	HandlerType: (*ProtectServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddProtectedRange",
+			Handler:    _ProtectService_AddProtectedRange_Handler,
+		},
+		{
+			MethodName: "DeleteProtectedRange",
+			Handler:    _ProtectService_DeleteProtectedRange_Handler,
+		},
+		{
+			MethodName: "GetProtectedRange",
+			Handler:    _ProtectService_GetProtectedRange_Handler,
+		},
+		{
+			MethodName: "ListProtectedRange",
+			Handler:    _ProtectService_ListProtectedRange_Handler,
+		},
+		{
+			MethodName: "ListEffectiveRanges",
+			Handler:    _ProtectService_ListEffectiveRanges_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "orch/v1/protect/protect.proto",
+}
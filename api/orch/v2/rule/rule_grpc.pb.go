@@ -0,0 +1,1155 @@
+// Use https://cloud.google.com/endpoints/docs/grpc-service-config/reference/rpc/google.api#google.api.HttpRule
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.2
+// source: orch/v2/rule/rule.proto
+
+package rule
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RuleService_AddRule_FullMethodName               = "/rule.v2.RuleService/AddRule"
+	RuleService_DeleteRule_FullMethodName            = "/rule.v2.RuleService/DeleteRule"
+	RuleService_UpdateRule_FullMethodName            = "/rule.v2.RuleService/UpdateRule"
+	RuleService_GetRule_FullMethodName               = "/rule.v2.RuleService/GetRule"
+	RuleService_ListRule_FullMethodName              = "/rule.v2.RuleService/ListRule"
+	RuleService_DeleteRulesByKey_FullMethodName      = "/rule.v2.RuleService/DeleteRulesByKey"
+	RuleService_DeleteRulesBySelector_FullMethodName = "/rule.v2.RuleService/DeleteRulesBySelector"
+	RuleService_DeleteRuleSet_FullMethodName         = "/rule.v2.RuleService/DeleteRuleSet"
+	RuleService_ExtendRule_FullMethodName            = "/rule.v2.RuleService/ExtendRule"
+	RuleService_ImportRules_FullMethodName           = "/rule.v2.RuleService/ImportRules"
+	RuleService_ExportRules_FullMethodName           = "/rule.v2.RuleService/ExportRules"
+	RuleService_SearchRules_FullMethodName           = "/rule.v2.RuleService/SearchRules"
+	RuleService_ListVersions_FullMethodName          = "/rule.v2.RuleService/ListVersions"
+	RuleService_DiffVersions_FullMethodName          = "/rule.v2.RuleService/DiffVersions"
+	RuleService_RollbackRuleSet_FullMethodName       = "/rule.v2.RuleService/RollbackRuleSet"
+	RuleService_GetIncludes_FullMethodName           = "/rule.v2.RuleService/GetIncludes"
+	RuleService_SetIncludes_FullMethodName           = "/rule.v2.RuleService/SetIncludes"
+	RuleService_GetEffectiveRuleSet_FullMethodName   = "/rule.v2.RuleService/GetEffectiveRuleSet"
+	RuleService_CheckIdentities_FullMethodName       = "/rule.v2.RuleService/CheckIdentities"
+	RuleService_PreviewAggregation_FullMethodName    = "/rule.v2.RuleService/PreviewAggregation"
+	RuleService_AnalyzeRuleSet_FullMethodName        = "/rule.v2.RuleService/AnalyzeRuleSet"
+	RuleService_Explain_FullMethodName               = "/rule.v2.RuleService/Explain"
+	RuleService_AddScheduledRule_FullMethodName      = "/rule.v2.RuleService/AddScheduledRule"
+	RuleService_DeleteScheduledRule_FullMethodName   = "/rule.v2.RuleService/DeleteScheduledRule"
+	RuleService_ListScheduledRules_FullMethodName    = "/rule.v2.RuleService/ListScheduledRules"
+	RuleService_ListActivations_FullMethodName       = "/rule.v2.RuleService/ListActivations"
+)
+
+// RuleServiceClient is the client API for RuleService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// RuleService is the typed rule api, it is served side by side with rule.RuleService.
+// Agents keep watching the rules through rule.RuleService.WatchRuleResources.
+type RuleServiceClient interface {
+	AddRule(ctx context.Context, in *AddRuleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	DeleteRule(ctx context.Context, in *DeleteRuleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	UpdateRule(ctx context.Context, in *UpdateRuleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetRule(ctx context.Context, in *GetRuleRequest, opts ...grpc.CallOption) (*RuleSet, error)
+	ListRule(ctx context.Context, in *ListRuleRequest, opts ...grpc.CallOption) (*ListRuleResponse, error)
+	// DeleteRulesByKey, DeleteRulesBySelector and DeleteRuleSet each run in one
+	// transaction, which also deletes the identities and the name left unused
+	DeleteRulesByKey(ctx context.Context, in *DeleteRulesByKeyRequest, opts ...grpc.CallOption) (*DeleteRulesResponse, error)
+	DeleteRulesBySelector(ctx context.Context, in *DeleteRulesBySelectorRequest, opts ...grpc.CallOption) (*DeleteRulesResponse, error)
+	DeleteRuleSet(ctx context.Context, in *DeleteRuleSetRequest, opts ...grpc.CallOption) (*DeleteRulesResponse, error)
+	// ExtendRule moves a rule to a new expiry in place, agents never see it deleted
+	ExtendRule(ctx context.Context, in *ExtendRuleRequest, opts ...grpc.CallOption) (*Rule, error)
+	// ImportRules adds the rules of a NDJSON, CSV or plain IP list upload, the
+	// first message is the header and the others are chunks of the upload.
+	// Over http POST the upload to /v2/rulesets/{name}/rules:import.
+	ImportRules(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ImportRulesRequest, ImportRulesResponse], error)
+	// ExportRules streams a rule set in a format ImportRules reads back.
+	// Over http GET /v2/rulesets/{name}/rules:export.
+	ExportRules(ctx context.Context, in *ExportRulesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportRulesResponse], error)
+	// SearchRules finds the rules of every rule set overlapping an IP or a CIDR
+	SearchRules(ctx context.Context, in *SearchRulesRequest, opts ...grpc.CallOption) (*SearchRulesResponse, error)
+	// ListVersions lists the history of a rule set, every change is a version
+	ListVersions(ctx context.Context, in *ListVersionsRequest, opts ...grpc.CallOption) (*ListVersionsResponse, error)
+	// DiffVersions returns what changed in a rule set between two versions
+	DiffVersions(ctx context.Context, in *DiffVersionsRequest, opts ...grpc.CallOption) (*DiffVersionsResponse, error)
+	// RollbackRuleSet turns a rule set back into a version in one transaction,
+	// the rollback is recorded as a new version
+	RollbackRuleSet(ctx context.Context, in *RollbackRuleSetRequest, opts ...grpc.CallOption) (*Version, error)
+	// GetIncludes returns the rule sets a rule set includes
+	GetIncludes(ctx context.Context, in *GetIncludesRequest, opts ...grpc.CallOption) (*Includes, error)
+	// SetIncludes replaces the includes of a rule set, an empty list removes
+	// them and a cycle is refused
+	SetIncludes(ctx context.Context, in *Includes, opts ...grpc.CallOption) (*Includes, error)
+	// GetEffectiveRuleSet returns the rule set merged with its includes, the
+	// rules its agents get
+	GetEffectiveRuleSet(ctx context.Context, in *GetEffectiveRuleSetRequest, opts ...grpc.CallOption) (*EffectiveRuleSet, error)
+	// CheckIdentities finds the CIDRs sharing an identity and the identities
+	// which are not registered, repair fixes them
+	CheckIdentities(ctx context.Context, in *CheckIdentitiesRequest, opts ...grpc.CallOption) (*CheckIdentitiesResponse, error)
+	// PreviewAggregation reports how the rules of a rule set with the same
+	// protocol, ports, comment and expiry would merge into covering prefixes,
+	// nothing is written. ImportRules merges the rules of an upload the same way.
+	PreviewAggregation(ctx context.Context, in *PreviewAggregationRequest, opts ...grpc.CallOption) (*AggregationPreview, error)
+	// AnalyzeRuleSet finds the duplicate, shadowed, conflicting and unreachable
+	// rules of a rule set the way the XDP program applies them: the longest
+	// prefix of a source wins, then the banlist entries of its identity are
+	// matched. With an agent its effective rule set is analyzed.
+	AnalyzeRuleSet(ctx context.Context, in *AnalyzeRuleSetRequest, opts ...grpc.CallOption) (*Analysis, error)
+	// Explain returns the verdict the XDP program of the agents watching a rule
+	// set gives a packet and the rule matching it, the maps are built the way
+	// the agents build them. With an agent its effective rule set is used.
+	Explain(ctx context.Context, in *ExplainRequest, opts ...grpc.CallOption) (*Explanation, error)
+	// AddScheduledRule schedules a rule: the leader adds it to the rule set at
+	// the start of each window of its schedule and it expires at the end.
+	// Agents see the rules of a rule set as usual.
+	AddScheduledRule(ctx context.Context, in *AddScheduledRuleRequest, opts ...grpc.CallOption) (*ScheduledRule, error)
+	// DeleteScheduledRule deletes a scheduled rule, its rule is withdrawn if a
+	// window is active
+	DeleteScheduledRule(ctx context.Context, in *DeleteScheduledRuleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ListScheduledRules lists the scheduled rules of a rule set, of every rule
+	// set without a name
+	ListScheduledRules(ctx context.Context, in *ListScheduledRulesRequest, opts ...grpc.CallOption) (*ListScheduledRulesResponse, error)
+	// ListActivations lists the windows of the scheduled rules in progress or
+	// starting soon, by start
+	ListActivations(ctx context.Context, in *ListActivationsRequest, opts ...grpc.CallOption) (*ListActivationsResponse, error)
+}
+
+type ruleServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRuleServiceClient(cc grpc.ClientConnInterface) RuleServiceClient {
+	return &ruleServiceClient{cc}
+}
+
+func (c *ruleServiceClient) AddRule(ctx context.Context, in *AddRuleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, RuleService_AddRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) DeleteRule(ctx context.Context, in *DeleteRuleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, RuleService_DeleteRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) UpdateRule(ctx context.Context, in *UpdateRuleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, RuleService_UpdateRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) GetRule(ctx context.Context, in *GetRuleRequest, opts ...grpc.CallOption) (*RuleSet, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RuleSet)
+	err := c.cc.Invoke(ctx, RuleService_GetRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) ListRule(ctx context.Context, in *ListRuleRequest, opts ...grpc.CallOption) (*ListRuleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRuleResponse)
+	err := c.cc.Invoke(ctx, RuleService_ListRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) DeleteRulesByKey(ctx context.Context, in *DeleteRulesByKeyRequest, opts ...grpc.CallOption) (*DeleteRulesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteRulesResponse)
+	err := c.cc.Invoke(ctx, RuleService_DeleteRulesByKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) DeleteRulesBySelector(ctx context.Context, in *DeleteRulesBySelectorRequest, opts ...grpc.CallOption) (*DeleteRulesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteRulesResponse)
+	err := c.cc.Invoke(ctx, RuleService_DeleteRulesBySelector_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) DeleteRuleSet(ctx context.Context, in *DeleteRuleSetRequest, opts ...grpc.CallOption) (*DeleteRulesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteRulesResponse)
+	err := c.cc.Invoke(ctx, RuleService_DeleteRuleSet_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) ExtendRule(ctx context.Context, in *ExtendRuleRequest, opts ...grpc.CallOption) (*Rule, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Rule)
+	err := c.cc.Invoke(ctx, RuleService_ExtendRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) ImportRules(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ImportRulesRequest, ImportRulesResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RuleService_ServiceDesc.Streams[0], RuleService_ImportRules_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ImportRulesRequest, ImportRulesResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RuleService_ImportRulesClient = grpc.ClientStreamingClient[ImportRulesRequest, ImportRulesResponse]
+
+func (c *ruleServiceClient) ExportRules(ctx context.Context, in *ExportRulesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportRulesResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RuleService_ServiceDesc.Streams[1], RuleService_ExportRules_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExportRulesRequest, ExportRulesResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RuleService_ExportRulesClient = grpc.ServerStreamingClient[ExportRulesResponse]
+
+func (c *ruleServiceClient) SearchRules(ctx context.Context, in *SearchRulesRequest, opts ...grpc.CallOption) (*SearchRulesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchRulesResponse)
+	err := c.cc.Invoke(ctx, RuleService_SearchRules_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) ListVersions(ctx context.Context, in *ListVersionsRequest, opts ...grpc.CallOption) (*ListVersionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListVersionsResponse)
+	err := c.cc.Invoke(ctx, RuleService_ListVersions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) DiffVersions(ctx context.Context, in *DiffVersionsRequest, opts ...grpc.CallOption) (*DiffVersionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DiffVersionsResponse)
+	err := c.cc.Invoke(ctx, RuleService_DiffVersions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) RollbackRuleSet(ctx context.Context, in *RollbackRuleSetRequest, opts ...grpc.CallOption) (*Version, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Version)
+	err := c.cc.Invoke(ctx, RuleService_RollbackRuleSet_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) GetIncludes(ctx context.Context, in *GetIncludesRequest, opts ...grpc.CallOption) (*Includes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Includes)
+	err := c.cc.Invoke(ctx, RuleService_GetIncludes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) SetIncludes(ctx context.Context, in *Includes, opts ...grpc.CallOption) (*Includes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Includes)
+	err := c.cc.Invoke(ctx, RuleService_SetIncludes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) GetEffectiveRuleSet(ctx context.Context, in *GetEffectiveRuleSetRequest, opts ...grpc.CallOption) (*EffectiveRuleSet, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EffectiveRuleSet)
+	err := c.cc.Invoke(ctx, RuleService_GetEffectiveRuleSet_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) CheckIdentities(ctx context.Context, in *CheckIdentitiesRequest, opts ...grpc.CallOption) (*CheckIdentitiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckIdentitiesResponse)
+	err := c.cc.Invoke(ctx, RuleService_CheckIdentities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) PreviewAggregation(ctx context.Context, in *PreviewAggregationRequest, opts ...grpc.CallOption) (*AggregationPreview, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AggregationPreview)
+	err := c.cc.Invoke(ctx, RuleService_PreviewAggregation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) AnalyzeRuleSet(ctx context.Context, in *AnalyzeRuleSetRequest, opts ...grpc.CallOption) (*Analysis, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Analysis)
+	err := c.cc.Invoke(ctx, RuleService_AnalyzeRuleSet_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) Explain(ctx context.Context, in *ExplainRequest, opts ...grpc.CallOption) (*Explanation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Explanation)
+	err := c.cc.Invoke(ctx, RuleService_Explain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) AddScheduledRule(ctx context.Context, in *AddScheduledRuleRequest, opts ...grpc.CallOption) (*ScheduledRule, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScheduledRule)
+	err := c.cc.Invoke(ctx, RuleService_AddScheduledRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) DeleteScheduledRule(ctx context.Context, in *DeleteScheduledRuleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, RuleService_DeleteScheduledRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) ListScheduledRules(ctx context.Context, in *ListScheduledRulesRequest, opts ...grpc.CallOption) (*ListScheduledRulesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListScheduledRulesResponse)
+	err := c.cc.Invoke(ctx, RuleService_ListScheduledRules_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleServiceClient) ListActivations(ctx context.Context, in *ListActivationsRequest, opts ...grpc.CallOption) (*ListActivationsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListActivationsResponse)
+	err := c.cc.Invoke(ctx, RuleService_ListActivations_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RuleServiceServer is the server API for RuleService service.
+// All implementations must embed UnimplementedRuleServiceServer
+// for forward compatibility.
+//
+// RuleService is the typed rule api, it is served side by side with rule.RuleService.
+// Agents keep watching the rules through rule.RuleService.WatchRuleResources.
+type RuleServiceServer interface {
+	AddRule(context.Context, *AddRuleRequest) (*emptypb.Empty, error)
+	DeleteRule(context.Context, *DeleteRuleRequest) (*emptypb.Empty, error)
+	UpdateRule(context.Context, *UpdateRuleRequest) (*emptypb.Empty, error)
+	GetRule(context.Context, *GetRuleRequest) (*RuleSet, error)
+	ListRule(context.Context, *ListRuleRequest) (*ListRuleResponse, error)
+	// DeleteRulesByKey, DeleteRulesBySelector and DeleteRuleSet each run in one
+	// transaction, which also deletes the identities and the name left unused
+	DeleteRulesByKey(context.Context, *DeleteRulesByKeyRequest) (*DeleteRulesResponse, error)
+	DeleteRulesBySelector(context.Context, *DeleteRulesBySelectorRequest) (*DeleteRulesResponse, error)
+	DeleteRuleSet(context.Context, *DeleteRuleSetRequest) (*DeleteRulesResponse, error)
+	// ExtendRule moves a rule to a new expiry in place, agents never see it deleted
+	ExtendRule(context.Context, *ExtendRuleRequest) (*Rule, error)
+	// ImportRules adds the rules of a NDJSON, CSV or plain IP list upload, the
+	// first message is the header and the others are chunks of the upload.
+	// Over http POST the upload to /v2/rulesets/{name}/rules:import.
+	ImportRules(grpc.ClientStreamingServer[ImportRulesRequest, ImportRulesResponse]) error
+	// ExportRules streams a rule set in a format ImportRules reads back.
+	// Over http GET /v2/rulesets/{name}/rules:export.
+	ExportRules(*ExportRulesRequest, grpc.ServerStreamingServer[ExportRulesResponse]) error
+	// SearchRules finds the rules of every rule set overlapping an IP or a CIDR
+	SearchRules(context.Context, *SearchRulesRequest) (*SearchRulesResponse, error)
+	// ListVersions lists the history of a rule set, every change is a version
+	ListVersions(context.Context, *ListVersionsRequest) (*ListVersionsResponse, error)
+	// DiffVersions returns what changed in a rule set between two versions
+	DiffVersions(context.Context, *DiffVersionsRequest) (*DiffVersionsResponse, error)
+	// RollbackRuleSet turns a rule set back into a version in one transaction,
+	// the rollback is recorded as a new version
+	RollbackRuleSet(context.Context, *RollbackRuleSetRequest) (*Version, error)
+	// GetIncludes returns the rule sets a rule set includes
+	GetIncludes(context.Context, *GetIncludesRequest) (*Includes, error)
+	// SetIncludes replaces the includes of a rule set, an empty list removes
+	// them and a cycle is refused
+	SetIncludes(context.Context, *Includes) (*Includes, error)
+	// GetEffectiveRuleSet returns the rule set merged with its includes, the
+	// rules its agents get
+	GetEffectiveRuleSet(context.Context, *GetEffectiveRuleSetRequest) (*EffectiveRuleSet, error)
+	// CheckIdentities finds the CIDRs sharing an identity and the identities
+	// which are not registered, repair fixes them
+	CheckIdentities(context.Context, *CheckIdentitiesRequest) (*CheckIdentitiesResponse, error)
+	// PreviewAggregation reports how the rules of a rule set with the same
+	// protocol, ports, comment and expiry would merge into covering prefixes,
+	// nothing is written. ImportRules merges the rules of an upload the same way.
+	PreviewAggregation(context.Context, *PreviewAggregationRequest) (*AggregationPreview, error)
+	// AnalyzeRuleSet finds the duplicate, shadowed, conflicting and unreachable
+	// rules of a rule set the way the XDP program applies them: the longest
+	// prefix of a source wins, then the banlist entries of its identity are
+	// matched. With an agent its effective rule set is analyzed.
+	AnalyzeRuleSet(context.Context, *AnalyzeRuleSetRequest) (*Analysis, error)
+	// Explain returns the verdict the XDP program of the agents watching a rule
+	// set gives a packet and the rule matching it, the maps are built the way
+	// the agents build them. With an agent its effective rule set is used.
+	Explain(context.Context, *ExplainRequest) (*Explanation, error)
+	// AddScheduledRule schedules a rule: the leader adds it to the rule set at
+	// the start of each window of its schedule and it expires at the end.
+	// Agents see the rules of a rule set as usual.
+	AddScheduledRule(context.Context, *AddScheduledRuleRequest) (*ScheduledRule, error)
+	// DeleteScheduledRule deletes a scheduled rule, its rule is withdrawn if a
+	// window is active
+	DeleteScheduledRule(context.Context, *DeleteScheduledRuleRequest) (*emptypb.Empty, error)
+	// ListScheduledRules lists the scheduled rules of a rule set, of every rule
+	// set without a name
+	ListScheduledRules(context.Context, *ListScheduledRulesRequest) (*ListScheduledRulesResponse, error)
+	// ListActivations lists the windows of the scheduled rules in progress or
+	// starting soon, by start
+	ListActivations(context.Context, *ListActivationsRequest) (*ListActivationsResponse, error)
+	mustEmbedUnimplementedRuleServiceServer()
+}
+
+// UnimplementedRuleServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRuleServiceServer struct{}
+
+func (UnimplementedRuleServiceServer) AddRule(context.Context, *AddRuleRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddRule not implemented")
+}
+func (UnimplementedRuleServiceServer) DeleteRule(context.Context, *DeleteRuleRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRule not implemented")
+}
+func (UnimplementedRuleServiceServer) UpdateRule(context.Context, *UpdateRuleRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRule not implemented")
+}
+func (UnimplementedRuleServiceServer) GetRule(context.Context, *GetRuleRequest) (*RuleSet, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRule not implemented")
+}
+func (UnimplementedRuleServiceServer) ListRule(context.Context, *ListRuleRequest) (*ListRuleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRule not implemented")
+}
+func (UnimplementedRuleServiceServer) DeleteRulesByKey(context.Context, *DeleteRulesByKeyRequest) (*DeleteRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRulesByKey not implemented")
+}
+func (UnimplementedRuleServiceServer) DeleteRulesBySelector(context.Context, *DeleteRulesBySelectorRequest) (*DeleteRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRulesBySelector not implemented")
+}
+func (UnimplementedRuleServiceServer) DeleteRuleSet(context.Context, *DeleteRuleSetRequest) (*DeleteRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRuleSet not implemented")
+}
+func (UnimplementedRuleServiceServer) ExtendRule(context.Context, *ExtendRuleRequest) (*Rule, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExtendRule not implemented")
+}
+func (UnimplementedRuleServiceServer) ImportRules(grpc.ClientStreamingServer[ImportRulesRequest, ImportRulesResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ImportRules not implemented")
+}
+func (UnimplementedRuleServiceServer) ExportRules(*ExportRulesRequest, grpc.ServerStreamingServer[ExportRulesResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ExportRules not implemented")
+}
+func (UnimplementedRuleServiceServer) SearchRules(context.Context, *SearchRulesRequest) (*SearchRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchRules not implemented")
+}
+func (UnimplementedRuleServiceServer) ListVersions(context.Context, *ListVersionsRequest) (*ListVersionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListVersions not implemented")
+}
+func (UnimplementedRuleServiceServer) DiffVersions(context.Context, *DiffVersionsRequest) (*DiffVersionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DiffVersions not implemented")
+}
+func (UnimplementedRuleServiceServer) RollbackRuleSet(context.Context, *RollbackRuleSetRequest) (*Version, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RollbackRuleSet not implemented")
+}
+func (UnimplementedRuleServiceServer) GetIncludes(context.Context, *GetIncludesRequest) (*Includes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetIncludes not implemented")
+}
+func (UnimplementedRuleServiceServer) SetIncludes(context.Context, *Includes) (*Includes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetIncludes not implemented")
+}
+func (UnimplementedRuleServiceServer) GetEffectiveRuleSet(context.Context, *GetEffectiveRuleSetRequest) (*EffectiveRuleSet, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEffectiveRuleSet not implemented")
+}
+func (UnimplementedRuleServiceServer) CheckIdentities(context.Context, *CheckIdentitiesRequest) (*CheckIdentitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckIdentities not implemented")
+}
+func (UnimplementedRuleServiceServer) PreviewAggregation(context.Context, *PreviewAggregationRequest) (*AggregationPreview, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PreviewAggregation not implemented")
+}
+func (UnimplementedRuleServiceServer) AnalyzeRuleSet(context.Context, *AnalyzeRuleSetRequest) (*Analysis, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AnalyzeRuleSet not implemented")
+}
+func (UnimplementedRuleServiceServer) Explain(context.Context, *ExplainRequest) (*Explanation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Explain not implemented")
+}
+func (UnimplementedRuleServiceServer) AddScheduledRule(context.Context, *AddScheduledRuleRequest) (*ScheduledRule, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddScheduledRule not implemented")
+}
+func (UnimplementedRuleServiceServer) DeleteScheduledRule(context.Context, *DeleteScheduledRuleRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteScheduledRule not implemented")
+}
+func (UnimplementedRuleServiceServer) ListScheduledRules(context.Context, *ListScheduledRulesRequest) (*ListScheduledRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListScheduledRules not implemented")
+}
+func (UnimplementedRuleServiceServer) ListActivations(context.Context, *ListActivationsRequest) (*ListActivationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListActivations not implemented")
+}
+func (UnimplementedRuleServiceServer) mustEmbedUnimplementedRuleServiceServer() {}
+func (UnimplementedRuleServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeRuleServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RuleServiceServer will
+// result in compilation errors.
+type UnsafeRuleServiceServer interface {
+	mustEmbedUnimplementedRuleServiceServer()
+}
+
+func RegisterRuleServiceServer(s grpc.ServiceRegistrar, srv RuleServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRuleServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RuleService_ServiceDesc, srv)
+}
+
+func _RuleService_AddRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).AddRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_AddRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).AddRule(ctx, req.(*AddRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_DeleteRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).DeleteRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_DeleteRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).DeleteRule(ctx, req.(*DeleteRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_UpdateRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).UpdateRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_UpdateRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).UpdateRule(ctx, req.(*UpdateRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_GetRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).GetRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_GetRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).GetRule(ctx, req.(*GetRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_ListRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).ListRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_ListRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).ListRule(ctx, req.(*ListRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_DeleteRulesByKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRulesByKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).DeleteRulesByKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_DeleteRulesByKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).DeleteRulesByKey(ctx, req.(*DeleteRulesByKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_DeleteRulesBySelector_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRulesBySelectorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).DeleteRulesBySelector(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_DeleteRulesBySelector_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).DeleteRulesBySelector(ctx, req.(*DeleteRulesBySelectorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_DeleteRuleSet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRuleSetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).DeleteRuleSet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_DeleteRuleSet_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).DeleteRuleSet(ctx, req.(*DeleteRuleSetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_ExtendRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExtendRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).ExtendRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_ExtendRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).ExtendRule(ctx, req.(*ExtendRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_ImportRules_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RuleServiceServer).ImportRules(&grpc.GenericServerStream[ImportRulesRequest, ImportRulesResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RuleService_ImportRulesServer = grpc.ClientStreamingServer[ImportRulesRequest, ImportRulesResponse]
+
+func _RuleService_ExportRules_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRulesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RuleServiceServer).ExportRules(m, &grpc.GenericServerStream[ExportRulesRequest, ExportRulesResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RuleService_ExportRulesServer = grpc.ServerStreamingServer[ExportRulesResponse]
+
+func _RuleService_SearchRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).SearchRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_SearchRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).SearchRules(ctx, req.(*SearchRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_ListVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListVersionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).ListVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_ListVersions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).ListVersions(ctx, req.(*ListVersionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_DiffVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiffVersionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).DiffVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_DiffVersions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).DiffVersions(ctx, req.(*DiffVersionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_RollbackRuleSet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackRuleSetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).RollbackRuleSet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_RollbackRuleSet_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).RollbackRuleSet(ctx, req.(*RollbackRuleSetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_GetIncludes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetIncludesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).GetIncludes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_GetIncludes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).GetIncludes(ctx, req.(*GetIncludesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_SetIncludes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Includes)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).SetIncludes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_SetIncludes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).SetIncludes(ctx, req.(*Includes))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_GetEffectiveRuleSet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEffectiveRuleSetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).GetEffectiveRuleSet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_GetEffectiveRuleSet_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).GetEffectiveRuleSet(ctx, req.(*GetEffectiveRuleSetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_CheckIdentities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckIdentitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).CheckIdentities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_CheckIdentities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).CheckIdentities(ctx, req.(*CheckIdentitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_PreviewAggregation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PreviewAggregationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).PreviewAggregation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_PreviewAggregation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).PreviewAggregation(ctx, req.(*PreviewAggregationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_AnalyzeRuleSet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnalyzeRuleSetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).AnalyzeRuleSet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_AnalyzeRuleSet_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).AnalyzeRuleSet(ctx, req.(*AnalyzeRuleSetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_Explain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExplainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).Explain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_Explain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).Explain(ctx, req.(*ExplainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_AddScheduledRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddScheduledRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).AddScheduledRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_AddScheduledRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).AddScheduledRule(ctx, req.(*AddScheduledRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_DeleteScheduledRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteScheduledRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).DeleteScheduledRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_DeleteScheduledRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).DeleteScheduledRule(ctx, req.(*DeleteScheduledRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_ListScheduledRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListScheduledRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).ListScheduledRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_ListScheduledRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).ListScheduledRules(ctx, req.(*ListScheduledRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleService_ListActivations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListActivationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleServiceServer).ListActivations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleService_ListActivations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleServiceServer).ListActivations(ctx, req.(*ListActivationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RuleService_ServiceDesc is the grpc.ServiceDesc for RuleService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RuleService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rule.v2.RuleService",
+	HandlerType: (*RuleServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddRule",
+			Handler:    _RuleService_AddRule_Handler,
+		},
+		{
+			MethodName: "DeleteRule",
+			Handler:    _RuleService_DeleteRule_Handler,
+		},
+		{
+			MethodName: "UpdateRule",
+			Handler:    _RuleService_UpdateRule_Handler,
+		},
+		{
+			MethodName: "GetRule",
+			Handler:    _RuleService_GetRule_Handler,
+		},
+		{
+			MethodName: "ListRule",
+			Handler:    _RuleService_ListRule_Handler,
+		},
+		{
+			MethodName: "DeleteRulesByKey",
+			Handler:    _RuleService_DeleteRulesByKey_Handler,
+		},
+		{
+			MethodName: "DeleteRulesBySelector",
+			Handler:    _RuleService_DeleteRulesBySelector_Handler,
+		},
+		{
+			MethodName: "DeleteRuleSet",
+			Handler:    _RuleService_DeleteRuleSet_Handler,
+		},
+		{
+			MethodName: "ExtendRule",
+			Handler:    _RuleService_ExtendRule_Handler,
+		},
+		{
+			MethodName: "SearchRules",
+			Handler:    _RuleService_SearchRules_Handler,
+		},
+		{
+			MethodName: "ListVersions",
+			Handler:    _RuleService_ListVersions_Handler,
+		},
+		{
+			MethodName: "DiffVersions",
+			Handler:    _RuleService_DiffVersions_Handler,
+		},
+		{
+			MethodName: "RollbackRuleSet",
+			Handler:    _RuleService_RollbackRuleSet_Handler,
+		},
+		{
+			MethodName: "GetIncludes",
+			Handler:    _RuleService_GetIncludes_Handler,
+		},
+		{
+			MethodName: "SetIncludes",
+			Handler:    _RuleService_SetIncludes_Handler,
+		},
+		{
+			MethodName: "GetEffectiveRuleSet",
+			Handler:    _RuleService_GetEffectiveRuleSet_Handler,
+		},
+		{
+			MethodName: "CheckIdentities",
+			Handler:    _RuleService_CheckIdentities_Handler,
+		},
+		{
+			MethodName: "PreviewAggregation",
+			Handler:    _RuleService_PreviewAggregation_Handler,
+		},
+		{
+			MethodName: "AnalyzeRuleSet",
+			Handler:    _RuleService_AnalyzeRuleSet_Handler,
+		},
+		{
+			MethodName: "Explain",
+			Handler:    _RuleService_Explain_Handler,
+		},
+		{
+			MethodName: "AddScheduledRule",
+			Handler:    _RuleService_AddScheduledRule_Handler,
+		},
+		{
+			MethodName: "DeleteScheduledRule",
+			Handler:    _RuleService_DeleteScheduledRule_Handler,
+		},
+		{
+			MethodName: "ListScheduledRules",
+			Handler:    _RuleService_ListScheduledRules_Handler,
+		},
+		{
+			MethodName: "ListActivations",
+			Handler:    _RuleService_ListActivations_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ImportRules",
+			Handler:       _RuleService_ImportRules_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ExportRules",
+			Handler:       _RuleService_ExportRules_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "orch/v2/rule/rule.proto",
+}
@@ -5,16 +5,14 @@ import (
 	"xdp-banner/orch/cmd/global"
 	initCluster "xdp-banner/orch/cmd/init"
 	"xdp-banner/orch/cmd/join"
+	"xdp-banner/orch/cmd/migrate"
+	"xdp-banner/orch/cmd/replay"
 	"xdp-banner/orch/cmd/reset"
 	"xdp-banner/orch/cmd/server"
 
 	"github.com/spf13/cobra"
 )
 
-var (
-	cfgFile string // 用来接收 --config
-)
-
 func NewOrchCmd() *cobra.Command {
 	opt := global.DefaultOption()
 
@@ -23,32 +21,26 @@ func NewOrchCmd() *cobra.Command {
 		Short: "xdp-banner Orchestrator",
 		Long:  `Orch is the control plane for the xdp-banner cluster.`,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			// 加载文件
-			vpr, loadedOpts, err := global.LoadConfig(cfgFile)
-			if err != nil {
-				return fmt.Errorf("failed to load config %q: %w", cfgFile, err)
+			// 配置文件覆盖默认值, 环境变量和命令行参数再覆盖配置文件
+			loader := opt.Loader(cmd.Flags())
+			if _, err := loader.Load(map[string]any{"": opt}); err != nil {
+				return fmt.Errorf("failed to load config %q: %w", opt.ConfigFile, err)
 			}
-			// 用用户文件里的配置 覆盖 掉 opt 的默认值
-			*opt = *loadedOpts
 
-			// （如果你需要 viper 实例，也可以把 vpr 赋给包级变量）
-			_ = vpr
-			return nil
+			return opt.Check()
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.Help()
 		},
 	}
 
-	// 3) 在顶级命令上注册 --config / -c flag
-	cmd.PersistentFlags().StringVarP(
-		&cfgFile, "config", "c", "./config.yaml",
-		"config file (default is ./config.yaml)",
-	)
+	opt.SetConfigFlag(cmd)
 
 	opt.SetFlags(cmd)
 	cmd.AddCommand(initCluster.Cmd(opt))
 	cmd.AddCommand(join.Cmd(opt))
+	cmd.AddCommand(migrate.Cmd(opt))
+	cmd.AddCommand(replay.Cmd(opt))
 	cmd.AddCommand(reset.Cmd(opt))
 	cmd.AddCommand(server.Cmd(opt))
 
@@ -2,16 +2,17 @@ package global
 
 import (
 	"fmt"
-	"path/filepath"
-	"strings"
 	"time"
 
+	"xdp-banner/orch/logic/protect"
+	"xdp-banner/orch/logic/rulecenter"
+	"xdp-banner/orch/logic/rulecenter/validation"
 	commconfig "xdp-banner/pkg/config"
 	errors "xdp-banner/pkg/errors"
 	random_string "xdp-banner/pkg/random"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"github.com/spf13/pflag"
 )
 
 /* Controller Config Struct */
@@ -210,6 +211,36 @@ type ControllerOptions struct {
 	Metric         MetricOptions `mapstructure:"metric"`
 	Trace          TraceOptions  `mapstructure:"trace"`
 	Log            LogOptions    `mapstructure:"log"`
+	// Validation is the validator chain of the rules
+	Validation validation.Config `mapstructure:"validation"`
+	// Protect is the never-ban list
+	Protect protect.Config `mapstructure:"protect"`
+	// History is the version history of the rule sets
+	History rulecenter.HistoryConfig `mapstructure:"history"`
+
+	// ConfigFile is set by --config, it is not part of the file itself
+	ConfigFile string `mapstructure:"-"`
+}
+
+// DefaultConfigFile is read when it exists, --config makes it mandatory
+const DefaultConfigFile = "./config.yaml"
+
+// EnvPrefix is the prefix of the environment variables overriding the config file
+const EnvPrefix = "XDP_ORCH"
+
+// SetConfigFlag registers --config on the root command
+func (e *ControllerOptions) SetConfigFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&e.ConfigFile, "config", "c", e.ConfigFile, "config file, flags and XDP_ORCH_* environment variables override it")
+}
+
+// Loader returns the loader of the config file, the default file may be missing
+func (e *ControllerOptions) Loader(flags *pflag.FlagSet) commconfig.Loader {
+	return commconfig.Loader{
+		File:      e.ConfigFile,
+		Optional:  !flags.Changed("config"),
+		EnvPrefix: EnvPrefix,
+		Flags:     flags,
+	}
 }
 
 func DefaultOption() *ControllerOptions {
@@ -227,6 +258,10 @@ func DefaultOption() *ControllerOptions {
 			LeaseTTL:       10 * time.Second,
 			ElectionKey:    "/election",
 		},
+		Validation: validation.DefaultConfig(),
+		Protect:    protect.DefaultConfig(),
+		History:    rulecenter.DefaultHistoryConfig(),
+		ConfigFile: DefaultConfigFile,
 		Metric: MetricOptions{
 			Enabled:        false,
 			PrometheusPort: 9090,
@@ -269,6 +304,18 @@ func (e *ControllerOptions) Check() error {
 	if err != nil {
 		return err
 	}
+
+	if err := e.Validation.Check(); err != nil {
+		return errors.NewInputErrorf("%v.Check your config", err)
+	}
+
+	if err := e.Protect.Check(); err != nil {
+		return errors.NewInputErrorf("%v.Check your config", err)
+	}
+
+	if err := e.History.Check(); err != nil {
+		return errors.NewInputErrorf("%v.Check your config", err)
+	}
 	return nil
 }
 
@@ -277,25 +324,14 @@ func (e *ControllerOptions) SetFlags(cmd *cobra.Command) {
 	e.Metric.SetFlags(cmd)
 	e.Trace.SetFlags(cmd)
 	e.Log.SetFlags(cmd)
-}
-
-func LoadConfig(config_path string) (*viper.Viper, *ControllerOptions, error) {
-
-	var controllerConfig ControllerOptions
 
-	// 默认 file 的后缀为 .yaml
-	dir, file := filepath.Split(config_path)
-	filename := strings.Split(file, ".")
-	viperSample, err := commconfig.NewFromConfig(filename[0], dir, &controllerConfig)
+	cmdPrefix := "validation-"
+	cmd.Flags().IntVar(&e.Validation.MaxRulesPerSet, cmdPrefix+"max-rules", e.Validation.MaxRulesPerSet, "max rules per rule set, 0 is unlimited")
+	cmd.Flags().DurationVar(&e.Validation.MinDuration, cmdPrefix+"min-duration", e.Validation.MinDuration, "min rule duration, 0 is unbounded")
+	cmd.Flags().DurationVar(&e.Validation.MaxDuration, cmdPrefix+"max-duration", e.Validation.MaxDuration, "max rule duration, 0 is unbounded")
 
-	if err != nil {
-		return nil, nil, err
-	}
-
-	err = controllerConfig.Check()
-	if err != nil {
-		return nil, nil, err
-	}
+	cmd.Flags().StringVar(&e.Protect.Mode, "protect-mode", e.Protect.Mode, "what to do with rules containing protected ranges, reject or clip")
+	cmd.Flags().StringSliceVar(&e.Protect.Ranges, "protect-ranges", e.Protect.Ranges, "cidrs which can never be banned, in addition to the managed ones")
 
-	return viperSample, &controllerConfig, err
+	cmd.Flags().IntVar(&e.History.Retention, "history-retention", e.History.Retention, "versions kept per rule set, 0 disables the history")
 }
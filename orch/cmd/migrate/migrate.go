@@ -0,0 +1,59 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"xdp-banner/orch/cmd/global"
+	"xdp-banner/orch/storage/agent/rule"
+	"xdp-banner/pkg/log"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// Cmd moves the rule set names of a cluster from the JSON list of older
+// versions to one key per rule set. Run it once every orchestrator is
+// upgraded, running it again is harmless.
+func Cmd(parentOpt *global.ControllerOptions) *cobra.Command {
+	opt := DefaultOption(parentOpt)
+
+	cmd := &cobra.Command{
+		Use:   "migrate-names",
+		Short: "move the rule set names to one key per rule set",
+		Long: `migrate-names writes a name key for every rule set having rules and deletes
+the name list of older versions. Stop the orchestrators of older versions first,
+they keep writing the list.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opt.Check(); err != nil {
+				return err
+			}
+
+			return migrateNames(opt)
+		},
+	}
+
+	opt.SetFlags(cmd)
+
+	return cmd
+}
+
+func migrateNames(opt *Option) error {
+	ctx, cancel := context.WithTimeout(context.Background(), opt.Timeout)
+	defer cancel()
+
+	if err := global.CreateGlobalEtcdInstance(opt.Parent); err != nil {
+		return fmt.Errorf("connect etcd failed: %w", err)
+	}
+
+	migration, err := rule.New(global.Cli).MigrateNames(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate rule set names failed: %w", err)
+	}
+
+	log.Info("Rule set names migrated",
+		zap.Int("ruleSets", migration.RuleSets),
+		zap.Strings("added", migration.Added),
+		zap.Strings("dropped", migration.Dropped),
+		zap.Bool("listRemoved", migration.ListRemoved))
+	return nil
+}
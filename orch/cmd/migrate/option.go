@@ -0,0 +1,33 @@
+package migrate
+
+import (
+	"time"
+	"xdp-banner/orch/cmd/global"
+
+	"github.com/spf13/cobra"
+)
+
+type Option struct {
+	Timeout time.Duration
+
+	Parent *global.ControllerOptions
+}
+
+func DefaultOption(parent *global.ControllerOptions) *Option {
+	return &Option{
+		Timeout: 5 * time.Minute,
+		Parent:  parent,
+	}
+}
+
+func (o *Option) Check() error {
+	if err := o.Parent.Check(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *Option) SetFlags(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "timeout of the migration")
+}
@@ -0,0 +1,221 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+	"xdp-banner/orch/cmd/global"
+	"xdp-banner/orch/logic/protect"
+	"xdp-banner/orch/logic/rulecenter"
+	"xdp-banner/orch/logic/rulecenter/bulk"
+	protectModel "xdp-banner/orch/model/protect"
+	model "xdp-banner/orch/model/rule"
+	protectStorage "xdp-banner/orch/storage/agent/protect"
+	ruleStorage "xdp-banner/orch/storage/agent/rule"
+	orchnode "xdp-banner/orch/storage/orch/node"
+	"xdp-banner/pkg/cidr"
+	"xdp-banner/pkg/pcap"
+	prule "xdp-banner/pkg/rule"
+
+	"github.com/spf13/cobra"
+)
+
+// fileSource is the source of the rules of a rule file
+const fileSource = "file"
+
+// Cmd replays a capture against a rule set with the decision of the XDP
+// program, offline: it needs neither root nor a network interface.
+func Cmd(parentOpt *global.ControllerOptions) *cobra.Command {
+	opt := DefaultOption(parentOpt)
+
+	cmd := &cobra.Command{
+		Use:   "replay <capture>",
+		Short: "replay a pcap or pcapng capture against a rule set",
+		Long: `replay decides every packet of a pcap or pcapng capture with the maps the agents
+would write for a rule set, the way their XDP program does, and reports what would
+be dropped: by rule, by reason and the top sources. The rule set is read from etcd
+with its includes and the protected ranges, or from a rule file in the format of
+the bulk import. The static rules of the agents are not known.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opt.Check(); err != nil {
+				return err
+			}
+
+			return run(opt, args[0])
+		},
+	}
+
+	opt.SetFlags(cmd)
+
+	return cmd
+}
+
+func run(opt *Option, capture string) error {
+	f, err := os.Open(capture)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader, err := pcap.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", capture, err)
+	}
+
+	var rules []model.EffectiveRule
+	var protected []protectModel.EffectiveRange
+	if opt.RuleSet != "" {
+		rules, protected, err = loadRuleSet(opt)
+	} else {
+		rules, protected, err = loadRuleFile(opt)
+	}
+	if err != nil {
+		return err
+	}
+
+	replay := NewReplay(rulecenter.NewMatcher(rules, protected), opt.StripVLAN)
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read %s: %w", capture, err)
+		}
+		replay.Add(record)
+	}
+
+	report := replay.Report(opt.Top)
+	if opt.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printReport(os.Stdout, report)
+	return nil
+}
+
+// loadRuleSet reads the merged view of the rule set and the protected ranges
+func loadRuleSet(opt *Option) ([]model.EffectiveRule, []protectModel.EffectiveRange, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opt.Timeout)
+	defer cancel()
+
+	if err := global.CreateGlobalEtcdInstance(opt.Parent); err != nil {
+		return nil, nil, fmt.Errorf("connect etcd failed: %w", err)
+	}
+
+	_, rules, err := ruleStorage.NewComposer(ctx, global.Cli).Effective(ctx, opt.RuleSet)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read rule set %s failed: %w", opt.RuleSet, err)
+	}
+	if len(rules) == 0 {
+		fmt.Fprintf(os.Stderr, "rule set %s has no rules\n", opt.RuleSet)
+	}
+
+	guard := protect.New(protectStorage.New(global.Cli), orchnode.NewInfoStorage(global.Cli), nil, opt.Parent.Protect)
+	protected, err := guard.Effective(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read protected ranges failed: %w", err)
+	}
+	return rules, protected, nil
+}
+
+// loadRuleFile reads a rule file, the rules of a CIDR get an identity of
+// their own like the rules of a rule set. Only the protected ranges of the
+// config apply.
+func loadRuleFile(opt *Option) ([]model.EffectiveRule, []protectModel.EffectiveRange, error) {
+	f, err := os.Open(opt.Rules)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	format, _ := bulk.ParseFormat(opt.Format) // Option.Check 已经检查过
+	reader := bulk.NewReader(f, format, bulk.Defaults{Protocol: opt.Protocol})
+
+	var rules []model.EffectiveRule
+	identities := make(map[string]string)
+	for {
+		line, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("read %s: %w", opt.Rules, err)
+		}
+		if line.Err != nil {
+			fmt.Fprintf(os.Stderr, "skip line %d of %s: %v\n", line.Number, opt.Rules, line.Err)
+			continue
+		}
+
+		identity, ok := identities[line.Info.Cidr]
+		if !ok {
+			identity = strconv.Itoa(len(identities) + 1)
+			identities[line.Info.Cidr] = identity
+		}
+		rules = append(rules, model.EffectiveRule{
+			Source: fileSource,
+			Rule: model.Rule{
+				RuleMeta: prule.RuleMeta{Comment: line.Info.Comment, Identity: identity},
+				RuleInfo: line.Info,
+			},
+		})
+	}
+
+	var protected []protectModel.EffectiveRange
+	for _, r := range opt.Parent.Protect.Ranges {
+		if p, err := cidr.Parse(r); err == nil {
+			protected = append(protected, protectModel.EffectiveRange{Cidr: p.String(), Source: protectModel.SourceConfig})
+		}
+	}
+	return rules, protected, nil
+}
+
+func printReport(out io.Writer, report Report) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "packets\t%d\t%d bytes\n", report.Packets, report.Bytes)
+	if !report.First.IsZero() {
+		fmt.Fprintf(w, "captured\t%s\t%s\n", report.First.Format(time.RFC3339), report.Last.Sub(report.First).Round(time.Millisecond))
+	}
+	fmt.Fprintf(w, "dropped\t%d\t%d bytes\n", report.Dropped, report.DroppedBytes)
+	fmt.Fprintf(w, "passed\t%d\t%d not ip\n", report.Passed, report.NotIP)
+	fmt.Fprintf(w, "vlan tagged\t%d\t\n", report.VLAN)
+	fmt.Fprintf(w, "undecided\t%d\t%d truncated, %d unsupported link types\n", report.Truncated+report.Unsupported, report.Truncated, report.Unsupported)
+	w.Flush()
+
+	if len(report.Rules) > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RULE\tSOURCE\tPACKETS\tBYTES")
+		for _, r := range report.Rules {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", r.Rule.Rule.RuleInfo.Key(), r.Rule.Source, r.Packets, r.Bytes)
+		}
+		w.Flush()
+	}
+
+	if len(report.Reasons) > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PACKETS\tBYTES\tDROPPED WITHOUT A RULE")
+		for _, r := range report.Reasons {
+			fmt.Fprintf(w, "%d\t%d\t%s\n", r.Packets, r.Bytes, r.Reason)
+		}
+		w.Flush()
+	}
+
+	if len(report.Sources) > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SOURCE\tPACKETS\tBYTES")
+		for _, s := range report.Sources {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", s.Src, s.Packets, s.Bytes)
+		}
+		w.Flush()
+	}
+}
@@ -0,0 +1,64 @@
+package replay
+
+import (
+	"errors"
+	"time"
+	"xdp-banner/orch/cmd/global"
+	"xdp-banner/orch/logic/rulecenter/bulk"
+
+	"github.com/spf13/cobra"
+)
+
+type Option struct {
+	// RuleSet is the rule set replayed with its includes, read from etcd
+	RuleSet string
+	// Rules is a rule file replayed instead, in Format
+	Rules    string
+	Format   string
+	Protocol string
+
+	// StripVLAN decodes the packets inside VLAN tags, like behind a NIC
+	// stripping them, the XDP program lets the tagged frames pass otherwise
+	StripVLAN bool
+	Top       int
+	JSON      bool
+	Timeout   time.Duration
+
+	Parent *global.ControllerOptions
+}
+
+func DefaultOption(parent *global.ControllerOptions) *Option {
+	return &Option{
+		Top:     10,
+		Timeout: time.Minute,
+		Parent:  parent,
+	}
+}
+
+func (o *Option) Check() error {
+	if (o.RuleSet == "") == (o.Rules == "") {
+		return errors.New("one of --ruleset and --rules is required")
+	}
+	if _, err := bulk.ParseFormat(o.Format); err != nil {
+		return err
+	}
+	if o.Top <= 0 {
+		return errors.New("top must be greater than 0")
+	}
+	if o.RuleSet != "" {
+		return o.Parent.Check()
+	}
+
+	return nil
+}
+
+func (o *Option) SetFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.RuleSet, "ruleset", o.RuleSet, "the rule set to replay with its includes and the protected ranges, read from etcd")
+	cmd.Flags().StringVar(&o.Rules, "rules", o.Rules, "a rule file to replay instead of a rule set, with the protect ranges of the config")
+	cmd.Flags().StringVar(&o.Format, "format", o.Format, "the format of the rule file, ndjson, csv or plain")
+	cmd.Flags().StringVar(&o.Protocol, "protocol", o.Protocol, "the protocol of the rules of the file without one")
+	cmd.Flags().BoolVar(&o.StripVLAN, "strip-vlan", o.StripVLAN, "decide the packets inside VLAN tags, as behind a NIC stripping them")
+	cmd.Flags().IntVar(&o.Top, "top", o.Top, "the number of top dropped sources reported")
+	cmd.Flags().BoolVar(&o.JSON, "json", o.JSON, "print the report as json")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "timeout of reading the rule set from etcd")
+}
@@ -0,0 +1,247 @@
+package replay
+
+import (
+	"cmp"
+	"encoding/binary"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+	"xdp-banner/orch/logic/rulecenter"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/datapath"
+	"xdp-banner/pkg/pcap"
+)
+
+const (
+	sllHeader  = 16
+	sll2Header = 20
+
+	reasonShort = "the XDP program drops the frames ending before the end of their IP header"
+)
+
+// Report is what the replay of a capture would drop
+type Report struct {
+	Packets int64 `json:"packets"`
+	Bytes   int64 `json:"bytes"`
+	// First and Last are the timestamps of the first and last packets
+	First time.Time `json:"first,omitzero"`
+	Last  time.Time `json:"last,omitzero"`
+
+	Dropped      int64 `json:"dropped"`
+	DroppedBytes int64 `json:"dropped_bytes"`
+	Passed       int64 `json:"passed"`
+	// NotIP are the passed frames the XDP program does not look into
+	NotIP int64 `json:"not_ip"`
+	// VLAN are the frames with a VLAN tag, decided or not
+	VLAN int64 `json:"vlan"`
+	// Truncated are the packets the capture misses headers of, and
+	// Unsupported the packets of link types not decoded, neither is decided
+	Truncated   int64 `json:"truncated"`
+	Unsupported int64 `json:"unsupported"`
+
+	// Rules are the rules dropping packets, Reasons the drops without a rule
+	Rules   []RuleCount   `json:"rules"`
+	Reasons []ReasonCount `json:"reasons"`
+	// Sources are the sources with the most dropped packets
+	Sources []SourceCount `json:"sources"`
+}
+
+type RuleCount struct {
+	Rule    model.EffectiveRule `json:"rule"`
+	Packets int64               `json:"packets"`
+	Bytes   int64               `json:"bytes"`
+}
+
+type ReasonCount struct {
+	Reason  string `json:"reason"`
+	Packets int64  `json:"packets"`
+	Bytes   int64  `json:"bytes"`
+}
+
+type SourceCount struct {
+	Src     string `json:"src"`
+	Packets int64  `json:"packets"`
+	Bytes   int64  `json:"bytes"`
+}
+
+type count struct {
+	packets, bytes int64
+}
+
+func (c *count) add(bytes int) {
+	c.packets++
+	c.bytes += int64(bytes)
+}
+
+// Replay decides the packets of captures with the maps of a rule set
+type Replay struct {
+	matcher   *rulecenter.Matcher
+	stripVLAN bool
+
+	report  Report
+	rules   map[*model.EffectiveRule]*count
+	reasons map[string]*count
+	sources map[netip.Addr]*count
+}
+
+func NewReplay(matcher *rulecenter.Matcher, stripVLAN bool) *Replay {
+	return &Replay{
+		matcher:   matcher,
+		stripVLAN: stripVLAN,
+		rules:     make(map[*model.EffectiveRule]*count),
+		reasons:   make(map[string]*count),
+		sources:   make(map[netip.Addr]*count),
+	}
+}
+
+// Add decides a captured packet
+func (r *Replay) Add(record pcap.Record) {
+	r.report.Packets++
+	r.report.Bytes += int64(record.Length)
+	if !record.Timestamp.IsZero() {
+		if r.report.First.IsZero() || record.Timestamp.Before(r.report.First) {
+			r.report.First = record.Timestamp
+		}
+		if record.Timestamp.After(r.report.Last) {
+			r.report.Last = record.Timestamp
+		}
+	}
+
+	packet, frame, ok := r.decode(record)
+	if !ok {
+		r.report.Unsupported++
+		return
+	}
+
+	switch frame {
+	case datapath.FrameNotIP:
+		r.report.NotIP++
+		r.report.Passed++
+	case datapath.FrameTruncated:
+		r.report.Truncated++
+	case datapath.FrameShort:
+		r.drop(record.Length, netip.Addr{}, nil, reasonShort)
+	case datapath.FrameIP:
+		v, rule := r.matcher.Decide(packet)
+		if !v.Drop {
+			r.report.Passed++
+			return
+		}
+		r.drop(record.Length, packet.Src, rule, v.Reason)
+	}
+}
+
+// decode decodes a record of its link type, false for an unsupported one
+func (r *Replay) decode(record pcap.Record) (datapath.Packet, datapath.Frame, bool) {
+	data, length := record.Data, record.Length
+
+	var etherType uint16
+	switch record.LinkType {
+	case pcap.LinkTypeEthernet:
+		packet, frame, vlan := datapath.DecodeEthernet(data, length, r.stripVLAN)
+		if vlan {
+			r.report.VLAN++
+		}
+		return packet, frame, true
+	case pcap.LinkTypeRaw:
+		if len(data) == 0 {
+			return datapath.Packet{}, datapath.FrameTruncated, true
+		}
+		switch data[0] >> 4 {
+		case 4:
+			etherType = datapath.EtherTypeIPv4
+		case 6:
+			etherType = datapath.EtherTypeIPv6
+		}
+	case pcap.LinkTypeIPv4:
+		etherType = datapath.EtherTypeIPv4
+	case pcap.LinkTypeIPv6:
+		etherType = datapath.EtherTypeIPv6
+	case pcap.LinkTypeLinuxSLL, pcap.LinkTypeLinuxSLL2:
+		header, protocol := sllHeader, 14
+		if record.LinkType == pcap.LinkTypeLinuxSLL2 {
+			header, protocol = sll2Header, 0
+		}
+		if len(data) < header {
+			return datapath.Packet{}, datapath.FrameTruncated, true
+		}
+		etherType = binary.BigEndian.Uint16(data[protocol:])
+		data, length = data[header:], length-header
+	default:
+		return datapath.Packet{}, 0, false
+	}
+
+	// 没有以太网头的链路, 按带有相同 ethertype 的以太网帧判定
+	packet, frame := datapath.DecodeIP(etherType, data, length)
+	return packet, frame, true
+}
+
+func (r *Replay) drop(length int, src netip.Addr, rule *model.EffectiveRule, reason string) {
+	r.report.Dropped++
+	r.report.DroppedBytes += int64(length)
+
+	if rule != nil {
+		c, ok := r.rules[rule]
+		if !ok {
+			c = &count{}
+			r.rules[rule] = c
+		}
+		c.add(length)
+	} else {
+		c, ok := r.reasons[reason]
+		if !ok {
+			c = &count{}
+			r.reasons[reason] = c
+		}
+		c.add(length)
+	}
+
+	if src.IsValid() {
+		c, ok := r.sources[src]
+		if !ok {
+			c = &count{}
+			r.sources[src] = c
+		}
+		c.add(length)
+	}
+}
+
+// Report returns the report of the packets added, with the top sources
+func (r *Replay) Report(top int) Report {
+	report := r.report
+
+	report.Rules = make([]RuleCount, 0, len(r.rules))
+	for rule, c := range r.rules {
+		report.Rules = append(report.Rules, RuleCount{Rule: *rule, Packets: c.packets, Bytes: c.bytes})
+	}
+	slices.SortFunc(report.Rules, func(a, b RuleCount) int {
+		return cmp.Or(
+			cmp.Compare(b.Packets, a.Packets),
+			strings.Compare(a.Rule.Rule.RuleInfo.Key(), b.Rule.Rule.RuleInfo.Key()),
+			strings.Compare(a.Rule.Source, b.Rule.Source),
+		)
+	})
+
+	report.Reasons = make([]ReasonCount, 0, len(r.reasons))
+	for reason, c := range r.reasons {
+		report.Reasons = append(report.Reasons, ReasonCount{Reason: reason, Packets: c.packets, Bytes: c.bytes})
+	}
+	slices.SortFunc(report.Reasons, func(a, b ReasonCount) int {
+		return cmp.Or(cmp.Compare(b.Packets, a.Packets), strings.Compare(a.Reason, b.Reason))
+	})
+
+	sources := make([]netip.Addr, 0, len(r.sources))
+	for src := range r.sources {
+		sources = append(sources, src)
+	}
+	slices.SortFunc(sources, func(a, b netip.Addr) int {
+		return cmp.Or(cmp.Compare(r.sources[b].packets, r.sources[a].packets), a.Compare(b))
+	})
+	report.Sources = make([]SourceCount, 0, min(top, len(sources)))
+	for _, src := range sources[:min(top, len(sources))] {
+		c := r.sources[src]
+		report.Sources = append(report.Sources, SourceCount{Src: src.String(), Packets: c.packets, Bytes: c.bytes})
+	}
+	return report
+}
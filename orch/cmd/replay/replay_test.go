@@ -0,0 +1,77 @@
+package replay
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+	"xdp-banner/orch/logic/rulecenter"
+	protectModel "xdp-banner/orch/model/protect"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/datapath"
+	"xdp-banner/pkg/pcap"
+	"xdp-banner/pkg/rule"
+)
+
+// frame builds an Ethernet frame of an IPv4 TCP or UDP packet
+func frame(src string, protocol uint8, sport, dport uint16) []byte {
+	data := make([]byte, 14+20+20)
+	binary.BigEndian.PutUint16(data[12:], datapath.EtherTypeIPv4)
+	data[14] = 0x45
+	data[14+9] = protocol
+	a := netip.MustParseAddr(src).As4()
+	copy(data[14+12:], a[:])
+	binary.BigEndian.PutUint16(data[34:], sport)
+	binary.BigEndian.PutUint16(data[36:], dport)
+	return data
+}
+
+func TestReplay(t *testing.T) {
+	r := func(cidr, protocol string, dport uint16, identity string) model.EffectiveRule {
+		return model.EffectiveRule{Source: "web", Rule: model.Rule{
+			RuleMeta: rule.RuleMeta{Identity: identity},
+			RuleInfo: rule.RuleInfo{Cidr: cidr, Protocol: protocol, Dport: dport},
+		}}
+	}
+	rules := []model.EffectiveRule{
+		r("10.0.0.0/8", "TCP", 22, "1"),
+		r("10.0.0.0/8", "UDP", 0, "1"),
+	}
+	protected := []protectModel.EffectiveRange{{Cidr: "10.2.0.0/16", Source: "config"}}
+	replay := NewReplay(rulecenter.NewMatcher(rules, protected), false)
+
+	records := []pcap.Record{
+		{LinkType: pcap.LinkTypeEthernet, Data: frame("10.0.0.1", datapath.ProtoTCP, 4000, 22)},
+		{LinkType: pcap.LinkTypeEthernet, Data: frame("10.0.0.1", datapath.ProtoTCP, 4001, 22)},
+		{LinkType: pcap.LinkTypeEthernet, Data: frame("10.0.0.2", datapath.ProtoUDP, 4000, 53)},
+		{LinkType: pcap.LinkTypeEthernet, Data: frame("10.0.0.2", datapath.ProtoTCP, 4000, 80)},
+		{LinkType: pcap.LinkTypeEthernet, Data: frame("10.2.0.1", datapath.ProtoTCP, 4000, 22)},
+		// 其他协议直接丢弃
+		{LinkType: pcap.LinkTypeEthernet, Data: frame("10.0.0.3", 47, 0, 0)},
+		{LinkType: pcap.LinkTypeEthernet, Data: frame("192.0.2.1", datapath.ProtoTCP, 4000, 22)[:40]},
+		// Linux cooked capture, 没有以太网头
+		{LinkType: pcap.LinkTypeLinuxSLL, Data: append(make([]byte, 2), frame("10.0.0.1", datapath.ProtoTCP, 4000, 22)...)},
+		{LinkType: 9999, Data: make([]byte, 60)},
+	}
+	for i := range records {
+		records[i].Length = len(records[i].Data)
+	}
+	records[6].Length = 60
+
+	for _, record := range records {
+		replay.Add(record)
+	}
+	report := replay.Report(1)
+
+	if report.Packets != 9 || report.Dropped != 5 || report.Passed != 2 || report.Truncated != 1 || report.Unsupported != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	if len(report.Rules) != 2 || report.Rules[0].Rule.Rule.RuleInfo.Key() != rules[0].Rule.RuleInfo.Key() || report.Rules[0].Packets != 3 || report.Rules[1].Packets != 1 {
+		t.Errorf("rules = %+v", report.Rules)
+	}
+	if len(report.Reasons) != 1 || report.Reasons[0].Packets != 1 {
+		t.Errorf("reasons = %+v", report.Reasons)
+	}
+	if len(report.Sources) != 1 || report.Sources[0].Src != "10.0.0.1" || report.Sources[0].Packets != 3 {
+		t.Errorf("sources = %+v", report.Sources)
+	}
+}
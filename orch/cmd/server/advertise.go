@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"xdp-banner/orch/logic"
+	"xdp-banner/pkg/log"
+	"xdp-banner/pkg/node"
+)
+
+// advertise records the grpc endpoint of this orch in its info, agents
+// discover the other orchestrators of the cluster from it
+func advertise(ctx context.Context, opt *Option, logic *logic.Logic) error {
+	endpoint, err := advertiseAddr(opt)
+	if err != nil {
+		return err
+	}
+
+	name, err := node.Name()
+	if err != nil {
+		return fmt.Errorf("get node name: %w", err)
+	}
+
+	if err := logic.Orch.Advertise(ctx, name, endpoint); err != nil {
+		return err
+	}
+
+	log.Info("advertised grpc endpoint to the agents", log.StringField("endpoint", endpoint))
+	return nil
+}
+
+func advertiseAddr(opt *Option) (string, error) {
+	if opt.AdvertiseAddr != "" {
+		return opt.AdvertiseAddr, nil
+	}
+
+	_, port, err := net.SplitHostPort(opt.GrpcAddr)
+	if err != nil {
+		return "", fmt.Errorf("invalid grpc addr %q: %w", opt.GrpcAddr, err)
+	}
+
+	ip, err := node.DefaultIP()
+	if err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(ip.String(), port), nil
+}
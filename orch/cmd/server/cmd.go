@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
 	"sync"
@@ -9,10 +10,14 @@ import (
 	"xdp-banner/orch/cmd/global"
 	"xdp-banner/orch/logic"
 	"xdp-banner/orch/storage"
+	"xdp-banner/pkg/config"
+	"xdp-banner/pkg/election"
 	"xdp-banner/pkg/log"
+	"xdp-banner/pkg/notify"
 	"xdp-banner/pkg/otlp"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"go.opentelemetry.io/contrib/instrumentation/runtime"
 	"go.uber.org/zap"
 )
@@ -24,11 +29,16 @@ func Cmd(parentOpt *global.ControllerOptions) *cobra.Command {
 		Use:   "server",
 		Short: "Start the server",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			loader := opt.Parent.Loader(cmd.Flags())
+			v, err := loader.Load(opt.sections())
+			if err != nil {
+				return fmt.Errorf("failed to load config %q: %w", opt.Parent.ConfigFile, err)
+			}
 			if err := opt.Check(); err != nil {
 				return err
 			}
 
-			run(opt)
+			run(opt, loader, v)
 			return nil
 		},
 	}
@@ -38,8 +48,15 @@ func Cmd(parentOpt *global.ControllerOptions) *cobra.Command {
 	return cmd
 }
 
-func run(opt *Option) {
-	setupOtlp(opt)
+func run(opt *Option, loader config.Loader, v *viper.Viper) {
+	if err := log.SetLevel(opt.Parent.Log.Level); err != nil {
+		log.Fatal("invalid log level", zap.Error(err))
+	}
+	logging := config.NewLogging("orch")
+	if err := logging.Setup(opt.Otlp); err != nil {
+		log.Fatal("otlp log error", zap.Error(err))
+	}
+	setupOtlpMetric(opt)
 	setupOtelInstrumentation()
 
 	// Cli init
@@ -55,13 +72,40 @@ func run(opt *Option) {
 	defer cancel()
 
 	storage := storage.New(ctx, global.Cli)
-	logic := logic.New(storage)
+	logic := logic.New(storage, opt.Parent.Validation, opt.Parent.Protect, opt.Parent.History)
+
+	if legacy, err := storage.Rule.HasLegacyNames(ctx); err == nil && legacy {
+		log.Warn("rule set names are still in the list of older versions, ListRule misses them until `orch migrate-names` runs")
+	}
+	// 旧版本的 identity 是哈希得到的且没有注册, 注册之后新分配的 identity 才会跳过它们
+	if n, err := storage.Rule.RegisterIdentities(ctx); err != nil {
+		log.Warn("failed to register the identities of older versions, new identities may collide with them until CheckIdentities repairs them", log.ErrorField(err))
+	} else if n > 0 {
+		log.Info("registered the identities of older versions", log.IntField("identities", n))
+	}
 
-	go runController(ctx, global.Cli, logic, wg, errChan)
+	if err := advertise(ctx, opt, logic); err != nil {
+		// agents 仍然可以使用配置中的 endpoints, 只是无法自动发现这个 orch
+		log.Warn("failed to advertise grpc endpoint", log.ErrorField(err))
+	}
+
+	elec, err := newElection(ctx, global.Cli)
+	if err != nil {
+		log.Fatal("failed to create election", zap.Error(err))
+	}
+
+	go runController(ctx, global.Cli, elec, logic, wg, errChan)
 	go runServer(ctx, opt, logic, wg, errChan)
+	go watchConfig(ctx, v, loader, opt, logging)
+
+	go notify.RunWatchdog(ctx, func() string { return statusLine(elec) },
+		notify.Check{Name: "etcd session", Check: elec.Alive},
+	)
+	_ = notify.Ready()
 
 	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, os.Interrupt, syscall.SIGHUP, syscall.SIGTERM)
+	// SIGHUP 用来重新加载配置, 见 watchConfig
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
 	defer func() {
 		signal.Stop(signals)
 	}()
@@ -73,37 +117,17 @@ func run(opt *Option) {
 		log.Info("receive exit signal, exiting", log.AnyField("signal", sig))
 	}
 
+	_ = notify.Stopping()
 	cancel()
 	wg.Wait()
 }
 
-func setupOtlp(opt *Option) {
-	setupOtlpLog(opt)
-	setupOtlpMetric(opt)
-}
-
-func setupOtlpLog(opt *Option) {
-	res, err := otlp.DefaultResource("orch")
-	if err != nil {
-		log.Fatal("otlp resource error", zap.Error(err))
-	}
-
-	core, err := otlp.NewLog(
-		otlp.WithName("orch"),
-		otlp.WithHTTPEndpoint(opt.Otlp.LoggerHTTPEndpoint),
-		otlp.WithGrpcEndpoint(opt.Otlp.LoggerGrpcEndpoint),
-		otlp.WithResource(res),
-		otlp.WithInsecure(opt.Otlp.Insecure),
-		otlp.WithGzip(opt.Otlp.Gzip),
-		otlp.WithHeader(opt.Otlp.Header),
-		otlp.WithTimeout(opt.Otlp.Timeout),
-	)
-	if err != nil {
-		log.Fatal("otlp log error", zap.Error(err))
+// statusLine summarizes the orchestrator for the STATUS= line of systemd
+func statusLine(elec *election.Election) string {
+	if elec.IsLeader() {
+		return "serving, leader"
 	}
-
-	core = log.NewTreeWithDefaultLogger(core)
-	log.SetGlobalLogger(zap.New(core))
+	return "serving, follower"
 }
 
 func setupOtlpMetric(opt *Option) {
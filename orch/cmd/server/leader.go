@@ -4,11 +4,13 @@ import (
 	"context"
 	"sync"
 	nodeController "xdp-banner/orch/internal/controller/node"
+	scheduleController "xdp-banner/orch/internal/controller/schedule"
 	strategyController "xdp-banner/orch/internal/controller/strategy"
 	"xdp-banner/orch/internal/otlp"
 	"xdp-banner/orch/logic"
 	applieds "xdp-banner/orch/storage/agent/applied"
 	nodes "xdp-banner/orch/storage/agent/node"
+	schedules "xdp-banner/orch/storage/agent/schedule"
 	"xdp-banner/pkg/election"
 	"xdp-banner/pkg/etcd"
 	"xdp-banner/pkg/informer"
@@ -19,26 +21,25 @@ import (
 	"go.opentelemetry.io/otel/metric"
 )
 
-func runController(ctx context.Context, client etcd.Client, logic *logic.Logic, wg *sync.WaitGroup, errChan chan error) {
-	wg.Add(1)
-	defer wg.Done()
-
+func newElection(ctx context.Context, client etcd.Client) (*election.Election, error) {
 	nodeName, err := node.Name()
 	if err != nil {
-		errChan <- err
+		return nil, err
 	}
 
+	return election.New(ctx, client, election.NodeInfo{Name: nodeName})
+}
+
+func runController(ctx context.Context, client etcd.Client, elec *election.Election, logic *logic.Logic, wg *sync.WaitGroup, errChan chan error) {
+	wg.Add(1)
+	defer wg.Done()
+
 	isLeader, err := otlp.Meter().Int64Gauge("is_Leader", metric.WithDescription("current node is leader or not"))
 	if err != nil {
 		errChan <- err
 	}
 	isLeader.Record(ctx, 0)
 
-	elec, err := election.New(ctx, client, election.NodeInfo{Name: nodeName})
-	if err != nil {
-		errChan <- err
-	}
-
 	leaderChan := elec.Subscribe("controller")
 
 	ws := wait.SingleInstance{
@@ -91,19 +92,22 @@ type controller struct {
 	informer   informer.Informer
 	nc         nodeController.NodeController
 	sc         strategyController.StrategyController
+	schc       scheduleController.ScheduleController
 }
 
 func newController(client etcd.Client, logic *logic.Logic) *controller {
-	deltaFIFO := informer.NewDeltaFIFOWithWait(2)
+	deltaFIFO := informer.NewDeltaFIFOWithWait(3)
 
 	reflectors := []*informer.Reflector{
 		informer.NewReflector(client, "agentnode_reflector", nodes.EtcdDir, deltaFIFO),
 		informer.NewReflector(client, "applied_reflector", applieds.EtcdDirRunning, deltaFIFO),
+		informer.NewReflector(client, "schedule_reflector", schedules.EtcdDir, deltaFIFO),
 	}
 	i := informer.New(deltaFIFO)
 
 	nc := nodeController.New(i)
 	sc := strategyController.New(i, logic.Control, logic.Applied)
+	schc := scheduleController.New(i, logic.ConfigCenter)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -115,6 +119,7 @@ func newController(client etcd.Client, logic *logic.Logic) *controller {
 		informer:   i,
 		nc:         nc,
 		sc:         sc,
+		schc:       schc,
 	}
 }
 
@@ -126,6 +131,7 @@ func (c *controller) Run() {
 	c.wg.StartWithContext(c.ctx, c.informer.Run)
 	c.wg.StartWithContext(c.ctx, c.nc.Run)
 	c.wg.StartWithContext(c.ctx, c.sc.Run)
+	c.wg.StartWithContext(c.ctx, c.schc.Run)
 }
 
 func (c *controller) Stop() {
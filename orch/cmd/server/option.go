@@ -2,6 +2,7 @@ package server
 
 import (
 	"fmt"
+	"net"
 	"xdp-banner/orch/cmd/global"
 	"xdp-banner/pkg/option"
 
@@ -9,11 +10,13 @@ import (
 )
 
 type Option struct {
-	Parent *global.ControllerOptions
+	Parent *global.ControllerOptions `mapstructure:"-"`
 
-	GrpcAddr string
-	HttpAddr string
-	Otlp     *option.OtlpOption
+	GrpcAddr string `mapstructure:"grpcAddr"`
+	HttpAddr string `mapstructure:"httpAddr"`
+	// AdvertiseAddr is the grpc address the agents discover, the default ip and the grpc port when empty
+	AdvertiseAddr string             `mapstructure:"advertiseAddr"`
+	Otlp          *option.OtlpOption `mapstructure:"otlp"`
 }
 
 func DefaultOption(parent *global.ControllerOptions) *Option {
@@ -25,6 +28,11 @@ func DefaultOption(parent *global.ControllerOptions) *Option {
 	}
 }
 
+// sections maps the sections of the config file to the options
+func (o *Option) sections() map[string]any {
+	return map[string]any{"": o.Parent, "server": o}
+}
+
 func (o *Option) Check() error {
 	if err := o.Parent.Check(); err != nil {
 		return err
@@ -34,6 +42,12 @@ func (o *Option) Check() error {
 		return fmt.Errorf("grpc addr is empty")
 	}
 
+	if o.AdvertiseAddr != "" {
+		if _, _, err := net.SplitHostPort(o.AdvertiseAddr); err != nil {
+			return fmt.Errorf("invalid advertise addr %q: %w", o.AdvertiseAddr, err)
+		}
+	}
+
 	return nil
 }
 
@@ -43,4 +57,5 @@ func (o *Option) SetFlags(cmd *cobra.Command) {
 
 	cmd.Flags().StringVar(&o.GrpcAddr, "server.grpc-addr", o.GrpcAddr, "set grpc address")
 	cmd.Flags().StringVar(&o.HttpAddr, "server.http-addr", o.HttpAddr, "set http address")
+	cmd.Flags().StringVar(&o.AdvertiseAddr, "server.advertise-addr", o.AdvertiseAddr, "grpc address advertised to the agents, default ip with the grpc port when empty")
 }
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"reflect"
+
+	"xdp-banner/orch/cmd/global"
+	"xdp-banner/pkg/config"
+
+	"github.com/spf13/viper"
+)
+
+// watchConfig applies the safe fields of the config on SIGHUP or when the file changes
+func watchConfig(ctx context.Context, v *viper.Viper, loader config.Loader, opt *Option, logging *config.Logging) {
+	r := &config.Reloader[Option]{
+		Loader:  loader,
+		Logging: logging,
+		Fresh: func() (*Option, map[string]any) {
+			fresh := DefaultOption(global.DefaultOption())
+			return fresh, fresh.sections()
+		},
+		Common: func(o *Option) config.Common {
+			return config.Common{Level: &o.Parent.Log.Level, Otlp: o.Otlp}
+		},
+		Check: (*Option).Check,
+		Apply: needRestart,
+	}
+	r.Watch(ctx, v, opt)
+}
+
+// needRestart reports whether fields which only take effect after a restart
+// changed, the etcd client, the listeners and the metric exporter are
+// created at start
+func needRestart(fresh, old *Option) bool {
+	return fresh.GrpcAddr != old.GrpcAddr || fresh.HttpAddr != old.HttpAddr || fresh.AdvertiseAddr != old.AdvertiseAddr ||
+		fresh.Otlp.MetricChanged(old.Otlp) || fresh.Parent.ControllerName != old.Parent.ControllerName ||
+		!reflect.DeepEqual(fresh.Parent.Etcd, old.Parent.Etcd)
+}
@@ -3,6 +3,7 @@ package node
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"xdp-banner/api/orch/v1/agent/report"
@@ -47,13 +48,21 @@ func New(i informer.Informer) NodeController {
 type nodeController struct {
 	cp      clientPool
 	metrics metrics
+
+	// recoveries are the reloads of the unhealthy nodes, see needRecoverNode
+	recoveries *recoveries
+	// reload reloads a node, reloadNode except in tests
+	reload func(node) error
 }
 
 func newNodeController() *nodeController {
-	return &nodeController{
-		cp:      newClientPool(),
-		metrics: newMetrics(),
+	n := &nodeController{
+		cp:         newClientPool(),
+		metrics:    newMetrics(),
+		recoveries: newRecoveries(),
 	}
+	n.reload = n.reloadNode
+	return n
 }
 
 func (n *nodeController) SyncHandler(key string, informer informer.Informer) error {
@@ -142,6 +151,22 @@ func (r *nodeController) reconcile(node node) error {
 		return r.reloadNode(node)
 	}
 
+	now := time.Now()
+	recover, wait := needRecoverNode(node, now)
+	switch {
+	case recover:
+		// 重新加载后仍不健康的节点按退避时间重试, 不在每次同步时重新加载
+		if next, ok := r.recoveries.allow(node.info.Name, now); !ok {
+			logger.Debug("node is unhealthy, wait for the backoff to reload it", log.AnyField("next", next))
+			return fmt.Errorf("need retry")
+		}
+		logger.Info("node is unhealthy, reload it", log.StringField("phase", node.status.Phase))
+		return r.reload(node)
+	case wait:
+		return fmt.Errorf("need retry")
+	}
+
+	r.recoveries.reset(node.info.Name)
 	return nil
 }
 
@@ -187,7 +212,7 @@ func (r *nodeController) startNode(node node) error {
 }
 
 func needReloadNode(node node) bool {
-	if node.status.Phase != report.Phase_Running.String() {
+	if !report.IsActive(report.ToPhase(node.status.Phase)) {
 		return false
 	}
 
@@ -198,6 +223,91 @@ func needReloadNode(node node) bool {
 	return false
 }
 
+// recoverGrace is how long an agent may stay Failed, or Degraded by a lost
+// rule stream, before it is reloaded. Reload attaches the interfaces and
+// opens the rule stream again.
+const recoverGrace = time.Minute
+
+const (
+	conditionInterfacesAttached  = "InterfacesAttached"
+	conditionRuleStreamConnected = "RuleStreamConnected"
+)
+
+// needRecoverNode reports whether the node should be reloaded now, or
+// whether it is unhealthy but still in its grace period
+func needRecoverNode(node node, now time.Time) (recover bool, wait bool) {
+	var since time.Time
+
+	switch node.status.Phase {
+	case report.Phase_Failed.String():
+		if c := node.status.Condition(conditionInterfacesAttached); c != nil {
+			since = c.LastTransitionTime
+		}
+	case report.Phase_Degraded.String():
+		c := node.status.Condition(conditionRuleStreamConnected)
+		if c == nil || c.Status {
+			// degraded for other reasons, e.g. map usage, reload does not help
+			return false, false
+		}
+		since = c.LastTransitionTime
+	default:
+		return false, false
+	}
+
+	if now.Sub(since) < recoverGrace {
+		return false, true
+	}
+	return true, false
+}
+
+const (
+	// recoverBackoff is the wait after the first reload of an unhealthy
+	// node before the next one, it doubles with each reload up to
+	// maxRecoverBackoff while the node stays unhealthy
+	recoverBackoff    = time.Minute
+	maxRecoverBackoff = 30 * time.Minute
+)
+
+// recoveries records the last reload of the unhealthy nodes
+type recoveries struct {
+	mu       sync.Mutex
+	attempts map[string]recoveryAttempt
+}
+
+type recoveryAttempt struct {
+	at    time.Time
+	count int
+}
+
+func newRecoveries() *recoveries {
+	return &recoveries{attempts: make(map[string]recoveryAttempt)}
+}
+
+// allow reports whether the node may be reloaded now and records the reload,
+// otherwise it returns when it may
+func (rs *recoveries) allow(name string, now time.Time) (time.Time, bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	last, ok := rs.attempts[name]
+	if ok {
+		backoff := recoverBackoff << min(last.count-1, 5)
+		if next := last.at.Add(min(backoff, maxRecoverBackoff)); now.Before(next) {
+			return next, false
+		}
+	}
+	rs.attempts[name] = recoveryAttempt{at: now, count: last.count + 1}
+	return now, true
+}
+
+// reset forgets the reloads of a node which is healthy again
+func (rs *recoveries) reset(name string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	delete(rs.attempts, name)
+}
+
 func (r *nodeController) reloadNode(node node) error {
 	client, err := r.cp.connect(node.status.GrpcEndpoint)
 	if err != nil {
@@ -0,0 +1,72 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"xdp-banner/api/orch/v1/agent/report"
+	nodem "xdp-banner/orch/model/node"
+)
+
+func TestReconcileRecoverBackoff(t *testing.T) {
+	reloads := 0
+	r := &nodeController{
+		recoveries: newRecoveries(),
+		reload: func(node) error {
+			reloads++
+			return nil
+		},
+	}
+
+	failed := node{
+		info: &nodem.AgentInfo{CommonInfo: nodem.CommonInfo{Name: "agent-1"}, Enable: true, Config: "default"},
+		status: &nodem.AgentStatus{
+			Phase:  report.Phase_Failed.String(),
+			Config: "default",
+			Conditions: []nodem.Condition{{
+				Type:               conditionInterfacesAttached,
+				LastTransitionTime: time.Now().Add(-2 * recoverGrace),
+			}},
+		},
+	}
+
+	if err := r.reconcile(failed); err != nil || reloads != 1 {
+		t.Fatalf("first reconcile = %v, %d reloads", err, reloads)
+	}
+	// 退避时间内不再重新加载
+	if err := r.reconcile(failed); err == nil || reloads != 1 {
+		t.Fatalf("second reconcile = %v, %d reloads", err, reloads)
+	}
+
+	// 节点恢复后重新计算退避
+	healthy := node{info: failed.info, status: &nodem.AgentStatus{Phase: report.Phase_Running.String(), Config: "default"}}
+	if err := r.reconcile(healthy); err != nil {
+		t.Fatalf("healthy reconcile = %v", err)
+	}
+	if err := r.reconcile(failed); err != nil || reloads != 2 {
+		t.Fatalf("reconcile after recovery = %v, %d reloads", err, reloads)
+	}
+}
+
+func TestRecoveriesBackoff(t *testing.T) {
+	rs := newRecoveries()
+	now := time.Now()
+
+	if _, ok := rs.allow("a", now); !ok {
+		t.Fatal("first reload refused")
+	}
+	if next, ok := rs.allow("a", now.Add(recoverBackoff/2)); ok || !next.Equal(now.Add(recoverBackoff)) {
+		t.Fatalf("reload inside the backoff = %s, %v", next, ok)
+	}
+	now = now.Add(recoverBackoff)
+	if _, ok := rs.allow("a", now); !ok {
+		t.Fatal("reload after the backoff refused")
+	}
+	// 第二次之后退避时间加倍
+	if next, ok := rs.allow("a", now.Add(recoverBackoff)); ok || !next.Equal(now.Add(2*recoverBackoff)) {
+		t.Fatalf("second backoff = %s, %v", next, ok)
+	}
+	if _, ok := rs.allow("b", now); !ok {
+		t.Fatal("reload of another node refused")
+	}
+}
@@ -0,0 +1,97 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"xdp-banner/orch/logic/rulecenter"
+	model "xdp-banner/orch/model/rule"
+	schedules "xdp-banner/orch/storage/agent/schedule"
+
+	"xdp-banner/pkg/controller"
+	"xdp-banner/pkg/etcd"
+	"xdp-banner/pkg/informer"
+	"xdp-banner/pkg/log"
+)
+
+const (
+	// maxRequeue bounds the wait for the next transition, a clock jump or a
+	// rule deleted by hand is caught up within it
+	maxRequeue = time.Hour
+	// syncTimeout bounds the etcd calls of a sync
+	syncTimeout = 10 * time.Second
+)
+
+var _ controller.ControllerImpl = (*scheduleController)(nil)
+
+type ScheduleController struct {
+	*controller.Controller
+}
+
+// New returns the controller adding the scheduled rules to their rule sets
+// at the start of their windows and withdrawing them at the end, agents only
+// see the PUT and DELETE of ordinary rules.
+func New(i informer.Informer, rules *rulecenter.RuleCenter) ScheduleController {
+	sc := &scheduleController{rules: rules}
+
+	c := controller.New(i, controller.ControllerOption{
+		Name: "schedule",
+		Impl: sc,
+	})
+	sc.controller = c
+
+	return ScheduleController{
+		Controller: c,
+	}
+}
+
+type scheduleController struct {
+	rules      *rulecenter.RuleCenter
+	controller *controller.Controller
+}
+
+func (sc *scheduleController) ListenPrefix() []string {
+	return []string{schedules.EtcdDir}
+}
+
+func (sc *scheduleController) KeyProcessor(key etcd.Key) etcd.Key {
+	return key
+}
+
+func (sc *scheduleController) SyncHandler(key string, informer informer.Informer) error {
+	raw, exist, err := informer.Get(key)
+	if err != nil {
+		return fmt.Errorf("get scheduled rule failed %w", err)
+	}
+
+	var sr *model.ScheduledRule
+	if exist {
+		rawStr, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("scheduled rule data is not string")
+		}
+		sr = new(model.ScheduledRule)
+		if err := sr.Unmarshal([]byte(rawStr)); err != nil {
+			return fmt.Errorf("unmarshal scheduled rule failed %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+
+	now := time.Now()
+	next, err := sc.rules.ReconcileSchedule(ctx, key, sr, now)
+	if err != nil {
+		return err
+	}
+
+	// 删除的定时规则和没有后续窗口的定时规则不再需要唤醒
+	if sr == nil || next.IsZero() {
+		return nil
+	}
+	wait := min(next.Sub(now), maxRequeue)
+	log.Debug("scheduled rule synced", log.StringField("key", key), log.StringField("next", next.Format(time.RFC3339)))
+	sc.controller.EnqueueAfter(key, max(wait, time.Second))
+	return nil
+}
@@ -1,17 +1,24 @@
 package control
 
-import "xdp-banner/orch/storage/agent/node"
+import (
+	"xdp-banner/orch/storage/agent/node"
+	"xdp-banner/orch/storage/agent/rule"
+)
 
 type Control struct {
 	registers node.RegisterStorage
 	infos     node.InfoStorage
 	statuss   node.StatusStorage
+	rules     rule.Storage
+	dial      dialAgent
 }
 
-func New(rs node.RegisterStorage, is node.InfoStorage, ss node.StatusStorage) *Control {
+func New(rs node.RegisterStorage, is node.InfoStorage, ss node.StatusStorage, rules rule.Storage) *Control {
 	return &Control{
 		registers: rs,
 		infos:     is,
 		statuss:   ss,
+		rules:     rules,
+		dial:      dialAgentInsecure,
 	}
 }
@@ -0,0 +1,295 @@
+package control
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+	agentcontrol "xdp-banner/api/agent/v1/control"
+	"xdp-banner/api/orch/v1/agent/report"
+	nodem "xdp-banner/orch/model/node"
+	rulem "xdp-banner/orch/model/rule"
+	"xdp-banner/orch/server"
+	"xdp-banner/orch/storage/agent/node"
+	"xdp-banner/pkg/errors"
+
+	"google.golang.org/grpc"
+)
+
+const datapathTimeout = 10 * time.Second
+
+// protocols is the same mapping the agent uses to write the banlist
+var protocols = map[string]uint32{
+	"TCP": 6, "tcp": 6,
+	"UDP": 17, "udp": 17,
+	"ICMP": 1, "icmp": 1,
+}
+
+// dialAgent connects to the grpc control service of an agent
+type dialAgent func(endpoint string) (agentcontrol.ControlServiceClient, func() error, error)
+
+func dialAgentInsecure(endpoint string) (agentcontrol.ControlServiceClient, func() error, error) {
+	creds, err := server.NewCreditsInsecure()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create credentials: %w", err)
+	}
+
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return agentcontrol.NewControlServiceClient(conn), conn.Close, nil
+}
+
+// CheckDatapath compares the maps of an agent, or of all running agents when
+// name is empty, with the rules of its config. With repair the full rule set
+// of the config is pushed to agents which drifted.
+func (c *Control) CheckDatapath(ctx context.Context, name string, repair bool) (nodem.DatapathReportItems, error) {
+	if name != "" {
+		status, err := c.GetStatus(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if !report.IsActive(report.ToPhase(status.Phase)) {
+			return nil, errors.NewInputErrorf("agent is %s, not running", status.Phase)
+		}
+
+		return nodem.DatapathReportItems{name: c.checkAgent(ctx, status, repair)}, nil
+	}
+
+	reports := make(nodem.DatapathReportItems)
+	cursor := ""
+	for {
+		il, err := c.infos.List(ctx, 100, cursor)
+		if err != nil {
+			return nil, errors.NewServiceErrorf("failed to list agents: %v", err)
+		}
+
+		for _, info := range il.Items {
+			status, err := c.statuss.Get(ctx, info.Name, false)
+			if err == node.ErrStatusNotFound {
+				continue
+			} else if err != nil {
+				return nil, errors.NewServiceErrorf("get status failed, %v", err)
+			}
+			if !report.IsActive(report.ToPhase(status.Phase)) {
+				continue
+			}
+
+			reports[info.Name] = c.checkAgent(ctx, status, repair)
+		}
+
+		if !il.HasNext {
+			return reports, nil
+		}
+		cursor = il.NextCursor
+	}
+}
+
+// checkAgent never fails, errors of a single agent are put into its report
+func (c *Control) checkAgent(ctx context.Context, status *nodem.AgentStatus, repair bool) *nodem.DatapathReport {
+	r := &nodem.DatapathReport{Config: status.Config}
+
+	cli, closeConn, err := c.dial(status.GrpcEndpoint)
+	if err != nil {
+		r.Error = fmt.Sprintf("connect agent: %v", err)
+		return r
+	}
+	defer closeConn()
+
+	dctx, cancel := context.WithTimeout(ctx, datapathTimeout)
+	defer cancel()
+
+	dump, err := cli.DumpMaps(dctx, &agentcontrol.DumpMapsRequest{})
+	if err != nil {
+		r.Error = fmt.Sprintf("dump maps: %v", err)
+		return r
+	}
+	if dump.ConfigName != "" {
+		r.Config = dump.ConfigName
+	}
+
+	rules, err := c.rules.GetRuleKeys(ctx, r.Config)
+	if err != nil {
+		r.Error = fmt.Sprintf("get rules: %v", err)
+		return r
+	}
+
+	diffDatapath(r, rules, dump)
+	if !repair || r.InSync() {
+		return r
+	}
+
+	_, err = cli.RepairMaps(dctx, &agentcontrol.RepairMapsRequest{
+		ConfigName: r.Config,
+		Rules:      rulesToMapRules(rules),
+	})
+	if err != nil {
+		r.Error = fmt.Sprintf("repair maps: %v", err)
+		return r
+	}
+	r.Repaired = true
+
+	return r
+}
+
+// matchKey identifies a banlist entry regardless of its identity
+type matchKey struct {
+	cidr     string
+	protocol uint32
+	sport    uint32
+	dport    uint32
+}
+
+// diffDatapath fills r with the difference between the etcd rules of the
+// config and the dumped maps. Entries written for the agent's local rules and
+// rules covered by its allow list are not considered drift.
+func diffDatapath(r *nodem.DatapathReport, rules map[string]rulem.Rule, dump *agentcontrol.DumpMapsResponse) {
+	var allows []*net.IPNet
+	allowed := make(map[string]bool, len(dump.AllowedCidrs))
+	for _, cidr := range dump.AllowedCidrs {
+		if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+			allows = append(allows, ipNet)
+			allowed[ipNet.String()] = true
+		}
+	}
+
+	expected := make(map[matchKey]nodem.DatapathEntry, len(rules))
+	for key, rule := range rules {
+		_, ipNet, err := net.ParseCIDR(rule.RuleInfo.Cidr)
+		if err != nil {
+			continue
+		}
+		proto, ok := protocols[rule.RuleInfo.Protocol]
+		if !ok || coveredBy(ipNet, allows) {
+			// the agent never writes these
+			continue
+		}
+
+		expected[matchKey{ipNet.String(), proto, uint32(rule.RuleInfo.Sport), uint32(rule.RuleInfo.Dport)}] = nodem.DatapathEntry{
+			Key:      key,
+			Cidr:     ipNet.String(),
+			Identity: rule.RuleMeta.Identity,
+			Protocol: rule.RuleInfo.Protocol,
+			Sport:    rule.RuleInfo.Sport,
+			Dport:    rule.RuleInfo.Dport,
+		}
+	}
+
+	local := make(map[matchKey]string, len(dump.LocalRules))
+	localCidrs := make(map[string]bool, len(dump.LocalRules))
+	for _, l := range dump.LocalRules {
+		local[matchKey{l.Cidr, l.Protocol, l.Sport, l.Dport}] = l.Identity
+		localCidrs[l.Cidr] = true
+	}
+
+	actual := make(map[matchKey]nodem.DatapathEntry, len(dump.Rules))
+	ruleCidrs := make(map[string]bool, len(dump.Rules))
+	for _, a := range dump.Rules {
+		ruleCidrs[a.Cidr] = true
+
+		key := matchKey{a.Cidr, a.Protocol, a.Sport, a.Dport}
+		if identity, ok := local[key]; ok && identity == a.Identity {
+			continue
+		}
+		actual[key] = mapRuleToEntry(a)
+	}
+
+	for key, e := range expected {
+		a, ok := actual[key]
+		switch {
+		case !ok:
+			r.Missing = append(r.Missing, e)
+		case a.Identity != e.Identity:
+			r.Mismatched = append(r.Mismatched, nodem.DatapathMismatch{Expected: e, Actual: a})
+		}
+	}
+
+	for key, a := range actual {
+		if _, ok := expected[key]; !ok {
+			r.Extra = append(r.Extra, a)
+		}
+	}
+
+	// identity 没有任何 ban 的条目会让非 TCP/UDP/ICMP 报文被丢弃, 同样视为多余条目
+	for _, i := range dump.Identities {
+		if ruleCidrs[i.Cidr] || localCidrs[i.Cidr] || allowed[i.Cidr] {
+			continue
+		}
+		r.Extra = append(r.Extra, nodem.DatapathEntry{Cidr: i.Cidr, Identity: strconv.FormatUint(uint64(i.Identity), 10)})
+	}
+
+	sort.Slice(r.Missing, func(i, j int) bool { return r.Missing[i].Key < r.Missing[j].Key })
+	sort.Slice(r.Mismatched, func(i, j int) bool { return r.Mismatched[i].Expected.Key < r.Mismatched[j].Expected.Key })
+	sort.Slice(r.Extra, func(i, j int) bool { return entryLess(r.Extra[i], r.Extra[j]) })
+}
+
+func rulesToMapRules(rules map[string]rulem.Rule) []*agentcontrol.MapRule {
+	dto := make([]*agentcontrol.MapRule, 0, len(rules))
+	for key, rule := range rules {
+		proto, ok := protocols[rule.RuleInfo.Protocol]
+		if !ok {
+			continue
+		}
+		dto = append(dto, &agentcontrol.MapRule{
+			Key:      key,
+			Cidr:     rule.RuleInfo.Cidr,
+			Identity: rule.RuleMeta.Identity,
+			Protocol: proto,
+			Sport:    uint32(rule.RuleInfo.Sport),
+			Dport:    uint32(rule.RuleInfo.Dport),
+		})
+	}
+	return dto
+}
+
+func mapRuleToEntry(r *agentcontrol.MapRule) nodem.DatapathEntry {
+	return nodem.DatapathEntry{
+		Cidr:     r.Cidr,
+		Identity: r.Identity,
+		Protocol: protocolName(r.Protocol),
+		Sport:    uint16(r.Sport),
+		Dport:    uint16(r.Dport),
+	}
+}
+
+func protocolName(proto uint32) string {
+	switch proto {
+	case 6:
+		return "TCP"
+	case 17:
+		return "UDP"
+	case 1:
+		return "ICMP"
+	default:
+		return strconv.FormatUint(uint64(proto), 10)
+	}
+}
+
+func entryLess(a, b nodem.DatapathEntry) bool {
+	if a.Cidr != b.Cidr {
+		return a.Cidr < b.Cidr
+	}
+	if a.Protocol != b.Protocol {
+		return a.Protocol < b.Protocol
+	}
+	if a.Sport != b.Sport {
+		return a.Sport < b.Sport
+	}
+	return a.Dport < b.Dport
+}
+
+// coveredBy reports whether one of nets contains ipNet entirely
+func coveredBy(ipNet *net.IPNet, nets []*net.IPNet) bool {
+	ones, bits := ipNet.Mask.Size()
+	for _, n := range nets {
+		o, b := n.Mask.Size()
+		if b == bits && o <= ones && n.Contains(ipNet.IP) {
+			return true
+		}
+	}
+	return false
+}
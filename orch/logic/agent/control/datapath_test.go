@@ -0,0 +1,68 @@
+package control
+
+import (
+	"testing"
+	agentcontrol "xdp-banner/api/agent/v1/control"
+	nodem "xdp-banner/orch/model/node"
+	rulem "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/rule"
+)
+
+func testRule(cidr, proto string, dport uint16, identity string) rulem.Rule {
+	return rulem.Rule{
+		RuleInfo: rule.RuleInfo{Cidr: cidr, Protocol: proto, Dport: dport},
+		RuleMeta: rule.RuleMeta{Identity: identity},
+	}
+}
+
+func TestDiffDatapath(t *testing.T) {
+	rules := map[string]rulem.Rule{
+		"/agent/rule/web/192.0.2.0/24/TCP/0-22/":    testRule("192.0.2.0/24", "TCP", 22, "100"),
+		"/agent/rule/web/198.51.100.1/32/UDP/0-53/": testRule("198.51.100.1/32", "UDP", 53, "200"),
+		"/agent/rule/web/203.0.113.0/24/TCP/0-80/":  testRule("203.0.113.0/24", "TCP", 80, "300"),
+		// covered by the allow list of the agent, never written
+		"/agent/rule/web/10.1.0.0/16/TCP/0-80/": testRule("10.1.0.0/16", "TCP", 80, "400"),
+	}
+
+	dump := &agentcontrol.DumpMapsResponse{
+		Identities: []*agentcontrol.IdentityEntry{
+			{Cidr: "192.0.2.0/24", Identity: 100},
+			{Cidr: "203.0.113.0/24", Identity: 999},
+			{Cidr: "172.16.0.0/12", Identity: 700},
+			{Cidr: "192.0.2.128/25", Identity: 800},
+			{Cidr: "10.0.0.0/8", Identity: 900},
+		},
+		Rules: []*agentcontrol.MapRule{
+			{Cidr: "192.0.2.0/24", Identity: "100", Protocol: 6, Dport: 22},
+			{Cidr: "203.0.113.0/24", Identity: "999", Protocol: 6, Dport: 80},
+			{Cidr: "172.16.0.0/12", Identity: "700", Protocol: 17, Dport: 123},
+			{Cidr: "192.0.2.0/24", Identity: "100", Protocol: 1},
+		},
+		LocalRules: []*agentcontrol.MapRule{
+			{Cidr: "192.0.2.0/24", Identity: "100", Protocol: 1},
+		},
+		AllowedCidrs: []string{"10.0.0.0/8"},
+	}
+
+	r := &nodem.DatapathReport{}
+	diffDatapath(r, rules, dump)
+
+	if len(r.Missing) != 1 || r.Missing[0].Cidr != "198.51.100.1/32" {
+		t.Fatalf("unexpected missing %+v", r.Missing)
+	}
+	if len(r.Mismatched) != 1 || r.Mismatched[0].Expected.Identity != "300" || r.Mismatched[0].Actual.Identity != "999" {
+		t.Fatalf("unexpected mismatched %+v", r.Mismatched)
+	}
+	if len(r.Extra) != 2 {
+		t.Fatalf("unexpected extra %+v", r.Extra)
+	}
+	if r.Extra[0].Cidr != "172.16.0.0/12" || r.Extra[0].Protocol != "UDP" {
+		t.Fatalf("unexpected extra rule %+v", r.Extra[0])
+	}
+	if r.Extra[1].Cidr != "192.0.2.128/25" || r.Extra[1].Protocol != "" {
+		t.Fatalf("identity without ban should be reported %+v", r.Extra[1])
+	}
+	if r.InSync() {
+		t.Fatal("report should not be in sync")
+	}
+}
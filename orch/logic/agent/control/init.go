@@ -10,7 +10,7 @@ import (
 	"xdp-banner/pkg/errors"
 )
 
-func (c *Control) Init(ctx context.Context, name string, token string, ipAddress []net.IP, pub []byte) (cert []byte, ca []byte, err error) {
+func (c *Control) Init(ctx context.Context, name string, token string, ipAddress []net.IP, pub []byte, caps *model.HostCapabilities) (cert []byte, ca []byte, err error) {
 	r, err := c.registers.Get(ctx, name)
 	if err != nil {
 		// if err == node.ErrRegisterNotFound {
@@ -36,8 +36,9 @@ func (c *Control) Init(ctx context.Context, name string, token string, ipAddress
 		CommonInfo: model.CommonInfo{
 			Name: name,
 		},
-		Enable: true,
-		Config: "default",
+		Enable:       true,
+		Config:       "default",
+		Capabilities: caps,
 	}); err != nil {
 		return nil, nil, errors.NewServiceErrorf("init failed, %v", err)
 	}
@@ -80,3 +81,25 @@ func signCert(name string, ipAddress []net.IP, pubPem []byte) (newCert []byte, e
 
 	return newCert, nil
 }
+
+// RenewCertificate signs a new certificate for an agent which already holds a
+// valid one, name comes from the verified client certificate.
+func (c *Control) RenewCertificate(ctx context.Context, name string, ipAddress []net.IP, pub []byte) (cert []byte, ca []byte, err error) {
+	if _, err := c.infos.Get(ctx, name); err != nil {
+		if err == node.ErrInfoNotFound {
+			return nil, nil, errors.NewPermissionError("agent not found")
+		}
+
+		return nil, nil, errors.NewServiceErrorf("renew certificate failed, %v", err)
+	}
+
+	if cert, err = signCert(name, ipAddress, pub); err != nil {
+		return nil, nil, err
+	}
+
+	if ca, err = icert.GetLocalCaFile(); err != nil {
+		return nil, nil, errors.NewServiceErrorf("renew certificate failed, %v", err)
+	}
+
+	return
+}
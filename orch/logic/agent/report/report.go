@@ -9,11 +9,13 @@ import (
 
 type Report struct {
 	storage node.StatusStorage
+	infos   node.InfoStorage
 }
 
-func New(ns node.StatusStorage) *Report {
+func New(ns node.StatusStorage, is node.InfoStorage) *Report {
 	return &Report{
 		storage: ns,
+		infos:   is,
 	}
 }
 
@@ -27,4 +29,30 @@ func (c *Report) UpdateStatus(ctx context.Context, name string, config *model.Ag
 	return nil
 }
 
+// UpdateCapabilities stores the reported host capabilities in the agent info.
+// The info is only written when something other than the probe time changed,
+// every write of the info makes the node controller resync the agent.
+func (c *Report) UpdateCapabilities(ctx context.Context, name string, caps *model.HostCapabilities) error {
+	if caps == nil {
+		return nil
+	}
+
+	// 与其它对 info 的写入并发时基于 ModRevision 比较后重试, 不会覆盖它们
+	err := c.infos.Modify(ctx, name, func(info *model.AgentInfo) bool {
+		if info.Capabilities.Equal(caps) {
+			return false
+		}
+		info.Capabilities = caps
+		return true
+	})
+	if err != nil {
+		if err == node.ErrInfoNotFound {
+			return errors.NewInputErrorf("agent %s not found", name)
+		}
+		return errors.NewServiceErrorf("update capabilities failed, %v", err)
+	}
+
+	return nil
+}
+
 // DeleteConfig deletes a config.
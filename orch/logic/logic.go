@@ -4,7 +4,9 @@ import (
 	"xdp-banner/orch/logic/agent/control"
 	"xdp-banner/orch/logic/agent/report"
 	"xdp-banner/orch/logic/orch"
+	"xdp-banner/orch/logic/protect"
 	"xdp-banner/orch/logic/rulecenter"
+	"xdp-banner/orch/logic/rulecenter/validation"
 	"xdp-banner/orch/logic/strategy"
 	"xdp-banner/orch/storage"
 )
@@ -16,12 +18,15 @@ type Logic struct {
 	Orch         *orch.Orch
 	Strategy     *strategy.Strategy
 	Applied      *strategy.Applied
+	Protect      *protect.Protect
 }
 
-func New(s storage.Storage) *Logic {
-	cc := rulecenter.New(s.Rule)
-	ctrl := control.New(s.AgentRegisteration, s.AgentInfo, s.AgentStatus)
-	report := report.New(s.AgentStatus)
+func New(s storage.Storage, vc validation.Config, pc protect.Config, hc rulecenter.HistoryConfig) *Logic {
+	s.Rule = s.Rule.WithRetention(hc.Retention)
+	protect := protect.New(s.Protect, s.OrchInfo, s.ProtectChanges, pc)
+	cc := rulecenter.New(s.Rule, s.RuleIndex, s.RuleComposer, validation.New(vc, s.Rule), protect, s.AgentInfo, s.Schedule)
+	ctrl := control.New(s.AgentRegisteration, s.AgentInfo, s.AgentStatus, s.Rule)
+	report := report.New(s.AgentStatus, s.AgentInfo)
 	orch := orch.New(s.OrchInfo)
 	applied := strategy.NewApplied(s.Strategy, s.AgentInfo, s.Applied)
 	strategy := strategy.NewStrategy(s.Strategy)
@@ -33,5 +38,6 @@ func New(s storage.Storage) *Logic {
 		Orch:         orch,
 		Strategy:     strategy,
 		Applied:      applied,
+		Protect:      protect,
 	}
 }
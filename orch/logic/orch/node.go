@@ -39,3 +39,26 @@ func (c *Orch) ListInfo(ctx context.Context, pageSize int64, nextCursor string)
 
 	return il, nil
 }
+
+// Advertise records the grpc endpoint the agents use to reach the orch
+func (c *Orch) Advertise(ctx context.Context, name, endpoint string) error {
+	info, err := c.infos.Get(ctx, name)
+	if err != nil {
+		if err == node.ErrInfoNotFound {
+			return errors.NewInputErrorf("orch %s is not registered, run init or join first", name)
+		}
+
+		return errors.NewServiceErrorf("get info failed, %v", err)
+	}
+
+	if info.Endpoint == endpoint {
+		return nil
+	}
+
+	info.Endpoint = endpoint
+	if err := c.infos.Update(ctx, info); err != nil {
+		return errors.NewServiceErrorf("update info failed, %v", err)
+	}
+
+	return nil
+}
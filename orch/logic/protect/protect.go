@@ -0,0 +1,355 @@
+package protect
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	model "xdp-banner/orch/model/protect"
+	rulemodel "xdp-banner/orch/model/rule"
+	"xdp-banner/orch/storage/agent/protect"
+	orchnode "xdp-banner/orch/storage/orch/node"
+	"xdp-banner/pkg/cidr"
+	"xdp-banner/pkg/errors"
+	"xdp-banner/pkg/log"
+)
+
+const (
+	// ModeReject rejects the rules overlapping a protected range
+	ModeReject = "reject"
+	// ModeClip bans the prefixes around the protected ranges instead
+	ModeClip = "clip"
+
+	// maxClipped 限制一条规则被裁剪出的规则数, /0 裁掉一个 /128 会得到 128 条
+	maxClipped = 256
+
+	lookupTimeout = 3 * time.Second
+	// cacheTTL bounds how long the protected ranges are cached, the addresses
+	// the endpoints of the orchestrators resolve to change without any event
+	cacheTTL = time.Minute
+)
+
+// Config is the protect section of the orch config
+type Config struct {
+	// Mode is what the rule center does with a rule containing a protected range
+	Mode string `mapstructure:"mode"`
+	// Ranges are protected in addition to the managed ones
+	Ranges []string `mapstructure:"ranges"`
+	// Orchestrators protects the advertised addresses of the orchestrators
+	Orchestrators bool `mapstructure:"orchestrators"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Mode:          ModeReject,
+		Orchestrators: true,
+	}
+}
+
+func (c *Config) Check() error {
+	if c.Mode != ModeReject && c.Mode != ModeClip {
+		return fmt.Errorf("protect mode should be %q or %q", ModeReject, ModeClip)
+	}
+
+	for _, r := range c.Ranges {
+		if _, err := parseRange(r); err != nil {
+			return fmt.Errorf("protect range: %w", err)
+		}
+	}
+
+	return nil
+}
+
+type Protect struct {
+	ranges  protect.Storage
+	orchs   orchnode.InfoStorage
+	changes *protect.Changes
+	config  Config
+
+	mu sync.Mutex
+	// cached is the result of Effective at generation, nil when there is none
+	cached     []model.EffectiveRange
+	generation uint64
+	cachedAt   time.Time
+	// invalidations counts the calls of invalidate, a result read before one
+	// of them is not cached
+	invalidations uint64
+}
+
+// New returns the protected ranges, Effective is cached until changes sees a
+// change of them. Without changes nothing is cached.
+func New(ranges protect.Storage, orchs orchnode.InfoStorage, changes *protect.Changes, c Config) *Protect {
+	return &Protect{
+		ranges:  ranges,
+		orchs:   orchs,
+		changes: changes,
+		config:  c,
+	}
+}
+
+// Add adds a managed protected range, the cidr is stored in its canonical form
+func (p *Protect) Add(ctx context.Context, r *model.ProtectedRange) error {
+	if r.Name == "" || strings.Contains(r.Name, "/") {
+		return errors.NewInputError("name is required and can not contain '/'")
+	}
+
+	prefix, err := parseRange(r.Cidr)
+	if err != nil {
+		return errors.NewInputErrorf("valid protected range failed: %v", err)
+	}
+
+	r.Cidr = prefix.String()
+	r.CreatedAt = time.Now()
+	if err := p.ranges.Add(ctx, r); err != nil {
+		if err == protect.ErrRangeAlreadyExists {
+			return errors.NewInputError("protected range already exists, please use another name")
+		}
+		return errors.NewServiceErrorf("failed to add protected range: %v", err)
+	}
+	// 不等 watch 事件, 本节点之后的规则立即受保护
+	p.invalidate()
+
+	log.Info("protected range added", log.StringField("name", r.Name), log.StringField("cidr", r.Cidr))
+	return nil
+}
+
+// Delete deletes a managed protected range.
+func (p *Protect) Delete(ctx context.Context, name string) error {
+	if err := p.ranges.Delete(ctx, name); err != nil {
+		if err == protect.ErrRangeNotFound {
+			return errors.NewInputError("protected range not found")
+		}
+		return errors.NewServiceErrorf("failed to delete protected range: %v", err)
+	}
+	p.invalidate()
+
+	log.Info("protected range deleted", log.StringField("name", name))
+	return nil
+}
+
+// Get gets a managed protected range.
+func (p *Protect) Get(ctx context.Context, name string) (*model.ProtectedRange, error) {
+	r, err := p.ranges.Get(ctx, name)
+	if err != nil {
+		if err == protect.ErrRangeNotFound {
+			return nil, errors.NewInputError("protected range not found")
+		}
+		return nil, errors.NewServiceErrorf("failed to get protected range: %v", err)
+	}
+
+	return r, nil
+}
+
+// List lists the managed protected ranges.
+func (p *Protect) List(ctx context.Context, pageSize int64, nextCursor string) (model.ProtectedRangeList, error) {
+	if pageSize <= 0 {
+		return model.ProtectedRangeList{}, errors.NewInputError("page size must be greater than 0")
+	}
+
+	list, err := p.ranges.List(ctx, pageSize, nextCursor)
+	if err != nil {
+		return model.ProtectedRangeList{}, errors.NewServiceErrorf("failed to list protected ranges: %v", err)
+	}
+
+	return list, nil
+}
+
+// Effective returns every protected CIDR: the managed ranges, the ranges of
+// the config and the addresses of the orchestrators. The result is cached
+// until the managed ranges or the orchestrators change, or for cacheTTL.
+func (p *Protect) Effective(ctx context.Context) ([]model.EffectiveRange, error) {
+	if p.changes == nil {
+		return p.effective(ctx)
+	}
+
+	generation, ok := p.changes.Generation()
+	p.mu.Lock()
+	cached, invalidations := p.cached, p.invalidations
+	hit := ok && cached != nil && p.generation == generation && time.Since(p.cachedAt) < cacheTTL
+	p.mu.Unlock()
+	if hit {
+		return slices.Clone(cached), nil
+	}
+
+	result, err := p.effective(ctx)
+	if err != nil || !ok {
+		return result, err
+	}
+
+	// 读取期间发生的变化会让 generation 前进, 下次读取时重新计算;
+	// 读取期间调用过 invalidate 的结果可能已经过时, 不缓存
+	p.mu.Lock()
+	if p.invalidations == invalidations {
+		p.cached, p.generation, p.cachedAt = slices.Clone(result), generation, time.Now()
+		if p.cached == nil {
+			p.cached = []model.EffectiveRange{}
+		}
+	}
+	p.mu.Unlock()
+	return result, nil
+}
+
+// invalidate drops the cached protected ranges and the results being read
+func (p *Protect) invalidate() {
+	p.mu.Lock()
+	p.cached = nil
+	p.invalidations++
+	p.mu.Unlock()
+}
+
+// effective reads every protected CIDR, see Effective
+func (p *Protect) effective(ctx context.Context) ([]model.EffectiveRange, error) {
+	managed, err := p.ranges.All(ctx)
+	if err != nil {
+		return nil, errors.NewServiceErrorf("failed to list protected ranges: %v", err)
+	}
+
+	var result []model.EffectiveRange
+	for _, r := range managed {
+		result = append(result, model.EffectiveRange{Cidr: r.Cidr, Source: model.SourceManaged + r.Name})
+	}
+	for _, r := range p.config.Ranges {
+		prefix, _ := parseRange(r) // Config.Check 已经检查过
+		result = append(result, model.EffectiveRange{Cidr: prefix.String(), Source: model.SourceConfig})
+	}
+
+	if p.config.Orchestrators {
+		orchs, err := p.orchRanges(ctx)
+		if err != nil {
+			return nil, errors.NewServiceErrorf("failed to list orchestrators: %v", err)
+		}
+		result = append(result, orchs...)
+	}
+
+	return result, nil
+}
+
+// orchRanges resolves the advertised endpoints of the orchestrators
+func (p *Protect) orchRanges(ctx context.Context) ([]model.EffectiveRange, error) {
+	var result []model.EffectiveRange
+
+	cursor := ""
+	for {
+		list, err := p.orchs.List(ctx, 100, cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, info := range list.Items {
+			if info.Endpoint == "" {
+				continue
+			}
+			for _, addr := range lookupEndpoint(ctx, info.Endpoint) {
+				result = append(result, model.EffectiveRange{Cidr: cidr.HostPrefix(addr).String(), Source: model.SourceOrch + name})
+			}
+		}
+
+		if !list.HasNext {
+			return result, nil
+		}
+		cursor = list.NextCursor
+	}
+}
+
+func lookupEndpoint(ctx context.Context, endpoint string) []netip.Addr {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		host = endpoint
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		log.Warn("resolve orchestrator endpoint, it is not protected", log.StringField("endpoint", endpoint), log.ErrorField(err))
+		return nil
+	}
+	return addrs
+}
+
+// Guard checks a rule against the protected ranges. A rule inside a protected
+// range is always rejected. A rule containing protected ranges is rejected, or
+// clipped into the rules banning the prefixes around them in clip mode.
+func (p *Protect) Guard(ctx context.Context, r *rulemodel.Rule) ([]*rulemodel.Rule, error) {
+	ranges, err := p.Effective(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.GuardRanges(r, ranges)
+}
+
+// GuardRanges is Guard against ranges returned by Effective, the many rules
+// of an import are guarded against the ranges read once
+func (p *Protect) GuardRanges(r *rulemodel.Rule, ranges []model.EffectiveRange) ([]*rulemodel.Rule, error) {
+	prefix, err := cidr.Parse(r.RuleInfo.Cidr)
+	if err != nil {
+		return nil, errors.NewInputErrorf("valid rule failed: %v", err)
+	}
+
+	pieces, err := guard(prefix, ranges, p.config.Mode)
+	if err != nil {
+		return nil, errors.NewInputErrorf("rule refused by the protected ranges: %v", err)
+	}
+	if len(pieces) == 1 && pieces[0] == prefix {
+		return []*rulemodel.Rule{r}, nil
+	}
+
+	log.Info("rule clipped around the protected ranges", log.StringField("cidr", r.RuleInfo.Cidr), log.IntField("rules", len(pieces)))
+	result := make([]*rulemodel.Rule, 0, len(pieces))
+	for _, piece := range pieces {
+		clipped := *r
+		clipped.RuleInfo.Cidr = piece.String()
+		result = append(result, &clipped)
+	}
+	return result, nil
+}
+
+// guard returns the prefixes to ban for prefix, see Protect.Guard
+func guard(prefix netip.Prefix, ranges []model.EffectiveRange, mode string) ([]netip.Prefix, error) {
+	var holes []netip.Prefix
+	var hit []string
+	for _, r := range ranges {
+		rp, err := cidr.Parse(r.Cidr)
+		if err != nil || !rp.Overlaps(prefix) {
+			continue
+		}
+		if cidr.Contains(rp, prefix) {
+			return nil, fmt.Errorf("%s is inside the protected range %s (%s)", prefix, rp, r.Source)
+		}
+		holes = append(holes, rp)
+		hit = append(hit, fmt.Sprintf("%s (%s)", rp, r.Source))
+	}
+
+	if len(holes) == 0 {
+		return []netip.Prefix{prefix}, nil
+	}
+	if mode != ModeClip {
+		return nil, fmt.Errorf("%s contains the protected ranges %s", prefix, strings.Join(hit, ", "))
+	}
+
+	pieces := cidr.Subtract(prefix, holes...)
+	if len(pieces) > maxClipped {
+		return nil, fmt.Errorf("clipping %s around %s needs %d rules, more than %d", prefix, strings.Join(hit, ", "), len(pieces), maxClipped)
+	}
+	return pieces, nil
+}
+
+func parseRange(s string) (netip.Prefix, error) {
+	prefix, err := cidr.Parse(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if prefix.Bits() == 0 {
+		return netip.Prefix{}, fmt.Errorf("protecting %s would disable every ban", prefix)
+	}
+	return prefix, nil
+}
@@ -0,0 +1,87 @@
+package protect
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	model "xdp-banner/orch/model/protect"
+)
+
+func TestGuard(t *testing.T) {
+	ranges := []model.EffectiveRange{
+		{Cidr: "10.0.0.0/24", Source: model.SourceManaged + "mgmt"},
+		{Cidr: "192.0.2.10/32", Source: model.SourceOrch + "orch-1"},
+		{Cidr: "2001:db8::/64", Source: model.SourceConfig},
+	}
+
+	tests := []struct {
+		name    string
+		prefix  string
+		mode    string
+		want    []string
+		wantErr string
+	}{
+		{"unrelated", "198.51.100.0/24", ModeReject, []string{"198.51.100.0/24"}, ""},
+		{"inside", "10.0.0.5/32", ModeClip, nil, "inside the protected range 10.0.0.0/24 (managed/mgmt)"},
+		{"same", "10.0.0.0/24", ModeClip, nil, "inside the protected range"},
+		{"contains rejected", "192.0.2.0/24", ModeReject, nil, "contains the protected ranges 192.0.2.10/32 (orch/orch-1)"},
+		{"contains clipped", "192.0.2.8/29", ModeClip, []string{"192.0.2.8/31", "192.0.2.11/32", "192.0.2.12/30"}, ""},
+		{"clipped", "192.0.2.8/30", ModeClip, []string{"192.0.2.8/31", "192.0.2.11/32"}, ""},
+		{"other family", "2001:db9::/32", ModeReject, []string{"2001:db9::/32"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := guard(netip.MustParsePrefix(tt.prefix), ranges, tt.mode)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var strs []string
+			for _, p := range got {
+				strs = append(strs, p.String())
+			}
+			if !reflect.DeepEqual(strs, tt.want) {
+				t.Fatalf("guard = %v, want %v", strs, tt.want)
+			}
+		})
+	}
+}
+
+func TestGuardTooManyRules(t *testing.T) {
+	var ranges []model.EffectiveRange
+	for i := 1; i <= 10; i++ {
+		ranges = append(ranges, model.EffectiveRange{Cidr: fmt.Sprintf("%d.0.0.1/32", i*20), Source: model.SourceConfig})
+	}
+
+	_, err := guard(netip.MustParsePrefix("0.0.0.0/0"), ranges, ModeClip)
+	if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("more than %d", maxClipped)) {
+		t.Fatalf("error = %v, want the clipping to be refused", err)
+	}
+}
+
+func TestConfigCheck(t *testing.T) {
+	c := DefaultConfig()
+	c.Ranges = []string{"10.0.0.1", "192.168.0.0/16"}
+	if err := c.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Mode = "drop"
+	if err := c.Check(); err == nil {
+		t.Fatal("unknown mode should fail")
+	}
+
+	c = DefaultConfig()
+	c.Ranges = []string{"::/0"}
+	if err := c.Check(); err == nil || !strings.Contains(err.Error(), "disable every ban") {
+		t.Fatalf("error = %v, protecting everything should fail", err)
+	}
+}
@@ -0,0 +1,183 @@
+package rulecenter
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/cidr"
+	"xdp-banner/pkg/errors"
+)
+
+// maxAggregated bounds the original CIDRs kept in the meta of a merged rule,
+// the meta goes to every agent with the rule
+const maxAggregated = 256
+
+// aggregateKey is what the rules merged into one rule share
+type aggregateKey struct {
+	protocol  string
+	sport     uint16
+	dport     uint16
+	comment   string
+	expiresAt int64
+}
+
+func aggregateKeyOf(r *model.Rule) aggregateKey {
+	key := aggregateKey{
+		protocol: r.RuleInfo.Protocol,
+		sport:    r.RuleInfo.Sport,
+		dport:    r.RuleInfo.Dport,
+		comment:  r.RuleMeta.Comment,
+	}
+	if !r.RuleMeta.Permanent() {
+		key.expiresAt = r.RuleMeta.ExpiresAt.UnixNano()
+	}
+	return key
+}
+
+// aggregated is a rule of the result of aggregateRules, members are the
+// indexes of the rules it replaces
+type aggregated struct {
+	rule    *model.Rule
+	members []int
+}
+
+// merged reports whether the rule replaces other rules
+func (a aggregated) merged() bool {
+	return len(a.members) > 1
+}
+
+// aggregateRules merges the rules with the same protocol, ports, comment and
+// expiry into the fewest covering prefixes, the addresses they cover do not
+// change. A merged rule keeps the CIDRs it replaces in its meta, a rule left
+// as it is is returned as it is. The agents match the longest prefix, so a
+// rule is not merged past a prefix of another group or of existing, the
+// prefixes already in the rule set, see cidr.MergeAround.
+func aggregateRules(rules []*model.Rule, existing []netip.Prefix) []aggregated {
+	var keys []aggregateKey
+	groups := make(map[aggregateKey][]int)
+	prefixes := make([]netip.Prefix, len(rules))
+	var invalid []aggregated
+	for i, r := range rules {
+		p, err := cidr.Parse(r.RuleInfo.Cidr)
+		if err != nil {
+			invalid = append(invalid, aggregated{rule: r, members: []int{i}})
+			continue
+		}
+		prefixes[i] = p
+		key := aggregateKeyOf(r)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	var result []aggregated
+	for _, key := range keys {
+		byPrefix := make(map[netip.Prefix][]int)
+		group := make([]netip.Prefix, 0, len(groups[key]))
+		for _, i := range groups[key] {
+			byPrefix[prefixes[i]] = append(byPrefix[prefixes[i]], i)
+			group = append(group, prefixes[i])
+		}
+		// 其它组和 rule set 中已有的前缀
+		others := slices.Clone(existing)
+		for _, other := range keys {
+			if other == key {
+				continue
+			}
+			for _, i := range groups[other] {
+				others = append(others, prefixes[i])
+			}
+		}
+
+		for _, a := range cidr.MergeAround(group, others, maxAggregated) {
+			var members []int
+			for j, p := range a.Members {
+				// 重复的前缀在 a.Members 中相邻出现
+				if j > 0 && a.Members[j-1] == p {
+					continue
+				}
+				members = append(members, byPrefix[p]...)
+			}
+			if len(members) == 1 {
+				result = append(result, aggregated{rule: rules[members[0]], members: members})
+				continue
+			}
+			result = append(result, aggregated{rule: mergedRule(rules, a.Prefix, members), members: members})
+		}
+	}
+	return append(result, invalid...)
+}
+
+// mergedRule is the rule covering prefix which replaces the members
+func mergedRule(rules []*model.Rule, prefix netip.Prefix, members []int) *model.Rule {
+	first := rules[members[0]]
+	r := &model.Rule{RuleInfo: first.RuleInfo, RuleMeta: first.RuleMeta}
+	r.RuleInfo.Cidr = prefix.String()
+	r.RuleMeta.Identity = "0"
+	r.RuleMeta.Aggregated = nil
+	for _, i := range members {
+		if replaced := rules[i].RuleMeta.Aggregated; len(replaced) > 0 {
+			r.RuleMeta.Aggregated = append(r.RuleMeta.Aggregated, replaced...)
+			continue
+		}
+		r.RuleMeta.Aggregated = append(r.RuleMeta.Aggregated, rules[i].RuleInfo.Cidr)
+	}
+	return r
+}
+
+// viewPrefixes returns the prefixes of the merged view of a rule set, without
+// the rules coming from the set skip
+func (r *RuleCenter) viewPrefixes(ctx context.Context, name, skip string) ([]netip.Prefix, error) {
+	_, rules, err := r.composer.Effective(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge rule set: %w", err)
+	}
+	prefixes := make([]netip.Prefix, 0, len(rules))
+	for _, e := range rules {
+		if e.Source == skip {
+			continue
+		}
+		if p, err := cidr.Parse(e.Rule.RuleInfo.Cidr); err == nil {
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes, nil
+}
+
+// PreviewAggregation reports how aggregating the rules of a rule set would
+// merge them, nothing is written. Rules merge when they have the same
+// protocol, ports, comment and expiry, so the rules of one import merge.
+func (r *RuleCenter) PreviewAggregation(ctx context.Context, name string) (*model.AggregationPreview, error) {
+	items, err := r.GetRule(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]*model.Rule, len(items))
+	for i := range items {
+		rules[i] = &items[i]
+	}
+
+	// 被包含的 rule set 的前缀
+	existing, err := r.viewPrefixes(ctx, name, name)
+	if err != nil {
+		return nil, errors.NewServiceErrorf("failed to preview aggregation: %v", err)
+	}
+
+	result := aggregateRules(rules, existing)
+	preview := &model.AggregationPreview{Before: len(rules), After: len(result), Merged: []model.Aggregation{}}
+	for _, a := range result {
+		if !a.merged() {
+			continue
+		}
+		merged := model.Aggregation{Rule: *a.rule, Replaces: make(model.RuleItem, 0, len(a.members))}
+		for _, i := range a.members {
+			merged.Replaces = append(merged.Replaces, *rules[i])
+		}
+		preview.Merged = append(preview.Merged, merged)
+	}
+	return preview, nil
+}
@@ -0,0 +1,116 @@
+package rulecenter
+
+import (
+	"net/netip"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/datapath"
+	"xdp-banner/pkg/rule"
+)
+
+func TestAggregateRules(t *testing.T) {
+	expiresAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := func(cidr string, dport uint16, expires time.Time) *model.Rule {
+		return &model.Rule{
+			RuleInfo: rule.RuleInfo{Cidr: cidr, Protocol: "TCP", Dport: dport},
+			RuleMeta: rule.RuleMeta{Comment: "blocklist", ExpiresAt: expires, Identity: "0"},
+		}
+	}
+	rules := []*model.Rule{
+		r("10.0.0.0/32", 22, expiresAt),
+		r("10.0.0.1/32", 22, expiresAt),
+		r("10.0.0.2/31", 22, expiresAt),
+		r("10.0.0.3/32", 22, expiresAt),
+		// 端口或过期时间不同的规则不合并
+		r("10.0.0.4/31", 80, expiresAt),
+		r("10.0.0.6/31", 22, time.Time{}),
+		r("invalid", 22, expiresAt),
+	}
+
+	result := aggregateRules(rules, nil)
+	if len(result) != 4 {
+		t.Fatalf("aggregateRules() = %d rules, want 4", len(result))
+	}
+
+	merged := result[0]
+	if !merged.merged() || merged.rule.RuleInfo.Cidr != "10.0.0.0/30" || !slices.Equal(merged.members, []int{0, 1, 2, 3}) {
+		t.Errorf("merged = %s from %v", merged.rule.RuleInfo.Cidr, merged.members)
+	}
+	if want := []string{"10.0.0.0/32", "10.0.0.1/32", "10.0.0.2/31", "10.0.0.3/32"}; !slices.Equal(merged.rule.RuleMeta.Aggregated, want) {
+		t.Errorf("Aggregated = %v, want %v", merged.rule.RuleMeta.Aggregated, want)
+	}
+	if merged.rule.RuleMeta.Comment != "blocklist" || !merged.rule.RuleMeta.ExpiresAt.Equal(expiresAt) || rules[0].RuleInfo.Cidr != "10.0.0.0/32" {
+		t.Errorf("merged rule = %+v", merged.rule)
+	}
+
+	for _, a := range result[1:] {
+		if a.merged() || a.rule != rules[a.members[0]] {
+			t.Errorf("%s is not left as it is", a.rule.RuleInfo.Key())
+		}
+	}
+}
+
+func TestAggregateRulesKeepVerdicts(t *testing.T) {
+	r := func(cidr string, dport uint16) *model.Rule {
+		return &model.Rule{
+			RuleInfo: rule.RuleInfo{Cidr: cidr, Protocol: "TCP", Dport: dport},
+			RuleMeta: rule.RuleMeta{Comment: "blocklist", Identity: "0"},
+		}
+	}
+	rules := []*model.Rule{
+		// 10.0.0.64/26 也有端口 80 的规则, 10.0.0.128/25 已在 rule set 中
+		r("10.0.0.0/26", 22), r("10.0.0.64/26", 22), r("10.0.0.128/26", 22), r("10.0.0.192/26", 22),
+		r("10.0.0.64/26", 80),
+		// 10.0.0.16/28 内部的 10.0.0.16/30 属于另一组
+		r("10.0.0.16/28", 22), r("10.0.0.16/30", 80), r("10.0.0.20/30", 22),
+		// 可以合并
+		r("10.0.1.0/25", 22), r("10.0.1.128/25", 22), r("10.0.1.7/32", 22),
+	}
+	existing := []*model.Rule{r("10.0.0.128/25", 443)}
+
+	// identity 按 CIDR 分配, 与存储相同
+	identities := make(map[string]string)
+	effective := func(rules []*model.Rule) []model.EffectiveRule {
+		var result []model.EffectiveRule
+		for _, rule := range rules {
+			copied := *rule
+			if _, ok := identities[copied.RuleInfo.Cidr]; !ok {
+				identities[copied.RuleInfo.Cidr] = strconv.Itoa(1000 + len(identities))
+			}
+			copied.RuleMeta.Identity = identities[copied.RuleInfo.Cidr]
+			result = append(result, model.EffectiveRule{Source: "set", Rule: copied})
+		}
+		return result
+	}
+
+	existingPrefixes := []netip.Prefix{netip.MustParsePrefix("10.0.0.128/25")}
+	result := aggregateRules(rules, existingPrefixes)
+	after := slices.Clone(existing)
+	merged := 0
+	for _, a := range result {
+		after = append(after, a.rule)
+		if a.merged() {
+			merged++
+		}
+	}
+	if merged == 0 {
+		t.Fatal("nothing merged")
+	}
+
+	before := NewMatcher(effective(append(slices.Clone(existing), rules...)), nil)
+	aggregated := NewMatcher(effective(after), nil)
+	for i := range 512 {
+		src := netip.AddrFrom4([4]byte{10, 0, byte(i >> 8), byte(i)})
+		for _, dport := range []uint16{22, 80, 443} {
+			packet := datapath.Packet{Src: src, Protocol: 6, Dport: dport}
+			want, _ := before.Decide(packet)
+			got, _ := aggregated.Decide(packet)
+			if got.Drop != want.Drop {
+				t.Errorf("%s port %d: drop = %v after aggregation, %v before", src, dport, got.Drop, want.Drop)
+			}
+		}
+	}
+}
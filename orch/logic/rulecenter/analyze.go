@@ -0,0 +1,345 @@
+package rulecenter
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	protectModel "xdp-banner/orch/model/protect"
+	model "xdp-banner/orch/model/rule"
+	agentStorage "xdp-banner/orch/storage/agent/node"
+	"xdp-banner/pkg/cidr"
+	"xdp-banner/pkg/datapath"
+	"xdp-banner/pkg/errors"
+)
+
+const (
+	// maxFindings bounds the findings of an analysis
+	maxFindings = 1000
+	// maxRelated bounds the related rules of a finding
+	maxRelated = 16
+)
+
+// AnalyzeRuleSet analyzes the rules of a rule set the way the XDP program of
+// the agents applies them, see analyzeRules. With effective the includes are
+// merged first, like for the agents watching the set.
+func (r *RuleCenter) AnalyzeRuleSet(ctx context.Context, name string, effective bool) (*model.Analysis, error) {
+	var sets []string
+	var rules []model.EffectiveRule
+	if effective {
+		order, layers, err := r.composer.Layers(ctx, name)
+		if err != nil {
+			return nil, errors.NewServiceErrorf("failed to merge rule set: %v", err)
+		}
+		sets, rules = order, layers
+	} else {
+		items, err := r.GetRule(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		sets = []string{name}
+		for i := range items {
+			rules = append(rules, model.EffectiveRule{Source: name, Rule: items[i]})
+		}
+	}
+
+	protected, err := r.guard.Effective(ctx)
+	if err != nil {
+		return nil, errors.NewServiceErrorf("failed to get the protected ranges: %v", err)
+	}
+
+	findings := analyzeRules(rules, protected)
+	analysis := &model.Analysis{Sets: sets, Rules: len(rules), Findings: findings}
+	if len(findings) > maxFindings {
+		analysis.Findings = findings[:maxFindings]
+		analysis.Truncated = true
+	}
+	return analysis, nil
+}
+
+// AnalyzeAgent analyzes the effective rule set an agent watches
+func (r *RuleCenter) AnalyzeAgent(ctx context.Context, agent string) (*model.Analysis, error) {
+	name, err := r.watchedRuleSet(ctx, agent)
+	if err != nil {
+		return nil, err
+	}
+	return r.AnalyzeRuleSet(ctx, name, true)
+}
+
+// watchedRuleSet returns the rule set an agent watches
+func (r *RuleCenter) watchedRuleSet(ctx context.Context, agent string) (string, error) {
+	info, err := r.agents.Get(ctx, agent)
+	if err == agentStorage.ErrInfoNotFound {
+		return "", errors.NewInputErrorf("agent %s not found", agent)
+	}
+	if err != nil {
+		return "", errors.NewServiceErrorf("failed to get agent %s: %v", agent, err)
+	}
+	if info.Config == "" {
+		return "", errors.NewInputErrorf("agent %s watches no rule set", agent)
+	}
+	return info.Config, nil
+}
+
+// prefixNode is a prefix of identity_ipcache, the CIDR of rules or a
+// protected range the agents punch out of a banned CIDR
+type prefixNode struct {
+	prefix netip.Prefix
+	// rules are the indexes of the rules writing a banlist entry, entries
+	// are their entries
+	rules   []int
+	entries []datapath.Entry
+	// protected is the source of a protected range
+	protected string
+	// covered is true when every address of the prefix is in its children
+	covered bool
+
+	parent   *prefixNode
+	children []*prefixNode
+}
+
+type analyzer struct {
+	rules    []model.EffectiveRule
+	findings []model.Finding
+	// flagged are the rules already found shadowed or unreachable
+	flagged map[int]bool
+}
+
+// analyzeRules finds the rules which do not do what they seem to. It follows
+// the XDP program: a source takes the identity of its longest prefix in
+// identity_ipcache, so the rules of a CIDR do not apply to the prefixes inside
+// it having rules of their own, and the packets are then matched against the
+// banlist entries of that identity only, see datapath.Entry. The agents do
+// not install the rules inside a protected range and punch the protected
+// ranges out of the CIDRs containing them. The static rules of the agents
+// are not known here.
+func analyzeRules(rules []model.EffectiveRule, protected []protectModel.EffectiveRange) []model.Finding {
+	a := &analyzer{rules: rules, flagged: make(map[int]bool)}
+
+	var ranges []netip.Prefix
+	var sources []string
+	for _, pr := range protected {
+		if p, err := cidr.Parse(pr.Cidr); err == nil {
+			ranges = append(ranges, p)
+			sources = append(sources, pr.Source)
+		}
+	}
+
+	type entryKey struct {
+		prefix netip.Prefix
+		entry  datapath.Entry
+	}
+	first := make(map[entryKey]int)
+	nodes := make(map[netip.Prefix]*prefixNode)
+
+rules:
+	for i := range rules {
+		info := rules[i].Rule.RuleInfo
+		prefix, err := cidr.Parse(info.Cidr)
+		if err != nil {
+			a.unreachable(i, "the agents can not parse the CIDR %q", info.Cidr)
+			continue
+		}
+		protocol, ok := datapath.ParseProtocol(info.Protocol)
+		if !ok {
+			a.unreachable(i, "the agents do not know the protocol %q", info.Protocol)
+			continue
+		}
+		if !datapath.Checked(prefix.Addr().Is4(), protocol) {
+			a.unreachable(i, "the XDP program looks up the ICMP packets of IPv6 sources as ICMPv6")
+			continue
+		}
+		for j, pr := range ranges {
+			if cidr.Contains(pr, prefix) {
+				a.unreachable(i, "%s is inside the protected range %s of %s, the agents do not install it", prefix, pr, sources[j])
+				continue rules
+			}
+		}
+
+		entry := datapath.NewEntry(protocol, info.Sport, info.Dport)
+		key := entryKey{prefix, entry}
+		if j, ok := first[key]; ok {
+			if rules[j].Rule.RuleInfo.Key() == info.Key() {
+				a.add(model.FindingDuplicate, i, []int{j}, "", "the agents get the rule of %s instead", rules[j].Source)
+			} else {
+				a.add(model.FindingDuplicate, i, []int{j}, "", "it writes the same banlist entry as %s of %s", rules[j].Rule.RuleInfo.Key(), rules[j].Source)
+			}
+			continue
+		}
+		first[key] = i
+
+		n, ok := nodes[prefix]
+		if !ok {
+			n = &prefixNode{prefix: prefix}
+			nodes[prefix] = n
+		}
+		n.rules = append(n.rules, i)
+		n.entries = append(n.entries, entry)
+	}
+
+	// 被封禁网段包含的保护网段以空规则的前缀出现在 identity_ipcache 中
+	for j, pr := range ranges {
+		if _, ok := nodes[pr]; ok {
+			continue
+		}
+		for p := range nodes {
+			if cidr.Contains(p, pr) && p != pr {
+				nodes[pr] = &prefixNode{prefix: pr, protected: sources[j]}
+				break
+			}
+		}
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(nodes))
+	for p := range nodes {
+		prefixes = append(prefixes, p)
+	}
+	cidr.Sort(prefixes)
+
+	// 排序后父前缀先于子前缀出现
+	var stack []*prefixNode
+	for _, p := range prefixes {
+		n := nodes[p]
+		for len(stack) > 0 && !cidr.Contains(stack[len(stack)-1].prefix, p) {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 {
+			n.parent = stack[len(stack)-1]
+			n.parent.children = append(n.parent.children, n)
+		}
+		stack = append(stack, n)
+	}
+
+	for _, p := range prefixes {
+		n := nodes[p]
+		if n.protected != "" {
+			if !n.parent.covered {
+				a.protectedConflicts(n)
+			}
+			continue
+		}
+		if n.covered = a.covered(n); n.covered {
+			continue
+		}
+		a.shadowedInPrefix(n)
+		if n.parent != nil && !n.parent.covered {
+			a.nested(n)
+		}
+	}
+
+	slices.SortFunc(a.findings, func(x, y model.Finding) int {
+		return cmp.Or(
+			strings.Compare(x.Rule.Rule.RuleInfo.Key(), y.Rule.Rule.RuleInfo.Key()),
+			strings.Compare(x.Rule.Source, y.Rule.Source),
+			strings.Compare(x.Kind, y.Kind),
+			strings.Compare(x.Cidr, y.Cidr),
+		)
+	})
+	return a.findings
+}
+
+// covered reports the rules of a prefix whose every address is in a more
+// specific prefix, they never apply
+func (a *analyzer) covered(n *prefixNode) bool {
+	if len(n.children) == 0 {
+		return false
+	}
+	children := make([]netip.Prefix, len(n.children))
+	for i, c := range n.children {
+		children[i] = c.prefix
+	}
+	merged := cidr.Merge(children, 0)
+	if len(merged) != 1 || merged[0].Prefix != n.prefix {
+		return false
+	}
+
+	for _, i := range n.rules {
+		a.unreachable(i, "every address of %s is in a more specific prefix with rules of its own or protected", n.prefix)
+	}
+	return true
+}
+
+// shadowedInPrefix reports the rules of a prefix matching packets another
+// rule of the prefix matches too
+func (a *analyzer) shadowedInPrefix(n *prefixNode) {
+	for k, i := range n.rules {
+		var by []int
+		for _, j := range datapath.Covering(n.entries, n.entries[k]) {
+			if j != k {
+				by = append(by, n.rules[j])
+			}
+		}
+		if len(by) > 0 {
+			a.flagged[i] = true
+			a.add(model.FindingShadowed, i, by, "", "every packet it matches is matched by %s", a.rules[by[0]].Rule.RuleInfo.Key())
+		}
+	}
+}
+
+// nested compares a prefix with its parent. The rules of the parent not
+// covered by the prefix conflict with it, the packets they match from the
+// prefix pass. Without conflicts, the rules of the prefix covered by the
+// parent are shadowed: the prefix could go without changing a verdict.
+func (a *analyzer) nested(n *prefixNode) {
+	parent := n.parent
+	conflicts := false
+	for k, i := range parent.rules {
+		if len(datapath.Covering(n.entries, parent.entries[k])) > 0 {
+			continue
+		}
+		conflicts = true
+		a.add(model.FindingConflict, i, n.rules, n.prefix.String(),
+			"it does not apply to %s which has rules of its own, the longest prefix wins and the packets it matches from %s pass", n.prefix, n.prefix)
+	}
+	if conflicts {
+		return
+	}
+
+	shadowed := make([][]int, len(n.rules))
+	for k := range n.rules {
+		for _, j := range datapath.Covering(parent.entries, n.entries[k]) {
+			shadowed[k] = append(shadowed[k], parent.rules[j])
+		}
+		if len(shadowed[k]) == 0 {
+			return
+		}
+	}
+	for k, i := range n.rules {
+		if a.flagged[i] {
+			continue
+		}
+		a.flagged[i] = true
+		a.add(model.FindingShadowed, i, shadowed[k], "",
+			"%s matches the same packets as the broader %s, every packet it matches is matched by %s", n.prefix, parent.prefix, a.rules[shadowed[k][0]].Rule.RuleInfo.Key())
+	}
+}
+
+// protectedConflicts reports the rules of the prefix a protected range is
+// punched out of
+func (a *analyzer) protectedConflicts(n *prefixNode) {
+	for _, i := range n.parent.rules {
+		a.add(model.FindingConflict, i, nil, n.prefix.String(),
+			"the protected range %s of %s inside it is never banned, the packets it matches from %s pass", n.prefix, n.protected, n.prefix)
+	}
+}
+
+func (a *analyzer) unreachable(i int, format string, args ...any) {
+	a.flagged[i] = true
+	a.add(model.FindingUnreachable, i, nil, "", format, args...)
+}
+
+func (a *analyzer) add(kind string, i int, related []int, prefix string, format string, args ...any) {
+	f := model.Finding{
+		Kind:    kind,
+		Rule:    a.rules[i],
+		Related: make([]model.EffectiveRule, 0, min(len(related), maxRelated)),
+		Cidr:    prefix,
+		Message: fmt.Sprintf(format, args...),
+	}
+	for _, j := range related[:min(len(related), maxRelated)] {
+		f.Related = append(f.Related, a.rules[j])
+	}
+	a.findings = append(a.findings, f)
+}
@@ -0,0 +1,70 @@
+package rulecenter
+
+import (
+	"testing"
+	protectModel "xdp-banner/orch/model/protect"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/rule"
+)
+
+func TestAnalyzeRules(t *testing.T) {
+	r := func(source, cidr, protocol string, sport, dport uint16) model.EffectiveRule {
+		return model.EffectiveRule{Source: source, Rule: model.Rule{RuleInfo: rule.RuleInfo{Cidr: cidr, Protocol: protocol, Sport: sport, Dport: dport}}}
+	}
+	rules := []model.EffectiveRule{
+		// 10.1.0.0/16 的 TCP 规则不作用于有自己规则的 10.1.2.0/24
+		r("web", "10.1.0.0/16", "TCP", 0, 0),
+		r("web", "10.1.2.0/24", "UDP", 0, 53),
+		// 同一前缀上被任意端口规则覆盖
+		r("web", "10.1.2.0/24", "UDP", 1234, 53),
+		r("web", "10.1.2.0/24", "UDP", 0, 0),
+		// 与父前缀匹配相同的报文
+		r("web", "10.1.3.0/24", "TCP", 0, 22),
+		r("web", "10.1.3.0/24", "TCP", 0, 0),
+		// 不同规则集中的同一规则
+		r("base", "10.1.3.0/24", "TCP", 0, 22),
+		// IPv6 源的 ICMP 报文按 ICMPv6 查找
+		r("web", "2001:db8::/32", "ICMP", 0, 0),
+		r("web", "192.0.2.0/24", "Tcp", 0, 0),
+		r("web", "192.168.1.0/24", "TCP", 0, 0),
+		r("web", "172.16.0.0/12", "TCP", 0, 0),
+		// 被两个子前缀完全覆盖
+		r("web", "198.51.100.0/24", "TCP", 0, 0),
+		r("web", "198.51.100.0/25", "UDP", 0, 0),
+		r("web", "198.51.100.128/25", "UDP", 0, 0),
+	}
+	protected := []protectModel.EffectiveRange{
+		{Cidr: "192.168.0.0/16", Source: "config"},
+		{Cidr: "172.16.1.1/32", Source: "orch/orch-1"},
+	}
+
+	type finding struct {
+		kind, key, source, cidr string
+	}
+	want := map[finding]bool{
+		{model.FindingConflict, rules[0].Rule.RuleInfo.Key(), "web", "10.1.2.0/24"}:    true,
+		{model.FindingShadowed, rules[2].Rule.RuleInfo.Key(), "web", ""}:               true,
+		{model.FindingShadowed, rules[1].Rule.RuleInfo.Key(), "web", ""}:               true,
+		{model.FindingShadowed, rules[4].Rule.RuleInfo.Key(), "web", ""}:               true,
+		{model.FindingShadowed, rules[5].Rule.RuleInfo.Key(), "web", ""}:               true,
+		{model.FindingDuplicate, rules[6].Rule.RuleInfo.Key(), "base", ""}:             true,
+		{model.FindingUnreachable, rules[7].Rule.RuleInfo.Key(), "web", ""}:            true,
+		{model.FindingUnreachable, rules[8].Rule.RuleInfo.Key(), "web", ""}:            true,
+		{model.FindingUnreachable, rules[9].Rule.RuleInfo.Key(), "web", ""}:            true,
+		{model.FindingConflict, rules[10].Rule.RuleInfo.Key(), "web", "172.16.1.1/32"}: true,
+		{model.FindingUnreachable, rules[11].Rule.RuleInfo.Key(), "web", ""}:           true,
+	}
+
+	findings := analyzeRules(rules, protected)
+	for _, f := range findings {
+		got := finding{f.Kind, f.Rule.Rule.RuleInfo.Key(), f.Rule.Source, f.Cidr}
+		if !want[got] {
+			t.Errorf("unexpected finding %+v: %s", got, f.Message)
+			continue
+		}
+		delete(want, got)
+	}
+	for f := range want {
+		t.Errorf("missing finding %+v", f)
+	}
+}
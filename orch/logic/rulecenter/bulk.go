@@ -0,0 +1,278 @@
+package rulecenter
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"slices"
+	"time"
+	"xdp-banner/orch/logic/rulecenter/bulk"
+	"xdp-banner/orch/logic/rulecenter/validation"
+	protectModel "xdp-banner/orch/model/protect"
+	model "xdp-banner/orch/model/rule"
+	ruleStorage "xdp-banner/orch/storage/agent/rule"
+	"xdp-banner/pkg/errors"
+	prule "xdp-banner/pkg/rule"
+)
+
+// maxReportedErrors bounds the line errors of an import report
+const maxReportedErrors = 1000
+
+// pendingRule is a rule of an import waiting for its batch, a merged rule
+// comes from several lines
+type pendingRule struct {
+	lines []int
+	rule  *model.Rule
+}
+
+type importer struct {
+	rc        *RuleCenter
+	name      string
+	createdAt time.Time
+	aggregate bool
+
+	// validator and ranges are read once for the whole import
+	validator validation.Validator
+	ranges    []protectModel.EffectiveRange
+
+	report model.ImportReport
+	failed map[int]bool
+	batch  []pendingRule
+}
+
+// ImportRules adds the rules read from src to a rule set. Every rule is
+// validated and guarded like AddRule, against the rule count and the
+// protected ranges read once at the start, the rules are written BatchSize
+// per etcd transaction. A line which is not a valid rule is reported and
+// skipped, an error of etcd or of a validator stops the import, the batches
+// written before stay.
+//
+// With aggregate the rules are read before any of them is written and merged
+// by aggregateRules around the prefixes of the merged view of the set, a
+// merged rule is validated again and its rules are added as they are when it
+// is refused.
+func (r *RuleCenter) ImportRules(ctx context.Context, name string, src io.Reader, format bulk.Format, defaults bulk.Defaults, aggregate bool) (*model.ImportReport, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	ctx = withActor(ctx)
+	im := &importer{
+		rc:        r,
+		name:      name,
+		createdAt: time.Now(),
+		aggregate: aggregate,
+		failed:    make(map[int]bool),
+	}
+
+	// 规则数和保护网段只在导入开始时读取一次, 每行在内存中检查
+	validator, err := validation.Snapshot(ctx, r.validator, name)
+	if err != nil {
+		return nil, errors.NewServiceErrorf("import stopped before adding any rule: %v", err)
+	}
+	ranges, err := r.guard.Effective(ctx)
+	if err != nil {
+		return nil, err
+	}
+	im.validator, im.ranges = validator, ranges
+
+	reader := bulk.NewReader(src, format, defaults)
+	for {
+		line, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.NewInputErrorf("read %s failed after adding %d rules: %v", format, im.report.Added, err)
+		}
+
+		im.report.Lines++
+		if line.Err != nil {
+			im.fail(line.Number, line.Err)
+			continue
+		}
+		if err := im.add(ctx, line); err != nil {
+			return nil, err
+		}
+	}
+
+	if im.aggregate {
+		if err := im.merge(ctx); err != nil {
+			return nil, err
+		}
+	}
+	for len(im.batch) > 0 {
+		if err := im.flush(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	slices.SortFunc(im.report.Errors, func(a, b model.LineError) int { return a.Line - b.Line })
+	return &im.report, nil
+}
+
+// add validates and guards the rule of a line and queues the resulting rules
+func (im *importer) add(ctx context.Context, line bulk.Line) error {
+	d, err := prule.ParseRuleDuration(line.Info.Duration)
+	if err != nil {
+		im.fail(line.Number, err)
+		return nil
+	}
+
+	rule := &model.Rule{
+		RuleInfo: line.Info,
+		RuleMeta: prule.RuleMeta{
+			Comment:   line.Info.Comment,
+			CreatedAt: im.createdAt,
+			// Init when needed
+			Identity: "0",
+		},
+	}
+	if d > 0 {
+		// 同一次导入中相同 duration 的规则共用一个 lease
+		rule.RuleMeta.ExpiresAt = im.createdAt.Add(d)
+	}
+
+	if err := validateWith(ctx, im.validator, im.name, rule); err != nil {
+		return im.failOrStop(line.Number, err)
+	}
+	rules, err := im.rc.guard.GuardRanges(rule, im.ranges)
+	if err != nil {
+		return im.failOrStop(line.Number, err)
+	}
+
+	for _, rule := range rules {
+		im.batch = append(im.batch, pendingRule{lines: []int{line.Number}, rule: rule})
+	}
+	for !im.aggregate && len(im.batch) >= ruleStorage.BatchSize {
+		if err := im.flush(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush writes the first BatchSize queued rules in one transaction
+func (im *importer) flush(ctx context.Context) error {
+	n := min(len(im.batch), ruleStorage.BatchSize)
+	batch := im.batch[:n]
+
+	rules := make([]*model.Rule, n)
+	for i, p := range batch {
+		rules[i] = p.rule
+	}
+
+	errs, err := im.rc.storage.AddBatch(ctx, im.name, rules)
+	if err != nil {
+		return errors.NewServiceErrorf("import stopped at line %d after adding %d rules: %v", batch[0].lines[0], im.report.Added, err)
+	}
+
+	for i, err := range errs {
+		if err == nil {
+			im.report.Added++
+			continue
+		}
+		if err == ruleStorage.ErrRuleAlreadyExists {
+			err = stderrors.New("rule " + rules[i].RuleInfo.Key() + " already exists")
+		}
+		for _, line := range batch[i].lines {
+			im.fail(line, err)
+		}
+	}
+
+	im.batch = im.batch[n:]
+	return nil
+}
+
+// merge replaces the queued rules by their aggregation
+func (im *importer) merge(ctx context.Context) error {
+	rules := make([]*model.Rule, len(im.batch))
+	for i, p := range im.batch {
+		rules[i] = p.rule
+	}
+
+	// 代理按最长前缀匹配, 合并不能越过规则集视图中已有的前缀
+	existing, err := im.rc.viewPrefixes(ctx, im.name, "")
+	if err != nil {
+		return errors.NewServiceErrorf("import stopped before adding any rule: %v", err)
+	}
+
+	batch := make([]pendingRule, 0, len(im.batch))
+	for _, a := range aggregateRules(rules, existing) {
+		if !a.merged() {
+			batch = append(batch, im.batch[a.members[0]])
+			continue
+		}
+
+		lines := make([]int, 0, len(a.members))
+		for _, i := range a.members {
+			lines = append(lines, im.batch[i].lines...)
+		}
+		slices.Sort(lines)
+		lines = slices.Compact(lines)
+
+		if err := validateWith(ctx, im.validator, im.name, a.rule); err != nil {
+			var appErr *errors.AppError
+			if stderrors.As(err, &appErr) && appErr.Type == errors.ServiceError {
+				return errors.NewServiceErrorf("import stopped at line %d before adding any rule: %s", lines[0], appErr.Message)
+			}
+			// 合并后的规则被拒绝时原样写入原来的规则
+			for _, i := range a.members {
+				batch = append(batch, im.batch[i])
+			}
+			continue
+		}
+		batch = append(batch, pendingRule{lines: lines, rule: a.rule})
+	}
+
+	im.report.Saved = int64(len(im.batch) - len(batch))
+	im.batch = batch
+	return nil
+}
+
+// failOrStop reports the error of a line, a service error stops the import
+func (im *importer) failOrStop(line int, err error) error {
+	var appErr *errors.AppError
+	if stderrors.As(err, &appErr) && appErr.Type == errors.ServiceError {
+		return errors.NewServiceErrorf("import stopped at line %d after adding %d rules: %s", line, im.report.Added, appErr.Message)
+	}
+
+	im.fail(line, err)
+	return nil
+}
+
+func (im *importer) fail(line int, err error) {
+	if im.failed[line] {
+		return
+	}
+	im.failed[line] = true
+	im.report.Failed++
+
+	if len(im.report.Errors) >= maxReportedErrors {
+		return
+	}
+	msg := err.Error()
+	var appErr *errors.AppError
+	if stderrors.As(err, &appErr) {
+		msg = appErr.Message
+	}
+	im.report.Errors = append(im.report.Errors, model.LineError{Line: line, Error: msg})
+}
+
+// ExportRules writes a rule set to w in the format, see bulk.Writer
+func (r *RuleCenter) ExportRules(ctx context.Context, name string, w io.Writer, format bulk.Format) error {
+	rules, err := r.GetRule(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	writer := bulk.NewWriter(w, format, time.Now())
+	for _, rule := range rules {
+		if err := writer.Write(rule); err != nil {
+			return errors.NewServiceErrorf("failed to export rule: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return errors.NewServiceErrorf("failed to export rule: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package bulk
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/rule"
+)
+
+func readAll(t *testing.T, input string, format Format, defaults Defaults) []Line {
+	t.Helper()
+
+	var lines []Line
+	reader := NewReader(strings.NewReader(input), format, defaults)
+	for {
+		line, err := reader.Next()
+		if err == io.EOF {
+			return lines
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestReader(t *testing.T) {
+	defaults := Defaults{Protocol: "TCP", Duration: "1h", Comment: "imported"}
+
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+		want   []rule.RuleInfo
+		errors []int // the line numbers with an error
+	}{
+		{
+			name:   "ndjson",
+			format: NDJSON,
+			input: `{"cidr":"10.0.0.1/24","protocol":"udp","dport":53,"duration":"permanent","comment":"dns"}
+
+# skipped
+{"cidr":"192.0.2.1"}
+{"cidr":"192.0.2.1"
+{"cidr":"192.0.2.0/24","dport":70000}
+`,
+			want: []rule.RuleInfo{
+				{Cidr: "10.0.0.0/24", Protocol: "UDP", Dport: 53, Duration: "permanent", Comment: "dns"},
+				{Cidr: "192.0.2.1/32", Protocol: "TCP", Duration: "1h", Comment: "imported"},
+			},
+			errors: []int{5, 6},
+		},
+		{
+			name:   "csv with header",
+			format: CSV,
+			input: `cidr,dport,comment
+10.0.0.0/8,22,ssh
+# skipped
+2001:db8::1,"443","web, tls"
+10.0.0.0/8,x
+10.0.0.0/8,1,a,b
+`,
+			want: []rule.RuleInfo{
+				{Cidr: "10.0.0.0/8", Protocol: "TCP", Dport: 22, Duration: "1h", Comment: "ssh"},
+				{Cidr: "2001:db8::1/128", Protocol: "TCP", Dport: 443, Duration: "1h", Comment: "web, tls"},
+			},
+			errors: []int{5, 6},
+		},
+		{
+			name:   "csv without header",
+			format: CSV,
+			input:  "10.0.0.0/8,ICMP,0,0,5m\n\n192.0.2.7\nnot-an-ip,TCP\n",
+			want: []rule.RuleInfo{
+				{Cidr: "10.0.0.0/8", Protocol: "ICMP", Duration: "5m", Comment: "imported"},
+				{Cidr: "192.0.2.7/32", Protocol: "TCP", Duration: "1h", Comment: "imported"},
+			},
+			errors: []int{4},
+		},
+		{
+			name:   "plain",
+			format: Plain,
+			input:  "# blocklist\n192.0.2.1\n198.51.100.0/24 ; SBL123\n2001:db8::/32 # bad net\n\n192.0.2.1 192.0.2.2\n",
+			want: []rule.RuleInfo{
+				{Cidr: "192.0.2.1/32", Protocol: "TCP", Duration: "1h", Comment: "imported"},
+				{Cidr: "198.51.100.0/24", Protocol: "TCP", Duration: "1h", Comment: "SBL123"},
+				{Cidr: "2001:db8::/32", Protocol: "TCP", Duration: "1h", Comment: "bad net"},
+			},
+			errors: []int{6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []rule.RuleInfo
+			var errors []int
+			for _, line := range readAll(t, tt.input, tt.format, defaults) {
+				if line.Err != nil {
+					errors = append(errors, line.Number)
+					continue
+				}
+				got = append(got, line.Info)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rules = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(errors, tt.errors) {
+				t.Errorf("error lines = %v, want %v", errors, tt.errors)
+			}
+		})
+	}
+}
+
+func TestReaderNoDefaultProtocol(t *testing.T) {
+	lines := readAll(t, "192.0.2.1\n", Plain, Defaults{})
+	if len(lines) != 1 || lines[0].Err == nil {
+		t.Fatalf("lines = %+v, want a protocol error", lines)
+	}
+}
+
+func TestReaderUnknownColumn(t *testing.T) {
+	reader := NewReader(strings.NewReader("cidr,port\n10.0.0.0/8,22\n"), CSV, Defaults{})
+	if _, err := reader.Next(); err == nil {
+		t.Fatal("Next() error = nil, want unknown column")
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rules := model.RuleItem{
+		{
+			RuleInfo: rule.RuleInfo{Cidr: "10.0.0.0/24", Protocol: "TCP", Dport: 22},
+			RuleMeta: rule.RuleMeta{Comment: "ssh, brute force", ExpiresAt: now.Add(90*time.Minute + 500*time.Millisecond)},
+		},
+		{
+			RuleInfo: rule.RuleInfo{Cidr: "10.0.0.0/24", Protocol: "UDP", Sport: 123},
+			RuleMeta: rule.RuleMeta{Comment: "ntp"},
+		},
+		{
+			RuleInfo: rule.RuleInfo{Cidr: "2001:db8::/32", Protocol: "ICMP"},
+			RuleMeta: rule.RuleMeta{ExpiresAt: now.Add(-time.Second)},
+		},
+	}
+	infos := []rule.RuleInfo{
+		{Cidr: "10.0.0.0/24", Protocol: "TCP", Dport: 22, Duration: "1h30m1s", Comment: "ssh, brute force"},
+		{Cidr: "10.0.0.0/24", Protocol: "UDP", Sport: 123, Duration: "permanent", Comment: "ntp"},
+		{Cidr: "2001:db8::/32", Protocol: "ICMP", Duration: "1s"},
+	}
+
+	for _, format := range []Format{NDJSON, CSV, Plain} {
+		t.Run(format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, format, now)
+			for _, r := range rules {
+				if err := w.Write(r); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			var got []rule.RuleInfo
+			for _, line := range readAll(t, buf.String(), format, Defaults{Protocol: "TCP"}) {
+				if line.Err != nil {
+					t.Fatalf("line %d: %v\n%s", line.Number, line.Err, buf.String())
+				}
+				got = append(got, line.Info)
+			}
+
+			want := infos
+			if format == Plain {
+				want = []rule.RuleInfo{{Cidr: "10.0.0.0/24", Protocol: "TCP"}, {Cidr: "2001:db8::/32", Protocol: "TCP"}}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("read back %+v, want %+v\n%s", got, want, buf.String())
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for s, want := range map[string]Format{"": NDJSON, "NDJSON": NDJSON, "csv": CSV, "plain": Plain} {
+		if got, err := ParseFormat(s); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error(`ParseFormat("xml") error = nil`)
+	}
+
+	if got, ok := FormatFromContentType("text/csv; charset=utf-8"); !ok || got != CSV {
+		t.Errorf("FormatFromContentType() = %v, %v, want csv", got, ok)
+	}
+	if _, ok := FormatFromContentType("application/json"); ok {
+		t.Error("FormatFromContentType(application/json) ok = true")
+	}
+}
@@ -0,0 +1,74 @@
+// Package bulk reads and writes rule sets as NDJSON, CSV or plain IP lists
+package bulk
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// Format is the format of a bulk import or export
+type Format int
+
+const (
+	// NDJSON is one rule per line in the v1 rule json, e.g.
+	// {"cidr":"10.0.0.0/24","protocol":"TCP","dport":22,"duration":"1h"}
+	NDJSON Format = iota
+	// CSV has the columns cidr,protocol,sport,dport,duration,comment, a
+	// header line names the columns when they are in another order
+	CSV
+	// Plain is one IP or CIDR per line, text after # or ; is the comment.
+	// It keeps no protocol, port or duration.
+	Plain
+)
+
+var formatNames = map[Format]string{
+	NDJSON: "ndjson",
+	CSV:    "csv",
+	Plain:  "plain",
+}
+
+var contentTypes = map[Format]string{
+	NDJSON: "application/x-ndjson",
+	CSV:    "text/csv",
+	Plain:  "text/plain",
+}
+
+func (f Format) String() string {
+	if name, ok := formatNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// ContentType is the http content type of the format
+func (f Format) ContentType() string {
+	return contentTypes[f]
+}
+
+// ParseFormat parses the name of a format, an empty name is NDJSON
+func ParseFormat(s string) (Format, error) {
+	if s == "" {
+		return NDJSON, nil
+	}
+	for f, name := range formatNames {
+		if strings.EqualFold(s, name) {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown format %q, use one of ndjson, csv and plain", s)
+}
+
+// FormatFromContentType returns the format of a http content type
+func FormatFromContentType(contentType string) (Format, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return 0, false
+	}
+	for f, ct := range contentTypes {
+		if mediaType == ct {
+			return f, true
+		}
+	}
+	return 0, false
+}
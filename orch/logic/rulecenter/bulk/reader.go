@@ -0,0 +1,234 @@
+package bulk
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"xdp-banner/pkg/cidr"
+	"xdp-banner/pkg/rule"
+)
+
+// maxLineSize bounds a line of NDJSON and plain lists
+const maxLineSize = 1 << 20
+
+// Defaults fill the fields a line leaves empty
+type Defaults struct {
+	Protocol string
+	Duration string
+	Comment  string
+}
+
+// Line is a rule read from a line, Err is set for a line which is not a rule
+type Line struct {
+	Number int
+	Info   rule.RuleInfo
+	Err    error
+}
+
+// Reader reads rules line by line, blank lines and lines starting with # are
+// skipped. A malformed line is returned with its Err, only the errors of the
+// underlying reader stop it.
+type Reader struct {
+	format   Format
+	defaults Defaults
+
+	lines  *bufio.Scanner
+	number int
+
+	csv     *csv.Reader
+	columns []string
+}
+
+// NewReader returns a Reader reading the format from r
+func NewReader(r io.Reader, format Format, defaults Defaults) *Reader {
+	reader := &Reader{format: format, defaults: defaults}
+	if format == CSV {
+		reader.csv = csv.NewReader(r)
+		reader.csv.Comment = '#'
+		reader.csv.FieldsPerRecord = -1
+		reader.csv.TrimLeadingSpace = true
+		reader.csv.ReuseRecord = true
+	} else {
+		reader.lines = bufio.NewScanner(r)
+		reader.lines.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	}
+	return reader
+}
+
+// Next returns the next line, io.EOF after the last one
+func (r *Reader) Next() (Line, error) {
+	if r.format == CSV {
+		return r.nextCSV()
+	}
+
+	for r.lines.Scan() {
+		r.number++
+		text := strings.TrimSpace(r.lines.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		line := Line{Number: r.number}
+		switch r.format {
+		case NDJSON:
+			line.Info, line.Err = parseJSON(text)
+		case Plain:
+			line.Info, line.Err = parsePlain(text)
+		default:
+			return Line{}, fmt.Errorf("unsupported format %s", r.format)
+		}
+		if line.Err == nil {
+			line.Err = r.complete(&line.Info)
+		}
+		return line, nil
+	}
+
+	if err := r.lines.Err(); err != nil {
+		return Line{}, fmt.Errorf("line %d: %w", r.number+1, err)
+	}
+	return Line{}, io.EOF
+}
+
+func (r *Reader) nextCSV() (Line, error) {
+	for {
+		record, err := r.csv.Read()
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Line{Number: parseErr.StartLine, Err: parseErr.Err}, nil
+		}
+		if err != nil {
+			return Line{}, err
+		}
+		number, _ := r.csv.FieldPos(0)
+
+		if r.columns == nil {
+			r.columns = csvColumns
+			if strings.EqualFold(strings.TrimSpace(record[0]), "cidr") {
+				if r.columns, err = parseHeader(record); err != nil {
+					return Line{}, fmt.Errorf("line %d: %w", number, err)
+				}
+				continue
+			}
+		}
+
+		line := Line{Number: number}
+		line.Info, line.Err = parseCSV(r.columns, record)
+		if line.Err == nil {
+			line.Err = r.complete(&line.Info)
+		}
+		return line, nil
+	}
+}
+
+// complete applies the defaults and makes the cidr canonical
+func (r *Reader) complete(info *rule.RuleInfo) error {
+	if info.Cidr == "" {
+		return errors.New("cidr is missing")
+	}
+	prefix, err := cidr.Parse(info.Cidr)
+	if err != nil {
+		return err
+	}
+	info.Cidr = prefix.String()
+
+	if info.Protocol == "" {
+		info.Protocol = r.defaults.Protocol
+	}
+	if info.Protocol == "" {
+		return errors.New("protocol is missing and there is no default protocol")
+	}
+	info.Protocol = strings.ToUpper(info.Protocol)
+
+	if info.Duration == "" {
+		info.Duration = r.defaults.Duration
+	}
+	if info.Comment == "" {
+		info.Comment = r.defaults.Comment
+	}
+	return nil
+}
+
+func parseJSON(text string) (rule.RuleInfo, error) {
+	var info rule.RuleInfo
+	if err := json.Unmarshal([]byte(text), &info); err != nil {
+		return rule.RuleInfo{}, fmt.Errorf("invalid json: %w", err)
+	}
+	return info, nil
+}
+
+// parsePlain parses "<ip or cidr> [# comment]", ";" starts a comment as well
+func parsePlain(text string) (rule.RuleInfo, error) {
+	address, comment := text, ""
+	if i := strings.IndexAny(text, "#;"); i >= 0 {
+		address, comment = text[:i], strings.TrimSpace(text[i+1:])
+	}
+
+	address = strings.TrimSpace(address)
+	if strings.ContainsAny(address, " \t") {
+		return rule.RuleInfo{}, fmt.Errorf("expected one ip or cidr per line, got %q", address)
+	}
+	return rule.RuleInfo{Cidr: address, Comment: comment}, nil
+}
+
+// csvColumns is the order of the columns without a header
+var csvColumns = []string{"cidr", "protocol", "sport", "dport", "duration", "comment"}
+
+func parseHeader(record []string) ([]string, error) {
+	columns := make([]string, len(record))
+	for i, column := range record {
+		columns[i] = strings.ToLower(strings.TrimSpace(column))
+		if !slices.Contains(csvColumns, columns[i]) {
+			return nil, fmt.Errorf("unknown column %q, the columns are %s", column, strings.Join(csvColumns, ","))
+		}
+	}
+	return columns, nil
+}
+
+func parseCSV(columns []string, record []string) (rule.RuleInfo, error) {
+	if len(record) > len(columns) {
+		return rule.RuleInfo{}, fmt.Errorf("expected at most %d fields, got %d", len(columns), len(record))
+	}
+
+	var info rule.RuleInfo
+	for i, field := range record {
+		field = strings.TrimSpace(field)
+		switch columns[i] {
+		case "cidr":
+			info.Cidr = field
+		case "protocol":
+			info.Protocol = field
+		case "sport", "dport":
+			port, err := parsePort(field)
+			if err != nil {
+				return rule.RuleInfo{}, fmt.Errorf("invalid %s: %w", columns[i], err)
+			}
+			if columns[i] == "sport" {
+				info.Sport = port
+			} else {
+				info.Dport = port
+			}
+		case "duration":
+			info.Duration = field
+		case "comment":
+			info.Comment = field
+		}
+	}
+	return info, nil
+}
+
+func parsePort(s string) (uint16, error) {
+	if s == "" {
+		return 0, nil
+	}
+	port, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a port number", s)
+	}
+	return uint16(port), nil
+}
@@ -0,0 +1,108 @@
+package bulk
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/rule"
+)
+
+// Writer writes rules in a format the Reader reads back. The duration of a
+// rule is what is left of it, rounded up to the second.
+type Writer struct {
+	format Format
+	now    time.Time
+
+	w      *bufio.Writer
+	csv    *csv.Writer
+	header bool
+	// cidrs are the CIDRs a plain list already has
+	cidrs map[string]bool
+}
+
+// NewWriter returns a Writer writing the format to w, the durations are
+// counted from now
+func NewWriter(w io.Writer, format Format, now time.Time) *Writer {
+	writer := &Writer{format: format, now: now, w: bufio.NewWriterSize(w, 32*1024)}
+	switch format {
+	case CSV:
+		writer.csv = csv.NewWriter(writer.w)
+	case Plain:
+		writer.cidrs = make(map[string]bool)
+	}
+	return writer
+}
+
+// Write writes a rule, a plain list has every CIDR once
+func (w *Writer) Write(r model.Rule) error {
+	info := r.RuleInfo
+	info.Comment = r.RuleMeta.Comment
+	info.Duration = w.remaining(r.RuleMeta)
+
+	switch w.format {
+	case NDJSON:
+		b, err := json.Marshal(info)
+		if err != nil {
+			return err
+		}
+		if _, err := w.w.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	case CSV:
+		if !w.header {
+			// 第一行写入表头
+			w.header = true
+			if err := w.csv.Write(csvColumns); err != nil {
+				return err
+			}
+		}
+		return w.csv.Write([]string{
+			info.Cidr,
+			info.Protocol,
+			strconv.FormatUint(uint64(info.Sport), 10),
+			strconv.FormatUint(uint64(info.Dport), 10),
+			info.Duration,
+			info.Comment,
+		})
+	case Plain:
+		if w.cidrs[info.Cidr] {
+			return nil
+		}
+		w.cidrs[info.Cidr] = true
+		if _, err := fmt.Fprintln(w.w, info.Cidr); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported format %s", w.format)
+	}
+	return nil
+}
+
+// Flush writes the buffered rules to the underlying writer
+func (w *Writer) Flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return w.w.Flush()
+}
+
+func (w *Writer) remaining(meta rule.RuleMeta) string {
+	if meta.Permanent() {
+		return rule.PermanentDuration
+	}
+
+	d := meta.ExpiresAt.Sub(w.now)
+	if d < time.Second {
+		// 已到期但 lease 尚未回收
+		return time.Second.String()
+	}
+	return (d + time.Second - 1).Truncate(time.Second).String()
+}
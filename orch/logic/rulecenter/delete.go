@@ -0,0 +1,131 @@
+package rulecenter
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	model "xdp-banner/orch/model/rule"
+	ruleStorage "xdp-banner/orch/storage/agent/rule"
+	"xdp-banner/pkg/cidr"
+	"xdp-banner/pkg/errors"
+)
+
+// Selector selects rules of a rule set, the set fields must all match
+type Selector struct {
+	// Cidr matches the rules inside it
+	Cidr string
+	// Protocol matches the protocol, case insensitive
+	Protocol string
+	// Comment matches the rules whose comment contains it
+	Comment string
+}
+
+// matcher returns the func matching the rules, an empty selector is refused
+func (s Selector) matcher() (ruleStorage.SelectFunc, error) {
+	if s.Cidr == "" && s.Protocol == "" && s.Comment == "" {
+		return nil, fmt.Errorf("empty selector, delete the rule set to delete all its rules")
+	}
+
+	var prefix netip.Prefix
+	if s.Cidr != "" {
+		var err error
+		if prefix, err = cidr.Parse(s.Cidr); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(_ string, r model.Rule) bool {
+		if s.Cidr != "" {
+			p, err := cidr.Parse(r.RuleInfo.Cidr)
+			if err != nil || !cidr.Contains(prefix, p) {
+				return false
+			}
+		}
+		if s.Protocol != "" && !strings.EqualFold(s.Protocol, r.RuleInfo.Protocol) {
+			return false
+		}
+		if s.Comment != "" && !strings.Contains(r.RuleMeta.Comment, s.Comment) {
+			return false
+		}
+		return true
+	}, nil
+}
+
+// DeleteRulesByKey deletes the rules of the keys, see RuleInfo.Key. The keys
+// not found are returned, the found ones are deleted anyway.
+func (r *RuleCenter) DeleteRulesByKey(ctx context.Context, name string, keys []string) (*model.Removed, []string, error) {
+	if len(keys) == 0 {
+		return nil, nil, errors.NewInputError("keys are required")
+	}
+
+	wanted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		wanted[strings.TrimSuffix(key, "/")+"/"] = false
+	}
+
+	removed, err := r.deleteRules(ctx, name, func(key string, _ model.Rule) bool {
+		if _, ok := wanted[key]; !ok {
+			return false
+		}
+		wanted[key] = true
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var notFound []string
+	for key, found := range wanted {
+		if !found {
+			notFound = append(notFound, key)
+		}
+	}
+	return removed, notFound, nil
+}
+
+// DeleteRulesBySelector deletes the rules matching the selector
+func (r *RuleCenter) DeleteRulesBySelector(ctx context.Context, name string, sel Selector) (*model.Removed, error) {
+	match, err := sel.matcher()
+	if err != nil {
+		return nil, errors.NewInputErrorf("invalid selector: %v", err)
+	}
+
+	return r.deleteRules(ctx, name, match)
+}
+
+// DeleteRuleSet deletes a rule set, its identities, its name and its
+// scheduled rules
+func (r *RuleCenter) DeleteRuleSet(ctx context.Context, name string) (*model.Removed, error) {
+	ctx = withActor(ctx)
+	// 先删除定时规则, 以免 leader 在下一个窗口又把规则加回来
+	scheduled, err := r.schedules.DeleteSet(ctx, name)
+	if err != nil {
+		return nil, errors.NewServiceErrorf("failed to delete scheduled rules: %v", err)
+	}
+	removed, err := r.storage.DeleteRuleSet(ctx, name)
+	if err != nil {
+		if err == ruleStorage.ErrRuleNotFound && scheduled > 0 {
+			return &model.Removed{}, nil
+		}
+		return nil, deleteError(err)
+	}
+	return removed, nil
+}
+
+func (r *RuleCenter) deleteRules(ctx context.Context, name string, match ruleStorage.SelectFunc) (*model.Removed, error) {
+	ctx = withActor(ctx)
+	removed, err := r.storage.DeleteRules(ctx, name, match)
+	if err != nil {
+		return nil, deleteError(err)
+	}
+	return removed, nil
+}
+
+func deleteError(err error) error {
+	switch err {
+	case ruleStorage.ErrRuleNotFound:
+		return errors.NewInputError("rule set not found")
+	}
+	return errors.NewServiceErrorf("failed to delete rules: %v", err)
+}
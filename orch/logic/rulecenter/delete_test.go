@@ -0,0 +1,45 @@
+package rulecenter
+
+import (
+	"testing"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/rule"
+)
+
+func TestSelector(t *testing.T) {
+	r := model.Rule{
+		RuleInfo: rule.RuleInfo{Cidr: "10.1.0.0/16", Protocol: "TCP", Dport: 22},
+		RuleMeta: rule.RuleMeta{Comment: "ssh brute force"},
+	}
+
+	tests := []struct {
+		sel  Selector
+		want bool
+	}{
+		{Selector{Cidr: "10.0.0.0/8"}, true},
+		{Selector{Cidr: "10.1.0.0/16"}, true},
+		{Selector{Cidr: "10.1.0.0/24"}, false},
+		{Selector{Protocol: "tcp"}, true},
+		{Selector{Protocol: "UDP"}, false},
+		{Selector{Comment: "brute"}, true},
+		{Selector{Cidr: "10.0.0.0/8", Protocol: "UDP"}, false},
+		{Selector{Cidr: "10.0.0.0/8", Protocol: "TCP", Comment: "ssh"}, true},
+	}
+
+	for _, tt := range tests {
+		match, err := tt.sel.matcher()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := match(r.RuleInfo.Key(), r); got != tt.want {
+			t.Fatalf("%+v matches = %v, want %v", tt.sel, got, tt.want)
+		}
+	}
+
+	if _, err := (Selector{}).matcher(); err == nil {
+		t.Fatal("empty selector should be refused")
+	}
+	if _, err := (Selector{Cidr: "bad"}).matcher(); err == nil {
+		t.Fatal("invalid cidr should be refused")
+	}
+}
@@ -0,0 +1,160 @@
+package rulecenter
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strconv"
+	protectModel "xdp-banner/orch/model/protect"
+	model "xdp-banner/orch/model/rule"
+	ruleStorage "xdp-banner/orch/storage/agent/rule"
+	"xdp-banner/pkg/cidr"
+	"xdp-banner/pkg/datapath"
+	"xdp-banner/pkg/errors"
+)
+
+// Explain returns the verdict the XDP program of the agents watching a rule
+// set gives a packet, from the maps they write for its merged view and the
+// protected ranges. The static rules of the agents are not known here.
+func (r *RuleCenter) Explain(ctx context.Context, name string, packet datapath.Packet) (*model.Explanation, error) {
+	sets, rules, err := r.composer.Effective(ctx, name)
+	if err != nil {
+		return nil, errors.NewServiceErrorf("failed to merge rule set: %v", err)
+	}
+
+	protected, err := r.guard.Effective(ctx)
+	if err != nil {
+		return nil, errors.NewServiceErrorf("failed to get the protected ranges: %v", err)
+	}
+
+	explanation := explain(rules, protected, packet)
+	explanation.Sets = sets
+	return explanation, nil
+}
+
+// ExplainAgent explains a packet with the effective rule set an agent watches
+func (r *RuleCenter) ExplainAgent(ctx context.Context, agent string, packet datapath.Packet) (*model.Explanation, error) {
+	name, err := r.watchedRuleSet(ctx, agent)
+	if err != nil {
+		return nil, err
+	}
+	return r.Explain(ctx, name, packet)
+}
+
+// explain builds the maps of the rules like the agents and decides the packet
+// with them
+func explain(rules []model.EffectiveRule, protected []protectModel.EffectiveRange, packet datapath.Packet) *model.Explanation {
+	return NewMatcher(rules, protected).Explain(packet)
+}
+
+// matchKey is a banlist entry of a prefix
+type matchKey struct {
+	prefix netip.Prefix
+	entry  datapath.Entry
+}
+
+// Matcher decides the packets with the maps the agents write for rules and
+// protected ranges, and tells the rule matching them
+type Matcher struct {
+	maps  *datapath.Maps
+	rules []model.EffectiveRule
+	// matches are the rules writing a banlist entry, the one with the
+	// smallest key when several write it
+	matches map[matchKey]int
+	// protected are the sources of the protected ranges
+	protected map[netip.Prefix]string
+}
+
+// NewMatcher builds the maps of the rules like the agents. The rules the
+// agents fail to install, with an invalid CIDR, protocol or identity, are
+// left out.
+func NewMatcher(rules []model.EffectiveRule, protected []protectModel.EffectiveRange) *Matcher {
+	m := &Matcher{
+		rules:     rules,
+		matches:   make(map[matchKey]int),
+		protected: make(map[netip.Prefix]string),
+	}
+
+	installed := make([]datapath.Rule, 0, len(rules))
+	// sources 与 installed 一一对应
+	sources := make([]int, 0, len(rules))
+	for i := range rules {
+		info := rules[i].Rule.RuleInfo
+		prefix, err := cidr.Parse(info.Cidr)
+		if err != nil {
+			continue
+		}
+		protocol, ok := datapath.ParseProtocol(info.Protocol)
+		if !ok {
+			continue
+		}
+		identity, err := strconv.ParseUint(rules[i].Rule.RuleMeta.Identity, 10, 32)
+		if err != nil {
+			continue
+		}
+		r := datapath.Rule{
+			Key:      ruleStorage.RuleKey(rules[i].Source, info.Key()),
+			Prefix:   prefix.Masked(),
+			Identity: uint32(identity),
+			Entry:    datapath.NewEntry(protocol, info.Sport, info.Dport),
+		}
+		installed = append(installed, r)
+		sources = append(sources, i)
+
+		key := matchKey{r.Prefix, r.Entry}
+		// 多条规则写入同一条目时, 取 key 最小的那条
+		if j, ok := m.matches[key]; !ok || r.Key < installed[j].Key {
+			m.matches[key] = len(installed) - 1
+		}
+	}
+	for key, j := range m.matches {
+		m.matches[key] = sources[j]
+	}
+
+	var ranges []netip.Prefix
+	for _, pr := range protected {
+		if p, err := cidr.Parse(pr.Cidr); err == nil {
+			ranges = append(ranges, p)
+			if _, ok := m.protected[p.Masked()]; !ok {
+				m.protected[p.Masked()] = pr.Source
+			}
+		}
+	}
+
+	m.maps = datapath.Build(installed, ranges)
+	return m
+}
+
+// Decide returns the verdict of the XDP program and the rule matching the packet
+func (m *Matcher) Decide(packet datapath.Packet) (datapath.Verdict, *model.EffectiveRule) {
+	v := m.maps.Decide(packet)
+	if !v.Matched {
+		return v, nil
+	}
+	if i, ok := m.matches[matchKey{v.Prefix, v.Entry}]; ok {
+		return v, &m.rules[i]
+	}
+	return v, nil
+}
+
+// Explain decides the packet and explains the verdict
+func (m *Matcher) Explain(packet datapath.Packet) *model.Explanation {
+	v, rule := m.Decide(packet)
+	e := &model.Explanation{Drop: v.Drop, Reason: v.Reason}
+	if !v.Prefix.IsValid() {
+		return e
+	}
+	e.Cidr = v.Prefix.String()
+	e.Identity = strconv.FormatUint(uint64(v.Identity), 10)
+
+	if source, ok := m.protected[v.Prefix]; ok && v.Identity == datapath.AllowIdentity {
+		e.Protected = source
+		e.Reason = fmt.Sprintf("%s is the protected range of %s, no banlist entry uses its identity", v.Prefix, source)
+	}
+	if rule != nil {
+		matched := *rule
+		e.Rule = &matched
+		e.Reason = fmt.Sprintf("the rule %s of %s matches", rule.Rule.RuleInfo.Key(), rule.Source)
+	}
+	return e
+}
@@ -0,0 +1,64 @@
+package rulecenter
+
+import (
+	"net/netip"
+	"testing"
+	protectModel "xdp-banner/orch/model/protect"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/datapath"
+	"xdp-banner/pkg/rule"
+)
+
+func TestExplain(t *testing.T) {
+	r := func(source, cidr, protocol string, sport, dport uint16, identity string) model.EffectiveRule {
+		return model.EffectiveRule{Source: source, Rule: model.Rule{
+			RuleMeta: rule.RuleMeta{Identity: identity},
+			RuleInfo: rule.RuleInfo{Cidr: cidr, Protocol: protocol, Sport: sport, Dport: dport},
+		}}
+	}
+	rules := []model.EffectiveRule{
+		r("web", "10.0.0.0/8", "TCP", 0, 22, "100"),
+		r("base", "10.0.0.0/8", "UDP", 0, 0, "101"),
+		r("web", "10.1.0.0/16", "TCP", 0, 443, "102"),
+		// identity 非法的规则 agent 无法下发
+		r("web", "192.0.2.0/24", "TCP", 0, 0, ""),
+	}
+	protected := []protectModel.EffectiveRange{{Cidr: "10.2.0.0/16", Source: "config"}}
+
+	packet := func(src string, protocol uint8, sport, dport uint16) datapath.Packet {
+		return datapath.Packet{Src: netip.MustParseAddr(src), Protocol: protocol, Sport: sport, Dport: dport}
+	}
+	tests := []struct {
+		packet    datapath.Packet
+		drop      bool
+		cidr      string
+		protected string
+		rule      int
+	}{
+		{packet("10.9.0.1", datapath.ProtoTCP, 40000, 22), true, "10.0.0.0/8", "", 0},
+		{packet("10.9.0.1", datapath.ProtoUDP, 40000, 53), true, "10.0.0.0/8", "", 1},
+		{packet("10.9.0.1", datapath.ProtoTCP, 40000, 80), false, "10.0.0.0/8", "", -1},
+		// 最长前缀 10.1.0.0/16 有自己的规则, 10.0.0.0/8 的规则不生效
+		{packet("10.1.0.1", datapath.ProtoTCP, 40000, 22), false, "10.1.0.0/16", "", -1},
+		{packet("10.1.0.1", datapath.ProtoTCP, 40000, 443), true, "10.1.0.0/16", "", 2},
+		{packet("10.2.0.1", datapath.ProtoTCP, 40000, 22), false, "10.2.0.0/16", "config", -1},
+		{packet("192.0.2.1", datapath.ProtoTCP, 40000, 22), false, "", "", -1},
+	}
+	for _, tt := range tests {
+		e := explain(rules, protected, tt.packet)
+		if e.Drop != tt.drop || e.Cidr != tt.cidr || e.Protected != tt.protected {
+			t.Errorf("explain(%+v) = %+v, want drop %v from %q protected %q", tt.packet, e, tt.drop, tt.cidr, tt.protected)
+		}
+		switch {
+		case tt.rule < 0 && e.Rule != nil:
+			t.Errorf("explain(%+v) matched %+v", tt.packet, e.Rule)
+		case tt.rule >= 0 && (e.Rule == nil || e.Rule.Source != rules[tt.rule].Source || e.Rule.Rule.RuleInfo != rules[tt.rule].Rule.RuleInfo):
+			t.Errorf("explain(%+v) matched %+v, want %+v", tt.packet, e.Rule, rules[tt.rule])
+		}
+	}
+
+	// 10.0.0.0/8 的规则共用 key 最小的规则的 identity
+	if e := explain(rules, protected, packet("10.9.0.1", datapath.ProtoUDP, 1, 2)); e.Identity != "101" {
+		t.Errorf("identity of 10.0.0.0/8 = %s, want 101", e.Identity)
+	}
+}
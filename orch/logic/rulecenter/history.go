@@ -0,0 +1,130 @@
+package rulecenter
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"xdp-banner/orch/logic/rulecenter/validation"
+	model "xdp-banner/orch/model/rule"
+	ruleStorage "xdp-banner/orch/storage/agent/rule"
+	"xdp-banner/pkg/errors"
+	"xdp-banner/pkg/server/middleware"
+
+	"google.golang.org/grpc/peer"
+)
+
+// maxVersionsPerPage is the default and the largest page of ListVersions
+const maxVersionsPerPage = 100
+
+// HistoryConfig is the history section of the orch config
+type HistoryConfig struct {
+	// Retention is the number of versions kept per rule set, 0 disables the
+	// history
+	Retention int `mapstructure:"retention"`
+}
+
+func DefaultHistoryConfig() HistoryConfig {
+	return HistoryConfig{Retention: ruleStorage.DefaultRetention}
+}
+
+func (c *HistoryConfig) Check() error {
+	if c.Retention < 0 {
+		return fmt.Errorf("history retention should not be negative")
+	}
+	return nil
+}
+
+// withActor records the caller as the author of the changes made with ctx,
+// the verified client certificate first, then the peer name and address
+func withActor(ctx context.Context) context.Context {
+	if name, err := middleware.VerifiedPeerName(ctx); err == nil {
+		return ruleStorage.WithActor(ctx, name)
+	}
+	if name, ok := ctx.Value(middleware.PeerName).(string); ok && name != "" {
+		return ruleStorage.WithActor(ctx, name)
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return ruleStorage.WithActor(ctx, p.Addr.String())
+	}
+	return ctx
+}
+
+// ListVersions lists the versions of a rule set before the given one, newest
+// first, before 0 starts from the latest version
+func (r *RuleCenter) ListVersions(ctx context.Context, name string, before int64, pageSize int64) (*model.VersionList, error) {
+	if pageSize <= 0 || pageSize > maxVersionsPerPage {
+		pageSize = maxVersionsPerPage
+	}
+
+	list, err := r.storage.ListVersions(ctx, name, before, pageSize)
+	if err != nil {
+		return nil, errors.NewServiceErrorf("failed to list versions: %v", err)
+	}
+	return list, nil
+}
+
+// DiffVersions returns what changed in a rule set from a version to another
+func (r *RuleCenter) DiffVersions(ctx context.Context, name string, from, to int64) (*model.Diff, error) {
+	diff, err := r.storage.Diff(ctx, name, from, to)
+	if err != nil {
+		return nil, historyError(err)
+	}
+	return diff, nil
+}
+
+// Rollback turns a rule set back into a version, the rollback is a new
+// version. The rules put back go through the validators and the protected
+// ranges like new rules, a rule refused or in a protected range refuses the
+// whole rollback. The rules expired since the version are not restored.
+func (r *RuleCenter) Rollback(ctx context.Context, name string, version int64) (*model.Change, error) {
+	change, err := r.storage.Rollback(withActor(ctx), name, version, r.checkRollback(name))
+	if err != nil {
+		var appErr *errors.AppError
+		if stderrors.As(err, &appErr) {
+			return nil, err
+		}
+		return nil, historyError(err)
+	}
+	return change, nil
+}
+
+// checkRollback checks the rules a rollback of name puts back, the rule count
+// and the protected ranges are read once per attempt
+func (r *RuleCenter) checkRollback(name string) ruleStorage.RollbackCheck {
+	return func(ctx context.Context, puts model.RuleItem) error {
+		validator, err := validation.Snapshot(ctx, r.validator, name)
+		if err != nil {
+			return errors.NewServiceErrorf("validate rule failed: %v", err)
+		}
+		ranges, err := r.guard.Effective(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := range puts {
+			rule := &puts[i]
+			if err := validateWith(ctx, validator, name, rule); err != nil {
+				return err
+			}
+			// 回滚不裁剪规则, 裁剪后就不是原来的版本了
+			rules, err := r.guard.GuardRanges(rule, ranges)
+			if err != nil {
+				return err
+			}
+			if len(rules) != 1 || rules[0].RuleInfo.Cidr != rule.RuleInfo.Cidr {
+				return errors.NewInputErrorf("rule %s contains protected ranges, it can not be rolled back", rule.RuleInfo.Cidr)
+			}
+		}
+		return nil
+	}
+}
+
+func historyError(err error) error {
+	switch err {
+	case ruleStorage.ErrVersionNotFound, ruleStorage.ErrVersionTruncated, ruleStorage.ErrNoChange, ruleStorage.ErrHistoryDisabled:
+		return errors.NewInputError(err.Error())
+	case ruleStorage.ErrTooManyRules:
+		return errors.NewInputErrorf("%v, roll back to a closer version first", err)
+	}
+	return errors.NewServiceErrorf("rule set history failed: %v", err)
+}
@@ -0,0 +1,74 @@
+package rulecenter
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"xdp-banner/orch/logic/rulecenter/validation"
+	protectModel "xdp-banner/orch/model/protect"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/errors"
+	prule "xdp-banner/pkg/rule"
+)
+
+// clipGuard clips the rules covering its range in two halves
+type clipGuard struct {
+	cidr   string
+	halves [2]string
+}
+
+func (g clipGuard) Guard(ctx context.Context, r *model.Rule) ([]*model.Rule, error) {
+	ranges, _ := g.Effective(ctx)
+	return g.GuardRanges(r, ranges)
+}
+
+func (g clipGuard) GuardRanges(r *model.Rule, _ []protectModel.EffectiveRange) ([]*model.Rule, error) {
+	if r.RuleInfo.Cidr != g.cidr {
+		return []*model.Rule{r}, nil
+	}
+	low, high := *r, *r
+	low.RuleInfo.Cidr, high.RuleInfo.Cidr = g.halves[0], g.halves[1]
+	return []*model.Rule{&low, &high}, nil
+}
+
+func (g clipGuard) Effective(context.Context) ([]protectModel.EffectiveRange, error) {
+	return []protectModel.EffectiveRange{{Cidr: g.cidr, Source: "test"}}, nil
+}
+
+func TestCheckRollback(t *testing.T) {
+	r := &RuleCenter{
+		validator: validation.Chain{validation.CIDRValidator{}, validation.ProtocolValidator{}},
+		guard:     clipGuard{cidr: "10.0.0.0/8", halves: [2]string{"10.0.0.0/9", "10.128.0.0/9"}},
+	}
+	check := r.checkRollback("office")
+	rule := func(cidr, protocol string) model.Rule {
+		return model.Rule{RuleInfo: prule.RuleInfo{Cidr: cidr, Protocol: protocol}}
+	}
+
+	tests := []struct {
+		name string
+		puts model.RuleItem
+		ok   bool
+	}{
+		{"valid", model.RuleItem{rule("192.0.2.0/24", "TCP"), rule("198.51.100.0/24", "ICMP")}, true},
+		{"invalid protocol", model.RuleItem{rule("192.0.2.0/24", "TCP"), rule("198.51.100.0/24", "GRE")}, false},
+		{"not canonical", model.RuleItem{rule("192.0.2.1/24", "TCP")}, false},
+		{"protected", model.RuleItem{rule("192.0.2.0/24", "TCP"), rule("10.0.0.0/8", "UDP")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := check(context.Background(), tt.puts)
+			if tt.ok {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var appErr *errors.AppError
+			if !stderrors.As(err, &appErr) || appErr.Type != errors.InputError {
+				t.Fatalf("error = %v, want an input error", err)
+			}
+		})
+	}
+}
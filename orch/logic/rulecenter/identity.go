@@ -0,0 +1,18 @@
+package rulecenter
+
+import (
+	"context"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/errors"
+)
+
+// CheckIdentities checks that no two CIDRs share an identity, that every
+// identity is registered and that the rules carry the identity of their CIDR.
+// With repair the problems found are fixed, the rules keep their leases.
+func (r *RuleCenter) CheckIdentities(ctx context.Context, repair bool) (*model.IdentityReport, error) {
+	report, err := r.storage.CheckIdentities(ctx, repair)
+	if err != nil {
+		return nil, errors.NewServiceErrorf("failed to check identities: %v", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,97 @@
+package rulecenter
+
+import (
+	"context"
+	stderrors "errors"
+	"slices"
+	"strings"
+	model "xdp-banner/orch/model/rule"
+	ruleStorage "xdp-banner/orch/storage/agent/rule"
+	"xdp-banner/pkg/errors"
+)
+
+const (
+	// maxIncludes bounds the includes of a rule set
+	maxIncludes = 32
+	// watchBuffer is the number of changes a watch may lag behind
+	watchBuffer = 1000
+	// includeRetries 是 includes 被并发修改时的重试次数
+	includeRetries = 3
+)
+
+// GetIncludes returns the includes of a rule set in their declared order
+func (r *RuleCenter) GetIncludes(ctx context.Context, name string) ([]string, error) {
+	includes, err := r.storage.ListIncludes(ctx)
+	if err != nil {
+		return nil, errors.NewServiceErrorf("failed to get includes: %v", err)
+	}
+	return append([]string{}, includes.Sets[name]...), nil
+}
+
+// SetIncludes replaces the includes of a rule set, an empty list removes
+// them. The rules of the set win over the ones of its includes, which are
+// merged in their declared order, every include with its own includes. The
+// includes may name rule sets without rules yet, a cycle is refused.
+func (r *RuleCenter) SetIncludes(ctx context.Context, name string, includes []string) ([]string, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	cleaned := make([]string, 0, len(includes))
+	for _, inc := range includes {
+		inc = strings.TrimSpace(inc)
+		switch {
+		case inc == "" || strings.Contains(inc, "/"):
+			return nil, errors.NewInputErrorf("invalid include %q, a rule set name can not be empty or contain '/'", inc)
+		case inc == name:
+			return nil, errors.NewInputError("a rule set can not include itself")
+		}
+		if !slices.Contains(cleaned, inc) {
+			cleaned = append(cleaned, inc)
+		}
+	}
+	if len(cleaned) > maxIncludes {
+		return nil, errors.NewInputErrorf("a rule set includes at most %d rule sets", maxIncludes)
+	}
+
+	for range includeRetries {
+		current, err := r.storage.ListIncludes(ctx)
+		if err != nil {
+			return nil, errors.NewServiceErrorf("failed to get includes: %v", err)
+		}
+
+		// 只有 name 的 includes 改变, 新出现的环一定经过 name
+		current.Sets[name] = cleaned
+		if _, err := ruleStorage.ResolveIncludes(current.Sets, name); err != nil {
+			return nil, errors.NewInputError(err.Error())
+		}
+
+		err = r.storage.SetIncludes(ctx, name, cleaned, current.Revision)
+		if err == nil {
+			return cleaned, nil
+		}
+		if !stderrors.Is(err, ruleStorage.ErrConflict) {
+			return nil, errors.NewServiceErrorf("failed to set includes: %v", err)
+		}
+	}
+	return nil, errors.NewServiceError(ruleStorage.ErrConflict.Error())
+}
+
+// EffectiveRules returns the merged view of a rule set, the one its agents
+// watch, and the rule sets merged from the highest precedence
+func (r *RuleCenter) EffectiveRules(ctx context.Context, name string) ([]string, []model.EffectiveRule, error) {
+	order, rules, err := r.composer.Effective(ctx, name)
+	if err != nil {
+		return nil, nil, errors.NewServiceErrorf("failed to merge rule set: %v", err)
+	}
+	return order, rules, nil
+}
+
+// WatchRuleSet returns the merged view of a rule set and a channel of its
+// changes, see ruleStorage.Composer.Watch
+func (r *RuleCenter) WatchRuleSet(ctx context.Context, name string) ([]ruleStorage.ViewEvent, <-chan ruleStorage.ViewEvent, error) {
+	initial, events, err := r.composer.Watch(ctx, name, watchBuffer)
+	if err != nil {
+		return nil, nil, errors.NewServiceErrorf("failed to watch rule set: %v", err)
+	}
+	return initial, events, nil
+}
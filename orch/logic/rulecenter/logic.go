@@ -2,39 +2,108 @@ package rulecenter
 
 import (
 	"context"
+	stderrors "errors"
+	"strings"
+	"time"
 	"xdp-banner/orch/logic/rulecenter/validation"
+	"xdp-banner/orch/model/node"
+	protectModel "xdp-banner/orch/model/protect"
 	model "xdp-banner/orch/model/rule"
 	ruleStorage "xdp-banner/orch/storage/agent/rule"
+	scheduleStorage "xdp-banner/orch/storage/agent/schedule"
 	"xdp-banner/pkg/errors"
+	prule "xdp-banner/pkg/rule"
 )
 
+// Guard keeps the rules away from the protected ranges, it returns the rules
+// to store instead of the given one, see logic/protect
+type Guard interface {
+	Guard(ctx context.Context, r *model.Rule) ([]*model.Rule, error)
+	// GuardRanges is Guard against ranges returned by Effective
+	GuardRanges(r *model.Rule, ranges []protectModel.EffectiveRange) ([]*model.Rule, error)
+	// Effective returns every protected range
+	Effective(ctx context.Context) ([]protectModel.EffectiveRange, error)
+}
+
+// AgentReader reads the rule set an agent watches, the agent info storage
+// implements it
+type AgentReader interface {
+	Get(ctx context.Context, name string) (*node.AgentInfo, error)
+}
+
 type RuleCenter struct {
 	storage   ruleStorage.Storage
+	index     *ruleStorage.Index
+	composer  *ruleStorage.Composer
 	validator validation.Validator
+	guard     Guard
+	agents    AgentReader
+	schedules scheduleStorage.Storage
 }
 
-func New(rs ruleStorage.Storage) *RuleCenter {
-	// TODO: validator should be configurable
-	validator := new(validation.MockValidator)
-
+func New(rs ruleStorage.Storage, index *ruleStorage.Index, composer *ruleStorage.Composer, validator validation.Validator, guard Guard, agents AgentReader, schedules scheduleStorage.Storage) *RuleCenter {
 	return &RuleCenter{
 		storage:   rs,
+		index:     index,
+		composer:  composer,
 		validator: validator,
+		guard:     guard,
+		agents:    agents,
+		schedules: schedules,
+	}
+}
+
+func (r *RuleCenter) validate(ctx context.Context, name string, rule *model.Rule) error {
+	return validateWith(ctx, r.validator, name, rule)
+}
+
+// checkName refuses the rule set names the storage keys can not hold, a name
+// is the first segment of the keys of its rules
+func checkName(name string) error {
+	if name == "" || strings.Contains(name, "/") {
+		return errors.NewInputErrorf("invalid rule set name %q, a rule set name can not be empty or contain '/'", name)
 	}
+	return nil
+}
+
+// validateWith validates a rule with v, the error is an input error when the
+// rule is refused and a service error when v could not decide. Every rule
+// written by the rule center goes through it, so it checks the name first.
+func validateWith(ctx context.Context, v validation.Validator, name string, rule *model.Rule) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	err := v.Validate(ctx, name, rule)
+	if err == nil {
+		return nil
+	}
+
+	if stderrors.Is(err, validation.ErrUnavailable) {
+		return errors.NewServiceErrorf("validate rule failed: %v", err)
+	}
+	return errors.NewInputErrorf("valid rule failed: %v", err)
 }
 
 // AddRule adds a new rule.
 func (r *RuleCenter) AddRule(ctx context.Context, name string, rule *model.Rule) error {
-	if err := r.validator.Validate(ctx, rule); err != nil {
-		return errors.NewInputErrorf("valid rule failed: %v", err)
+	ctx = withActor(ctx)
+	if err := r.validate(ctx, name, rule); err != nil {
+		return err
 	}
 
-	err := r.storage.Add(ctx, name, rule)
+	rules, err := r.guard.Guard(ctx, rule)
 	if err != nil {
-		if err == ruleStorage.ErrRuleAlreadyExists {
-			return errors.NewInputError("rule already exists, please use another name")
-		}
+		return err
+	}
 
+	// 裁剪出的多条规则在同一个事务中写入, 不会只添加其中一部分
+	if err := r.storage.AddAll(ctx, name, rules); err != nil {
+		switch err {
+		case ruleStorage.ErrRuleAlreadyExists:
+			return errors.NewInputErrorf("rule %s already exists, please use another name", rule.RuleInfo.Cidr)
+		case ruleStorage.ErrTooManyRules:
+			return errors.NewInputErrorf("rule %s is clipped into %d rules, more than %d can be added at once", rule.RuleInfo.Cidr, len(rules), ruleStorage.BatchSize)
+		}
 		return errors.NewServiceErrorf("failed to add rule: %v", err)
 	}
 
@@ -43,6 +112,7 @@ func (r *RuleCenter) AddRule(ctx context.Context, name string, rule *model.Rule)
 
 // DeleteRule deletes a rule.
 func (r *RuleCenter) DeleteRule(ctx context.Context, name string, rule *model.Rule) error {
+	ctx = withActor(ctx)
 	//TODO: check Strategy is using the Rule, if is true, we should prevent deleting the Rule.
 	if err := r.storage.Delete(ctx, name, rule); err != nil {
 		return errors.NewServiceErrorf("failed to delete rule: %v", err)
@@ -52,8 +122,13 @@ func (r *RuleCenter) DeleteRule(ctx context.Context, name string, rule *model.Ru
 
 // UpdateRule updates a rule.
 func (r *RuleCenter) UpdateRule(ctx context.Context, name string, rule *model.Rule) error {
-	if err := r.validator.Validate(ctx, rule); err != nil {
-		return errors.NewInputErrorf("valid rule failed: %v", err)
+	ctx = withActor(ctx)
+	if err := r.validate(ctx, name, rule); err != nil {
+		return err
+	}
+
+	if err := r.guardInPlace(ctx, rule); err != nil {
+		return err
 	}
 
 	err := r.storage.Update(ctx, name, rule)
@@ -67,6 +142,48 @@ func (r *RuleCenter) UpdateRule(ctx context.Context, name string, rule *model.Ru
 	return nil
 }
 
+// guardInPlace guards a rule rewritten in place, it can not be clipped into
+// several rules
+func (r *RuleCenter) guardInPlace(ctx context.Context, rule *model.Rule) error {
+	rules, err := r.guard.Guard(ctx, rule)
+	if err != nil {
+		return err
+	}
+	if len(rules) != 1 || rules[0].RuleInfo.Cidr != rule.RuleInfo.Cidr {
+		return errors.NewInputErrorf("rule %s contains protected ranges, delete it and add it again to clip it", rule.RuleInfo.Cidr)
+	}
+	return nil
+}
+
+// ExtendRule makes a rule expire d from now, 0 makes it permanent. The rule
+// is moved to a new lease in place, agents never see it deleted.
+func (r *RuleCenter) ExtendRule(ctx context.Context, name string, info prule.RuleInfo, d time.Duration) (*model.Rule, error) {
+	ctx = withActor(ctx)
+	var expiresAt time.Time
+	info.Duration = prule.PermanentDuration
+	if d > 0 {
+		expiresAt = time.Now().Add(d)
+		info.Duration = d.String()
+	}
+
+	rule := &model.Rule{RuleInfo: info, RuleMeta: prule.RuleMeta{ExpiresAt: expiresAt}}
+	if err := r.validate(ctx, name, rule); err != nil {
+		return nil, err
+	}
+	if err := r.guardInPlace(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	extended, err := r.storage.Extend(ctx, name, info, expiresAt)
+	if err != nil {
+		if err == ruleStorage.ErrRuleNotFound {
+			return nil, errors.NewInputError("rule not found")
+		}
+		return nil, errors.NewServiceErrorf("failed to extend rule: %v", err)
+	}
+	return extended, nil
+}
+
 // GetRule gets a new rule.
 func (c *RuleCenter) GetRule(ctx context.Context, name string) (model.RuleItem, error) {
 	cfg, err := c.storage.GetRule(ctx, name)
@@ -0,0 +1,40 @@
+package rulecenter
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+	"testing"
+	"xdp-banner/orch/logic/rulecenter/bulk"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/errors"
+	prule "xdp-banner/pkg/rule"
+)
+
+func TestRuleSetName(t *testing.T) {
+	// 名称在访问存储之前被拒绝, 空的 RuleCenter 就够了
+	r := &RuleCenter{}
+	ctx := context.Background()
+	rule := &model.Rule{RuleInfo: prule.RuleInfo{Cidr: "10.0.0.0/24", Protocol: "TCP", Dport: 22}}
+
+	for _, name := range []string{"a/b", "/", ""} {
+		_, importErr := r.ImportRules(ctx, name, strings.NewReader(""), bulk.CSV, bulk.Defaults{}, false)
+		_, includeErr := r.SetIncludes(ctx, name, nil)
+		for _, err := range []error{
+			r.AddRule(ctx, name, rule),
+			r.UpdateRule(ctx, name, rule),
+			importErr,
+			includeErr,
+			r.AddScheduledRule(ctx, &model.ScheduledRule{Name: name, RuleInfo: rule.RuleInfo}),
+		} {
+			var appErr *errors.AppError
+			if !stderrors.As(err, &appErr) || appErr.Type != errors.InputError {
+				t.Fatalf("name %q: error = %v, want an input error", name, err)
+			}
+		}
+	}
+
+	if err := checkName("office"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,261 @@
+package rulecenter
+
+import (
+	"cmp"
+	"context"
+	stderrors "errors"
+	"slices"
+	"strings"
+	"time"
+	model "xdp-banner/orch/model/rule"
+	ruleStorage "xdp-banner/orch/storage/agent/rule"
+	scheduleStorage "xdp-banner/orch/storage/agent/schedule"
+	"xdp-banner/pkg/errors"
+	"xdp-banner/pkg/log"
+	prule "xdp-banner/pkg/rule"
+	"xdp-banner/pkg/schedule"
+)
+
+const (
+	// maxActivations is the default and the largest number of activations listed
+	maxActivations = 100
+	// defaultLookahead is how far ListActivations looks without a bound
+	defaultLookahead = 7 * 24 * time.Hour
+	// scheduleActor is the author of the changes of the scheduled rules in the
+	// history of the rule sets
+	scheduleActor = "schedule"
+)
+
+// parseSchedule parses the schedule of a scheduled rule
+func parseSchedule(s model.Schedule) (*schedule.Schedule, error) {
+	var d time.Duration
+	if s.Duration != "" {
+		var err error
+		if d, err = prule.ParseDuration(s.Duration); err != nil {
+			return nil, errors.NewInputErrorf("invalid schedule duration %q", s.Duration)
+		}
+	}
+	sched, err := schedule.New(s.Cron, d, s.Timezone, s.Start, s.End)
+	if err != nil {
+		return nil, errors.NewInputErrorf("invalid schedule: %v", err)
+	}
+	return sched, nil
+}
+
+// windowLength is how long a window of the schedule lasts, the duration the
+// rule is validated with
+func windowLength(s model.Schedule) string {
+	if s.Cron != "" {
+		return s.Duration
+	}
+	return s.End.Sub(s.Start).String()
+}
+
+// scheduledRule is the rule materialized for a window ending at end
+func scheduledRule(sr *model.ScheduledRule, now, end time.Time) *model.Rule {
+	info := sr.RuleInfo
+	info.Duration = windowLength(sr.Schedule)
+	return &model.Rule{
+		RuleInfo: info,
+		RuleMeta: prule.RuleMeta{
+			Comment:   info.Comment,
+			CreatedAt: now,
+			ExpiresAt: end,
+			// Init when needed
+			Identity:  "0",
+			Scheduled: true,
+		},
+	}
+}
+
+// AddScheduledRule schedules a rule, the leader adds it to the rule set at
+// the start of each window and it expires at the end
+func (r *RuleCenter) AddScheduledRule(ctx context.Context, sr *model.ScheduledRule) error {
+	if err := checkName(sr.Name); err != nil {
+		return err
+	}
+	sched, err := parseSchedule(sr.Schedule)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if _, ok := sched.Transition(now); !ok {
+		return errors.NewInputError("the schedule has no window left")
+	}
+
+	rule := scheduledRule(sr, now, time.Time{})
+	if err := r.validate(ctx, sr.Name, rule); err != nil {
+		return err
+	}
+	if err := r.guardInPlace(ctx, rule); err != nil {
+		return err
+	}
+
+	sr.RuleInfo.Duration = ""
+	sr.CreatedAt = now
+	if err := r.schedules.Add(ctx, sr); err != nil {
+		if err == scheduleStorage.ErrScheduleAlreadyExists {
+			return errors.NewInputErrorf("rule %s of rule set %s is already scheduled", sr.RuleInfo.Key(), sr.Name)
+		}
+		return errors.NewServiceErrorf("failed to add scheduled rule: %v", err)
+	}
+	return nil
+}
+
+// DeleteScheduledRule deletes a scheduled rule, the leader withdraws its rule
+// if it is active
+func (r *RuleCenter) DeleteScheduledRule(ctx context.Context, name, key string) error {
+	if err := r.schedules.Delete(ctx, name, key); err != nil {
+		if err == scheduleStorage.ErrScheduleNotFound {
+			return errors.NewInputError("scheduled rule not found")
+		}
+		return errors.NewServiceErrorf("failed to delete scheduled rule: %v", err)
+	}
+	return nil
+}
+
+// ListScheduledRules lists the scheduled rules of a rule set, of every rule
+// set when name is empty
+func (r *RuleCenter) ListScheduledRules(ctx context.Context, name string) ([]*model.ScheduledRule, error) {
+	rules, err := r.schedules.List(ctx, name)
+	if err != nil {
+		return nil, errors.NewServiceErrorf("failed to list scheduled rules: %v", err)
+	}
+	return rules, nil
+}
+
+// ListActivations lists the windows of the scheduled rules of a rule set, of
+// every rule set when name is empty, in progress or starting within the
+// given duration, by start
+func (r *RuleCenter) ListActivations(ctx context.Context, name string, within time.Duration, limit int) ([]model.Activation, error) {
+	if within <= 0 {
+		within = defaultLookahead
+	}
+	if limit <= 0 || limit > maxActivations {
+		limit = maxActivations
+	}
+
+	rules, err := r.ListScheduledRules(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return activations(rules, time.Now(), within, limit), nil
+}
+
+func activations(rules []*model.ScheduledRule, now time.Time, within time.Duration, limit int) []model.Activation {
+	until := now.Add(within)
+	result := []model.Activation{}
+	for _, sr := range rules {
+		sched, err := parseSchedule(sr.Schedule)
+		if err != nil {
+			continue
+		}
+		// 每条规则最多 limit 个窗口, 合并排序后再截断
+		for _, w := range sched.Windows(now, limit) {
+			if !w.Start.Before(until) {
+				break
+			}
+			result = append(result, model.Activation{
+				Name:     sr.Name,
+				RuleInfo: sr.RuleInfo,
+				Start:    w.Start,
+				End:      w.End,
+				Active:   w.Contains(now),
+			})
+		}
+	}
+
+	slices.SortFunc(result, func(a, b model.Activation) int {
+		return cmp.Or(
+			a.Start.Compare(b.Start),
+			strings.Compare(a.Name, b.Name),
+			strings.Compare(a.RuleInfo.Key(), b.RuleInfo.Key()),
+		)
+	})
+	return result[:min(limit, len(result))]
+}
+
+// ReconcileSchedule makes the rule of a scheduled rule exist in its rule set
+// while a window is active, expiring at the end of the window, and not
+// otherwise. sr is nil when the scheduled rule of key was deleted. It returns
+// the next time the rule changes, zero when it never does. A rule of the same
+// key not added by a schedule is left alone.
+func (r *RuleCenter) ReconcileSchedule(ctx context.Context, key string, sr *model.ScheduledRule, now time.Time) (time.Time, error) {
+	name, ruleKey, ok := scheduleStorage.ParseKey(key)
+	if !ok {
+		return time.Time{}, nil
+	}
+	info, ok := ruleStorage.ParseRuleKey(ruleKey)
+	if !ok {
+		return time.Time{}, nil
+	}
+	ctx = ruleStorage.WithActor(ctx, scheduleActor)
+
+	var window schedule.Window
+	var active bool
+	var next time.Time
+	if sr != nil {
+		sched, err := parseSchedule(sr.Schedule)
+		if err != nil {
+			log.Warn("invalid schedule, withdraw the scheduled rule", log.StringField("key", key), log.ErrorField(err))
+		} else {
+			window, active = sched.Active(now)
+			next, _ = sched.Transition(now)
+		}
+	}
+
+	current, err := r.storage.Get(ctx, name, info)
+	if err != nil && err != ruleStorage.ErrRuleNotFound {
+		return time.Time{}, err
+	}
+	if current != nil && !current.RuleMeta.Scheduled {
+		if active {
+			log.Warn("a rule not added by a schedule exists, skip the scheduled rule",
+				log.StringField("name", name), log.StringField("rule", ruleKey))
+		}
+		return next, nil
+	}
+
+	switch {
+	case !active && current != nil:
+		// 读取之后同一 key 可能被换成了不是定时添加的规则, 删除时再检查一次
+		key := info.Key()
+		removed, err := r.storage.DeleteRules(ctx, name, func(k string, rule model.Rule) bool {
+			return k == key && rule.RuleMeta.Scheduled
+		})
+		if err != nil {
+			return time.Time{}, err
+		}
+		if len(removed.Rules) > 0 {
+			log.Info("scheduled rule withdrawn", log.StringField("name", name), log.StringField("rule", ruleKey))
+		}
+	case active && current == nil:
+		rule := scheduledRule(sr, now, window.End)
+		err := r.validate(ctx, name, rule)
+		if err == nil {
+			err = r.guardInPlace(ctx, rule)
+		}
+		if err != nil {
+			if !isInputError(err) {
+				return time.Time{}, err
+			}
+			// 规则本身被拒绝, 重试无济于事, 等下一个窗口
+			log.Warn("scheduled rule refused", log.StringField("name", name), log.StringField("rule", ruleKey), log.ErrorField(err))
+			return next, nil
+		}
+		if err := r.storage.Add(ctx, name, rule); err != nil {
+			return time.Time{}, err
+		}
+		log.Info("scheduled rule materialized", log.StringField("name", name), log.StringField("rule", ruleKey))
+	case active && !current.RuleMeta.ExpiresAt.Equal(window.End):
+		if _, err := r.storage.Extend(ctx, name, info, window.End); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return next, nil
+}
+
+func isInputError(err error) bool {
+	var appErr *errors.AppError
+	return stderrors.As(err, &appErr) && appErr.Type == errors.InputError
+}
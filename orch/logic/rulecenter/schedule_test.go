@@ -0,0 +1,72 @@
+package rulecenter
+
+import (
+	"testing"
+	"time"
+	model "xdp-banner/orch/model/rule"
+	prule "xdp-banner/pkg/rule"
+)
+
+func TestActivations(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	rules := []*model.ScheduledRule{
+		{
+			Name:     "office",
+			RuleInfo: prule.RuleInfo{Cidr: "10.0.0.0/8", Protocol: "TCP", Dport: 22},
+			// 每天 9 点到 18 点, 正在生效
+			Schedule: model.Schedule{Cron: "0 9 * * *", Duration: "9h"},
+		},
+		{
+			Name:     "maintenance",
+			RuleInfo: prule.RuleInfo{Cidr: "192.0.2.0/24", Protocol: "UDP"},
+			Schedule: model.Schedule{Start: now.Add(2 * time.Hour), End: now.Add(3 * time.Hour)},
+		},
+		{
+			Name:     "broken",
+			RuleInfo: prule.RuleInfo{Cidr: "198.51.100.0/24", Protocol: "TCP"},
+			Schedule: model.Schedule{Cron: "0 9 * * *"},
+		},
+	}
+
+	got := activations(rules, now, 48*time.Hour, 10)
+	want := []struct {
+		name   string
+		start  time.Time
+		active bool
+	}{
+		{"office", now.Add(-3 * time.Hour), true},
+		{"maintenance", now.Add(2 * time.Hour), false},
+		{"office", now.Add(21 * time.Hour), false},
+		{"office", now.Add(45 * time.Hour), false},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("activations = %+v", got)
+	}
+	for i, w := range want {
+		if got[i].Name != w.name || !got[i].Start.Equal(w.start) || got[i].Active != w.active {
+			t.Errorf("activation %d = %+v, want %s at %s active %v", i, got[i], w.name, w.start, w.active)
+		}
+	}
+
+	if got := activations(rules, now, 48*time.Hour, 2); len(got) != 2 || got[1].Name != "maintenance" {
+		t.Errorf("limited activations = %+v", got)
+	}
+}
+
+func TestScheduledRule(t *testing.T) {
+	now := time.Now()
+	sr := &model.ScheduledRule{
+		Name:     "office",
+		RuleInfo: prule.RuleInfo{Cidr: "10.0.0.0/8", Protocol: "TCP", Comment: "after hours"},
+		Schedule: model.Schedule{Start: now, End: now.Add(90 * time.Minute)},
+	}
+
+	rule := scheduledRule(sr, now, now.Add(time.Hour))
+	if !rule.RuleMeta.Scheduled || !rule.RuleMeta.ExpiresAt.Equal(now.Add(time.Hour)) || rule.RuleMeta.Comment != "after hours" {
+		t.Errorf("scheduled rule meta = %+v", rule.RuleMeta)
+	}
+	// 校验按整个窗口的长度进行
+	if rule.RuleInfo.Duration != "1h30m0s" {
+		t.Errorf("scheduled rule duration = %q", rule.RuleInfo.Duration)
+	}
+}
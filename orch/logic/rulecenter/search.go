@@ -0,0 +1,81 @@
+package rulecenter
+
+import (
+	"context"
+	"slices"
+	"strings"
+	model "xdp-banner/orch/model/rule"
+	ruleStorage "xdp-banner/orch/storage/agent/rule"
+	"xdp-banner/pkg/cidr"
+	"xdp-banner/pkg/errors"
+)
+
+// maxSearchResults is the default and the largest limit of a search
+const maxSearchResults = 1000
+
+// Query selects the rules of SearchRules, the empty fields match any rule
+type Query struct {
+	// Cidr is an IP or a CIDR, it is required
+	Cidr     string
+	Protocol string
+	// Sport and Dport match the rules on the port or on any port
+	Sport uint16
+	Dport uint16
+	// Limit bounds the results, 0 is maxSearchResults
+	Limit int
+}
+
+func (q Query) match(r model.Rule) bool {
+	if q.Protocol != "" && !strings.EqualFold(q.Protocol, r.RuleInfo.Protocol) {
+		return false
+	}
+	if q.Sport != 0 && r.RuleInfo.Sport != 0 && q.Sport != r.RuleInfo.Sport {
+		return false
+	}
+	if q.Dport != 0 && r.RuleInfo.Dport != 0 && q.Dport != r.RuleInfo.Dport {
+		return false
+	}
+	return true
+}
+
+// SearchRules returns the rules of every rule set overlapping the CIDR of q,
+// sorted by rule set and key. The rules covering the CIDR are found first, so
+// they are kept when the results are truncated, which is reported.
+func (r *RuleCenter) SearchRules(ctx context.Context, q Query) ([]model.Match, bool, error) {
+	prefix, err := cidr.Parse(q.Cidr)
+	if err != nil {
+		return nil, false, errors.NewInputError(err.Error())
+	}
+	limit := q.Limit
+	if limit <= 0 || limit > maxSearchResults {
+		limit = maxSearchResults
+	}
+
+	var matches []model.Match
+	truncated := false
+	err = r.index.Search(prefix, func(m model.Match) bool {
+		if !q.match(m.Rule) {
+			return true
+		}
+		if len(matches) == limit {
+			truncated = true
+			return false
+		}
+		matches = append(matches, m)
+		return true
+	})
+	if err != nil {
+		if err == ruleStorage.ErrIndexNotSynced {
+			return nil, false, errors.NewServiceError(err.Error())
+		}
+		return nil, false, errors.NewServiceErrorf("failed to search rules: %v", err)
+	}
+
+	slices.SortFunc(matches, func(a, b model.Match) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Rule.RuleInfo.Key(), b.Rule.RuleInfo.Key())
+	})
+	return matches, truncated, nil
+}
@@ -0,0 +1,33 @@
+package rulecenter
+
+import (
+	"testing"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/rule"
+)
+
+func TestQueryMatch(t *testing.T) {
+	ssh := model.Rule{RuleInfo: rule.RuleInfo{Cidr: "10.1.0.0/16", Protocol: "TCP", Dport: 22}}
+	anyPort := model.Rule{RuleInfo: rule.RuleInfo{Cidr: "10.1.0.0/16", Protocol: "UDP"}}
+
+	tests := []struct {
+		q    Query
+		r    model.Rule
+		want bool
+	}{
+		{Query{}, ssh, true},
+		{Query{Protocol: "tcp"}, ssh, true},
+		{Query{Protocol: "UDP"}, ssh, false},
+		{Query{Dport: 22}, ssh, true},
+		{Query{Dport: 80}, ssh, false},
+		{Query{Sport: 1234}, ssh, true},
+		{Query{Dport: 80}, anyPort, true},
+		{Query{Protocol: "UDP", Dport: 53}, anyPort, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.q.match(tt.r); got != tt.want {
+			t.Errorf("%+v matches %s = %v, want %v", tt.q, tt.r.RuleInfo.Key(), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+	"xdp-banner/orch/model/rule"
+	prule "xdp-banner/pkg/rule"
+)
+
+// CIDRValidator requires the cidr to be valid and in its canonical form,
+// e.g. 10.0.0.0/24 instead of 10.0.0.1/24
+type CIDRValidator struct{}
+
+func (v CIDRValidator) Validate(_ context.Context, _ string, r *rule.Rule) error {
+	ip, ipNet, err := net.ParseCIDR(r.RuleInfo.Cidr)
+	if err != nil {
+		return fmt.Errorf("invalid cidr %q", r.RuleInfo.Cidr)
+	}
+
+	if !ip.Equal(ipNet.IP) || ipNet.String() != r.RuleInfo.Cidr {
+		return fmt.Errorf("cidr %q is not canonical, use %q", r.RuleInfo.Cidr, ipNet.String())
+	}
+
+	return nil
+}
+
+// protocols are the names the agents parse, the value tells whether ports apply
+var protocols = map[string]bool{
+	"TCP": true, "tcp": true,
+	"UDP": true, "udp": true,
+	"ICMP": false, "icmp": false,
+}
+
+// ProtocolValidator requires a protocol the agents understand, and no ports for ICMP
+type ProtocolValidator struct{}
+
+func (v ProtocolValidator) Validate(_ context.Context, _ string, r *rule.Rule) error {
+	ports, ok := protocols[r.RuleInfo.Protocol]
+	if !ok {
+		return fmt.Errorf("unknown protocol %q, use one of TCP, UDP and ICMP", r.RuleInfo.Protocol)
+	}
+
+	if !ports && (r.RuleInfo.Sport != 0 || r.RuleInfo.Dport != 0) {
+		return fmt.Errorf("%s rule %s can not set ports", r.RuleInfo.Protocol, r.RuleInfo.Cidr)
+	}
+
+	return nil
+}
+
+// DurationValidator bounds how long a rule lives, a zero bound is not checked
+type DurationValidator struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+func (v DurationValidator) Validate(_ context.Context, _ string, r *rule.Rule) error {
+	var d time.Duration
+	switch {
+	case r.RuleInfo.Duration != "":
+		var err error
+		if d, err = prule.ParseRuleDuration(r.RuleInfo.Duration); err != nil {
+			return fmt.Errorf("invalid duration %q", r.RuleInfo.Duration)
+		}
+	case !r.RuleMeta.ExpiresAt.IsZero():
+		d = time.Until(r.RuleMeta.ExpiresAt)
+	default:
+		return nil
+	}
+
+	if d == 0 {
+		// 永久规则
+		if v.Max > 0 {
+			return fmt.Errorf("permanent rules are not allowed, the max duration is %s", v.Max)
+		}
+		return nil
+	}
+
+	if v.Min > 0 && d < v.Min {
+		return fmt.Errorf("duration %s is shorter than %s", d, v.Min)
+	}
+	if v.Max > 0 && d > v.Max {
+		return fmt.Errorf("duration %s is longer than %s", d, v.Max)
+	}
+
+	return nil
+}
+
+// RuleSetReader reads the rules of a rule set, the rule storage implements it
+type RuleSetReader interface {
+	GetRuleKeys(ctx context.Context, name string) (map[string]rule.Rule, error)
+}
+
+// MaxRulesValidator limits the number of rules in a rule set, updating an
+// existing rule is always allowed
+type MaxRulesValidator struct {
+	Max   int
+	Rules RuleSetReader
+}
+
+func (v MaxRulesValidator) Validate(ctx context.Context, name string, r *rule.Rule) error {
+	rules, err := v.Rules.GetRuleKeys(ctx, name)
+	if err != nil {
+		return fmt.Errorf("%w: count rules of %s: %v", ErrUnavailable, name, err)
+	}
+
+	if len(rules) < v.Max {
+		return nil
+	}
+
+	key := r.RuleInfo.Key()
+	for _, existing := range rules {
+		if existing.RuleInfo.Key() == key {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("rule set %s already has %d rules, the limit is %d", name, len(rules), v.Max)
+}
+
+// Snapshot reads the rule keys of the rule set once, the snapshot counts the
+// new rules it accepts as added
+func (v MaxRulesValidator) Snapshot(ctx context.Context, name string) (Validator, error) {
+	rules, err := v.Rules.GetRuleKeys(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: count rules of %s: %v", ErrUnavailable, name, err)
+	}
+
+	keys := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		keys[r.RuleInfo.Key()] = true
+	}
+	return &maxRulesSnapshot{max: v.Max, keys: keys}, nil
+}
+
+// maxRulesSnapshot is MaxRulesValidator on the rule keys read by Snapshot
+type maxRulesSnapshot struct {
+	max  int
+	keys map[string]bool
+}
+
+func (v *maxRulesSnapshot) Validate(_ context.Context, name string, r *rule.Rule) error {
+	key := r.RuleInfo.Key()
+	if v.keys[key] {
+		return nil
+	}
+	if len(v.keys) >= v.max {
+		return fmt.Errorf("rule set %s already has %d rules, the limit is %d", name, len(v.keys), v.max)
+	}
+
+	v.keys[key] = true
+	return nil
+}
@@ -1 +1,100 @@
 package validation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+	"xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/log"
+)
+
+const (
+	defaultExternalTimeout = 5 * time.Second
+	// maxReasonLen 限制外部 validator 返回给用户的错误信息长度
+	maxReasonLen = 512
+)
+
+// Request is the json the external validators receive, on stdin for the
+// commands and as the body of a POST for the webhooks
+type Request struct {
+	Name string     `json:"name"`
+	Rule *rule.Rule `json:"rule"`
+}
+
+// CommandValidator runs an external command for every rule. The rule is
+// written to stdin as a Request, exit status 0 accepts it, any other status
+// rejects it with the output of the command as the reason.
+type CommandValidator struct {
+	Path    string
+	Args    []string
+	Timeout time.Duration
+	// FailOpen accepts the rule when the command can not be run or times out
+	FailOpen bool
+}
+
+func (v CommandValidator) Validate(ctx context.Context, name string, r *rule.Rule) error {
+	body, err := json.Marshal(Request{Name: name, Rule: r})
+	if err != nil {
+		return fmt.Errorf("marshal validation request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeoutOrDefault(v.Timeout))
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, v.Path, v.Args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err == nil {
+		return nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		reason := firstNonEmpty(stderr.String(), stdout.String(), exitErr.Error())
+		return fmt.Errorf("rejected by %s: %s", v.Path, truncate(reason))
+	}
+
+	return unavailable(v.FailOpen, fmt.Sprintf("command %s", v.Path), err)
+}
+
+// unavailable reports a validator which could not decide, nil when it fails open
+func unavailable(failOpen bool, validator string, err error) error {
+	if failOpen {
+		log.Warn("external validator unavailable, accepting the rule", log.StringField("validator", validator), log.ErrorField(err))
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s: %v", ErrUnavailable, validator, err)
+}
+
+func timeoutOrDefault(d time.Duration) time.Duration {
+	if d <= 0 {
+		return defaultExternalTimeout
+	}
+	return d
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func truncate(s string) string {
+	if len(s) <= maxReasonLen {
+		return s
+	}
+	return s[:maxReasonLen] + "..."
+}
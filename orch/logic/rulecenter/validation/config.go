@@ -0,0 +1,97 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Config selects the validators of the rule center, it is the validation
+// section of the orch config
+type Config struct {
+	CIDR     bool `mapstructure:"cidr"`
+	Protocol bool `mapstructure:"protocol"`
+	// MinDuration and MaxDuration bound the rule duration, zero is unbounded
+	MinDuration time.Duration `mapstructure:"minDuration"`
+	MaxDuration time.Duration `mapstructure:"maxDuration"`
+	// MaxRulesPerSet limits the rules of a rule set, zero is unlimited
+	MaxRulesPerSet int `mapstructure:"maxRulesPerSet"`
+
+	Commands []CommandConfig `mapstructure:"commands"`
+	Webhooks []WebhookConfig `mapstructure:"webhooks"`
+}
+
+type CommandConfig struct {
+	Path     string        `mapstructure:"path"`
+	Args     []string      `mapstructure:"args"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+	FailOpen bool          `mapstructure:"failOpen"`
+}
+
+type WebhookConfig struct {
+	URL      string            `mapstructure:"url"`
+	Header   map[string]string `mapstructure:"header"`
+	Timeout  time.Duration     `mapstructure:"timeout"`
+	FailOpen bool              `mapstructure:"failOpen"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		CIDR:        true,
+		Protocol:    true,
+		MinDuration: time.Second,
+	}
+}
+
+func (c *Config) Check() error {
+	if c.MinDuration < 0 || c.MaxDuration < 0 {
+		return fmt.Errorf("validation durations can not be negative")
+	}
+	if c.MaxDuration > 0 && c.MinDuration > c.MaxDuration {
+		return fmt.Errorf("validation minDuration %s is longer than maxDuration %s", c.MinDuration, c.MaxDuration)
+	}
+	if c.MaxRulesPerSet < 0 {
+		return fmt.Errorf("validation maxRulesPerSet can not be negative")
+	}
+
+	for _, cmd := range c.Commands {
+		if cmd.Path == "" {
+			return fmt.Errorf("validation command path is empty")
+		}
+	}
+	for _, hook := range c.Webhooks {
+		u, err := url.Parse(hook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid validation webhook url %q", hook.URL)
+		}
+	}
+
+	return nil
+}
+
+// New builds the validator chain of the config, the builtin validators run
+// before the external ones
+func New(c Config, rules RuleSetReader) Chain {
+	var chain Chain
+	if c.CIDR {
+		chain = append(chain, CIDRValidator{})
+	}
+	if c.Protocol {
+		chain = append(chain, ProtocolValidator{})
+	}
+	if c.MinDuration > 0 || c.MaxDuration > 0 {
+		chain = append(chain, DurationValidator{Min: c.MinDuration, Max: c.MaxDuration})
+	}
+	if c.MaxRulesPerSet > 0 {
+		chain = append(chain, MaxRulesValidator{Max: c.MaxRulesPerSet, Rules: rules})
+	}
+
+	for _, cmd := range c.Commands {
+		chain = append(chain, CommandValidator{Path: cmd.Path, Args: cmd.Args, Timeout: cmd.Timeout, FailOpen: cmd.FailOpen})
+	}
+	for _, hook := range c.Webhooks {
+		chain = append(chain, WebhookValidator{URL: hook.URL, Header: hook.Header, Timeout: hook.Timeout, FailOpen: hook.FailOpen})
+	}
+
+	return chain
+}
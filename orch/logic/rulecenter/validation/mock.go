@@ -8,6 +8,6 @@ import (
 type MockValidator struct {
 }
 
-func (v *MockValidator) Validate(ctx context.Context, c *rule.Rule) error {
+func (v *MockValidator) Validate(ctx context.Context, name string, c *rule.Rule) error {
 	return nil
 }
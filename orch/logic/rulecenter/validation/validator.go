@@ -2,11 +2,58 @@ package validation
 
 import (
 	"context"
+	"errors"
 	"xdp-banner/orch/model/rule"
 )
 
+// ErrUnavailable is returned when a validator could not decide, e.g. an
+// external validator timed out. The rule is not invalid, the check failed.
+var ErrUnavailable = errors.New("validator unavailable")
+
 // Validator validates the configuration.
 type Validator interface {
-	// Validate validates the configuration.
-	Validate(ctx context.Context, c *rule.Rule) error
+	// Validate validates the rule before it is added to the rule set name.
+	Validate(ctx context.Context, name string, c *rule.Rule) error
+}
+
+// Snapshotter is a validator reading the rule set, Snapshot returns a validator
+// checking the many rules of an import against the rule set read once
+type Snapshotter interface {
+	Snapshot(ctx context.Context, name string) (Validator, error)
+}
+
+// Snapshot returns v with its validators reading the rule set name replaced
+// by their snapshots, see Snapshotter
+func Snapshot(ctx context.Context, v Validator, name string) (Validator, error) {
+	if s, ok := v.(Snapshotter); ok {
+		return s.Snapshot(ctx, name)
+	}
+	return v, nil
+}
+
+// Chain runs every validator and reports all the failures together
+type Chain []Validator
+
+func (c Chain) Validate(ctx context.Context, name string, r *rule.Rule) error {
+	var errs []error
+	for _, v := range c {
+		if err := v.Validate(ctx, name, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c Chain) Snapshot(ctx context.Context, name string) (Validator, error) {
+	snapshot := make(Chain, 0, len(c))
+	for _, v := range c {
+		v, err := Snapshot(ctx, v, name)
+		if err != nil {
+			return nil, err
+		}
+		snapshot = append(snapshot, v)
+	}
+
+	return snapshot, nil
 }
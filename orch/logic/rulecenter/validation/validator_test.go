@@ -0,0 +1,177 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"xdp-banner/orch/model/rule"
+	prule "xdp-banner/pkg/rule"
+)
+
+func newRule(cidr, protocol string, sport, dport uint16, duration string) *rule.Rule {
+	return &rule.Rule{RuleInfo: prule.RuleInfo{
+		Cidr:     cidr,
+		Protocol: protocol,
+		Sport:    sport,
+		Dport:    dport,
+		Duration: duration,
+	}}
+}
+
+func TestBuiltinValidators(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator Validator
+		rule      *rule.Rule
+		wantErr   string
+	}{
+		{"cidr ok", CIDRValidator{}, newRule("10.0.0.0/24", "TCP", 0, 22, ""), ""},
+		{"cidr v6 ok", CIDRValidator{}, newRule("2001:db8::/32", "TCP", 0, 22, ""), ""},
+		{"cidr host bits", CIDRValidator{}, newRule("10.0.0.1/24", "TCP", 0, 22, ""), `use "10.0.0.0/24"`},
+		{"cidr not compressed", CIDRValidator{}, newRule("2001:db8:0:0::/64", "TCP", 0, 22, ""), `use "2001:db8::/64"`},
+		{"cidr bare ip", CIDRValidator{}, newRule("10.0.0.1", "TCP", 0, 22, ""), "invalid cidr"},
+		{"tcp ports", ProtocolValidator{}, newRule("10.0.0.0/24", "tcp", 1024, 22, ""), ""},
+		{"icmp", ProtocolValidator{}, newRule("10.0.0.0/24", "ICMP", 0, 0, ""), ""},
+		{"icmp ports", ProtocolValidator{}, newRule("10.0.0.0/24", "ICMP", 0, 22, ""), "can not set ports"},
+		{"unknown protocol", ProtocolValidator{}, newRule("10.0.0.0/24", "Tcp", 0, 22, ""), "unknown protocol"},
+		{"duration ok", DurationValidator{Min: time.Second, Max: time.Hour}, newRule("10.0.0.0/24", "TCP", 0, 22, "30m"), ""},
+		{"duration days", DurationValidator{Max: 48 * time.Hour}, newRule("10.0.0.0/24", "TCP", 0, 22, "3d"), "longer than"},
+		{"duration short", DurationValidator{Min: time.Minute}, newRule("10.0.0.0/24", "TCP", 0, 22, "5s"), "shorter than"},
+		{"duration invalid", DurationValidator{Min: time.Minute}, newRule("10.0.0.0/24", "TCP", 0, 22, "soon"), "invalid duration"},
+		{"permanent", DurationValidator{Min: time.Minute}, newRule("10.0.0.0/24", "TCP", 0, 22, "permanent"), ""},
+		{"permanent bounded", DurationValidator{Max: time.Hour}, newRule("10.0.0.0/24", "TCP", 0, 22, "0"), "permanent rules are not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validator.Validate(context.Background(), "set", tt.rule)
+			checkErr(t, err, tt.wantErr)
+		})
+	}
+}
+
+type fakeRuleSet map[string]rule.Rule
+
+func (f fakeRuleSet) GetRuleKeys(context.Context, string) (map[string]rule.Rule, error) {
+	return f, nil
+}
+
+func TestMaxRulesValidator(t *testing.T) {
+	existing := newRule("10.0.0.0/24", "TCP", 0, 22, "")
+	v := MaxRulesValidator{Max: 1, Rules: fakeRuleSet{"/rule/set/10.0.0.0/24/TCP/0-22/": *existing}}
+
+	// 更新已有的规则不受限制
+	checkErr(t, v.Validate(context.Background(), "set", newRule("10.0.0.0/24", "TCP", 0, 22, "1h")), "")
+	checkErr(t, v.Validate(context.Background(), "set", newRule("10.0.1.0/24", "TCP", 0, 22, "")), "limit is 1")
+}
+
+func TestMaxRulesSnapshot(t *testing.T) {
+	existing := newRule("10.0.0.0/24", "TCP", 0, 22, "")
+	chain := Chain{ProtocolValidator{}, MaxRulesValidator{Max: 2, Rules: fakeRuleSet{"/rule/set/10.0.0.0/24/TCP/0-22/": *existing}}}
+	v, err := Snapshot(context.Background(), chain, "set")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 快照在内存中计入已接受的新规则
+	checkErr(t, v.Validate(context.Background(), "set", newRule("10.0.0.0/24", "TCP", 0, 22, "1h")), "")
+	checkErr(t, v.Validate(context.Background(), "set", newRule("10.0.1.0/24", "TCP", 0, 22, "")), "")
+	checkErr(t, v.Validate(context.Background(), "set", newRule("10.0.1.0/24", "TCP", 0, 22, "")), "")
+	checkErr(t, v.Validate(context.Background(), "set", newRule("10.0.2.0/24", "TCP", 0, 22, "")), "limit is 2")
+}
+
+func TestChainJoinsErrors(t *testing.T) {
+	chain := New(DefaultConfig(), nil)
+	err := chain.Validate(context.Background(), "set", newRule("10.0.0.1/24", "ICMP", 0, 22, ""))
+	checkErr(t, err, "not canonical")
+	checkErr(t, err, "can not set ports")
+}
+
+func TestCommandValidator(t *testing.T) {
+	script := `read -r body; case "$body" in *'"cidr":"10.0.0.0/8"'*) echo "too wide" >&2; exit 1;; esac`
+	v := CommandValidator{Path: "sh", Args: []string{"-c", script}}
+
+	checkErr(t, v.Validate(context.Background(), "set", newRule("10.0.0.0/24", "TCP", 0, 22, "")), "")
+	checkErr(t, v.Validate(context.Background(), "set", newRule("10.0.0.0/8", "TCP", 0, 22, "")), "too wide")
+
+	missing := CommandValidator{Path: "/nonexistent/validator"}
+	if err := missing.Validate(context.Background(), "set", newRule("10.0.0.0/24", "TCP", 0, 22, "")); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("error = %v, want %v", err, ErrUnavailable)
+	}
+
+	missing.FailOpen = true
+	checkErr(t, missing.Validate(context.Background(), "set", newRule("10.0.0.0/24", "TCP", 0, 22, "")), "")
+}
+
+func TestWebhookValidator(t *testing.T) {
+	allowed := func(b bool) *bool { return &b }
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch req.Rule.RuleInfo.Cidr {
+		case "10.0.0.0/8":
+			_ = json.NewEncoder(w).Encode(Response{Allowed: allowed(false), Reason: "too wide for " + req.Name})
+		case "10.1.0.0/16":
+			// 空响应不表示接受
+		case "10.2.0.0/16":
+			_, _ = w.Write([]byte("{}"))
+		default:
+			_ = json.NewEncoder(w).Encode(Response{Allowed: allowed(true)})
+		}
+	}))
+	defer srv.Close()
+
+	v := WebhookValidator{URL: srv.URL, Header: map[string]string{"X-Token": "secret"}}
+	checkErr(t, v.Validate(context.Background(), "set", newRule("10.0.0.0/24", "TCP", 0, 22, "")), "")
+	checkErr(t, v.Validate(context.Background(), "set", newRule("10.0.0.0/8", "TCP", 0, 22, "")), "too wide for set")
+	for _, cidr := range []string{"10.1.0.0/16", "10.2.0.0/16"} {
+		if err := v.Validate(context.Background(), "set", newRule(cidr, "TCP", 0, 22, "")); !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("%s: error = %v, want %v", cidr, err, ErrUnavailable)
+		}
+	}
+	failOpen := v
+	failOpen.FailOpen = true
+	checkErr(t, failOpen.Validate(context.Background(), "set", newRule("10.1.0.0/16", "TCP", 0, 22, "")), "")
+
+	v.Header = nil
+	if err := v.Validate(context.Background(), "set", newRule("10.0.0.0/24", "TCP", 0, 22, "")); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("error = %v, want %v", err, ErrUnavailable)
+	}
+}
+
+func TestConfigCheck(t *testing.T) {
+	c := DefaultConfig()
+	c.Webhooks = []WebhookConfig{{URL: "ftp://example.com"}}
+	checkErr(t, c.Check(), "invalid validation webhook url")
+
+	c = DefaultConfig()
+	c.MinDuration, c.MaxDuration = time.Hour, time.Minute
+	checkErr(t, c.Check(), "longer than maxDuration")
+}
+
+func checkErr(t *testing.T, err error, want string) {
+	t.Helper()
+
+	if want == "" {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("error = %v, want it to contain %q", err, want)
+	}
+}
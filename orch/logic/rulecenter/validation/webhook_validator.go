@@ -0,0 +1,85 @@
+package validation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"xdp-banner/orch/model/rule"
+)
+
+// Response is the json answer of a webhook, Allowed is nil when the answer
+// does not have it and the rule is not decided
+type Response struct {
+	Allowed *bool  `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// WebhookValidator POSTs every rule as a Request to an http endpoint. Only a
+// 2xx status with {"allowed": true} accepts the rule, any other answer the
+// rule can not be decided with, an empty body included, is handled as the
+// webhook being unavailable.
+type WebhookValidator struct {
+	URL     string
+	Header  map[string]string
+	Timeout time.Duration
+	// FailOpen accepts the rule when the webhook can not be reached or does not answer 2xx
+	FailOpen bool
+
+	Client *http.Client
+}
+
+func (v WebhookValidator) Validate(ctx context.Context, name string, r *rule.Rule) error {
+	body, err := json.Marshal(Request{Name: name, Rule: r})
+	if err != nil {
+		return fmt.Errorf("marshal validation request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeoutOrDefault(v.Timeout))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create validation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, val := range v.Header {
+		req.Header.Set(k, val)
+	}
+
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return unavailable(v.FailOpen, "webhook "+v.URL, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return unavailable(v.FailOpen, "webhook "+v.URL, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return unavailable(v.FailOpen, "webhook "+v.URL, fmt.Errorf("status %s: %s", resp.Status, truncate(string(bytes.TrimSpace(data)))))
+	}
+
+	// allowed 必须显式给出, 空响应或缺少该字段不能视为接受
+	var answer Response
+	if err := json.Unmarshal(data, &answer); err != nil {
+		return unavailable(v.FailOpen, "webhook "+v.URL, fmt.Errorf("decode response: %w", err))
+	}
+	if answer.Allowed == nil {
+		return unavailable(v.FailOpen, "webhook "+v.URL, fmt.Errorf("decode response: allowed is missing"))
+	}
+	if !*answer.Allowed {
+		return fmt.Errorf("rejected by %s: %s", v.URL, truncate(firstNonEmpty(answer.Reason, "no reason given")))
+	}
+
+	return nil
+}
@@ -58,6 +58,11 @@ func (a *Applied) Create(ctx context.Context, strategyName string) error {
 		}
 	}
 
+	capabilities, err := parseCapabilitySelector(strategy.CapabilitySelector)
+	if err != nil {
+		return fmt.Errorf("failed to parse capability selector %s: %w", strategy.CapabilitySelector, err)
+	}
+
 	options := etcd.ListOption{
 		Size: 20,
 	}
@@ -81,6 +86,16 @@ func (a *Applied) Create(ctx context.Context, strategyName string) error {
 
 	selectedNode := make([]string, 0, 5)
 	for _, agent := range agents {
+		if !capabilities.Matches(agent.Capabilities) {
+			continue
+		}
+
+		// 只有 capability selector 时按能力选择所有 agent
+		if nameReg == nil && labelsReg == nil {
+			selectedNode = append(selectedNode, agent.Name)
+			continue
+		}
+
 		if nameReg != nil && nameReg.MatchString(agent.Name) {
 			selectedNode = append(selectedNode, agent.Name)
 			continue
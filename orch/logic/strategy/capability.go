@@ -0,0 +1,211 @@
+package strategy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	nodem "xdp-banner/orch/model/node"
+)
+
+// capability selector 语法: 逗号分隔的条件, 全部满足才算选中
+//
+//	kernel>=5.10          内核版本, 按数字逐段比较, 支持 = != > >= < <=
+//	cpus>=8               cpu 数量, 比较同上
+//	arch=amd64            架构, 只支持 = 和 !=
+//	driver=mlx5_core      存在使用该驱动的网卡, != 表示不存在
+//	feature=prog_type/xdp 内核支持该 bpf 特性, != 表示不支持
+//	btf, xdp_native       布尔条件, 前缀 ! 取反. xdp_native 表示至少一个网卡支持原生 XDP
+//
+// 没有上报 capabilities 的 agent 不会被非空的 selector 选中
+type capabilitySelector []capabilityRequirement
+
+type capabilityRequirement struct {
+	key   string
+	op    string
+	value string
+}
+
+// operators ordered so that the two-character ones are tried first
+var capabilityOps = []string{">=", "<=", "!=", ">", "<", "="}
+
+func parseCapabilitySelector(s string) (capabilitySelector, error) {
+	var selector capabilitySelector
+	for _, raw := range strings.Split(s, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		r, err := parseCapabilityRequirement(raw)
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, r)
+	}
+
+	return selector, nil
+}
+
+func parseCapabilityRequirement(raw string) (capabilityRequirement, error) {
+	for _, op := range capabilityOps {
+		key, value, ok := strings.Cut(raw, op)
+		if !ok {
+			continue
+		}
+
+		r := capabilityRequirement{key: strings.TrimSpace(key), op: op, value: strings.TrimSpace(value)}
+		if r.value == "" {
+			return r, fmt.Errorf("missing value in %q", raw)
+		}
+
+		switch r.key {
+		case "kernel":
+			if _, err := parseVersion(r.value); err != nil {
+				return r, fmt.Errorf("invalid kernel version in %q: %w", raw, err)
+			}
+		case "cpus":
+			if _, err := strconv.Atoi(r.value); err != nil {
+				return r, fmt.Errorf("invalid cpu count in %q", raw)
+			}
+		case "arch", "driver", "feature":
+			if op != "=" && op != "!=" {
+				return r, fmt.Errorf("%s only supports = and !=, got %q", r.key, raw)
+			}
+		default:
+			return r, fmt.Errorf("unknown capability %q", r.key)
+		}
+
+		return r, nil
+	}
+
+	// boolean requirement
+	key, negate := strings.CutPrefix(raw, "!")
+	switch key = strings.TrimSpace(key); key {
+	case "btf", "xdp_native":
+	default:
+		return capabilityRequirement{}, fmt.Errorf("unknown capability %q", key)
+	}
+
+	op := "="
+	if negate {
+		op = "!="
+	}
+	return capabilityRequirement{key: key, op: op, value: "true"}, nil
+}
+
+// Matches reports whether the capabilities satisfy all the requirements
+func (s capabilitySelector) Matches(c *nodem.HostCapabilities) bool {
+	if len(s) == 0 {
+		return true
+	}
+	if c == nil {
+		return false
+	}
+
+	for _, r := range s {
+		if !r.matches(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r capabilityRequirement) matches(c *nodem.HostCapabilities) bool {
+	switch r.key {
+	case "kernel":
+		have, err := parseVersion(c.KernelVersion)
+		if err != nil {
+			return false
+		}
+		want, _ := parseVersion(r.value)
+		return compare(r.op, compareVersion(have, want))
+	case "cpus":
+		want, _ := strconv.Atoi(r.value)
+		return compare(r.op, c.CPUCount-want)
+	case "arch":
+		return (c.Arch == r.value) == (r.op == "=")
+	case "driver":
+		found := false
+		for _, nic := range c.NICs {
+			if nic.Driver == r.value {
+				found = true
+				break
+			}
+		}
+		return found == (r.op == "=")
+	case "feature":
+		return c.BPFFeatures[r.value] == (r.op == "=")
+	case "btf":
+		return c.BTF == (r.op == "=")
+	case "xdp_native":
+		native := false
+		for _, nic := range c.NICs {
+			if nic.XDPNative {
+				native = true
+				break
+			}
+		}
+		return native == (r.op == "=")
+	}
+
+	return false
+}
+
+func compare(op string, cmp int) bool {
+	switch op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// parseVersion parses the leading numeric part of a kernel release,
+// "5.15.0-91-generic" gives [5 15 0]
+func parseVersion(v string) ([]int, error) {
+	if i := strings.IndexFunc(v, func(r rune) bool { return r != '.' && (r < '0' || r > '9') }); i >= 0 {
+		v = v[:i]
+	}
+	v = strings.TrimSuffix(v, ".")
+	if v == "" {
+		return nil, fmt.Errorf("no version number")
+	}
+
+	parts := strings.Split(v, ".")
+	version := make([]int, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, err
+		}
+		version = append(version, n)
+	}
+	return version, nil
+}
+
+// compareVersion compares the versions segment by segment, missing segments are 0
+func compareVersion(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
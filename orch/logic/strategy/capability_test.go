@@ -0,0 +1,64 @@
+package strategy
+
+import (
+	"testing"
+
+	nodem "xdp-banner/orch/model/node"
+)
+
+func TestCapabilitySelector(t *testing.T) {
+	caps := &nodem.HostCapabilities{
+		KernelVersion: "5.15.0-91-generic",
+		Arch:          "amd64",
+		CPUCount:      16,
+		BTF:           true,
+		BPFFeatures:   map[string]bool{"prog_type/xdp": true, "bounded_loops": false},
+		NICs: []nodem.NicCapability{
+			{Name: "eth0", Driver: "mlx5_core", XDPNative: true},
+			{Name: "eth1", Driver: "e1000"},
+		},
+	}
+
+	cases := []struct {
+		selector string
+		match    bool
+	}{
+		{"", true},
+		{"kernel>=5.10", true},
+		{"kernel>=5.15.1", false},
+		{"kernel<6", true},
+		{"kernel=5.15", true},
+		{"cpus>8, arch=amd64", true},
+		{"cpus<=8", false},
+		{"arch!=amd64", false},
+		{"btf,xdp_native", true},
+		{"!btf", false},
+		{"driver=mlx5_core", true},
+		{"driver!=e1000", false},
+		{"driver=ixgbe", false},
+		{"feature=prog_type/xdp", true},
+		{"feature=bounded_loops", false},
+		{"feature!=map_type/lpm_trie", true},
+	}
+	for _, c := range cases {
+		s, err := parseCapabilitySelector(c.selector)
+		if err != nil {
+			t.Fatalf("parse %q: %v", c.selector, err)
+		}
+		if got := s.Matches(caps); got != c.match {
+			t.Errorf("%q: expected %v, got %v", c.selector, c.match, got)
+		}
+	}
+
+	// agents which never reported capabilities only match the empty selector
+	s, _ := parseCapabilitySelector("btf")
+	if s.Matches(nil) {
+		t.Error("nil capabilities should not match")
+	}
+
+	for _, invalid := range []string{"kernel>=abc", "cpus=many", "arch>amd64", "memory>1", "gpu", "driver="} {
+		if _, err := parseCapabilitySelector(invalid); err == nil {
+			t.Errorf("%q should be rejected", invalid)
+		}
+	}
+}
@@ -99,8 +99,8 @@ func validateStrategy(new *strategym.Strategy) error {
 		return errors.NewInputErrorf("invalid strategy action: %s", new.Action)
 	}
 
-	if new.NameSelector == "" && new.LabelSelector == "" {
-		return errors.NewInputError("strategy name selector and label selector are both empty")
+	if new.NameSelector == "" && new.LabelSelector == "" && new.CapabilitySelector == "" {
+		return errors.NewInputError("strategy name selector, label selector and capability selector are all empty")
 	}
 
 	// validate strategy name selector
@@ -119,5 +119,10 @@ func validateStrategy(new *strategym.Strategy) error {
 		}
 	}
 
+	// validate strategy capability selector
+	if _, err := parseCapabilitySelector(new.CapabilitySelector); err != nil {
+		return errors.NewInputErrorf("strategy capability selector is invalid: %v", err)
+	}
+
 	return nil
 }
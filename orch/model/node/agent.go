@@ -31,9 +31,10 @@ type AgentList struct {
 }
 
 type AgentInfo struct {
-	CommonInfo `json:",inline"`
-	Enable     bool   `json:"enable"`
-	Config     string `json:"config"`
+	CommonInfo   `json:",inline"`
+	Enable       bool              `json:"enable"`
+	Config       string            `json:"config"`
+	Capabilities *HostCapabilities `json:"capabilities,omitempty"`
 }
 
 func (i *AgentInfo) Marshal() []byte {
@@ -66,11 +67,46 @@ type ErrorTime struct {
 
 type AgentStatus struct {
 	CommonStatus `json:",inline"`
-	GrpcEndpoint string     `json:"grpc_endpoint"`
-	HttpEndpoint string     `json:"http_endpoint"`
-	Config       string     `json:"config"`
-	Phase        string     `json:"phase"`
-	Error        *ErrorTime `json:"error"`
+	GrpcEndpoint string            `json:"grpc_endpoint"`
+	HttpEndpoint string            `json:"http_endpoint"`
+	Config       string            `json:"config"`
+	Phase        string            `json:"phase"`
+	Error        *ErrorTime        `json:"error"`
+	Conditions   []Condition       `json:"conditions,omitempty"`
+	Interfaces   []InterfaceHealth `json:"interfaces,omitempty"`
+	Maps         []MapUsage        `json:"maps,omitempty"`
+}
+
+// Condition is one aspect of the agent health reported by the agent
+type Condition struct {
+	Type               string    `json:"type"`
+	Status             bool      `json:"status"`
+	Reason             string    `json:"reason,omitempty"`
+	Message            string    `json:"message,omitempty"`
+	LastTransitionTime time.Time `json:"last_transition_time"`
+}
+
+type InterfaceHealth struct {
+	Name     string `json:"name"`
+	Attached bool   `json:"attached"`
+	Up       bool   `json:"up"`
+	Error    string `json:"error,omitempty"`
+}
+
+type MapUsage struct {
+	Name       string `json:"name"`
+	Entries    uint32 `json:"entries"`
+	MaxEntries uint32 `json:"max_entries"`
+}
+
+// Condition returns the condition of the given type, nil if not reported
+func (s *AgentStatus) Condition(conditionType string) *Condition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == conditionType {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
 }
 
 func (s *AgentStatus) Marshal() []byte {
@@ -0,0 +1,47 @@
+package node
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// HostCapabilities is what the agent probed on its host
+type HostCapabilities struct {
+	KernelVersion string          `json:"kernel_version"`
+	Arch          string          `json:"arch"`
+	CPUCount      int             `json:"cpu_count"`
+	BTF           bool            `json:"btf"`
+	BPFFeatures   map[string]bool `json:"bpf_features,omitempty"`
+	NICs          []NicCapability `json:"nics,omitempty"`
+	ProbedAt      time.Time       `json:"probed_at"`
+}
+
+type NicCapability struct {
+	Name      string `json:"name"`
+	Driver    string `json:"driver"`
+	RxQueues  uint32 `json:"rx_queues"`
+	TxQueues  uint32 `json:"tx_queues"`
+	XDPNative bool   `json:"xdp_native"`
+	// Up is the link state at the probe, the current one is reported in
+	// the interfaces of the agent status
+	Up bool `json:"up"`
+}
+
+// Equal reports whether both describe the same host. Only the static fields
+// are compared, ProbedAt, the link state and the queue counts of the NICs
+// change without the host changing.
+func (c *HostCapabilities) Equal(o *HostCapabilities) bool {
+	if c == nil || o == nil {
+		return c == o
+	}
+
+	return c.KernelVersion == o.KernelVersion &&
+		c.Arch == o.Arch &&
+		c.CPUCount == o.CPUCount &&
+		c.BTF == o.BTF &&
+		maps.Equal(c.BPFFeatures, o.BPFFeatures) &&
+		slices.EqualFunc(c.NICs, o.NICs, func(a, b NicCapability) bool {
+			return a.Name == b.Name && a.Driver == b.Driver && a.XDPNative == b.XDPNative
+		})
+}
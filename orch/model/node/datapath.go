@@ -0,0 +1,43 @@
+package node
+
+import (
+	"xdp-banner/orch/model/common"
+)
+
+// DatapathEntry is one rule as written to the agent maps
+type DatapathEntry struct {
+	Key      string `json:"key,omitempty"` // etcd key, only set for expected rules
+	Cidr     string `json:"cidr"`
+	Identity string `json:"identity"`
+	Protocol string `json:"protocol,omitempty"` // empty for identity_ipcache entries without ban
+	Sport    uint16 `json:"sport"`
+	Dport    uint16 `json:"dport"`
+}
+
+// DatapathMismatch is a rule present in both etcd and the maps with different identities
+type DatapathMismatch struct {
+	Expected DatapathEntry `json:"expected"`
+	Actual   DatapathEntry `json:"actual"`
+}
+
+// DatapathReport is the difference between the rules of the agent config and
+// the maps of the agent
+type DatapathReport struct {
+	Config     string             `json:"config"`
+	Missing    []DatapathEntry    `json:"missing"`
+	Extra      []DatapathEntry    `json:"extra"`
+	Mismatched []DatapathMismatch `json:"mismatched"`
+	Repaired   bool               `json:"repaired"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// InSync reports whether no drift is found
+func (r *DatapathReport) InSync() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Mismatched) == 0
+}
+
+func (r *DatapathReport) Marshal() []byte {
+	return common.MustMarshal(r)
+}
+
+type DatapathReportItems = map[string]*DatapathReport
@@ -7,6 +7,9 @@ import (
 
 type OrchInfo struct {
 	CommonInfo `json:",inline"`
+
+	// Endpoint is the grpc address advertised to the agents, empty until the server started once
+	Endpoint string `json:"endpoint,omitempty"`
 }
 
 func (i *OrchInfo) Marshal() []byte {
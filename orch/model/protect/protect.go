@@ -0,0 +1,57 @@
+package protect
+
+import (
+	"encoding/json"
+	"time"
+	"xdp-banner/orch/model/common"
+)
+
+// ProtectedRange is a CIDR which can never be banned
+type ProtectedRange struct {
+	Name      string    `json:"name" yaml:"name"`
+	Cidr      string    `json:"cidr" yaml:"cidr"`
+	Comment   string    `json:"comment" yaml:"comment"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
+
+func (p *ProtectedRange) Marshal() []byte {
+	return common.MustMarshal(p)
+}
+
+func (p *ProtectedRange) MarshalStr() string {
+	return string(p.Marshal())
+}
+
+func (p *ProtectedRange) Unmarshal(data []byte) error {
+	return json.Unmarshal(data, p)
+}
+
+func (p *ProtectedRange) UnmarshalStr(data string) error {
+	return p.Unmarshal([]byte(data))
+}
+
+type ProtectedRangeItems = map[name]*ProtectedRange
+
+// ProtectedRangeList is a list of managed protected ranges.
+type ProtectedRangeList struct {
+	common.List `json:",inline"`
+	// Items is the list of ranges identified by their names.
+	Items ProtectedRangeItems `json:"items"`
+}
+
+type name = string
+
+const (
+	// SourceManaged prefixes the ranges managed through the api
+	SourceManaged = "managed/"
+	// SourceConfig is the source of the ranges of the orch config
+	SourceConfig = "config"
+	// SourceOrch prefixes the addresses of the orchestrators
+	SourceOrch = "orch/"
+)
+
+// EffectiveRange is a protected CIDR with where it comes from
+type EffectiveRange struct {
+	Cidr   string `json:"cidr"`
+	Source string `json:"source"`
+}
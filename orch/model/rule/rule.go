@@ -3,6 +3,7 @@ package rule
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 	"xdp-banner/orch/model/common"
 	"xdp-banner/pkg/rule"
 )
@@ -49,3 +50,248 @@ type RuleList struct {
 }
 
 type name = string
+
+// Removed is what a delete removed from a rule set
+type Removed struct {
+	// Rules are the deleted rules
+	Rules RuleItem `json:"rules"`
+	// Identities are the CIDRs whose identity key was deleted with their last rule
+	Identities []string `json:"identities"`
+	// RuleSetRemoved is true when the name was dropped from the rule set names
+	RuleSetRemoved bool `json:"rule_set_removed"`
+}
+
+// LineError is the error of a line of a bulk import
+type LineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportReport is the result of a bulk import
+type ImportReport struct {
+	// Lines is the number of lines holding a rule
+	Lines int64 `json:"lines"`
+	// Added is the number of rules added, a clipped line adds several
+	Added int64 `json:"added"`
+	// Failed is the number of lines with an error
+	Failed int64 `json:"failed"`
+	// Errors are the errors of the first failed lines
+	Errors []LineError `json:"errors"`
+	// Saved is the number of rules an aggregated import did not write, they
+	// are merged into the added ones
+	Saved int64 `json:"saved"`
+}
+
+// Aggregation is a rule merged from the rules it replaces
+type Aggregation struct {
+	Rule     Rule     `json:"rule"`
+	Replaces RuleItem `json:"replaces"`
+}
+
+// AggregationPreview is what aggregating a rule set would change
+type AggregationPreview struct {
+	// Before and After are the number of rules without and with aggregation
+	Before int `json:"before"`
+	After  int `json:"after"`
+	// Merged are the merged rules, the rules left as they are are not listed
+	Merged []Aggregation `json:"merged"`
+}
+
+// Match is a rule found by a search
+type Match struct {
+	// Name is the rule set of the rule
+	Name string `json:"name"`
+	Rule Rule   `json:"rule"`
+	// Covers is true when the rule covers the whole searched CIDR, false when
+	// the rule is inside it
+	Covers bool `json:"covers"`
+}
+
+// The operations recorded in the history of a rule set
+const (
+	OpAdd      = "add"
+	OpUpdate   = "update"
+	OpExtend   = "extend"
+	OpDelete   = "delete"
+	OpRollback = "rollback"
+)
+
+// Change is a version of a rule set, the mutation which produced it. An update
+// removes the old rule and adds the new one.
+type Change struct {
+	Version int64     `json:"version"`
+	Time    time.Time `json:"time"`
+	// Actor is the client which made the change
+	Actor string `json:"actor"`
+	Op    string `json:"op"`
+	// Target is the version a rollback went back to
+	Target  int64    `json:"target,omitempty"`
+	Added   RuleItem `json:"added"`
+	Removed RuleItem `json:"removed"`
+	// Truncated is true when the change touched too many rules to be recorded,
+	// Added and Removed are then empty and it can not be rolled back
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// VersionList is a page of the history of a rule set, newest first
+type VersionList struct {
+	// Head is the current version, 0 before the first change
+	Head     int64    `json:"head"`
+	Versions []Change `json:"versions"`
+	// NextBefore lists the following page, 0 at the end of the history
+	NextBefore int64 `json:"next_before"`
+}
+
+// Diff is what changed in a rule set between two versions, an updated rule
+// is both removed and added
+type Diff struct {
+	From    int64    `json:"from"`
+	To      int64    `json:"to"`
+	Added   RuleItem `json:"added"`
+	Removed RuleItem `json:"removed"`
+}
+
+// EffectiveRule is a rule of the merged view of a rule set
+type EffectiveRule struct {
+	// Source is the rule set the rule comes from, the set itself or an include
+	Source string `json:"source"`
+	Rule   Rule   `json:"rule"`
+}
+
+// The kinds of the findings of an analysis
+const (
+	// FindingDuplicate is a rule writing the same banlist entry as another
+	FindingDuplicate = "duplicate"
+	// FindingShadowed is a rule whose packets are all matched by other rules
+	FindingShadowed = "shadowed"
+	// FindingConflict is a rule not applied to a prefix inside its CIDR, which
+	// has rules of its own or is protected, so some packets it matches pass
+	FindingConflict = "conflict"
+	// FindingUnreachable is a rule which never matches a packet
+	FindingUnreachable = "unreachable"
+)
+
+// Finding is a rule which does not do what it seems to
+type Finding struct {
+	Kind string        `json:"kind"`
+	Rule EffectiveRule `json:"rule"`
+	// Related are the first rules causing the finding: the duplicated, the
+	// shadowing or the more specific rules
+	Related []EffectiveRule `json:"related"`
+	// Cidr is the more specific prefix or the protected range of a conflict
+	Cidr    string `json:"cidr,omitempty"`
+	Message string `json:"message"`
+}
+
+// Analysis is what the analysis of a rule set found
+type Analysis struct {
+	// Sets are the rule sets analyzed, from the highest precedence
+	Sets []string `json:"sets"`
+	// Rules is the number of rules analyzed
+	Rules    int       `json:"rules"`
+	Findings []Finding `json:"findings"`
+	// Truncated is true when there were more findings than returned
+	Truncated bool `json:"truncated"`
+}
+
+// Explanation is the verdict the XDP program of the agents watching a rule
+// set gives a packet
+type Explanation struct {
+	// Sets are the rule sets merged, from the highest precedence
+	Sets []string `json:"sets"`
+	Drop bool     `json:"drop"`
+	// Cidr and Identity are the longest prefix of the source in
+	// identity_ipcache, empty when the source has no identity
+	Cidr     string `json:"cidr,omitempty"`
+	Identity string `json:"identity,omitempty"`
+	// Protected is the source of the protected range Cidr is, if it is one
+	Protected string `json:"protected,omitempty"`
+	// Rule is the rule of the banlist entry matching the packet
+	Rule   *EffectiveRule `json:"rule,omitempty"`
+	Reason string         `json:"reason"`
+}
+
+// IdentityFix is an identity key given a new identity, it shared its identity
+// with another key
+type IdentityFix struct {
+	Key         string `json:"key"`
+	Identity    string `json:"identity"`
+	NewIdentity string `json:"new_identity"`
+}
+
+// IdentityReport is what an identity check found, and fixed when Repaired
+type IdentityReport struct {
+	// IdentityKeys is the number of identity keys checked
+	IdentityKeys int `json:"identity_keys"`
+	// Duplicates are the identity keys holding an identity another key holds,
+	// the key registered to it keeps it
+	Duplicates []IdentityFix `json:"duplicates"`
+	// Unregistered are the identity keys whose identity is not registered to them
+	Unregistered []string `json:"unregistered"`
+	// Orphans are the registered identities no identity key holds
+	Orphans []string `json:"orphans"`
+	// StaleRules are the rule keys whose identity is not the one of their identity key
+	StaleRules []string `json:"stale_rules"`
+	// MissingKeys are the identity keys missing for the rules of their CIDR,
+	// created again with the identity of the rules when no other key holds it
+	MissingKeys []IdentityFix `json:"missing_keys"`
+	Repaired    bool          `json:"repaired"`
+}
+
+// NameMigration is the result of moving the rule set names to their own keys
+type NameMigration struct {
+	// RuleSets is the number of rule sets having rules
+	RuleSets int `json:"rule_sets"`
+	// Added are the rule sets given a name key
+	Added []string `json:"added"`
+	// Dropped are the names of the old list without rules
+	Dropped []string `json:"dropped"`
+	// ListRemoved is true when the old list was deleted
+	ListRemoved bool `json:"list_removed"`
+}
+
+// Schedule is when a scheduled rule is active: for Duration after every tick
+// of Cron in Timezone, or from Start to End without Cron. With Cron, Start
+// and End bound the windows and may be zero.
+type Schedule struct {
+	Cron     string    `json:"cron,omitempty"`
+	Duration string    `json:"duration,omitempty"`
+	Timezone string    `json:"timezone,omitempty"`
+	Start    time.Time `json:"start,omitzero"`
+	End      time.Time `json:"end,omitzero"`
+}
+
+// ScheduledRule is a rule the leader adds to a rule set at the start of each
+// window of its schedule, it expires at the end of the window
+type ScheduledRule struct {
+	// Name is the rule set the rule is added to
+	Name      string        `json:"name"`
+	RuleInfo  rule.RuleInfo `json:"info"`
+	Schedule  Schedule      `json:"schedule"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+func (s *ScheduledRule) MarshalStr() string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		panic(fmt.Errorf("marshal scheduled rule failed: %v", err))
+	}
+	return string(b)
+}
+
+func (s *ScheduledRule) Unmarshal(b []byte) error {
+	if err := json.Unmarshal(b, s); err != nil {
+		return fmt.Errorf("unmarshal scheduled rule failed: %v", err)
+	}
+	return nil
+}
+
+// Activation is a window of a scheduled rule
+type Activation struct {
+	Name     string        `json:"name"`
+	RuleInfo rule.RuleInfo `json:"info"`
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	// Active is true for the window in progress
+	Active bool `json:"active"`
+}
@@ -16,6 +16,9 @@ type Strategy struct {
 	Action StrategyAction `json:"action" yaml:"action"`
 	// Value  depends on the action
 	Value string `json:"value" yaml:"value"`
+	// CapabilitySelector is a comma separated list of requirements on the
+	// host capabilities, all of them must hold, e.g. "kernel>=5.10,btf,driver=mlx5_core"
+	CapabilitySelector string `json:"capabilitySelector,omitempty" yaml:"capabilitySelector,omitempty"`
 }
 
 type StrategyAction string
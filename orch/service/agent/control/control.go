@@ -8,6 +8,7 @@ import (
 	logic "xdp-banner/orch/logic/agent/control"
 	"xdp-banner/orch/service/convert"
 	"xdp-banner/pkg/server/common"
+	"xdp-banner/pkg/server/middleware"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"google.golang.org/grpc"
@@ -58,7 +59,7 @@ func (s *ControlService) Init(ctx context.Context, r *control.InitRequest) (*con
 		ipAddress = append(ipAddress, ip)
 	}
 	fmt.Println("init is starting")
-	cert, ca, err := s.logic.Init(ctx, r.Name, r.Token, ipAddress, r.PubKeyPem)
+	cert, ca, err := s.logic.Init(ctx, r.Name, r.Token, ipAddress, r.PubKeyPem, convert.HostCapabilitiesFromDto(r.Capabilities))
 	if err != nil {
 		return nil, common.HandleError(err)
 	}
@@ -66,6 +67,35 @@ func (s *ControlService) Init(ctx context.Context, r *control.InitRequest) (*con
 	return &control.InitResponse{Cert: cert, Ca: ca}, nil
 }
 
+func (s *ControlService) RenewCertificate(ctx context.Context, r *control.RenewCertificateRequest) (*control.RenewCertificateResponse, error) {
+	// 只信任证书中的身份, 不使用 AuthInterceptor 的 localhost 放行
+	name, err := middleware.VerifiedPeerName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case r.PubKeyPem == nil:
+		return nil, common.InvalidArgumentError("pub_key_pem is required")
+	}
+
+	ipAddress := make([]net.IP, 0, len(r.IpAddresses))
+	for _, ipStr := range r.IpAddresses {
+		ip := net.ParseIP(ipStr)
+		if ip == nil {
+			return nil, common.InvalidArgumentError("invalid ip address")
+		}
+		ipAddress = append(ipAddress, ip)
+	}
+
+	cert, ca, err := s.logic.RenewCertificate(ctx, name, ipAddress, r.PubKeyPem)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return &control.RenewCertificateResponse{Cert: cert, Ca: ca}, nil
+}
+
 func (s *ControlService) Register(ctx context.Context, r *control.RegisterRequest) (*control.RegisterResponse, error) {
 	switch {
 	case r.Name == "":
@@ -300,3 +330,23 @@ func (s *ControlService) ListAgents(ctx context.Context, r *control.ListAgentsRe
 		Agents:     dto,
 	}, nil
 }
+
+func (s *ControlService) CheckDatapath(ctx context.Context, r *control.CheckDatapathRequest) (*control.CheckDatapathResponse, error) {
+	if r == nil {
+		return nil, common.InvalidArgumentError("request is required")
+	}
+
+	reports, err := s.logic.CheckDatapath(ctx, r.Name, r.Repair)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	dto, err := convert.DatapathReportItemsToDto(reports)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return &control.CheckDatapathResponse{
+		Reports: dto,
+	}, nil
+}
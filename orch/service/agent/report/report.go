@@ -7,6 +7,7 @@ import (
 
 	logic "xdp-banner/orch/logic/agent/report"
 	model "xdp-banner/orch/model/node"
+	"xdp-banner/orch/service/convert"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"google.golang.org/grpc"
@@ -47,6 +48,21 @@ func (s *ReportService) Report(ctx context.Context, status *report.Status) (*rep
 		Config:       status.ConfigName,
 		Phase:        status.Phase.String(),
 	}
+	for _, c := range status.Conditions {
+		m.Conditions = append(m.Conditions, model.Condition{
+			Type:               c.Type,
+			Status:             c.Status,
+			Reason:             c.Reason,
+			Message:            c.Message,
+			LastTransitionTime: c.LastTransitionTime.AsTime(),
+		})
+	}
+	for _, i := range status.Interfaces {
+		m.Interfaces = append(m.Interfaces, model.InterfaceHealth{Name: i.Name, Attached: i.Attached, Up: i.Up, Error: i.Error})
+	}
+	for _, u := range status.Maps {
+		m.Maps = append(m.Maps, model.MapUsage{Name: u.Name, Entries: u.Entries, MaxEntries: u.MaxEntries})
+	}
 	if status.Error != nil {
 		m.Error = &model.ErrorTime{
 			Message: status.Error.Message,
@@ -59,5 +75,10 @@ func (s *ReportService) Report(ctx context.Context, status *report.Status) (*rep
 		return nil, err
 	}
 
+	err = s.logic.UpdateCapabilities(ctx, status.Name, convert.HostCapabilitiesFromDto(status.Capabilities))
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
 	return nil, nil
 }
@@ -3,6 +3,7 @@ package convert
 import (
 	"fmt"
 	"xdp-banner/api/orch/v1/agent/control"
+	"xdp-banner/api/orch/v1/agent/report"
 	model "xdp-banner/orch/model/node"
 
 	"google.golang.org/protobuf/types/known/structpb"
@@ -106,3 +107,44 @@ func OrchInfoItemsToDto(items model.OrchInfoItems) (map[string]*structpb.Struct,
 
 	return dto, nil
 }
+
+func DatapathReportItemsToDto(reports model.DatapathReportItems) (map[string]*structpb.Struct, error) {
+	dto := make(map[string]*structpb.Struct, len(reports))
+	for name, report := range reports {
+		s := &structpb.Struct{}
+		if err := s.UnmarshalJSON(report.Marshal()); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal json to pb struct: %w", err)
+		}
+		dto[name] = s
+	}
+
+	return dto, nil
+}
+
+// HostCapabilitiesFromDto converts the probed capabilities, nil stays nil
+func HostCapabilitiesFromDto(dto *report.HostCapabilities) *model.HostCapabilities {
+	if dto == nil {
+		return nil
+	}
+
+	c := &model.HostCapabilities{
+		KernelVersion: dto.KernelVersion,
+		Arch:          dto.Arch,
+		CPUCount:      int(dto.CpuCount),
+		BTF:           dto.Btf,
+		BPFFeatures:   dto.BpfFeatures,
+		ProbedAt:      dto.ProbedAt.AsTime(),
+	}
+	for _, n := range dto.Nics {
+		c.NICs = append(c.NICs, model.NicCapability{
+			Name:      n.Name,
+			Driver:    n.Driver,
+			RxQueues:  n.RxQueues,
+			TxQueues:  n.TxQueues,
+			XDPNative: n.XdpNative,
+			Up:        n.Up,
+		})
+	}
+
+	return c
+}
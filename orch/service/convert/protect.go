@@ -0,0 +1,50 @@
+package convert
+
+import (
+	api "xdp-banner/api/orch/v1/protect"
+	"xdp-banner/orch/model/protect"
+)
+
+func ProtectedRangeDtoToModel(dto *api.ProtectedRange) (*protect.ProtectedRange, error) {
+	if dto == nil {
+		return nil, NewErrInvalidField("protected range", "empty protected range")
+	}
+
+	return &protect.ProtectedRange{
+		Name:    dto.Name,
+		Cidr:    dto.Cidr,
+		Comment: dto.Comment,
+	}, nil
+}
+
+func ProtectedRangeModelToDto(model *protect.ProtectedRange) (*api.ProtectedRange, error) {
+	if model == nil {
+		return nil, NewErrInvalidField("protected range", "empty protected range")
+	}
+
+	return &api.ProtectedRange{
+		Name:    model.Name,
+		Cidr:    model.Cidr,
+		Comment: model.Comment,
+	}, nil
+}
+
+func ProtectedRangeListItemToDto(models protect.ProtectedRangeItems) (map[string]*api.ProtectedRange, error) {
+	dtos := make(map[string]*api.ProtectedRange, len(models))
+	for name, r := range models {
+		dto, err := ProtectedRangeModelToDto(r)
+		if err != nil {
+			return nil, NewErrInvalidField("protected range", "failed to convert protected range model to dto")
+		}
+		dtos[name] = dto
+	}
+	return dtos, nil
+}
+
+func EffectiveRangesToDto(models []protect.EffectiveRange) []*api.EffectiveRange {
+	dtos := make([]*api.EffectiveRange, 0, len(models))
+	for _, r := range models {
+		dtos = append(dtos, &api.EffectiveRange{Cidr: r.Cidr, Source: r.Source})
+	}
+	return dtos
+}
@@ -38,11 +38,15 @@ func RuleDtoToModel(dto *structpb.Struct) (*model.Rule, error) {
 	if ruleinfo.Duration == "" {
 		ruleinfo.Duration = "300s"
 	}
-	d, err := time.ParseDuration(ruleinfo.Duration)
+	// "0" 和 "permanent" 表示永久规则, ExpiresAt 为零值
+	d, err := rule.ParseRuleDuration(ruleinfo.Duration)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal durations input: %w", err)
+		return nil, NewErrInvalidField("duration", err.Error())
+	}
+	var expiresAt time.Time
+	if d > 0 {
+		expiresAt = createdAt.Add(d)
 	}
-	expiresAt := createdAt.Add(d)
 
 	rule := &model.Rule{
 		RuleMeta: rule.RuleMeta{
@@ -0,0 +1,576 @@
+package convert
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+	api "xdp-banner/api/orch/v2/rule"
+	"xdp-banner/orch/logic/rulecenter"
+	"xdp-banner/orch/logic/rulecenter/bulk"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/rule"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// defaultRuleDuration is the duration of a rule without one, same as v1
+const defaultRuleDuration = 300 * time.Second
+
+var (
+	protocolToModel = map[api.Protocol]string{
+		api.Protocol_PROTOCOL_TCP:  "TCP",
+		api.Protocol_PROTOCOL_UDP:  "UDP",
+		api.Protocol_PROTOCOL_ICMP: "ICMP",
+	}
+	protocolToDto = map[string]api.Protocol{
+		"TCP":  api.Protocol_PROTOCOL_TCP,
+		"UDP":  api.Protocol_PROTOCOL_UDP,
+		"ICMP": api.Protocol_PROTOCOL_ICMP,
+	}
+)
+
+// RuleMatchV2ToModel converts the match of a v2 rule, the cidr and the
+// protocol are required
+func RuleMatchV2ToModel(dto *api.RuleMatch) (rule.RuleInfo, error) {
+	if dto == nil {
+		return rule.RuleInfo{}, NewErrInvalidField("match", "missing")
+	}
+	if dto.Cidr == "" {
+		return rule.RuleInfo{}, NewErrInvalidField("match.cidr", "missing")
+	}
+
+	protocol, ok := protocolToModel[dto.Protocol]
+	if !ok {
+		return rule.RuleInfo{}, NewErrInvalidField("match.protocol", "must be PROTOCOL_TCP, PROTOCOL_UDP or PROTOCOL_ICMP")
+	}
+	if dto.Sport > math.MaxUint16 {
+		return rule.RuleInfo{}, NewErrInvalidField("match.sport", "must be a port number")
+	}
+	if dto.Dport > math.MaxUint16 {
+		return rule.RuleInfo{}, NewErrInvalidField("match.dport", "must be a port number")
+	}
+	if protocol == "ICMP" && (dto.Sport != 0 || dto.Dport != 0) {
+		return rule.RuleInfo{}, NewErrInvalidField("match", "ICMP rules can not set ports")
+	}
+
+	return rule.RuleInfo{
+		Cidr:     dto.Cidr,
+		Protocol: protocol,
+		Sport:    uint16(dto.Sport),
+		Dport:    uint16(dto.Dport),
+	}, nil
+}
+
+// RuleV2DtoToModel converts a v2 rule of a request, meta and key are ignored
+func RuleV2DtoToModel(dto *api.Rule) (*model.Rule, error) {
+	if dto == nil {
+		return nil, NewErrInvalidField("rule", "empty rule")
+	}
+
+	info, err := RuleMatchV2ToModel(dto.Match)
+	if err != nil {
+		return nil, err
+	}
+
+	switch dto.Action {
+	case api.Action_ACTION_UNSPECIFIED, api.Action_ACTION_DENY:
+	default:
+		return nil, NewErrInvalidField("action", "only ACTION_DENY is supported")
+	}
+
+	d, err := DurationV2ToModel(dto.Duration)
+	if err != nil {
+		return nil, err
+	}
+	info.Duration = durationString(d)
+	info.Comment = dto.Comment
+
+	createdAt := time.Now()
+	var expiresAt time.Time
+	if d > 0 {
+		expiresAt = createdAt.Add(d)
+	}
+	return &model.Rule{
+		RuleMeta: rule.RuleMeta{
+			Comment:   dto.Comment,
+			CreatedAt: createdAt,
+			ExpiresAt: expiresAt,
+			// Init when needed
+			Identity: "0",
+		},
+		RuleInfo: info,
+	}, nil
+}
+
+// DurationV2ToModel returns the duration of a rule, defaultRuleDuration when
+// unset and 0 for a permanent rule
+func DurationV2ToModel(dto *durationpb.Duration) (time.Duration, error) {
+	if dto == nil {
+		return defaultRuleDuration, nil
+	}
+	if err := dto.CheckValid(); err != nil {
+		return 0, NewErrInvalidField("duration", err.Error())
+	}
+
+	d := dto.AsDuration()
+	if d < 0 {
+		return 0, NewErrInvalidField("duration", "must not be negative, 0 is a permanent rule")
+	}
+	return d, nil
+}
+
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return rule.PermanentDuration
+	}
+	return d.String()
+}
+
+func RuleModelToV2Dto(m *model.Rule) *api.Rule {
+	comment := m.RuleMeta.Comment
+	if comment == "" {
+		comment = m.RuleInfo.Comment
+	}
+
+	dto := &api.Rule{
+		Key: m.RuleInfo.Key(),
+		Match: &api.RuleMatch{
+			Cidr:     m.RuleInfo.Cidr,
+			Protocol: protocolToDto[strings.ToUpper(m.RuleInfo.Protocol)],
+			Sport:    uint32(m.RuleInfo.Sport),
+			Dport:    uint32(m.RuleInfo.Dport),
+		},
+		Action:  api.Action_ACTION_DENY,
+		Comment: comment,
+		Meta: &api.RuleMeta{
+			Identity:   m.RuleMeta.Identity,
+			Aggregated: m.RuleMeta.Aggregated,
+			Scheduled:  m.RuleMeta.Scheduled,
+		},
+	}
+	if !m.RuleMeta.CreatedAt.IsZero() {
+		dto.Meta.CreatedAt = timestamppb.New(m.RuleMeta.CreatedAt)
+	}
+	switch {
+	case m.RuleMeta.Permanent():
+		dto.Duration = durationpb.New(0)
+	default:
+		dto.Meta.ExpiresAt = timestamppb.New(m.RuleMeta.ExpiresAt)
+		if !m.RuleMeta.CreatedAt.IsZero() {
+			dto.Duration = durationpb.New(m.RuleMeta.ExpiresAt.Sub(m.RuleMeta.CreatedAt))
+		}
+	}
+
+	return dto
+}
+
+// RuleSetToV2Dto converts the rules of a rule set, sorted by key
+func RuleSetToV2Dto(name string, rules model.RuleItem) *api.RuleSet {
+	dto := &api.RuleSet{Name: name, Rules: make([]*api.Rule, 0, len(rules))}
+	for i := range rules {
+		dto.Rules = append(dto.Rules, RuleModelToV2Dto(&rules[i]))
+	}
+	slices.SortFunc(dto.Rules, func(a, b *api.Rule) int { return strings.Compare(a.Key, b.Key) })
+
+	return dto
+}
+
+// RuleListToV2Dto converts the rule sets of a page, sorted by name
+func RuleListToV2Dto(items model.RuleItems) []*api.RuleSet {
+	dtos := make([]*api.RuleSet, 0, len(items))
+	for name, rules := range items {
+		dtos = append(dtos, RuleSetToV2Dto(name, rules))
+	}
+	slices.SortFunc(dtos, func(a, b *api.RuleSet) int { return strings.Compare(a.Name, b.Name) })
+
+	return dtos
+}
+
+// RemovedToV2Dto converts the result of a delete
+func RemovedToV2Dto(removed *model.Removed, notFound []string) *api.DeleteRulesResponse {
+	dto := &api.DeleteRulesResponse{
+		Removed:           make([]*api.Rule, 0, len(removed.Rules)),
+		RemovedIdentities: removed.Identities,
+		RuleSetRemoved:    removed.RuleSetRemoved,
+		NotFound:          notFound,
+	}
+	for i := range removed.Rules {
+		dto.Removed = append(dto.Removed, RuleModelToV2Dto(&removed.Rules[i]))
+	}
+	slices.Sort(dto.NotFound)
+
+	return dto
+}
+
+func RuleSelectorV2ToModel(dto *api.RuleSelector) (rulecenter.Selector, error) {
+	if dto == nil {
+		return rulecenter.Selector{}, NewErrInvalidField("selector", "missing")
+	}
+
+	sel := rulecenter.Selector{Cidr: dto.Cidr, Comment: dto.Comment}
+	if dto.Protocol != api.Protocol_PROTOCOL_UNSPECIFIED {
+		protocol, ok := protocolToModel[dto.Protocol]
+		if !ok {
+			return rulecenter.Selector{}, NewErrInvalidField("selector.protocol", "unknown protocol")
+		}
+		sel.Protocol = protocol
+	}
+	return sel, nil
+}
+
+var formatToModel = map[api.Format]bulk.Format{
+	api.Format_FORMAT_UNSPECIFIED: bulk.NDJSON,
+	api.Format_FORMAT_NDJSON:      bulk.NDJSON,
+	api.Format_FORMAT_CSV:         bulk.CSV,
+	api.Format_FORMAT_PLAIN:       bulk.Plain,
+}
+
+func FormatV2ToModel(dto api.Format) (bulk.Format, error) {
+	format, ok := formatToModel[dto]
+	if !ok {
+		return 0, NewErrInvalidField("format", "must be FORMAT_NDJSON, FORMAT_CSV or FORMAT_PLAIN")
+	}
+	return format, nil
+}
+
+// ImportHeaderV2ToModel returns the format of an import and the defaults of
+// its lines, plain lists have no protocol of their own so it is required
+func ImportHeaderV2ToModel(dto *api.ImportRulesHeader) (bulk.Format, bulk.Defaults, error) {
+	format, err := FormatV2ToModel(dto.Format)
+	if err != nil {
+		return 0, bulk.Defaults{}, err
+	}
+
+	var defaults bulk.Defaults
+	if dto.Protocol != api.Protocol_PROTOCOL_UNSPECIFIED {
+		protocol, ok := protocolToModel[dto.Protocol]
+		if !ok {
+			return 0, bulk.Defaults{}, NewErrInvalidField("protocol", "unknown protocol")
+		}
+		defaults.Protocol = protocol
+	}
+	if format == bulk.Plain && defaults.Protocol == "" {
+		return 0, bulk.Defaults{}, NewErrInvalidField("protocol", "required for FORMAT_PLAIN")
+	}
+
+	d, err := DurationV2ToModel(dto.Duration)
+	if err != nil {
+		return 0, bulk.Defaults{}, err
+	}
+	defaults.Duration = durationString(d)
+	defaults.Comment = dto.Comment
+
+	return format, defaults, nil
+}
+
+func ImportReportToV2Dto(report *model.ImportReport) *api.ImportRulesResponse {
+	dto := &api.ImportRulesResponse{
+		Lines:  report.Lines,
+		Added:  report.Added,
+		Failed: report.Failed,
+		Errors: make([]*api.ImportLineError, 0, len(report.Errors)),
+		Saved:  report.Saved,
+	}
+	for _, e := range report.Errors {
+		dto.Errors = append(dto.Errors, &api.ImportLineError{Line: int64(e.Line), Error: e.Error})
+	}
+
+	return dto
+}
+
+func FormatToV2Dto(format bulk.Format) api.Format {
+	switch format {
+	case bulk.CSV:
+		return api.Format_FORMAT_CSV
+	case bulk.Plain:
+		return api.Format_FORMAT_PLAIN
+	default:
+		return api.Format_FORMAT_NDJSON
+	}
+}
+
+// SearchRequestV2ToModel converts a search, the cidr is checked by the logic
+func SearchRequestV2ToModel(dto *api.SearchRulesRequest) (rulecenter.Query, error) {
+	if dto.Cidr == "" {
+		return rulecenter.Query{}, NewErrInvalidField("cidr", "missing")
+	}
+	if dto.Sport > math.MaxUint16 {
+		return rulecenter.Query{}, NewErrInvalidField("sport", "must be a port number")
+	}
+	if dto.Dport > math.MaxUint16 {
+		return rulecenter.Query{}, NewErrInvalidField("dport", "must be a port number")
+	}
+
+	q := rulecenter.Query{
+		Cidr:  dto.Cidr,
+		Sport: uint16(dto.Sport),
+		Dport: uint16(dto.Dport),
+		Limit: int(dto.Limit),
+	}
+	if dto.Protocol != api.Protocol_PROTOCOL_UNSPECIFIED {
+		protocol, ok := protocolToModel[dto.Protocol]
+		if !ok {
+			return rulecenter.Query{}, NewErrInvalidField("protocol", "unknown protocol")
+		}
+		q.Protocol = protocol
+	}
+	return q, nil
+}
+
+func SearchResultsToV2Dto(matches []model.Match, truncated bool) *api.SearchRulesResponse {
+	dto := &api.SearchRulesResponse{
+		Results:   make([]*api.SearchResult, 0, len(matches)),
+		Truncated: truncated,
+	}
+	for i := range matches {
+		dto.Results = append(dto.Results, &api.SearchResult{
+			Name:   matches[i].Name,
+			Rule:   RuleModelToV2Dto(&matches[i].Rule),
+			Covers: matches[i].Covers,
+		})
+	}
+
+	return dto
+}
+
+func rulesToV2Dto(rules model.RuleItem) []*api.Rule {
+	dto := make([]*api.Rule, 0, len(rules))
+	for i := range rules {
+		dto = append(dto, RuleModelToV2Dto(&rules[i]))
+	}
+	return dto
+}
+
+func ChangeToV2Dto(c *model.Change) *api.Version {
+	return &api.Version{
+		Version:   c.Version,
+		Time:      timestamppb.New(c.Time),
+		Actor:     c.Actor,
+		Op:        c.Op,
+		Target:    c.Target,
+		Added:     rulesToV2Dto(c.Added),
+		Removed:   rulesToV2Dto(c.Removed),
+		Truncated: c.Truncated,
+	}
+}
+
+func VersionListToV2Dto(list *model.VersionList) *api.ListVersionsResponse {
+	dto := &api.ListVersionsResponse{
+		Head:       list.Head,
+		Versions:   make([]*api.Version, 0, len(list.Versions)),
+		NextBefore: list.NextBefore,
+	}
+	for i := range list.Versions {
+		dto.Versions = append(dto.Versions, ChangeToV2Dto(&list.Versions[i]))
+	}
+
+	return dto
+}
+
+func DiffToV2Dto(diff *model.Diff) *api.DiffVersionsResponse {
+	return &api.DiffVersionsResponse{
+		From:    diff.From,
+		To:      diff.To,
+		Added:   rulesToV2Dto(diff.Added),
+		Removed: rulesToV2Dto(diff.Removed),
+	}
+}
+
+func EffectiveRuleSetToV2Dto(name string, sets []string, rules []model.EffectiveRule) *api.EffectiveRuleSet {
+	dto := &api.EffectiveRuleSet{Name: name, Sets: sets, Rules: make([]*api.EffectiveRule, 0, len(rules))}
+	for i := range rules {
+		dto.Rules = append(dto.Rules, effectiveRuleToV2Dto(&rules[i]))
+	}
+	return dto
+}
+
+func IdentityReportToV2Dto(report *model.IdentityReport) *api.CheckIdentitiesResponse {
+	dto := &api.CheckIdentitiesResponse{
+		IdentityKeys: int64(report.IdentityKeys),
+		Unregistered: report.Unregistered,
+		Orphans:      report.Orphans,
+		StaleRules:   report.StaleRules,
+		Repaired:     report.Repaired,
+	}
+	for _, fix := range report.Duplicates {
+		dto.Duplicates = append(dto.Duplicates, &api.IdentityFix{
+			Key:         fix.Key,
+			Identity:    fix.Identity,
+			NewIdentity: fix.NewIdentity,
+		})
+	}
+	for _, fix := range report.MissingKeys {
+		dto.MissingKeys = append(dto.MissingKeys, &api.IdentityFix{
+			Key:         fix.Key,
+			Identity:    fix.Identity,
+			NewIdentity: fix.NewIdentity,
+		})
+	}
+	return dto
+}
+
+func AggregationPreviewToV2Dto(name string, preview *model.AggregationPreview) *api.AggregationPreview {
+	dto := &api.AggregationPreview{
+		Name:   name,
+		Before: int64(preview.Before),
+		After:  int64(preview.After),
+		Merged: make([]*api.Aggregation, 0, len(preview.Merged)),
+	}
+	for i := range preview.Merged {
+		dto.Merged = append(dto.Merged, &api.Aggregation{
+			Rule:     RuleModelToV2Dto(&preview.Merged[i].Rule),
+			Replaces: rulesToV2Dto(preview.Merged[i].Replaces),
+		})
+	}
+	return dto
+}
+
+var findingKindToDto = map[string]api.FindingKind{
+	model.FindingDuplicate:   api.FindingKind_FINDING_KIND_DUPLICATE,
+	model.FindingShadowed:    api.FindingKind_FINDING_KIND_SHADOWED,
+	model.FindingConflict:    api.FindingKind_FINDING_KIND_CONFLICT,
+	model.FindingUnreachable: api.FindingKind_FINDING_KIND_UNREACHABLE,
+}
+
+func effectiveRuleToV2Dto(r *model.EffectiveRule) *api.EffectiveRule {
+	return &api.EffectiveRule{Source: r.Source, Rule: RuleModelToV2Dto(&r.Rule)}
+}
+
+func AnalysisToV2Dto(analysis *model.Analysis) *api.Analysis {
+	dto := &api.Analysis{
+		Sets:      analysis.Sets,
+		Rules:     int64(analysis.Rules),
+		Findings:  make([]*api.Finding, 0, len(analysis.Findings)),
+		Truncated: analysis.Truncated,
+	}
+	for i := range analysis.Findings {
+		f := &analysis.Findings[i]
+		finding := &api.Finding{
+			Kind:    findingKindToDto[f.Kind],
+			Rule:    effectiveRuleToV2Dto(&f.Rule),
+			Related: make([]*api.EffectiveRule, 0, len(f.Related)),
+			Cidr:    f.Cidr,
+			Message: f.Message,
+		}
+		for j := range f.Related {
+			finding.Related = append(finding.Related, effectiveRuleToV2Dto(&f.Related[j]))
+		}
+		dto.Findings = append(dto.Findings, finding)
+	}
+	return dto
+}
+
+func ExplanationToV2Dto(e *model.Explanation) *api.Explanation {
+	dto := &api.Explanation{
+		Sets:      e.Sets,
+		Drop:      e.Drop,
+		Cidr:      e.Cidr,
+		Identity:  e.Identity,
+		Protected: e.Protected,
+		Reason:    e.Reason,
+	}
+	if e.Rule != nil {
+		dto.Rule = effectiveRuleToV2Dto(e.Rule)
+	}
+	return dto
+}
+
+// ScheduledRuleV2ToModel converts a request scheduling a rule
+func ScheduledRuleV2ToModel(dto *api.AddScheduledRuleRequest) (*model.ScheduledRule, error) {
+	info, err := RuleMatchV2ToModel(dto.Match)
+	if err != nil {
+		return nil, err
+	}
+	info.Comment = dto.Comment
+
+	if dto.Schedule == nil {
+		return nil, NewErrInvalidField("schedule", "missing")
+	}
+	s := model.Schedule{Cron: dto.Schedule.Cron, Timezone: dto.Schedule.Timezone}
+	if dto.Schedule.Duration != nil {
+		if err := dto.Schedule.Duration.CheckValid(); err != nil {
+			return nil, NewErrInvalidField("schedule.duration", err.Error())
+		}
+		if d := dto.Schedule.Duration.AsDuration(); d != 0 {
+			s.Duration = d.String()
+		}
+	}
+	if dto.Schedule.Start != nil {
+		if err := dto.Schedule.Start.CheckValid(); err != nil {
+			return nil, NewErrInvalidField("schedule.start", err.Error())
+		}
+		s.Start = dto.Schedule.Start.AsTime()
+	}
+	if dto.Schedule.End != nil {
+		if err := dto.Schedule.End.CheckValid(); err != nil {
+			return nil, NewErrInvalidField("schedule.end", err.Error())
+		}
+		s.End = dto.Schedule.End.AsTime()
+	}
+
+	return &model.ScheduledRule{Name: dto.Name, RuleInfo: info, Schedule: s}, nil
+}
+
+func ruleMatchToV2Dto(info *rule.RuleInfo) *api.RuleMatch {
+	return &api.RuleMatch{
+		Cidr:     info.Cidr,
+		Protocol: protocolToDto[strings.ToUpper(info.Protocol)],
+		Sport:    uint32(info.Sport),
+		Dport:    uint32(info.Dport),
+	}
+}
+
+func timestampToV2Dto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func ScheduledRuleToV2Dto(sr *model.ScheduledRule) *api.ScheduledRule {
+	dto := &api.ScheduledRule{
+		Name:    sr.Name,
+		Match:   ruleMatchToV2Dto(&sr.RuleInfo),
+		Comment: sr.RuleInfo.Comment,
+		Schedule: &api.Schedule{
+			Cron:     sr.Schedule.Cron,
+			Timezone: sr.Schedule.Timezone,
+			Start:    timestampToV2Dto(sr.Schedule.Start),
+			End:      timestampToV2Dto(sr.Schedule.End),
+		},
+		CreatedAt: timestampToV2Dto(sr.CreatedAt),
+		Key:       sr.RuleInfo.Key(),
+	}
+	if sr.Schedule.Duration != "" {
+		if d, err := rule.ParseDuration(sr.Schedule.Duration); err == nil {
+			dto.Schedule.Duration = durationpb.New(d)
+		}
+	}
+	return dto
+}
+
+func ScheduledRulesToV2Dto(rules []*model.ScheduledRule) *api.ListScheduledRulesResponse {
+	dto := &api.ListScheduledRulesResponse{Rules: make([]*api.ScheduledRule, 0, len(rules))}
+	for _, sr := range rules {
+		dto.Rules = append(dto.Rules, ScheduledRuleToV2Dto(sr))
+	}
+	return dto
+}
+
+func ActivationsToV2Dto(activations []model.Activation) *api.ListActivationsResponse {
+	dto := &api.ListActivationsResponse{Activations: make([]*api.Activation, 0, len(activations))}
+	for i := range activations {
+		a := &activations[i]
+		dto.Activations = append(dto.Activations, &api.Activation{
+			Name:   a.Name,
+			Match:  ruleMatchToV2Dto(&a.RuleInfo),
+			Key:    a.RuleInfo.Key(),
+			Start:  timestamppb.New(a.Start),
+			End:    timestamppb.New(a.End),
+			Active: a.Active,
+		})
+	}
+	return dto
+}
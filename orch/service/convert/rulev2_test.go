@@ -0,0 +1,147 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+	"time"
+	api "xdp-banner/api/orch/v2/rule"
+	"xdp-banner/orch/logic/rulecenter/bulk"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestRuleV2DtoToModel(t *testing.T) {
+	dto := &api.Rule{
+		Match:    &api.RuleMatch{Cidr: "192.0.2.0/24", Protocol: api.Protocol_PROTOCOL_TCP, Dport: 22},
+		Comment:  "ssh",
+		Duration: durationpb.New(time.Hour),
+	}
+
+	m, err := RuleV2DtoToModel(dto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.RuleInfo.Key() != "192.0.2.0/24/TCP/0-22/" || m.RuleInfo.Duration != "1h0m0s" || m.RuleMeta.Comment != "ssh" {
+		t.Fatalf("unexpected model %+v", m)
+	}
+	if got := m.RuleMeta.ExpiresAt.Sub(m.RuleMeta.CreatedAt); got != time.Hour {
+		t.Fatalf("expires after %v, want 1h", got)
+	}
+
+	back := RuleModelToV2Dto(m)
+	if back.Key != m.RuleInfo.Key() || back.Match.Protocol != api.Protocol_PROTOCOL_TCP || back.Duration.AsDuration() != time.Hour {
+		t.Fatalf("unexpected dto %v", back)
+	}
+
+	m, err = RuleV2DtoToModel(&api.Rule{Match: &api.RuleMatch{Cidr: "192.0.2.0/24", Protocol: api.Protocol_PROTOCOL_ICMP}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.RuleInfo.Duration != defaultRuleDuration.String() {
+		t.Fatalf("duration = %s, want the default", m.RuleInfo.Duration)
+	}
+
+	m, err = RuleV2DtoToModel(&api.Rule{Match: &api.RuleMatch{Cidr: "192.0.2.0/24", Protocol: api.Protocol_PROTOCOL_UDP}, Duration: durationpb.New(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.RuleMeta.Permanent() || m.RuleInfo.Duration != "permanent" {
+		t.Fatalf("zero duration should be a permanent rule, got %+v", m)
+	}
+	if back := RuleModelToV2Dto(m); back.Duration.AsDuration() != 0 || back.Meta.ExpiresAt != nil {
+		t.Fatalf("permanent rule dto %v", back)
+	}
+}
+
+func TestRuleV2DtoToModelInvalid(t *testing.T) {
+	match := func(cidr string, p api.Protocol, sport, dport uint32) *api.RuleMatch {
+		return &api.RuleMatch{Cidr: cidr, Protocol: p, Sport: sport, Dport: dport}
+	}
+
+	tests := []struct {
+		name  string
+		rule  *api.Rule
+		field string
+	}{
+		{"nil", nil, "rule"},
+		{"no match", &api.Rule{}, "match"},
+		{"no cidr", &api.Rule{Match: match("", api.Protocol_PROTOCOL_TCP, 0, 0)}, "match.cidr"},
+		{"no protocol", &api.Rule{Match: match("192.0.2.0/24", api.Protocol_PROTOCOL_UNSPECIFIED, 0, 0)}, "match.protocol"},
+		{"port", &api.Rule{Match: match("192.0.2.0/24", api.Protocol_PROTOCOL_TCP, 0, 70000)}, "match.dport"},
+		{"icmp port", &api.Rule{Match: match("192.0.2.0/24", api.Protocol_PROTOCOL_ICMP, 1, 0)}, "match"},
+		{"action", &api.Rule{Match: match("192.0.2.0/24", api.Protocol_PROTOCOL_TCP, 0, 0), Action: api.Action(7)}, "action"},
+		{"duration", &api.Rule{Match: match("192.0.2.0/24", api.Protocol_PROTOCOL_TCP, 0, 0), Duration: durationpb.New(-time.Second)}, "duration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RuleV2DtoToModel(tt.rule)
+			e, ok := err.(*ErrInvalidField)
+			if !ok || e.Field() != tt.field {
+				t.Fatalf("error = %v, want an invalid field %s", err, tt.field)
+			}
+			if !strings.Contains(e.Status().Message(), "invalid field") {
+				t.Fatalf("status = %v", e.Status())
+			}
+		})
+	}
+}
+
+func TestImportHeaderV2ToModel(t *testing.T) {
+	format, defaults, err := ImportHeaderV2ToModel(&api.ImportRulesHeader{
+		Format:   api.Format_FORMAT_PLAIN,
+		Protocol: api.Protocol_PROTOCOL_UDP,
+		Duration: durationpb.New(0),
+		Comment:  "blocklist",
+	})
+	if err != nil {
+		t.Fatalf("ImportHeaderV2ToModel() error = %v", err)
+	}
+	want := bulk.Defaults{Protocol: "UDP", Duration: "permanent", Comment: "blocklist"}
+	if format != bulk.Plain || defaults != want {
+		t.Errorf("ImportHeaderV2ToModel() = %v, %+v, want plain, %+v", format, defaults, want)
+	}
+
+	format, defaults, err = ImportHeaderV2ToModel(&api.ImportRulesHeader{})
+	if err != nil || format != bulk.NDJSON || defaults.Duration != "5m0s" {
+		t.Errorf("ImportHeaderV2ToModel(empty) = %v, %+v, %v, want ndjson lasting 5m", format, defaults, err)
+	}
+
+	_, _, err = ImportHeaderV2ToModel(&api.ImportRulesHeader{Format: api.Format_FORMAT_PLAIN})
+	if e, ok := err.(*ErrInvalidField); !ok || e.Field() != "protocol" {
+		t.Errorf("plain without protocol error = %v, want an invalid field protocol", err)
+	}
+}
+
+func TestScheduledRuleV2ToModel(t *testing.T) {
+	dto := &api.AddScheduledRuleRequest{
+		Name:    "office",
+		Match:   &api.RuleMatch{Cidr: "192.0.2.0/24", Protocol: api.Protocol_PROTOCOL_TCP, Dport: 22},
+		Comment: "no ssh after hours",
+		Schedule: &api.Schedule{
+			Cron:     "0 18 * * mon-fri",
+			Duration: durationpb.New(15 * time.Hour),
+			Timezone: "Asia/Shanghai",
+		},
+	}
+
+	m, err := ScheduledRuleV2ToModel(dto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "office" || m.RuleInfo.Key() != "192.0.2.0/24/TCP/0-22/" || m.RuleInfo.Comment != "no ssh after hours" {
+		t.Fatalf("unexpected model %+v", m)
+	}
+	if m.Schedule.Duration != "15h0m0s" || !m.Schedule.Start.IsZero() || !m.Schedule.End.IsZero() {
+		t.Fatalf("unexpected schedule %+v", m.Schedule)
+	}
+
+	back := ScheduledRuleToV2Dto(m)
+	if back.Key != m.RuleInfo.Key() || back.Schedule.Duration.AsDuration() != 15*time.Hour || back.Schedule.Start != nil || back.Comment != dto.Comment {
+		t.Fatalf("unexpected dto %v", back)
+	}
+
+	if _, err := ScheduledRuleV2ToModel(&api.AddScheduledRuleRequest{Name: "office", Match: dto.Match}); err == nil {
+		t.Fatal("a missing schedule should be refused")
+	}
+}
@@ -26,6 +26,8 @@ func StrategyDtoToModel(dto *api.Strategy) (*strategy.Strategy, error) {
 		LabelSelector: dto.LabelSelector,
 		Action:        action,
 		Value:         dto.Value,
+
+		CapabilitySelector: dto.CapabilitySelector,
 	}, nil
 }
 
@@ -40,6 +42,8 @@ func StrategyModelToDto(model *strategy.Strategy) (*api.Strategy, error) {
 		LabelSelector: model.LabelSelector,
 		Action:        string(model.Action),
 		Value:         model.Value,
+
+		CapabilitySelector: model.CapabilitySelector,
 	}, nil
 }
 
@@ -0,0 +1,73 @@
+package protect
+
+import (
+	"context"
+
+	api "xdp-banner/api/orch/v1/protect"
+	"xdp-banner/orch/service/convert"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func (s *ProtectService) AddProtectedRange(ctx context.Context, req *api.ProtectedRange) (*emptypb.Empty, error) {
+	pm, err := convert.ProtectedRangeDtoToModel(req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.pl.Add(ctx, pm)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (s *ProtectService) DeleteProtectedRange(ctx context.Context, req *api.DeleteProtectedRangeRequest) (*emptypb.Empty, error) {
+	err := s.pl.Delete(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (s *ProtectService) GetProtectedRange(ctx context.Context, req *api.GetProtectedRangeRequest) (*api.ProtectedRange, error) {
+	pm, err := s.pl.Get(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert.ProtectedRangeModelToDto(pm)
+}
+
+func (s *ProtectService) ListProtectedRange(ctx context.Context, req *api.ListProtectedRangeRequest) (*api.ListProtectedRangeResponse, error) {
+	ranges, err := s.pl.List(ctx, req.Pagesize, req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos, err := convert.ProtectedRangeListItemToDto(ranges.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.ListProtectedRangeResponse{
+		Total:       ranges.TotalCount,
+		TotalPage:   ranges.TotalPage,
+		CurrentPage: ranges.CurrentPage,
+		NextCursor:  ranges.NextCursor,
+		HasNext:     ranges.HasNext,
+		Items:       dtos,
+	}, nil
+}
+
+// ListEffectiveRanges is what the agents enforce locally
+func (s *ProtectService) ListEffectiveRanges(ctx context.Context, _ *emptypb.Empty) (*api.ListEffectiveRangesResponse, error) {
+	ranges, err := s.pl.Effective(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.ListEffectiveRangesResponse{Ranges: convert.EffectiveRangesToDto(ranges)}, nil
+}
@@ -0,0 +1,35 @@
+package protect
+
+import (
+	"context"
+	api "xdp-banner/api/orch/v1/protect"
+
+	"xdp-banner/orch/logic/protect"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+)
+
+type ProtectService struct {
+	api.UnimplementedProtectServiceServer
+
+	pl *protect.Protect
+}
+
+func New(pl *protect.Protect) *ProtectService {
+	return &ProtectService{
+		pl: pl,
+	}
+}
+
+func (s *ProtectService) RegisterGrpcService(gs grpc.ServiceRegistrar) {
+	api.RegisterProtectServiceServer(gs, s)
+}
+
+func (s *ProtectService) PublicGrpcMethods() []string {
+	return nil
+}
+
+func (s *ProtectService) RegisterHttpService(ctx context.Context, mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	return api.RegisterProtectServiceHandler(ctx, mux, conn)
+}
@@ -2,25 +2,20 @@ package rule
 
 import (
 	"context"
-	"errors"
 	"fmt"
 
-	"time"
-
 	"xdp-banner/api/orch/v1/rule"
 
-	"xdp-banner/orch/cmd/global"
 	ruleLogic "xdp-banner/orch/logic/rulecenter"
 	"xdp-banner/orch/service/convert"
-	ruleStorage "xdp-banner/orch/storage/agent/rule"
-	"xdp-banner/pkg/etcd"
-	"xdp-banner/pkg/informer"
 	"xdp-banner/pkg/log"
 	"xdp-banner/pkg/server/common"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
@@ -174,75 +169,52 @@ func (s *RuleService) ListRule(ctx context.Context, r *rule.ListRuleRequest) (*r
 // 当 gRPC 服务器为一个流式 RPC 调用时，会自动生成一个 context，但不会将其传入第一个参数，而是通过 stream.Context() 返回这个上下文。
 // 因此，从功能上来看，它们具有相同的取消信号和截止时间，能够同步响应客户端断开连接等事件。
 
+// WatchRuleResources streams the merged view of a rule set: its own rules and
+// the rules of its includes, under the keys of the sets they come from. The
+// agents do not know about the includes.
 func (s *RuleService) WatchRuleResources(req *rule.WatchRuleRequest,
 	stream rule.RuleService_WatchRuleResourcesServer) error {
 
 	ctx := stream.Context()
-	prefix := etcd.Join(ruleStorage.EtcdDir, req.GetRuleName())
-	log.Info("WatchRuleName:", zap.String("prefix", prefix))
-
-	inf, ok := informer.GlobalInformerRegistry.Get(prefix)
-
-	if !ok {
-		var err error
-		inf, err = informer.CreateInformerForPrefix(ctx, global.Cli, prefix)
-		if err != nil {
-			return err
-		}
-		informer.GlobalInformerRegistry.Add(prefix, inf)
-	}
-
-	syncCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-	if !inf.WaitForCacheSync(syncCtx) {
-		return errors.New("informer cache sync 超时")
-	}
-
 	log.Info("Start A new instance for name:", zap.String("rulename", req.GetRuleName()))
-	// 创建用于后续事件通知的 channel
-	eventCh := make(chan *rule.WatchRuleResponse, 1000)
 
-	// 构造并注册一个临时事件处理器，将事件写入 eventCh
-	handler := &tempEventHandler{
-		prefix: prefix,
-		ch:     eventCh,
-	}
-
-	itemsMap, err := inf.RegisterHandlerAndList([]string{prefix}, handler)
-	if err != nil {
-		return err
-	}
-
-	// --- ADDED: 将返回的 map[string]any 转为 WatchRuleResponse 并初次发送给客户端
-	// 这里演示一个简单转换：对每个 key/value 都打包成自定义响应
-	// 你可根据业务需求灵活处理，比如过滤掉不是 prefix 的、或解析 value。
-	initialEvents, err := convertMapToWatchRuleResponses(itemsMap, prefix)
+	initial, events, err := s.rl.WatchRuleSet(ctx, req.GetRuleName())
 	if err != nil {
-		return err
+		return common.HandleError(err)
 	}
 
-	log.Info("WatchRuleResources: 队列初始化成功", zap.Int("EventsNum", len(initialEvents)))
-
-	for _, evt := range initialEvents {
-		if err := stream.Send(evt); err != nil {
+	log.Info("WatchRuleResources: 队列初始化成功", zap.Int("EventsNum", len(initial)))
+	for _, evt := range initial {
+		resp, err := viewEventToResponse(evt)
+		if err != nil {
+			continue
+		}
+		if err := stream.Send(resp); err != nil {
 			log.Error("WatchRuleResources: 初次发送失败", zap.Error(err))
 			return err
 		}
-		log.Info("WatchRuleResources: 初始化发送成功")
 	}
+	log.Info("WatchRuleResources: 初始化发送成功")
 
-	// --- 进入循环，持续监听 eventCh，将事件流式返回给客户端，直到连接中断
+	// --- 进入循环，持续监听 events，将事件流式返回给客户端，直到连接中断
 	for {
 		select {
-		case <-stream.Context().Done():
+		case <-ctx.Done():
 			log.Info("WatchRuleResources: 客户端断开")
-			informer.GlobalInformerRegistry.Delete(prefix)
 			return nil
-		case event, ok := <-eventCh:
+		case evt, ok := <-events:
 			if !ok {
-				return nil
+				if ctx.Err() != nil {
+					return nil
+				}
+				// 跟不上变化的 watch 被关闭, agent 重新 watch 时会拿到完整的视图
+				return status.Error(codes.Unavailable, "rule watch fell behind, watch again")
+			}
+			resp, err := viewEventToResponse(evt)
+			if err != nil {
+				continue
 			}
-			if err := stream.Send(event); err != nil {
+			if err := stream.Send(resp); err != nil {
 				log.Error("WatchRuleResources: 发送事件失败", zap.Error(err))
 				return err
 			}
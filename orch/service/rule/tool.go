@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"strconv"
 	"xdp-banner/api/orch/v1/rule"
-	"xdp-banner/pkg/etcd"
+	ruleStorage "xdp-banner/orch/storage/agent/rule"
 	"xdp-banner/pkg/log"
 	model "xdp-banner/pkg/rule"
 
@@ -17,44 +17,34 @@ import (
 var ErrValItem = errors.New("rule val from refletor not string")
 var ErrSkipItem = errors.New("skip this item")
 
-func convertMapToWatchRuleResponses(
-	itemsMap map[string]any,
-	prefix string,
-) ([]*rule.WatchRuleResponse, error) {
-
-	var results []*rule.WatchRuleResponse
-
-	for key, val := range itemsMap {
-
-		if !hasPrefix(key, prefix) {
-			continue
-		}
-
-		ruleVal, err := parseRuleMeta(val)
-		switch {
-		case err == ErrSkipItem:
-			continue
-		case err == ErrValItem:
-			log.Warn("invalid rule val, skip", zap.String("key", key))
-			continue
-		case err != nil:
-			return nil, fmt.Errorf("parseRuleMeta failed for key %q: %w", key, err)
-		}
+// viewEventToResponse converts a change of the merged view of a rule set,
+// values which are not a rule meta are skipped
+func viewEventToResponse(evt ruleStorage.ViewEvent) (*rule.WatchRuleResponse, error) {
+	ruleVal, err := parseRuleMeta(evt.Value)
+	switch {
+	case err == ErrSkipItem:
+		return nil, err
+	case err == ErrValItem:
+		log.Warn("invalid rule val, skip", zap.String("key", evt.Key))
+		return nil, err
+	case err != nil:
+		return nil, fmt.Errorf("parseRuleMeta failed for key %q: %w", evt.Key, err)
+	}
 
-		valForGrpc, err := convertToStruct(*ruleVal)
-		if err != nil {
-			log.Error("event onAdd process went error", zap.Error(err))
-		}
+	valForGrpc, err := convertToStruct(*ruleVal)
+	if err != nil {
+		log.Error("convert rule val went error", zap.Error(err))
+	}
 
-		// 构造 WatchRuleResponse，对初次列出的数据可统一视为 “ADD” 或其它类型
-		results = append(results, &rule.WatchRuleResponse{
-			RuleKey:   key,
-			RuleVal:   valForGrpc,
-			EventType: 0,
-		})
-		log.Info("Send Rule key", zap.String("key", key))
+	eventType := rule.EventType_PUT
+	if evt.Deleted {
+		eventType = rule.EventType_DELETE
 	}
-	return results, nil
+	return &rule.WatchRuleResponse{
+		RuleKey:   evt.Key,
+		RuleVal:   valForGrpc,
+		EventType: eventType,
+	}, nil
 }
 
 func parseRuleMeta(val any) (*model.RuleMeta, error) {
@@ -97,110 +87,3 @@ func convertToStruct(obj any) (*structpb.Struct, error) {
 	}
 	return structpb.NewStruct(m)
 }
-
-// tempEventHandler 用于接收 informer 下发的事件，并写入 eventCh
-type tempEventHandler struct {
-	prefix string
-	ch     chan *rule.WatchRuleResponse
-}
-
-func (h *tempEventHandler) OnAdd(key etcd.Key, obj any, isInInitialList bool) {
-
-	log.Info("Processing key", zap.String("key", key))
-
-	if !hasPrefix(string(key), h.prefix) {
-		return
-	}
-
-	ruleVal, err := parseRuleMeta(obj)
-	switch {
-	case err == ErrSkipItem:
-		return
-	case err == ErrValItem:
-		log.Warn("invalid rule val, skip", zap.String("key", key))
-		return
-	case err != nil:
-		log.Error("event onAdd process went error", zap.Error(err))
-		return
-	}
-
-	valForGrpc, err := convertToStruct(*ruleVal)
-	if err != nil {
-		log.Error("event onAdd process went error", zap.Error(err))
-	}
-
-	h.ch <- &rule.WatchRuleResponse{
-		RuleKey:   string(key),
-		RuleVal:   valForGrpc,
-		EventType: 0,
-	}
-
-	log.Info("Send Rule key", zap.String("key", key))
-}
-
-func (h *tempEventHandler) OnUpdate(key etcd.Key, oldObj, newObj any) {
-	if !hasPrefix(string(key), h.prefix) {
-		return
-	}
-
-	ruleVal, err := parseRuleMeta(newObj)
-	switch {
-	case err == ErrSkipItem:
-		return
-	case err == ErrValItem:
-		log.Warn("invalid rule val, skip", zap.String("key", key))
-		return
-	case err != nil:
-		log.Error("event onUpdate process went error", zap.Error(err))
-		return
-	}
-
-	valForGrpc, err := convertToStruct(*ruleVal)
-	if err != nil {
-		log.Error("event onUpdate process went error", zap.Error(err))
-	}
-
-	h.ch <- &rule.WatchRuleResponse{
-		RuleKey:   string(key),
-		RuleVal:   valForGrpc,
-		EventType: 0,
-	}
-
-	log.Info("Send Rule key", zap.String("key", key))
-}
-
-func (h *tempEventHandler) OnDelete(key etcd.Key, obj any) {
-	if !hasPrefix(string(key), h.prefix) {
-		return
-	}
-
-	ruleVal, err := parseRuleMeta(obj)
-	switch {
-	case err == ErrSkipItem:
-		return
-	case err == ErrValItem:
-		log.Warn("invalid rule val, skip", zap.String("key", key))
-		return
-	case err != nil:
-		log.Error("event onDelete process went error", zap.Error(err))
-		return
-	}
-
-	valForGrpc, err := convertToStruct(*ruleVal)
-	if err != nil {
-		log.Error("event onDelete process went error", zap.Error(err))
-	}
-
-	h.ch <- &rule.WatchRuleResponse{
-		RuleKey:   string(key),
-		RuleVal:   valForGrpc,
-		EventType: 1,
-	}
-
-	log.Info("Delete Rule key", zap.String("key", key))
-}
-
-// hasPrefix 简单判断 key 是否以 prefix 开头
-func hasPrefix(key, prefix string) bool {
-	return len(key) >= len(prefix) && key[:len(prefix)] == prefix
-}
@@ -0,0 +1,99 @@
+package rulev2
+
+import (
+	"errors"
+	"io"
+
+	api "xdp-banner/api/orch/v2/rule"
+	"xdp-banner/orch/service/convert"
+	"xdp-banner/pkg/server/common"
+)
+
+// chunkSize is the size of the chunks ExportRules sends
+const chunkSize = 32 * 1024
+
+func (s *RuleService) ImportRules(stream api.RuleService_ImportRulesServer) error {
+	req, err := stream.Recv()
+	if err == io.EOF {
+		return common.InvalidArgumentError("header is required")
+	}
+	if err != nil {
+		return err
+	}
+
+	header := req.GetHeader()
+	if header == nil {
+		return common.InvalidArgumentError("the first message must be the header")
+	}
+	if header.Name == "" {
+		return common.InvalidArgumentError("name is required")
+	}
+
+	format, defaults, err := convert.ImportHeaderV2ToModel(header)
+	if err != nil {
+		return common.HandleError(err)
+	}
+
+	report, err := s.rl.ImportRules(stream.Context(), header.Name, &uploadReader{stream: stream}, format, defaults, header.Aggregate)
+	if err != nil {
+		return common.HandleError(err)
+	}
+
+	return stream.SendAndClose(convert.ImportReportToV2Dto(report))
+}
+
+func (s *RuleService) ExportRules(r *api.ExportRulesRequest, stream api.RuleService_ExportRulesServer) error {
+	if r.Name == "" {
+		return common.InvalidArgumentError("name is required")
+	}
+
+	format, err := convert.FormatV2ToModel(r.Format)
+	if err != nil {
+		return common.HandleError(err)
+	}
+
+	w := &chunkWriter{stream: stream}
+	if err := s.rl.ExportRules(stream.Context(), r.Name, w, format); err != nil {
+		return common.HandleError(err)
+	}
+	return nil
+}
+
+// uploadReader reads the chunks following the header of an ImportRules stream
+type uploadReader struct {
+	stream api.RuleService_ImportRulesServer
+	buf    []byte
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	for len(u.buf) == 0 {
+		req, err := u.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		if req.GetHeader() != nil {
+			return 0, errors.New("header is only allowed in the first message")
+		}
+		u.buf = req.GetChunk()
+	}
+
+	n := copy(p, u.buf)
+	u.buf = u.buf[n:]
+	return n, nil
+}
+
+// chunkWriter sends what is written as ExportRules chunks
+type chunkWriter struct {
+	stream api.RuleService_ExportRulesServer
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	for written := 0; written < len(p); {
+		n := min(len(p)-written, chunkSize)
+		if err := c.stream.Send(&api.ExportRulesResponse{Chunk: p[written : written+n]}); err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return len(p), nil
+}
@@ -0,0 +1,191 @@
+package rulev2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	api "xdp-banner/api/orch/v2/rule"
+	"xdp-banner/orch/logic/rulecenter/bulk"
+	"xdp-banner/orch/service/convert"
+	"xdp-banner/pkg/rule"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	importPath = "/v2/rulesets/{name}/rules:import"
+	exportPath = "/v2/rulesets/{name}/rules:export"
+)
+
+// registerBulkHandlers serves ImportRules and ExportRules over http with the
+// raw upload and download as the body, grpc-gateway would wrap the chunks in
+// json. The handlers call the grpc service through conn like the generated ones.
+func registerBulkHandlers(mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	client := api.NewRuleServiceClient(conn)
+
+	if err := mux.HandlePath(http.MethodPost, importPath, func(w http.ResponseWriter, req *http.Request, params map[string]string) {
+		handleImport(mux, client, w, req, params)
+	}); err != nil {
+		return err
+	}
+	return mux.HandlePath(http.MethodGet, exportPath, func(w http.ResponseWriter, req *http.Request, params map[string]string) {
+		handleExport(mux, client, w, req, params)
+	})
+}
+
+// handleImport uploads the body, the query sets format, protocol, duration
+// and comment. The format defaults to the one of the Content-Type, e.g.
+//
+//	curl -X POST -H 'Content-Type: text/plain' --data-binary @blocklist.txt \
+//	  'http://localhost:6062/v2/rulesets/default/rules:import?protocol=TCP&duration=1d'
+func handleImport(mux *runtime.ServeMux, client api.RuleServiceClient, w http.ResponseWriter, req *http.Request, params map[string]string) {
+	_, outbound := runtime.MarshalerForRequest(mux, req)
+	ctx, err := runtime.AnnotateContext(req.Context(), mux, req, "/rule.v2.RuleService/ImportRules", runtime.WithHTTPPathPattern(importPath))
+	if err != nil {
+		runtime.HTTPError(req.Context(), mux, outbound, w, req, err)
+		return
+	}
+	ctx = runtime.NewServerMetadataContext(ctx, runtime.ServerMetadata{})
+
+	header, err := importHeader(req, params["name"])
+	if err != nil {
+		runtime.HTTPError(ctx, mux, outbound, w, req, status.Error(codes.InvalidArgument, err.Error()))
+		return
+	}
+
+	resp, err := upload(ctx, client, header, req.Body)
+	if err != nil {
+		runtime.HTTPError(ctx, mux, outbound, w, req, err)
+		return
+	}
+
+	runtime.ForwardResponseMessage(ctx, mux, outbound, w, req, resp, mux.GetForwardResponseOptions()...)
+}
+
+func importHeader(req *http.Request, name string) (*api.ImportRulesHeader, error) {
+	query := req.URL.Query()
+	header := &api.ImportRulesHeader{Name: name, Comment: query.Get("comment")}
+
+	format, err := bulk.ParseFormat(query.Get("format"))
+	if err != nil {
+		return nil, err
+	}
+	if query.Get("format") == "" {
+		if f, ok := bulk.FormatFromContentType(req.Header.Get("Content-Type")); ok {
+			format = f
+		}
+	}
+	header.Format = convert.FormatToV2Dto(format)
+
+	if p := query.Get("protocol"); p != "" {
+		protocol, ok := api.Protocol_value["PROTOCOL_"+strings.ToUpper(p)]
+		if !ok {
+			return nil, fmt.Errorf("unknown protocol %q, use one of TCP, UDP and ICMP", p)
+		}
+		header.Protocol = api.Protocol(protocol)
+	}
+
+	if d := query.Get("duration"); d != "" {
+		duration, err := rule.ParseRuleDuration(d)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration: %w", err)
+		}
+		header.Duration = durationpb.New(duration)
+	}
+
+	if a := query.Get("aggregate"); a != "" {
+		aggregate, err := strconv.ParseBool(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid aggregate: %w", err)
+		}
+		header.Aggregate = aggregate
+	}
+
+	return header, nil
+}
+
+// upload streams body to ImportRules after the header
+func upload(ctx context.Context, client api.RuleServiceClient, header *api.ImportRulesHeader, body io.Reader) (*api.ImportRulesResponse, error) {
+	stream, err := client.ImportRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = stream.Send(&api.ImportRulesRequest{Payload: &api.ImportRulesRequest_Header{Header: header}})
+	buf := make([]byte, chunkSize)
+	for err == nil {
+		var n int
+		n, err = body.Read(buf)
+		if n > 0 {
+			if sendErr := stream.Send(&api.ImportRulesRequest{Payload: &api.ImportRulesRequest_Chunk{Chunk: buf[:n]}}); sendErr != nil {
+				err = sendErr
+			}
+		}
+	}
+	// Send 返回 io.EOF 时服务端已经结束, 真正的错误由 CloseAndRecv 返回
+	if err != io.EOF {
+		return nil, status.Errorf(codes.InvalidArgument, "read upload failed: %v", err)
+	}
+
+	return stream.CloseAndRecv()
+}
+
+// handleExport downloads a rule set, the query sets the format
+func handleExport(mux *runtime.ServeMux, client api.RuleServiceClient, w http.ResponseWriter, req *http.Request, params map[string]string) {
+	_, outbound := runtime.MarshalerForRequest(mux, req)
+	ctx, err := runtime.AnnotateContext(req.Context(), mux, req, "/rule.v2.RuleService/ExportRules", runtime.WithHTTPPathPattern(exportPath))
+	if err != nil {
+		runtime.HTTPError(req.Context(), mux, outbound, w, req, err)
+		return
+	}
+	ctx = runtime.NewServerMetadataContext(ctx, runtime.ServerMetadata{})
+
+	format, err := bulk.ParseFormat(req.URL.Query().Get("format"))
+	if err != nil {
+		runtime.HTTPError(ctx, mux, outbound, w, req, status.Error(codes.InvalidArgument, err.Error()))
+		return
+	}
+
+	stream, err := client.ExportRules(ctx, &api.ExportRulesRequest{Name: params["name"], Format: convert.FormatToV2Dto(format)})
+	if err != nil {
+		runtime.HTTPError(ctx, mux, outbound, w, req, err)
+		return
+	}
+
+	started := false
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			if !started {
+				w.Header().Set("Content-Type", format.ContentType())
+				w.WriteHeader(http.StatusOK)
+			}
+			return
+		}
+		if err != nil {
+			if !started {
+				runtime.HTTPError(ctx, mux, outbound, w, req, err)
+				return
+			}
+			// 已经开始写 body, 中断连接让客户端知道下载不完整
+			panic(http.ErrAbortHandler)
+		}
+
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", format.ContentType())
+		}
+		if _, err := w.Write(resp.Chunk); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,464 @@
+// Package rulev2 serves the typed rule api next to the v1 RuleService
+package rulev2
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	api "xdp-banner/api/orch/v2/rule"
+	ruleLogic "xdp-banner/orch/logic/rulecenter"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/orch/service/convert"
+	"xdp-banner/pkg/datapath"
+	"xdp-banner/pkg/server/common"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type RuleService struct {
+	api.UnimplementedRuleServiceServer
+
+	rl *ruleLogic.RuleCenter
+}
+
+func NewRuleService(rl *ruleLogic.RuleCenter) *RuleService {
+	return &RuleService{rl: rl}
+}
+
+func (s *RuleService) RegisterGrpcService(gs grpc.ServiceRegistrar) {
+	api.RegisterRuleServiceServer(gs, s)
+}
+
+func (s *RuleService) PublicGrpcMethods() []string {
+	return nil
+}
+
+func (s *RuleService) RegisterHttpService(ctx context.Context, mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	if err := api.RegisterRuleServiceHandler(ctx, mux, conn); err != nil {
+		return err
+	}
+	return registerBulkHandlers(mux, conn)
+}
+
+func (s *RuleService) AddRule(ctx context.Context, r *api.AddRuleRequest) (*emptypb.Empty, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+
+	ruleModel, err := convert.RuleV2DtoToModel(r.Rule)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	err = s.rl.AddRule(ctx, r.Name, ruleModel)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return nil, nil
+}
+
+func (s *RuleService) DeleteRule(ctx context.Context, r *api.DeleteRuleRequest) (*emptypb.Empty, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+
+	info, err := convert.RuleMatchV2ToModel(r.Match)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	err = s.rl.DeleteRule(ctx, r.Name, &model.Rule{RuleInfo: info})
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return nil, nil
+}
+
+func (s *RuleService) UpdateRule(ctx context.Context, r *api.UpdateRuleRequest) (*emptypb.Empty, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+
+	ruleModel, err := convert.RuleV2DtoToModel(r.Rule)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	err = s.rl.UpdateRule(ctx, r.Name, ruleModel)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return nil, nil
+}
+
+func (s *RuleService) GetRule(ctx context.Context, r *api.GetRuleRequest) (*api.RuleSet, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+
+	rules, err := s.rl.GetRule(ctx, r.Name)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.RuleSetToV2Dto(r.Name, rules), nil
+}
+
+func (s *RuleService) ListRule(ctx context.Context, r *api.ListRuleRequest) (*api.ListRuleResponse, error) {
+	if r.Pagesize <= 0 {
+		return nil, common.InvalidArgumentError("page size must be greater than 0")
+	}
+
+	rl, err := s.rl.ListRule(ctx, r.Pagesize, r.Cursor)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return &api.ListRuleResponse{
+		Total:       rl.TotalCount,
+		TotalPage:   rl.TotalPage,
+		CurrentPage: rl.CurrentPage,
+		HasNext:     rl.HasNext,
+		NextCursor:  rl.NextCursor,
+		RuleSets:    convert.RuleListToV2Dto(rl.Items),
+	}, nil
+}
+
+func (s *RuleService) DeleteRulesByKey(ctx context.Context, r *api.DeleteRulesByKeyRequest) (*api.DeleteRulesResponse, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+
+	removed, notFound, err := s.rl.DeleteRulesByKey(ctx, r.Name, r.Keys)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.RemovedToV2Dto(removed, notFound), nil
+}
+
+func (s *RuleService) DeleteRulesBySelector(ctx context.Context, r *api.DeleteRulesBySelectorRequest) (*api.DeleteRulesResponse, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+
+	sel, err := convert.RuleSelectorV2ToModel(r.Selector)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	removed, err := s.rl.DeleteRulesBySelector(ctx, r.Name, sel)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.RemovedToV2Dto(removed, nil), nil
+}
+
+func (s *RuleService) DeleteRuleSet(ctx context.Context, r *api.DeleteRuleSetRequest) (*api.DeleteRulesResponse, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+
+	removed, err := s.rl.DeleteRuleSet(ctx, r.Name)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.RemovedToV2Dto(removed, nil), nil
+}
+
+func (s *RuleService) ExtendRule(ctx context.Context, r *api.ExtendRuleRequest) (*api.Rule, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+	if r.Duration == nil {
+		return nil, common.InvalidArgumentError("duration is required, 0s makes the rule permanent")
+	}
+
+	info, err := convert.RuleMatchV2ToModel(r.Match)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+	d, err := convert.DurationV2ToModel(r.Duration)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	extended, err := s.rl.ExtendRule(ctx, r.Name, info, d)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.RuleModelToV2Dto(extended), nil
+}
+
+func (s *RuleService) SearchRules(ctx context.Context, r *api.SearchRulesRequest) (*api.SearchRulesResponse, error) {
+	q, err := convert.SearchRequestV2ToModel(r)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	matches, truncated, err := s.rl.SearchRules(ctx, q)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.SearchResultsToV2Dto(matches, truncated), nil
+}
+
+func (s *RuleService) ListVersions(ctx context.Context, r *api.ListVersionsRequest) (*api.ListVersionsResponse, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+	if r.Before < 0 || r.PageSize < 0 {
+		return nil, common.InvalidArgumentError("before and page size must not be negative")
+	}
+
+	list, err := s.rl.ListVersions(ctx, r.Name, r.Before, r.PageSize)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.VersionListToV2Dto(list), nil
+}
+
+func (s *RuleService) DiffVersions(ctx context.Context, r *api.DiffVersionsRequest) (*api.DiffVersionsResponse, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+
+	diff, err := s.rl.DiffVersions(ctx, r.Name, r.From, r.To)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.DiffToV2Dto(diff), nil
+}
+
+func (s *RuleService) RollbackRuleSet(ctx context.Context, r *api.RollbackRuleSetRequest) (*api.Version, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+
+	change, err := s.rl.Rollback(ctx, r.Name, r.Version)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.ChangeToV2Dto(change), nil
+}
+
+func (s *RuleService) GetIncludes(ctx context.Context, r *api.GetIncludesRequest) (*api.Includes, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+
+	includes, err := s.rl.GetIncludes(ctx, r.Name)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return &api.Includes{Name: r.Name, Includes: includes}, nil
+}
+
+func (s *RuleService) SetIncludes(ctx context.Context, r *api.Includes) (*api.Includes, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+
+	includes, err := s.rl.SetIncludes(ctx, r.Name, r.Includes)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return &api.Includes{Name: r.Name, Includes: includes}, nil
+}
+
+func (s *RuleService) GetEffectiveRuleSet(ctx context.Context, r *api.GetEffectiveRuleSetRequest) (*api.EffectiveRuleSet, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+
+	sets, rules, err := s.rl.EffectiveRules(ctx, r.Name)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.EffectiveRuleSetToV2Dto(r.Name, sets, rules), nil
+}
+
+func (s *RuleService) CheckIdentities(ctx context.Context, r *api.CheckIdentitiesRequest) (*api.CheckIdentitiesResponse, error) {
+	report, err := s.rl.CheckIdentities(ctx, r.Repair)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.IdentityReportToV2Dto(report), nil
+}
+
+func (s *RuleService) PreviewAggregation(ctx context.Context, r *api.PreviewAggregationRequest) (*api.AggregationPreview, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+
+	preview, err := s.rl.PreviewAggregation(ctx, r.Name)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.AggregationPreviewToV2Dto(r.Name, preview), nil
+}
+
+func (s *RuleService) AnalyzeRuleSet(ctx context.Context, r *api.AnalyzeRuleSetRequest) (*api.Analysis, error) {
+	var analysis *model.Analysis
+	var err error
+	switch {
+	case r.Name != "":
+		analysis, err = s.rl.AnalyzeRuleSet(ctx, r.Name, r.Effective)
+	case r.Agent != "":
+		analysis, err = s.rl.AnalyzeAgent(ctx, r.Agent)
+	default:
+		return nil, common.InvalidArgumentError("name or agent is required")
+	}
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.AnalysisToV2Dto(analysis), nil
+}
+
+func (s *RuleService) Explain(ctx context.Context, r *api.ExplainRequest) (*api.Explanation, error) {
+	packet, err := parsePacket(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var explanation *model.Explanation
+	switch {
+	case r.Name != "":
+		explanation, err = s.rl.Explain(ctx, r.Name, packet)
+	case r.Agent != "":
+		explanation, err = s.rl.ExplainAgent(ctx, r.Agent, packet)
+	default:
+		return nil, common.InvalidArgumentError("name or agent is required")
+	}
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.ExplanationToV2Dto(explanation), nil
+}
+
+func (s *RuleService) AddScheduledRule(ctx context.Context, r *api.AddScheduledRuleRequest) (*api.ScheduledRule, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+
+	sr, err := convert.ScheduledRuleV2ToModel(r)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	if err := s.rl.AddScheduledRule(ctx, sr); err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.ScheduledRuleToV2Dto(sr), nil
+}
+
+func (s *RuleService) DeleteScheduledRule(ctx context.Context, r *api.DeleteScheduledRuleRequest) (*emptypb.Empty, error) {
+	if r.Name == "" {
+		return nil, common.InvalidArgumentError("name is required")
+	}
+
+	info, err := convert.RuleMatchV2ToModel(r.Match)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	if err := s.rl.DeleteScheduledRule(ctx, r.Name, info.Key()); err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *RuleService) ListScheduledRules(ctx context.Context, r *api.ListScheduledRulesRequest) (*api.ListScheduledRulesResponse, error) {
+	rules, err := s.rl.ListScheduledRules(ctx, r.Name)
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.ScheduledRulesToV2Dto(rules), nil
+}
+
+func (s *RuleService) ListActivations(ctx context.Context, r *api.ListActivationsRequest) (*api.ListActivationsResponse, error) {
+	var within time.Duration
+	if r.Within != nil {
+		if err := r.Within.CheckValid(); err != nil {
+			return nil, common.InvalidArgumentError(fmt.Sprintf("invalid within: %v", err))
+		}
+		within = r.Within.AsDuration()
+	}
+	if within < 0 || r.Limit < 0 {
+		return nil, common.InvalidArgumentError("within and limit must not be negative")
+	}
+
+	activations, err := s.rl.ListActivations(ctx, r.Name, within, int(r.Limit))
+	if err != nil {
+		return nil, common.HandleError(err)
+	}
+
+	return convert.ActivationsToV2Dto(activations), nil
+}
+
+func parsePacket(r *api.ExplainRequest) (datapath.Packet, error) {
+	src, err := netip.ParseAddr(r.Src)
+	if err != nil {
+		return datapath.Packet{}, common.InvalidArgumentError(fmt.Sprintf("invalid src %q", r.Src))
+	}
+	src = src.Unmap()
+	if r.Dst != "" {
+		dst, err := netip.ParseAddr(r.Dst)
+		if err != nil {
+			return datapath.Packet{}, common.InvalidArgumentError(fmt.Sprintf("invalid dst %q", r.Dst))
+		}
+		if dst.Unmap().Is4() != src.Is4() {
+			return datapath.Packet{}, common.InvalidArgumentError("src and dst are of different families")
+		}
+	}
+	if r.Sport > math.MaxUint16 || r.Dport > math.MaxUint16 {
+		return datapath.Packet{}, common.InvalidArgumentError(fmt.Sprintf("ports must be at most %d", math.MaxUint16))
+	}
+
+	var protocol uint8
+	switch strings.ToLower(r.Protocol) {
+	case "tcp":
+		protocol = datapath.ProtoTCP
+	case "udp":
+		protocol = datapath.ProtoUDP
+	case "icmp":
+		protocol = datapath.ProtoICMP
+	case "icmpv6":
+		protocol = datapath.ProtoICMPv6
+	default:
+		n, err := strconv.ParseUint(r.Protocol, 10, 8)
+		if err != nil {
+			return datapath.Packet{}, common.InvalidArgumentError(fmt.Sprintf("invalid protocol %q", r.Protocol))
+		}
+		protocol = uint8(n)
+	}
+
+	return datapath.Packet{Src: src, Protocol: protocol, Sport: uint16(r.Sport), Dport: uint16(r.Dport)}, nil
+}
@@ -6,7 +6,9 @@ import (
 	"xdp-banner/orch/service/agent/report"
 	"xdp-banner/orch/service/auth"
 	"xdp-banner/orch/service/orch"
+	"xdp-banner/orch/service/protect"
 	"xdp-banner/orch/service/rule"
+	"xdp-banner/orch/service/rulev2"
 	"xdp-banner/orch/service/strategy"
 	"xdp-banner/pkg/server"
 )
@@ -15,16 +17,20 @@ func New(logic *logic.Logic) server.Services {
 	control := control.NewControlService(logic.Control)
 	report := report.NewReportService(logic.Report)
 	rule := rule.NewRuleService(logic.ConfigCenter)
+	rulev2 := rulev2.NewRuleService(logic.ConfigCenter)
 	orch := orch.NewOrchService(logic.Orch)
 	auth := auth.New()
 	strategy := strategy.New(logic.Strategy, logic.Applied)
+	protect := protect.New(logic.Protect)
 
 	return map[string]server.Service{
 		"control":  control,
 		"rule":     rule,
+		"rulev2":   rulev2,
 		"report":   report,
 		"orch":     orch,
 		"auth":     auth,
 		"strategy": strategy,
+		"protect":  protect,
 	}
 }
@@ -7,6 +7,8 @@ import (
 	"xdp-banner/orch/model/node"
 
 	"xdp-banner/pkg/etcd"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 var (
@@ -14,8 +16,13 @@ var (
 
 	ErrInfoExist    error = fmt.Errorf("agent info already exist with provided name")
 	ErrInfoNotFound error = fmt.Errorf("agent info not found with provided name")
+	ErrInfoConflict error = fmt.Errorf("agent info keeps changing, give up updating it")
 )
 
+// maxModifyRetries bounds the retries of Modify when the info changes between
+// its read and its write
+const maxModifyRetries = 5
+
 // InfoStorage store node info in etcd
 type InfoStorage struct {
 	client etcd.Client
@@ -64,6 +71,44 @@ func (s InfoStorage) Update(ctx context.Context, info *node.AgentInfo) error {
 	return nil
 }
 
+// Modify reads the info of an agent, applies modify and writes it back only if
+// the info did not change in between, it reads again otherwise. modify returns
+// false when nothing needs to be written.
+func (s InfoStorage) Modify(ctx context.Context, name string, modify func(info *node.AgentInfo) bool) error {
+	key := InfoKey(name)
+	for range maxModifyRetries {
+		resp, err := s.client.GetMustExist(ctx, key)
+		if err != nil {
+			if err == etcd.ErrKeyNotFound {
+				return ErrInfoNotFound
+			}
+			return err
+		}
+
+		info := &node.AgentInfo{}
+		if err := info.Unmarshal(resp.Kvs[0].Value); err != nil {
+			return err
+		}
+		if !modify(info) {
+			return nil
+		}
+
+		txn, cancel := s.client.Txn(ctx)
+		txnResp, err := txn.If(clientv3.Compare(clientv3.ModRevision(key), "=", resp.Kvs[0].ModRevision)).
+			Then(clientv3.OpPut(key, info.MarshalStr())).
+			Commit()
+		cancel()
+		if err != nil {
+			return err
+		}
+		if txnResp.Succeeded {
+			return nil
+		}
+	}
+
+	return ErrInfoConflict
+}
+
 func (s InfoStorage) Get(ctx context.Context, name string) (*node.AgentInfo, error) {
 	key := InfoKey(name)
 	resp, err := s.client.GetMustExist(ctx, key)
@@ -0,0 +1,60 @@
+package protect
+
+import (
+	"context"
+	"sync/atomic"
+	orchnode "xdp-banner/orch/storage/orch/node"
+	"xdp-banner/pkg/etcd"
+	"xdp-banner/pkg/informer"
+)
+
+// Changes counts the changes of the protected ranges and of the orchestrator
+// infos whose endpoints are protected, what is derived from them is stale once
+// the generation moves. It is kept up to date by an informer on both prefixes.
+type Changes struct {
+	// synced reports whether the informer has listed both prefixes
+	synced func() bool
+
+	generation atomic.Uint64
+}
+
+// NewChanges starts an informer counting the changes, it stops with ctx
+func NewChanges(ctx context.Context, client etcd.Client) *Changes {
+	// 两个目录都没有结尾的 "/", 避免匹配到同名前缀的其它目录
+	prefixes := []string{EtcdDir + "/", orchnode.EtcdDirInfo + "/"}
+
+	deltaFIFO := informer.NewDeltaFIFOWithWait(len(prefixes))
+	i := informer.New(deltaFIFO)
+
+	c := &Changes{synced: i.HasSynced}
+	i.Register(prefixes, c)
+
+	for _, prefix := range prefixes {
+		reflector := informer.NewReflector(client, "protect_changes_reflector", prefix, deltaFIFO)
+		go reflector.Run(ctx)
+	}
+	go i.Run(ctx)
+
+	return c
+}
+
+// Generation returns the number of changes seen, false before the informer
+// has synced, nothing derived then can be kept
+func (c *Changes) Generation() (uint64, bool) {
+	if !c.synced() {
+		return 0, false
+	}
+	return c.generation.Load(), true
+}
+
+func (c *Changes) OnAdd(etcd.Key, any, bool) {
+	c.generation.Add(1)
+}
+
+func (c *Changes) OnUpdate(etcd.Key, any, any) {
+	c.generation.Add(1)
+}
+
+func (c *Changes) OnDelete(etcd.Key, any) {
+	c.generation.Add(1)
+}
@@ -0,0 +1,125 @@
+package protect
+
+import (
+	"context"
+	"errors"
+	"xdp-banner/orch/model/common"
+	model "xdp-banner/orch/model/protect"
+	"xdp-banner/orch/storage/agent"
+	"xdp-banner/pkg/etcd"
+)
+
+var (
+	EtcdDir etcd.Key = etcd.Join(agent.EtcdDir, "protect/")
+)
+
+var (
+	// ErrRangeNotFound is returned when the protected range is not found.
+	ErrRangeNotFound = errors.New("protected range not found")
+	// ErrRangeAlreadyExists is returned when the protected range already exists.
+	ErrRangeAlreadyExists = errors.New("protected range already exists")
+)
+
+func RangeKey(name string) etcd.Key {
+	return etcd.Join(EtcdDir, name)
+}
+
+// Storage 提供对 etcd 的操作
+type Storage struct {
+	client etcd.Client
+}
+
+func New(client etcd.Client) Storage {
+	return Storage{client: client}
+}
+
+// Add creates a new protected range in etcd.
+func (s Storage) Add(ctx context.Context, r *model.ProtectedRange) error {
+	err := s.client.Create(ctx, RangeKey(r.Name), r.MarshalStr())
+	if err == etcd.ErrKeyExist {
+		return ErrRangeAlreadyExists
+	}
+
+	return err
+}
+
+// Delete deletes a protected range from etcd.
+func (s Storage) Delete(ctx context.Context, name string) error {
+	resp, err := s.client.Delete(ctx, RangeKey(name))
+	if err != nil {
+		return err
+	}
+	if resp.Deleted == 0 {
+		return ErrRangeNotFound
+	}
+
+	return nil
+}
+
+// Get retrieves a protected range from etcd.
+func (s Storage) Get(ctx context.Context, name string) (*model.ProtectedRange, error) {
+	raw, err := s.client.GetMustExist(ctx, RangeKey(name))
+	if err != nil {
+		if err == etcd.ErrKeyNotFound {
+			return nil, ErrRangeNotFound
+		}
+		return nil, err
+	}
+
+	r := new(model.ProtectedRange)
+	if err := r.Unmarshal(raw.Kvs[0].Value); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s Storage) List(ctx context.Context, size int64, nextCursor string) (model.ProtectedRangeList, error) {
+	raw, err := s.client.List(ctx, etcd.ListOption{
+		Prefix: EtcdDir,
+		Size:   size,
+		Cursor: nextCursor,
+	})
+	if err != nil {
+		return model.ProtectedRangeList{}, err
+	}
+
+	ranges := make(model.ProtectedRangeItems)
+	for key, v := range raw.Items.Iterator() {
+		r := new(model.ProtectedRange)
+		if err := r.UnmarshalStr(v.(string)); err != nil {
+			return model.ProtectedRangeList{}, err
+		}
+		ranges[etcd.Base(key)] = r
+	}
+
+	return model.ProtectedRangeList{
+		List: common.List{
+			TotalCount:  raw.TotalCount,
+			TotalPage:   raw.TotalPage,
+			CurrentPage: raw.CurrentPage,
+			HasNext:     raw.More(),
+			NextCursor:  raw.NextCursor,
+		},
+		Items: ranges,
+	}, nil
+}
+
+// All returns every protected range.
+func (s Storage) All(ctx context.Context) ([]*model.ProtectedRange, error) {
+	var result []*model.ProtectedRange
+
+	cursor := ""
+	for {
+		list, err := s.List(ctx, 500, cursor)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range list.Items {
+			result = append(result, r)
+		}
+		if !list.HasNext {
+			return result, nil
+		}
+		cursor = list.NextCursor
+	}
+}
@@ -0,0 +1,322 @@
+package rule
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/etcd"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// BatchSize is the most rules AddBatch writes in one transaction, every rule
+// puts its key, its identity key and the registration of its identity, the
+// name key, the identity counter and the history take the rest
+const BatchSize = (maxTxnOps - 1 - allocateOps - historyOps) / 3
+
+// batchState is what the keys of a batch look like at a revision
+type batchState struct {
+	revision int64
+	// rules are the rule keys of the CIDRs of the batch already in etcd
+	rules map[etcd.Key]bool
+	// identities are the identity keys in etcd, a missing key is absent
+	identities map[etcd.Key]*mvccpb.KeyValue
+	// leases are the leases the identity keys need for the rules in etcd
+	leases map[etcd.Key]*identityLease
+
+	// nameRevision is the CreateRevision of the name key, 0 when it is missing
+	nameRevision int64
+}
+
+// AddBatch adds the rules to a rule set in one transaction. The result has the
+// error of every rule, nil once it is added and ErrRuleAlreadyExists for a rule
+// in etcd or earlier in the batch. The returned error means nothing was added.
+func (s Storage) AddBatch(ctx context.Context, name string, rules []*model.Rule) ([]error, error) {
+	return s.addBatch(ctx, name, rules, false)
+}
+
+// AddAll adds the rules to a rule set in one transaction, all of them or none.
+// ErrRuleAlreadyExists is returned and nothing is added when one of them is
+// in etcd or earlier in the rules.
+func (s Storage) AddAll(ctx context.Context, name string, rules []*model.Rule) error {
+	errs, err := s.addBatch(ctx, name, rules, true)
+	if err != nil {
+		return err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addBatch is AddBatch, with all nothing is written when a rule can not be added
+func (s Storage) addBatch(ctx context.Context, name string, rules []*model.Rule, all bool) ([]error, error) {
+	if len(rules) > BatchSize {
+		return nil, ErrTooManyRules
+	}
+
+	operationLock.Lock()
+	defer operationLock.Unlock()
+
+	for range txnRetries {
+		state, err := s.readBatch(ctx, name, rules)
+		if err != nil {
+			return nil, err
+		}
+
+		h, err := s.readHead(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		errs := batchErrors(name, rules, state)
+		if all && slices.ContainsFunc(errs, func(err error) bool { return err != nil }) {
+			return errs, nil
+		}
+		leases, err := s.grantBatch(ctx, rules, errs)
+		if err != nil {
+			return nil, err
+		}
+
+		alloc, err := s.allocate(ctx, newIdentities(name, rules, errs, state))
+		if err != nil {
+			s.revokeBatch(ctx, leases)
+			return nil, err
+		}
+
+		cmps, ops, err := planAdd(name, rules, errs, state, leases, alloc.identity)
+		if err != nil {
+			s.revokeBatch(ctx, leases)
+			return nil, err
+		}
+		if len(ops) == 0 {
+			return errs, nil
+		}
+		acmps, aops := alloc.ops()
+		cmps, ops = append(cmps, acmps...), append(ops, aops...)
+
+		change := &model.Change{Op: model.OpAdd}
+		for i, r := range rules {
+			if errs[i] == nil {
+				change.Added = append(change.Added, *r)
+			}
+		}
+		hcmps, hops, err := s.record(ctx, name, h, change)
+		if err != nil {
+			s.revokeBatch(ctx, leases)
+			return nil, err
+		}
+		cmps, ops = append(cmps, hcmps...), append(ops, hops...)
+
+		txn, cancel := s.client.Txn(ctx)
+		resp, err := txn.If(cmps...).Then(ops...).Commit()
+		cancel()
+		if err != nil {
+			s.revokeBatch(ctx, leases)
+			return nil, fmt.Errorf("etcd transaction failed: %w", err)
+		}
+		if resp.Succeeded {
+			return errs, nil
+		}
+		s.revokeBatch(ctx, leases)
+	}
+
+	return nil, ErrConflict
+}
+
+// readBatch reads the identity keys of the CIDRs of a batch with their rules
+// and the name key of the rule set in one transaction
+func (s Storage) readBatch(ctx context.Context, name string, rules []*model.Rule) (*batchState, error) {
+	var idKeys []etcd.Key
+	for _, r := range rules {
+		idKeys = append(idKeys, RuleKey(name, r.RuleInfo.IdentityKey()))
+	}
+	slices.Sort(idKeys)
+	idKeys = slices.Compact(idKeys)
+
+	ops := make([]clientv3.Op, 0, 2*len(idKeys)+1)
+	for _, idKey := range idKeys {
+		ops = append(ops, clientv3.OpGet(idKey), clientv3.OpGet(idKey+"/", clientv3.WithPrefix()))
+	}
+	ops = append(ops, clientv3.OpGet(nameKey(name)))
+
+	txn, cancel := s.client.Txn(ctx)
+	resp, err := txn.Then(ops...).Commit()
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules from etcd: %w", err)
+	}
+
+	state := &batchState{
+		revision:   resp.Header.Revision,
+		rules:      make(map[etcd.Key]bool),
+		identities: make(map[etcd.Key]*mvccpb.KeyValue),
+		leases:     make(map[etcd.Key]*identityLease),
+	}
+	for i, idKey := range idKeys {
+		if kvs := resp.Responses[2*i].GetResponseRange().Kvs; len(kvs) > 0 {
+			state.identities[idKey] = kvs[0]
+		}
+		for _, kv := range resp.Responses[2*i+1].GetResponseRange().Kvs {
+			state.rules[string(kv.Key)] = true
+			if state.leases[idKey] == nil {
+				state.leases[idKey] = &identityLease{}
+			}
+			if err := state.leases[idKey].addKV(kv); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if kvs := resp.Responses[len(ops)-1].GetResponseRange().Kvs; len(kvs) > 0 {
+		state.nameRevision = kvs[0].CreateRevision
+	}
+
+	return state, nil
+}
+
+// batchErrors reports the rules already in etcd or earlier in the batch
+func batchErrors(name string, rules []*model.Rule, state *batchState) []error {
+	errs := make([]error, len(rules))
+	seen := make(map[etcd.Key]bool, len(rules))
+	for i, r := range rules {
+		key := RuleKey(name, r.RuleInfo.Key())
+		if state.rules[key] || seen[key] {
+			errs[i] = ErrRuleAlreadyExists
+		}
+		seen[key] = true
+	}
+	return errs
+}
+
+// grantBatch grants a lease per expiry of the rules to add
+func (s Storage) grantBatch(ctx context.Context, rules []*model.Rule, errs []error) (map[time.Time]clientv3.LeaseID, error) {
+	leases := make(map[time.Time]clientv3.LeaseID)
+	for i, r := range rules {
+		expiresAt := r.RuleMeta.ExpiresAt
+		if errs[i] != nil || expiresAt.IsZero() {
+			continue
+		}
+		if _, ok := leases[expiresAt]; ok {
+			continue
+		}
+
+		id, _, err := s.grantUntil(ctx, expiresAt)
+		if err != nil {
+			s.revokeBatch(ctx, leases)
+			return nil, err
+		}
+		leases[expiresAt] = id
+	}
+	return leases, nil
+}
+
+func (s Storage) revokeBatch(ctx context.Context, leases map[time.Time]clientv3.LeaseID) {
+	for _, id := range leases {
+		s.revoke(ctx, id)
+	}
+}
+
+// newIdentities counts the identity keys planAdd creates
+func newIdentities(name string, rules []*model.Rule, errs []error, state *batchState) int {
+	created := make(map[etcd.Key]bool)
+	for i, r := range rules {
+		idKey := RuleKey(name, r.RuleInfo.IdentityKey())
+		if kv := state.identities[idKey]; errs[i] == nil && (kv == nil || string(kv.Value) == "0") {
+			created[idKey] = true
+		}
+	}
+	return len(created)
+}
+
+// planAdd returns the transaction adding the rules without an error and sets
+// their identity. The identity key and the registration of its identity are
+// put on the lease of the rule of the CIDR expiring last, in etcd or added,
+// see identityLease.
+func planAdd(name string, rules []*model.Rule, errs []error, state *batchState,
+	leases map[time.Time]clientv3.LeaseID, newIdentity func() (string, error)) ([]clientv3.Cmp, []clientv3.Op, error) {
+
+	var cmps []clientv3.Cmp
+	var ops []clientv3.Op
+
+	// identity key -> the lease it needs for its rules
+	idLeases := make(map[etcd.Key]*identityLease)
+	var idKeys []etcd.Key
+	identities := make(map[etcd.Key]string)
+
+	for i, r := range rules {
+		if errs[i] != nil {
+			continue
+		}
+
+		key := RuleKey(name, r.RuleInfo.Key())
+		idKey := RuleKey(name, r.RuleInfo.IdentityKey())
+
+		identity, ok := identities[idKey]
+		if !ok {
+			idKeys = append(idKeys, idKey)
+			if kv := state.identities[idKey]; kv != nil && string(kv.Value) != "0" {
+				identity = string(kv.Value)
+			} else {
+				var err error
+				if identity, err = newIdentity(); err != nil {
+					return nil, nil, err
+				}
+			}
+			identities[idKey] = identity
+			idLeases[idKey] = &identityLease{}
+			if l := state.leases[idKey]; l != nil {
+				*idLeases[idKey] = *l
+			}
+		}
+		idLeases[idKey].add(r.RuleMeta.ExpiresAt, leases[r.RuleMeta.ExpiresAt])
+
+		r.RuleMeta.Identity = identity
+		ops = append(ops, clientv3.OpPut(key, r.RuleMeta.MarshalStr(), leaseOption(leases, r.RuleMeta.ExpiresAt)...))
+	}
+	if len(ops) == 0 {
+		return nil, nil, nil
+	}
+
+	for _, idKey := range idKeys {
+		// CIDR 的规则在读取之后都没有被修改过: 要添加的规则不存在, identity key 的 lease 是对的
+		cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(idKey+"/").WithPrefix(), "<", state.revision+1))
+
+		kv := state.identities[idKey]
+		if kv == nil {
+			cmps = append(cmps, clientv3.Compare(clientv3.Version(idKey), "=", 0))
+		} else {
+			cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(idKey), "=", kv.ModRevision))
+		}
+
+		lease := *idLeases[idKey]
+		if kv == nil || string(kv.Value) == "0" {
+			opts := lease.options()
+			rcmps, rops := registerIdentity(idKey, identities[idKey], opts...)
+			cmps = append(cmps, rcmps...)
+			ops = append(ops, clientv3.OpPut(idKey, identities[idKey], opts...))
+			ops = append(ops, rops...)
+		} else {
+			ops = append(ops, moveIdentity(kv, lease)...)
+		}
+	}
+
+	// 删除 rule set 会删除 name key, 比较它的 CreateRevision 以免留下没有 name 的规则
+	cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(nameKey(name)), "=", state.nameRevision))
+	if state.nameRevision == 0 {
+		ops = append(ops, clientv3.OpPut(nameKey(name), ""))
+	}
+
+	return cmps, ops, nil
+}
+
+func leaseOption(leases map[time.Time]clientv3.LeaseID, expiresAt time.Time) []clientv3.OpOption {
+	if expiresAt.IsZero() {
+		return nil
+	}
+	return []clientv3.OpOption{clientv3.WithLease(leases[expiresAt])}
+}
@@ -0,0 +1,182 @@
+package rule
+
+import (
+	"slices"
+	"testing"
+	"time"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/etcd"
+	"xdp-banner/pkg/rule"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestPlanAdd(t *testing.T) {
+	soon := time.Now().Add(time.Hour)
+	later := soon.Add(time.Hour)
+	leases := map[time.Time]clientv3.LeaseID{soon: 1, later: 2}
+
+	newRule := func(cidr string, dport uint16, expiresAt time.Time) *model.Rule {
+		return &model.Rule{
+			RuleInfo: rule.RuleInfo{Cidr: cidr, Protocol: "TCP", Dport: dport},
+			RuleMeta: rule.RuleMeta{ExpiresAt: expiresAt, Identity: "0"},
+		}
+	}
+	rules := []*model.Rule{
+		newRule("10.0.0.0/24", 22, soon),        // existing identity
+		newRule("10.0.0.0/24", 22, soon),        // duplicate
+		newRule("10.0.0.0/24", 80, time.Time{}), // permanent, detaches the identity
+		newRule("192.0.2.0/24", 22, soon),       // new identity
+		newRule("192.0.2.0/24", 80, later),      // the identity follows the later rule
+		newRule("198.51.100.0/24", 22, soon),    // existing rule
+	}
+
+	state := &batchState{
+		rules: map[etcd.Key]bool{RuleKey("set", rules[5].RuleInfo.Key()): true},
+		identities: map[etcd.Key]*mvccpb.KeyValue{
+			RuleKey("set", "10.0.0.0/24/"): {Key: []byte(RuleKey("set", "10.0.0.0/24/")), Value: []byte("42"), Lease: 7, ModRevision: 3},
+		},
+	}
+
+	errs := batchErrors("set", rules, state)
+	for i, want := range []error{nil, ErrRuleAlreadyExists, nil, nil, nil, ErrRuleAlreadyExists} {
+		if errs[i] != want {
+			t.Errorf("errs[%d] = %v, want %v", i, errs[i], want)
+		}
+	}
+
+	if n := newIdentities("set", rules, errs, state); n != 1 {
+		t.Errorf("newIdentities() = %d, want 1", n)
+	}
+	cmps, ops, err := planAdd("set", rules, errs, state, leases, func() (string, error) { return "100", nil })
+	if err != nil {
+		t.Fatalf("planAdd() error = %v", err)
+	}
+
+	// 4 rules, 10.0.0.0/24 and its registration detached, 192.0.2.0/24 created
+	// and registered, the name key
+	if len(ops) != 9 {
+		t.Fatalf("len(ops) = %d, want 9", len(ops))
+	}
+	// the rules and the identity key of 2 CIDRs, the registration of 100, the name key
+	if len(cmps) != 6 {
+		t.Errorf("len(cmps) = %d, want 6", len(cmps))
+	}
+
+	puts := make(map[string]string)
+	txns := 0
+	for _, op := range ops {
+		switch {
+		case op.IsTxn():
+			txns++
+		case op.IsPut():
+			puts[string(op.KeyBytes())] = string(op.ValueBytes())
+		default:
+			t.Fatalf("op on %s is not a put", op.KeyBytes())
+		}
+	}
+	if txns != 1 {
+		t.Errorf("%d registrations detached, want 1", txns)
+	}
+	if got := puts[identityIDsDir+"0000000100"]; got != RuleKey("set", "192.0.2.0/24") {
+		t.Errorf("100 registered to %q", got)
+	}
+	if got := puts[RuleKey("set", "10.0.0.0/24/")]; got != "42" {
+		t.Errorf("identity of 10.0.0.0/24 = %q, want 42", got)
+	}
+	if got := puts[RuleKey("set", "192.0.2.0/24/")]; got != "100" {
+		t.Errorf("identity of 192.0.2.0/24 = %q, want 100", got)
+	}
+	if _, ok := puts[nameKey("set")]; !ok {
+		t.Errorf("the name key is not created")
+	}
+	if rules[0].RuleMeta.Identity != "42" || rules[4].RuleMeta.Identity != "100" {
+		t.Errorf("identities = %s, %s, want 42, 100", rules[0].RuleMeta.Identity, rules[4].RuleMeta.Identity)
+	}
+}
+
+func TestPlanAddNothing(t *testing.T) {
+	rules := []*model.Rule{{RuleInfo: rule.RuleInfo{Cidr: "10.0.0.0/24", Protocol: "TCP"}}}
+	state := &batchState{rules: map[etcd.Key]bool{RuleKey("set", rules[0].RuleInfo.Key()): true}}
+
+	errs := batchErrors("set", rules, state)
+	if n := newIdentities("set", rules, errs, state); n != 0 {
+		t.Errorf("newIdentities() = %d, want 0", n)
+	}
+	_, ops, err := planAdd("set", rules, errs, state, nil, func() (string, error) { return "100", nil })
+	if err != nil || len(ops) != 0 {
+		t.Errorf("planAdd() = %d ops, %v, want nothing", len(ops), err)
+	}
+}
+
+func TestPlanAddIdentityLease(t *testing.T) {
+	soon := time.Now().Add(time.Hour)
+	later := soon.Add(time.Hour)
+	idKey := RuleKey("set", "10.0.0.0/24/")
+
+	newRule := func(dport uint16, expiresAt time.Time) *model.Rule {
+		return &model.Rule{
+			RuleInfo: rule.RuleInfo{Cidr: "10.0.0.0/24", Protocol: "TCP", Dport: dport},
+			RuleMeta: rule.RuleMeta{ExpiresAt: expiresAt, Identity: "0"},
+		}
+	}
+	// the identity key is on the lease of the rule expiring soon
+	newState := func(existing time.Time, lease clientv3.LeaseID) *batchState {
+		return &batchState{
+			revision: 10,
+			rules:    map[etcd.Key]bool{RuleKey("set", "10.0.0.0/24/TCP/0-22/"): true},
+			identities: map[etcd.Key]*mvccpb.KeyValue{
+				idKey: {Key: []byte(idKey), Value: []byte("42"), Lease: 1, ModRevision: 3},
+			},
+			leases:       map[etcd.Key]*identityLease{idKey: {lease: lease, expiresAt: existing}},
+			nameRevision: 5,
+		}
+	}
+	identityLeases := func(ops []clientv3.Op) []string {
+		var moved []string
+		for _, op := range ops {
+			if op.IsTxn() {
+				moved = append(moved, "registration")
+			} else if string(op.KeyBytes()) == idKey {
+				moved = append(moved, "identity")
+			}
+		}
+		return moved
+	}
+
+	// a rule expiring later moves the identity key and its registration to its lease
+	rules := []*model.Rule{newRule(80, later)}
+	state := newState(soon, 1)
+	_, ops, err := planAdd("set", rules, batchErrors("set", rules, state), state, map[time.Time]clientv3.LeaseID{later: 2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := identityLeases(ops); !slices.Equal(got, []string{"identity", "registration"}) {
+		t.Fatalf("identity key moves = %v", got)
+	}
+
+	// a rule expiring sooner than the ones in etcd leaves it alone
+	rules = []*model.Rule{newRule(80, soon)}
+	state = newState(later, 1)
+	_, ops, err = planAdd("set", rules, batchErrors("set", rules, state), state, map[time.Time]clientv3.LeaseID{soon: 3}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := identityLeases(ops); len(got) != 0 {
+		t.Fatalf("identity key moves = %v, want none", got)
+	}
+
+	// the lease of the rule expiring last wins, a permanent rule drops it
+	var lease identityLease
+	lease.add(later, 2)
+	lease.add(soon, 1)
+	if lease.lease != 2 {
+		t.Errorf("lease = %d, want 2", lease.lease)
+	}
+	lease.add(time.Time{}, clientv3.NoLease)
+	lease.add(later.Add(time.Hour), 3)
+	if lease.lease != clientv3.NoLease || lease.options() != nil {
+		t.Errorf("lease = %d, want none", lease.lease)
+	}
+}
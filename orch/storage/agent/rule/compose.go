@@ -0,0 +1,383 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/etcd"
+	"xdp-banner/pkg/informer"
+	"xdp-banner/pkg/log"
+	"xdp-banner/pkg/rule"
+	"xdp-banner/pkg/wait"
+)
+
+// ErrComposerNotSynced is returned when the composer has not listed the rules in time
+var ErrComposerNotSynced = errors.New("rule sets are not synced yet")
+
+// syncTimeout bounds the wait of a watch for the composer to list the rules
+const syncTimeout = 10 * time.Second
+
+// ViewEvent is a change of the merged view of a rule set. Key is the etcd key
+// of the rule in the set it comes from and Value is its RuleMeta JSON.
+type ViewEvent struct {
+	Key     etcd.Key
+	Value   string
+	Deleted bool
+}
+
+// viewEntry is a rule of a rule set
+type viewEntry struct {
+	set   string
+	key   etcd.Key
+	value string
+}
+
+// viewWatch is a watch of the merged view of a rule set
+type viewWatch struct {
+	name string
+	// order are the sets merged, see ResolveIncludes
+	order []string
+	// sent is the view as sent to the watch, keyed by RuleInfo.Key()
+	sent map[string]viewEntry
+	ch   chan ViewEvent
+}
+
+// Composer merges every rule set with its includes. It is kept up to date by
+// informers on EtcdDir and EtcdIncludesDir and pushes the changes of the merged
+// views to their watches, which see the rules of the included sets under their
+// own keys as if they were in the watched set.
+type Composer struct {
+	// synced reports whether the informers have listed the rules and includes
+	synced func() bool
+
+	mu sync.Mutex
+	// rules are the rules of every set keyed by RuleInfo.Key()
+	rules    map[string]map[string]viewEntry
+	includes map[string][]string
+	watches  map[*viewWatch]bool
+}
+
+// NewComposer starts the informers filling the composer, they stop with ctx
+func NewComposer(ctx context.Context, client etcd.Client) *Composer {
+	c := &Composer{
+		rules:    make(map[string]map[string]viewEntry),
+		includes: make(map[string][]string),
+		watches:  make(map[*viewWatch]bool),
+	}
+
+	rulesSynced := runInformer(ctx, client, "rule_composer_reflector", EtcdDir+"/", composerRules{c})
+	includesSynced := runInformer(ctx, client, "include_composer_reflector", EtcdIncludesDir+"/", composerIncludes{c})
+	c.synced = func() bool { return rulesSynced() && includesSynced() }
+
+	return c
+}
+
+func runInformer(ctx context.Context, client etcd.Client, name, prefix string, handler informer.ResourceEventHandler) func() bool {
+	deltaFIFO := informer.NewDeltaFIFOWithWait(1)
+	reflector := informer.NewReflector(client, name, prefix, deltaFIFO)
+	i := informer.New(deltaFIFO)
+	i.Register([]string{prefix}, handler)
+
+	go reflector.Run(ctx)
+	go i.Run(ctx)
+
+	return i.HasSynced
+}
+
+// Watch returns the merged view of a rule set and a channel of its changes.
+// The channel is closed once ctx is done, or before when the watch does not
+// keep up with the changes and has to watch again.
+func (c *Composer) Watch(ctx context.Context, name string, buffer int) ([]ViewEvent, <-chan ViewEvent, error) {
+	if err := c.waitForSync(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &viewWatch{name: name, ch: make(chan ViewEvent, buffer)}
+	w.order, _ = ResolveIncludes(c.includes, name)
+	w.sent = c.merge(w.order)
+	c.watches[w] = true
+
+	initial := make([]ViewEvent, 0, len(w.sent))
+	for _, e := range w.sent {
+		initial = append(initial, ViewEvent{Key: e.key, Value: e.value})
+	}
+	slices.SortFunc(initial, func(a, b ViewEvent) int { return strings.Compare(a.Key, b.Key) })
+
+	go func() {
+		<-ctx.Done()
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.stop(w)
+	}()
+
+	return initial, w.ch, nil
+}
+
+// Effective returns the merged view of a rule set sorted by key and the sets
+// merged, from the highest precedence
+func (c *Composer) Effective(ctx context.Context, name string) ([]string, []model.EffectiveRule, error) {
+	if err := c.waitForSync(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	order, _ := ResolveIncludes(c.includes, name)
+	view := c.merge(order)
+	c.mu.Unlock()
+
+	rules := make([]model.EffectiveRule, 0, len(view))
+	for key, e := range view {
+		if r, ok := e.effective(key); ok {
+			rules = append(rules, r)
+		}
+	}
+	slices.SortFunc(rules, func(a, b model.EffectiveRule) int {
+		return strings.Compare(a.Rule.RuleInfo.Key(), b.Rule.RuleInfo.Key())
+	})
+	return order, rules, nil
+}
+
+// Layers returns the rules of a rule set and of its includes before they are
+// merged, a rule key may come from several sets. The rules are sorted by the
+// precedence of their set and then by key.
+func (c *Composer) Layers(ctx context.Context, name string) ([]string, []model.EffectiveRule, error) {
+	if err := c.waitForSync(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	order, _ := ResolveIncludes(c.includes, name)
+	var rules []model.EffectiveRule
+	for _, set := range order {
+		layer := make([]model.EffectiveRule, 0, len(c.rules[set]))
+		for key, e := range c.rules[set] {
+			if r, ok := e.effective(key); ok {
+				layer = append(layer, r)
+			}
+		}
+		slices.SortFunc(layer, func(a, b model.EffectiveRule) int {
+			return strings.Compare(a.Rule.RuleInfo.Key(), b.Rule.RuleInfo.Key())
+		})
+		rules = append(rules, layer...)
+	}
+	return order, rules, nil
+}
+
+func (c *Composer) waitForSync(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+
+	err := wait.PollImmediateUntilWithContext(ctx, 100*time.Millisecond, func(context.Context) (bool, error) {
+		return c.synced(), nil
+	})
+	if err != nil {
+		return ErrComposerNotSynced
+	}
+	return nil
+}
+
+// effective decodes the entry of a rule key
+func (e viewEntry) effective(key string) (model.EffectiveRule, bool) {
+	info, _ := parseRuleKey(key)
+	var meta rule.RuleMeta
+	if err := json.Unmarshal([]byte(e.value), &meta); err != nil {
+		log.Warn("composer got an invalid rule meta", log.StringField("key", e.key), log.ErrorField(err))
+		return model.EffectiveRule{}, false
+	}
+	return model.EffectiveRule{Source: e.set, Rule: model.Rule{RuleInfo: info, RuleMeta: meta}}, true
+}
+
+// merge returns the rules of the sets, a rule key is taken from the first set
+// having it
+func (c *Composer) merge(order []string) map[string]viewEntry {
+	view := make(map[string]viewEntry)
+	for _, set := range order {
+		for key, e := range c.rules[set] {
+			if _, ok := view[key]; !ok {
+				view[key] = e
+			}
+		}
+	}
+	return view
+}
+
+// refresh sends the change of a rule key to the watches merging set
+func (c *Composer) refresh(set, key string) {
+	for w := range c.watches {
+		if !slices.Contains(w.order, set) {
+			continue
+		}
+
+		var winner *viewEntry
+		for _, s := range w.order {
+			if e, ok := c.rules[s][key]; ok {
+				winner = &e
+				break
+			}
+		}
+		c.update(w, key, winner)
+	}
+}
+
+// resync sends the difference between the merged view and what a watch has
+func (c *Composer) resync(w *viewWatch) {
+	view := c.merge(w.order)
+	for key := range w.sent {
+		if _, ok := view[key]; !ok {
+			if !c.update(w, key, nil) {
+				return
+			}
+		}
+	}
+	for key, e := range view {
+		if !c.update(w, key, &e) {
+			return
+		}
+	}
+}
+
+// update makes the watch have e for key, nil is no rule. It reports false
+// when the watch has been stopped.
+func (c *Composer) update(w *viewWatch, key string, e *viewEntry) bool {
+	old, had := w.sent[key]
+	switch {
+	case e == nil && !had:
+		return true
+	case e != nil && had && *e == old:
+		return true
+	}
+
+	if e == nil {
+		delete(w.sent, key)
+		return c.send(w, ViewEvent{Key: old.key, Value: old.value, Deleted: true})
+	}
+
+	// 来源变化时 key 不同, 先下发新的规则再删除旧的 key, 规则不会有空窗
+	w.sent[key] = *e
+	if !c.send(w, ViewEvent{Key: e.key, Value: e.value}) {
+		return false
+	}
+	if had && e.key != old.key {
+		return c.send(w, ViewEvent{Key: old.key, Value: old.value, Deleted: true})
+	}
+	return true
+}
+
+// send stops a watch which is full instead of blocking the informers
+func (c *Composer) send(w *viewWatch, event ViewEvent) bool {
+	if !c.watches[w] {
+		return false
+	}
+	select {
+	case w.ch <- event:
+		return true
+	default:
+		log.Warn("rule set watch fell behind, closing it", log.StringField("name", w.name))
+		c.stop(w)
+		return false
+	}
+}
+
+func (c *Composer) stop(w *viewWatch) {
+	if c.watches[w] {
+		delete(c.watches, w)
+		close(w.ch)
+	}
+}
+
+func (c *Composer) putRule(key etcd.Key, obj any) {
+	set, info, ok := parseIndexKey(key)
+	if !ok {
+		return
+	}
+	value, ok := obj.(string)
+	if !ok {
+		log.Warn("composer got a value which is not a string", log.StringField("key", key))
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rules, ok := c.rules[set]
+	if !ok {
+		rules = make(map[string]viewEntry)
+		c.rules[set] = rules
+	}
+	rules[info.Key()] = viewEntry{set: set, key: key, value: value}
+	c.refresh(set, info.Key())
+}
+
+func (c *Composer) deleteRule(key etcd.Key) {
+	set, info, ok := parseIndexKey(key)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.rules[set], info.Key())
+	if len(c.rules[set]) == 0 {
+		delete(c.rules, set)
+	}
+	c.refresh(set, info.Key())
+}
+
+func (c *Composer) setIncludes(key etcd.Key, obj any) {
+	set := strings.TrimPrefix(key, EtcdIncludesDir+"/")
+
+	var includes []string
+	if obj != nil {
+		raw, _ := obj.(string)
+		if err := json.Unmarshal([]byte(raw), &includes); err != nil {
+			log.Warn("composer got invalid includes, ignore them", log.StringField("key", key), log.ErrorField(err))
+			includes = nil
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(includes) == 0 {
+		delete(c.includes, set)
+	} else {
+		c.includes[set] = includes
+	}
+
+	for w := range c.watches {
+		order, err := ResolveIncludes(c.includes, w.name)
+		if err != nil {
+			// SetIncludes 拒绝了环, 只有直接修改 etcd 才会出现
+			log.Warn("rule set includes form a cycle", log.StringField("name", w.name), log.ErrorField(err))
+		}
+		if slices.Equal(order, w.order) {
+			continue
+		}
+		w.order = order
+		c.resync(w)
+	}
+}
+
+// composerRules and composerIncludes handle the events of the two informers
+type composerRules struct{ c *Composer }
+
+func (h composerRules) OnAdd(key etcd.Key, obj any, _ bool)  { h.c.putRule(key, obj) }
+func (h composerRules) OnUpdate(key etcd.Key, _, newObj any) { h.c.putRule(key, newObj) }
+func (h composerRules) OnDelete(key etcd.Key, _ any)         { h.c.deleteRule(key) }
+
+type composerIncludes struct{ c *Composer }
+
+func (h composerIncludes) OnAdd(key etcd.Key, obj any, _ bool)  { h.c.setIncludes(key, obj) }
+func (h composerIncludes) OnUpdate(key etcd.Key, _, newObj any) { h.c.setIncludes(key, newObj) }
+func (h composerIncludes) OnDelete(key etcd.Key, _ any)         { h.c.setIncludes(key, nil) }
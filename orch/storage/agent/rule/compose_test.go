@@ -0,0 +1,107 @@
+package rule
+
+import (
+	"context"
+	"testing"
+)
+
+func newTestComposer() *Composer {
+	return &Composer{
+		synced:   func() bool { return true },
+		rules:    make(map[string]map[string]viewEntry),
+		includes: make(map[string][]string),
+		watches:  make(map[*viewWatch]bool),
+	}
+}
+
+func drain(ch <-chan ViewEvent) []ViewEvent {
+	var events []ViewEvent
+	for {
+		select {
+		case evt, ok := <-ch:
+			if !ok {
+				return events
+			}
+			events = append(events, evt)
+		default:
+			return events
+		}
+	}
+}
+
+func TestComposer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := newTestComposer()
+	base := RuleKey("base", "10.0.0.0/24/TCP/0-22")
+	own := RuleKey("web", "10.0.0.0/24/TCP/0-22")
+	other := RuleKey("base", "192.0.2.0/24/TCP/0-22")
+
+	c.putRule(base, `{"comment":"base"}`)
+	c.putRule(other, `{"comment":"other"}`)
+	c.putRule(own, `{"comment":"own"}`)
+	c.putRule(RuleKey("web", "10.0.0.0/24"), "42")
+
+	initial, events, err := c.Watch(ctx, "web", 10)
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	if len(initial) != 1 || initial[0].Key != own {
+		t.Fatalf("initial = %+v, want the rule of web", initial)
+	}
+
+	// base 被包含后, 被 web 覆盖的 key 不发送
+	c.setIncludes(includesKey("web"), `["base"]`)
+	if got := drain(events); len(got) != 1 || got[0] != (ViewEvent{Key: other, Value: `{"comment":"other"}`}) {
+		t.Errorf("events after include = %+v", got)
+	}
+
+	// web 删除自己的规则后, base 的同一规则生效, 先下发新的来源再删除旧的
+	c.deleteRule(own)
+	got := drain(events)
+	want := []ViewEvent{{Key: base, Value: `{"comment":"base"}`}, {Key: own, Value: `{"comment":"own"}`, Deleted: true}}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("events after delete = %+v, want %+v", got, want)
+	}
+
+	// 不在视图中的 set 的变化不发送
+	c.putRule(RuleKey("unrelated", "10.0.0.0/24/TCP/0-22"), "x")
+	if got := drain(events); len(got) != 0 {
+		t.Errorf("events of an unrelated set = %+v", got)
+	}
+
+	sets, rules, err := c.Effective(ctx, "web")
+	if err != nil || len(sets) != 2 || len(rules) != 2 || rules[0].Source != "base" {
+		t.Errorf("Effective() = %v, %+v, %v", sets, rules, err)
+	}
+
+	c.setIncludes(includesKey("web"), nil)
+	if got := drain(events); len(got) != 2 || !got[0].Deleted || !got[1].Deleted {
+		t.Errorf("events after removing the include = %+v", got)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestComposerOverflow(t *testing.T) {
+	c := newTestComposer()
+	_, events, err := c.Watch(context.Background(), "web", 1)
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	c.putRule(RuleKey("web", "10.0.0.0/24/TCP/0-22"), "a")
+	c.putRule(RuleKey("web", "10.0.0.0/24/TCP/0-80"), "b")
+	if got := drain(events); len(got) != 1 {
+		t.Errorf("events = %+v, want the first one", got)
+	}
+	if _, ok := <-events; ok {
+		t.Error("the watch falling behind is not closed")
+	}
+	if len(c.watches) != 0 {
+		t.Error("the watch falling behind is still registered")
+	}
+}
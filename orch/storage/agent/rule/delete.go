@@ -0,0 +1,261 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/etcd"
+	"xdp-banner/pkg/rule"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	// maxTxnOps is the default --max-txn-ops of etcd
+	maxTxnOps = 128
+	// txnRetries 是事务因并发修改失败后的重试次数
+	txnRetries = 3
+	// deleteBatchSize is the most rules a delete removes in one transaction,
+	// every rule deletes its key and at most one identity key
+	deleteBatchSize = (maxTxnOps - historyOps) / 2
+)
+
+var (
+	// ErrTooManyRules is returned when a change does not fit in one transaction
+	ErrTooManyRules = fmt.Errorf("too many rules to change in one transaction, the limit is %d operations", maxTxnOps)
+	// ErrConflict is returned when the rule set keeps changing during a change
+	ErrConflict = errors.New("rule set modified concurrently, retry later")
+)
+
+// SelectFunc selects the rules to delete, key is RuleInfo.Key()
+type SelectFunc func(key string, r model.Rule) bool
+
+// ruleSet is a rule set read at a revision
+type ruleSet struct {
+	revision int64
+	rules    map[string]model.Rule // keyed by RuleInfo.Key()
+	// leases are the leases of the rules, keyed by RuleInfo.Key()
+	leases map[string]clientv3.LeaseID
+	// identities maps the CIDRs to their identity keys
+	identities map[string]etcd.Key
+	// identityKVs are the identity keys by CIDR
+	identityKVs map[string]*mvccpb.KeyValue
+
+	// nameRevision is the CreateRevision of the name key, 0 when it is missing
+	nameRevision int64
+}
+
+func (s Storage) readRuleSet(ctx context.Context, name string) (*ruleSet, error) {
+	prefix := setPrefix(name)
+	resp, err := s.client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rule set from etcd: %w", err)
+	}
+
+	set := &ruleSet{
+		revision:    resp.Header.Revision,
+		rules:       make(map[string]model.Rule, len(resp.Kvs)),
+		leases:      make(map[string]clientv3.LeaseID, len(resp.Kvs)),
+		identities:  make(map[string]etcd.Key),
+		identityKVs: make(map[string]*mvccpb.KeyValue),
+	}
+	for _, kv := range resp.Kvs {
+		relPath := strings.TrimPrefix(string(kv.Key), prefix)
+		info, ok := parseRuleKey(relPath)
+		if !ok {
+			// 其余的 key 是 "<ip>/<mask>" 形式的 identity key
+			cidr := strings.Trim(relPath, "/")
+			set.identities[cidr] = string(kv.Key)
+			set.identityKVs[cidr] = kv
+			continue
+		}
+
+		var meta rule.RuleMeta
+		if err := json.Unmarshal(kv.Value, &meta); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal rule meta: %w", err)
+		}
+		set.rules[info.Key()] = model.Rule{RuleInfo: info, RuleMeta: meta}
+		set.leases[info.Key()] = clientv3.LeaseID(kv.Lease)
+	}
+
+	nameResp, err := s.client.Get(ctx, nameKey(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get name key: %w", err)
+	}
+	if len(nameResp.Kvs) > 0 {
+		set.nameRevision = nameResp.Kvs[0].CreateRevision
+	}
+
+	return set, nil
+}
+
+// DeleteRules deletes the selected rules of a rule set, together with the
+// identity keys no remaining rule uses. The name key is deleted once the set
+// is empty. A selection which does not fit in one transaction is deleted in
+// batches of deleteBatchSize rules, every batch is a version of its own.
+func (s Storage) DeleteRules(ctx context.Context, name string, selectFn SelectFunc) (*model.Removed, error) {
+	operationLock.Lock()
+	defer operationLock.Unlock()
+
+	return s.deleteRules(ctx, name, selectFn, false)
+}
+
+// DeleteRuleSet deletes a whole rule set in one transaction, including its
+// identity keys and its name. ErrRuleNotFound is returned for an unknown set.
+func (s Storage) DeleteRuleSet(ctx context.Context, name string) (*model.Removed, error) {
+	operationLock.Lock()
+	defer operationLock.Unlock()
+
+	removed, err := s.deleteRules(ctx, name, func(string, model.Rule) bool { return true }, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(removed.Rules) == 0 && len(removed.Identities) == 0 && !removed.RuleSetRemoved {
+		return nil, ErrRuleNotFound
+	}
+	return removed, nil
+}
+
+func (s Storage) deleteRules(ctx context.Context, name string, selectFn SelectFunc, wholeSet bool) (*model.Removed, error) {
+	total := &model.Removed{}
+	for {
+		removed, more, err := s.deleteBatch(ctx, name, selectFn, wholeSet)
+		if err != nil {
+			// 已经提交的批次不会回滚, 重新执行同一个删除会继续删除剩下的规则
+			return nil, err
+		}
+		total.Rules = append(total.Rules, removed.Rules...)
+		total.Identities = append(total.Identities, removed.Identities...)
+		total.RuleSetRemoved = total.RuleSetRemoved || removed.RuleSetRemoved
+		if !more {
+			return total, nil
+		}
+	}
+}
+
+// deleteBatch deletes at most deleteBatchSize of the selected rules in one
+// transaction, more reports whether selected rules are left
+func (s Storage) deleteBatch(ctx context.Context, name string, selectFn SelectFunc, wholeSet bool) (*model.Removed, bool, error) {
+	for range txnRetries {
+		set, err := s.readRuleSet(ctx, name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		h, err := s.readHead(ctx, name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		removed, ops, more, err := planDelete(name, set, selectFn, wholeSet)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(ops) == 0 {
+			return removed, false, nil
+		}
+
+		// 读取之后 rule set 和 name key 都没有被修改过才能删除
+		cmps := []clientv3.Cmp{
+			clientv3.Compare(clientv3.ModRevision(setPrefix(name)).WithPrefix(), "<", set.revision+1),
+		}
+		if removed.RuleSetRemoved {
+			cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(nameKey(name)), "=", set.nameRevision))
+		}
+		hcmps, hops, err := s.record(ctx, name, h, &model.Change{Op: model.OpDelete, Removed: removed.Rules})
+		if err != nil {
+			return nil, false, err
+		}
+		cmps, ops = append(cmps, hcmps...), append(ops, hops...)
+
+		txn, cancel := s.client.Txn(ctx)
+		resp, err := txn.If(cmps...).Then(ops...).Commit()
+		cancel()
+		if err != nil {
+			return nil, false, fmt.Errorf("etcd transaction failed: %w", err)
+		}
+		if resp.Succeeded {
+			s.releaseIdentities(ctx, set.identitiesOf(removed.Identities))
+			return removed, more, nil
+		}
+	}
+
+	return nil, false, ErrConflict
+}
+
+// identitiesOf returns the identities of CIDRs keyed by their identity keys
+func (set *ruleSet) identitiesOf(cidrs []string) map[etcd.Key]string {
+	identities := make(map[etcd.Key]string, len(cidrs))
+	for _, cidr := range cidrs {
+		if kv := set.identityKVs[cidr]; kv != nil {
+			identities[string(kv.Key)] = string(kv.Value)
+		}
+	}
+	return identities
+}
+
+// planDelete returns what the delete removes and the etcd operations doing
+// it. Unless the whole set goes, at most deleteBatchSize rules are removed and
+// more reports whether selected rules are left for another batch.
+func planDelete(name string, set *ruleSet, selectFn SelectFunc, wholeSet bool) (*model.Removed, []clientv3.Op, bool, error) {
+	removed := &model.Removed{}
+	remaining := make(map[string]bool) // CIDRs still used by a rule
+
+	var selected []model.Rule
+	for key, r := range set.rules {
+		if selectFn(key, r) {
+			selected = append(selected, r)
+		} else {
+			remaining[r.RuleInfo.Cidr] = true
+		}
+	}
+	if len(selected) == 0 && !wholeSet {
+		return removed, nil, false, nil
+	}
+
+	// 有规则保留时逐个删除, 超过一个事务的规则按 key 的顺序分批删除
+	more := false
+	slices.SortFunc(selected, func(a, b model.Rule) int { return strings.Compare(a.RuleInfo.Key(), b.RuleInfo.Key()) })
+	if len(remaining) > 0 && len(selected) > deleteBatchSize {
+		for _, r := range selected[deleteBatchSize:] {
+			remaining[r.RuleInfo.Cidr] = true
+		}
+		selected, more = selected[:deleteBatchSize], true
+	}
+	removed.Rules = selected
+
+	for cidr := range set.identities {
+		if !remaining[cidr] {
+			removed.Identities = append(removed.Identities, cidr)
+		}
+	}
+
+	var ops []clientv3.Op
+	if len(remaining) == 0 {
+		// 整个 rule set 被删除, 一次删除整个前缀
+		ops = append(ops, clientv3.OpDelete(setPrefix(name), clientv3.WithPrefix()))
+
+		if set.nameRevision != 0 {
+			ops = append(ops, clientv3.OpDelete(nameKey(name)))
+			removed.RuleSetRemoved = true
+		}
+		if len(removed.Rules) == 0 && len(removed.Identities) == 0 && !removed.RuleSetRemoved {
+			ops = nil
+		}
+	} else {
+		for _, r := range removed.Rules {
+			ops = append(ops, clientv3.OpDelete(RuleKey(name, r.RuleInfo.Key())))
+		}
+		for _, cidr := range removed.Identities {
+			ops = append(ops, clientv3.OpDelete(set.identities[cidr]))
+		}
+	}
+
+	slices.Sort(removed.Identities)
+	return removed, ops, more, nil
+}
@@ -0,0 +1,138 @@
+package rule
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/rule"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func testRuleSet() *ruleSet {
+	rules := map[string]model.Rule{}
+	for _, info := range []rule.RuleInfo{
+		{Cidr: "10.0.0.0/24", Protocol: "TCP", Dport: 22},
+		{Cidr: "10.0.0.0/24", Protocol: "UDP", Dport: 53},
+		{Cidr: "192.0.2.0/24", Protocol: "ICMP"},
+	} {
+		rules[info.Key()] = model.Rule{RuleInfo: info}
+	}
+
+	return &ruleSet{
+		rules: rules,
+		identities: map[string]string{
+			"10.0.0.0/24":  RuleKey("set", "10.0.0.0/24"),
+			"192.0.2.0/24": RuleKey("set", "192.0.2.0/24"),
+		},
+		nameRevision: 5,
+	}
+}
+
+func TestPlanDelete(t *testing.T) {
+	byKey := func(keys ...string) SelectFunc {
+		return func(key string, _ model.Rule) bool {
+			for _, k := range keys {
+				if k == key {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	tests := []struct {
+		name       string
+		selectFn   SelectFunc
+		wholeSet   bool
+		rules      int
+		identities []string
+		setRemoved bool
+		ops        int
+	}{
+		{"nothing", byKey("172.16.0.0/12/TCP/0-22/"), false, 0, nil, false, 0},
+		{"identity kept", byKey("10.0.0.0/24/TCP/0-22/"), false, 1, nil, false, 1},
+		{"identity removed", byKey("192.0.2.0/24/ICMP/0-0/"), false, 1, []string{"192.0.2.0/24"}, false, 2},
+		{"cidr removed", byKey("10.0.0.0/24/TCP/0-22/", "10.0.0.0/24/UDP/0-53/"), false, 2, []string{"10.0.0.0/24"}, false, 3},
+		{"emptied", byKey("10.0.0.0/24/TCP/0-22/", "10.0.0.0/24/UDP/0-53/", "192.0.2.0/24/ICMP/0-0/"), false, 3, []string{"10.0.0.0/24", "192.0.2.0/24"}, true, 2},
+		{"whole set", func(string, model.Rule) bool { return true }, true, 3, []string{"10.0.0.0/24", "192.0.2.0/24"}, true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			removed, ops, more, err := planDelete("set", testRuleSet(), tt.selectFn, tt.wholeSet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(removed.Rules) != tt.rules || !reflect.DeepEqual(removed.Identities, tt.identities) ||
+				removed.RuleSetRemoved != tt.setRemoved || len(ops) != tt.ops || more {
+				t.Fatalf("removed %+v with %d ops", removed, len(ops))
+			}
+		})
+	}
+}
+
+func TestPlanDeleteExpiredSet(t *testing.T) {
+	// 规则都已过期, 只剩 name
+	set := &ruleSet{nameRevision: 5}
+	removed, ops, _, err := planDelete("set", set, func(string, model.Rule) bool { return true }, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !removed.RuleSetRemoved || len(ops) != 2 {
+		t.Fatalf("removed %+v with %d ops", removed, len(ops))
+	}
+
+	set.nameRevision = 0
+	if _, ops, _, _ := planDelete("set", set, func(string, model.Rule) bool { return true }, true); len(ops) != 0 {
+		t.Fatalf("unknown set should not be deleted, got %d ops", len(ops))
+	}
+}
+
+func TestPlanDeleteBatches(t *testing.T) {
+	set := &ruleSet{rules: map[string]model.Rule{}, identities: map[string]string{}}
+	for i := range 2*deleteBatchSize + 1 {
+		info := rule.RuleInfo{Cidr: fmt.Sprintf("10.%d.%d.0/24", i/256, i%256), Protocol: "TCP"}
+		set.rules[info.Key()] = model.Rule{RuleInfo: info}
+		set.identities[info.Cidr] = RuleKey("set", info.Cidr)
+	}
+	// 保留的规则和最后一批的规则共用 CIDR
+	keep := rule.RuleInfo{Cidr: "10.0.0.0/24", Protocol: "UDP"}
+	set.rules[keep.Key()] = model.Rule{RuleInfo: keep}
+	isTCP := func(_ string, r model.Rule) bool { return r.RuleInfo.Protocol == "TCP" }
+
+	batches, identities := 0, 0
+	for more := true; more; batches++ {
+		var removed *model.Removed
+		var ops []clientv3.Op
+		var err error
+		removed, ops, more, err = planDelete("set", set, isTCP, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ops) > maxTxnOps-historyOps {
+			t.Fatalf("batch %d has %d ops", batches, len(ops))
+		}
+		for _, r := range removed.Rules {
+			delete(set.rules, r.RuleInfo.Key())
+		}
+		for _, cidr := range removed.Identities {
+			if cidr == keep.Cidr {
+				t.Fatalf("identity of %s removed while a rule uses it", cidr)
+			}
+			delete(set.identities, cidr)
+		}
+		identities += len(removed.Identities)
+	}
+
+	if batches != 3 || len(set.rules) != 1 || identities != 2*deleteBatchSize {
+		t.Fatalf("%d batches left %d rules and removed %d identities", batches, len(set.rules), identities)
+	}
+}
+
+func TestSetPrefix(t *testing.T) {
+	if got := setPrefix("a"); got != EtcdDir+"/a/" {
+		t.Fatalf("setPrefix = %q", got)
+	}
+}
@@ -0,0 +1,547 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/orch/storage/agent"
+	"xdp-banner/pkg/etcd"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdHistoryDir 不能以 EtcdDir 开头, 否则 watch 规则的 agent 也会收到历史
+var EtcdHistoryDir etcd.Key = etcd.Join(agent.EtcdDir, "history/")
+
+const (
+	// DefaultRetention is the number of versions kept per rule set
+	DefaultRetention = 100
+	// historyOps are the operations recording a change: the entry, the head
+	// and the pruning of the old versions
+	historyOps = 3
+	// maxChangeRules bounds the rules recorded by a change, an etcd request is
+	// at most 1.5MiB by default
+	maxChangeRules = 1000
+	// minRestoreTTL 以内就会过期的规则不再恢复, lease 至少 1 秒
+	minRestoreTTL = 2 * time.Second
+)
+
+var (
+	// ErrHistoryDisabled is returned when the retention is 0
+	ErrHistoryDisabled = errors.New("rule set history is disabled")
+	// ErrVersionNotFound is returned for a version after the head or pruned
+	ErrVersionNotFound = errors.New("version not found, it is newer than the rule set or pruned")
+	// ErrVersionTruncated is returned when a change between the versions was
+	// too large to be recorded
+	ErrVersionTruncated = errors.New("a change between the versions touched too many rules to be recorded")
+	// ErrNoChange is returned by a rollback which would change nothing
+	ErrNoChange = errors.New("rule set already matches the version")
+)
+
+type actorKey struct{}
+
+// WithActor returns a context recording actor as the author of the changes
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return "unknown"
+}
+
+// WithRetention returns the storage keeping the last n versions of every rule
+// set, 0 disables the history
+func (s Storage) WithRetention(n int) Storage {
+	s.retention = n
+	return s
+}
+
+func historyPrefix(name string) etcd.Key {
+	return etcd.Join(EtcdHistoryDir, name) + "/"
+}
+
+// versionKey 补零使 key 的顺序就是版本的顺序
+func versionKey(name string, version int64) etcd.Key {
+	return fmt.Sprintf("%sv/%020d", historyPrefix(name), version)
+}
+
+func headKey(name string) etcd.Key {
+	return historyPrefix(name) + "head"
+}
+
+// head is the latest version of a rule set
+type head struct {
+	version int64
+	// revision is the ModRevision of the head key, 0 when it is absent
+	revision int64
+}
+
+func (s Storage) readHead(ctx context.Context, name string) (head, error) {
+	resp, err := s.client.Get(ctx, headKey(name))
+	if err != nil {
+		return head{}, fmt.Errorf("failed to get history head: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return head{}, nil
+	}
+
+	version, err := strconv.ParseInt(string(resp.Kvs[0].Value), 10, 64)
+	if err != nil {
+		return head{}, fmt.Errorf("invalid history head %q: %w", resp.Kvs[0].Value, err)
+	}
+	return head{version: version, revision: resp.Kvs[0].ModRevision}, nil
+}
+
+// record returns the compares and the operations recording change as the
+// version after h, nothing when the history is disabled. The versions beyond
+// the retention are pruned by the same transaction.
+func (s Storage) record(ctx context.Context, name string, h head, change *model.Change) ([]clientv3.Cmp, []clientv3.Op, error) {
+	if s.retention <= 0 {
+		return nil, nil, nil
+	}
+
+	entry := *change
+	entry.Version = h.version + 1
+	entry.Time = time.Now()
+	entry.Actor = actorFrom(ctx)
+	if len(entry.Added)+len(entry.Removed) > maxChangeRules {
+		entry.Added, entry.Removed, entry.Truncated = nil, nil, true
+	}
+	if entry.Added == nil {
+		entry.Added = model.RuleItem{}
+	}
+	if entry.Removed == nil {
+		entry.Removed = model.RuleItem{}
+	}
+	value, err := json.Marshal(&entry)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal change: %w", err)
+	}
+	*change = entry
+
+	cmps := []clientv3.Cmp{clientv3.Compare(clientv3.ModRevision(headKey(name)), "=", h.revision)}
+	ops := []clientv3.Op{
+		clientv3.OpPut(versionKey(name, entry.Version), string(value)),
+		clientv3.OpPut(headKey(name), strconv.FormatInt(entry.Version, 10)),
+	}
+	if oldest := entry.Version - int64(s.retention); oldest > 0 {
+		ops = append(ops, clientv3.OpDelete(versionKey(name, 0), clientv3.WithRange(versionKey(name, oldest+1))))
+	}
+	return cmps, ops, nil
+}
+
+// readVersions reads the versions after from up to to, all of them must still
+// be in the history
+func (s Storage) readVersions(ctx context.Context, name string, from, to int64) ([]model.Change, error) {
+	if from >= to {
+		return nil, nil
+	}
+
+	resp, err := s.client.Get(ctx, versionKey(name, from+1), clientv3.WithRange(versionKey(name, to+1)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history from etcd: %w", err)
+	}
+	if int64(len(resp.Kvs)) != to-from {
+		return nil, ErrVersionNotFound
+	}
+
+	changes := make([]model.Change, len(resp.Kvs))
+	for i, kv := range resp.Kvs {
+		if err := json.Unmarshal(kv.Value, &changes[i]); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal change: %w", err)
+		}
+	}
+	return changes, nil
+}
+
+// ListVersions lists the versions of a rule set before the given one, newest
+// first. before 0 starts from the head.
+func (s Storage) ListVersions(ctx context.Context, name string, before int64, limit int64) (*model.VersionList, error) {
+	h, err := s.readHead(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	end := h.version + 1
+	if before > 0 && before < end {
+		end = before
+	}
+	list := &model.VersionList{Head: h.version, Versions: []model.Change{}}
+	if end <= 1 {
+		return list, nil
+	}
+
+	resp, err := s.client.Get(ctx, versionKey(name, 0), clientv3.WithRange(versionKey(name, end)),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortDescend), clientv3.WithLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history from etcd: %w", err)
+	}
+
+	for _, kv := range resp.Kvs {
+		var change model.Change
+		if err := json.Unmarshal(kv.Value, &change); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal change: %w", err)
+		}
+		list.Versions = append(list.Versions, change)
+	}
+	if resp.More && len(list.Versions) > 0 {
+		list.NextBefore = list.Versions[len(list.Versions)-1].Version
+	}
+	return list, nil
+}
+
+// Diff returns what changed in a rule set from a version to another one, from
+// may be after to. The rules which expired meanwhile are not part of it.
+func (s Storage) Diff(ctx context.Context, name string, from, to int64) (*model.Diff, error) {
+	h, err := s.readHead(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if from < 0 || to < 0 || from > h.version || to > h.version {
+		return nil, ErrVersionNotFound
+	}
+
+	changes, err := s.readVersions(ctx, name, min(from, to), max(from, to))
+	if err != nil {
+		return nil, err
+	}
+	if slices.ContainsFunc(changes, func(c model.Change) bool { return c.Truncated }) {
+		return nil, ErrVersionTruncated
+	}
+
+	before, after := composeChanges(changes)
+	if from > to {
+		before, after = after, before
+	}
+	added, removed := diffRules(before, after)
+	return &model.Diff{From: from, To: to, Added: added, Removed: removed}, nil
+}
+
+// RollbackCheck checks the rules a rollback puts back before they are written,
+// an error refuses the rollback and is returned as it is
+type RollbackCheck func(ctx context.Context, puts model.RuleItem) error
+
+// Rollback turns a rule set back into a version in one transaction, which is
+// recorded as a new version. The rules which expired since can not come back,
+// the ones put back are checked by check.
+func (s Storage) Rollback(ctx context.Context, name string, version int64, check RollbackCheck) (*model.Change, error) {
+	if s.retention <= 0 {
+		return nil, ErrHistoryDisabled
+	}
+
+	operationLock.Lock()
+	defer operationLock.Unlock()
+
+	for range txnRetries {
+		h, err := s.readHead(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if version < 0 || version > h.version {
+			return nil, ErrVersionNotFound
+		}
+
+		changes, err := s.readVersions(ctx, name, version, h.version)
+		if err != nil {
+			return nil, err
+		}
+		set, err := s.readRuleSet(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		puts, removed, err := rollbackTarget(set, changes, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if len(puts) == 0 && len(removed) == 0 {
+			return nil, ErrNoChange
+		}
+		if err := check(ctx, puts); err != nil {
+			return nil, err
+		}
+
+		rules := make([]*model.Rule, len(puts))
+		for i := range puts {
+			rules[i] = &puts[i]
+		}
+		leases, err := s.grantBatch(ctx, rules, make([]error, len(rules)))
+		if err != nil {
+			return nil, err
+		}
+
+		// 缺少 identity key 的 CIDR 分配新的 identity
+		created := make(map[string]bool)
+		for _, r := range puts {
+			if kv := set.identityKVs[r.RuleInfo.Cidr]; kv == nil || string(kv.Value) == "0" {
+				created[r.RuleInfo.Cidr] = true
+			}
+		}
+		alloc, err := s.allocate(ctx, len(created))
+		if err != nil {
+			s.revokeBatch(ctx, leases)
+			return nil, err
+		}
+
+		cmps, ops, err := planRollback(name, set, puts, removed, leases, alloc.identity)
+		if err != nil {
+			s.revokeBatch(ctx, leases)
+			return nil, err
+		}
+		acmps, aops := alloc.ops()
+		cmps, ops = append(cmps, acmps...), append(ops, aops...)
+		change := &model.Change{Op: model.OpRollback, Target: version, Added: puts, Removed: removed}
+		hcmps, hops, err := s.record(ctx, name, h, change)
+		if err != nil {
+			s.revokeBatch(ctx, leases)
+			return nil, err
+		}
+		if len(ops)+len(hops) > maxTxnOps {
+			s.revokeBatch(ctx, leases)
+			return nil, ErrTooManyRules
+		}
+
+		txn, cancel := s.client.Txn(ctx)
+		resp, err := txn.If(append(cmps, hcmps...)...).Then(append(ops, hops...)...).Commit()
+		cancel()
+		if err != nil {
+			s.revokeBatch(ctx, leases)
+			return nil, fmt.Errorf("etcd transaction failed: %w", err)
+		}
+		if resp.Succeeded {
+			remaining := remainingCidrs(set, puts, removed)
+			var released []string
+			for cidr := range set.identities {
+				if !remaining[cidr] {
+					released = append(released, cidr)
+				}
+			}
+			s.releaseIdentities(ctx, set.identitiesOf(released))
+			return change, nil
+		}
+		s.revokeBatch(ctx, leases)
+	}
+
+	return nil, ErrConflict
+}
+
+// composeChanges returns the rules touched by the changes as they were before
+// the first change and after the last one, keyed by RuleInfo.Key(). A nil
+// rule is absent.
+func composeChanges(changes []model.Change) (before, after map[string]*model.Rule) {
+	before = make(map[string]*model.Rule)
+	after = make(map[string]*model.Rule)
+	touch := func(key string, r *model.Rule) {
+		if _, ok := before[key]; !ok {
+			before[key] = r
+		}
+	}
+
+	for _, c := range changes {
+		// 更新先删除旧规则再添加新规则
+		for i := range c.Removed {
+			key := c.Removed[i].RuleInfo.Key()
+			touch(key, &c.Removed[i])
+			after[key] = nil
+		}
+		for i := range c.Added {
+			key := c.Added[i].RuleInfo.Key()
+			touch(key, nil)
+			after[key] = &c.Added[i]
+		}
+	}
+	return before, after
+}
+
+// diffRules returns the rules to add and to remove to go from before to after
+func diffRules(before, after map[string]*model.Rule) (added, removed model.RuleItem) {
+	added, removed = model.RuleItem{}, model.RuleItem{}
+	for key, b := range before {
+		a := after[key]
+		if b != nil && a != nil && sameRule(b, a) {
+			continue
+		}
+		if b != nil {
+			removed = append(removed, *b)
+		}
+		if a != nil {
+			added = append(added, *a)
+		}
+	}
+
+	sortRules(added)
+	sortRules(removed)
+	return added, removed
+}
+
+// sameRule compares the metas of two rules of the same key, the identity is
+// left out as a restored rule may get a new one
+func sameRule(a, b *model.Rule) bool {
+	return a.RuleMeta.Comment == b.RuleMeta.Comment &&
+		a.RuleMeta.CreatedAt.Equal(b.RuleMeta.CreatedAt) &&
+		a.RuleMeta.ExpiresAt.Equal(b.RuleMeta.ExpiresAt)
+}
+
+func sortRules(rules model.RuleItem) {
+	slices.SortFunc(rules, func(a, b model.Rule) int { return strings.Compare(a.RuleInfo.Key(), b.RuleInfo.Key()) })
+}
+
+// rollbackTarget returns the rules to put and the rules to remove to undo the
+// changes on set. A rule put over an existing one removes it as well. The
+// rules expired or about to expire are not put back.
+func rollbackTarget(set *ruleSet, changes []model.Change, now time.Time) (puts, removed model.RuleItem, err error) {
+	if slices.ContainsFunc(changes, func(c model.Change) bool { return c.Truncated }) {
+		return nil, nil, ErrVersionTruncated
+	}
+
+	before, _ := composeChanges(changes)
+	for key, b := range before {
+		cur, exists := set.rules[key]
+		if b != nil && !b.RuleMeta.Permanent() && b.RuleMeta.ExpiresAt.Before(now.Add(minRestoreTTL)) {
+			b = nil
+		}
+
+		switch {
+		case b == nil:
+			if exists {
+				removed = append(removed, cur)
+			}
+		case exists && sameRule(&cur, b):
+		default:
+			if exists {
+				removed = append(removed, cur)
+			}
+			puts = append(puts, *b)
+		}
+	}
+
+	sortRules(puts)
+	sortRules(removed)
+	return puts, removed, nil
+}
+
+// remainingCidrs returns the CIDRs still used by a rule after a rollback
+func remainingCidrs(set *ruleSet, puts, removed model.RuleItem) map[string]bool {
+	remaining := make(map[string]bool)
+	for _, r := range puts {
+		remaining[r.RuleInfo.Cidr] = true
+	}
+	gone := make(map[string]bool, len(removed))
+	for _, r := range removed {
+		gone[r.RuleInfo.Key()] = true
+	}
+	for key, r := range set.rules {
+		if !gone[key] {
+			remaining[r.RuleInfo.Cidr] = true
+		}
+	}
+	return remaining
+}
+
+// planRollback returns the transaction putting and removing the rules of a
+// rollback. The identity keys follow their rules like in planAdd and
+// planDelete, on the lease of the rule kept or put expiring last, a missing identity key gets a new identity: the recorded one
+// may have been allocated to another CIDR since.
+func planRollback(name string, set *ruleSet, puts, removed model.RuleItem,
+	leases map[time.Time]clientv3.LeaseID, newIdentity func() (string, error)) ([]clientv3.Cmp, []clientv3.Op, error) {
+
+	remaining := remainingCidrs(set, puts, removed)
+	put := make(map[string]bool, len(puts))
+	for _, r := range puts {
+		put[r.RuleInfo.Key()] = true
+	}
+
+	// 读取之后 rule set 没有被修改过才能回滚
+	cmps := []clientv3.Cmp{
+		clientv3.Compare(clientv3.ModRevision(setPrefix(name)).WithPrefix(), "<", set.revision+1),
+	}
+	var ops []clientv3.Op
+
+	for _, r := range removed {
+		if !put[r.RuleInfo.Key()] {
+			ops = append(ops, clientv3.OpDelete(RuleKey(name, r.RuleInfo.Key())))
+		}
+	}
+	for cidr, key := range set.identities {
+		if !remaining[cidr] {
+			ops = append(ops, clientv3.OpDelete(key))
+		}
+	}
+
+	// CIDR -> the lease its identity key needs for the rules kept and put
+	idLeases := make(map[string]*identityLease)
+	gone := make(map[string]bool, len(removed))
+	for _, r := range removed {
+		gone[r.RuleInfo.Key()] = true
+	}
+	for key, r := range set.rules {
+		if gone[key] || put[key] {
+			continue
+		}
+		if idLeases[r.RuleInfo.Cidr] == nil {
+			idLeases[r.RuleInfo.Cidr] = &identityLease{}
+		}
+		idLeases[r.RuleInfo.Cidr].add(r.RuleMeta.ExpiresAt, set.leases[key])
+	}
+
+	var cidrs []string
+	identities := make(map[string]string)
+	for i := range puts {
+		r := &puts[i]
+		cidr := r.RuleInfo.Cidr
+
+		identity, ok := identities[cidr]
+		if !ok {
+			cidrs = append(cidrs, cidr)
+			if kv := set.identityKVs[cidr]; kv != nil && string(kv.Value) != "0" {
+				identity = string(kv.Value)
+			} else {
+				var err error
+				if identity, err = newIdentity(); err != nil {
+					return nil, nil, err
+				}
+			}
+			identities[cidr] = identity
+			if idLeases[cidr] == nil {
+				idLeases[cidr] = &identityLease{}
+			}
+		}
+		idLeases[cidr].add(r.RuleMeta.ExpiresAt, leases[r.RuleMeta.ExpiresAt])
+
+		r.RuleMeta.Identity = identity
+		ops = append(ops, clientv3.OpPut(RuleKey(name, r.RuleInfo.Key()), r.RuleMeta.MarshalStr(), leaseOption(leases, r.RuleMeta.ExpiresAt)...))
+	}
+	for _, cidr := range cidrs {
+		idKey := RuleKey(name, cidr)
+		kv := set.identityKVs[cidr]
+		if kv == nil || string(kv.Value) == "0" {
+			opts := idLeases[cidr].options()
+			rcmps, rops := registerIdentity(idKey, identities[cidr], opts...)
+			cmps = append(cmps, rcmps...)
+			ops = append(ops, clientv3.OpPut(idKey, identities[cidr], opts...))
+			ops = append(ops, rops...)
+		} else {
+			ops = append(ops, moveIdentity(kv, *idLeases[cidr])...)
+		}
+	}
+
+	named := set.nameRevision != 0
+	switch {
+	case len(remaining) == 0 && named:
+		ops = append(ops, clientv3.OpDelete(nameKey(name)))
+	case len(remaining) > 0 && !named:
+		ops = append(ops, clientv3.OpPut(nameKey(name), ""))
+	}
+	cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(nameKey(name)), "=", set.nameRevision))
+
+	return cmps, ops, nil
+}
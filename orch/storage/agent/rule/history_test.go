@@ -0,0 +1,210 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/rule"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func historyRule(cidr string, dport uint16, comment string, expiresAt time.Time) model.Rule {
+	return model.Rule{
+		RuleInfo: rule.RuleInfo{Cidr: cidr, Protocol: "TCP", Dport: dport},
+		RuleMeta: rule.RuleMeta{Comment: comment, ExpiresAt: expiresAt, Identity: "7"},
+	}
+}
+
+func ruleKeys(rules model.RuleItem) []string {
+	keys := make([]string, 0, len(rules))
+	for _, r := range rules {
+		keys = append(keys, r.RuleInfo.Key()+r.RuleMeta.Comment)
+	}
+	return keys
+}
+
+func TestDiffRules(t *testing.T) {
+	a := historyRule("10.0.0.0/24", 22, "a", time.Time{})
+	b := historyRule("10.0.0.0/24", 80, "b", time.Time{})
+	b2 := historyRule("10.0.0.0/24", 80, "b2", time.Time{})
+	c := historyRule("192.0.2.0/24", 22, "c", time.Time{})
+
+	// 1: add a, b  2: update b  3: delete a, add c  4: delete c
+	changes := []model.Change{
+		{Version: 1, Added: model.RuleItem{a, b}},
+		{Version: 2, Removed: model.RuleItem{b}, Added: model.RuleItem{b2}},
+		{Version: 3, Removed: model.RuleItem{a}, Added: model.RuleItem{c}},
+		{Version: 4, Removed: model.RuleItem{c}},
+	}
+
+	tests := []struct {
+		name           string
+		changes        []model.Change
+		added, removed []string
+	}{
+		{"add", changes[:1], []string{"10.0.0.0/24/TCP/0-22/a", "10.0.0.0/24/TCP/0-80/b"}, []string{}},
+		{"update", changes[1:2], []string{"10.0.0.0/24/TCP/0-80/b2"}, []string{"10.0.0.0/24/TCP/0-80/b"}},
+		{"added then deleted", changes[2:], []string{}, []string{"10.0.0.0/24/TCP/0-22/a"}},
+		{"all", changes, []string{"10.0.0.0/24/TCP/0-80/b2"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, after := composeChanges(tt.changes)
+			added, removed := diffRules(before, after)
+			if got := ruleKeys(added); !slices.Equal(got, tt.added) {
+				t.Errorf("added = %v, want %v", got, tt.added)
+			}
+			if got := ruleKeys(removed); !slices.Equal(got, tt.removed) {
+				t.Errorf("removed = %v, want %v", got, tt.removed)
+			}
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	now := time.Now()
+	a := historyRule("10.0.0.0/24", 22, "a", now.Add(time.Hour))
+	b := historyRule("10.0.0.0/24", 80, "b", time.Time{})
+	b2 := historyRule("10.0.0.0/24", 80, "b2", time.Time{})
+	c := historyRule("192.0.2.0/24", 22, "c", time.Time{})
+	expired := historyRule("198.51.100.0/24", 22, "expired", now.Add(-time.Minute))
+
+	// 版本 0 之后: 删除 a 和 expired, 更新 b, 添加 c
+	changes := []model.Change{
+		{Version: 1, Removed: model.RuleItem{a, expired}},
+		{Version: 2, Removed: model.RuleItem{b}, Added: model.RuleItem{b2}},
+		{Version: 3, Added: model.RuleItem{c}},
+	}
+	set := &ruleSet{
+		revision: 10,
+		rules: map[string]model.Rule{
+			b2.RuleInfo.Key(): b2,
+			c.RuleInfo.Key():  c,
+		},
+		identities: map[string]string{
+			"10.0.0.0/24":  RuleKey("set", "10.0.0.0/24"),
+			"192.0.2.0/24": RuleKey("set", "192.0.2.0/24"),
+		},
+		identityKVs: map[string]*mvccpb.KeyValue{
+			"10.0.0.0/24":  {Value: []byte("42")},
+			"192.0.2.0/24": {Value: []byte("43")},
+		},
+		nameRevision: 5,
+	}
+
+	puts, removed, err := rollbackTarget(set, changes, now)
+	if err != nil {
+		t.Fatalf("rollbackTarget() error = %v", err)
+	}
+	if got, want := ruleKeys(puts), []string{"10.0.0.0/24/TCP/0-22/a", "10.0.0.0/24/TCP/0-80/b"}; !slices.Equal(got, want) {
+		t.Errorf("puts = %v, want %v", got, want)
+	}
+	if got, want := ruleKeys(removed), []string{"10.0.0.0/24/TCP/0-80/b2", "192.0.2.0/24/TCP/0-22/c"}; !slices.Equal(got, want) {
+		t.Errorf("removed = %v, want %v", got, want)
+	}
+
+	leases := map[time.Time]clientv3.LeaseID{a.RuleMeta.ExpiresAt: 1}
+	_, ops, err := planRollback("set", set, puts, removed, leases, func() (string, error) { return "100", nil })
+	if err != nil {
+		t.Fatalf("planRollback() error = %v", err)
+	}
+
+	puts2 := make(map[string]string)
+	var deletes []string
+	for _, op := range ops {
+		if op.IsDelete() {
+			deletes = append(deletes, string(op.KeyBytes()))
+		} else {
+			puts2[string(op.KeyBytes())] = string(op.ValueBytes())
+		}
+	}
+	// c 被删除, 192.0.2.0/24 的 identity 随之删除; b2 被 b 覆盖
+	if want := []string{RuleKey("set", c.RuleInfo.Key()), RuleKey("set", "192.0.2.0/24")}; !slices.Equal(deletes, want) {
+		t.Errorf("deletes = %v, want %v", deletes, want)
+	}
+	if len(puts2) != 2 {
+		t.Fatalf("puts = %v, want the 2 rules", puts2)
+	}
+	var meta rule.RuleMeta
+	if err := json.Unmarshal([]byte(puts2[RuleKey("set", b.RuleInfo.Key())]), &meta); err != nil || meta.Comment != "b" || meta.Identity != "42" {
+		t.Errorf("restored b = %+v, %v", meta, err)
+	}
+
+	// 回到版本 2 什么都不用删除, 只需删除 c
+	puts, removed, _ = rollbackTarget(set, changes[2:], now)
+	if len(puts) != 0 || len(removed) != 1 {
+		t.Errorf("rollback to 2 = %v, %v, want c removed", ruleKeys(puts), ruleKeys(removed))
+	}
+
+	if _, _, err := rollbackTarget(set, []model.Change{{Truncated: true}}, now); err != ErrVersionTruncated {
+		t.Errorf("rollbackTarget() error = %v, want %v", err, ErrVersionTruncated)
+	}
+}
+
+func TestRollbackEmptySet(t *testing.T) {
+	a := historyRule("10.0.0.0/24", 22, "a", time.Time{})
+	set := &ruleSet{revision: 10, rules: map[string]model.Rule{}}
+
+	puts, removed, err := rollbackTarget(set, []model.Change{{Op: model.OpDelete, Removed: model.RuleItem{a}}}, time.Now())
+	if err != nil || len(puts) != 1 || len(removed) != 0 {
+		t.Fatalf("rollbackTarget() = %v, %v, %v", ruleKeys(puts), ruleKeys(removed), err)
+	}
+
+	cmps, ops, err := planRollback("set", set, puts, removed, nil, func() (string, error) { return "100", nil })
+	if err != nil {
+		t.Fatalf("planRollback() error = %v", err)
+	}
+	// rule set 的 revision, name key 和新 identity 的注册
+	if len(cmps) != 3 {
+		t.Errorf("len(cmps) = %d, want 3", len(cmps))
+	}
+	values := make(map[string]string)
+	for _, op := range ops {
+		values[string(op.KeyBytes())] = string(op.ValueBytes())
+	}
+	// 记录下来的 identity 可能已经分配给别的 CIDR, 分配新的 identity
+	if got := values[RuleKey("set", "10.0.0.0/24")]; got != "100" {
+		t.Errorf("identity = %q, want 100", got)
+	}
+	if got := values[identityIDsDir+"0000000100"]; got != RuleKey("set", "10.0.0.0/24") {
+		t.Errorf("100 registered to %q", got)
+	}
+	if _, ok := values[nameKey("set")]; !ok {
+		t.Errorf("the name key is not created")
+	}
+}
+
+func TestRecord(t *testing.T) {
+	ctx := WithActor(context.Background(), "admin")
+	s := Storage{retention: 3}
+
+	change := &model.Change{Op: model.OpAdd}
+	cmps, ops, err := s.record(ctx, "set", head{version: 5, revision: 9}, change)
+	if err != nil {
+		t.Fatalf("record() error = %v", err)
+	}
+	if len(cmps) != 1 || len(ops) != historyOps {
+		t.Fatalf("record() = %d cmps, %d ops", len(cmps), len(ops))
+	}
+	if change.Version != 6 || change.Actor != "admin" || change.Added == nil {
+		t.Errorf("change = %+v", change)
+	}
+	// 保留 4, 5, 6
+	if prune := ops[2]; !prune.IsDelete() || string(prune.RangeBytes()) != versionKey("set", 4) {
+		t.Errorf("prune range end = %s, want %s", prune.RangeBytes(), versionKey("set", 4))
+	}
+
+	_, ops, _ = s.record(context.Background(), "set", head{}, change)
+	if len(ops) != 2 || change.Actor != "unknown" {
+		t.Errorf("first version = %d ops, actor %s", len(ops), change.Actor)
+	}
+
+	if _, ops, _ := (Storage{}).record(ctx, "set", head{}, change); ops != nil {
+		t.Errorf("disabled history recorded %d ops", len(ops))
+	}
+}
@@ -0,0 +1,628 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/orch/storage/agent"
+	"xdp-banner/pkg/etcd"
+	"xdp-banner/pkg/log"
+	"xdp-banner/pkg/rule"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdIdentityDir holds the identity allocator. "<dir>/next" is the next
+// identity to try and "<dir>/ids/<identity>" registers an identity to the
+// identity key holding it, with the lease of that key.
+var EtcdIdentityDir etcd.Key = etcd.Join(agent.EtcdDir, "identity")
+
+var (
+	identityNextKey = etcd.Join(EtcdIdentityDir, "next")
+	identityIDsDir  = etcd.Join(EtcdIdentityDir, "ids") + "/"
+)
+
+// maxIdentity is the largest identity, the datapath keeps them in 32 bits and
+// 0 is no identity
+const maxIdentity = math.MaxUint32
+
+// allocateOps are the operations an allocation adds to its transaction, the
+// counter put
+const allocateOps = 1
+
+// ErrIdentitiesExhausted is returned when every identity is registered
+var ErrIdentitiesExhausted = errors.New("no identity left to allocate")
+
+// identityRegKey is the registration of an identity, false for an identity
+// which is not a number
+func identityRegKey(identity string) (etcd.Key, bool) {
+	id, err := strconv.ParseUint(identity, 10, 32)
+	if err != nil || id == 0 {
+		return "", false
+	}
+	return fmt.Sprintf("%s%010d", identityIDsDir, id), true
+}
+
+// allocation are the identities picked for a transaction, it fails when the
+// counter moved since or one of them got registered
+type allocation struct {
+	ids  []uint32
+	used int
+	// counterRevision is the ModRevision of the counter, 0 when it is missing
+	counterRevision int64
+}
+
+// identity hands out the next identity picked, the planners asked for as
+// many as they use. ErrIdentitiesExhausted is returned past them.
+func (a *allocation) identity() (string, error) {
+	if a.used == len(a.ids) {
+		return "", ErrIdentitiesExhausted
+	}
+	a.used++
+	return strconv.FormatUint(uint64(a.ids[a.used-1]), 10), nil
+}
+
+// ops moves the counter past the identities handed out
+func (a *allocation) ops() ([]clientv3.Cmp, []clientv3.Op) {
+	if a.used == 0 {
+		return nil, nil
+	}
+	next := uint64(a.ids[a.used-1]) + 1
+	if next > maxIdentity {
+		next = 1
+	}
+	cmp := clientv3.Compare(clientv3.ModRevision(identityNextKey), "=", a.counterRevision)
+	return []clientv3.Cmp{cmp}, []clientv3.Op{clientv3.OpPut(identityNextKey, strconv.FormatUint(next, 10))}
+}
+
+// allocate picks n identities which are not registered, from the counter on
+func (s Storage) allocate(ctx context.Context, n int) (*allocation, error) {
+	a := &allocation{}
+	if n == 0 {
+		return a, nil
+	}
+
+	resp, err := s.client.Get(ctx, identityNextKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identity counter: %w", err)
+	}
+	start := uint32(1)
+	if len(resp.Kvs) > 0 {
+		a.counterRevision = resp.Kvs[0].ModRevision
+		next, err := strconv.ParseUint(string(resp.Kvs[0].Value), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity counter %q: %w", resp.Kvs[0].Value, err)
+		}
+		start = uint32(next)
+	}
+
+	a.ids, err = pickIdentities(start, n, func(from uint32, limit int) ([]uint32, error) {
+		return s.registered(ctx, from, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// registered returns the registered identities from an identity on, sorted
+func (s Storage) registered(ctx context.Context, from uint32, limit int) ([]uint32, error) {
+	fromKey, _ := identityRegKey(strconv.FormatUint(uint64(from), 10))
+	resp, err := s.client.Get(ctx, fromKey,
+		clientv3.WithRange(clientv3.GetPrefixRangeEnd(identityIDsDir)),
+		clientv3.WithKeysOnly(), clientv3.WithLimit(int64(limit)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get registered identities: %w", err)
+	}
+
+	ids := make([]uint32, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		id, err := strconv.ParseUint(strings.TrimPrefix(string(kv.Key), identityIDsDir), 10, 32)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint32(id))
+	}
+	return ids, nil
+}
+
+// pickIdentities returns the first n identities from start on which taken
+// does not report, wrapping around after maxIdentity. taken returns at most
+// limit registered identities from an identity on, sorted.
+func pickIdentities(start uint32, n int, taken func(from uint32, limit int) ([]uint32, error)) ([]uint32, error) {
+	start = max(start, 1)
+	ids := make([]uint32, 0, n)
+
+	// 先从 start 找到 maxIdentity, 再从 1 找回 start
+	for _, r := range [][2]uint64{{uint64(start), maxIdentity}, {1, uint64(start) - 1}} {
+		c, end := r[0], r[1]
+		for c <= end && len(ids) < n {
+			limit := n - len(ids)
+			used, err := taken(uint32(c), limit)
+			if err != nil {
+				return nil, err
+			}
+			more := len(used) == limit
+
+			for _, t := range used {
+				if uint64(t) > end {
+					more = false
+					break
+				}
+				for ; c < uint64(t) && len(ids) < n; c++ {
+					ids = append(ids, uint32(c))
+				}
+				c = uint64(t) + 1
+			}
+			if !more {
+				for ; c <= end && len(ids) < n; c++ {
+					ids = append(ids, uint32(c))
+				}
+			}
+		}
+	}
+
+	if len(ids) < n {
+		return nil, ErrIdentitiesExhausted
+	}
+	return ids, nil
+}
+
+// registerIdentity registers a new identity to its identity key, the
+// transaction fails when another key registered it meanwhile
+func registerIdentity(idKey etcd.Key, identity string, opts ...clientv3.OpOption) ([]clientv3.Cmp, []clientv3.Op) {
+	regKey, ok := identityRegKey(identity)
+	if !ok {
+		return nil, nil
+	}
+	return []clientv3.Cmp{clientv3.Compare(clientv3.Version(regKey), "=", 0)},
+		[]clientv3.Op{clientv3.OpPut(regKey, idKey, opts...)}
+}
+
+// followIdentity moves the registration of an identity with its identity key
+// onto another lease. An identity registered to another key, which only the
+// data of the hashed identities has, is left to CheckIdentities.
+func followIdentity(idKey etcd.Key, identity string, opts ...clientv3.OpOption) []clientv3.Op {
+	regKey, ok := identityRegKey(identity)
+	if !ok {
+		return nil
+	}
+	return []clientv3.Op{clientv3.OpTxn(
+		[]clientv3.Cmp{clientv3.Compare(clientv3.Value(regKey), "=", idKey)},
+		[]clientv3.Op{clientv3.OpPut(regKey, idKey, opts...)},
+		nil,
+	)}
+}
+
+// moveIdentity puts an identity key and the registration of its identity on
+// the lease its rules need, nothing when the key is on it already
+func moveIdentity(kv *mvccpb.KeyValue, lease identityLease) []clientv3.Op {
+	if clientv3.LeaseID(kv.Lease) == lease.lease {
+		return nil
+	}
+	idKey, identity := string(kv.Key), string(kv.Value)
+	opts := lease.options()
+	return append([]clientv3.Op{clientv3.OpPut(idKey, identity, opts...)}, followIdentity(idKey, identity, opts...)...)
+}
+
+// releaseIdentities releases the identities of deleted identity keys, keyed
+// by the identity keys. It runs after the delete so the delete keeps fitting
+// in one transaction, an identity left registered is released by
+// CheckIdentities, or by its lease.
+func (s Storage) releaseIdentities(ctx context.Context, identities map[etcd.Key]string) {
+	var ops []clientv3.Op
+	for idKey, identity := range identities {
+		regKey, ok := identityRegKey(identity)
+		if !ok {
+			continue
+		}
+		ops = append(ops, clientv3.OpTxn(
+			[]clientv3.Cmp{clientv3.Compare(clientv3.Value(regKey), "=", idKey)},
+			[]clientv3.Op{clientv3.OpDelete(regKey)},
+			nil,
+		))
+	}
+
+	for len(ops) > 0 {
+		n := min(len(ops), maxTxnOps)
+		txn, cancel := s.client.Txn(ctx)
+		_, err := txn.Then(ops[:n]...).Commit()
+		cancel()
+		if err != nil {
+			log.Warn("failed to release identities, CheckIdentities releases them", log.ErrorField(err))
+			return
+		}
+		ops = ops[n:]
+	}
+}
+
+// identityHolder is an identity key and the rules using its identity, kv is
+// nil for a missing identity key
+type identityHolder struct {
+	key   etcd.Key
+	kv    *mvccpb.KeyValue
+	rules []*mvccpb.KeyValue
+}
+
+// identityPlan is how CheckIdentities repairs the identities
+type identityPlan struct {
+	report *model.IdentityReport
+	// duplicates get a new identity, one transaction each
+	duplicates []*identityHolder
+	// missing are the missing identity keys created with a new identity, one
+	// transaction each, at the same index of report.MissingKeys
+	missing map[int]*identityHolder
+	// recreated are the missing identity keys recreated by ops, at the same
+	// index of report.MissingKeys
+	recreated []int
+	// registers register the identities which are not, the hashed identities
+	// of older versions among them
+	registers []clientv3.Op
+	// ops release and rewrite rules. Like registers each is guarded by its
+	// own comparisons so a key changed meanwhile is left to the next check.
+	ops []clientv3.Op
+}
+
+// CheckIdentities checks that every identity is held by one identity key,
+// registered to it and set in its rules, and that every rule has an identity
+// key. Unless repair is false it gives the duplicates new identities,
+// registers the identities which are not, which the hashed identities of
+// older versions are, releases the registrations no key holds, creates the
+// missing identity keys and fixes the rules.
+func (s Storage) CheckIdentities(ctx context.Context, repair bool) (*model.IdentityReport, error) {
+	operationLock.Lock()
+	defer operationLock.Unlock()
+
+	plan, err := s.planIdentities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !repair {
+		return plan.report, nil
+	}
+
+	for i, h := range plan.duplicates {
+		identity, err := s.reassignIdentity(ctx, h)
+		if err != nil {
+			return nil, fmt.Errorf("failed to give %s a new identity: %w", h.key, err)
+		}
+		plan.report.Duplicates[i].NewIdentity = identity
+	}
+	for _, i := range slices.Sorted(maps.Keys(plan.missing)) {
+		h := plan.missing[i]
+		identity, err := s.reassignIdentity(ctx, h)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", h.key, err)
+		}
+		plan.report.MissingKeys[i].NewIdentity = identity
+	}
+
+	if err := s.commitEach(ctx, append(plan.registers, plan.ops...)); err != nil {
+		return nil, fmt.Errorf("failed to repair identities: %w", err)
+	}
+
+	for _, i := range plan.recreated {
+		plan.report.MissingKeys[i].NewIdentity = plan.report.MissingKeys[i].Identity
+	}
+
+	plan.report.Repaired = true
+	return plan.report, nil
+}
+
+// planIdentities reads the rules and the registrations at one revision and
+// plans their check
+func (s Storage) planIdentities(ctx context.Context) (*identityPlan, error) {
+	txn, cancel := s.client.Txn(ctx)
+	resp, err := txn.Then(
+		clientv3.OpGet(EtcdDir+"/", clientv3.WithPrefix()),
+		clientv3.OpGet(identityIDsDir, clientv3.WithPrefix()),
+	).Commit()
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identities from etcd: %w", err)
+	}
+	return planIdentityCheck(resp.Responses[0].GetResponseRange().Kvs, resp.Responses[1].GetResponseRange().Kvs)
+}
+
+// RegisterIdentities registers the identities which are not, so the
+// allocations skip them. The hashed identities of older versions are not
+// registered, it runs at start and returns how many it registered.
+func (s Storage) RegisterIdentities(ctx context.Context) (int, error) {
+	operationLock.Lock()
+	defer operationLock.Unlock()
+
+	plan, err := s.planIdentities(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.commitEach(ctx, plan.registers); err != nil {
+		return 0, fmt.Errorf("failed to register identities: %w", err)
+	}
+	return len(plan.registers), nil
+}
+
+// commitEach commits the self-guarded ops, maxTxnOps of them per transaction
+func (s Storage) commitEach(ctx context.Context, ops []clientv3.Op) error {
+	for len(ops) > 0 {
+		n := min(len(ops), maxTxnOps)
+		txn, cancel := s.client.Txn(ctx)
+		_, err := txn.Then(ops[:n]...).Commit()
+		cancel()
+		if err != nil {
+			return err
+		}
+		ops = ops[n:]
+	}
+	return nil
+}
+
+// reassignIdentity gives an identity key and its rules a new identity, the
+// keys keep their leases. A missing identity key is created on the lease of
+// its rules, see identityLease.
+func (s Storage) reassignIdentity(ctx context.Context, h *identityHolder) (string, error) {
+	for range txnRetries {
+		alloc, err := s.allocate(ctx, 1)
+		if err != nil {
+			return "", err
+		}
+		identity, err := alloc.identity()
+		if err != nil {
+			return "", err
+		}
+
+		cmps, ops, err := planReassign(h, identity)
+		if err != nil {
+			return "", err
+		}
+		acmps, aops := alloc.ops()
+		cmps, ops = append(cmps, acmps...), append(ops, aops...)
+		if len(ops) > maxTxnOps {
+			return "", ErrTooManyRules
+		}
+
+		txn, cancel := s.client.Txn(ctx)
+		resp, err := txn.If(cmps...).Then(ops...).Commit()
+		cancel()
+		if err != nil {
+			return "", fmt.Errorf("etcd transaction failed: %w", err)
+		}
+		if resp.Succeeded {
+			return identity, nil
+		}
+	}
+	return "", ErrConflict
+}
+
+// planReassign returns the transaction giving an identity key and its rules
+// a new identity, it fails when one of them changed since the check
+func planReassign(h *identityHolder, identity string) ([]clientv3.Cmp, []clientv3.Op, error) {
+	idKey := h.key
+	var cmps []clientv3.Cmp
+	var ops []clientv3.Op
+	if h.kv == nil {
+		var lease identityLease
+		for _, kv := range h.rules {
+			if err := lease.addKV(kv); err != nil {
+				return nil, nil, err
+			}
+		}
+		cmps, ops = registerIdentity(idKey, identity, lease.options()...)
+		cmps = append(cmps, clientv3.Compare(clientv3.Version(idKey), "=", 0))
+		ops = append(ops, clientv3.OpPut(idKey, identity, lease.options()...))
+	} else {
+		cmps, ops = registerIdentity(idKey, identity, leaseOf(h.kv)...)
+		cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(idKey), "=", h.kv.ModRevision))
+		ops = append(ops, clientv3.OpPut(idKey, identity, clientv3.WithIgnoreLease()))
+	}
+
+	for _, kv := range h.rules {
+		value, err := withIdentity(kv, identity)
+		if err != nil {
+			return nil, nil, err
+		}
+		cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(string(kv.Key)), "=", kv.ModRevision))
+		ops = append(ops, clientv3.OpPut(string(kv.Key), value, clientv3.WithIgnoreLease()))
+	}
+	return cmps, ops, nil
+}
+
+// planIdentityCheck finds the identity problems of the keys of EtcdDir and the
+// registrations. An identity held by several keys stays with the key it is
+// registered to, or the first key. A missing identity key is recreated with
+// the identity of its first rule when no other key holds it, with a new
+// identity otherwise.
+func planIdentityCheck(kvs, regs []*mvccpb.KeyValue) (*identityPlan, error) {
+	holders := make(map[etcd.Key]*identityHolder)
+	var ruleKVs []*mvccpb.KeyValue
+	for _, kv := range kvs {
+		if _, _, ok := parseIndexKey(string(kv.Key)); ok {
+			ruleKVs = append(ruleKVs, kv)
+		} else {
+			holders[string(kv.Key)] = &identityHolder{key: string(kv.Key), kv: kv}
+		}
+	}
+	// 缺少 identity key 的规则, 按 identity key 分组
+	missing := make(map[etcd.Key]*identityHolder)
+	for _, kv := range ruleKVs {
+		name, info, _ := parseIndexKey(string(kv.Key))
+		idKey := RuleKey(name, info.IdentityKey())
+		if h := holders[idKey]; h != nil {
+			h.rules = append(h.rules, kv)
+			continue
+		}
+		if missing[idKey] == nil {
+			missing[idKey] = &identityHolder{key: idKey}
+		}
+		missing[idKey].rules = append(missing[idKey].rules, kv)
+	}
+
+	// identity -> the identity keys holding it, sorted
+	held := make(map[string][]etcd.Key)
+	for key, h := range holders {
+		if _, ok := identityRegKey(string(h.kv.Value)); ok {
+			held[string(h.kv.Value)] = append(held[string(h.kv.Value)], key)
+		}
+	}
+	registered := make(map[string]*mvccpb.KeyValue, len(regs))
+	for _, kv := range regs {
+		id, err := strconv.ParseUint(strings.TrimPrefix(string(kv.Key), identityIDsDir), 10, 32)
+		if err != nil {
+			continue
+		}
+		registered[strconv.FormatUint(id, 10)] = kv
+	}
+
+	plan := &identityPlan{report: &model.IdentityReport{IdentityKeys: len(holders)}}
+	for _, identity := range slices.Sorted(maps.Keys(held)) {
+		keys := held[identity]
+		slices.Sort(keys)
+		reg := registered[identity]
+
+		keeper := keys[0]
+		if reg != nil && slices.Contains(keys, string(reg.Value)) {
+			keeper = string(reg.Value)
+		}
+		for _, key := range keys {
+			if key != keeper {
+				plan.duplicates = append(plan.duplicates, holders[key])
+				plan.report.Duplicates = append(plan.report.Duplicates, model.IdentityFix{Key: key, Identity: identity})
+			}
+		}
+
+		h := holders[keeper]
+		if reg == nil || string(reg.Value) != keeper {
+			plan.report.Unregistered = append(plan.report.Unregistered, keeper)
+			regKey, _ := identityRegKey(identity)
+			regCmp := clientv3.Compare(clientv3.Version(regKey), "=", 0)
+			if reg != nil {
+				regCmp = clientv3.Compare(clientv3.ModRevision(regKey), "=", reg.ModRevision)
+			}
+			plan.registers = append(plan.registers, clientv3.OpTxn(
+				[]clientv3.Cmp{regCmp, clientv3.Compare(clientv3.ModRevision(keeper), "=", h.kv.ModRevision)},
+				[]clientv3.Op{clientv3.OpPut(regKey, keeper, leaseOf(h.kv)...)},
+				nil,
+			))
+		}
+
+		for _, kv := range h.rules {
+			var meta rule.RuleMeta
+			if err := json.Unmarshal(kv.Value, &meta); err != nil || meta.Identity == identity {
+				continue
+			}
+			value, err := withIdentity(kv, identity)
+			if err != nil {
+				return nil, err
+			}
+			plan.report.StaleRules = append(plan.report.StaleRules, string(kv.Key))
+			plan.ops = append(plan.ops, clientv3.OpTxn(
+				[]clientv3.Cmp{clientv3.Compare(clientv3.ModRevision(string(kv.Key)), "=", kv.ModRevision)},
+				[]clientv3.Op{clientv3.OpPut(string(kv.Key), value, clientv3.WithIgnoreLease())},
+				nil,
+			))
+		}
+	}
+
+	plan.missing = make(map[int]*identityHolder)
+	for _, idKey := range slices.Sorted(maps.Keys(missing)) {
+		h := missing[idKey]
+		var meta rule.RuleMeta
+		if err := json.Unmarshal(h.rules[0].Value, &meta); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal rule meta of %s: %w", h.rules[0].Key, err)
+		}
+		identity := meta.Identity
+		plan.report.MissingKeys = append(plan.report.MissingKeys, model.IdentityFix{Key: idKey, Identity: identity})
+		fix := len(plan.report.MissingKeys) - 1
+
+		regKey, ok := identityRegKey(identity)
+		reg := registered[identity]
+		if !ok || len(held[identity]) > 0 || (reg != nil && string(reg.Value) != idKey) {
+			plan.missing[fix] = h
+			continue
+		}
+
+		// identity 没有被其它 key 持有, 原样恢复 identity key 和注册
+		held[identity] = []etcd.Key{idKey}
+		plan.recreated = append(plan.recreated, fix)
+		op, err := planRecreate(h, identity, regKey, reg)
+		if err != nil {
+			return nil, err
+		}
+		plan.ops = append(plan.ops, op)
+	}
+
+	for _, identity := range slices.Sorted(maps.Keys(registered)) {
+		if _, ok := held[identity]; ok {
+			continue
+		}
+		reg := registered[identity]
+		plan.report.Orphans = append(plan.report.Orphans, identity)
+		plan.ops = append(plan.ops, clientv3.OpTxn(
+			[]clientv3.Cmp{clientv3.Compare(clientv3.ModRevision(string(reg.Key)), "=", reg.ModRevision)},
+			[]clientv3.Op{clientv3.OpDelete(string(reg.Key))},
+			nil,
+		))
+	}
+
+	return plan, nil
+}
+
+// planRecreate returns the operation recreating a missing identity key with
+// the identity of its rules on the lease of its rules, registering it and
+// fixing the rules using another identity. It does nothing when one of the
+// keys changed since the check.
+func planRecreate(h *identityHolder, identity string, regKey etcd.Key, reg *mvccpb.KeyValue) (clientv3.Op, error) {
+	var lease identityLease
+	for _, kv := range h.rules {
+		if err := lease.addKV(kv); err != nil {
+			return clientv3.Op{}, err
+		}
+	}
+
+	regCmp := clientv3.Compare(clientv3.Version(regKey), "=", 0)
+	if reg != nil {
+		regCmp = clientv3.Compare(clientv3.ModRevision(regKey), "=", reg.ModRevision)
+	}
+	cmps := []clientv3.Cmp{clientv3.Compare(clientv3.Version(h.key), "=", 0), regCmp}
+	ops := []clientv3.Op{
+		clientv3.OpPut(h.key, identity, lease.options()...),
+		clientv3.OpPut(regKey, h.key, lease.options()...),
+	}
+	for _, kv := range h.rules {
+		var meta rule.RuleMeta
+		if err := json.Unmarshal(kv.Value, &meta); err != nil || meta.Identity == identity {
+			continue
+		}
+		value, err := withIdentity(kv, identity)
+		if err != nil {
+			return clientv3.Op{}, err
+		}
+		cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(string(kv.Key)), "=", kv.ModRevision))
+		ops = append(ops, clientv3.OpPut(string(kv.Key), value, clientv3.WithIgnoreLease()))
+	}
+	return clientv3.OpTxn(cmps, ops, nil), nil
+}
+
+// withIdentity returns the meta of a rule key with another identity
+func withIdentity(kv *mvccpb.KeyValue, identity string) (string, error) {
+	var meta rule.RuleMeta
+	if err := json.Unmarshal(kv.Value, &meta); err != nil {
+		return "", fmt.Errorf("failed to unmarshal rule meta of %s: %w", kv.Key, err)
+	}
+	meta.Identity = identity
+	return meta.MarshalStr(), nil
+}
+
+func leaseOf(kv *mvccpb.KeyValue) []clientv3.OpOption {
+	if kv.Lease == 0 {
+		return nil
+	}
+	return []clientv3.OpOption{clientv3.WithLease(clientv3.LeaseID(kv.Lease))}
+}
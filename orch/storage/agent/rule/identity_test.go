@@ -0,0 +1,191 @@
+package rule
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/rule"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// takenFrom fakes the registered identities for pickIdentities
+func takenFrom(registered ...uint32) func(from uint32, limit int) ([]uint32, error) {
+	return func(from uint32, limit int) ([]uint32, error) {
+		var ids []uint32
+		for _, id := range registered {
+			if id >= from && len(ids) < limit {
+				ids = append(ids, id)
+			}
+		}
+		return ids, nil
+	}
+}
+
+func TestPickIdentities(t *testing.T) {
+	tests := []struct {
+		name       string
+		start      uint32
+		n          int
+		registered []uint32
+		want       []uint32
+	}{
+		{"empty", 0, 3, nil, []uint32{1, 2, 3}},
+		{"skip registered", 5, 3, []uint32{2, 5, 6, 8}, []uint32{7, 9, 10}},
+		{"many registered", 1, 2, []uint32{1, 2, 3, 4, 5}, []uint32{6, 7}},
+		{"wrap around", maxIdentity - 1, 3, []uint32{1, maxIdentity}, []uint32{maxIdentity - 1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pickIdentities(tt.start, tt.n, takenFrom(tt.registered...))
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("pickIdentities() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocationOps(t *testing.T) {
+	a := &allocation{ids: []uint32{7, maxIdentity}, counterRevision: 3}
+	if cmps, ops := a.ops(); cmps != nil || ops != nil {
+		t.Errorf("an unused allocation moves the counter")
+	}
+
+	a.identity()
+	if got, err := a.identity(); got != "4294967295" || err != nil {
+		t.Errorf("identity() = %s, %v", got, err)
+	}
+	if _, err := a.identity(); err != ErrIdentitiesExhausted {
+		t.Errorf("identity() past the allocation = %v, want %v", err, ErrIdentitiesExhausted)
+	}
+	_, ops := a.ops()
+	if len(ops) != 1 || string(ops[0].KeyBytes()) != identityNextKey || string(ops[0].ValueBytes()) != "1" {
+		t.Errorf("the counter does not wrap around")
+	}
+}
+
+func TestPlanIdentityCheck(t *testing.T) {
+	kv := func(key, value string, revision int64) *mvccpb.KeyValue {
+		return &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value), ModRevision: revision}
+	}
+	idA := RuleKey("a", "10.0.0.0/24")
+	idB := RuleKey("b", "10.0.0.0/24")
+	idC := RuleKey("b", "192.0.2.0/24")
+	ruleA := RuleKey("a", "10.0.0.0/24/TCP/0-22")
+	ruleB := RuleKey("b", "10.0.0.0/24/TCP/0-22")
+	ruleC := RuleKey("b", "192.0.2.0/24/TCP/0-22")
+	regKey := func(identity string) string {
+		key, _ := identityRegKey(identity)
+		return key
+	}
+
+	kvs := []*mvccpb.KeyValue{
+		// a and b hashed to the same identity, b is registered to it
+		kv(idA, "42", 1), kv(ruleA, `{"identity":"42"}`, 2),
+		kv(idB, "42", 3), kv(ruleB, `{"identity":"42"}`, 4),
+		// c is not registered and its rule has an old identity
+		kv(idC, "7", 5), kv(ruleC, `{"identity":"8"}`, 6),
+	}
+	regs := []*mvccpb.KeyValue{kv(regKey("42"), idB, 7), kv(regKey("9"), idC, 8)}
+
+	plan, err := planIdentityCheck(kvs, regs)
+	if err != nil {
+		t.Fatalf("planIdentityCheck() error = %v", err)
+	}
+	report := plan.report
+	if report.IdentityKeys != 3 {
+		t.Errorf("IdentityKeys = %d, want 3", report.IdentityKeys)
+	}
+	if len(report.Duplicates) != 1 || report.Duplicates[0].Key != idA || len(plan.duplicates) != 1 || len(plan.duplicates[0].rules) != 1 {
+		t.Errorf("Duplicates = %+v, want %s", report.Duplicates, idA)
+	}
+	if !slices.Equal(report.Unregistered, []string{idC}) {
+		t.Errorf("Unregistered = %v", report.Unregistered)
+	}
+	if !slices.Equal(report.Orphans, []string{"9"}) {
+		t.Errorf("Orphans = %v", report.Orphans)
+	}
+	if !slices.Equal(report.StaleRules, []string{ruleC}) {
+		t.Errorf("StaleRules = %v", report.StaleRules)
+	}
+	// 注册 7, 修正 c 的规则, 释放 9
+	if len(plan.registers) != 1 || len(plan.ops) != 2 {
+		t.Errorf("len(registers) = %d, len(ops) = %d, want 1 and 2", len(plan.registers), len(plan.ops))
+	}
+
+	cmps, ops, err := planReassign(plan.duplicates[0], "100")
+	if err != nil {
+		t.Fatalf("planReassign() error = %v", err)
+	}
+	// 100 的注册, identity key 和规则
+	if len(cmps) != 3 || len(ops) != 3 {
+		t.Fatalf("planReassign() = %d cmps, %d ops", len(cmps), len(ops))
+	}
+	if string(ops[0].KeyBytes()) != regKey("100") || string(ops[0].ValueBytes()) != idA {
+		t.Errorf("registration = %s -> %s", ops[0].KeyBytes(), ops[0].ValueBytes())
+	}
+	var meta rule.RuleMeta
+	if err := json.Unmarshal(ops[2].ValueBytes(), &meta); err != nil || meta.Identity != "100" {
+		t.Errorf("rule = %s, %v", ops[2].ValueBytes(), err)
+	}
+}
+
+func TestPlanIdentityCheckMissingKeys(t *testing.T) {
+	kv := func(key, value string, revision int64) *mvccpb.KeyValue {
+		return &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value), ModRevision: revision}
+	}
+	idA := RuleKey("set", "10.0.0.0/24")
+	idD := RuleKey("set", "198.51.100.0/24")
+	idE := RuleKey("set", "203.0.113.0/24")
+	regKey := func(identity string) string {
+		key, _ := identityRegKey(identity)
+		return key
+	}
+
+	kvs := []*mvccpb.KeyValue{
+		kv(idA, "42", 1), kv(RuleKey("set", "10.0.0.0/24/TCP/0-22"), `{"identity":"42"}`, 2),
+		// the identity key of d expired, its identity is still registered to it
+		kv(RuleKey("set", "198.51.100.0/24/TCP/0-22"), `{"identity":"50"}`, 3),
+		kv(RuleKey("set", "198.51.100.0/24/TCP/0-80"), `{"identity":"51"}`, 4),
+		// the identity of e was handed to a
+		kv(RuleKey("set", "203.0.113.0/24/TCP/0-22"), `{"identity":"42"}`, 5),
+	}
+	regs := []*mvccpb.KeyValue{kv(regKey("42"), idA, 6), kv(regKey("50"), idD, 7)}
+
+	plan, err := planIdentityCheck(kvs, regs)
+	if err != nil {
+		t.Fatalf("planIdentityCheck() error = %v", err)
+	}
+	report := plan.report
+	want := []model.IdentityFix{{Key: idD, Identity: "50"}, {Key: idE, Identity: "42"}}
+	if !slices.Equal(report.MissingKeys, want) {
+		t.Fatalf("MissingKeys = %+v, want %+v", report.MissingKeys, want)
+	}
+	if len(report.Orphans) != 0 || len(report.Duplicates) != 0 {
+		t.Errorf("Orphans = %v, Duplicates = %v", report.Orphans, report.Duplicates)
+	}
+	// d 原样恢复, e 分配新的 identity
+	if !slices.Equal(plan.recreated, []int{0}) || len(plan.missing) != 1 || plan.missing[1].key != idE {
+		t.Fatalf("recreated = %v, missing = %v", plan.recreated, plan.missing)
+	}
+	if len(plan.ops) != 1 || !plan.ops[0].IsTxn() {
+		t.Fatalf("ops = %v, want the recreation of d", plan.ops)
+	}
+	cmps, ops, _ := plan.ops[0].Txn()
+	// 不存在的 identity key, 注册, 使用 51 的规则
+	if len(cmps) != 3 || len(ops) != 3 {
+		t.Errorf("recreation = %d cmps, %d ops", len(cmps), len(ops))
+	}
+
+	cmps, ops, err = planReassign(plan.missing[1], "100")
+	if err != nil {
+		t.Fatalf("planReassign() error = %v", err)
+	}
+	if len(cmps) != 3 || len(ops) != 3 {
+		t.Fatalf("planReassign() = %d cmps, %d ops", len(cmps), len(ops))
+	}
+	if string(ops[1].KeyBytes()) != idE || string(ops[1].ValueBytes()) != "100" {
+		t.Errorf("identity key = %s -> %s", ops[1].KeyBytes(), ops[1].ValueBytes())
+	}
+}
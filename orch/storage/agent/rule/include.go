@@ -0,0 +1,117 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"xdp-banner/orch/storage/agent"
+	"xdp-banner/pkg/etcd"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdIncludesDir holds the includes of the rule sets, "<dir>/<name>" is a
+// JSON list of names
+var EtcdIncludesDir etcd.Key = etcd.Join(agent.EtcdDir, "includes/")
+
+// ErrIncludeCycle is returned when the includes of a rule set lead back to it
+var ErrIncludeCycle = errors.New("rule set includes form a cycle")
+
+func includesKey(name string) etcd.Key {
+	return etcd.Join(EtcdIncludesDir, name)
+}
+
+// Includes are the includes of every rule set read at a revision
+type Includes struct {
+	Revision int64
+	Sets     map[string][]string
+}
+
+// ListIncludes reads the includes of every rule set
+func (s Storage) ListIncludes(ctx context.Context) (*Includes, error) {
+	prefix := EtcdIncludesDir + "/"
+	resp, err := s.client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get includes from etcd: %w", err)
+	}
+
+	includes := &Includes{Revision: resp.Header.Revision, Sets: make(map[string][]string, len(resp.Kvs))}
+	for _, kv := range resp.Kvs {
+		var names []string
+		if err := json.Unmarshal(kv.Value, &names); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal includes of %s: %w", kv.Key, err)
+		}
+		includes.Sets[strings.TrimPrefix(string(kv.Key), prefix)] = names
+	}
+	return includes, nil
+}
+
+// SetIncludes replaces the includes of a rule set, an empty list removes them.
+// It fails with ErrConflict when any includes changed after the revision they
+// were read at, the caller checked the cycles on that revision.
+func (s Storage) SetIncludes(ctx context.Context, name string, includes []string, revision int64) error {
+	op := clientv3.OpDelete(includesKey(name))
+	if len(includes) > 0 {
+		value, err := json.Marshal(includes)
+		if err != nil {
+			return fmt.Errorf("failed to marshal includes: %w", err)
+		}
+		op = clientv3.OpPut(includesKey(name), string(value))
+	}
+
+	txn, cancel := s.client.Txn(ctx)
+	defer cancel()
+	resp, err := txn.If(
+		clientv3.Compare(clientv3.ModRevision(EtcdIncludesDir+"/").WithPrefix(), "<", revision+1),
+	).Then(op).Commit()
+	if err != nil {
+		return fmt.Errorf("etcd transaction failed: %w", err)
+	}
+	if !resp.Succeeded {
+		return ErrConflict
+	}
+	return nil
+}
+
+// ResolveIncludes returns the rule sets merged into name, from the highest
+// precedence: name itself, then every include in its declared order followed
+// by its own includes. A set included several times keeps its first place.
+// A cycle is reported with the sets leading back to where it started, the
+// order stops at the cycle.
+func ResolveIncludes(includes map[string][]string, name string) ([]string, error) {
+	var order []string
+	seen := make(map[string]bool)
+	// path are the sets being resolved, a set of the path included again is a cycle
+	var path []string
+	var cycle []string
+
+	var visit func(set string)
+	visit = func(set string) {
+		if i := slices.Index(path, set); i >= 0 {
+			if cycle == nil {
+				cycle = append(append([]string{}, path[i:]...), set)
+			}
+			return
+		}
+		if seen[set] {
+			return
+		}
+		seen[set] = true
+		order = append(order, set)
+
+		path = append(path, set)
+		for _, inc := range includes[set] {
+			visit(inc)
+		}
+		path = path[:len(path)-1]
+	}
+	visit(name)
+
+	if cycle != nil {
+		return order, fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(cycle, " -> "))
+	}
+	return order, nil
+}
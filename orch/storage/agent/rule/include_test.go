@@ -0,0 +1,38 @@
+package rule
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestResolveIncludes(t *testing.T) {
+	tests := []struct {
+		name     string
+		includes map[string][]string
+		want     []string
+		cycle    bool
+	}{
+		{"no includes", nil, []string{"a"}, false},
+		{"depth first", map[string][]string{"a": {"b", "c"}, "b": {"d"}}, []string{"a", "b", "d", "c"}, false},
+		{"diamond", map[string][]string{"a": {"b", "c"}, "b": {"d"}, "c": {"d"}}, []string{"a", "b", "d", "c"}, false},
+		{"cycle", map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}}, []string{"a", "b", "c"}, true},
+		{"cycle below", map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"b"}}, []string{"a", "b", "c"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveIncludes(tt.includes, "a")
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ResolveIncludes() = %v, want %v", got, tt.want)
+			}
+			if errors.Is(err, ErrIncludeCycle) != tt.cycle {
+				t.Errorf("ResolveIncludes() error = %v, want cycle %v", err, tt.cycle)
+			}
+		})
+	}
+
+	_, err := ResolveIncludes(map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"b"}}, "a")
+	if want := "rule set includes form a cycle: b -> c -> b"; err == nil || err.Error() != want {
+		t.Errorf("error = %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,164 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/cidr"
+	"xdp-banner/pkg/etcd"
+	"xdp-banner/pkg/informer"
+	"xdp-banner/pkg/log"
+	"xdp-banner/pkg/rule"
+)
+
+// ErrIndexNotSynced is returned by a search before the index has listed the rules
+var ErrIndexNotSynced = errors.New("rule index is not synced yet")
+
+// indexEntry is a rule of the index
+type indexEntry struct {
+	name string
+	rule model.Rule
+}
+
+// Index indexes the rules of every rule set by CIDR. It is kept up to date by
+// an informer on EtcdDir, the identity keys are ignored.
+type Index struct {
+	// synced reports whether the informer has listed the rules
+	synced func() bool
+
+	mu sync.RWMutex
+	// trie maps a CIDR to its rules keyed by their etcd key
+	trie cidr.Trie[map[etcd.Key]indexEntry]
+}
+
+// NewIndex starts an informer filling the index, it stops with ctx
+func NewIndex(ctx context.Context, client etcd.Client) *Index {
+	// EtcdDir 没有结尾的 "/", 会匹配到 ruleNames
+	prefix := EtcdDir + "/"
+
+	deltaFIFO := informer.NewDeltaFIFOWithWait(1)
+	reflector := informer.NewReflector(client, "rule_index_reflector", prefix, deltaFIFO)
+	i := informer.New(deltaFIFO)
+
+	idx := &Index{synced: i.HasSynced}
+	i.Register([]string{prefix}, idx)
+
+	go reflector.Run(ctx)
+	go i.Run(ctx)
+
+	return idx
+}
+
+// Search yields the rules overlapping p, first the ones covering p from the
+// shortest CIDR, then the ones inside it in address order. The rules of a CIDR
+// are yielded by key, a search stopped early returns the same rules each time.
+func (x *Index) Search(p netip.Prefix, yield func(model.Match) bool) error {
+	if !x.synced() {
+		return ErrIndexNotSynced
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	stopped := false
+	visit := func(covers bool) func(netip.Prefix, map[etcd.Key]indexEntry) bool {
+		return func(_ netip.Prefix, entries map[etcd.Key]indexEntry) bool {
+			for _, key := range slices.Sorted(maps.Keys(entries)) {
+				e := entries[key]
+				if !yield(model.Match{Name: e.name, Rule: e.rule, Covers: covers}) {
+					stopped = true
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	x.trie.Covering(p, visit(true))
+	if !stopped {
+		x.trie.Inside(p, visit(false))
+	}
+	return nil
+}
+
+func (x *Index) OnAdd(key etcd.Key, obj any, _ bool) {
+	x.put(key, obj)
+}
+
+func (x *Index) OnUpdate(key etcd.Key, _, newObj any) {
+	x.put(key, newObj)
+}
+
+func (x *Index) OnDelete(key etcd.Key, _ any) {
+	_, info, ok := parseIndexKey(key)
+	if !ok {
+		return
+	}
+	p, err := cidr.Parse(info.Cidr)
+	if err != nil {
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	entries, ok := x.trie.Get(p)
+	if !ok {
+		return
+	}
+	delete(entries, key)
+	if len(entries) == 0 {
+		x.trie.Delete(p)
+	}
+}
+
+func (x *Index) put(key etcd.Key, obj any) {
+	name, info, ok := parseIndexKey(key)
+	if !ok {
+		return
+	}
+
+	raw, ok := obj.(string)
+	if !ok {
+		log.Warn("rule index got a value which is not a string", log.StringField("key", key))
+		return
+	}
+	var meta rule.RuleMeta
+	if err := json.Unmarshal([]byte(raw), &meta); err != nil {
+		log.Warn("rule index failed to unmarshal rule meta", log.StringField("key", key), log.ErrorField(err))
+		return
+	}
+	p, err := cidr.Parse(info.Cidr)
+	if err != nil {
+		log.Warn("rule index got an invalid cidr", log.StringField("key", key), log.ErrorField(err))
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	entries, ok := x.trie.Get(p)
+	if !ok {
+		entries = make(map[etcd.Key]indexEntry)
+		x.trie.Insert(p, entries)
+	}
+	entries[key] = indexEntry{name: name, rule: model.Rule{RuleInfo: info, RuleMeta: meta}}
+}
+
+// parseIndexKey parses "<EtcdDir>/<name>/<ip>/<mask>/<PROTO>/<sport>-<dport>",
+// identity keys are reported as not a rule
+func parseIndexKey(key etcd.Key) (string, rule.RuleInfo, bool) {
+	name, relPath, ok := strings.Cut(strings.TrimPrefix(key, EtcdDir+"/"), "/")
+	if !ok {
+		return "", rule.RuleInfo{}, false
+	}
+
+	info, ok := parseRuleKey(relPath)
+	return name, info, ok
+}
@@ -0,0 +1,103 @@
+package rule
+
+import (
+	"net/netip"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/rule"
+)
+
+func TestIndex(t *testing.T) {
+	x := &Index{synced: func() bool { return true }}
+
+	meta := rule.RuleMeta{Comment: "test", Identity: "1"}
+	for _, key := range []string{
+		RuleKey("a", "10.0.0.0/8/TCP/0-22"),
+		RuleKey("a", "10.1.0.0/16/UDP/0-53"),
+		RuleKey("b", "10.1.2.3/32/TCP/0-0"),
+		RuleKey("b", "10.1.2.3/32/ICMP/0-0"),
+		RuleKey("b", "2001:db8::/32/TCP/0-80"),
+	} {
+		x.OnAdd(key, meta.MarshalStr(), true)
+	}
+	// identity key 和其它 key 不进入索引
+	x.OnAdd(RuleKey("a", "10.0.0.0/8"), "42", true)
+	x.OnAdd(EtcdNamesDir, `["a","b"]`, true)
+
+	search := func(s string) []string {
+		var result []string
+		err := x.Search(netip.MustParsePrefix(s), func(m model.Match) bool {
+			result = append(result, m.Name+" "+m.Rule.RuleInfo.Key()+" "+map[bool]string{true: "covers", false: "inside"}[m.Covers])
+			return true
+		})
+		if err != nil {
+			t.Fatalf("Search(%s) error = %v", s, err)
+		}
+		slices.Sort(result)
+		return result
+	}
+
+	if got, want := search("10.1.2.3/32"), []string{
+		"a 10.0.0.0/8/TCP/0-22/ covers",
+		"a 10.1.0.0/16/UDP/0-53/ covers",
+		"b 10.1.2.3/32/ICMP/0-0/ covers",
+		"b 10.1.2.3/32/TCP/0-0/ covers",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search(10.1.2.3/32) = %v, want %v", got, want)
+	}
+	if got, want := search("10.1.0.0/16"), []string{
+		"a 10.0.0.0/8/TCP/0-22/ covers",
+		"a 10.1.0.0/16/UDP/0-53/ covers",
+		"b 10.1.2.3/32/ICMP/0-0/ inside",
+		"b 10.1.2.3/32/TCP/0-0/ inside",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search(10.1.0.0/16) = %v, want %v", got, want)
+	}
+
+	// 截断的结果按 key 排序, 每次相同
+	first := func() string {
+		var key string
+		_ = x.Search(netip.MustParsePrefix("10.1.0.0/16"), func(m model.Match) bool {
+			if m.Covers {
+				return true
+			}
+			key = m.Rule.RuleInfo.Key()
+			return false
+		})
+		return key
+	}
+	for range 10 {
+		if got := first(); got != "10.1.2.3/32/ICMP/0-0/" {
+			t.Fatalf("first rule inside 10.1.0.0/16 = %s", got)
+		}
+	}
+
+	x.OnDelete(RuleKey("b", "10.1.2.3/32/TCP/0-0"), nil)
+	x.OnDelete(RuleKey("b", "10.1.2.3/32/ICMP/0-0"), nil)
+	updated := rule.RuleMeta{Comment: "updated"}
+	x.OnUpdate(RuleKey("a", "10.0.0.0/8/TCP/0-22"), nil, updated.MarshalStr())
+	var comments []string
+	_ = x.Search(netip.MustParsePrefix("10.1.2.3/32"), func(m model.Match) bool {
+		comments = append(comments, m.Rule.RuleMeta.Comment)
+		return true
+	})
+	if !reflect.DeepEqual(comments, []string{"updated", "test"}) {
+		t.Errorf("comments after update = %v", comments)
+	}
+	if x.trie.Len() != 3 {
+		t.Errorf("index has %d CIDRs, want 3", x.trie.Len())
+	}
+	if got := search("2001:db8::1/128"); len(got) != 1 || !strings.HasPrefix(got[0], "b 2001:db8::/32") {
+		t.Errorf("Search(2001:db8::1/128) = %v", got)
+	}
+}
+
+func TestIndexNotSynced(t *testing.T) {
+	x := &Index{synced: func() bool { return false }}
+	if err := x.Search(netip.MustParsePrefix("10.0.0.0/8"), func(model.Match) bool { return true }); err != ErrIndexNotSynced {
+		t.Errorf("Search() error = %v, want ErrIndexNotSynced", err)
+	}
+}
@@ -0,0 +1,185 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/pkg/log"
+	"xdp-banner/pkg/rule"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// grantUntil grants a lease expiring at expiresAt, a zero expiresAt is a
+// permanent rule which gets no lease
+func (s Storage) grantUntil(ctx context.Context, expiresAt time.Time) (clientv3.LeaseID, []clientv3.OpOption, error) {
+	if expiresAt.IsZero() {
+		return clientv3.NoLease, nil, nil
+	}
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return clientv3.NoLease, nil, fmt.Errorf("ExpiresAt must be in the future")
+	}
+
+	leaseResp, err := s.client.Grant(ctx, ttl)
+	if err != nil {
+		return clientv3.NoLease, nil, fmt.Errorf("failed to create lease: %w", err)
+	}
+	return leaseResp.ID, []clientv3.OpOption{clientv3.WithLease(leaseResp.ID)}, nil
+}
+
+// identityLease is the lease the identity key of a CIDR is put with to live as
+// long as the rules of the CIDR: the lease of the rule expiring last, no lease
+// once one of them is permanent. The registration of the identity follows it.
+type identityLease struct {
+	lease     clientv3.LeaseID
+	expiresAt time.Time
+	permanent bool
+}
+
+// add accounts for a rule expiring at expiresAt on lease, NoLease for a
+// permanent rule
+func (l *identityLease) add(expiresAt time.Time, lease clientv3.LeaseID) {
+	switch {
+	case l.permanent:
+	case lease == clientv3.NoLease:
+		l.permanent, l.lease, l.expiresAt = true, clientv3.NoLease, time.Time{}
+	case l.lease == clientv3.NoLease || expiresAt.After(l.expiresAt):
+		l.lease, l.expiresAt = lease, expiresAt
+	}
+}
+
+// addKV accounts for a rule key read from etcd
+func (l *identityLease) addKV(kv *mvccpb.KeyValue) error {
+	var meta rule.RuleMeta
+	if err := json.Unmarshal(kv.Value, &meta); err != nil {
+		return fmt.Errorf("failed to unmarshal rule meta of %s: %w", kv.Key, err)
+	}
+	l.add(meta.ExpiresAt, clientv3.LeaseID(kv.Lease))
+	return nil
+}
+
+func (l identityLease) options() []clientv3.OpOption {
+	if l.lease == clientv3.NoLease {
+		return nil
+	}
+	return []clientv3.OpOption{clientv3.WithLease(l.lease)}
+}
+
+func (s Storage) revoke(ctx context.Context, id clientv3.LeaseID) {
+	if id == clientv3.NoLease {
+		return
+	}
+	if _, err := s.client.Revoke(ctx, id); err != nil {
+		log.Warn("revoke unused rule lease, it expires by itself", log.ErrorField(err))
+	}
+}
+
+// Extend moves a rule to a new lease expiring at expiresAt, a zero expiresAt
+// makes it permanent. The rule is rewritten in place, watchers see a PUT
+// and never a DELETE.
+func (s Storage) Extend(ctx context.Context, name string, info rule.RuleInfo, expiresAt time.Time) (*model.Rule, error) {
+	operationLock.Lock()
+	defer operationLock.Unlock()
+
+	return s.rewrite(ctx, name, info, model.OpExtend, func(meta *rule.RuleMeta) {
+		meta.ExpiresAt = expiresAt
+	})
+}
+
+// rewrite applies update to the meta of a rule and puts it with a lease
+// matching the new ExpiresAt in one transaction. The identity key and the
+// registration of its identity move to the lease of the rule of the CIDR
+// expiring last, see identityLease, otherwise they could expire first and
+// the identity be handed to another CIDR. The change is recorded as op.
+func (s Storage) rewrite(ctx context.Context, name string, info rule.RuleInfo, op string, update func(meta *rule.RuleMeta)) (*model.Rule, error) {
+	key := RuleKey(name, info.Key())
+	identityKey := RuleKey(name, info.IdentityKey())
+
+	for range txnRetries {
+		// 读取 CIDR 的全部规则, identity key 要比它们都活得久
+		resp, err := s.client.Get(ctx, identityKey+"/", clientv3.WithPrefix())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rule from etcd: %w", err)
+		}
+		var kv *mvccpb.KeyValue
+		var lease identityLease
+		for _, other := range resp.Kvs {
+			if string(other.Key) == key {
+				kv = other
+				continue
+			}
+			if err := lease.addKV(other); err != nil {
+				return nil, err
+			}
+		}
+		if kv == nil {
+			return nil, ErrRuleNotFound
+		}
+
+		var meta rule.RuleMeta
+		if err := json.Unmarshal(kv.Value, &meta); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal rule meta: %w", err)
+		}
+		old := meta
+		update(&meta)
+
+		idResp, err := s.client.Get(ctx, identityKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get identityKey: %w", err)
+		}
+		h, err := s.readHead(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		leaseID, leaseOpts, err := s.grantUntil(ctx, meta.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		lease.add(meta.ExpiresAt, leaseID)
+
+		// CIDR 的规则在读取之后都没有被修改过, identity key 的 lease 才是对的
+		cmps := []clientv3.Cmp{clientv3.Compare(clientv3.ModRevision(identityKey+"/").WithPrefix(), "<", resp.Header.Revision+1)}
+		var idOps []clientv3.Op
+		if len(idResp.Kvs) > 0 {
+			id := idResp.Kvs[0]
+			meta.Identity = string(id.Value)
+			if idOps = moveIdentity(id, lease); len(idOps) > 0 {
+				cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(identityKey), "=", id.ModRevision))
+			}
+		}
+		ops := append([]clientv3.Op{clientv3.OpPut(key, meta.MarshalStr(), leaseOpts...)}, idOps...)
+
+		change := &model.Change{
+			Op:      op,
+			Added:   model.RuleItem{{RuleInfo: info, RuleMeta: meta}},
+			Removed: model.RuleItem{{RuleInfo: info, RuleMeta: old}},
+		}
+		hcmps, hops, err := s.record(ctx, name, h, change)
+		if err != nil {
+			s.revoke(ctx, leaseID)
+			return nil, err
+		}
+		cmps, ops = append(cmps, hcmps...), append(ops, hops...)
+
+		txn, cancel := s.client.Txn(ctx)
+		txnResp, err := txn.If(cmps...).Then(ops...).Commit()
+		cancel()
+		if err != nil {
+			s.revoke(ctx, leaseID)
+			return nil, fmt.Errorf("etcd transaction failed: %w", err)
+		}
+		if txnResp.Succeeded {
+			// 旧的 lease 上已经没有 key, 到期后自行消失, 不主动 revoke 以免误删
+			return &model.Rule{RuleInfo: info, RuleMeta: meta}, nil
+		}
+		s.revoke(ctx, leaseID)
+	}
+
+	return nil, ErrConflict
+}
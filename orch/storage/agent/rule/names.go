@@ -0,0 +1,260 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"xdp-banner/orch/model/common"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/orch/storage/agent"
+	"xdp-banner/pkg/etcd"
+	"xdp-banner/pkg/log"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var (
+	// EtcdSetsDir indexes the rule sets, "<dir>/<name>" is written with the
+	// first rule of a set and deleted with its last rule. A set whose rules
+	// all expired keeps it until List finds the set empty and removes it.
+	EtcdSetsDir etcd.Key = etcd.Join(agent.EtcdDir, "sets")
+	// EtcdNamesDir is the JSON list of the rule set names older versions kept,
+	// MigrateNames replaces it with EtcdSetsDir
+	EtcdNamesDir etcd.Key = etcd.Join(agent.EtcdDir, "ruleNames/")
+)
+
+func nameKey(name string) etcd.Key {
+	return etcd.Join(EtcdSetsDir, name)
+}
+
+// List lists the rule sets sorted by name, a page starts after the name
+// nextCursor. A set deleted between the pages does not shift them.
+func (s Storage) List(ctx context.Context, size int64, nextCursor string) (*model.RuleList, error) {
+	if size <= 0 {
+		return nil, etcd.ErrInvalidPageSize
+	}
+
+	prefix := EtcdSetsDir + "/"
+	from := prefix
+	if nextCursor != "" {
+		// 游标之后的第一个 key
+		from = nameKey(nextCursor) + "\x00"
+	}
+
+	ops := []clientv3.Op{
+		clientv3.OpGet(from, clientv3.WithRange(clientv3.GetPrefixRangeEnd(prefix)), clientv3.WithKeysOnly(), clientv3.WithLimit(size)),
+		clientv3.OpGet(prefix, clientv3.WithPrefix(), clientv3.WithCountOnly()),
+	}
+	if nextCursor != "" {
+		// 游标之前的 rule set 数, 用于计算当前页
+		ops = append(ops, clientv3.OpGet(prefix, clientv3.WithRange(from), clientv3.WithCountOnly()))
+	}
+
+	txn, cancel := s.client.Txn(ctx)
+	resp, err := txn.Then(ops...).Commit()
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rule sets: %w", err)
+	}
+	page := resp.Responses[0].GetResponseRange()
+	total := resp.Responses[1].GetResponseRange().Count
+	var before int64
+	if nextCursor != "" {
+		before = resp.Responses[2].GetResponseRange().Count
+	}
+
+	names := make([]string, 0, len(page.Kvs))
+	for _, kv := range page.Kvs {
+		names = append(names, strings.TrimPrefix(string(kv.Key), prefix))
+	}
+
+	var wg sync.WaitGroup
+	result := make(model.RuleItems)
+	var resultLock sync.Mutex
+	var firstErr error
+	// empty 是规则都已过期的 rule set 数, 不计入 total
+	var empty int64
+
+	sem := make(chan struct{}, 10) // limit to 10 concurrent goroutines
+
+	for _, ruleName := range names {
+		wg.Add(1)
+		sem <- struct{}{} // acquire semaphore
+		go func(p string) {
+			defer func() {
+				<-sem // release semaphore
+				wg.Done()
+			}()
+			val, err := s.GetRule(ctx, p)
+			if errors.Is(err, ErrRuleNotFound) {
+				// 规则都已过期, 顺便移除 name key
+				removed, err := s.removeEmptyName(ctx, p)
+				if err != nil {
+					log.Warn("failed to remove the name key of an empty rule set", log.StringField("name", p), log.ErrorField(err))
+				}
+				if removed {
+					resultLock.Lock()
+					empty++
+					resultLock.Unlock()
+				}
+				return
+			}
+			if err != nil {
+				resultLock.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("get rule set %s failed: %w", p, err)
+				}
+				resultLock.Unlock()
+				return
+			}
+			resultLock.Lock()
+			result[p] = val
+			resultLock.Unlock()
+		}(ruleName)
+	}
+	wg.Wait()
+	close(sem)
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	total -= empty
+	next := ""
+	if page.More {
+		next = names[len(names)-1]
+	}
+
+	return &model.RuleList{
+		List: common.List{
+			TotalCount:  total,
+			TotalPage:   (total + size - 1) / size,
+			CurrentPage: before/size + 1,
+			HasNext:     page.More,
+			NextCursor:  next,
+		},
+		Items: result,
+	}, nil
+}
+
+// removeEmptyName deletes the name key of a rule set having no rule left,
+// unless a rule is added meanwhile. It reports whether the key was removed.
+func (s Storage) removeEmptyName(ctx context.Context, name string) (bool, error) {
+	prefix := setPrefix(name)
+	resp, err := s.client.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return false, fmt.Errorf("failed to get rule set from etcd: %w", err)
+	}
+	for _, kv := range resp.Kvs {
+		if _, ok := parseRuleKey(strings.TrimPrefix(string(kv.Key), prefix)); ok {
+			return false, nil
+		}
+	}
+
+	// 读取之后 rule set 没有被修改过, 剩下的只有 identity key
+	txn, cancel := s.client.Txn(ctx)
+	txnResp, err := txn.If(
+		clientv3.Compare(clientv3.ModRevision(prefix), "<", resp.Header.Revision+1).WithPrefix(),
+	).Then(clientv3.OpDelete(nameKey(name))).Commit()
+	cancel()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete name key: %w", err)
+	}
+	return txnResp.Succeeded, nil
+}
+
+// HasLegacyNames reports whether the name list of older versions is still
+// there, MigrateNames has not run yet
+func (s Storage) HasLegacyNames(ctx context.Context) (bool, error) {
+	resp, err := s.client.Get(ctx, EtcdNamesDir, clientv3.WithCountOnly())
+	if err != nil {
+		return false, fmt.Errorf("failed to get the name list: %w", err)
+	}
+	return resp.Count > 0, nil
+}
+
+// MigrateNames writes the name keys of the rule sets having rules and deletes
+// the name list of older versions, it is safe to run again. The orchestrators
+// of older versions must be stopped first, they still use the list.
+func (s Storage) MigrateNames(ctx context.Context) (*model.NameMigration, error) {
+	resp, err := s.client.Get(ctx, EtcdDir+"/", clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rules from etcd: %w", err)
+	}
+	var names []string
+	for _, kv := range resp.Kvs {
+		if name, _, ok := parseIndexKey(string(kv.Key)); ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	indexed, err := s.client.Get(ctx, EtcdSetsDir+"/", clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get name keys from etcd: %w", err)
+	}
+	exists := make(map[etcd.Key]bool, len(indexed.Kvs))
+	for _, kv := range indexed.Kvs {
+		exists[string(kv.Key)] = true
+	}
+
+	migration := &model.NameMigration{RuleSets: len(names)}
+	var ops []clientv3.Op
+	for _, name := range names {
+		if exists[nameKey(name)] {
+			continue
+		}
+		// 只在 name key 仍然不存在时写入, 不覆盖新版本写入的 key
+		ops = append(ops, clientv3.OpTxn(
+			[]clientv3.Cmp{clientv3.Compare(clientv3.CreateRevision(nameKey(name)), "=", 0)},
+			[]clientv3.Op{clientv3.OpPut(nameKey(name), "")},
+			nil,
+		))
+		migration.Added = append(migration.Added, name)
+	}
+	for len(ops) > 0 {
+		n := min(len(ops), maxTxnOps)
+		txn, cancel := s.client.Txn(ctx)
+		_, err := txn.Then(ops[:n]...).Commit()
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to write name keys: %w", err)
+		}
+		ops = ops[n:]
+	}
+
+	legacy, err := s.client.Get(ctx, EtcdNamesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the name list: %w", err)
+	}
+	if len(legacy.Kvs) > 0 {
+		var listed []string
+		if err := json.Unmarshal(legacy.Kvs[0].Value, &listed); err == nil {
+			for _, name := range listed {
+				if _, found := slices.BinarySearch(names, name); !found {
+					migration.Dropped = append(migration.Dropped, name)
+				}
+			}
+		}
+
+		txn, cancel := s.client.Txn(ctx)
+		resp, err := txn.If(
+			clientv3.Compare(clientv3.ModRevision(EtcdNamesDir), "=", legacy.Kvs[0].ModRevision),
+		).Then(clientv3.OpDelete(EtcdNamesDir)).Commit()
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete the name list: %w", err)
+		}
+		if !resp.Succeeded {
+			return nil, errors.New("the name list changed during the migration, an orchestrator of an older version is still running")
+		}
+		migration.ListRemoved = true
+	}
+
+	return migration, nil
+}
@@ -0,0 +1,130 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	model "xdp-banner/orch/model/rule"
+	"xdp-banner/orch/storage/agent"
+	"xdp-banner/pkg/etcd"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var (
+	EtcdDir etcd.Key = etcd.Join(agent.EtcdDir, "schedules/")
+)
+
+var (
+	// ErrScheduleNotFound is returned when the scheduled rule is not found.
+	ErrScheduleNotFound = errors.New("scheduled rule not found")
+	// ErrScheduleAlreadyExists is returned when the scheduled rule already exists.
+	ErrScheduleAlreadyExists = errors.New("scheduled rule already exists")
+)
+
+// ScheduleKey is the key of the scheduled rule of a rule set, ruleKey is the
+// key of the rule it adds, see RuleInfo.Key
+func ScheduleKey(name, ruleKey string) etcd.Key {
+	return etcd.Join(EtcdDir, name, ruleKey)
+}
+
+// setPrefix is the prefix of the scheduled rules of a rule set, every rule
+// set when name is empty
+func setPrefix(name string) etcd.Key {
+	if name == "" {
+		return EtcdDir + "/"
+	}
+	return ScheduleKey(name, "") + "/"
+}
+
+// ParseKey returns the rule set of a schedule key and the key of its rule
+func ParseKey(key etcd.Key) (name, ruleKey string, ok bool) {
+	rel, ok := strings.CutPrefix(key, EtcdDir+"/")
+	if !ok {
+		return "", "", false
+	}
+	name, ruleKey, ok = strings.Cut(rel, "/")
+	if !ok || name == "" || ruleKey == "" {
+		return "", "", false
+	}
+	return name, ruleKey, true
+}
+
+// Storage 提供对 etcd 的操作
+type Storage struct {
+	client etcd.Client
+}
+
+func New(client etcd.Client) Storage {
+	return Storage{client: client}
+}
+
+// Add creates a scheduled rule in etcd, the leader reconciles it.
+func (s Storage) Add(ctx context.Context, r *model.ScheduledRule) error {
+	err := s.client.Create(ctx, ScheduleKey(r.Name, r.RuleInfo.Key()), r.MarshalStr())
+	if err == etcd.ErrKeyExist {
+		return ErrScheduleAlreadyExists
+	}
+
+	return err
+}
+
+// Delete deletes a scheduled rule from etcd, the leader withdraws its rule.
+func (s Storage) Delete(ctx context.Context, name, ruleKey string) error {
+	resp, err := s.client.Delete(ctx, ScheduleKey(name, ruleKey))
+	if err != nil {
+		return err
+	}
+	if resp.Deleted == 0 {
+		return ErrScheduleNotFound
+	}
+
+	return nil
+}
+
+// DeleteSet deletes the scheduled rules of a rule set, it returns how many
+// were deleted.
+func (s Storage) DeleteSet(ctx context.Context, name string) (int64, error) {
+	resp, err := s.client.Delete(ctx, setPrefix(name), clientv3.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+	return resp.Deleted, nil
+}
+
+// Get retrieves a scheduled rule from etcd.
+func (s Storage) Get(ctx context.Context, name, ruleKey string) (*model.ScheduledRule, error) {
+	raw, err := s.client.GetMustExist(ctx, ScheduleKey(name, ruleKey))
+	if err != nil {
+		if err == etcd.ErrKeyNotFound {
+			return nil, ErrScheduleNotFound
+		}
+		return nil, err
+	}
+
+	r := new(model.ScheduledRule)
+	if err := r.Unmarshal(raw.Kvs[0].Value); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// List returns the scheduled rules of a rule set, of every rule set when
+// name is empty, ordered by key.
+func (s Storage) List(ctx context.Context, name string) ([]*model.ScheduledRule, error) {
+	resp, err := s.client.Get(ctx, setPrefix(name), clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled rules from etcd: %w", err)
+	}
+
+	rules := make([]*model.ScheduledRule, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		r := new(model.ScheduledRule)
+		if err := r.Unmarshal(kv.Value); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
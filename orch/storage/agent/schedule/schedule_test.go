@@ -0,0 +1,20 @@
+package schedule
+
+import (
+	"testing"
+	"xdp-banner/pkg/rule"
+)
+
+func TestParseKey(t *testing.T) {
+	info := rule.RuleInfo{Cidr: "10.0.0.0/8", Protocol: "TCP", Dport: 22}
+	name, ruleKey, ok := ParseKey(ScheduleKey("office", info.Key()))
+	if !ok || name != "office" || ruleKey != "10.0.0.0/8/TCP/0-22" {
+		t.Errorf("ParseKey = %q, %q, %v", name, ruleKey, ok)
+	}
+
+	for _, key := range []string{EtcdDir, EtcdDir + "/office", "/agent/rule/office/10.0.0.0/8/TCP/0-22"} {
+		if _, _, ok := ParseKey(key); ok {
+			t.Errorf("ParseKey(%q) succeeded", key)
+		}
+	}
+}
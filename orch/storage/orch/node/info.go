@@ -51,7 +51,7 @@ func (s InfoStorage) Delete(ctx context.Context, name string) error {
 
 func (s InfoStorage) Update(ctx context.Context, info *node.OrchInfo) error {
 	key := InfoKey(info.CommonInfo.Name)
-	err := s.client.Create(ctx, key, info.MarshalStr())
+	err := s.client.Update(ctx, key, info.MarshalStr())
 	if err != nil {
 		if err == etcd.ErrKeyNotFound {
 			return ErrInfoNotFound
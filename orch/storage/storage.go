@@ -5,7 +5,9 @@ import (
 	"xdp-banner/orch/storage/agent"
 	"xdp-banner/orch/storage/agent/applied"
 	agentnode "xdp-banner/orch/storage/agent/node"
+	"xdp-banner/orch/storage/agent/protect"
 	"xdp-banner/orch/storage/agent/rule"
+	"xdp-banner/orch/storage/agent/schedule"
 	"xdp-banner/orch/storage/agent/strategy"
 	"xdp-banner/orch/storage/orch"
 	"xdp-banner/orch/storage/orch/cert"
@@ -14,8 +16,11 @@ import (
 )
 
 type Storage struct {
-	Rule rule.Storage
-	Cert cert.Storage
+	Rule      rule.Storage
+	RuleIndex *rule.Index
+	// RuleComposer merges the rule sets with their includes
+	RuleComposer *rule.Composer
+	Cert         cert.Storage
 
 	Orch     orch.Storage
 	OrchInfo orchnode.InfoStorage
@@ -27,12 +32,18 @@ type Storage struct {
 
 	Strategy strategy.Storage
 	Applied  applied.Storage
+	Protect  protect.Storage
+	// ProtectChanges counts the changes the protected ranges depend on
+	ProtectChanges *protect.Changes
+	Schedule       schedule.Storage
 }
 
 func New(ctx context.Context, client etcd.Client) Storage {
 	return Storage{
-		Rule: rule.New(client),
-		Cert: cert.New(client),
+		Rule:         rule.New(client),
+		RuleIndex:    rule.NewIndex(ctx, client),
+		RuleComposer: rule.NewComposer(ctx, client),
+		Cert:         cert.New(client),
 
 		Orch:     orch.New(client),
 		OrchInfo: orchnode.NewInfoStorage(client),
@@ -42,7 +53,10 @@ func New(ctx context.Context, client etcd.Client) Storage {
 		AgentStatus:        agentnode.NewStatusStorage(ctx, client),
 		AgentRegisteration: agentnode.NewRegisterStorage(client),
 
-		Strategy: strategy.New(client),
-		Applied:  applied.New(client),
+		Strategy:       strategy.New(client),
+		Applied:        applied.New(client),
+		Protect:        protect.New(client),
+		ProtectChanges: protect.NewChanges(ctx, client),
+		Schedule:       schedule.New(client),
 	}
 }
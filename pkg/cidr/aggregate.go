@@ -0,0 +1,134 @@
+package cidr
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// Aggregate is a prefix covering exactly the union of its members
+type Aggregate struct {
+	Prefix  netip.Prefix
+	Members []netip.Prefix
+}
+
+// Merge returns the fewest prefixes covering exactly the union of prefixes,
+// sorted. A prefix inside another is dropped into it and the two halves of a
+// prefix are replaced by it, so no address is added or lost. Merging two
+// halves into more than maxMembers members is skipped, 0 does not bound it.
+func Merge(prefixes []netip.Prefix, maxMembers int) []Aggregate {
+	return MergeAround(prefixes, nil, maxMembers)
+}
+
+// MergeAround is Merge keeping the longest prefix match of every address
+// between the prefixes and others: a prefix is not dropped or merged into
+// an aggregate when a prefix of others inside the aggregate contains it, the
+// address would match the aggregate or the other prefix instead.
+func MergeAround(prefixes, others []netip.Prefix, maxMembers int) []Aggregate {
+	sorted := make([]netip.Prefix, len(prefixes))
+	for i, p := range prefixes {
+		sorted[i] = p.Masked()
+	}
+	Sort(sorted)
+
+	var around Trie[struct{}]
+	for _, p := range others {
+		around.Insert(p.Masked(), struct{}{})
+	}
+
+	// 排序后包含 p 的前缀只可能是最后保留的那个
+	var kept []*Aggregate
+	for _, p := range sorted {
+		if n := len(kept); n > 0 && Contains(kept[n-1].Prefix, p) {
+			if !splits(&around, kept[n-1].Prefix, []netip.Prefix{p}) {
+				kept[n-1].Members = append(kept[n-1].Members, p)
+				continue
+			}
+			// p 单独保留, 之后的合并也不能越过它
+			around.Insert(p, struct{}{})
+		}
+		kept = append(kept, &Aggregate{Prefix: p, Members: []netip.Prefix{p}})
+	}
+
+	// 自底向上合并兄弟前缀, 合并出的父前缀在下一层继续合并
+	byPrefix := make(map[netip.Prefix]*Aggregate, len(kept))
+	var levels [129][]netip.Prefix
+	for _, a := range kept {
+		byPrefix[a.Prefix] = a
+		levels[a.Prefix.Bits()] = append(levels[a.Prefix.Bits()], a.Prefix)
+	}
+	for bits := 128; bits > 0; bits-- {
+		for _, p := range levels[bits] {
+			a, ok := byPrefix[p]
+			if !ok {
+				continue
+			}
+			b, ok := byPrefix[sibling(p)]
+			if !ok || !fits(maxMembers, len(a.Members)+len(b.Members)) {
+				continue
+			}
+			parent := netip.PrefixFrom(p.Addr(), bits-1).Masked()
+			members := append(slices.Clip(a.Members), b.Members...)
+			if splits(&around, parent, members) {
+				continue
+			}
+
+			delete(byPrefix, a.Prefix)
+			delete(byPrefix, b.Prefix)
+			byPrefix[parent] = &Aggregate{Prefix: parent, Members: members}
+			levels[bits-1] = append(levels[bits-1], parent)
+		}
+	}
+
+	result := make([]Aggregate, 0, len(byPrefix))
+	for _, a := range byPrefix {
+		Sort(a.Members)
+		result = append(result, *a)
+	}
+	slices.SortFunc(result, func(a, b Aggregate) int {
+		if c := a.Prefix.Addr().Compare(b.Prefix.Addr()); c != 0 {
+			return c
+		}
+		return a.Prefix.Bits() - b.Prefix.Bits()
+	})
+	return result
+}
+
+// splits reports whether a prefix of around inside prefix, prefix included,
+// contains one of the members other than prefix itself
+func splits(around *Trie[struct{}], prefix netip.Prefix, members []netip.Prefix) bool {
+	found := false
+	check := func(q netip.Prefix, _ struct{}) bool {
+		for _, m := range members {
+			if m != prefix && Contains(q, m) {
+				found = true
+				return false
+			}
+		}
+		return true
+	}
+	if _, ok := around.Get(prefix); ok {
+		check(prefix, struct{}{})
+	}
+	if !found {
+		around.Inside(prefix, check)
+	}
+	return found
+}
+
+// sibling returns the other half of the parent of p, p must not be a /0
+func sibling(p netip.Prefix) netip.Prefix {
+	i := p.Bits() - 1
+	if p.Addr().Is4() {
+		b := p.Addr().As4()
+		b[i/8] ^= 0x80 >> (i % 8)
+		return netip.PrefixFrom(netip.AddrFrom4(b), p.Bits())
+	}
+
+	b := p.Addr().As16()
+	b[i/8] ^= 0x80 >> (i % 8)
+	return netip.PrefixFrom(netip.AddrFrom16(b), p.Bits())
+}
+
+func fits(maxMembers, n int) bool {
+	return maxMembers <= 0 || n <= maxMembers
+}
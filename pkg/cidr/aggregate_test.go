@@ -0,0 +1,74 @@
+package cidr
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	var hosts []string
+	for i := range 256 {
+		hosts = append(hosts, fmt.Sprintf("10.0.1.%d/32", i))
+	}
+
+	tests := []struct {
+		name       string
+		in         []netip.Prefix
+		maxMembers int
+		want       []string
+	}{
+		{"siblings", prefixes("10.0.0.0/25", "10.0.0.128/25"), 0, []string{"10.0.0.0/24"}},
+		{"not siblings", prefixes("10.0.0.128/25", "10.0.1.0/25"), 0, []string{"10.0.0.128/25", "10.0.1.0/25"}},
+		{"contained", prefixes("10.0.0.5/32", "10.0.0.0/24", "10.0.0.0/24"), 0, []string{"10.0.0.0/24"}},
+		{"cascade", prefixes(append(hosts, "10.0.0.0/24")...), 0, []string{"10.0.0.0/23"}},
+		{"bounded", prefixes(hosts[:4]...), 2, []string{"10.0.1.0/31", "10.0.1.2/31"}},
+		{"ipv6", prefixes("2001:db8::/33", "2001:db8:8000::/33", "10.0.0.0/32"), 0, []string{"10.0.0.0/32", "2001:db8::/32"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			members := 0
+			for _, a := range Merge(tt.in, tt.maxMembers) {
+				got = append(got, a.Prefix.String())
+				members += len(a.Members)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Merge() = %v, want %v", got, tt.want)
+			}
+			if members != len(tt.in) {
+				t.Errorf("Merge() kept %d members, want %d", members, len(tt.in))
+			}
+		})
+	}
+}
+
+func TestMergeAround(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     []string
+		others []string
+		want   []string
+	}{
+		// 其它前缀在成员内部时, 最长前缀匹配不变
+		{"inside a member", []string{"10.0.0.0/25", "10.0.0.128/25"}, []string{"10.0.0.1/32"}, []string{"10.0.0.0/24"}},
+		{"equal to the result", []string{"10.0.0.0/25", "10.0.0.128/25"}, []string{"10.0.0.0/24"}, []string{"10.0.0.0/25", "10.0.0.128/25"}},
+		{"equal to a member", []string{"10.0.0.0/25", "10.0.0.128/25"}, []string{"10.0.0.128/25"}, []string{"10.0.0.0/25", "10.0.0.128/25"}},
+		{"between", []string{"10.0.0.0/24", "10.0.0.0/26"}, []string{"10.0.0.0/25"}, []string{"10.0.0.0/24", "10.0.0.0/26"}},
+		{"outside", []string{"10.0.0.0/24", "10.0.0.0/26"}, []string{"10.0.0.128/25", "10.0.0.0/16"}, []string{"10.0.0.0/24"}},
+		// 没有合并的 10.0.0.0/26 也不能被越过
+		{"kept apart", []string{"10.0.0.0/25", "10.0.0.0/26", "10.0.0.128/25"}, []string{"10.0.0.0/25"}, []string{"10.0.0.0/25", "10.0.0.0/26", "10.0.0.128/25"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, a := range MergeAround(prefixes(tt.in...), prefixes(tt.others...), 0) {
+				got = append(got, a.Prefix.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("MergeAround() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Package cidr has the prefix arithmetic shared by the orchestrator and the agents
+package cidr
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// Parse parses a CIDR or a bare address, which stands for its host prefix.
+// The result is masked, e.g. 10.0.0.1/24 gives 10.0.0.0/24.
+func Parse(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid cidr %q", s)
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid cidr %q", s)
+	}
+	return p.Masked(), nil
+}
+
+// HostPrefix returns the single address prefix of addr
+func HostPrefix(addr netip.Addr) netip.Prefix {
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen())
+}
+
+// Contains reports whether outer contains inner entirely
+func Contains(outer, inner netip.Prefix) bool {
+	return outer.Addr().Is4() == inner.Addr().Is4() &&
+		outer.Bits() <= inner.Bits() &&
+		outer.Contains(inner.Addr())
+}
+
+// Subtract returns the prefixes covering p without the holes, sorted. It is
+// empty when the holes cover p entirely.
+func Subtract(p netip.Prefix, holes ...netip.Prefix) []netip.Prefix {
+	result := []netip.Prefix{p.Masked()}
+
+	for _, hole := range holes {
+		hole = hole.Masked()
+
+		var next []netip.Prefix
+		for _, q := range result {
+			switch {
+			case !q.Overlaps(hole):
+				next = append(next, q)
+			case Contains(hole, q):
+				// q 整个被挖掉
+			default:
+				next = append(next, split(q, hole)...)
+			}
+		}
+		result = next
+	}
+
+	Sort(result)
+	return result
+}
+
+// split cuts q, which contains hole, into the halves not containing hole
+func split(q, hole netip.Prefix) []netip.Prefix {
+	var result []netip.Prefix
+	for q.Bits() < hole.Bits() {
+		lo := netip.PrefixFrom(q.Addr(), q.Bits()+1)
+		hi := netip.PrefixFrom(setBit(q.Addr(), q.Bits()), q.Bits()+1)
+		if lo.Contains(hole.Addr()) {
+			result = append(result, hi)
+			q = lo
+		} else {
+			result = append(result, lo)
+			q = hi
+		}
+	}
+	return result
+}
+
+// setBit sets the bit i of addr, counting from the most significant one
+func setBit(addr netip.Addr, i int) netip.Addr {
+	if addr.Is4() {
+		b := addr.As4()
+		b[i/8] |= 0x80 >> (i % 8)
+		return netip.AddrFrom4(b)
+	}
+
+	b := addr.As16()
+	b[i/8] |= 0x80 >> (i % 8)
+	return netip.AddrFrom16(b)
+}
+
+// Sort orders prefixes by family, address and then length
+func Sort(prefixes []netip.Prefix) {
+	slices.SortFunc(prefixes, func(a, b netip.Prefix) int {
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c
+		}
+		return a.Bits() - b.Bits()
+	})
+}
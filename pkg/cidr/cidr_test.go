@@ -0,0 +1,92 @@
+package cidr
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func prefixes(ss ...string) []netip.Prefix {
+	var result []netip.Prefix
+	for _, s := range ss {
+		result = append(result, netip.MustParsePrefix(s))
+	}
+	return result
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.1/24":       "10.0.0.0/24",
+		"10.0.0.1":          "10.0.0.1/32",
+		"::ffff:10.0.0.1":   "10.0.0.1/32",
+		"2001:db8::1":       "2001:db8::1/128",
+		" 2001:db8::/32 ":   "2001:db8::/32",
+		"192.168.0.0/16":    "192.168.0.0/16",
+		"0.0.0.0/0":         "0.0.0.0/0",
+		"2001:db8:0:0::/64": "2001:db8::/64",
+	}
+	for in, want := range tests {
+		got, err := Parse(in)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", in, err)
+		}
+		if got.String() != want {
+			t.Fatalf("Parse(%q) = %s, want %s", in, got, want)
+		}
+	}
+
+	for _, in := range []string{"", "10.0.0.0/33", "host"} {
+		if _, err := Parse(in); err == nil {
+			t.Fatalf("Parse(%q) should fail", in)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	p := netip.MustParsePrefix
+	if !Contains(p("10.0.0.0/8"), p("10.1.0.0/16")) {
+		t.Fatal("10.0.0.0/8 should contain 10.1.0.0/16")
+	}
+	if Contains(p("10.1.0.0/16"), p("10.0.0.0/8")) {
+		t.Fatal("10.1.0.0/16 should not contain 10.0.0.0/8")
+	}
+	if Contains(p("::/0"), p("10.0.0.0/8")) {
+		t.Fatal("families should not mix")
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	tests := []struct {
+		name  string
+		p     string
+		holes []string
+		want  []netip.Prefix
+	}{
+		{"no overlap", "10.0.0.0/24", []string{"10.0.1.0/24"}, prefixes("10.0.0.0/24")},
+		{"covered", "10.0.0.0/24", []string{"10.0.0.0/16"}, nil},
+		{"one host", "10.0.0.0/30", []string{"10.0.0.1"}, prefixes("10.0.0.0/32", "10.0.0.2/31")},
+		{"half", "10.0.0.0/8", []string{"10.128.0.0/9"}, prefixes("10.0.0.0/9")},
+		{"two holes", "10.0.0.0/29", []string{"10.0.0.0/31", "10.0.0.6/31"}, prefixes("10.0.0.2/31", "10.0.0.4/31")},
+		{"everything", "0.0.0.0/0", []string{"128.0.0.0/2"}, prefixes("0.0.0.0/1", "192.0.0.0/2")},
+		{"v6", "2001:db8::/126", []string{"2001:db8::3"}, prefixes("2001:db8::/127", "2001:db8::2/128")},
+		{"other family", "10.0.0.0/8", []string{"::/0"}, prefixes("10.0.0.0/8")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var holes []netip.Prefix
+			for _, h := range tt.holes {
+				hp, err := Parse(h)
+				if err != nil {
+					t.Fatal(err)
+				}
+				holes = append(holes, hp)
+			}
+
+			got := Subtract(netip.MustParsePrefix(tt.p), holes...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Subtract = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
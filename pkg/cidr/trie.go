@@ -0,0 +1,215 @@
+package cidr
+
+import (
+	"math/bits"
+	"net/netip"
+)
+
+// Trie maps prefixes to values. It is a path compressed binary trie, a node
+// is either a prefix holding a value or the fork of two longer prefixes.
+// Prefixes are masked before use. A Trie is not safe for concurrent use.
+type Trie[T any] struct {
+	v4, v6 *trieNode[T]
+	size   int
+}
+
+type trieNode[T any] struct {
+	prefix   netip.Prefix
+	value    T
+	set      bool
+	children [2]*trieNode[T]
+}
+
+func (t *Trie[T]) root(p netip.Prefix) **trieNode[T] {
+	if p.Addr().Is4() {
+		return &t.v4
+	}
+	return &t.v6
+}
+
+// Len returns the number of prefixes in the trie
+func (t *Trie[T]) Len() int {
+	return t.size
+}
+
+// Insert sets the value of p, replacing the one it had
+func (t *Trie[T]) Insert(p netip.Prefix, value T) {
+	p = p.Masked()
+	n := t.root(p)
+	for {
+		cur := *n
+		if cur == nil {
+			*n = &trieNode[T]{prefix: p, value: value, set: true}
+			t.size++
+			return
+		}
+
+		common := commonBits(cur.prefix, p)
+		switch {
+		case common == cur.prefix.Bits() && common == p.Bits():
+			if !cur.set {
+				t.size++
+			}
+			cur.value, cur.set = value, true
+			return
+		case common == cur.prefix.Bits():
+			// cur 包含 p, 继续向下
+			n = &cur.children[bit(p.Addr(), common)]
+		case common == p.Bits():
+			// p 包含 cur, p 成为 cur 的父节点
+			node := &trieNode[T]{prefix: p, value: value, set: true}
+			node.children[bit(cur.prefix.Addr(), common)] = cur
+			*n = node
+			t.size++
+			return
+		default:
+			// 在第 common 位分叉
+			fork := &trieNode[T]{prefix: netip.PrefixFrom(p.Addr(), common).Masked()}
+			fork.children[bit(cur.prefix.Addr(), common)] = cur
+			fork.children[bit(p.Addr(), common)] = &trieNode[T]{prefix: p, value: value, set: true}
+			*n = fork
+			t.size++
+			return
+		}
+	}
+}
+
+// Get returns the value of p
+func (t *Trie[T]) Get(p netip.Prefix) (T, bool) {
+	p = p.Masked()
+	for n := *t.root(p); n != nil && n.prefix.Bits() <= p.Bits() && n.prefix.Contains(p.Addr()); {
+		if n.prefix.Bits() == p.Bits() {
+			return n.value, n.set
+		}
+		n = n.children[bit(p.Addr(), n.prefix.Bits())]
+	}
+
+	var zero T
+	return zero, false
+}
+
+// Delete removes p and reports whether it was in the trie
+func (t *Trie[T]) Delete(p netip.Prefix) bool {
+	p = p.Masked()
+	var parent **trieNode[T]
+	n := t.root(p)
+	for *n != nil {
+		cur := *n
+		if cur.prefix.Bits() > p.Bits() || !cur.prefix.Contains(p.Addr()) {
+			return false
+		}
+		if cur.prefix.Bits() < p.Bits() {
+			parent, n = n, &cur.children[bit(p.Addr(), cur.prefix.Bits())]
+			continue
+		}
+
+		if !cur.set {
+			return false
+		}
+		var zero T
+		cur.value, cur.set = zero, false
+		t.size--
+
+		// 去掉没有值的节点, 父节点可能因此成为只有一个子节点的分叉
+		compact(n)
+		if parent != nil {
+			compact(parent)
+		}
+		return true
+	}
+	return false
+}
+
+// compact removes a node without a value and with less than two children
+func compact[T any](n **trieNode[T]) {
+	cur := *n
+	if cur == nil || cur.set {
+		return
+	}
+	switch {
+	case cur.children[0] == nil:
+		*n = cur.children[1]
+	case cur.children[1] == nil:
+		*n = cur.children[0]
+	}
+}
+
+// Covering yields the prefixes containing p, p included, from the shortest
+func (t *Trie[T]) Covering(p netip.Prefix, yield func(netip.Prefix, T) bool) {
+	p = p.Masked()
+	for n := *t.root(p); n != nil && n.prefix.Bits() <= p.Bits() && n.prefix.Contains(p.Addr()); {
+		if n.set && !yield(n.prefix, n.value) {
+			return
+		}
+		if n.prefix.Bits() == p.Bits() {
+			return
+		}
+		n = n.children[bit(p.Addr(), n.prefix.Bits())]
+	}
+}
+
+// Inside yields the prefixes inside p, p excluded, in address order
+func (t *Trie[T]) Inside(p netip.Prefix, yield func(netip.Prefix, T) bool) {
+	p = p.Masked()
+	n := *t.root(p)
+	for n != nil && n.prefix.Bits() < p.Bits() {
+		if !n.prefix.Contains(p.Addr()) {
+			return
+		}
+		n = n.children[bit(p.Addr(), n.prefix.Bits())]
+	}
+	if n == nil || !p.Contains(n.prefix.Addr()) {
+		return
+	}
+
+	if n.prefix.Bits() == p.Bits() {
+		// p 自身不算在内
+		_ = walk(n.children[0], yield) && walk(n.children[1], yield)
+		return
+	}
+	walk(n, yield)
+}
+
+// All yields every prefix of the trie, IPv4 first and in address order
+func (t *Trie[T]) All(yield func(netip.Prefix, T) bool) {
+	_ = walk(t.v4, yield) && walk(t.v6, yield)
+}
+
+func walk[T any](n *trieNode[T], yield func(netip.Prefix, T) bool) bool {
+	if n == nil {
+		return true
+	}
+	if n.set && !yield(n.prefix, n.value) {
+		return false
+	}
+	return walk(n.children[0], yield) && walk(n.children[1], yield)
+}
+
+// bit returns the i-th bit of addr from the most significant one
+func bit(addr netip.Addr, i int) int {
+	if addr.Is4() {
+		i += 96
+	}
+	b := addr.As16()
+	return int(b[i/8]>>(7-i%8)) & 1
+}
+
+// commonBits returns the length of the longest prefix shared by a and b
+func commonBits(a, b netip.Prefix) int {
+	limit := min(a.Bits(), b.Bits())
+	offset := 0
+	if a.Addr().Is4() {
+		offset = 96
+	}
+
+	x, y := a.Addr().As16(), b.Addr().As16()
+	n := 0
+	for i := offset / 8; i < 16 && n < limit; i++ {
+		if x[i] != y[i] {
+			n += bits.LeadingZeros8(x[i] ^ y[i])
+			break
+		}
+		n += 8
+	}
+	return min(n, limit)
+}
@@ -0,0 +1,116 @@
+package cidr
+
+import (
+	"math/rand"
+	"net/netip"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func collect(seq func(func(netip.Prefix, int) bool)) []netip.Prefix {
+	var result []netip.Prefix
+	seq(func(p netip.Prefix, _ int) bool {
+		result = append(result, p)
+		return true
+	})
+	return result
+}
+
+func TestTrie(t *testing.T) {
+	var trie Trie[int]
+	for i, p := range prefixes("10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "10.2.0.0/16", "0.0.0.0/0", "2001:db8::/32", "10.1.2.3/32") {
+		trie.Insert(p, i)
+	}
+	trie.Insert(netip.MustParsePrefix("10.1.0.0/16"), 10)
+
+	if trie.Len() != 7 {
+		t.Errorf("Len() = %d, want 7", trie.Len())
+	}
+	if v, ok := trie.Get(netip.MustParsePrefix("10.1.0.0/16")); !ok || v != 10 {
+		t.Errorf("Get(10.1.0.0/16) = %d, %v, want 10", v, ok)
+	}
+	if _, ok := trie.Get(netip.MustParsePrefix("10.0.0.0/9")); ok {
+		t.Error("Get(10.0.0.0/9) ok = true")
+	}
+
+	covering := collect(func(yield func(netip.Prefix, int) bool) {
+		trie.Covering(netip.MustParsePrefix("10.1.2.3/32"), yield)
+	})
+	if want := prefixes("0.0.0.0/0", "10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "10.1.2.3/32"); !reflect.DeepEqual(covering, want) {
+		t.Errorf("Covering() = %v, want %v", covering, want)
+	}
+
+	inside := collect(func(yield func(netip.Prefix, int) bool) {
+		trie.Inside(netip.MustParsePrefix("10.0.0.0/8"), yield)
+	})
+	if want := prefixes("10.1.0.0/16", "10.1.2.0/24", "10.1.2.3/32", "10.2.0.0/16"); !reflect.DeepEqual(inside, want) {
+		t.Errorf("Inside() = %v, want %v", inside, want)
+	}
+
+	if !trie.Delete(netip.MustParsePrefix("10.1.0.0/16")) || trie.Delete(netip.MustParsePrefix("10.1.0.0/16")) {
+		t.Error("Delete(10.1.0.0/16) should succeed once")
+	}
+	all := collect(trie.All)
+	if want := prefixes("0.0.0.0/0", "10.0.0.0/8", "10.1.2.0/24", "10.1.2.3/32", "10.2.0.0/16", "2001:db8::/32"); !reflect.DeepEqual(all, want) {
+		t.Errorf("All() = %v, want %v", all, want)
+	}
+}
+
+// TestTrieRandom compares the trie with a brute force search over random prefixes
+func TestTrieRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	randomPrefix := func() netip.Prefix {
+		// 集中在 10.0.0.0/12 里, 让前缀之间大量重叠
+		addr := netip.AddrFrom4([4]byte{10, byte(rnd.Intn(16)), byte(rnd.Intn(4)), byte(rnd.Intn(256))})
+		return netip.PrefixFrom(addr, 8+rnd.Intn(25)).Masked()
+	}
+
+	var trie Trie[int]
+	set := make(map[netip.Prefix]int)
+	for i := range 5000 {
+		p := randomPrefix()
+		if rnd.Intn(3) == 0 {
+			delete(set, p)
+			trie.Delete(p)
+			continue
+		}
+		set[p] = i
+		trie.Insert(p, i)
+	}
+	if trie.Len() != len(set) {
+		t.Fatalf("Len() = %d, want %d", trie.Len(), len(set))
+	}
+
+	for range 500 {
+		q := randomPrefix()
+
+		var wantCovering, wantInside []netip.Prefix
+		for p := range set {
+			switch {
+			case Contains(p, q):
+				wantCovering = append(wantCovering, p)
+			case Contains(q, p):
+				wantInside = append(wantInside, p)
+			}
+		}
+		Sort(wantCovering)
+		Sort(wantInside)
+
+		gotCovering := collect(func(yield func(netip.Prefix, int) bool) { trie.Covering(q, yield) })
+		gotInside := collect(func(yield func(netip.Prefix, int) bool) { trie.Inside(q, yield) })
+		Sort(gotCovering)
+		Sort(gotInside)
+
+		if !slices.Equal(gotCovering, wantCovering) {
+			t.Fatalf("Covering(%s) = %v, want %v", q, gotCovering, wantCovering)
+		}
+		if !slices.Equal(gotInside, wantInside) {
+			t.Fatalf("Inside(%s) = %v, want %v", q, gotInside, wantInside)
+		}
+		v, ok := trie.Get(q)
+		if want, wantOK := set[q]; ok != wantOK || v != want {
+			t.Fatalf("Get(%s) = %d, %v, want %d, %v", q, v, ok, want, wantOK)
+		}
+	}
+}
@@ -27,3 +27,21 @@ func NewFromConfig(configName string, path string, configStruct interface{}) (*v
 
 	return v, nil
 }
+
+// NewFromFile loads and unmarshals the given config file, the config type is
+// derived from the file extension, so both yaml and json files are accepted
+func NewFromFile(file string, configStruct interface{}) (*viper.Viper, error) {
+	v := viper.New()
+
+	v.SetConfigFile(file)
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	if err := v.Unmarshal(configStruct); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Loader loads the config of a binary. Sources are applied in order, each one
+// overriding the previous: the defaults already set in the targets, the
+// config file, the environment variables and the flags set on the command line.
+//
+// Environment variables are named <EnvPrefix>_<SECTION>_<KEY>, e.g.
+// XDP_AGENT_SERVER_REPORTINTERVAL for the key server.reportInterval.
+type Loader struct {
+	// File is the yaml config file, no file is read when empty
+	File string
+	// Optional skips a missing file instead of failing
+	Optional bool
+	// EnvPrefix is the prefix of the environment variables, e.g. XDP_AGENT
+	EnvPrefix string
+	// Flags are the parsed flags bound to the fields of the targets
+	Flags *pflag.FlagSet
+}
+
+// Load fills every target with its section of the config, the section ""
+// is the whole file. Targets must be pointers to structs.
+func (l Loader) Load(sections map[string]any) (*viper.Viper, error) {
+	// 文件会覆盖 flag 绑定的字段, 先记下命令行上设置过的 flag 最后再写回去
+	restore := l.snapshotFlags()
+
+	v, err := l.Read(sections)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := restore(); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// Read is Load without the flags, it is used to reload the config into
+// fresh targets, see FlagChanged for keeping the command line values.
+func (l Loader) Read(sections map[string]any) (*viper.Viper, error) {
+	v := viper.New()
+
+	if l.File != "" {
+		_, err := os.Stat(l.File)
+		switch {
+		case err == nil:
+			dir, file := filepath.Split(l.File)
+			name := strings.TrimSuffix(file, filepath.Ext(file))
+
+			raw := map[string]any{}
+			if v, err = NewFromConfig(name, dir, &raw); err != nil {
+				return nil, fmt.Errorf("load config %s: %w", l.File, err)
+			}
+		case os.IsNotExist(err) && l.Optional:
+		default:
+			return nil, fmt.Errorf("load config %s: %w", l.File, err)
+		}
+	}
+
+	if l.EnvPrefix != "" {
+		v.SetEnvPrefix(l.EnvPrefix)
+		v.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+		for section, target := range sections {
+			for _, key := range keys(reflect.TypeOf(target), strings.ToLower(section)) {
+				if err := v.BindEnv(key); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	settings := v.AllSettings()
+	for section, target := range sections {
+		if err := decode(settings, section, target); err != nil {
+			return nil, fmt.Errorf("load config section %q: %w", section, err)
+		}
+	}
+
+	return v, nil
+}
+
+// FlagChanged reports whether the flag was set on the command line
+func (l Loader) FlagChanged(name string) bool {
+	return l.Flags != nil && l.Flags.Changed(name)
+}
+
+func (l Loader) snapshotFlags() func() error {
+	if l.Flags == nil {
+		return func() error { return nil }
+	}
+
+	var restores []func() error
+	l.Flags.Visit(func(f *pflag.Flag) {
+		if s, ok := f.Value.(pflag.SliceValue); ok {
+			values := append([]string(nil), s.GetSlice()...) // 解码会复用底层数组
+			restores = append(restores, func() error { return s.Replace(values) })
+			return
+		}
+
+		value := f.Value.String()
+		if f.Value.Type() == "stringToString" {
+			// String() 带有 [], Set 不接受; 这类 flag 会合并到文件中的 map
+			if value = strings.Trim(value, "[]"); value == "" {
+				return
+			}
+		}
+		restores = append(restores, func() error { return f.Value.Set(value) })
+	})
+
+	return func() error {
+		for _, restore := range restores {
+			if err := restore(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// decode unmarshals one section of the settings into target with the same
+// decode hooks as viper
+func decode(settings map[string]any, section string, target any) error {
+	data := settings
+	if section != "" {
+		sub, ok := settings[strings.ToLower(section)].(map[string]any)
+		if !ok {
+			return nil
+		}
+		data = sub
+	}
+
+	sv := viper.New()
+	if err := sv.MergeConfigMap(data); err != nil {
+		return err
+	}
+	return sv.Unmarshal(target)
+}
+
+// keys lists the leaf keys of a struct type as viper sees them, using the
+// mapstructure tag or the field name. Maps are not listed, they can not be
+// set from a single environment variable.
+func keys(t reflect.Type, prefix string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var result []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		key := strings.ToLower(name)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if opts == "squash" {
+			key = prefix
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft.Kind() == reflect.Map:
+		case ft.Kind() == reflect.Struct && ft.PkgPath() != "time":
+			result = append(result, keys(ft, key)...)
+		default:
+			result = append(result, key)
+		}
+	}
+
+	return result
+}
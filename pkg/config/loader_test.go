@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type testServer struct {
+	Addr     string        `mapstructure:"addr"`
+	Interval time.Duration `mapstructure:"interval"`
+	Peers    []string      `mapstructure:"peers"`
+}
+
+type testRoot struct {
+	Name  string `mapstructure:"name"`
+	Level string `mapstructure:"level"`
+}
+
+func TestLoader(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	data := "name: from-file\nlevel: info\nserver:\n  addr: 127.0.0.1:1\n  interval: 5s\n  peers: [a, b]\n"
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := &testRoot{Name: "default", Level: "debug"}
+	server := &testServer{Addr: "0.0.0.0:0", Interval: time.Second}
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.StringVar(&server.Addr, "addr", server.Addr, "")
+	flags.StringSliceVar(&server.Peers, "peers", server.Peers, "")
+	flags.StringVar(&root.Level, "level", root.Level, "")
+	if err := flags.Parse([]string{"--addr", "10.0.0.1:2", "--peers", "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("XDP_TEST_SERVER_INTERVAL", "30s")
+	t.Setenv("XDP_TEST_LEVEL", "warn")
+
+	l := Loader{File: file, EnvPrefix: "XDP_TEST", Flags: flags}
+	if _, err := l.Load(map[string]any{"": root, "server": server}); err != nil {
+		t.Fatal(err)
+	}
+
+	if root.Name != "from-file" {
+		t.Errorf("file should override the default, got %q", root.Name)
+	}
+	if root.Level != "warn" || server.Interval != 30*time.Second {
+		t.Errorf("env should override the file, got %q %s", root.Level, server.Interval)
+	}
+	if server.Addr != "10.0.0.1:2" || len(server.Peers) != 1 || server.Peers[0] != "c" {
+		t.Errorf("flags should override everything, got %q %v", server.Addr, server.Peers)
+	}
+	if !l.FlagChanged("addr") || l.FlagChanged("level") {
+		t.Error("unexpected changed flags")
+	}
+
+	// a missing optional file keeps the defaults
+	l = Loader{File: filepath.Join(t.TempDir(), "missing.yaml"), Optional: true}
+	fresh := &testServer{Addr: "default"}
+	if _, err := l.Load(map[string]any{"server": fresh}); err != nil || fresh.Addr != "default" {
+		t.Fatalf("unexpected %v %+v", err, fresh)
+	}
+
+	l.Optional = false
+	if _, err := l.Load(map[string]any{"server": fresh}); err == nil {
+		t.Fatal("a missing file should fail when it is not optional")
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"context"
+	"sync"
+
+	"xdp-banner/pkg/log"
+	"xdp-banner/pkg/option"
+	"xdp-banner/pkg/otlp"
+
+	"go.uber.org/zap"
+)
+
+// Logging owns the otlp log pipeline of a binary, it is rebuilt when the
+// endpoints change
+type Logging struct {
+	// name is the service name of the logs
+	name string
+
+	mu       sync.Mutex
+	pipeline *otlp.Log
+	// option is the otlp option the pipeline was built with
+	option *option.OtlpOption
+}
+
+func NewLogging(name string) *Logging {
+	return &Logging{name: name}
+}
+
+// Setup builds the pipeline and makes it the global logger, a previous
+// pipeline is flushed and closed
+func (l *Logging) Setup(o *option.OtlpOption) error {
+	res, err := otlp.DefaultResource(l.name)
+	if err != nil {
+		return err
+	}
+
+	pipeline, err := otlp.NewLogPipeline(
+		otlp.WithName(l.name),
+		otlp.WithHTTPEndpoint(o.LoggerHTTPEndpoint),
+		otlp.WithGrpcEndpoint(o.LoggerGrpcEndpoint),
+		otlp.WithResource(res),
+		otlp.WithInsecure(o.Insecure),
+		otlp.WithGzip(o.Gzip),
+		otlp.WithHeader(o.Header),
+		otlp.WithTimeout(o.Timeout),
+	)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	log.SetGlobalLogger(zap.New(log.NewTreeWithDefaultLogger(pipeline.Core)))
+	if l.pipeline != nil {
+		// 旧的 pipeline 里可能还有未发送的日志, flush 之后再关闭
+		if err := l.pipeline.Shutdown(context.Background()); err != nil {
+			log.Warn("shutdown the previous otlp log pipeline", log.ErrorField(err))
+		}
+	}
+	l.pipeline, l.option = pipeline, o
+
+	return nil
+}
+
+// update rebuilds the pipeline when o changes what it was built with
+func (l *Logging) update(o *option.OtlpOption) error {
+	l.mu.Lock()
+	changed := l.option == nil || o.LoggerChanged(l.option)
+	l.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+	return l.Setup(o)
+}
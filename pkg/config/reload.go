@@ -0,0 +1,135 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"xdp-banner/pkg/log"
+	"xdp-banner/pkg/option"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Common are the fields of a config every binary applies at runtime
+type Common struct {
+	Level *string
+	Otlp  *option.OtlpOption
+}
+
+// commonReloadable are the flags of the common fields, a flag set on the
+// command line keeps winning over the reloaded file
+var commonReloadable = map[string]func(dst, src Common){
+	"log-level":                 func(dst, src Common) { *dst.Level = *src.Level },
+	"otel.insecure":             func(dst, src Common) { dst.Otlp.Insecure = src.Otlp.Insecure },
+	"otel.gzip":                 func(dst, src Common) { dst.Otlp.Gzip = src.Otlp.Gzip },
+	"otel.timeout":              func(dst, src Common) { dst.Otlp.Timeout = src.Otlp.Timeout },
+	"otel.header":               func(dst, src Common) { dst.Otlp.Header = src.Otlp.Header },
+	"otel.logger-grpc-endpoint": func(dst, src Common) { dst.Otlp.LoggerGrpcEndpoint = src.Otlp.LoggerGrpcEndpoint },
+	"otel.logger-http-endpoint": func(dst, src Common) { dst.Otlp.LoggerHTTPEndpoint = src.Otlp.LoggerHTTPEndpoint },
+}
+
+// Reloader reloads the config of a binary and applies the fields which can
+// change at runtime: the common ones and those of Apply. The other fields
+// only take effect after a restart.
+type Reloader[T any] struct {
+	Loader  Loader
+	Logging *Logging
+	// Fresh returns a config holding its defaults and its sections, see
+	// Loader.Read
+	Fresh func() (*T, map[string]any)
+	// Common returns the common fields of a config
+	Common func(*T) Common
+	// Reloadable are the flags of the other fields applied at runtime, a
+	// flag set on the command line keeps winning over the reloaded file
+	Reloadable map[string]func(dst, src *T)
+	// Check validates a reloaded config, the current one is kept when it fails
+	Check func(*T) error
+	// Apply applies the other fields, it reports whether fields which only
+	// take effect after a restart changed
+	Apply func(fresh, old *T) (restart bool)
+
+	current *T
+}
+
+// Watch reloads the config on SIGHUP or when the file v was read from
+// changes, current is the config running. It returns when ctx is done.
+func (r *Reloader[T]) Watch(ctx context.Context, v *viper.Viper, current *T) {
+	r.current = current
+	WatchReload(ctx, v, r.Reload)
+}
+
+// Reload reads the config again and applies it
+func (r *Reloader[T]) Reload() {
+	fresh, sections := r.Fresh()
+	if _, err := r.Loader.Read(sections); err != nil {
+		log.Error("reload config", log.ErrorField(err))
+		return
+	}
+	old := r.current
+	freshCommon, oldCommon := r.Common(fresh), r.Common(old)
+	for flag, keep := range commonReloadable {
+		if r.Loader.FlagChanged(flag) {
+			keep(freshCommon, oldCommon)
+		}
+	}
+	for flag, keep := range r.Reloadable {
+		if r.Loader.FlagChanged(flag) {
+			keep(fresh, old)
+		}
+	}
+	if err := r.Check(fresh); err != nil {
+		log.Error("reload config, keeping the current one", log.ErrorField(err))
+		return
+	}
+
+	if *freshCommon.Level != *oldCommon.Level {
+		if err := log.SetLevel(*freshCommon.Level); err != nil {
+			log.Error("reload log level", log.ErrorField(err))
+		}
+	}
+	if r.Logging != nil {
+		// 失败时保留原来的 pipeline, 下一次 reload 再重建
+		if err := r.Logging.update(freshCommon.Otlp); err != nil {
+			log.Error("reload otlp log", log.ErrorField(err))
+		}
+	}
+	if r.Apply != nil && r.Apply(fresh, old) {
+		log.Warn("config changed fields which only take effect after a restart")
+	}
+
+	r.current = fresh
+	log.Info("config reloaded", log.StringField("level", *freshCommon.Level))
+}
+
+// WatchReload calls reload on SIGHUP and, when v was read from a file, every
+// time the file changes. It returns when ctx is done.
+func WatchReload(ctx context.Context, v *viper.Viper, reload func()) {
+	changed := make(chan struct{}, 1)
+	if v != nil && v.ConfigFileUsed() != "" {
+		v.OnConfigChange(func(fsnotify.Event) {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+		v.WatchConfig()
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-changed:
+		}
+
+		reload()
+	}
+}
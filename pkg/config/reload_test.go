@@ -0,0 +1,84 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"xdp-banner/pkg/option"
+
+	"github.com/spf13/pflag"
+)
+
+type testReloaded struct {
+	Level    string             `mapstructure:"level"`
+	Addr     string             `mapstructure:"addr"`
+	Interval time.Duration      `mapstructure:"interval"`
+	Otlp     *option.OtlpOption `mapstructure:"-"`
+}
+
+func TestReloader(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(data string) {
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	current := &testReloaded{Level: "warn", Addr: "127.0.0.1:1", Interval: time.Second, Otlp: &option.OtlpOption{}}
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.StringVar(&current.Level, "log-level", current.Level, "")
+	if err := flags.Parse([]string{"--log-level", "warn"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var restarts int
+	r := &Reloader[testReloaded]{
+		Loader: Loader{File: file, Flags: flags},
+		Fresh: func() (*testReloaded, map[string]any) {
+			fresh := &testReloaded{Otlp: &option.OtlpOption{}}
+			return fresh, map[string]any{"": fresh}
+		},
+		Common: func(c *testReloaded) Common { return Common{Level: &c.Level, Otlp: c.Otlp} },
+		Reloadable: map[string]func(dst, src *testReloaded){
+			"interval": func(dst, src *testReloaded) { dst.Interval = src.Interval },
+		},
+		Check: func(c *testReloaded) error {
+			if c.Addr == "" {
+				return errors.New("addr is required")
+			}
+			return nil
+		},
+		Apply: func(fresh, old *testReloaded) bool {
+			if fresh.Addr != old.Addr {
+				restarts++
+				return true
+			}
+			return false
+		},
+		current: current,
+	}
+
+	// 命令行设置的 log-level 不被文件覆盖
+	write("level: debug\naddr: 127.0.0.1:1\ninterval: 5s\n")
+	r.Reload()
+	if r.current.Level != "warn" || r.current.Interval != 5*time.Second || restarts != 0 {
+		t.Errorf("after reload = %+v, %d restarts", r.current, restarts)
+	}
+
+	write("level: debug\naddr: 127.0.0.1:2\n")
+	r.Reload()
+	if r.current.Addr != "127.0.0.1:2" || restarts != 1 {
+		t.Errorf("after changing addr = %+v, %d restarts", r.current, restarts)
+	}
+
+	// 检查失败时保留当前配置
+	applied := r.current
+	write("level: debug\n")
+	r.Reload()
+	if r.current != applied {
+		t.Errorf("an invalid config was applied: %+v", r.current)
+	}
+}
@@ -122,6 +122,12 @@ func (c *Controller) enqueue(key etcd.Key) {
 	c.queue.Add(key)
 }
 
+// EnqueueAfter syncs a key again after d, for the keys whose state changes
+// with time and not only with their events
+func (c *Controller) EnqueueAfter(key etcd.Key, d time.Duration) {
+	c.queue.AddAfter(key, d)
+}
+
 // noOpControllerImpl is a no-op implementation of ControllerImpl
 type noOpControllerImpl struct {
 }
@@ -0,0 +1,57 @@
+package datapath
+
+import (
+	"net/netip"
+
+	"xdp-banner/pkg/cidr"
+)
+
+// Rule is a rule an agent installs: the etcd key it watches the rule under,
+// the CIDR, the identity of its RuleMeta and its banlist entry
+type Rule struct {
+	Key      string
+	Prefix   netip.Prefix
+	Identity uint32
+	Entry    Entry
+}
+
+// Build writes the maps the agents write for the rules, see desired in
+// agent/internal/ruleset: the rules of a CIDR share the identity of the rule
+// with the smallest key, the rules inside a protected range are not installed
+// and the protected ranges inside a banned CIDR get AllowIdentity.
+func Build(rules []Rule, protected []netip.Prefix) *Maps {
+	owner := make(map[netip.Prefix]Rule)
+	var installed []Rule
+
+rules:
+	for _, r := range rules {
+		r.Prefix = r.Prefix.Masked()
+		for _, p := range protected {
+			if cidr.Contains(p, r.Prefix) {
+				continue rules
+			}
+		}
+		if o, ok := owner[r.Prefix]; !ok || r.Key < o.Key {
+			owner[r.Prefix] = r
+		}
+		installed = append(installed, r)
+	}
+
+	m := NewMaps()
+	for prefix, o := range owner {
+		m.SetIdentity(prefix, o.Identity)
+	}
+	for _, r := range installed {
+		m.AddEntry(owner[r.Prefix].Identity, r.Entry)
+	}
+
+	for _, p := range protected {
+		for prefix := range owner {
+			if cidr.Contains(prefix, p) {
+				m.SetIdentity(p.Masked(), AllowIdentity)
+				break
+			}
+		}
+	}
+	return m
+}
@@ -0,0 +1,100 @@
+// Package datapath models the decision of the XDP program of the agents,
+// agent/ebpf/c/xdp_banner.c, so that the rules can be reasoned about without a
+// kernel. The source address of a packet is looked up in identity_ipcache by
+// its longest prefix, a source without an identity passes. The banlist is then
+// looked up by lpm_rule_check with the identity, the protocol and the ports.
+package datapath
+
+// The IPPROTO numbers the XDP program looks at
+const (
+	ProtoICMP   uint8 = 1
+	ProtoTCP    uint8 = 6
+	ProtoUDP    uint8 = 17
+	ProtoICMPv6 uint8 = 58
+)
+
+// ParseProtocol returns the number the agents write for the protocol of a
+// rule key, it accepts the names xdp.ParseProtocol does
+func ParseProtocol(name string) (uint8, bool) {
+	switch name {
+	case "TCP", "tcp":
+		return ProtoTCP, true
+	case "UDP", "udp":
+		return ProtoUDP, true
+	case "ICMP", "icmp":
+		return ProtoICMP, true
+	default:
+		return 0, false
+	}
+}
+
+// Checked reports whether the XDP program runs lpm_rule_check for a packet
+// of the protocol from an IPv4 or an IPv6 source, the packets of the other
+// protocols from a source with an identity are dropped without it.
+func Checked(is4 bool, protocol uint8) bool {
+	switch protocol {
+	case ProtoTCP, ProtoUDP:
+		return true
+	case ProtoICMP:
+		return is4
+	case ProtoICMPv6:
+		return !is4
+	default:
+		return false
+	}
+}
+
+// Entry is a banlist entry of an identity. Zero ports match any port, an
+// entry without ports matches every packet of its protocol.
+type Entry struct {
+	Protocol uint8
+	Sport    uint16
+	Dport    uint16
+}
+
+// NewEntry returns the entry AddCIDRRule writes for a rule, the ports of the
+// protocols other than TCP and UDP are written under BANLIST_L3_FULL which
+// does not look at them.
+func NewEntry(protocol uint8, sport, dport uint16) Entry {
+	if protocol != ProtoTCP && protocol != ProtoUDP {
+		return Entry{Protocol: protocol}
+	}
+	return Entry{Protocol: protocol, Sport: sport, Dport: dport}
+}
+
+// Match reports whether lpm_rule_check finds the entry for a packet. Its
+// first lookup is bounded by PREFIX_FULL, so it finds the entries without
+// ports and the sport entries; an entry with only a dport is stored with a
+// zero sport under BANLIST_L4_DPORT and is found by the second lookup.
+func (e Entry) Match(protocol uint8, sport, dport uint16) bool {
+	if e.Protocol != protocol {
+		return false
+	}
+	switch {
+	case e.Sport == 0 && e.Dport == 0:
+		return true
+	case e.Dport == 0:
+		return sport == e.Sport
+	case e.Sport == 0:
+		return dport == e.Dport
+	default:
+		return sport == e.Sport && dport == e.Dport
+	}
+}
+
+// Covering returns the indexes of the entries matching every packet e
+// matches, an entry equal to e included. Together the entries cover e only
+// if one of them does: a packet with the zero port e leaves free is only
+// matched by an entry leaving that port free too.
+func Covering(entries []Entry, e Entry) []int {
+	var result []int
+	for i, c := range entries {
+		if c.Protocol != e.Protocol {
+			continue
+		}
+		if (c.Sport == 0 || c.Sport == e.Sport) && (c.Dport == 0 || c.Dport == e.Dport) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
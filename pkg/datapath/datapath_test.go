@@ -0,0 +1,59 @@
+package datapath
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEntryMatch(t *testing.T) {
+	tests := []struct {
+		e            Entry
+		protocol     uint8
+		sport, dport uint16
+		want         bool
+	}{
+		{NewEntry(ProtoTCP, 0, 0), ProtoTCP, 1234, 22, true},
+		{NewEntry(ProtoTCP, 0, 0), ProtoUDP, 1234, 22, false},
+		{NewEntry(ProtoTCP, 0, 22), ProtoTCP, 1234, 22, true},
+		{NewEntry(ProtoTCP, 0, 22), ProtoTCP, 22, 80, false},
+		{NewEntry(ProtoUDP, 53, 0), ProtoUDP, 53, 0, true},
+		{NewEntry(ProtoUDP, 53, 0), ProtoUDP, 1234, 53, false},
+		{NewEntry(ProtoTCP, 1234, 22), ProtoTCP, 1234, 22, true},
+		{NewEntry(ProtoTCP, 1234, 22), ProtoTCP, 1235, 22, false},
+		// ICMP 的端口写在 BANLIST_L3_FULL 之外
+		{NewEntry(ProtoICMP, 8, 0), ProtoICMP, 0, 0, true},
+	}
+	for _, tt := range tests {
+		if got := tt.e.Match(tt.protocol, tt.sport, tt.dport); got != tt.want {
+			t.Errorf("%+v.Match(%d, %d, %d) = %v, want %v", tt.e, tt.protocol, tt.sport, tt.dport, got, tt.want)
+		}
+	}
+}
+
+func TestCovering(t *testing.T) {
+	entries := []Entry{
+		NewEntry(ProtoTCP, 0, 22),
+		NewEntry(ProtoTCP, 1234, 0),
+		NewEntry(ProtoUDP, 0, 0),
+	}
+	tests := []struct {
+		e    Entry
+		want []int
+	}{
+		{NewEntry(ProtoTCP, 1234, 22), []int{0, 1}},
+		{NewEntry(ProtoTCP, 0, 22), []int{0}},
+		{NewEntry(ProtoTCP, 1234, 80), []int{1}},
+		{NewEntry(ProtoTCP, 0, 0), nil},
+		{NewEntry(ProtoUDP, 53, 53), []int{2}},
+		{NewEntry(ProtoICMP, 0, 0), nil},
+	}
+	for _, tt := range tests {
+		if got := Covering(entries, tt.e); !slices.Equal(got, tt.want) {
+			t.Errorf("Covering(%+v) = %v, want %v", tt.e, got, tt.want)
+		}
+	}
+
+	if !Checked(true, ProtoICMP) || Checked(false, ProtoICMP) || !Checked(false, ProtoICMPv6) || Checked(true, 47) {
+		t.Errorf("Checked() does not follow check_v4 and check_v6")
+	}
+}
@@ -0,0 +1,152 @@
+package datapath
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+// The ethertypes the XDP program and the decoding look at
+const (
+	EtherTypeIPv4 uint16 = 0x0800
+	EtherTypeIPv6 uint16 = 0x86dd
+	EtherTypeVLAN uint16 = 0x8100
+	EtherTypeQinQ uint16 = 0x88a8
+
+	ethernetHeader = 14
+	vlanTag        = 4
+	ipv4Header     = 20
+	ipv6Header     = 40
+	// ctx_no_room is checked against the whole TCP or UDP header
+	tcpHeader = 20
+	udpHeader = 8
+)
+
+// Frame is what the decoding of a frame found
+type Frame int
+
+const (
+	// FrameIP is an IPv4 or IPv6 packet, decided by Decide
+	FrameIP Frame = iota
+	// FrameNotIP passes the XDP program: a runt, an 802.3 frame or an
+	// ethertype other than IPv4 and IPv6, a VLAN tag included
+	FrameNotIP
+	// FrameShort is dropped by the XDP program: the frame ends before the
+	// end of its IP header
+	FrameShort
+	// FrameTruncated is a capture ending before the headers the XDP program
+	// reads, the packet on the wire was longer and can not be decided
+	FrameTruncated
+)
+
+func (f Frame) String() string {
+	switch f {
+	case FrameIP:
+		return "ip"
+	case FrameNotIP:
+		return "not-ip"
+	case FrameShort:
+		return "short"
+	case FrameTruncated:
+		return "truncated"
+	default:
+		return "unknown"
+	}
+}
+
+// DecodeEthernet decodes an Ethernet frame the way check_filters reads it.
+// data is the captured bytes of a frame of length bytes on the wire. The XDP
+// program reads the ethertype right after the MAC addresses, a VLAN tagged
+// frame passes it; with stripVLAN the tags are removed first, like a NIC
+// offloading them does before the XDP program runs. vlan reports a tag.
+func DecodeEthernet(data []byte, length int, stripVLAN bool) (p Packet, f Frame, vlan bool) {
+	if length < ethernetHeader {
+		return Packet{}, FrameNotIP, false
+	}
+	if len(data) < ethernetHeader {
+		return Packet{}, FrameTruncated, false
+	}
+
+	offset := 12
+	etherType := binary.BigEndian.Uint16(data[offset:])
+	for etherType == EtherTypeVLAN || etherType == EtherTypeQinQ {
+		vlan = true
+		if !stripVLAN {
+			return Packet{}, FrameNotIP, true
+		}
+		offset += vlanTag
+		if length < offset+2 {
+			return Packet{}, FrameNotIP, true
+		}
+		if len(data) < offset+2 {
+			return Packet{}, FrameTruncated, true
+		}
+		etherType = binary.BigEndian.Uint16(data[offset:])
+	}
+
+	offset += 2
+	p, f = DecodeIP(etherType, data[offset:], length-offset)
+	return p, f, vlan
+}
+
+// DecodeIP decodes the IP packet following an ethertype, for the link types
+// without an Ethernet header. data is the captured bytes of a packet of
+// length bytes.
+func DecodeIP(etherType uint16, data []byte, length int) (Packet, Frame) {
+	var p Packet
+	var l4 int
+	switch etherType {
+	case EtherTypeIPv4:
+		if f, ok := need(data, length, ipv4Header); !ok {
+			return Packet{}, f
+		}
+		p.Options = data[0]&0x0f != 5
+		p.Protocol = data[9]
+		p.Src = netip.AddrFrom4([4]byte(data[12:16]))
+		l4 = ipv4Header
+	case EtherTypeIPv6:
+		if f, ok := need(data, length, ipv6Header); !ok {
+			return Packet{}, f
+		}
+		p.Protocol = data[6]
+		p.Src = netip.AddrFrom16([16]byte(data[8:24]))
+		l4 = ipv6Header
+	default:
+		return Packet{}, FrameNotIP
+	}
+
+	// 与 XDP 程序一致, 端口总是从固定长度的 IP 头之后读取, 不考虑分片和扩展头
+	var size int
+	switch p.Protocol {
+	case ProtoTCP:
+		size = tcpHeader
+	case ProtoUDP:
+		size = udpHeader
+	default:
+		return p, FrameIP
+	}
+	if p.Options {
+		return p, FrameIP
+	}
+	if length < l4+size {
+		p.Short = true
+		return p, FrameIP
+	}
+	if len(data) < l4+size {
+		return Packet{}, FrameTruncated
+	}
+	p.Sport = binary.BigEndian.Uint16(data[l4:])
+	p.Dport = binary.BigEndian.Uint16(data[l4+2:])
+	return p, FrameIP
+}
+
+// need checks that a header of size bytes is in the packet and in the capture
+func need(data []byte, length, size int) (Frame, bool) {
+	switch {
+	case length < size:
+		return FrameShort, false
+	case len(data) < size:
+		return FrameTruncated, false
+	default:
+		return FrameIP, true
+	}
+}
@@ -0,0 +1,75 @@
+package datapath
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+)
+
+func ethernet(etherType uint16, payload ...[]byte) []byte {
+	frame := make([]byte, 12, 64)
+	frame = binary.BigEndian.AppendUint16(frame, etherType)
+	for _, p := range payload {
+		frame = append(frame, p...)
+	}
+	return frame
+}
+
+func ipv4(protocol uint8, ihl byte, src string) []byte {
+	h := make([]byte, 4*int(ihl))
+	h[0] = 0x40 | ihl
+	h[9] = protocol
+	a := netip.MustParseAddr(src).As4()
+	copy(h[12:], a[:])
+	return h
+}
+
+func ipv6(next uint8, src string) []byte {
+	h := make([]byte, 40)
+	h[0] = 0x60
+	h[6] = next
+	a := netip.MustParseAddr(src).As16()
+	copy(h[8:], a[:])
+	return h
+}
+
+func ports(sport, dport uint16, size int) []byte {
+	h := make([]byte, size)
+	binary.BigEndian.PutUint16(h, sport)
+	binary.BigEndian.PutUint16(h[2:], dport)
+	return h
+}
+
+func TestDecodeEthernet(t *testing.T) {
+	tcp := ethernet(EtherTypeIPv4, ipv4(ProtoTCP, 5, "192.0.2.1"), ports(1234, 22, 20))
+	vlan := ethernet(EtherTypeVLAN, []byte{0, 10}, binary.BigEndian.AppendUint16(nil, EtherTypeIPv6), ipv6(ProtoUDP, "2001:db8::1"), ports(53, 4000, 8))
+
+	tests := []struct {
+		name      string
+		data      []byte
+		length    int
+		stripVLAN bool
+		packet    Packet
+		frame     Frame
+		vlan      bool
+	}{
+		{"tcp", tcp, len(tcp), false, Packet{Src: netip.MustParseAddr("192.0.2.1"), Protocol: ProtoTCP, Sport: 1234, Dport: 22}, FrameIP, false},
+		{"snaplen", tcp[:40], len(tcp), false, Packet{}, FrameTruncated, false},
+		// 报文本身就不完整, XDP 程序丢弃
+		{"short tcp", tcp[:40], 40, false, Packet{Src: netip.MustParseAddr("192.0.2.1"), Protocol: ProtoTCP, Short: true}, FrameIP, false},
+		{"short ip", tcp[:30], 30, false, Packet{}, FrameShort, false},
+		{"options", ethernet(EtherTypeIPv4, ipv4(ProtoTCP, 6, "192.0.2.1")), 38, false, Packet{Src: netip.MustParseAddr("192.0.2.1"), Protocol: ProtoTCP, Options: true}, FrameIP, false},
+		{"icmp", ethernet(EtherTypeIPv4, ipv4(ProtoICMP, 5, "192.0.2.1")), 34, false, Packet{Src: netip.MustParseAddr("192.0.2.1"), Protocol: ProtoICMP}, FrameIP, false},
+		{"arp", ethernet(0x0806, make([]byte, 28)), 42, false, Packet{}, FrameNotIP, false},
+		{"802.3", ethernet(100, make([]byte, 100)), 114, false, Packet{}, FrameNotIP, false},
+		{"runt", tcp[:10], 10, false, Packet{}, FrameNotIP, false},
+		{"vlan", vlan, len(vlan), false, Packet{}, FrameNotIP, true},
+		{"stripped vlan", vlan, len(vlan), true, Packet{Src: netip.MustParseAddr("2001:db8::1"), Protocol: ProtoUDP, Sport: 53, Dport: 4000}, FrameIP, true},
+	}
+	for _, tt := range tests {
+		packet, frame, vlan := DecodeEthernet(tt.data, tt.length, tt.stripVLAN)
+		if packet != tt.packet || frame != tt.frame || vlan != tt.vlan {
+			t.Errorf("%s: DecodeEthernet = %+v %s %v, want %+v %s %v", tt.name, packet, frame, vlan, tt.packet, tt.frame, tt.vlan)
+		}
+	}
+}
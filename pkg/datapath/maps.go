@@ -0,0 +1,321 @@
+package datapath
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"net/netip"
+	"slices"
+)
+
+// The address families of identity_ipcache keys
+const (
+	AFInet  uint8 = 2
+	AFInet6 uint8 = 10
+)
+
+// The prefix lengths of the keys, see eps.h and common.h
+const (
+	// IpcacheStaticPrefix is the length of pad1, pad2 and family
+	IpcacheStaticPrefix = 32
+
+	BanlistL3Full  = 64 // protocol + identity
+	BanlistL4Sport = 80 // + sport
+	BanlistL4Dport = 96 // + dport
+	BanlistL4Full  = 96 // protocol + identity + sport + dport
+)
+
+const (
+	ipcacheKeySize = 4 + 20
+	banlistKeySize = 4 + 12
+)
+
+// AllowIdentity is the identity the agents write for a protected range
+// inside a banned CIDR, no banlist entry uses it
+var AllowIdentity = crc32.ChecksumIEEE([]byte("static/allow"))
+
+// IpcacheKey encodes the identity_ipcache key of a prefix the way
+// newIpcacheKey does: the prefix length, then pad1, pad2, family and the
+// address, an IPv4 address in its first 4 bytes
+func IpcacheKey(prefix netip.Prefix) []byte {
+	key := make([]byte, ipcacheKeySize)
+	binary.NativeEndian.PutUint32(key, uint32(IpcacheStaticPrefix+prefix.Bits()))
+
+	addr := prefix.Masked().Addr()
+	if addr.Is4() {
+		key[7] = AFInet
+		a := addr.As4()
+		copy(key[8:], a[:])
+	} else {
+		key[7] = AFInet6
+		a := addr.As16()
+		copy(key[8:], a[:])
+	}
+	return key
+}
+
+// BanlistKey encodes the xdp_banner_banlist key of an entry the way
+// AddCIDRRule does: the prefix length, then pad1, pad2, protocol, the
+// identity in host order and the ports in network order
+func BanlistKey(identity uint32, e Entry) []byte {
+	key := make([]byte, banlistKeySize)
+	binary.NativeEndian.PutUint32(key, e.prefixLen())
+	key[7] = e.Protocol
+	binary.NativeEndian.PutUint32(key[8:], identity)
+	binary.BigEndian.PutUint16(key[12:], e.Sport)
+	binary.BigEndian.PutUint16(key[14:], e.Dport)
+	return key
+}
+
+// prefixLen is the prefix length AddCIDRRule writes the entry under
+func (e Entry) prefixLen() uint32 {
+	if e.Protocol != ProtoTCP && e.Protocol != ProtoUDP {
+		return BanlistL3Full
+	}
+	switch {
+	case e.Sport != 0 && e.Dport != 0:
+		return BanlistL4Full
+	case e.Dport != 0:
+		return BanlistL4Dport
+	case e.Sport != 0:
+		return BanlistL4Sport
+	default:
+		return BanlistL3Full
+	}
+}
+
+// lpm is a BPF_MAP_TYPE_LPM_TRIE. A key is a 32 bits prefix length in host
+// order followed by the data, an update replaces the entry with the same
+// prefix and a lookup returns the entry with the longest prefix matching the
+// data, no longer than the prefix length of the looked up key.
+type lpm[V any] struct {
+	size int
+	// entries are keyed by prefix length and then by the masked data, lengths
+	// are the prefix lengths having entries, longest first
+	entries map[uint32]map[string]V
+	lengths []uint32
+}
+
+func newLPM[V any](size int) *lpm[V] {
+	return &lpm[V]{size: size, entries: make(map[uint32]map[string]V)}
+}
+
+func (t *lpm[V]) parse(key []byte) (uint32, []byte, error) {
+	if len(key) != t.size {
+		return 0, nil, fmt.Errorf("key of %d bytes, want %d", len(key), t.size)
+	}
+	prefixLen := binary.NativeEndian.Uint32(key)
+	if int(prefixLen) > (t.size-4)*8 {
+		return 0, nil, fmt.Errorf("prefix length %d is longer than the key", prefixLen)
+	}
+	return prefixLen, key[4:], nil
+}
+
+func (t *lpm[V]) update(key []byte, value V) error {
+	prefixLen, data, err := t.parse(key)
+	if err != nil {
+		return err
+	}
+	if t.entries[prefixLen] == nil {
+		t.entries[prefixLen] = make(map[string]V)
+		t.lengths = append(t.lengths, prefixLen)
+		slices.SortFunc(t.lengths, func(a, b uint32) int { return cmp.Compare(b, a) })
+	}
+	t.entries[prefixLen][mask(data, prefixLen)] = value
+	return nil
+}
+
+func (t *lpm[V]) delete(key []byte) (bool, error) {
+	prefixLen, data, err := t.parse(key)
+	if err != nil {
+		return false, err
+	}
+	m := mask(data, prefixLen)
+	if _, ok := t.entries[prefixLen][m]; !ok {
+		return false, nil
+	}
+	delete(t.entries[prefixLen], m)
+	return true, nil
+}
+
+func (t *lpm[V]) lookup(key []byte) (V, uint32, bool) {
+	prefixLen, data, err := t.parse(key)
+	if err == nil {
+		for _, l := range t.lengths {
+			if l > prefixLen {
+				continue
+			}
+			if v, ok := t.entries[l][mask(data, l)]; ok {
+				return v, l, true
+			}
+		}
+	}
+	var zero V
+	return zero, 0, false
+}
+
+// mask keeps the first bits of data
+func mask(data []byte, bits uint32) string {
+	b := make([]byte, len(data))
+	n := int(bits) / 8
+	copy(b, data[:n])
+	if r := bits % 8; r != 0 {
+		b[n] = data[n] & (0xff << (8 - r))
+	}
+	return string(b)
+}
+
+// Maps is the content of identity_ipcache and xdp_banner_banlist, written
+// with the keys the agents write and read by Decide like the XDP program
+type Maps struct {
+	ipcache *lpm[uint32]
+	banlist *lpm[struct{}]
+}
+
+func NewMaps() *Maps {
+	return &Maps{ipcache: newLPM[uint32](ipcacheKeySize), banlist: newLPM[struct{}](banlistKeySize)}
+}
+
+// UpdateIpcache writes an encoded identity_ipcache key, see IpcacheKey
+func (m *Maps) UpdateIpcache(key []byte, identity uint32) error {
+	return m.ipcache.update(key, identity)
+}
+
+// DeleteIpcache deletes an encoded identity_ipcache key, false if it is not there
+func (m *Maps) DeleteIpcache(key []byte) (bool, error) {
+	return m.ipcache.delete(key)
+}
+
+// UpdateBanlist writes an encoded banlist key, see BanlistKey
+func (m *Maps) UpdateBanlist(key []byte) error {
+	return m.banlist.update(key, struct{}{})
+}
+
+// DeleteBanlist deletes an encoded banlist key, false if it is not there
+func (m *Maps) DeleteBanlist(key []byte) (bool, error) {
+	return m.banlist.delete(key)
+}
+
+// SetIdentity writes the identity of a prefix like SetCIDRIdentity
+func (m *Maps) SetIdentity(prefix netip.Prefix, identity uint32) {
+	// 编码出的 key 总是合法的
+	_ = m.UpdateIpcache(IpcacheKey(prefix), identity)
+}
+
+// AddEntry writes a banlist entry of an identity like AddCIDRRule
+func (m *Maps) AddEntry(identity uint32, e Entry) {
+	_ = m.UpdateBanlist(BanlistKey(identity, e))
+}
+
+// Packet is what the XDP program looks at in a packet
+type Packet struct {
+	Src      netip.Addr
+	Protocol uint8
+	Sport    uint16
+	Dport    uint16
+	// Options is true for an IPv4 header longer than 20 bytes
+	Options bool
+	// Short is true for a TCP or UDP packet ending before the end of its
+	// TCP or UDP header
+	Short bool
+}
+
+// Verdict is the decision of the XDP program for a packet
+type Verdict struct {
+	Drop bool
+	// Prefix and Identity are the identity_ipcache entry of the source,
+	// Prefix is invalid when the source has no identity
+	Prefix   netip.Prefix
+	Identity uint32
+	// Entry is the banlist entry found by lpm_rule_check, Matched is false
+	// when the verdict does not come from one
+	Entry   Entry
+	Matched bool
+	Reason  string
+}
+
+// Decide returns the verdict of check_v4 and check_v6 for a packet
+func (m *Maps) Decide(p Packet) Verdict {
+	src := p.Src.Unmap()
+	is4 := src.Is4()
+	if is4 && p.Options {
+		return Verdict{Drop: true, Reason: "the XDP program drops the IPv4 packets with options"}
+	}
+
+	bits := 128
+	if is4 {
+		bits = 32
+	}
+	identity, prefixLen, ok := m.ipcache.lookup(IpcacheKey(netip.PrefixFrom(src, bits)))
+	if !ok {
+		return Verdict{Reason: "the source has no identity"}
+	}
+	v := Verdict{
+		Prefix:   netip.PrefixFrom(src, int(prefixLen)-IpcacheStaticPrefix).Masked(),
+		Identity: identity,
+	}
+
+	sport, dport := p.Sport, p.Dport
+	switch {
+	case p.Protocol == ProtoTCP || p.Protocol == ProtoUDP:
+		if p.Short {
+			v.Drop = true
+			v.Reason = "the XDP program drops the packets too short for their TCP or UDP header"
+			return v
+		}
+	case Checked(is4, p.Protocol):
+		sport, dport = 0, 0
+	default:
+		v.Drop = true
+		v.Reason = fmt.Sprintf("the XDP program drops protocol %d from a source with an identity", p.Protocol)
+		return v
+	}
+
+	if e, ok := m.ruleCheck(p.Protocol, identity, sport, dport); ok {
+		v.Drop = true
+		v.Entry = e
+		v.Matched = true
+		v.Reason = "a banlist entry of the identity matches"
+		return v
+	}
+	v.Reason = "no banlist entry of the identity matches"
+	return v
+}
+
+// ruleCheck follows lpm_rule_check, it returns the entry found
+func (m *Maps) ruleCheck(protocol uint8, identity uint32, sport, dport uint16) (Entry, bool) {
+	type lookup struct {
+		prefixLen    uint32
+		sport, dport uint16
+	}
+	lookups := []lookup{{BanlistL4Full, sport, dport}}
+	if dport != 0 {
+		lookups = append(lookups, lookup{BanlistL4Dport, 0, dport})
+	}
+	if sport != 0 {
+		lookups = append(lookups, lookup{BanlistL4Sport, sport, 0})
+	}
+	lookups = append(lookups, lookup{BanlistL3Full, 0, 0})
+
+	for _, l := range lookups {
+		key := BanlistKey(identity, Entry{Protocol: protocol, Sport: l.sport, Dport: l.dport})
+		binary.NativeEndian.PutUint32(key, l.prefixLen)
+		if _, prefixLen, ok := m.banlist.lookup(key); ok {
+			return entryOf(protocol, l.sport, l.dport, prefixLen), true
+		}
+	}
+	return Entry{}, false
+}
+
+// entryOf is the entry found by a lookup of the ports under a prefix length
+func entryOf(protocol uint8, sport, dport uint16, prefixLen uint32) Entry {
+	switch {
+	case prefixLen >= BanlistL4Full:
+		return Entry{Protocol: protocol, Sport: sport, Dport: dport}
+	case prefixLen >= BanlistL4Sport:
+		return Entry{Protocol: protocol, Sport: sport}
+	default:
+		return Entry{Protocol: protocol}
+	}
+}
@@ -0,0 +1,87 @@
+package datapath
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestBuildDecide(t *testing.T) {
+	rules := []Rule{
+		{Key: "b", Prefix: netip.MustParsePrefix("10.0.0.0/8"), Identity: 1, Entry: NewEntry(ProtoTCP, 0, 22)},
+		// 同一 CIDR 取 key 最小的规则的 identity
+		{Key: "a", Prefix: netip.MustParsePrefix("10.0.0.0/8"), Identity: 2, Entry: NewEntry(ProtoICMP, 0, 0)},
+		{Key: "c", Prefix: netip.MustParsePrefix("10.1.0.0/16"), Identity: 3, Entry: NewEntry(ProtoUDP, 53, 0)},
+		// 保护网段内的规则不会下发
+		{Key: "d", Prefix: netip.MustParsePrefix("10.2.3.0/24"), Identity: 4, Entry: NewEntry(ProtoTCP, 0, 0)},
+		{Key: "e", Prefix: netip.MustParsePrefix("2001:db8::/32"), Identity: 5, Entry: NewEntry(ProtoTCP, 1234, 443)},
+	}
+	m := Build(rules, []netip.Prefix{netip.MustParsePrefix("10.2.0.0/16")})
+
+	tests := []struct {
+		p        Packet
+		drop     bool
+		prefix   string
+		identity uint32
+		entry    Entry
+	}{
+		{Packet{Src: netip.MustParseAddr("10.9.0.1"), Protocol: ProtoTCP, Sport: 40000, Dport: 22}, true, "10.0.0.0/8", 2, NewEntry(ProtoTCP, 0, 22)},
+		{Packet{Src: netip.MustParseAddr("10.9.0.1"), Protocol: ProtoTCP, Sport: 40000, Dport: 80}, false, "10.0.0.0/8", 2, Entry{}},
+		{Packet{Src: netip.MustParseAddr("10.9.0.1"), Protocol: ProtoICMP}, true, "10.0.0.0/8", 2, NewEntry(ProtoICMP, 0, 0)},
+		// 其他协议直接丢弃
+		{Packet{Src: netip.MustParseAddr("10.9.0.1"), Protocol: 47}, true, "10.0.0.0/8", 2, Entry{}},
+		{Packet{Src: netip.MustParseAddr("10.1.0.1"), Protocol: ProtoTCP, Sport: 40000, Dport: 22}, false, "10.1.0.0/16", 3, Entry{}},
+		{Packet{Src: netip.MustParseAddr("10.1.0.1"), Protocol: ProtoUDP, Sport: 53, Dport: 40000}, true, "10.1.0.0/16", 3, NewEntry(ProtoUDP, 53, 0)},
+		{Packet{Src: netip.MustParseAddr("10.2.3.4"), Protocol: ProtoTCP, Sport: 40000, Dport: 22}, false, "10.2.0.0/16", AllowIdentity, Entry{}},
+		{Packet{Src: netip.MustParseAddr("2001:db8::1"), Protocol: ProtoTCP, Sport: 1234, Dport: 443}, true, "2001:db8::/32", 5, NewEntry(ProtoTCP, 1234, 443)},
+		{Packet{Src: netip.MustParseAddr("2001:db8::1"), Protocol: ProtoICMPv6}, false, "2001:db8::/32", 5, Entry{}},
+		{Packet{Src: netip.MustParseAddr("192.0.2.1"), Protocol: 47}, false, "invalid Prefix", 0, Entry{}},
+		{Packet{Src: netip.MustParseAddr("192.0.2.1"), Protocol: ProtoTCP, Options: true}, true, "invalid Prefix", 0, Entry{}},
+	}
+	for _, tt := range tests {
+		v := m.Decide(tt.p)
+		if v.Drop != tt.drop || v.Prefix.String() != tt.prefix || v.Identity != tt.identity || v.Entry != tt.entry {
+			t.Errorf("Decide(%+v) = %+v, want drop %v from %s identity %d entry %+v", tt.p, v, tt.drop, tt.prefix, tt.identity, tt.entry)
+		}
+	}
+}
+
+// TestDecideMatch checks lpm_rule_check against Entry.Match
+func TestDecideMatch(t *testing.T) {
+	prefix := netip.MustParsePrefix("192.0.2.0/24")
+	entries := []Entry{
+		NewEntry(ProtoTCP, 0, 0),
+		NewEntry(ProtoTCP, 1000, 0),
+		NewEntry(ProtoTCP, 0, 2000),
+		NewEntry(ProtoTCP, 1000, 2000),
+	}
+	ports := []uint16{0, 1000, 2000, 3000}
+
+	// 每个条目子集分别建表, 对比所有端口组合
+	for mask := 0; mask < 1<<len(entries); mask++ {
+		var installed []Entry
+		m := NewMaps()
+		m.SetIdentity(prefix, 7)
+		for i, e := range entries {
+			if mask&(1<<i) != 0 {
+				installed = append(installed, e)
+				m.AddEntry(7, e)
+			}
+		}
+
+		for _, sport := range ports {
+			for _, dport := range ports {
+				want := false
+				for _, e := range installed {
+					want = want || e.Match(ProtoTCP, sport, dport)
+				}
+				v := m.Decide(Packet{Src: netip.MustParseAddr("192.0.2.1"), Protocol: ProtoTCP, Sport: sport, Dport: dport})
+				if v.Drop != want {
+					t.Errorf("entries %+v, ports %d %d: drop %v, want %v", installed, sport, dport, v.Drop, want)
+				}
+				if v.Drop && !v.Entry.Match(ProtoTCP, sport, dport) {
+					t.Errorf("entries %+v, ports %d %d: matched %+v", installed, sport, dport, v.Entry)
+				}
+			}
+		}
+	}
+}
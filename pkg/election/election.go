@@ -3,6 +3,7 @@ package election
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"xdp-banner/pkg/etcd"
@@ -167,6 +168,25 @@ func (e *Election) Resign(force bool) error {
 	return nil
 }
 
+// IsLeader reports whether this node holds the leadership
+func (e *Election) IsLeader() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.isLeader
+}
+
+// Alive returns an error once the etcd session is gone, the lease expired or
+// is no longer kept alive, this node can neither lead nor campaign anymore
+func (e *Election) Alive() error {
+	select {
+	case <-e.session.Done():
+		return errors.New("etcd session expired")
+	default:
+		return nil
+	}
+}
+
 func (e *Election) Close() {
 	e.cancel()
 	e.session.Close()
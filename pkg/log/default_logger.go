@@ -20,7 +20,7 @@ func newDefaultProductionLog() zap.Logger {
 	bl.writer, _ = bl.writerOpener.OpenWriter()
 
 	bl.encoder = newDefaultProductionLogEncoder(bl.writerOpener)
-	bl.levelEnabler = zapcore.DebugLevel
+	bl.levelEnabler = level
 
 	bl.buildCore()
 
@@ -1,10 +1,32 @@
 package log
 
-import "go.uber.org/zap/zapcore"
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// level is the minimum level of the loggers built by this package, it can be
+// changed at runtime by SetLevel
+var level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+
+// SetLevel changes the minimum level of the global logger, e.g. "info"
+func SetLevel(l string) error {
+	return level.UnmarshalText([]byte(l))
+}
+
+// Level returns the current minimum level
+func Level() string {
+	return level.String()
+}
 
 func NewTreeWithDefaultLogger(cores ...zapcore.Core) zapcore.Core {
 	def := newDefaultProductionLog()
 
 	cores = append(cores, def.Core())
-	return zapcore.NewTee(cores...)
+	core, err := zapcore.NewIncreaseLevelCore(zapcore.NewTee(cores...), level)
+	if err != nil {
+		// the tee enables every level, it can not happen
+		return zapcore.NewTee(cores...)
+	}
+	return core
 }
@@ -0,0 +1,31 @@
+package log
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSetLevel(t *testing.T) {
+	defer SetLevel("debug")
+
+	obs, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(NewTreeWithDefaultLogger(obs))
+
+	l.Debug("before")
+	if err := SetLevel("warn"); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("filtered")
+	l.Warn("after")
+
+	if logs.Len() != 2 || logs.All()[1].Message != "after" {
+		t.Fatalf("unexpected logs %v", logs.AllUntimed())
+	}
+
+	if err := SetLevel("verbose"); err == nil {
+		t.Fatal("invalid level should be rejected")
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // The documentation about this IPC protocol is available here:
@@ -64,4 +66,25 @@ func Error(err error, errno int) error {
 	return sdNotify(msg)
 }
 
+// Watchdog tells the service manager that the process is still alive, it
+// must be sent within WatchdogSec or systemd kills the process.
+func Watchdog() error {
+	return sdNotify("WATCHDOG=1")
+}
+
+// WatchdogEnabled returns the watchdog timeout set by systemd, false when
+// the watchdog is disabled or meant for another process.
+func WatchdogEnabled() (time.Duration, bool) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+
+	return time.Duration(usec) * time.Microsecond, true
+}
+
 var socketPath, _ = os.LookupEnv("NOTIFY_SOCKET")
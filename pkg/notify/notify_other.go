@@ -2,8 +2,13 @@
 
 package notify
 
+import "time"
+
 func Ready() error               { return nil }
 func Reloading() error           { return nil }
 func Stopping() error            { return nil }
 func Status(_ string) error      { return nil }
 func Error(_ error, _ int) error { return nil }
+func Watchdog() error            { return nil }
+
+func WatchdogEnabled() (time.Duration, bool) { return 0, false }
@@ -1,6 +1,10 @@
 package notify
 
-import "golang.org/x/sys/windows/svc"
+import (
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+)
 
 // globalStatus store windows service status, it can be
 // use to notify caddy status.
@@ -39,3 +43,8 @@ func Status(_ string) error { return nil }
 
 // TODO: not implemented
 func Error(_ error, _ int) error { return nil }
+
+// Watchdog is not supported by the windows service manager
+func Watchdog() error { return nil }
+
+func WatchdogEnabled() (time.Duration, bool) { return 0, false }
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// statusInterval is how often STATUS= is refreshed when the watchdog is disabled
+const statusInterval = 30 * time.Second
+
+// Check is an internal health check, it returns nil when healthy
+type Check struct {
+	Name  string
+	Check func() error
+}
+
+// RunWatchdog pings the watchdog at half of WatchdogSec as long as all the
+// checks pass, a failing check withholds the ping so systemd restarts the
+// process once the timeout expires. status summarizes the state in the
+// STATUS= line, it is sent even when the watchdog is disabled.
+func RunWatchdog(ctx context.Context, status func() string, checks ...Check) {
+	timeout, enabled := WatchdogEnabled()
+	interval := statusInterval
+	if enabled {
+		interval = timeout / 2
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := runChecks(checks); err != nil {
+			_ = Status("unhealthy: " + err.Error())
+		} else {
+			_ = Status(status())
+			if enabled {
+				_ = Watchdog()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runChecks(checks []Check) error {
+	for _, c := range checks {
+		if err := c.Check(); err != nil {
+			return fmt.Errorf("%s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package notify
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRunChecks(t *testing.T) {
+	ok := Check{Name: "ok", Check: func() error { return nil }}
+	bad := Check{Name: "stream", Check: func() error { return errors.New("disconnected") }}
+
+	if err := runChecks([]Check{ok}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	err := runChecks([]Check{ok, bad})
+	if err == nil || err.Error() != "stream: disconnected" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
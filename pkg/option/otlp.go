@@ -1,21 +1,36 @@
 package option
 
 import (
+	"maps"
 	"time"
 
 	"github.com/spf13/cobra"
 )
 
 type OtlpOption struct {
-	Insecure           bool
-	Gzip               bool
-	Timeout            time.Duration
-	MetricInterval     time.Duration
-	Header             map[string]string
-	MetricGrpcEndpoint string
-	MetricHTTPEndpoint string
-	LoggerGrpcEndpoint string
-	LoggerHTTPEndpoint string
+	Insecure           bool              `mapstructure:"insecure"`
+	Gzip               bool              `mapstructure:"gzip"`
+	Timeout            time.Duration     `mapstructure:"timeout"`
+	MetricInterval     time.Duration     `mapstructure:"metricInterval"`
+	Header             map[string]string `mapstructure:"header"`
+	MetricGrpcEndpoint string            `mapstructure:"metricGrpcEndpoint"`
+	MetricHTTPEndpoint string            `mapstructure:"metricHttpEndpoint"`
+	LoggerGrpcEndpoint string            `mapstructure:"loggerGrpcEndpoint"`
+	LoggerHTTPEndpoint string            `mapstructure:"loggerHttpEndpoint"`
+}
+
+// LoggerChanged reports whether the log pipeline has to be rebuilt for o
+func (o *OtlpOption) LoggerChanged(old *OtlpOption) bool {
+	return o.Insecure != old.Insecure || o.Gzip != old.Gzip || o.Timeout != old.Timeout ||
+		!maps.Equal(o.Header, old.Header) ||
+		o.LoggerGrpcEndpoint != old.LoggerGrpcEndpoint || o.LoggerHTTPEndpoint != old.LoggerHTTPEndpoint
+}
+
+// MetricChanged reports whether the metric pipeline has to be rebuilt for o
+func (o *OtlpOption) MetricChanged(old *OtlpOption) bool {
+	return o.Insecure != old.Insecure || o.Gzip != old.Gzip || o.Timeout != old.Timeout ||
+		!maps.Equal(o.Header, old.Header) || o.MetricInterval != old.MetricInterval ||
+		o.MetricGrpcEndpoint != old.MetricGrpcEndpoint || o.MetricHTTPEndpoint != old.MetricHTTPEndpoint
 }
 
 func (o *OtlpOption) SetFlags(cmd *cobra.Command) {
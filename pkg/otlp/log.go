@@ -14,12 +14,31 @@ import (
 )
 
 func NewLog(opts ...OptionFunc) (zapcore.Core, error) {
+	l, err := NewLogPipeline(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return l.Core, nil
+}
+
+// Log is an opentelemetry log pipeline bridged into zap, Shutdown flushes
+// and stops it so the pipeline can be replaced when the endpoints change.
+type Log struct {
+	Core     zapcore.Core
+	provider *log.LoggerProvider
+}
+
+func NewLogPipeline(opts ...OptionFunc) (*Log, error) {
 	opt := NewOption(opts...)
 	return setupZapLog(opt)
 }
 
+func (l *Log) Shutdown(ctx context.Context) error {
+	return l.provider.Shutdown(ctx)
+}
+
 // setupLog sets up the logger and config opentelemetry log for the application.
-func setupZapLog(opt Option) (zapcore.Core, error) {
+func setupZapLog(opt Option) (*Log, error) {
 	exp, err := newLogExporter(opt)
 	if err != nil {
 		return nil, err
@@ -28,14 +47,14 @@ func setupZapLog(opt Option) (zapcore.Core, error) {
 	prod := newLoggerProvider(exp, opt.Resource)
 	core := newZapBridgeLogger(opt.Name, prod)
 
-	return core, nil
+	return &Log{Core: core, provider: prod}, nil
 }
 
 func newZapBridgeLogger(name string, provider otel.LoggerProvider) zapcore.Core {
 	return otelzap.NewCore(name, otelzap.WithLoggerProvider(provider))
 }
 
-func newLoggerProvider(exporter log.Exporter, res *resource.Resource) otel.LoggerProvider {
+func newLoggerProvider(exporter log.Exporter, res *resource.Resource) *log.LoggerProvider {
 	loggerProvider := log.NewLoggerProvider(
 		log.WithProcessor(log.NewBatchProcessor(exporter)),
 		log.WithResource(res),
@@ -0,0 +1,339 @@
+// Package pcap reads the packets of a capture file, in the pcap format of
+// libpcap or in pcapng, without cgo or a capture device.
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// The link types of the captures decoded by the replay, see
+// https://www.tcpdump.org/linktypes.html
+const (
+	LinkTypeEthernet  uint32 = 1
+	LinkTypeRaw       uint32 = 101
+	LinkTypeLinuxSLL  uint32 = 113
+	LinkTypeIPv4      uint32 = 228
+	LinkTypeIPv6      uint32 = 229
+	LinkTypeLinuxSLL2 uint32 = 276
+)
+
+const (
+	magicMicros      = 0xa1b2c3d4
+	magicNanos       = 0xa1b23c4d
+	pcapHeaderSize   = 24
+	pcapRecordHeader = 16
+
+	blockSectionHeader   = 0x0a0d0d0a
+	blockInterface       = 0x00000001
+	blockPacket          = 0x00000002
+	blockSimplePacket    = 0x00000003
+	blockEnhancedPacket  = 0x00000006
+	byteOrderMagic       = 0x1a2b3c4d
+	optionEnd            = 0
+	optionIfTsresol      = 9
+	pcapngBlockMinLength = 12
+
+	// maxRecord bounds the memory a record or a block can take, a corrupted
+	// length does not allocate more
+	maxRecord = 16 << 20
+)
+
+// ErrFormat is returned for a file which is neither pcap nor pcapng
+var ErrFormat = errors.New("not a pcap or pcapng file")
+
+// Record is a captured packet. Data is the captured bytes, it is reused by
+// the next call to Next; Length is the length of the packet on the wire,
+// more than len(Data) when the capture was truncated to its snaplen.
+type Record struct {
+	Timestamp time.Time
+	LinkType  uint32
+	Data      []byte
+	Length    int
+}
+
+// Reader reads the records of a pcap or a pcapng file
+type Reader struct {
+	r      *bufio.Reader
+	order  binary.ByteOrder
+	buf    []byte
+	header [pcapRecordHeader]byte
+
+	// ng is true for pcapng, pcap has one link type and resolution
+	ng         bool
+	linkType   uint32
+	nanos      bool
+	interfaces []iface
+}
+
+// iface is an interface description block of the current pcapng section
+type iface struct {
+	linkType uint32
+	// units is the number of timestamp units per second
+	units uint64
+}
+
+// NewReader reads the header of a capture, the format is told by its magic
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReaderSize(r, 1<<16)}
+
+	magic, err := reader.r.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	if binary.LittleEndian.Uint32(magic) == blockSectionHeader {
+		reader.ng = true
+		return reader, nil
+	}
+
+	header := make([]byte, pcapHeaderSize)
+	if _, err := io.ReadFull(reader.r, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	switch {
+	case binary.LittleEndian.Uint32(header) == magicMicros:
+		reader.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(header) == magicMicros:
+		reader.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(header) == magicNanos:
+		reader.order, reader.nanos = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(header) == magicNanos:
+		reader.order, reader.nanos = binary.BigEndian, true
+	default:
+		return nil, ErrFormat
+	}
+	// 高 4 位可能带有 FCS 信息
+	reader.linkType = reader.order.Uint32(header[20:]) & 0x0fffffff
+	return reader, nil
+}
+
+// Next returns the next record, io.EOF at the end of the file
+func (r *Reader) Next() (Record, error) {
+	if r.ng {
+		return r.nextBlock()
+	}
+	return r.nextRecord()
+}
+
+func (r *Reader) nextRecord() (Record, error) {
+	header := r.header[:]
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return Record{}, fmt.Errorf("truncated record header: %w", err)
+		}
+		return Record{}, err
+	}
+
+	sec := r.order.Uint32(header)
+	frac := r.order.Uint32(header[4:])
+	captured := r.order.Uint32(header[8:])
+	length := r.order.Uint32(header[12:])
+	if captured > maxRecord {
+		return Record{}, fmt.Errorf("record of %d bytes is too large", captured)
+	}
+	data, err := r.read(int(captured))
+	if err != nil {
+		return Record{}, fmt.Errorf("truncated record: %w", err)
+	}
+
+	nsec := int64(frac) * 1000
+	if r.nanos {
+		nsec = int64(frac)
+	}
+	return Record{
+		Timestamp: time.Unix(int64(sec), nsec),
+		LinkType:  r.linkType,
+		Data:      data,
+		Length:    max(int(length), len(data)),
+	}, nil
+}
+
+// nextBlock reads the blocks until a packet, the section headers and the
+// interface descriptions are kept for the packets following them
+func (r *Reader) nextBlock() (Record, error) {
+	for {
+		head, err := r.r.Peek(8)
+		if err != nil {
+			if err == io.EOF && len(head) == 0 {
+				return Record{}, io.EOF
+			}
+			return Record{}, fmt.Errorf("truncated block header: %w", err)
+		}
+
+		if binary.LittleEndian.Uint32(head) == blockSectionHeader {
+			if err := r.sectionHeader(); err != nil {
+				return Record{}, err
+			}
+			continue
+		}
+		if r.order == nil {
+			return Record{}, ErrFormat
+		}
+
+		blockType := r.order.Uint32(head)
+		total := r.order.Uint32(head[4:])
+		if total < pcapngBlockMinLength || total%4 != 0 || total > maxRecord {
+			return Record{}, fmt.Errorf("invalid block length %d", total)
+		}
+		block, err := r.read(int(total))
+		if err != nil {
+			return Record{}, fmt.Errorf("truncated block: %w", err)
+		}
+		body := block[8 : total-4]
+
+		switch blockType {
+		case blockInterface:
+			if err := r.interfaceDescription(body); err != nil {
+				return Record{}, err
+			}
+		case blockEnhancedPacket:
+			return r.enhancedPacket(body)
+		case blockPacket:
+			return r.packet(body)
+		case blockSimplePacket:
+			return r.simplePacket(body)
+		}
+	}
+}
+
+func (r *Reader) sectionHeader() error {
+	head, err := r.r.Peek(12)
+	if err != nil {
+		return fmt.Errorf("truncated section header: %w", err)
+	}
+	switch {
+	case binary.LittleEndian.Uint32(head[8:]) == byteOrderMagic:
+		r.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(head[8:]) == byteOrderMagic:
+		r.order = binary.BigEndian
+	default:
+		return ErrFormat
+	}
+
+	total := r.order.Uint32(head[4:])
+	if total < pcapngBlockMinLength+4 || total%4 != 0 || total > maxRecord {
+		return fmt.Errorf("invalid section header length %d", total)
+	}
+	if _, err := r.read(int(total)); err != nil {
+		return fmt.Errorf("truncated section header: %w", err)
+	}
+	// 每个 section 重新编号接口
+	r.interfaces = r.interfaces[:0]
+	return nil
+}
+
+func (r *Reader) interfaceDescription(body []byte) error {
+	if len(body) < 8 {
+		return fmt.Errorf("interface description of %d bytes", len(body))
+	}
+	i := iface{linkType: uint32(r.order.Uint16(body)), units: 1_000_000}
+
+	for options := body[8:]; len(options) >= 4; {
+		code := r.order.Uint16(options)
+		length := int(r.order.Uint16(options[2:]))
+		if code == optionEnd || 4+length > len(options) {
+			break
+		}
+		if code == optionIfTsresol && length >= 1 {
+			units, ok := tsresol(options[4])
+			if !ok {
+				return fmt.Errorf("unsupported timestamp resolution %#x", options[4])
+			}
+			i.units = units
+		}
+		next := 4 + (length+3)&^3
+		if next > len(options) {
+			break
+		}
+		options = options[next:]
+	}
+
+	r.interfaces = append(r.interfaces, i)
+	return nil
+}
+
+// tsresol returns the units per second of an if_tsresol option
+func tsresol(v byte) (uint64, bool) {
+	exp := uint64(v & 0x7f)
+	if v&0x80 != 0 {
+		if exp > 63 {
+			return 0, false
+		}
+		return 1 << exp, true
+	}
+	if exp > 19 {
+		return 0, false
+	}
+	return uint64(math.Pow10(int(exp))), true
+}
+
+func (r *Reader) enhancedPacket(body []byte) (Record, error) {
+	if len(body) < 20 {
+		return Record{}, fmt.Errorf("enhanced packet block of %d bytes", len(body))
+	}
+	ts := uint64(r.order.Uint32(body[4:]))<<32 | uint64(r.order.Uint32(body[8:]))
+	return r.record(r.order.Uint32(body), ts, body[20:], r.order.Uint32(body[12:]), r.order.Uint32(body[16:]))
+}
+
+func (r *Reader) packet(body []byte) (Record, error) {
+	if len(body) < 20 {
+		return Record{}, fmt.Errorf("packet block of %d bytes", len(body))
+	}
+	ts := uint64(r.order.Uint32(body[4:]))<<32 | uint64(r.order.Uint32(body[8:]))
+	return r.record(uint32(r.order.Uint16(body)), ts, body[20:], r.order.Uint32(body[12:]), r.order.Uint32(body[16:]))
+}
+
+// simplePacket is a packet of the first interface without a timestamp
+func (r *Reader) simplePacket(body []byte) (Record, error) {
+	if len(body) < 4 {
+		return Record{}, fmt.Errorf("simple packet block of %d bytes", len(body))
+	}
+	length := r.order.Uint32(body)
+	return r.record(0, 0, body[4:], min(length, uint32(len(body)-4)), length)
+}
+
+func (r *Reader) record(id uint32, ts uint64, data []byte, captured, length uint32) (Record, error) {
+	if int(id) >= len(r.interfaces) {
+		return Record{}, fmt.Errorf("packet of undescribed interface %d", id)
+	}
+	if int(captured) > len(data) {
+		return Record{}, fmt.Errorf("packet of %d bytes in a block of %d", captured, len(data))
+	}
+	i := r.interfaces[id]
+
+	var timestamp time.Time
+	if ts != 0 {
+		sec, frac := ts/i.units, ts%i.units
+		// frac < units, 商小于 1e9 不会溢出
+		hi, lo := bits.Mul64(frac, uint64(time.Second))
+		nsec, _ := bits.Div64(hi, lo, i.units)
+		timestamp = time.Unix(int64(sec), int64(nsec))
+	}
+	return Record{
+		Timestamp: timestamp,
+		LinkType:  i.linkType,
+		Data:      data[:captured],
+		Length:    max(int(length), int(captured)),
+	}, nil
+}
+
+// read reads n bytes into the buffer reused across records
+func (r *Reader) read(n int) ([]byte, error) {
+	if cap(r.buf) < n {
+		r.buf = make([]byte, n)
+	}
+	buf := r.buf[:n]
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf, nil
+}
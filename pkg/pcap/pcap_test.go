@@ -0,0 +1,135 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func pcapFile(order binary.ByteOrder, magic uint32, linkType uint32, records ...[]uint32) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, order, []uint32{magic, 0x00040002, 0, 0, 65535, linkType})
+	for _, r := range records {
+		// r: sec, frac, captured, length
+		binary.Write(&buf, order, r)
+		buf.Write(make([]byte, r[2]))
+	}
+	return buf.Bytes()
+}
+
+func TestReadPcap(t *testing.T) {
+	tests := []struct {
+		name  string
+		order binary.ByteOrder
+		magic uint32
+		want  time.Time
+	}{
+		{"little endian micros", binary.LittleEndian, magicMicros, time.Unix(100, 5000)},
+		{"big endian nanos", binary.BigEndian, magicNanos, time.Unix(100, 5)},
+	}
+	for _, tt := range tests {
+		data := pcapFile(tt.order, tt.magic, LinkTypeEthernet|0x10000000, []uint32{100, 5, 60, 60}, []uint32{101, 0, 14, 1514})
+		r, err := NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		record, err := r.Next()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !record.Timestamp.Equal(tt.want) || record.LinkType != LinkTypeEthernet || len(record.Data) != 60 || record.Length != 60 {
+			t.Errorf("%s: first record %v %d %d %d", tt.name, record.Timestamp, record.LinkType, len(record.Data), record.Length)
+		}
+		record, err = r.Next()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(record.Data) != 14 || record.Length != 1514 {
+			t.Errorf("%s: truncated record %d %d", tt.name, len(record.Data), record.Length)
+		}
+		if _, err := r.Next(); err != io.EOF {
+			t.Errorf("%s: Next at the end = %v, want EOF", tt.name, err)
+		}
+	}
+
+	if _, err := NewReader(bytes.NewReader([]byte("not a capture file"))); !errors.Is(err, ErrFormat) {
+		t.Errorf("NewReader of a text = %v, want ErrFormat", err)
+	}
+	data := pcapFile(binary.LittleEndian, magicMicros, LinkTypeEthernet, []uint32{1, 0, 60, 60})
+	r, _ := NewReader(bytes.NewReader(data[:len(data)-10]))
+	if _, err := r.Next(); err == nil || err == io.EOF {
+		t.Errorf("Next of a truncated record = %v", err)
+	}
+}
+
+// block encodes a pcapng block
+func block(order binary.ByteOrder, blockType uint32, body []byte) []byte {
+	padded := (len(body) + 3) &^ 3
+	total := uint32(12 + padded)
+	var buf bytes.Buffer
+	binary.Write(&buf, order, []uint32{blockType, total})
+	buf.Write(body)
+	buf.Write(make([]byte, padded-len(body)))
+	binary.Write(&buf, order, total)
+	return buf.Bytes()
+}
+
+func fields(order binary.ByteOrder, values ...any) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		binary.Write(&buf, order, v)
+	}
+	return buf.Bytes()
+}
+
+func TestReadPcapng(t *testing.T) {
+	var buf bytes.Buffer
+	le, be := binary.LittleEndian, binary.BigEndian
+
+	// 小端 section: 纳秒精度的以太网接口和 SLL 接口
+	buf.Write(block(le, blockSectionHeader, fields(le, uint32(byteOrderMagic), uint16(1), uint16(0), int64(-1))))
+	buf.Write(block(le, blockInterface, fields(le, uint16(LinkTypeEthernet), uint16(0), uint32(0),
+		uint16(optionIfTsresol), uint16(1), [4]byte{9}, uint16(optionEnd), uint16(0))))
+	buf.Write(block(le, blockInterface, fields(le, uint16(LinkTypeLinuxSLL), uint16(0), uint32(0))))
+	ts := uint64(100*time.Second + 7)
+	buf.Write(block(le, blockEnhancedPacket, fields(le, uint32(1), uint32(ts>>32), uint32(ts), uint32(3), uint32(1000), [3]byte{1, 2, 3})))
+	buf.Write(block(le, blockEnhancedPacket, fields(le, uint32(0), uint32(ts>>32), uint32(ts), uint32(2), uint32(2), [2]byte{4, 5})))
+	// 未知的 block 被跳过
+	buf.Write(block(le, 0x00000bad, []byte{1, 2, 3, 4}))
+
+	// 大端 section 重新编号接口
+	buf.Write(block(be, blockSectionHeader, fields(be, uint32(byteOrderMagic), uint16(1), uint16(0), int64(-1))))
+	buf.Write(block(be, blockInterface, fields(be, uint16(LinkTypeRaw), uint16(0), uint32(0))))
+	buf.Write(block(be, blockSimplePacket, fields(be, uint32(5), [5]byte{6, 7, 8, 9, 10})))
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		ts       time.Time
+		linkType uint32
+		data     []byte
+		length   int
+	}{
+		{time.Unix(0, int64(ts*1000)), LinkTypeLinuxSLL, []byte{1, 2, 3}, 1000},
+		{time.Unix(100, 7), LinkTypeEthernet, []byte{4, 5}, 2},
+		{time.Time{}, LinkTypeRaw, []byte{6, 7, 8, 9, 10}, 5},
+	}
+	for i, w := range want {
+		record, err := r.Next()
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if !record.Timestamp.Equal(w.ts) || record.LinkType != w.linkType || !bytes.Equal(record.Data, w.data) || record.Length != w.length {
+			t.Errorf("record %d = %v %d %v %d, want %v %d %v %d", i, record.Timestamp, record.LinkType, record.Data, record.Length, w.ts, w.linkType, w.data, w.length)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Next at the end = %v, want EOF", err)
+	}
+}
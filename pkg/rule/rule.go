@@ -11,8 +11,12 @@ import (
 type RuleMeta struct {
 	Comment   string    `json:"comment"`
 	CreatedAt time.Time `json:"created_at"`
-	ExpiresAt time.Time `json:"expires_at"`
-	Identity  string    `json:"identity"` // 新增字段，用来保存 identity 信息
+	ExpiresAt time.Time `json:"expires_at,omitzero"` // 永久规则为零值, 不绑定 lease
+	Identity  string    `json:"identity"`            // 新增字段，用来保存 identity 信息
+	// Aggregated 是合并进这条规则的原始 CIDR, 规则未经合并时为空
+	Aggregated []string `json:"aggregated,omitempty"`
+	// Scheduled 表示规则由定时规则在其激活窗口内写入, 窗口结束时由 lease 撤回
+	Scheduled bool `json:"scheduled,omitempty"`
 }
 
 type RuleInfo struct {
@@ -24,6 +28,11 @@ type RuleInfo struct {
 	Duration string `json:"duration,omitempty"`
 }
 
+// Permanent reports whether the rule never expires
+func (c *RuleMeta) Permanent() bool {
+	return c.ExpiresAt.IsZero()
+}
+
 func (c *RuleMeta) Marshal() []byte {
 	b, err := json.Marshal(c)
 	if err != nil {
@@ -31,6 +31,25 @@ func (d *Duration) UnmarshalJSON(b []byte) error {
 	return err
 }
 
+// PermanentDuration is the duration of a rule which never expires, "0" works as well
+const PermanentDuration = "permanent"
+
+// ParseRuleDuration parses the duration of a rule, a permanent rule gives 0
+func ParseRuleDuration(s string) (time.Duration, error) {
+	if s == PermanentDuration {
+		return 0, nil
+	}
+
+	d, err := ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration %q", s)
+	}
+	return d, nil
+}
+
 // ParseDuration parses a duration string, adding
 // support for the "d" unit meaning number of days,
 // where a day is assumed to be 24h. The maximum
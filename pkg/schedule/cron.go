@@ -0,0 +1,225 @@
+// Package schedule computes the activation windows of the scheduled rules,
+// from a cron expression in a timezone or from absolute start and end times.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// 容器内常常没有 tzdata, 时区数据编译进二进制
+	_ "time/tzdata"
+)
+
+// maxSearchDays bounds the search of the next tick, an expression like
+// "0 0 30 2 *" never matches
+const maxSearchDays = 5 * 366
+
+// field is the range of a field of a cron expression
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 0 和 7 都是星期日
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Cron is a parsed cron expression of the five standard fields
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar are true for the fields starting with "*", as
+	// in Vixie cron a day matches either restricted day field
+	domStar, dowStar bool
+	loc              *time.Location
+}
+
+// ParseCron parses "minute hour day-of-month month day-of-week" or one of
+// the descriptors @yearly, @monthly, @weekly, @daily and @hourly. A field is
+// a comma separated list of "*", "n", "n-m", each with an optional "/step";
+// months and days of week accept their three letter English names.
+func ParseCron(expr string, loc *time.Location) (*Cron, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	spec := strings.TrimSpace(expr)
+	if d, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = d
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	c := &Cron{loc: loc}
+	var err error
+	if c.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, fmt.Errorf("cron %q: %w", expr, err)
+	}
+	if c.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, fmt.Errorf("cron %q: %w", expr, err)
+	}
+	if c.dom, err = parseField(fields[2], domField); err != nil {
+		return nil, fmt.Errorf("cron %q: %w", expr, err)
+	}
+	if c.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, fmt.Errorf("cron %q: %w", expr, err)
+	}
+	if c.dow, err = parseField(fields[4], dowField); err != nil {
+		return nil, fmt.Errorf("cron %q: %w", expr, err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = strings.HasPrefix(fields[2], "*")
+	c.dowStar = strings.HasPrefix(fields[4], "*")
+	return c, nil
+}
+
+func parseField(s string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		b, err := parseRange(part, f)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+func parseRange(s string, f field) (uint64, error) {
+	expr, step := s, 1
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		n, err := strconv.Atoi(s[i+1:])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid step %q of %s", s[i+1:], f.name)
+		}
+		expr, step = s[:i], n
+	}
+
+	var lo, hi int
+	switch {
+	case expr == "*":
+		lo, hi = f.min, f.max
+	case strings.Contains(expr, "-"):
+		a, b, _ := strings.Cut(expr, "-")
+		var err error
+		if lo, err = f.value(a); err != nil {
+			return 0, err
+		}
+		if hi, err = f.value(b); err != nil {
+			return 0, err
+		}
+		if hi < lo {
+			return 0, fmt.Errorf("invalid range %q of %s", expr, f.name)
+		}
+	default:
+		var err error
+		if lo, err = f.value(expr); err != nil {
+			return 0, err
+		}
+		hi = lo
+		// "n/step" 从 n 开始直到最大值
+		if strings.Contains(s, "/") {
+			hi = f.max
+		}
+	}
+
+	var bits uint64
+	for v := lo; v <= hi; v += step {
+		bits |= 1 << v
+	}
+	return bits, nil
+}
+
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", f.name, s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s %d out of range %d-%d", f.name, v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Location is the timezone the expression is evaluated in
+func (c *Cron) Location() *time.Location {
+	return c.loc
+}
+
+// Next returns the first tick strictly after t, the zero time when none
+// comes within five years. The times skipped by a daylight saving change do
+// not tick.
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.In(c.loc)
+	y, m, d := t.Date()
+
+	for i := 0; i <= maxSearchDays; i++ {
+		// 用正午计算日期和星期, 避开午夜的夏令时切换
+		day := time.Date(y, m, d+i, 12, 0, 0, 0, c.loc)
+		dy, dm, dd := day.Date()
+		if !c.matchDay(dm, dd, day.Weekday()) {
+			continue
+		}
+
+		for h := 0; h < 24; h++ {
+			if c.hour&(1<<h) == 0 {
+				continue
+			}
+			for min := 0; min < 60; min++ {
+				if c.minute&(1<<min) == 0 {
+					continue
+				}
+				tick := time.Date(dy, dm, dd, h, min, 0, 0, c.loc)
+				if tick.Day() != dd || tick.Hour() != h || tick.Minute() != min {
+					// 夏令时跳过的时间不存在
+					continue
+				}
+				if tick.After(t) {
+					return tick
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func (c *Cron) matchDay(m time.Month, d int, wd time.Weekday) bool {
+	if c.month&(1<<uint(m)) == 0 {
+		return false
+	}
+	dom := c.dom&(1<<d) != 0
+	dow := c.dow&(1<<uint(wd)) != 0
+	if !c.domStar && !c.dowStar {
+		return dom || dow
+	}
+	return dom && dow
+}
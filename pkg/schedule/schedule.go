@@ -0,0 +1,171 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxMerge bounds the ticks merged into one window, the ticks of "* * * * *"
+// with a window of an hour never leave a gap
+const maxMerge = 24 * 60
+
+// Window is a period a scheduled rule is active, from Start included to End
+// excluded
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Contains reports whether t is in the window
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// Schedule is when a scheduled rule is active: for Duration after every tick
+// of a cron expression, or from Start to End. With a cron expression Start
+// and End bound its windows, a zero one is unbounded.
+type Schedule struct {
+	cron     *Cron
+	duration time.Duration
+	start    time.Time
+	end      time.Time
+}
+
+// New returns the schedule of a cron expression in timezone, an empty one
+// is UTC, whose windows last duration. Without an expression the schedule is
+// the single window from start to end.
+func New(cron string, duration time.Duration, timezone string, start, end time.Time) (*Schedule, error) {
+	if !start.IsZero() && !end.IsZero() && !end.After(start) {
+		return nil, errors.New("end must be after start")
+	}
+
+	if cron == "" {
+		if start.IsZero() || end.IsZero() {
+			return nil, errors.New("a schedule without cron requires both start and end")
+		}
+		if duration != 0 || timezone != "" {
+			return nil, errors.New("duration and timezone only apply to a cron schedule")
+		}
+		return &Schedule{start: start, end: end}, nil
+	}
+
+	if duration <= 0 {
+		return nil, errors.New("a cron schedule requires a positive duration")
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
+	c, err := ParseCron(cron, loc)
+	if err != nil {
+		return nil, err
+	}
+	return &Schedule{cron: c, duration: duration, start: start, end: end}, nil
+}
+
+// Active returns the window containing t. The window of a tick lasts
+// duration, the windows overlapping or touching are merged so the rule is
+// not withdrawn between them.
+func (s *Schedule) Active(t time.Time) (Window, bool) {
+	if s.cron == nil {
+		w := Window{Start: s.start, End: s.end}
+		return w, w.Contains(t)
+	}
+
+	// 覆盖 t 的最早一次触发在 (t-duration, t] 之内
+	tick := s.cron.Next(t.Add(-s.duration))
+	if tick.IsZero() || tick.After(t) {
+		return Window{}, false
+	}
+	w, ok := s.clip(s.merge(tick))
+	if !ok || !w.Contains(t) {
+		return Window{}, false
+	}
+	return w, true
+}
+
+// Next returns the first window starting after t, it does not start in the
+// window active at t.
+func (s *Schedule) Next(t time.Time) (Window, bool) {
+	if s.cron == nil {
+		if s.start.After(t) {
+			return Window{Start: s.start, End: s.end}, true
+		}
+		return Window{}, false
+	}
+
+	from := t
+	if w, ok := s.Active(t); ok {
+		from = w.End
+	}
+	// 早于 start-duration 的触发在 start 之前就结束了
+	if !s.start.IsZero() && from.Before(s.start.Add(-s.duration)) {
+		from = s.start.Add(-s.duration)
+	}
+
+	tick := s.cron.Next(from)
+	if tick.IsZero() {
+		return Window{}, false
+	}
+	return s.clip(s.merge(tick))
+}
+
+// Windows returns the windows from t on, up to n, the one active at t first
+func (s *Schedule) Windows(t time.Time, n int) []Window {
+	var windows []Window
+	if w, ok := s.Active(t); ok {
+		windows = append(windows, w)
+	}
+	for len(windows) < n {
+		w, ok := s.Next(t)
+		if !ok {
+			break
+		}
+		windows = append(windows, w)
+		t = w.Start
+	}
+	return windows
+}
+
+// Transition returns the next time the schedule changes from t on: the end of
+// the window active at t, or else the start of the next one. false means the
+// schedule has no window left.
+func (s *Schedule) Transition(t time.Time) (time.Time, bool) {
+	if w, ok := s.Active(t); ok {
+		return w.End, true
+	}
+	if w, ok := s.Next(t); ok {
+		return w.Start, true
+	}
+	return time.Time{}, false
+}
+
+// merge returns the window of a tick with the ticks following it before its
+// end
+func (s *Schedule) merge(tick time.Time) Window {
+	w := Window{Start: tick, End: tick.Add(s.duration)}
+	last := tick
+	for range maxMerge {
+		next := s.cron.Next(last)
+		if next.IsZero() || next.After(w.End) {
+			break
+		}
+		w.End, last = next.Add(s.duration), next
+	}
+	return w
+}
+
+// clip bounds a window to start and end, false when nothing is left
+func (s *Schedule) clip(w Window) (Window, bool) {
+	if !s.start.IsZero() && w.Start.Before(s.start) {
+		w.Start = s.start
+	}
+	if !s.end.IsZero() && w.End.After(s.end) {
+		w.End = s.end
+	}
+	if !w.End.After(w.Start) {
+		return Window{}, false
+	}
+	return w, true
+}
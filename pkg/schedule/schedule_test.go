@@ -0,0 +1,202 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func mustTime(t *testing.T, loc *time.Location, s string) time.Time {
+	t.Helper()
+	v, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestParseCron(t *testing.T) {
+	for _, expr := range []string{
+		"* * * * *", "0 9 * * mon-fri", "*/15 8-18 * * 1,3,5", "30 2 1 jan,jul *",
+		"0 0 * * 7", "5/10 * * * *", "@daily", "@Hourly",
+	} {
+		if _, err := ParseCron(expr, nil); err != nil {
+			t.Errorf("ParseCron(%q) failed: %v", expr, err)
+		}
+	}
+
+	for _, expr := range []string{
+		"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *",
+		"* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@every",
+	} {
+		if _, err := ParseCron(expr, nil); err == nil {
+			t.Errorf("ParseCron(%q) succeeded", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		expr string
+		loc  *time.Location
+		from string
+		want string
+	}{
+		{"0 9 * * mon-fri", shanghai, "2026-10-16 09:00", "2026-10-19 09:00"}, // 周五之后是周一
+		{"0 9 * * mon-fri", shanghai, "2026-10-19 08:59", "2026-10-19 09:00"},
+		{"*/15 * * * *", time.UTC, "2026-10-19 10:07", "2026-10-19 10:15"},
+		{"5/20 * * * *", time.UTC, "2026-10-19 10:26", "2026-10-19 10:45"},
+		{"0 0 29 2 *", time.UTC, "2026-01-01 00:00", "2028-02-29 00:00"},
+		{"0 0 1 * 0", time.UTC, "2026-10-19 00:00", "2026-10-25 00:00"}, // 两个日期字段取或
+		{"0 0 * * 7", time.UTC, "2026-10-19 00:00", "2026-10-25 00:00"},
+		{"0 0 1 jan *", time.UTC, "2026-10-19 00:00", "2027-01-01 00:00"},
+		// 2026-03-08 02:30 在纽约不存在
+		{"30 2 * * *", newYork, "2026-03-07 03:00", "2026-03-09 02:30"},
+	}
+	for _, c := range cases {
+		cron, err := ParseCron(c.expr, c.loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := cron.Next(mustTime(t, c.loc, c.from))
+		if want := mustTime(t, c.loc, c.want); !got.Equal(want) {
+			t.Errorf("%q.Next(%s) = %s, want %s", c.expr, c.from, got, want)
+		}
+	}
+
+	never, err := ParseCron("0 0 30 2 *", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := never.Next(time.Now()); !got.IsZero() {
+		t.Errorf("30 of February ticks at %s", got)
+	}
+}
+
+func TestScheduleCron(t *testing.T) {
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	at := func(s string) time.Time { return mustTime(t, shanghai, s) }
+
+	// 工作日 9 点到 18 点
+	s, err := New("0 9 * * mon-fri", 9*time.Hour, "Asia/Shanghai", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, ok := s.Active(at("2026-10-19 12:00"))
+	if !ok || !w.Start.Equal(at("2026-10-19 09:00")) || !w.End.Equal(at("2026-10-19 18:00")) {
+		t.Errorf("Active at noon = %v, %v", w, ok)
+	}
+	if _, ok := s.Active(at("2026-10-19 18:00")); ok {
+		t.Error("active at the end of the window")
+	}
+	if _, ok := s.Active(at("2026-10-18 12:00")); ok {
+		t.Error("active on sunday")
+	}
+
+	w, ok = s.Next(at("2026-10-19 12:00"))
+	if !ok || !w.Start.Equal(at("2026-10-20 09:00")) {
+		t.Errorf("Next at noon = %v, %v", w, ok)
+	}
+	if tr, _ := s.Transition(at("2026-10-19 12:00")); !tr.Equal(at("2026-10-19 18:00")) {
+		t.Errorf("Transition at noon = %s", tr)
+	}
+	if tr, _ := s.Transition(at("2026-10-17 12:00")); !tr.Equal(at("2026-10-19 09:00")) {
+		t.Errorf("Transition on saturday = %s", tr)
+	}
+
+	windows := s.Windows(at("2026-10-23 12:00"), 3)
+	want := []string{"2026-10-23 09:00", "2026-10-26 09:00", "2026-10-27 09:00"}
+	if len(windows) != len(want) {
+		t.Fatalf("Windows = %v", windows)
+	}
+	for i, w := range windows {
+		if !w.Start.Equal(at(want[i])) {
+			t.Errorf("window %d starts at %s, want %s", i, w.Start, want[i])
+		}
+	}
+}
+
+func TestScheduleMergeAndBounds(t *testing.T) {
+	at := func(s string) time.Time { return mustTime(t, time.UTC, s) }
+
+	// 每 30 分钟触发一次, 每次持续 45 分钟, 窗口首尾相连
+	s, err := New("0,30 10-11 * * *", 45*time.Minute, "", at("2026-10-19 10:10"), at("2026-10-20 00:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, ok := s.Active(at("2026-10-19 10:20"))
+	if !ok || !w.Start.Equal(at("2026-10-19 10:10")) || !w.End.Equal(at("2026-10-19 12:15")) {
+		t.Errorf("Active = %v, %v", w, ok)
+	}
+	if _, ok := s.Active(at("2026-10-19 10:05")); ok {
+		t.Error("active before start")
+	}
+	if w, ok := s.Next(at("2026-10-19 10:20")); ok {
+		t.Errorf("Next after the last window = %v", w)
+	}
+	w, ok = s.Next(at("2026-10-19 08:00"))
+	if !ok || !w.Start.Equal(at("2026-10-19 10:10")) {
+		t.Errorf("Next before start = %v, %v", w, ok)
+	}
+	if tr, ok := s.Transition(at("2026-10-19 13:00")); ok {
+		t.Errorf("Transition after the last window = %s", tr)
+	}
+}
+
+func TestScheduleAbsolute(t *testing.T) {
+	at := func(s string) time.Time { return mustTime(t, time.UTC, s) }
+	s, err := New("", 0, "", at("2026-10-20 01:00"), at("2026-10-20 03:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.Active(at("2026-10-20 00:59")); ok {
+		t.Error("active before start")
+	}
+	if _, ok := s.Active(at("2026-10-20 01:00")); !ok {
+		t.Error("inactive at start")
+	}
+	if w, ok := s.Next(at("2026-10-20 00:00")); !ok || !w.End.Equal(at("2026-10-20 03:00")) {
+		t.Errorf("Next = %v, %v", w, ok)
+	}
+	if _, ok := s.Next(at("2026-10-20 01:00")); ok {
+		t.Error("next window after start")
+	}
+	if tr, _ := s.Transition(at("2026-10-20 02:00")); !tr.Equal(at("2026-10-20 03:00")) {
+		t.Errorf("Transition = %s", tr)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		cron     string
+		duration time.Duration
+		timezone string
+		start    time.Time
+		end      time.Time
+	}{
+		{"", 0, "", now, time.Time{}},
+		{"", 0, "", now, now},
+		{"", time.Hour, "", now, now.Add(time.Hour)},
+		{"0 9 * * *", 0, "", time.Time{}, time.Time{}},
+		{"0 9 * * *", time.Hour, "Mars/Olympus", time.Time{}, time.Time{}},
+		{"0 9 * *", time.Hour, "", time.Time{}, time.Time{}},
+		{"0 9 * * *", time.Hour, "", now, now.Add(-time.Hour)},
+	}
+	for _, c := range cases {
+		if _, err := New(c.cron, c.duration, c.timezone, c.start, c.end); err == nil {
+			t.Errorf("New(%q, %s, %q, %s, %s) succeeded", c.cron, c.duration, c.timezone, c.start, c.end)
+		}
+	}
+}
@@ -9,12 +9,15 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type ToPublicMethod func(fullMethodName string)
 
 type GrpcServer struct {
 	server    *grpc.Server
+	health    *health.Server
 	authInter *middleware.MTLSAuthInterceptor
 }
 
@@ -30,8 +33,13 @@ func NewGrpcServer(service GrpcServices, creds credentials.TransportCredentials)
 	setupGrpcService(service, gs)
 	setupGrpcPublicMethod(service, authInter.ToPublic())
 
+	// 客户端根据 grpc health 检查结果在多个 server 之间做负载均衡和故障转移
+	hs := health.NewServer()
+	healthgrpc.RegisterHealthServer(gs, hs)
+
 	return GrpcServer{
 		server:    gs,
+		health:    hs,
 		authInter: authInter,
 	}
 }
@@ -47,6 +55,8 @@ func (gs GrpcServer) Serve(addr string) error {
 }
 
 func (gs GrpcServer) Stop() {
+	// 先通知客户端切换到其它 server, 再断开连接
+	gs.health.Shutdown()
 	gs.server.Stop()
 }
 
@@ -108,3 +108,29 @@ func getPeerName(ctx context.Context) string {
 
 	return tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
 }
+
+// VerifiedPeerName returns the common name of the verified client certificate.
+// Unlike the PeerName context value it never falls back to "localhost", use it
+// when the caller identity itself is what is being authorized.
+func VerifiedPeerName(ctx context.Context) (string, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", status.Errorf(codes.Unauthenticated, "unable to retrieve peer information")
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return "", status.Errorf(codes.Unauthenticated, "you must use mTLS to access this method")
+	}
+
+	if len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return "", status.Errorf(codes.Unauthenticated, "client certificate verification failed")
+	}
+
+	name := tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+	if name == "" {
+		return "", status.Errorf(codes.Unauthenticated, "client certificate has no common name")
+	}
+
+	return name, nil
+}
@@ -28,7 +28,7 @@ import (
 // implement these handlers yourself.
 func TrapSignals(exitProcess func()) {
 	trapSignalsCrossPlatform(exitProcess)
-	trapSignalsPosix()
+	trapSignalsPosix(exitProcess)
 }
 
 // trapSignalsCrossPlatform captures SIGINT or interrupt (depending
@@ -16,4 +16,4 @@
 
 package sig
 
-func trapSignalsPosix() {}
+func trapSignalsPosix(exitProcess func()) {}
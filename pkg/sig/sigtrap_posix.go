@@ -52,8 +52,8 @@ func trapSignalsPosix(exitProcess func()) {
 				log.Info("not implemented", zap.String("signal", "SIGUSR2"))
 
 			case syscall.SIGHUP:
-				// ignore; this signal is sometimes sent outside of the user's control
-				log.Info("not implemented", zap.String("signal", "SIGHUP"))
+				// the config reloader handles it, see config.WatchReload
+				log.Debug("reloading config", zap.String("signal", "SIGHUP"))
 			}
 		}
 	}()